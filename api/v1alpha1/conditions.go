@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -8,8 +10,15 @@ import (
 type ConditionType string
 
 const (
-	TypeReady  ConditionType = "Ready"
-	TypeSynced ConditionType = "Synced"
+	TypeReady         ConditionType = "Ready"
+	TypeSynced        ConditionType = "Synced"
+	TypeChartReleased ConditionType = "ChartReleased"
+	TypeRolledOut     ConditionType = "RolledOut"
+	TypeProvisioning  ConditionType = "Provisioning"
+	TypeDegraded      ConditionType = "Degraded"
+
+	TypeIngressAvailable     ConditionType = "IngressAvailable"
+	TypeChartInstallTimedOut ConditionType = "ChartInstallTimedOut"
 )
 
 type ConditionReason string
@@ -19,12 +28,35 @@ const (
 	ReasonUnavailable ConditionReason = "Unavailable"
 	ReasonCreating    ConditionReason = "Creating"
 	ReasonDeleting    ConditionReason = "Deleting"
+
+	ReasonWaitingForLoadBalancer ConditionReason = "WaitingForLoadBalancer"
 )
 
 const (
-	ReasonReconcileSuccess ConditionReason = "ReconcileSuccess"
-	ReasonReconcileError   ConditionReason = "ReconcileError"
-	ReasonReconcilePaused  ConditionReason = "ReconcilePaused"
+	ReasonReconcileSuccess     ConditionReason = "ReconcileSuccess"
+	ReasonReconcileError       ConditionReason = "ReconcileError"
+	ReasonReconcilePaused      ConditionReason = "ReconcilePaused"
+	ReasonWaitingForReady      ConditionReason = "WaitingForReady"
+	ReasonWaitingForKubeconfig ConditionReason = "WaitingForKubeconfig"
+)
+
+const (
+	ReasonChartNotInstalled ConditionReason = "NotInstalled"
+)
+
+const (
+	ReasonRolloutInProgress ConditionReason = "RolloutInProgress"
+	ReasonRolloutComplete   ConditionReason = "RolloutComplete"
+)
+
+const (
+	ReasonInstalling           ConditionReason = "Installing"
+	ReasonProvisioned          ConditionReason = "Provisioned"
+	ReasonHealthy              ConditionReason = "Healthy"
+	ReasonAPIServerUnavailable ConditionReason = "APIServerUnavailable"
+	ReasonNoIngressController  ConditionReason = "NoIngressController"
+	ReasonChartInstallTimeout  ConditionReason = "ChartInstallTimeout"
+	ReasonChartInstallComplete ConditionReason = "ChartInstallComplete"
 )
 
 // ControlPlaneCondition describes the state of a control plane at a certain point.
@@ -35,6 +67,9 @@ type ControlPlaneCondition struct {
 	LastTransitionTime metav1.Time            `json:"lastTransitionTime"`
 	Reason             ConditionReason        `json:"reason"`
 	Message            string                 `json:"message"`
+	// ObservedGeneration is the generation of the control plane spec the condition was set for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // areConditionsEqual compares two ControlPlaneCondition structs and
@@ -101,6 +136,16 @@ func AreConditionSlicesSame(c1, c2 []ControlPlaneCondition) bool {
 	return true
 }
 
+// GetCondition returns the condition of the given type, or nil if it is not set
+func GetCondition(conditions []ControlPlaneCondition, conditionType ConditionType) *ControlPlaneCondition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
 func HasConditionAvailable(conditions []ControlPlaneCondition) bool {
 	for _, condition := range conditions {
 		if condition.Type == TypeReady &&
@@ -112,7 +157,20 @@ func HasConditionAvailable(conditions []ControlPlaneCondition) bool {
 	return false
 }
 
+// RemoveCondition removes the condition of the given type from the conditions of cp, if set
+func RemoveCondition(cp *ControlPlane, conditionType ConditionType) {
+	for i := range cp.Status.Conditions {
+		if cp.Status.Conditions[i].Type == conditionType {
+			cp.Status.Conditions = append(cp.Status.Conditions[:i], cp.Status.Conditions[i+1:]...)
+			return
+		}
+	}
+}
+
+// EnsureCondition sets newCondition in the conditions of cp, recording the generation of the
+// spec of cp it was set for
 func EnsureCondition(cp *ControlPlane, newCondition ControlPlaneCondition) {
+	newCondition.ObservedGeneration = cp.Generation
 	if cp.Status.Conditions == nil {
 		cp.Status.Conditions = []ControlPlaneCondition{}
 	}
@@ -167,6 +225,19 @@ func ConditionUnavailable() ControlPlaneCondition {
 	}
 }
 
+// ConditionWaitingForLoadBalancer returns a condition indicating that the cp is not
+// available yet because the load balancer exposing its API server has no address assigned
+func ConditionWaitingForLoadBalancer(service string) ControlPlaneCondition {
+	return ControlPlaneCondition{
+		Type:               TypeReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             ReasonWaitingForLoadBalancer,
+		Message:            fmt.Sprintf("waiting for load balancer service %s to be assigned an external IP or hostname", service),
+	}
+}
+
 // ReconcileSuccess returns a condition indicating that KubeFlex reconciled the resource
 func ConditionReconcileSuccess() ControlPlaneCondition {
 	return ControlPlaneCondition{
@@ -190,3 +261,154 @@ func ConditionReconcileError(err error) ControlPlaneCondition {
 		Message:            err.Error(),
 	}
 }
+
+// ConditionWaitingForReady returns a condition indicating that KubeFlex reconciled the
+// resources of the control plane and waits for its API server to have a ready replica
+// before reporting success
+func ConditionWaitingForReady(message string) ControlPlaneCondition {
+	return ControlPlaneCondition{
+		Type:               TypeSynced,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             ReasonWaitingForReady,
+		Message:            message,
+	}
+}
+
+// ConditionWaitingForKubeconfig returns a condition that indicates the reconciler waits for
+// the kubeconfig secret of the control plane to be generated
+func ConditionWaitingForKubeconfig(message string) ControlPlaneCondition {
+	return ControlPlaneCondition{
+		Type:               TypeSynced,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             ReasonWaitingForKubeconfig,
+		Message:            message,
+	}
+}
+
+// ConditionChartReleased returns a condition reporting the status of the helm release of the
+// control plane chart. The condition is true when the release is deployed, and the reason is
+// the helm release status.
+func ConditionChartReleased(deployed bool, reason ConditionReason, message string) ControlPlaneCondition {
+	status := corev1.ConditionFalse
+	if deployed {
+		status = corev1.ConditionTrue
+	}
+	return ControlPlaneCondition{
+		Type:               TypeChartReleased,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// ConditionRolledOut returns a condition reporting whether all the replicas of the control
+// plane workload run its latest spec, for instance after a chart upgrade changed its resources
+func ConditionRolledOut(complete bool, message string) ControlPlaneCondition {
+	status := corev1.ConditionFalse
+	reason := ReasonRolloutInProgress
+	if complete {
+		status = corev1.ConditionTrue
+		reason = ReasonRolloutComplete
+	}
+	return ControlPlaneCondition{
+		Type:               TypeRolledOut,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// ConditionProvisioning returns a condition reporting whether the control plane is still being
+// installed, that is it has not been ready yet. It turns false the first time the control plane
+// is ready and stays false afterwards.
+func ConditionProvisioning(provisioning bool) ControlPlaneCondition {
+	status := corev1.ConditionFalse
+	reason := ReasonProvisioned
+	if provisioning {
+		status = corev1.ConditionTrue
+		reason = ReasonInstalling
+	}
+	return ControlPlaneCondition{
+		Type:               TypeProvisioning,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             reason,
+	}
+}
+
+// ConditionDegraded returns a condition reporting whether a control plane that was ready
+// is now failing
+func ConditionDegraded(degraded bool) ControlPlaneCondition {
+	status := corev1.ConditionFalse
+	reason := ReasonHealthy
+	if degraded {
+		status = corev1.ConditionTrue
+		reason = ReasonAPIServerUnavailable
+	}
+	return ControlPlaneCondition{
+		Type:               TypeDegraded,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             reason,
+	}
+}
+
+// ConditionIngressAvailable returns an IngressAvailable condition reporting that the hosting
+// cluster has an ingress controller to serve the API server ingress of the control plane
+func ConditionIngressAvailable() ControlPlaneCondition {
+	return ControlPlaneCondition{
+		Type:               TypeIngressAvailable,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             ReasonAvailable,
+	}
+}
+
+// ConditionNoIngressController returns an IngressAvailable condition reporting that the API
+// server of the control plane is not exposed because the hosting cluster has no ingress controller
+func ConditionNoIngressController(message string) ControlPlaneCondition {
+	return ControlPlaneCondition{
+		Type:               TypeIngressAvailable,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             ReasonNoIngressController,
+		Message:            message,
+	}
+}
+
+// ConditionChartInstallTimeout returns a ChartInstallTimedOut condition reporting that the
+// chart of the control plane has not been installed or upgraded within its timeout
+func ConditionChartInstallTimeout(message string) ControlPlaneCondition {
+	return ControlPlaneCondition{
+		Type:               TypeChartInstallTimedOut,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             ReasonChartInstallTimeout,
+		Message:            message,
+	}
+}
+
+// ConditionChartInstallComplete returns a ChartInstallTimedOut condition reporting that the
+// chart of the control plane, which had timed out, is now installed and rolled out
+func ConditionChartInstallComplete() ControlPlaneCondition {
+	return ControlPlaneCondition{
+		Type:               TypeChartInstallTimedOut,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		LastUpdateTime:     metav1.Now(),
+		Reason:             ReasonChartInstallComplete,
+	}
+}
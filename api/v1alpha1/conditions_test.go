@@ -87,6 +87,20 @@ func TestAreConditionSlicesSame(t *testing.T) {
 	}
 }
 
+func TestGetCondition(t *testing.T) {
+	conditions := []ControlPlaneCondition{
+		generateCondition(TypeReady, ReasonAvailable, "", corev1.ConditionTrue, metav1.Now(), metav1.Now()),
+	}
+
+	c := GetCondition(conditions, TypeReady)
+	if c == nil || c.Reason != ReasonAvailable {
+		t.Errorf("GetCondition failed: expected ready condition, but got %+v", c)
+	}
+	if GetCondition(conditions, TypeSynced) != nil {
+		t.Errorf("GetCondition failed: expected nil for missing condition")
+	}
+}
+
 func generateCondition(ctype ConditionType, reason ConditionReason, message string, status corev1.ConditionStatus, ltt, ltu metav1.Time) ControlPlaneCondition {
 	return ControlPlaneCondition{
 		Type:               ctype,
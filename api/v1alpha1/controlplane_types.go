@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,6 +28,159 @@ type ControlPlaneSpec struct {
 	Type           ControlPlaneType `json:"type,omitempty"`
 	Backend        BackendDBType    `json:"backend,omitempty"`
 	PostCreateHook *string          `json:"postCreateHook,omitempty"`
+	// PostCreateHooks references ConfigMaps and Secrets in the hosting cluster whose data
+	// holds manifests to apply into the control plane once it is available. Each reference
+	// is applied once, as recorded in status.appliedPostCreateHooks
+	// +optional
+	PostCreateHooks []PostCreateHookReference `json:"postCreateHooks,omitempty"`
+	// EgressSelector configures the API server egress through an EgressSelectorConfiguration.
+	// Only honored by the k8s control plane type
+	// +optional
+	EgressSelector *EgressSelectorSpec `json:"egressSelector,omitempty"`
+	// ImagePullSecrets references docker config secrets that are copied into the control plane
+	// namespace and used to pull the control plane images
+	// +optional
+	ImagePullSecrets []ImagePullSecretReference `json:"imagePullSecrets,omitempty"`
+	// AuthenticationWebhook configures the API server to authenticate bearer tokens through
+	// a token review webhook. Only honored by the k8s control plane type
+	// +optional
+	AuthenticationWebhook *AuthenticationWebhookSpec `json:"authenticationWebhook,omitempty"`
+	// ExternalCerts references externally issued certificates that are used verbatim
+	// instead of the ones generated by kubeflex. Only honored by the k8s control plane type
+	// +optional
+	ExternalCerts *ExternalCertsSpec `json:"externalCerts,omitempty"`
+	// ShutdownDelay is how long the API server keeps serving after it is asked to stop,
+	// while reporting not ready so that it is removed from the service endpoints first.
+	// Honored by the k8s and vcluster control plane types
+	// +optional
+	ShutdownDelay *metav1.Duration `json:"shutdownDelay,omitempty"`
+	// Audit enables API server audit logging, either with a policy generated for an audit
+	// level or with a custom policy. Only honored by the k8s control plane type
+	// +optional
+	Audit *AuditSpec `json:"audit,omitempty"`
+	// TopologySpreadConstraints controls how the control plane pods are spread across
+	// topology domains such as zones and nodes. When a constraint has no labelSelector,
+	// it selects the pods of the same control plane component.
+	// Honored by the k8s and vcluster control plane types
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// Replicas is the number of API server replicas of the control plane. When unset the
+	// k8s type runs one replica and the chart defaults apply to the vcluster and ocm types.
+	// Honored by the k8s, vcluster and ocm control plane types
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// PodDisruptionBudget creates a pod disruption budget for the API server pods, so that
+	// node drains keep enough replicas running. The control plane is only reported ready
+	// once the minimum available replicas are ready
+	// +optional
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// Resources are the resource requests and limits of the main container of the control
+	// plane, set through the chart values. When unset the chart defaults apply, and changing
+	// them upgrades the chart. Honored by the vcluster and ocm control plane types
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// MetricsRBAC creates a service account inside the control plane that can read the
+	// metrics endpoints, and a kubeconfig for it in the control plane namespace, for use
+	// by a metrics scraper running in the hosting cluster
+	// +optional
+	MetricsRBAC *MetricsRBACSpec `json:"metricsRBAC,omitempty"`
+	// ChartVerification requires the provenance of the control plane chart to be verified
+	// before the chart is installed. Only honored by the ocm and vcluster control plane types
+	// +optional
+	ChartVerification *ChartVerificationSpec `json:"chartVerification,omitempty"`
+	// ChartInstallTimeout is how long the chart of the control plane may keep failing to
+	// install or upgrade before the control plane is marked Degraded. It overrides the
+	// chartInstallTimeout of the kubeflex-config config map, and zero disables the timeout.
+	// Only honored by the ocm and vcluster control plane types
+	// +optional
+	ChartInstallTimeout *metav1.Duration `json:"chartInstallTimeout,omitempty"`
+	// Chart installs the control plane chart from an OCI registry instead of the default
+	// chart repository. Only honored by the ocm and vcluster control plane types
+	// +optional
+	Chart *ChartSpec `json:"chart,omitempty"`
+	// ValuesFrom references ConfigMaps and Secrets holding YAML values for the control plane
+	// chart. The values are merged in order, so that a later reference overrides an earlier
+	// one, and are overridden by the values kubeflex sets and by spec.vcluster.values.
+	// Changing a referenced object upgrades the chart. Only honored by the ocm and vcluster
+	// control plane types
+	// +optional
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+	// DefaultStorageClass creates a default StorageClass inside the control plane once
+	// the control plane is available
+	// +optional
+	DefaultStorageClass *DefaultStorageClassSpec `json:"defaultStorageClass,omitempty"`
+	// BootstrapToken creates a bootstrap token inside the control plane for joining nodes
+	// or agents, and rotates it before it expires. Only honored by the k8s control plane type
+	// +optional
+	BootstrapToken *BootstrapTokenSpec `json:"bootstrapToken,omitempty"`
+	// ServiceAccountIssuer sets the issuer of the service account tokens and, optionally,
+	// the key used to sign them. Only honored by the k8s control plane type
+	// +optional
+	ServiceAccountIssuer *ServiceAccountIssuerSpec `json:"serviceAccountIssuer,omitempty"`
+	// WatchCache sizes the API server watch caches. Only honored by the k8s control plane type
+	// +optional
+	WatchCache *WatchCacheSpec `json:"watchCache,omitempty"`
+	// OIDC configures the API server to authenticate OpenID Connect ID tokens.
+	// Only honored by the k8s control plane type
+	// +optional
+	OIDC *OIDCSpec `json:"oidc,omitempty"`
+	// EtcdMaintenance configures the compaction and periodic defragmentation of the etcd
+	// datastore of the API server. Only honored by the k8s control plane type
+	// +optional
+	EtcdMaintenance *EtcdMaintenanceSpec `json:"etcdMaintenance,omitempty"`
+	// GoawayChance is the probability, between 0 and 0.02, that the API server sends a GOAWAY
+	// to an HTTP/2 client so that it reconnects, possibly to another replica. It is a decimal
+	// string such as "0.001", passed to --goaway-chance. Only honored by the k8s control plane type
+	// +kubebuilder:validation:Pattern=`^0(\.[0-9]+)?$`
+	// +optional
+	GoawayChance string `json:"goawayChance,omitempty"`
+	// ProfilingEnabled exposes the API server profiling handlers under /debug/pprof. Profiling
+	// is disabled unless set, as required by most security baselines; enable it for debugging.
+	// Only honored by the k8s control plane type
+	// +kubebuilder:default=false
+	// +optional
+	ProfilingEnabled bool `json:"profilingEnabled,omitempty"`
+	// VCluster customizes the vcluster chart installed for the control plane. It is applied
+	// when the chart is installed. Only honored by the vcluster control plane type
+	// +optional
+	VCluster *VClusterSpec `json:"vcluster,omitempty"`
+	// OCM customizes the hub settings of the multicluster-controlplane chart installed for the
+	// control plane. It is applied when the chart is installed. Only honored by the ocm control
+	// plane type
+	// +optional
+	OCM *OCMSpec `json:"ocm,omitempty"`
+	// Host customizes the access given by the kubeconfig of a host control plane to its
+	// namespace. Only honored by the host control plane type
+	// +optional
+	Host *HostSpec `json:"host,omitempty"`
+	// Expose selects how the API server is exposed outside the hosting cluster: through an
+	// ingress, a node port or a load balancer service, or not at all with none. Only honored
+	// by the k8s control plane type, and ignored on OpenShift where a route is used unless none
+	// +kubebuilder:default=ingress
+	// +optional
+	Expose ExposeType `json:"expose,omitempty"`
+	// Ingress customizes the ingress exposing the API server. Ignored on OpenShift where a
+	// route is used
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+	// External references the kubeconfig of an existing cluster adopted as a control plane.
+	// Required by the external control plane type and ignored by the others
+	// +optional
+	External *ExternalSpec `json:"external,omitempty"`
+	// ContextName is the name of the kubeconfig context kflex merges for the control plane,
+	// used verbatim. When empty the context is named after the control plane
+	// +optional
+	ContextName string `json:"contextName,omitempty"`
+	// ProxyURL is the proxy-url kflex sets on the cluster of the merged context, for control
+	// planes reached through a shared gateway. The --proxy-url flag of kflex takes precedence
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+	// TLSServerName is the tls-server-name kflex sets on the cluster of the merged context, the
+	// name the API server certificate is verified against. The --tls-server-name flag of kflex
+	// takes precedence
+	// +optional
+	TLSServerName string `json:"tlsServerName,omitempty"`
 }
 
 // ControlPlaneStatus defines the observed state of ControlPlane
@@ -35,6 +191,35 @@ type ControlPlaneStatus struct {
 	SecretRef *SecretReference `json:"secretRef,omitempty"`
 	// +optional
 	PostCreateHooks map[string]bool `json:"postCreateHooks,omitempty"`
+	// AppliedPostCreateHooks records the spec.postCreateHooks references whose manifests
+	// have been applied into the control plane, keyed by kind/namespace/name
+	// +optional
+	AppliedPostCreateHooks map[string]bool `json:"appliedPostCreateHooks,omitempty"`
+	// BootstrapToken reports the current bootstrap token of the control plane
+	// +optional
+	BootstrapToken *BootstrapTokenStatus `json:"bootstrapToken,omitempty"`
+	// NodePort is the node port assigned to the API server service when the API server
+	// is exposed through a node port
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
+	// LastDefragTime is when the last datastore defragmentation was started
+	// +optional
+	LastDefragTime *metav1.Time `json:"lastDefragTime,omitempty"`
+	// APIServerEndpoint is the URL clients outside the hosting cluster use to reach the
+	// API server, which is the server of the kubeconfig in SecretRef
+	// +optional
+	APIServerEndpoint string `json:"apiServerEndpoint,omitempty"`
+	// VClusterDistro is the distro the vcluster control plane was installed with
+	// +optional
+	VClusterDistro VClusterDistro `json:"vclusterDistro,omitempty"`
+	// ValuesFromHash is the hash of the spec.valuesFrom values the control plane chart was
+	// last installed or upgraded with
+	// +optional
+	ValuesFromHash string `json:"valuesFromHash,omitempty"`
+	// ChartInstallStartTime is when the chart of the control plane started failing to install
+	// or upgrade, or to roll out, since it was last rolled out
+	// +optional
+	ChartInstallStartTime *metav1.Time `json:"chartInstallStartTime,omitempty"`
 }
 
 // ControlPlane is the Schema for the controlplanes API
@@ -43,6 +228,7 @@ type ControlPlaneStatus struct {
 // +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
 // +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
 // +kubebuilder:printcolumn:name="TYPE",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="CHART",type="string",JSONPath=".status.conditions[?(@.type=='ChartReleased')].reason"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:resource:scope=Cluster,shortName={cp,cps}
 type ControlPlane struct {
@@ -70,13 +256,53 @@ const (
 	BackendDBTypeDedicated BackendDBType = "dedicated"
 )
 
-// +kubebuilder:validation:Enum=k8s;ocm;vcluster
+// +kubebuilder:validation:Enum=k8s;ocm;vcluster;external;host
 type ControlPlaneType string
 
 const (
 	ControlPlaneTypeK8S      ControlPlaneType = "k8s"
 	ControlPlaneTypeOCM      ControlPlaneType = "ocm"
 	ControlPlaneTypeVCluster ControlPlaneType = "vcluster"
+	ControlPlaneTypeExternal ControlPlaneType = "external"
+	ControlPlaneTypeHost     ControlPlaneType = "host"
+)
+
+// ControlPlaneTypes lists the supported control plane types, in the order of the enum above
+var ControlPlaneTypes = []ControlPlaneType{
+	ControlPlaneTypeK8S,
+	ControlPlaneTypeOCM,
+	ControlPlaneTypeVCluster,
+	ControlPlaneTypeExternal,
+	ControlPlaneTypeHost,
+}
+
+// +kubebuilder:validation:Enum=ingress;nodeport;loadbalancer;none
+type ExposeType string
+
+const (
+	// ExposeIngress exposes the API server through an ingress with SSL passthrough
+	ExposeIngress ExposeType = "ingress"
+	// ExposeNodePort exposes the API server on a node port of the hosting cluster nodes
+	ExposeNodePort ExposeType = "nodeport"
+	// ExposeLoadBalancer exposes the API server through a load balancer service
+	ExposeLoadBalancer ExposeType = "loadbalancer"
+	// ExposeNone does not expose the API server outside the hosting cluster, for control
+	// planes only reached by clients running in the hosting cluster. The kubeconfig secret
+	// then points at the API server service
+	ExposeNone ExposeType = "none"
+)
+
+// +kubebuilder:validation:Enum=None;Metadata;RequestResponse
+type AuditLevel string
+
+const (
+	// AuditLevelNone disables audit logging
+	AuditLevelNone AuditLevel = "None"
+	// AuditLevelMetadata logs the metadata of all requests
+	AuditLevelMetadata AuditLevel = "Metadata"
+	// AuditLevelRequestResponse logs the metadata of all requests, and request and
+	// response bodies of changes to sensitive resources such as RBAC rules
+	AuditLevelRequestResponse AuditLevel = "RequestResponse"
 )
 
 // We do not use ObjectReference as its use is discouraged in favor of a locally defined type.
@@ -94,6 +320,470 @@ type SecretReference struct {
 	InClusterKey string `json:"inClusterKey"`
 }
 
+// PostCreateHookReference refers to a ConfigMap or Secret in any namespace whose data
+// values are YAML or JSON manifests, possibly holding several documents
+type PostCreateHookReference struct {
+	// `kind` is the kind of the referenced object.
+	// Required
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+	// `namespace` is the namespace of the referenced object.
+	// Required
+	Namespace string `json:"namespace"`
+	// `name` is the name of the referenced object.
+	// Required
+	Name string `json:"name"`
+}
+
+// ValuesReference refers to a key of a ConfigMap or Secret in any namespace whose value
+// holds chart values in YAML
+type ValuesReference struct {
+	// `kind` is the kind of the referenced object.
+	// Required
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+	// `namespace` is the namespace of the referenced object.
+	// Required
+	Namespace string `json:"namespace"`
+	// `name` is the name of the referenced object.
+	// Required
+	Name string `json:"name"`
+	// `key` is the key holding the values in the referenced object. Defaults to values.yaml
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// ImagePullSecretReference refers to an image pull secret in any namespace
+type ImagePullSecretReference struct {
+	// `namespace` is the namespace of the secret.
+	// Required
+	Namespace string `json:"namespace"`
+	// `name` is the name of the secret.
+	// Required
+	Name string `json:"name"`
+}
+
+// SecretKeyReference refers to a key in a Secret in any namespace
+type SecretKeyReference struct {
+	// `namespace` is the namespace of the secret.
+	// Required
+	Namespace string `json:"namespace"`
+	// `name` is the name of the secret.
+	// Required
+	Name string `json:"name"`
+	// `key` is the key holding the data in the secret.
+	// Required
+	Key string `json:"key"`
+}
+
+// TLSSecretReference refers to a secret in any namespace holding a certificate
+// and key under tls.crt and tls.key, and optionally the issuing CA under ca.crt
+type TLSSecretReference struct {
+	// `namespace` is the namespace of the secret.
+	// Required
+	Namespace string `json:"namespace"`
+	// `name` is the name of the secret.
+	// Required
+	Name string `json:"name"`
+}
+
+// ConfigMapKeyReference refers to a key in a ConfigMap in any namespace
+type ConfigMapKeyReference struct {
+	// `namespace` is the namespace of the config map.
+	// Required
+	Namespace string `json:"namespace"`
+	// `name` is the name of the config map.
+	// Required
+	Name string `json:"name"`
+	// `key` is the key holding the data in the config map.
+	// Required
+	Key string `json:"key"`
+}
+
+// EgressSelectorSpec defines how the API server reaches cluster resources
+type EgressSelectorSpec struct {
+	// ConfigMapRef references the EgressSelectorConfiguration passed to --egress-selector-config-file.
+	// Required
+	ConfigMapRef ConfigMapKeyReference `json:"configMapRef"`
+	// Konnectivity deploys a konnectivity server sidecar next to the API server, listening on
+	// the unix socket /etc/kubernetes/konnectivity-server/konnectivity-server.socket, and a
+	// konnectivity agent in the control plane namespace.
+	// +optional
+	Konnectivity bool `json:"konnectivity,omitempty"`
+}
+
+// AuthenticationWebhookSpec configures the API server token authentication webhook
+type AuthenticationWebhookSpec struct {
+	// ConfigSecretRef references the kubeconfig formatted webhook configuration
+	// passed to --authentication-token-webhook-config-file.
+	// Required
+	ConfigSecretRef SecretKeyReference `json:"configSecretRef"`
+	// CacheTTL is the duration to cache responses from the webhook token authenticator
+	// +optional
+	CacheTTL *metav1.Duration `json:"cacheTTL,omitempty"`
+	// Version is the API version of the TokenReview objects sent to the webhook
+	// +kubebuilder:validation:Enum=v1;v1beta1
+	// +optional
+	Version string `json:"version,omitempty"`
+}
+
+// ServiceAccountIssuerSpec configures the issuer and signing key of service account tokens
+type ServiceAccountIssuerSpec struct {
+	// URL is the https URL passed to --service-account-issuer, used as the iss claim of
+	// service account tokens and to serve the OIDC discovery document.
+	// Required
+	URL string `json:"url"`
+	// SigningKeySecretRef references the PEM encoded RSA or ECDSA private key used to sign
+	// service account tokens. When not set, the key generated by kubeflex is used
+	// +optional
+	SigningKeySecretRef *SecretKeyReference `json:"signingKeySecretRef,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=RS256;RS384;RS512;ES256;ES384;ES512;PS256;PS384;PS512
+type OIDCSigningAlgorithm string
+
+// OIDCSpec configures the API server OpenID Connect token authenticator
+type OIDCSpec struct {
+	// IssuerURL is the https URL of the OpenID provider, passed to --oidc-issuer-url.
+	// Required
+	IssuerURL string `json:"issuerURL"`
+	// ClientID is the client id that ID tokens must be issued for, passed to --oidc-client-id.
+	// Required
+	ClientID string `json:"clientID"`
+	// UsernameClaim is the ID token claim used as the user name
+	// +kubebuilder:default=sub
+	// +optional
+	UsernameClaim string `json:"usernameClaim,omitempty"`
+	// UsernamePrefix is prepended to user names to avoid clashes with other authenticators
+	// +optional
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+	// GroupsClaim is the ID token claim used as the user groups
+	// +optional
+	GroupsClaim string `json:"groupsClaim,omitempty"`
+	// GroupsPrefix is prepended to group names to avoid clashes with other authenticators
+	// +optional
+	GroupsPrefix string `json:"groupsPrefix,omitempty"`
+	// RequiredClaims are claims that must be present in the ID token with a matching value
+	// +optional
+	RequiredClaims map[string]string `json:"requiredClaims,omitempty"`
+	// SigningAlgorithms are the accepted ID token signing algorithms. Defaults to RS256
+	// +optional
+	SigningAlgorithms []OIDCSigningAlgorithm `json:"signingAlgorithms,omitempty"`
+	// CASecretRef references the PEM encoded CA certificates used to verify the OpenID
+	// provider. When not set, the host root CAs are used
+	// +optional
+	CASecretRef *SecretKeyReference `json:"caSecretRef,omitempty"`
+}
+
+// EtcdMaintenanceSpec configures the maintenance of the etcd datastore
+type EtcdMaintenanceSpec struct {
+	// CompactionInterval is passed to --etcd-compaction-interval. Zero disables the
+	// compaction requests of the API server
+	// +optional
+	CompactionInterval *metav1.Duration `json:"compactionInterval,omitempty"`
+	// DefragInterval is the interval between defragmentations of the datastore, run by
+	// kubeflex as a job in the control plane namespace. Defragmentation is disabled when
+	// not set
+	// +optional
+	DefragInterval *metav1.Duration `json:"defragInterval,omitempty"`
+}
+
+// WatchCacheSpec configures the API server watch cache sizes
+type WatchCacheSpec struct {
+	// DefaultSize is passed to --default-watch-cache-size. Zero disables the watch cache
+	// for the resources without an explicit size
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DefaultSize *int32 `json:"defaultSize,omitempty"`
+	// Sizes sets the watch cache size of individual resources, passed to --watch-cache-sizes
+	// +optional
+	Sizes []WatchCacheSize `json:"sizes,omitempty"`
+}
+
+// WatchCacheSize is the watch cache size of a resource
+type WatchCacheSize struct {
+	// Resource is the lowercase plural name of the resource, followed by the API group
+	// for resources outside the core group, e.g. pods or deployments.apps.
+	// Required
+	Resource string `json:"resource"`
+	// Size is the number of objects kept in the watch cache. Zero disables it.
+	// Required
+	// +kubebuilder:validation:Minimum=0
+	Size int32 `json:"size"`
+}
+
+// AuditSpec configures the API server audit policy. Audit events are written to the
+// API server log
+type AuditSpec struct {
+	// Level selects the generated audit policy. Ignored when PolicyConfigMapRef is set
+	// +kubebuilder:default=Metadata
+	// +optional
+	Level AuditLevel `json:"level,omitempty"`
+	// PolicyConfigMapRef references a custom audit policy passed to --audit-policy-file
+	// +optional
+	PolicyConfigMapRef *ConfigMapKeyReference `json:"policyConfigMapRef,omitempty"`
+}
+
+// PodDisruptionBudgetSpec configures the pod disruption budget of the API server
+type PodDisruptionBudgetSpec struct {
+	// MinAvailable is the number of API server replicas that must stay available during
+	// voluntary disruptions. Defaults to one less than the number of replicas, and at least one
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinAvailable *int32 `json:"minAvailable,omitempty"`
+}
+
+// MetricsRBACSpec configures the metrics reader created inside the control plane
+type MetricsRBACSpec struct {
+	// ServiceAccountName is the name of the metrics reader service account, created in
+	// the kube-system namespace of the control plane
+	// +kubebuilder:default=kflex-metrics-reader
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=k3s;k0s;k8s;eks
+type VClusterDistro string
+
+const (
+	VClusterDistroK3s VClusterDistro = "k3s"
+	VClusterDistroK0s VClusterDistro = "k0s"
+	VClusterDistroK8s VClusterDistro = "k8s"
+	VClusterDistroEKS VClusterDistro = "eks"
+)
+
+// VClusterSpec customizes the vcluster chart. Values are applied on top of the chart defaults
+// and of the values kubeflex sets, so a key set here takes precedence over both; overriding
+// the syncer extra args kubeflex sets for the API server certificate and endpoint breaks the
+// generated kubeconfig.
+type VClusterSpec struct {
+	// Distro is the Kubernetes distribution run by the vcluster, which selects the chart.
+	// Defaults to k3s. The distro cannot be changed once the vcluster is installed
+	// +optional
+	Distro VClusterDistro `json:"distro,omitempty"`
+	// ChartVersion is the version of the vcluster chart. Defaults to the version kubeflex is tested with.
+	// Changing it upgrades or downgrades the installed release
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// NodeSelector constrains the nodes of the hosting cluster the vcluster pods run on
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Values are passed to the chart as helm --set values, in the key=value form
+	// +optional
+	Values []string `json:"values,omitempty"`
+	// ServiceName is the name of the vcluster API server service rendered by the chart, which
+	// backs the ingress. Set it when the chart values customize the service name. Defaults to vcluster
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+	// ServicePort is the port of the vcluster API server service that backs the ingress.
+	// Defaults to the port of the service named https, or to its only port
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ServicePort int32 `json:"servicePort,omitempty"`
+	// Persistence configures the volume holding the vcluster data. Defaults to the chart
+	// persistence settings
+	// +optional
+	Persistence *VClusterPersistenceSpec `json:"persistence,omitempty"`
+}
+
+// OCMSpec customizes the hub of an ocm control plane
+type OCMSpec struct {
+	// FeatureGates enables or disables features of the hub registration and work controllers,
+	// such as ManagedClusterAutoApproval or DefaultClusterSet. Defaults to the chart features
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// AutoApprovalUsers are the users whose managed cluster registration requests are accepted
+	// without approval, such as the user of the bootstrap kubeconfig of the klusterlet agents.
+	// Requires the ManagedClusterAutoApproval feature gate
+	// +optional
+	AutoApprovalUsers []string `json:"autoApprovalUsers,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=namespace-admin;view
+type HostAccess string
+
+const (
+	// HostAccessNamespaceAdmin grants read and write access to most resources of the namespace,
+	// including roles and role bindings, through the admin cluster role
+	HostAccessNamespaceAdmin HostAccess = "namespace-admin"
+	// HostAccessView grants read only access to most resources of the namespace, excluding
+	// secrets, through the view cluster role
+	HostAccessView HostAccess = "view"
+)
+
+// HostSpec customizes a host control plane
+type HostSpec struct {
+	// Access selects the access of the service account token of the kubeconfig to the
+	// namespace of the control plane. Defaults to namespace-admin
+	// +kubebuilder:default=namespace-admin
+	// +optional
+	Access HostAccess `json:"access,omitempty"`
+}
+
+// VClusterPersistenceSpec configures the persistent volume of the vcluster data. The volume is
+// created with the control plane and its settings cannot be changed afterwards
+type VClusterPersistenceSpec struct {
+	// Enabled selects a persistent volume for the data. When false the data is kept in an
+	// emptyDir volume and is lost when the vcluster pod restarts. Defaults to true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// StorageClass is the storage class of the volume. Defaults to the default storage class
+	// of the hosting cluster
+	// +optional
+	StorageClass string `json:"storageClass,omitempty"`
+	// Size is the requested size of the volume. Defaults to the chart default
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+}
+
+// ExternalSpec describes an existing cluster tracked by kubeflex without provisioning it
+type ExternalSpec struct {
+	// KubeconfigSecretRef references the user supplied secret holding the kubeconfig of
+	// the cluster. The current context of the kubeconfig is used.
+	// Required
+	KubeconfigSecretRef SecretKeyReference `json:"kubeconfigSecretRef"`
+}
+
+// ChartSpec references the control plane chart in an OCI registry
+type ChartSpec struct {
+	// URL is the reference of the chart in the OCI registry, optionally with a tag, such as
+	// oci://registry.example.com/charts/vcluster:0.16.4. Without a tag the vcluster chart
+	// version is used as tag for the vcluster type, and the latest tag for the ocm type.
+	// Required
+	// +kubebuilder:validation:Pattern=`^oci://[^/]+/.+`
+	URL string `json:"url"`
+	// PullSecretRef references a kubernetes.io/dockerconfigjson secret holding the
+	// credentials to log in to the registry
+	// +optional
+	PullSecretRef *ImagePullSecretReference `json:"pullSecretRef,omitempty"`
+}
+
+// ChartVerificationSpec configures the verification of the control plane chart provenance
+type ChartVerificationSpec struct {
+	// KeyringSecretRef references the PGP public keyring used to verify the signature
+	// of the chart provenance file.
+	// Required
+	KeyringSecretRef SecretKeyReference `json:"keyringSecretRef"`
+}
+
+// DefaultStorageClassSpec describes the default StorageClass created inside the control plane.
+// The StorageClass is created once and is not updated afterwards
+type DefaultStorageClassSpec struct {
+	// Name is the name of the StorageClass
+	// +kubebuilder:default=standard
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Provisioner is the volume provisioner of the StorageClass. Defaults to the provisioner
+	// of the default StorageClass of the hosting cluster
+	// +optional
+	Provisioner string `json:"provisioner,omitempty"`
+	// Parameters holds the provisioner parameters
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// ReclaimPolicy is the reclaim policy of the volumes provisioned for the StorageClass
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +optional
+	ReclaimPolicy *corev1.PersistentVolumeReclaimPolicy `json:"reclaimPolicy,omitempty"`
+	// VolumeBindingMode controls when volumes are provisioned and bound
+	// +kubebuilder:validation:Enum=Immediate;WaitForFirstConsumer
+	// +optional
+	VolumeBindingMode *storagev1.VolumeBindingMode `json:"volumeBindingMode,omitempty"`
+	// AllowVolumeExpansion allows the volumes of the StorageClass to be expanded
+	// +optional
+	AllowVolumeExpansion *bool `json:"allowVolumeExpansion,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=authentication;signing
+type BootstrapTokenUsage string
+
+const (
+	BootstrapTokenUsageAuthentication BootstrapTokenUsage = "authentication"
+	BootstrapTokenUsageSigning        BootstrapTokenUsage = "signing"
+)
+
+// BootstrapTokenSpec configures the bootstrap token created inside the control plane
+type BootstrapTokenSpec struct {
+	// TTL is how long a bootstrap token is valid after it is created. A new token is
+	// created when less than a quarter of the TTL is left
+	// +kubebuilder:default="24h"
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// Usages lists what the bootstrap token can be used for
+	// +kubebuilder:default={authentication,signing}
+	// +optional
+	Usages []BootstrapTokenUsage `json:"usages,omitempty"`
+	// ExtraGroups are additional groups the bootstrap token authenticates as. Group
+	// names must start with system:bootstrappers:
+	// +optional
+	ExtraGroups []string `json:"extraGroups,omitempty"`
+}
+
+// BootstrapTokenStatus describes the current bootstrap token of the control plane
+type BootstrapTokenStatus struct {
+	// TokenID is the id of the bootstrap token, stored in the bootstrap-token-<id>
+	// secret in the kube-system namespace of the control plane
+	TokenID string `json:"tokenID"`
+	// Expiration is when the bootstrap token expires
+	Expiration metav1.Time `json:"expiration"`
+}
+
+// IngressSpec customizes the ingress exposing the API server
+type IngressSpec struct {
+	// Annotations are added to the ingress, and take precedence over the annotations set by
+	// kubeflex. Annotations removed from this list are removed from the ingress
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Hostname replaces the host generated from the control plane name and domain. It is
+	// included in the API server certificate, which is generated once, so for the k8s control
+	// plane type it must be set when the control plane is created.
+	// For the ocm and vcluster types it is only applied when the chart is installed
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+	// TLS configures TLS termination at the ingress. When not set, TLS is passed through to
+	// the API server
+	// +optional
+	TLS *IngressTLSSpec `json:"tls,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+type CertManagerIssuerKind string
+
+const (
+	CertManagerIssuerKindIssuer        CertManagerIssuerKind = "Issuer"
+	CertManagerIssuerKindClusterIssuer CertManagerIssuerKind = "ClusterIssuer"
+)
+
+// IngressTLSSpec configures the TLS of the ingress exposing the API server
+type IngressTLSSpec struct {
+	// Passthrough passes TLS through to the API server instead of terminating it at the
+	// ingress, so that clients can authenticate with client certificates. SecretName and
+	// Issuer are ignored when set
+	// +optional
+	Passthrough bool `json:"passthrough,omitempty"`
+	// SecretName is the secret holding the certificate the ingress terminates TLS with.
+	// Required unless Passthrough is set
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// Issuer is the name of the cert-manager issuer that issues the certificate into SecretName
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+	// IssuerKind is the kind of the cert-manager issuer. Defaults to Issuer
+	// +optional
+	IssuerKind CertManagerIssuerKind `json:"issuerKind,omitempty"`
+}
+
+// ExternalCertsSpec references externally issued certificates for the control plane
+type ExternalCertsSpec struct {
+	// APIServerSecretRef references the API server serving certificate and key.
+	// Required
+	APIServerSecretRef TLSSecretReference `json:"apiServerSecretRef"`
+	// AdminSecretRef references the admin client certificate and key used to
+	// build the admin kubeconfig. The issuing CA is trusted by the API server.
+	// Required
+	AdminSecretRef TLSSecretReference `json:"adminSecretRef"`
+}
+
 func init() {
 	SchemeBuilder.Register(&ControlPlane{}, &ControlPlaneList{})
 }
@@ -22,9 +22,150 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuditSpec) DeepCopyInto(out *AuditSpec) {
+	*out = *in
+	if in.PolicyConfigMapRef != nil {
+		in, out := &in.PolicyConfigMapRef, &out.PolicyConfigMapRef
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuditSpec.
+func (in *AuditSpec) DeepCopy() *AuditSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AuditSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthenticationWebhookSpec) DeepCopyInto(out *AuthenticationWebhookSpec) {
+	*out = *in
+	out.ConfigSecretRef = in.ConfigSecretRef
+	if in.CacheTTL != nil {
+		in, out := &in.CacheTTL, &out.CacheTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationWebhookSpec.
+func (in *AuthenticationWebhookSpec) DeepCopy() *AuthenticationWebhookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthenticationWebhookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapTokenSpec) DeepCopyInto(out *BootstrapTokenSpec) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Usages != nil {
+		in, out := &in.Usages, &out.Usages
+		*out = make([]BootstrapTokenUsage, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraGroups != nil {
+		in, out := &in.ExtraGroups, &out.ExtraGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapTokenSpec.
+func (in *BootstrapTokenSpec) DeepCopy() *BootstrapTokenSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapTokenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapTokenStatus) DeepCopyInto(out *BootstrapTokenStatus) {
+	*out = *in
+	in.Expiration.DeepCopyInto(&out.Expiration)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapTokenStatus.
+func (in *BootstrapTokenStatus) DeepCopy() *BootstrapTokenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapTokenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartSpec) DeepCopyInto(out *ChartSpec) {
+	*out = *in
+	if in.PullSecretRef != nil {
+		in, out := &in.PullSecretRef, &out.PullSecretRef
+		*out = new(ImagePullSecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartSpec.
+func (in *ChartSpec) DeepCopy() *ChartSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartVerificationSpec) DeepCopyInto(out *ChartVerificationSpec) {
+	*out = *in
+	out.KeyringSecretRef = in.KeyringSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartVerificationSpec.
+func (in *ChartVerificationSpec) DeepCopy() *ChartVerificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartVerificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ControlPlane) DeepCopyInto(out *ControlPlane) {
 	*out = *in
@@ -109,6 +250,143 @@ func (in *ControlPlaneSpec) DeepCopyInto(out *ControlPlaneSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.PostCreateHooks != nil {
+		in, out := &in.PostCreateHooks, &out.PostCreateHooks
+		*out = make([]PostCreateHookReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.EgressSelector != nil {
+		in, out := &in.EgressSelector, &out.EgressSelector
+		*out = new(EgressSelectorSpec)
+		**out = **in
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]ImagePullSecretReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.AuthenticationWebhook != nil {
+		in, out := &in.AuthenticationWebhook, &out.AuthenticationWebhook
+		*out = new(AuthenticationWebhookSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalCerts != nil {
+		in, out := &in.ExternalCerts, &out.ExternalCerts
+		*out = new(ExternalCertsSpec)
+		**out = **in
+	}
+	if in.ShutdownDelay != nil {
+		in, out := &in.ShutdownDelay, &out.ShutdownDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Audit != nil {
+		in, out := &in.Audit, &out.Audit
+		*out = new(AuditSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(PodDisruptionBudgetSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MetricsRBAC != nil {
+		in, out := &in.MetricsRBAC, &out.MetricsRBAC
+		*out = new(MetricsRBACSpec)
+		**out = **in
+	}
+	if in.ChartVerification != nil {
+		in, out := &in.ChartVerification, &out.ChartVerification
+		*out = new(ChartVerificationSpec)
+		**out = **in
+	}
+	if in.ChartInstallTimeout != nil {
+		in, out := &in.ChartInstallTimeout, &out.ChartInstallTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Chart != nil {
+		in, out := &in.Chart, &out.Chart
+		*out = new(ChartSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultStorageClass != nil {
+		in, out := &in.DefaultStorageClass, &out.DefaultStorageClass
+		*out = new(DefaultStorageClassSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BootstrapToken != nil {
+		in, out := &in.BootstrapToken, &out.BootstrapToken
+		*out = new(BootstrapTokenSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccountIssuer != nil {
+		in, out := &in.ServiceAccountIssuer, &out.ServiceAccountIssuer
+		*out = new(ServiceAccountIssuerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WatchCache != nil {
+		in, out := &in.WatchCache, &out.WatchCache
+		*out = new(WatchCacheSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(OIDCSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EtcdMaintenance != nil {
+		in, out := &in.EtcdMaintenance, &out.EtcdMaintenance
+		*out = new(EtcdMaintenanceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VCluster != nil {
+		in, out := &in.VCluster, &out.VCluster
+		*out = new(VClusterSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OCM != nil {
+		in, out := &in.OCM, &out.OCM
+		*out = new(OCMSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Host != nil {
+		in, out := &in.Host, &out.Host
+		*out = new(HostSpec)
+		**out = **in
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.External != nil {
+		in, out := &in.External, &out.External
+		*out = new(ExternalSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneSpec.
@@ -143,6 +421,26 @@ func (in *ControlPlaneStatus) DeepCopyInto(out *ControlPlaneStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.AppliedPostCreateHooks != nil {
+		in, out := &in.AppliedPostCreateHooks, &out.AppliedPostCreateHooks
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.BootstrapToken != nil {
+		in, out := &in.BootstrapToken, &out.BootstrapToken
+		*out = new(BootstrapTokenStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastDefragTime != nil {
+		in, out := &in.LastDefragTime, &out.LastDefragTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ChartInstallStartTime != nil {
+		in, out := &in.ChartInstallStartTime, &out.ChartInstallStartTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneStatus.
@@ -155,6 +453,189 @@ func (in *ControlPlaneStatus) DeepCopy() *ControlPlaneStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultStorageClassSpec) DeepCopyInto(out *DefaultStorageClassSpec) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ReclaimPolicy != nil {
+		in, out := &in.ReclaimPolicy, &out.ReclaimPolicy
+		*out = new(v1.PersistentVolumeReclaimPolicy)
+		**out = **in
+	}
+	if in.VolumeBindingMode != nil {
+		in, out := &in.VolumeBindingMode, &out.VolumeBindingMode
+		*out = new(storagev1.VolumeBindingMode)
+		**out = **in
+	}
+	if in.AllowVolumeExpansion != nil {
+		in, out := &in.AllowVolumeExpansion, &out.AllowVolumeExpansion
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultStorageClassSpec.
+func (in *DefaultStorageClassSpec) DeepCopy() *DefaultStorageClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultStorageClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressSelectorSpec) DeepCopyInto(out *EgressSelectorSpec) {
+	*out = *in
+	out.ConfigMapRef = in.ConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressSelectorSpec.
+func (in *EgressSelectorSpec) DeepCopy() *EgressSelectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressSelectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdMaintenanceSpec) DeepCopyInto(out *EtcdMaintenanceSpec) {
+	*out = *in
+	if in.CompactionInterval != nil {
+		in, out := &in.CompactionInterval, &out.CompactionInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DefragInterval != nil {
+		in, out := &in.DefragInterval, &out.DefragInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdMaintenanceSpec.
+func (in *EtcdMaintenanceSpec) DeepCopy() *EtcdMaintenanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdMaintenanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalCertsSpec) DeepCopyInto(out *ExternalCertsSpec) {
+	*out = *in
+	out.APIServerSecretRef = in.APIServerSecretRef
+	out.AdminSecretRef = in.AdminSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalCertsSpec.
+func (in *ExternalCertsSpec) DeepCopy() *ExternalCertsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalCertsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalSpec) DeepCopyInto(out *ExternalSpec) {
+	*out = *in
+	out.KubeconfigSecretRef = in.KubeconfigSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalSpec.
+func (in *ExternalSpec) DeepCopy() *ExternalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostSpec) DeepCopyInto(out *HostSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostSpec.
+func (in *HostSpec) DeepCopy() *HostSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HostSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePullSecretReference) DeepCopyInto(out *ImagePullSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePullSecretReference.
+func (in *ImagePullSecretReference) DeepCopy() *ImagePullSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePullSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(IngressTLSSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpec.
+func (in *IngressSpec) DeepCopy() *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTLSSpec) DeepCopyInto(out *IngressTLSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTLSSpec.
+func (in *IngressTLSSpec) DeepCopy() *IngressTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Manifest) DeepCopyInto(out *Manifest) {
 	*out = *in
@@ -171,6 +652,100 @@ func (in *Manifest) DeepCopy() *Manifest {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsRBACSpec) DeepCopyInto(out *MetricsRBACSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsRBACSpec.
+func (in *MetricsRBACSpec) DeepCopy() *MetricsRBACSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsRBACSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCMSpec) DeepCopyInto(out *OCMSpec) {
+	*out = *in
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AutoApprovalUsers != nil {
+		in, out := &in.AutoApprovalUsers, &out.AutoApprovalUsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCMSpec.
+func (in *OCMSpec) DeepCopy() *OCMSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OCMSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCSpec) DeepCopyInto(out *OIDCSpec) {
+	*out = *in
+	if in.RequiredClaims != nil {
+		in, out := &in.RequiredClaims, &out.RequiredClaims
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SigningAlgorithms != nil {
+		in, out := &in.SigningAlgorithms, &out.SigningAlgorithms
+		*out = make([]OIDCSigningAlgorithm, len(*in))
+		copy(*out, *in)
+	}
+	if in.CASecretRef != nil {
+		in, out := &in.CASecretRef, &out.CASecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCSpec.
+func (in *OIDCSpec) DeepCopy() *OIDCSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostCreateHook) DeepCopyInto(out *PostCreateHook) {
 	*out = *in
@@ -230,6 +805,21 @@ func (in *PostCreateHookList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostCreateHookReference) DeepCopyInto(out *PostCreateHookReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostCreateHookReference.
+func (in *PostCreateHookReference) DeepCopy() *PostCreateHookReference {
+	if in == nil {
+		return nil
+	}
+	out := new(PostCreateHookReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostCreateHookSpec) DeepCopyInto(out *PostCreateHookSpec) {
 	*out = *in
@@ -279,6 +869,21 @@ func (in *PostCreateHookStatus) DeepCopy() *PostCreateHookStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -293,3 +898,150 @@ func (in *SecretReference) DeepCopy() *SecretReference {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountIssuerSpec) DeepCopyInto(out *ServiceAccountIssuerSpec) {
+	*out = *in
+	if in.SigningKeySecretRef != nil {
+		in, out := &in.SigningKeySecretRef, &out.SigningKeySecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountIssuerSpec.
+func (in *ServiceAccountIssuerSpec) DeepCopy() *ServiceAccountIssuerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountIssuerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSecretReference) DeepCopyInto(out *TLSSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSecretReference.
+func (in *TLSSecretReference) DeepCopy() *TLSSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VClusterPersistenceSpec) DeepCopyInto(out *VClusterPersistenceSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VClusterPersistenceSpec.
+func (in *VClusterPersistenceSpec) DeepCopy() *VClusterPersistenceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VClusterPersistenceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VClusterSpec) DeepCopyInto(out *VClusterSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Persistence != nil {
+		in, out := &in.Persistence, &out.Persistence
+		*out = new(VClusterPersistenceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VClusterSpec.
+func (in *VClusterSpec) DeepCopy() *VClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesReference) DeepCopyInto(out *ValuesReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesReference.
+func (in *ValuesReference) DeepCopy() *ValuesReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatchCacheSize) DeepCopyInto(out *WatchCacheSize) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatchCacheSize.
+func (in *WatchCacheSize) DeepCopy() *WatchCacheSize {
+	if in == nil {
+		return nil
+	}
+	out := new(WatchCacheSize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatchCacheSpec) DeepCopyInto(out *WatchCacheSpec) {
+	*out = *in
+	if in.DefaultSize != nil {
+		in, out := &in.DefaultSize, &out.DefaultSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Sizes != nil {
+		in, out := &in.Sizes, &out.Sizes
+		*out = make([]WatchCacheSize, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatchCacheSpec.
+func (in *WatchCacheSpec) DeepCopy() *WatchCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WatchCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
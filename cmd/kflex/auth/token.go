@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/cmd/kflex/common"
+	"github.com/kubestellar/kubeflex/pkg/kubeconfig"
+)
+
+type CPAuth struct {
+	common.CP
+	// HostingContext is the kubeconfig context of the hosting cluster. It defaults to the
+	// initial context recorded by kflex.
+	HostingContext string
+}
+
+// Token prints to stdout an ExecCredential with the credentials of the control plane, read
+// from its kubeconfig secret in the hosting cluster. It is run by the exec credential of
+// the contexts merged with --exec-credential.
+func (c *CPAuth) Token() {
+	restConfig, err := c.hostingRestConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building the hosting cluster client config: %s\n", err)
+		os.Exit(1)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		fmt.Fprintf(os.Stderr, "Error adding to schema: %v\n", err)
+		os.Exit(1)
+	}
+	kfcClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating client: %v\n", err)
+		os.Exit(1)
+	}
+	cp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: v1.ObjectMeta{
+			Name: c.Name,
+		},
+	}
+	if err := kfcClient.Get(context.TODO(), client.ObjectKeyFromObject(cp), cp, &client.GetOptions{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting control plane %s: %s\n", c.Name, err)
+		os.Exit(1)
+	}
+
+	var opts []kubeconfig.MergeOption
+	if cp.Spec.Type == tenancyv1alpha1.ControlPlaneTypeExternal {
+		if cp.Status.SecretRef == nil {
+			fmt.Fprintf(os.Stderr, "Kubeconfig of external control plane %s is not validated yet\n", c.Name)
+			os.Exit(1)
+		}
+		opts = append(opts, kubeconfig.WithKubeconfigSecretRef(cp.Status.SecretRef))
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating clientset: %v\n", err)
+		os.Exit(1)
+	}
+	cred, err := kubeconfig.ExecCredentialForControlPlane(c.Ctx, *clientset, c.Name, string(cp.Spec.Type), opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting credentials of control plane %s: %s\n", c.Name, err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(cred); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing credentials: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// hostingRestConfig returns the client config of the hosting cluster context. The current
+// context is not used: it is usually the control plane context running this command, and
+// using it would run the exec credential again.
+func (c *CPAuth) hostingRestConfig() (*rest.Config, error) {
+	path := c.Kubeconfig
+	if path == "" {
+		path = kubeconfig.DefaultKubeconfigPath()
+	}
+	kconf, err := kubeconfig.LoadKubeconfigFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	hostingContext := c.HostingContext
+	if hostingContext == "" {
+		hostingContext = kubeconfig.GetInitialContext(kconf)
+	}
+	if hostingContext == "" {
+		return nil, fmt.Errorf("no hosting cluster context is recorded, set it with --context")
+	}
+	return clientcmd.NewNonInteractiveClientConfig(*kconf, hostingContext, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kubestellar/kubeflex/pkg/kubeconfig"
+)
+
+// TLSFlags select how the merged control plane kubeconfig reaches the API server and verifies
+// its certificate
+type TLSFlags struct {
+	// CertificateAuthority is the path of a CA bundle replacing the one of the control plane kubeconfig
+	CertificateAuthority string
+	// InsecureSkipTLSVerify skips the verification of the API server certificate, for dev clusters only
+	InsecureSkipTLSVerify bool
+	// ProxyURL is the proxy the API server is reached through, such as a shared gateway
+	ProxyURL string
+	// TLSServerName is the name the API server certificate is verified against
+	TLSServerName string
+}
+
+// MergeOptions returns the merge options for the flags. It warns on stderr when the
+// verification of the API server certificate is skipped.
+func (f TLSFlags) MergeOptions() ([]kubeconfig.MergeOption, error) {
+	var opts []kubeconfig.MergeOption
+	if f.CertificateAuthority != "" {
+		if f.InsecureSkipTLSVerify {
+			return nil, fmt.Errorf("--certificate-authority cannot be used with --insecure-skip-tls-verify")
+		}
+		caData, err := os.ReadFile(f.CertificateAuthority)
+		if err != nil {
+			return nil, fmt.Errorf("error reading certificate authority: %w", err)
+		}
+		opts = append(opts, kubeconfig.WithCertificateAuthorityData(caData))
+	}
+	if f.InsecureSkipTLSVerify {
+		fmt.Fprintf(os.Stderr, "Warning: the API server certificate will not be verified, the connection is insecure. Use it only for development clusters.\n")
+		opts = append(opts, kubeconfig.WithInsecureSkipTLSVerify(true))
+	}
+	if f.ProxyURL != "" {
+		opts = append(opts, kubeconfig.WithProxyURL(f.ProxyURL))
+	}
+	if f.TLSServerName != "" {
+		opts = append(opts, kubeconfig.WithTLSServerName(f.TLSServerName))
+	}
+	return opts, nil
+}
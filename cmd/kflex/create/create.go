@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -33,14 +34,42 @@ import (
 	"github.com/kubestellar/kubeflex/pkg/util"
 )
 
+// readyTimeout bounds the wait for the API server of the new control plane to answer
+const readyTimeout = 5 * time.Minute
+
 type CPCreate struct {
 	common.CP
+	TLS common.TLSFlags
+	// InternalContext also merges a context for the in-cluster endpoint of the control plane
+	InternalContext bool
+	// ExecCredential replaces the merged credentials with an exec credential running kflex auth token
+	ExecCredential bool
+	// Verify checks that the merged context reaches the API server before writing the kubeconfig
+	Verify bool
 }
 
-// Create a ne control plane
-func (c *CPCreate) Create(controlPlaneType, backendType, hook string) {
+// Create a ne control plane. With noSwitch the context of the new control plane is added
+// to the kubeconfig but the current context is restored to the one in use before create.
+func (c *CPCreate) Create(controlPlaneType, backendType, hook string, noSwitch bool) {
 	done := make(chan bool)
 	var wg sync.WaitGroup
+	if err := util.ValidateControlPlaneName(c.Name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating instance: %v\n", err)
+		os.Exit(1)
+	}
+	if err := util.ValidateControlPlaneType(controlPlaneType); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating instance: %v\n", err)
+		os.Exit(1)
+	}
+	var originalContext string
+	if noSwitch {
+		kconf, err := kubeconfig.LoadKubeconfig(c.Ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading kubeconfig: %v\n", err)
+			os.Exit(1)
+		}
+		originalContext = kconf.CurrentContext
+	}
 	cx := cont.CPCtx{}
 	cx.Context()
 
@@ -58,9 +87,17 @@ func (c *CPCreate) Create(controlPlaneType, backendType, hook string) {
 	clientset := *(kfclient.GetClientSet(c.Kubeconfig))
 
 	util.PrintStatus("Waiting for API server to become ready...", done, &wg)
-	kubeconfig.WatchForSecretCreation(clientset, c.Name, util.GetKubeconfSecretNameByControlPlaneType(controlPlaneType))
+	if err := kubeconfig.WatchForSecretCreation(c.Ctx, clientset, c.Name,
+		util.GetKubeconfSecretNameByControlPlaneType(controlPlaneType),
+		util.GetKubeconfSecretKeyNameByControlPlaneType(controlPlaneType)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error waiting for kubeconfig secret: %v\n", err)
+		os.Exit(1)
+	}
 
-	if controlPlaneType == string(tenancyv1alpha1.ControlPlaneTypeVCluster) {
+	switch controlPlaneType {
+	case string(tenancyv1alpha1.ControlPlaneTypeHost):
+		// a host control plane runs no API server of its own
+	case string(tenancyv1alpha1.ControlPlaneTypeVCluster):
 		if err := util.WaitForStatefulSetReady(clientset,
 			util.GetAPIServerDeploymentNameByControlPlaneType(controlPlaneType),
 			util.GenerateNamespaceFromControlPlaneName(cp.Name)); err != nil {
@@ -68,7 +105,7 @@ func (c *CPCreate) Create(controlPlaneType, backendType, hook string) {
 			fmt.Fprintf(os.Stderr, "Error waiting for stateful set to become ready: %v\n", err)
 			os.Exit(1)
 		}
-	} else {
+	default:
 		if err := util.WaitForDeploymentReady(clientset,
 			util.GetAPIServerDeploymentNameByControlPlaneType(controlPlaneType),
 			util.GenerateNamespaceFromControlPlaneName(cp.Name)); err != nil {
@@ -77,14 +114,38 @@ func (c *CPCreate) Create(controlPlaneType, backendType, hook string) {
 			os.Exit(1)
 		}
 	}
+	endpoint, err := kubeconfig.WaitForControlPlaneReady(c.Ctx, clientset, c.Name, controlPlaneType, readyTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error waiting for API server to become ready: %v\n", err)
+		os.Exit(1)
+	}
 	done <- true
 
-	if err := kubeconfig.LoadAndMerge(c.Ctx, clientset, c.Name, controlPlaneType); err != nil {
+	tlsOpts, err := c.TLS.MergeOptions()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading and merging kubeconfig: %v\n", err)
 		os.Exit(1)
 	}
+	warnConflicts := kubeconfig.WithAuditSink(func(entry kubeconfig.AuditEntry) {
+		for _, conflict := range entry.Conflicts {
+			fmt.Fprintf(os.Stderr, "Warning: replaced existing kubeconfig %s %s\n", conflict.Kind, conflict.Name)
+		}
+	})
+	opts := append(tlsOpts, kubeconfig.WithSetCurrentContext(!noSwitch), kubeconfig.WithInternalContext(c.InternalContext), kubeconfig.WithExecCredential(c.ExecCredential), kubeconfig.WithVerify(c.Verify), warnConflicts)
+	if err := kubeconfig.LoadAndMerge(c.Ctx, clientset, c.Name, controlPlaneType, opts...); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading and merging kubeconfig: %v\n", err)
+		os.Exit(1)
+	}
+
+	if noSwitch && originalContext != "" {
+		if err := kubeconfig.SwitchToHostingClusterContext(c.Ctx, originalContext); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring context %s: %v\n", originalContext, err)
+			os.Exit(1)
+		}
+	}
 
 	wg.Wait()
+	fmt.Printf("Control plane %s ready at %s\n", c.Name, endpoint)
 }
 
 func (c *CPCreate) generateControlPlane(controlPlaneType, backendType, hook string) *tenancyv1alpha1.ControlPlane {
@@ -36,6 +36,13 @@ import (
 
 type CPCtx struct {
 	common.CP
+	TLS common.TLSFlags
+	// InternalContext also merges a context for the in-cluster endpoint of the control plane
+	InternalContext bool
+	// ExecCredential replaces the merged credentials with an exec credential running kflex auth token
+	ExecCredential bool
+	// PortForward points the control plane context to a local port forwarded to its API server
+	PortForward int
 }
 
 // Context switch context in Kubeconfig
@@ -48,7 +55,21 @@ func (c *CPCtx) Context() {
 		os.Exit(1)
 	}
 
+	previous := kconf.CurrentContext
 	switch c.CP.Name {
+	case "-":
+		util.PrintStatus("Switching to previous context...", done, &wg)
+		warning, err := kubeconfig.RestorePreviousContext(c.Ctx, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error switching kubeconfig to previous context: %s\n", err)
+			os.Exit(1)
+		}
+		done <- true
+		wg.Wait()
+		if warning != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		return
 	case "":
 		util.PrintStatus("Checking for saved initial context...", done, &wg)
 		time.Sleep(1 * time.Second)
@@ -79,9 +100,21 @@ func (c *CPCtx) Context() {
 				os.Exit(1)
 			}
 		}
+		if c.PortForward != 0 {
+			if err = kubeconfig.RewriteServerToLocalhost(kconf, kconf.CurrentContext, c.PortForward); err != nil {
+				fmt.Fprintf(os.Stderr, "Error pointing context to the forwarded port: %s\n", err)
+				os.Exit(1)
+			}
+		}
 		done <- true
 	}
 
+	if kconf.CurrentContext != previous {
+		if err = kubeconfig.RecordPreviousContext(kconf, previous); err != nil {
+			fmt.Fprintf(os.Stderr, "Error recording previous context: %s\n", err)
+			os.Exit(1)
+		}
+	}
 	if err = kubeconfig.WriteKubeconfig(c.Ctx, kconf); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing kubeconfig: %s\n", err)
 		os.Exit(1)
@@ -101,7 +134,20 @@ func (c *CPCtx) loadAndMergeFromServer(kconfig *api.Config) error {
 	}
 
 	clientset := *(kfclient.GetClientSet(c.Kubeconfig))
-	if err := kubeconfig.LoadAndMergeNoWrite(c.Ctx, clientset, c.Name, string(cp.Spec.Type), kconfig); err != nil {
+	tlsOpts, err := c.TLS.MergeOptions()
+	if err != nil {
+		return err
+	}
+	// the flags go after the spec so that they take precedence
+	opts := append([]kubeconfig.MergeOption{kubeconfig.WithProxyURL(cp.Spec.ProxyURL), kubeconfig.WithTLSServerName(cp.Spec.TLSServerName)}, tlsOpts...)
+	opts = append(opts, kubeconfig.WithContextName(cp.Spec.ContextName), kubeconfig.WithInternalContext(c.InternalContext), kubeconfig.WithExecCredential(c.ExecCredential))
+	if cp.Spec.Type == tenancyv1alpha1.ControlPlaneTypeExternal {
+		if cp.Status.SecretRef == nil {
+			return fmt.Errorf("kubeconfig of external control plane %s is not validated yet", c.Name)
+		}
+		opts = append(opts, kubeconfig.WithKubeconfigSecretRef(cp.Status.SecretRef))
+	}
+	if err := kubeconfig.LoadAndMergeNoWrite(c.Ctx, clientset, c.Name, string(cp.Spec.Type), kconfig, opts...); err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading and merging kubeconfig: %v\n", err)
 		os.Exit(1)
 	}
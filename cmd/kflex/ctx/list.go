@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctx
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kubestellar/kubeflex/pkg/kubeconfig"
+)
+
+// List prints the control plane contexts of the kubeconfig with the expiry of their client
+// certificate, flagging the certificates that expire within kubeconfig.DefaultCertExpiryThreshold
+func (c *CPCtx) List() {
+	kconf, err := kubeconfig.LoadKubeconfig(c.Ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading kubeconfig: %s\n", err)
+		os.Exit(1)
+	}
+	contexts, err := kubeconfig.ListControlPlaneContexts(c.Ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing control plane contexts: %s\n", err)
+		os.Exit(1)
+	}
+	infos, err := kubeconfig.InspectKubeconfigCerts(kconf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error inspecting kubeconfig certificates: %s\n", err)
+		os.Exit(1)
+	}
+	clientCerts := map[string]kubeconfig.CertInfo{}
+	for _, info := range infos {
+		if info.Kind == kubeconfig.CertKindClientCertificate {
+			clientCerts[info.Name] = info
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCURRENT\tCERT EXPIRY")
+	for _, cpCtx := range contexts {
+		current := ""
+		if cpCtx.Current {
+			current = "*"
+		}
+		expiry := "-"
+		if info, ok := clientCerts[cpCtx.AuthInfo]; ok {
+			expiry = info.NotAfter.Format(time.RFC3339)
+			switch {
+			case info.ExpiresWithin(0):
+				expiry += " (expired)"
+			case info.ExpiringSoon:
+				expiry += fmt.Sprintf(" (expires in %dd, rotate soon)", int(time.Until(info.NotAfter).Hours()/24))
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", cpCtx.ContextName, current, expiry)
+	}
+	w.Flush()
+}
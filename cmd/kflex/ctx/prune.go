@@ -0,0 +1,43 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ctx
+
+import (
+	"fmt"
+	"os"
+
+	kfclient "github.com/kubestellar/kubeflex/pkg/client"
+	"github.com/kubestellar/kubeflex/pkg/kubeconfig"
+)
+
+// Prune removes the kubeconfig contexts of control planes that no longer exist, or only
+// lists them with dryRun
+func (c *CPCtx) Prune(dryRun bool) {
+	kfcClient := *(kfclient.GetClient(c.Kubeconfig))
+	pruned, err := kubeconfig.PruneOrphanedContexts(c.Ctx, kfcClient, kubeconfig.WithPruneDryRun(dryRun))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error pruning kubeconfig contexts: %s\n", err)
+		os.Exit(1)
+	}
+	action := "Pruned"
+	if dryRun {
+		action = "Would prune"
+	}
+	for _, name := range pruned {
+		fmt.Printf("%s context %s\n", action, name)
+	}
+}
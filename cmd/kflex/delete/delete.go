@@ -20,10 +20,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -37,50 +39,64 @@ type CPDelete struct {
 	common.CP
 }
 
-func (c *CPDelete) Delete() {
+// Delete deletes the control planes with the given names. Their kubeconfig entries are removed
+// in a single pass and the context is switched back to the initial context.
+func (c *CPDelete) Delete(names []string) {
 	done := make(chan bool)
-	cp := c.generateControlPlane()
 	var wg sync.WaitGroup
 
-	util.PrintStatus(fmt.Sprintf("Deleting control plane %s...", c.Name), done, &wg)
-	kconf, err := kubeconfig.LoadKubeconfig(c.Ctx)
+	util.PrintStatus(fmt.Sprintf("Deleting control plane %s...", strings.Join(names, ", ")), done, &wg)
+	refs := make([]kubeconfig.ControlPlaneRef, 0, len(names))
+	for _, name := range names {
+		refs = append(refs, kubeconfig.ControlPlaneRef{Name: name})
+	}
+	removed, err := kubeconfig.RemoveControlPlanesFromKubeconfig(c.Ctx, refs)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading kubeconfig: %s\n", err)
+		fmt.Fprintf(os.Stderr, "Error removing kubeconfig contexts: %s\n", err)
 		os.Exit(1)
 	}
-
-	if err = kubeconfig.DeleteContext(kconf, c.Name); err != nil {
-		fmt.Fprintf(os.Stderr, "no kubeconfig context for %s was found: %s\n", c.Name, err)
+	removedNames := sets.New(removed...)
+	for _, name := range names {
+		if !removedNames.Has(name) {
+			fmt.Fprintf(os.Stderr, "no kubeconfig context for %s was found\n", name)
+		}
 	}
 
+	kconf, err := kubeconfig.LoadKubeconfig(c.Ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading kubeconfig: %s\n", err)
+		os.Exit(1)
+	}
 	if err = kubeconfig.SwitchToInitialContext(kconf, true); err != nil {
 		fmt.Fprintf(os.Stderr, "no initial kubeconfig context was found: %s\n", err)
 	}
-
 	if err = kubeconfig.WriteKubeconfig(c.Ctx, kconf); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing kubeconfig: %s\n", err)
 		os.Exit(1)
 	}
 
 	kfcClient := *(kfclient.GetClient(c.Kubeconfig))
-	if err := kfcClient.Delete(context.TODO(), cp, &client.DeleteOptions{}); err != nil {
-		fmt.Fprintf(os.Stderr, "Error deleting instance: %s\n", err)
-		os.Exit(1)
+	for _, name := range names {
+		if err := kfcClient.Delete(context.TODO(), generateControlPlane(name), &client.DeleteOptions{}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting instance %s: %s\n", name, err)
+			os.Exit(1)
+		}
 	}
 	done <- true
 
 	clientset := *(kfclient.GetClientSet(c.Kubeconfig))
-	util.PrintStatus(fmt.Sprintf("Waiting for control plane %s to be deleted...", c.Name), done, &wg)
-	util.WaitForNamespaceDeletion(clientset, util.GenerateNamespaceFromControlPlaneName(c.Name))
-
-	done <- true
+	for _, name := range names {
+		util.PrintStatus(fmt.Sprintf("Waiting for control plane %s to be deleted...", name), done, &wg)
+		util.WaitForNamespaceDeletion(clientset, util.GenerateNamespaceFromControlPlaneName(name))
+		done <- true
+	}
 	wg.Wait()
 }
 
-func (c *CPDelete) generateControlPlane() *tenancyv1alpha1.ControlPlane {
+func generateControlPlane(name string) *tenancyv1alpha1.ControlPlane {
 	return &tenancyv1alpha1.ControlPlane{
 		ObjectMeta: v1.ObjectMeta{
-			Name: c.Name,
+			Name: name,
 		},
 	}
 }
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package get
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/cmd/kflex/common"
+	kfclient "github.com/kubestellar/kubeflex/pkg/client"
+	"github.com/kubestellar/kubeflex/pkg/kubeconfig"
+)
+
+type CPGet struct {
+	common.CP
+}
+
+// PrintKubeconfig prints the kubeconfig of the control plane to stdout without merging it
+func (c *CPGet) PrintKubeconfig() {
+	kfcClient := *(kfclient.GetClient(c.Kubeconfig))
+	cp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: v1.ObjectMeta{
+			Name: c.Name,
+		},
+	}
+	if err := kfcClient.Get(context.TODO(), client.ObjectKeyFromObject(cp), cp, &client.GetOptions{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting control plane %s: %s\n", c.Name, err)
+		os.Exit(1)
+	}
+
+	var opts []kubeconfig.MergeOption
+	if cp.Spec.Type == tenancyv1alpha1.ControlPlaneTypeExternal {
+		if cp.Status.SecretRef == nil {
+			fmt.Fprintf(os.Stderr, "Kubeconfig of external control plane %s is not validated yet\n", c.Name)
+			os.Exit(1)
+		}
+		opts = append(opts, kubeconfig.WithKubeconfigSecretRef(cp.Status.SecretRef))
+	}
+	clientset := *(kfclient.GetClientSet(c.Kubeconfig))
+	data, _, err := kubeconfig.GetControlPlaneKubeconfig(c.Ctx, clientset, c.Name, string(cp.Spec.Type), opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting kubeconfig of control plane %s: %s\n", c.Name, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(data)
+}
@@ -24,10 +24,12 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/cmd/kflex/auth"
 	"github.com/kubestellar/kubeflex/cmd/kflex/common"
 	cr "github.com/kubestellar/kubeflex/cmd/kflex/create"
 	cont "github.com/kubestellar/kubeflex/cmd/kflex/ctx"
 	del "github.com/kubestellar/kubeflex/cmd/kflex/delete"
+	"github.com/kubestellar/kubeflex/cmd/kflex/get"
 	in "github.com/kubestellar/kubeflex/cmd/kflex/init"
 	cluster "github.com/kubestellar/kubeflex/cmd/kflex/init/cluster"
 	"github.com/kubestellar/kubeflex/pkg/util"
@@ -43,6 +45,15 @@ var BuildDate string
 var CType string
 var BkType string
 var Hook string
+var noSwitch bool
+var tlsFlags common.TLSFlags
+var internalContext bool
+var execCredential bool
+var verify bool
+var portForward int
+var hostingContext string
+var controlPlane string
+var dryRun bool
 var domain string
 var externalPort int
 
@@ -99,6 +110,10 @@ var createCmd = &cobra.Command{
 				Name:       args[0],
 				Kubeconfig: kubeconfig,
 			},
+			TLS:             tlsFlags,
+			InternalContext: internalContext,
+			ExecCredential:  execCredential,
+			Verify:          verify,
 		}
 		if CType == "" {
 			CType = CTypeDefault
@@ -107,25 +122,24 @@ var createCmd = &cobra.Command{
 			BkType = BKTypeDefault
 		}
 		// create passing the control plane type and backend type
-		cp.Create(CType, BkType, Hook)
+		cp.Create(CType, BkType, Hook, noSwitch)
 	},
 }
 
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
-	Short: "Delete a control plane instance",
-	Long: `Delete a control plane instance and switches the context back to 
+	Short: "Delete control plane instances",
+	Long: `Delete one or more control plane instances and switches the context back to 
 	        the hosting cluster context`,
-	Args: cobra.ExactArgs(1),
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		cp := del.CPDelete{
 			CP: common.CP{
 				Ctx:        createContext(),
-				Name:       args[0],
 				Kubeconfig: kubeconfig,
 			},
 		}
-		cp.Delete()
+		cp.Delete(args)
 	},
 }
 
@@ -134,7 +148,7 @@ var ctxCmd = &cobra.Command{
 	Short: "Switch kubeconfig context to a control plane instance",
 	Long: `Running without an argument switches the context back to the initial context,
 			        while providing the control plane name as argument switches the context to
-					that control plane`,
+					that control plane. Passing - switches back to the previous context`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		cpName := ""
@@ -147,11 +161,96 @@ var ctxCmd = &cobra.Command{
 				Name:       cpName,
 				Kubeconfig: kubeconfig,
 			},
+			TLS:             tlsFlags,
+			InternalContext: internalContext,
+			ExecCredential:  execCredential,
+			PortForward:     portForward,
 		}
 		cp.Context()
 	},
 }
 
+var ctxPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove kubeconfig contexts of deleted control planes",
+	Long: `Removes the kubeconfig contexts, clusters and users of control planes that no
+			        longer exist on the hosting cluster. Contexts not created by kflex are kept`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		cp := cont.CPCtx{
+			CP: common.CP{
+				Ctx:        createContext(),
+				Kubeconfig: kubeconfig,
+			},
+		}
+		cp.Prune(dryRun)
+	},
+}
+
+var ctxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the kubeconfig contexts of control planes",
+	Long: `Lists the kubeconfig contexts of control planes with the expiry of their client
+			        certificate, flagging the certificates that expire within 30 days`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		cp := cont.CPCtx{
+			CP: common.CP{
+				Ctx:        createContext(),
+				Kubeconfig: kubeconfig,
+			},
+		}
+		cp.List()
+	},
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Display a resource of a control plane instance",
+}
+
+var getKubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig",
+	Short: "Print the kubeconfig of a control plane instance",
+	Long: `Prints the kubeconfig of a control plane instance to stdout without merging it
+	        in the kubeconfig file, e.g. KUBECONFIG=<(kflex get kubeconfig cp1) kubectl get ns`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cp := get.CPGet{
+			CP: common.CP{
+				Ctx:        createContext(),
+				Name:       args[0],
+				Kubeconfig: kubeconfig,
+			},
+		}
+		cp.PrintKubeconfig()
+	},
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Provide the credentials of control plane instances",
+}
+
+var authTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print the credentials of a control plane instance as an ExecCredential",
+	Long: `Prints the credentials of a control plane instance, read from its kubeconfig secret in
+	        the hosting cluster, as an ExecCredential. It is run by the contexts added with --exec-credential`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		cp := auth.CPAuth{
+			CP: common.CP{
+				Ctx:        createContext(),
+				Name:       controlPlane,
+				Kubeconfig: kubeconfig,
+			},
+			HostingContext: hostingContext,
+		}
+		cp.Token()
+	},
+}
+
 func init() {
 	versionCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
 
@@ -163,21 +262,54 @@ func init() {
 
 	createCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
 	createCmd.Flags().IntVarP(&verbosity, "verbosity", "v", 0, "log level") // TODO - figure out how to inject verbosity
-	createCmd.Flags().StringVarP(&CType, "type", "t", "", "type of control plane: k8s|ocm|vcluster")
+	createCmd.Flags().StringVarP(&CType, "type", "t", "", "type of control plane: k8s|ocm|vcluster|host")
 	createCmd.Flags().StringVarP(&BkType, "backend-type", "b", "", "backend DB sharing: shared|dedicated")
 	createCmd.Flags().StringVarP(&Hook, "postcreate-hook", "p", "", "name of post create hook to run")
+	createCmd.Flags().BoolVar(&noSwitch, "no-switch", false, "add the control plane context to the kubeconfig without switching to it")
+	createCmd.Flags().StringVar(&tlsFlags.CertificateAuthority, "certificate-authority", "", "path to a CA bundle verifying the API server certificate of the control plane")
+	createCmd.Flags().BoolVar(&tlsFlags.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "do not verify the API server certificate of the control plane (insecure, dev clusters only)")
+	createCmd.Flags().StringVar(&tlsFlags.ProxyURL, "proxy-url", "", "proxy the API server of the control plane is reached through, such as a shared gateway")
+	createCmd.Flags().StringVar(&tlsFlags.TLSServerName, "tls-server-name", "", "name the API server certificate of the control plane is verified against")
+	createCmd.Flags().BoolVar(&internalContext, "internal-context", false, "also add a <name>-internal context for the in-cluster endpoint of the control plane")
+	createCmd.Flags().BoolVar(&execCredential, "exec-credential", false, "use an exec credential running kflex auth token instead of embedding the control plane credentials")
+	createCmd.Flags().BoolVar(&verify, "verify", false, "check that the control plane context reaches the API server before writing the kubeconfig")
 
 	deleteCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
 	deleteCmd.Flags().IntVarP(&verbosity, "verbosity", "v", 0, "log level") // TODO - figure out how to inject verbosity
 
 	ctxCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
 	ctxCmd.Flags().IntVarP(&verbosity, "verbosity", "v", 0, "log level") // TODO - figure out how to inject verbosity
+	ctxCmd.Flags().StringVar(&tlsFlags.CertificateAuthority, "certificate-authority", "", "path to a CA bundle verifying the API server certificate of the control plane")
+	ctxCmd.Flags().BoolVar(&tlsFlags.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "do not verify the API server certificate of the control plane (insecure, dev clusters only)")
+	ctxCmd.Flags().StringVar(&tlsFlags.ProxyURL, "proxy-url", "", "proxy the API server of the control plane is reached through, such as a shared gateway")
+	ctxCmd.Flags().StringVar(&tlsFlags.TLSServerName, "tls-server-name", "", "name the API server certificate of the control plane is verified against")
+	ctxCmd.Flags().BoolVar(&internalContext, "internal-context", false, "also add a <name>-internal context for the in-cluster endpoint of the control plane")
+	ctxCmd.Flags().BoolVar(&execCredential, "exec-credential", false, "use an exec credential running kflex auth token instead of embedding the control plane credentials")
+	ctxCmd.Flags().IntVar(&portForward, "port-forward", 0, "point the control plane context to https://localhost:<port>, forwarded to its API server with kubectl port-forward")
+
+	ctxPruneCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
+	ctxPruneCmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the contexts that would be pruned without removing them")
+	ctxCmd.AddCommand(ctxPruneCmd)
+
+	ctxListCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
+	ctxCmd.AddCommand(ctxListCmd)
+
+	getKubeconfigCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
+	getCmd.AddCommand(getKubeconfigCmd)
+
+	authTokenCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "path to kubeconfig file")
+	authTokenCmd.Flags().StringVar(&controlPlane, "controlplane", "", "name of the control plane")
+	authTokenCmd.Flags().StringVar(&hostingContext, "context", "", "kubeconfig context of the hosting cluster, defaults to the initial context recorded by kflex")
+	authTokenCmd.MarkFlagRequired("controlplane")
+	authCmd.AddCommand(authTokenCmd)
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(createCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(ctxCmd)
+	rootCmd.AddCommand(getCmd)
+	rootCmd.AddCommand(authCmd)
 }
 
 // TODO - work on passing the verbosity to the logger
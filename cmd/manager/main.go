@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -38,6 +39,8 @@ import (
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
 	"github.com/kubestellar/kubeflex/internal/controller"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -61,16 +64,31 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var namespacePrefix string
+	var maxConcurrentChartOps int
+	var provisioningRequeueInterval time.Duration
+	var steadyStateRequeueInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&namespacePrefix, "namespace-prefix", util.GetNamespacePrefix(),
+		"Prefix prepended to the namespace of every control plane, defaults to the value of "+util.NamespacePrefixEnvVar+". "+
+			"Set a different prefix for each KubeFlex installation sharing a hosting cluster.")
+	flag.IntVar(&maxConcurrentChartOps, "max-concurrent-chart-ops", 0,
+		"Maximum number of control plane chart installs and upgrades running at the same time. "+
+			"Reconciles over the limit are requeued. Zero or less sets no limit.")
+	flag.DurationVar(&provisioningRequeueInterval, "provisioning-requeue-interval", shared.DefaultProvisioningRequeueInterval,
+		"Delay after which the reconcile of an ocm or vcluster control plane waiting to be provisioned is requeued.")
+	flag.DurationVar(&steadyStateRequeueInterval, "steady-state-requeue-interval", 0,
+		"Delay after which the reconcile of a provisioned ocm or vcluster control plane is requeued. Zero disables the periodic reconcile.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+	util.SetNamespacePrefix(namespacePrefix)
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
@@ -103,11 +121,15 @@ func main() {
 	addExtraTypesToScheme(mgr.GetScheme())
 
 	if err = (&controller.ControlPlaneReconciler{
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		Version:       Version,
-		ClientSet:     kubernetes.NewForConfigOrDie(config),
-		DynamicClient: dynamic.NewForConfigOrDie(config),
+		Client:                      mgr.GetClient(),
+		Scheme:                      mgr.GetScheme(),
+		Version:                     Version,
+		ClientSet:                   kubernetes.NewForConfigOrDie(config),
+		DynamicClient:               dynamic.NewForConfigOrDie(config),
+		Recorder:                    mgr.GetEventRecorderFor("controlplane-controller"),
+		MaxConcurrentChartOps:       maxConcurrentChartOps,
+		ProvisioningRequeueInterval: provisioningRequeueInterval,
+		SteadyStateRequeueInterval:  steadyStateRequeueInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ControlPlane")
 		os.Exit(1)
@@ -19,22 +19,30 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	clog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/external"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/host"
 	"github.com/kubestellar/kubeflex/pkg/reconcilers/k8s"
 	"github.com/kubestellar/kubeflex/pkg/reconcilers/ocm"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
 	"github.com/kubestellar/kubeflex/pkg/reconcilers/vcluster"
 	"github.com/kubestellar/kubeflex/pkg/util"
 )
@@ -48,6 +56,16 @@ type ControlPlaneReconciler struct {
 	Version       string
 	ClientSet     *kubernetes.Clientset
 	DynamicClient *dynamic.DynamicClient
+	Recorder      record.EventRecorder
+	// MaxConcurrentChartOps bounds the chart installs and upgrades running at the same time
+	// for all the control planes. Zero or less sets no limit
+	MaxConcurrentChartOps int
+	// ProvisioningRequeueInterval and SteadyStateRequeueInterval set how often the chart based
+	// control planes are requeued while provisioning and once provisioned, see
+	// shared.BaseReconciler
+	ProvisioningRequeueInterval time.Duration
+	SteadyStateRequeueInterval  time.Duration
+	chartLimiter                *shared.ChartLimiter
 }
 
 //+kubebuilder:rbac:groups=tenancy.kflex.kubestellar.org,resources=controlplanes,verbs=get;list;watch;create;update;patch;delete
@@ -61,10 +79,14 @@ type ControlPlaneReconciler struct {
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete;services
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=clusterroles,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=clusterroles,verbs=bind,resourceNames=admin;view;system:auth-delegator
 //+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=clusterrolebindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=roles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
@@ -80,6 +102,7 @@ type ControlPlaneReconciler struct {
 //+kubebuilder:rbac:groups="apps",resources=replicasets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="coordination.k8s.io",resources=leases,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="apiextensions.k8s.io",resources=customresourcedefinitions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="storage.k8s.io",resources=storageclasses,verbs=get;list;watch
 //+kubebuilder:rbac:urls=/metrics,verbs=get
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -114,6 +137,7 @@ func (r *ControlPlaneReconciler) Reconcile(ctx context.Context, req ctrl.Request
 				return ctrl.Result{}, err
 			}
 
+			shared.DeleteControlPlaneMetrics(hcp)
 			controllerutil.RemoveFinalizer(hcp, kfFinalizer)
 			err := r.Update(ctx, hcp)
 			if err != nil {
@@ -131,24 +155,41 @@ func (r *ControlPlaneReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
-	// check if API server is already in a ready state
-	ready, _ := util.IsAPIServerDeploymentReady(r.Client, *hcp)
-	if ready {
-		tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionAvailable())
-	} else {
-		tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionUnavailable())
+	// an unknown type would leave a half provisioned control plane, so report it before acting
+	if err := util.ValidateControlPlaneType(string(hcp.Spec.Type)); err != nil {
+		base := &shared.BaseReconciler{Client: r.Client, Scheme: r.Scheme, Recorder: r.Recorder}
+		return base.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	// check if API server is already in a ready state. External and host control planes have
+	// no API server deployment, their reconcilers set the condition once the kubeconfig is ready
+	if hcp.Spec.Type != tenancyv1alpha1.ControlPlaneTypeExternal && hcp.Spec.Type != tenancyv1alpha1.ControlPlaneTypeHost {
+		ready, _ := util.IsAPIServerDeploymentReady(r.Client, *hcp)
+		shared.SetHealthConditions(hcp, ready)
 	}
 
 	// select the reconciler to use for the type of control plane
 	switch hcp.Spec.Type {
 	case tenancyv1alpha1.ControlPlaneTypeK8S:
-		reconciler := k8s.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient)
+		reconciler := k8s.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient, r.Recorder)
 		return reconciler.Reconcile(ctx, hcp)
 	case tenancyv1alpha1.ControlPlaneTypeOCM:
-		reconciler := ocm.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient)
+		reconciler := ocm.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient, r.Recorder)
+		reconciler.ChartLimiter = r.chartLimiter
+		reconciler.ProvisioningRequeueInterval = r.ProvisioningRequeueInterval
+		reconciler.SteadyStateRequeueInterval = r.SteadyStateRequeueInterval
 		return reconciler.Reconcile(ctx, hcp)
 	case tenancyv1alpha1.ControlPlaneTypeVCluster:
-		reconciler := vcluster.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient)
+		reconciler := vcluster.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient, r.Recorder)
+		reconciler.ChartLimiter = r.chartLimiter
+		reconciler.ProvisioningRequeueInterval = r.ProvisioningRequeueInterval
+		reconciler.SteadyStateRequeueInterval = r.SteadyStateRequeueInterval
+		return reconciler.Reconcile(ctx, hcp)
+	case tenancyv1alpha1.ControlPlaneTypeExternal:
+		reconciler := external.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient, r.Recorder)
+		return reconciler.Reconcile(ctx, hcp)
+	case tenancyv1alpha1.ControlPlaneTypeHost:
+		reconciler := host.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient, r.Recorder)
 		return reconciler.Reconcile(ctx, hcp)
 	default:
 		return ctrl.Result{}, fmt.Errorf("unsupported control plane type: %s", hcp.Spec.Type)
@@ -157,6 +198,11 @@ func (r *ControlPlaneReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ControlPlaneReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.chartLimiter = shared.NewChartLimiter(r.MaxConcurrentChartOps)
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &tenancyv1alpha1.ControlPlane{},
+		shared.ValuesFromIndexField, shared.IndexValuesFrom); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&tenancyv1alpha1.ControlPlane{}).
 		Owns(&corev1.Service{}).
@@ -166,9 +212,59 @@ func (r *ControlPlaneReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.ServiceAccount{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.controlPlanesForValues("ConfigMap"))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.controlPlanesForValues("Secret"))).
+		Watches(&networkingv1.IngressClass{}, handler.EnqueueRequestsFromMapFunc(r.controlPlanesForIngressClass)).
 		Complete(r)
 }
 
+// controlPlanesForValues returns a map function enqueuing the control planes whose
+// spec.valuesFrom references an object of the given kind, so that editing the values
+// upgrades their chart
+func (r *ControlPlaneReconciler) controlPlanesForValues(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		list := &tenancyv1alpha1.ControlPlaneList{}
+		key := shared.ValuesFromIndexKey(kind, obj.GetNamespace(), obj.GetName())
+		if err := r.List(ctx, list, client.MatchingFields{shared.ValuesFromIndexField: key}); err != nil {
+			clog.FromContext(ctx).Error(err, "error listing control planes", "kind", kind, "name", obj.GetName())
+			return nil
+		}
+		var requests []reconcile.Request
+		for i := range list.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+		return requests
+	}
+}
+
+// controlPlanesForIngressClass enqueues the control planes exposed through an ingress when the
+// ingress class of their ingress is added or removed, so that the availability of an ingress
+// controller is reported without waiting for another change
+func (r *ControlPlaneReconciler) controlPlanesForIngressClass(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetName() != shared.IngressClassNameNGINX {
+		return nil
+	}
+	list := &tenancyv1alpha1.ControlPlaneList{}
+	if err := r.List(ctx, list); err != nil {
+		clog.FromContext(ctx).Error(err, "error listing control planes", "kind", "IngressClass", "name", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		cp := &list.Items[i]
+		switch cp.Spec.Type {
+		case tenancyv1alpha1.ControlPlaneTypeExternal, tenancyv1alpha1.ControlPlaneTypeHost:
+			continue
+		}
+		if cp.Spec.Expose != "" && cp.Spec.Expose != tenancyv1alpha1.ExposeIngress {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cp)})
+	}
+	return requests
+}
+
 func (r *ControlPlaneReconciler) deleteExternalResources(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
 	// add owner reference to cluster-scoped resources associated with the control plane
 	// so that the Kube GC will clean those when the CP is removed
@@ -176,20 +272,23 @@ func (r *ControlPlaneReconciler) deleteExternalResources(ctx context.Context, hc
 		return err
 	}
 
-	// bypass DB cleanup when running out of cluster as there is no connectivity to the DB
-	if !util.IsInCluster() {
-		return nil
-	}
-	// select the type of delete action (for now only k8s using sharedDB)
+	// select the type of delete action
 	switch hcp.Spec.Type {
 	case tenancyv1alpha1.ControlPlaneTypeK8S:
+		// bypass DB cleanup when running out of cluster as there is no connectivity to the DB
+		if !util.IsInCluster() {
+			return nil
+		}
 		if err := util.DropDatabase(ctx, hcp.Name, r.Client); err != nil {
 			return err
 		}
 	case tenancyv1alpha1.ControlPlaneTypeOCM:
 
 	case tenancyv1alpha1.ControlPlaneTypeVCluster:
-
+		reconciler := vcluster.New(r.Client, r.Scheme, r.Version, r.ClientSet, r.DynamicClient, r.Recorder)
+		if err := reconciler.Cleanup(ctx, hcp); err != nil {
+			return err
+		}
 	default:
 		return nil
 	}
@@ -24,7 +24,9 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
+	"net"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -34,6 +36,9 @@ import (
 
 const (
 	CertsSecretName = "k8s-certs"
+	// APIServerCAKey is the key of the certs secret holding the CA of an externally issued API
+	// server serving cert. It is kept out of ca.crt, which is the client CA of the API server.
+	APIServerCAKey = "apiserver-ca.crt"
 )
 
 type Certs struct {
@@ -49,6 +54,8 @@ type Certs struct {
 	frontProxyPEMCert []byte
 	saPEMKey          []byte
 	saPEMPubKey       []byte
+	// CA of an externally issued serving cert, the generated CA signs the serving cert when empty
+	servingCAPEMCert []byte
 }
 
 func New(ctx context.Context, extraDNSNames []string) (*Certs, error) {
@@ -79,7 +86,7 @@ func (c *Certs) generateAllCerts(ctx context.Context, extraDNSNames []string) er
 }
 
 func (c *Certs) GenerateCertsSecret(ctx context.Context, namespace string) *v1.Secret {
-	return &v1.Secret{
+	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      CertsSecretName,
 			Namespace: namespace,
@@ -99,6 +106,83 @@ func (c *Certs) GenerateCertsSecret(ctx context.Context, namespace string) *v1.S
 			"sa.pub":                       c.saPEMPubKey,
 		},
 	}
+	if c.servingCAPEMCert != nil {
+		secret.Data[APIServerCAKey] = c.servingCAPEMCert
+	}
+	return secret
+}
+
+// ServingCA returns the CA bundle verifying the API server serving cert, used as the
+// certificate authority of the kubeconfigs
+func (c *Certs) ServingCA() []byte {
+	if c.servingCAPEMCert != nil {
+		return c.servingCAPEMCert
+	}
+	return c.caPEMCert
+}
+
+// LoadCertsSecret returns the certs stored in a secret generated by GenerateCertsSecret, so
+// that new certificates can be signed by its CA. Only the generated CA is loaded from the ca.crt
+// bundle, the trusted external CAs are added again with AddTrustedCA.
+func LoadCertsSecret(secret *v1.Secret) (*Certs, error) {
+	c := &Certs{
+		caPEMKey:          secret.Data["ca.key"],
+		apiServerPEMKey:   secret.Data["apiserver.key"],
+		apiServerPEMCert:  secret.Data["apiserver.crt"],
+		kubeletPEMKey:     secret.Data["apiserver-kubelet-client.key"],
+		kubeletPEMCert:    secret.Data["apiserver-kubelet-client.crt"],
+		frontProxyPEMKey:  secret.Data["front-proxy-client.key"],
+		frontProxyPEMCert: secret.Data["front-proxy-client.crt"],
+		saPEMKey:          secret.Data["sa.key"],
+		saPEMPubKey:       secret.Data["sa.pub"],
+		servingCAPEMCert:  secret.Data[APIServerCAKey],
+	}
+	// the generated CA comes first in the ca.crt bundle
+	block, _ := pem.Decode(secret.Data["ca.crt"])
+	if block == nil {
+		return nil, fmt.Errorf("error decoding CA certificate of secret %s/%s", secret.Namespace, secret.Name)
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CA certificate of secret %s/%s: %s", secret.Namespace, secret.Name, err)
+	}
+	c.caPEMCert = encodeToPEMCertificate(block.Bytes)
+	block, _ = pem.Decode(c.caPEMKey)
+	if block == nil {
+		return nil, fmt.Errorf("error decoding CA key of secret %s/%s", secret.Namespace, secret.Name)
+	}
+	if c.caKey, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return nil, fmt.Errorf("error parsing CA key of secret %s/%s: %s", secret.Namespace, secret.Name, err)
+	}
+	c.caTemplate = *caCert
+	return c, nil
+}
+
+// APIServerCertHasHost returns true when the API server serving certificate is valid for host
+func (c *Certs) APIServerCertHasHost(host string) bool {
+	block, _ := pem.Decode(c.apiServerPEMCert)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	return cert.VerifyHostname(host) == nil
+}
+
+// RegenerateAPIServerCert issues a new API server serving certificate and key for extraDNSNames,
+// signed by the existing CA, so that the kubeconfigs generated before stay valid. It replaces an
+// external serving certificate along with its CA.
+func (c *Certs) RegenerateAPIServerCert(ctx context.Context, extraDNSNames []string) error {
+	c.servingCAPEMCert = nil
+	return c.generateAPIServerKeyAndCert(ctx, extraDNSNames)
+}
+
+// HasExternalAPIServerCert returns whether the API server serving certificate was issued
+// externally, as set by UseExternalAPIServerCert
+func (c *Certs) HasExternalAPIServerCert() bool {
+	return c.servingCAPEMCert != nil
 }
 
 func (c *Certs) generateCA(ctx context.Context) (err error) {
@@ -153,11 +237,20 @@ func (c *Certs) generateAPIServerKeyAndCert(ctx context.Context, extraDNSNames [
 		"localhost",
 		"kubeflex-control-plane"}
 
-	dnsNames = append(dnsNames, extraDNSNames...)
+	// clients only match IP addresses against the IP SANs
+	var ipAddresses []net.IP
+	for _, name := range extraDNSNames {
+		if ip := net.ParseIP(name); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, name)
+		}
+	}
 	certTemplate := x509.Certificate{
 		SerialNumber:          big.NewInt(1658),
 		Subject:               pkix.Name{CommonName: "kube-apiserver"},
 		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
 		NotBefore:             time.Now().Add(-1 * time.Hour),
 		NotAfter:              time.Now().AddDate(10, 0, 0),
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageDataEncipherment,
@@ -2,6 +2,8 @@ package certs
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 )
 
@@ -55,13 +57,24 @@ func TestCerts_generateAPIServerKeyAndCert(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error generating CA in order to create API server key and cert: %v", err)
 	}
-	extraDNSNames := []string{"example.com"}
+	extraDNSNames := []string{"example.com", "172.18.0.2"}
 	err = c.generateAPIServerKeyAndCert(ctx, extraDNSNames)
 	if err != nil {
 		t.Errorf("Error returned from generateAPIServerKeyAndCert function: %v", err)
 	}
 	if c.apiServerPEMCert == nil || c.apiServerPEMKey == nil {
-		t.Error("generateAPIServerKeyAndCert did not properly generate PEM certificates and keys")
+		t.Fatal("generateAPIServerKeyAndCert did not properly generate PEM certificates and keys")
+	}
+	block, _ := pem.Decode(c.apiServerPEMCert)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Error parsing API server certificate: %v", err)
+	}
+	if err := cert.VerifyHostname("example.com"); err != nil {
+		t.Errorf("API server certificate is not valid for the extra DNS name: %v", err)
+	}
+	if err := cert.VerifyHostname("172.18.0.2"); err != nil {
+		t.Errorf("API server certificate is not valid for the extra IP address: %v", err)
 	}
 }
 
@@ -108,3 +121,42 @@ func TestCerts_generateSAKey(t *testing.T) {
 		t.Error("generateSAKey did not properly generate PEM keys")
 	}
 }
+
+func TestLoadCertsSecret(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(ctx, []string{"cp1.example.com"})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	secret := c.GenerateCertsSecret(ctx, "cp1-system")
+
+	loaded, err := LoadCertsSecret(secret)
+	if err != nil {
+		t.Fatalf("LoadCertsSecret returned error: %v", err)
+	}
+	if !loaded.APIServerCertHasHost("cp1.example.com") || loaded.APIServerCertHasHost("cp1.example.org") {
+		t.Fatal("expected the loaded API server certificate to be valid for cp1.example.com only")
+	}
+	if err := loaded.RegenerateAPIServerCert(ctx, []string{"cp1.example.org"}); err != nil {
+		t.Fatalf("RegenerateAPIServerCert returned error: %v", err)
+	}
+	if !loaded.APIServerCertHasHost("cp1.example.org") {
+		t.Error("expected the regenerated API server certificate to be valid for cp1.example.org")
+	}
+
+	// the regenerated certificate is signed by the CA of the secret
+	regenerated := loaded.GenerateCertsSecret(ctx, "cp1-system")
+	for _, key := range []string{"ca.crt", "ca.key", "sa.key", "front-proxy-client.crt"} {
+		if string(regenerated.Data[key]) != string(secret.Data[key]) {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+	if err := ValidateKeyPair(regenerated.Data["apiserver.crt"], regenerated.Data["apiserver.key"], secret.Data["ca.crt"]); err != nil {
+		t.Errorf("expected the regenerated certificate to be issued by the CA: %v", err)
+	}
+
+	delete(secret.Data, "ca.key")
+	if _, err := LoadCertsSecret(secret); err == nil {
+		t.Error("expected an error loading a secret without a CA key")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// ValidateKeyPair checks that the PEM encoded certificate and key parse and match
+// and, if caPEM is not empty, that the certificate is issued by that CA
+func ValidateKeyPair(certPEM, keyPEM, caPEM []byte) error {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid certificate and key pair: %s", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("error parsing certificate: %s", err)
+	}
+	if len(caPEM) == 0 {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("error parsing CA certificate")
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate is not issued by the provided CA: %s", err)
+	}
+	return nil
+}
+
+// UseExternalAPIServerCert replaces the generated API server serving certificate and key
+// with externally issued ones. Their issuing CA is only used to verify the API server, as
+// the CA of the kubeconfigs, and is not trusted for client certificates.
+func (c *Certs) UseExternalAPIServerCert(certPEM, keyPEM, caPEM []byte) error {
+	if err := ValidateKeyPair(certPEM, keyPEM, caPEM); err != nil {
+		return err
+	}
+	c.apiServerPEMCert = certPEM
+	c.apiServerPEMKey = keyPEM
+	c.servingCAPEMCert = caPEM
+	return nil
+}
+
+// AddTrustedCA appends an externally managed CA to the ca.crt bundle, which is the client
+// CA of the API server, such as the CA issuing an external admin client certificate.
+// The generated CA is kept first in the bundle as it signs the generated certificates.
+func (c *Certs) AddTrustedCA(caPEM []byte) {
+	if len(caPEM) == 0 || bytes.Contains(c.caPEMCert, bytes.TrimSpace(caPEM)) {
+		return
+	}
+	bundle := append([]byte{}, c.caPEMCert...)
+	if len(bundle) > 0 && !bytes.HasSuffix(bundle, []byte("\n")) {
+		bundle = append(bundle, '\n')
+	}
+	c.caPEMCert = append(bundle, caPEM...)
+}
+
+// UseExternalClientCert makes the admin kubeconfigs use an externally issued client
+// certificate and key instead of generating one
+func (c *ConfigGen) UseExternalClientCert(certPEM, keyPEM []byte) error {
+	if err := ValidateKeyPair(certPEM, keyPEM, nil); err != nil {
+		return err
+	}
+	c.externalCert = certPEM
+	c.externalKey = keyPEM
+	return nil
+}
+
+// ExternalClientCert returns the external client certificate and key set by
+// UseExternalClientCert, or nil if the admin client certificate is generated
+func (c *ConfigGen) ExternalClientCert() ([]byte, []byte) {
+	return c.externalCert, c.externalKey
+}
@@ -0,0 +1,83 @@
+package certs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidateKeyPair(t *testing.T) {
+	ctx := context.Background()
+	c1, err := New(ctx, nil)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	c2, err := New(ctx, nil)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	if err := ValidateKeyPair(c1.apiServerPEMCert, c1.apiServerPEMKey, c1.caPEMCert); err != nil {
+		t.Errorf("expected matching pair to be valid, got %v", err)
+	}
+	if err := ValidateKeyPair(c1.apiServerPEMCert, c2.apiServerPEMKey, nil); err == nil {
+		t.Errorf("expected error for mismatched certificate and key")
+	}
+	if err := ValidateKeyPair(c1.apiServerPEMCert, c1.apiServerPEMKey, c2.caPEMCert); err == nil {
+		t.Errorf("expected error for certificate not issued by the CA")
+	}
+	if err := ValidateKeyPair([]byte("not a cert"), c1.apiServerPEMKey, nil); err == nil {
+		t.Errorf("expected error for unparseable certificate")
+	}
+}
+
+func TestUseExternalAPIServerCert(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(ctx, nil)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	external, err := New(ctx, nil)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	generatedCA := c.caPEMCert
+
+	if err := c.UseExternalAPIServerCert(external.apiServerPEMCert, external.apiServerPEMKey, external.caPEMCert); err != nil {
+		t.Fatalf("UseExternalAPIServerCert returned error: %v", err)
+	}
+	secret := c.GenerateCertsSecret(ctx, "cp1-system")
+	if string(secret.Data["apiserver.crt"]) != string(external.apiServerPEMCert) {
+		t.Errorf("expected external API server certificate in certs secret")
+	}
+	// the serving CA verifies the API server, but is not trusted for client certificates
+	if string(secret.Data["ca.crt"]) != string(generatedCA) {
+		t.Errorf("expected ca.crt to hold the generated CA only")
+	}
+	if string(secret.Data[APIServerCAKey]) != string(external.caPEMCert) || string(c.ServingCA()) != string(external.caPEMCert) {
+		t.Errorf("expected the external CA to be the serving CA")
+	}
+
+	// the CA of an external admin cert is trusted once
+	admin, err := New(ctx, nil)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	c.AddTrustedCA(admin.caPEMCert)
+	c.AddTrustedCA(admin.caPEMCert)
+	if string(c.caPEMCert) != string(generatedCA)+string(admin.caPEMCert) {
+		t.Errorf("expected the admin CA to be added only once to ca.crt")
+	}
+}
+
+func TestServingCAWithoutExternalCert(t *testing.T) {
+	c, err := New(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if string(c.ServingCA()) != string(c.caPEMCert) {
+		t.Errorf("expected the generated CA to be the serving CA")
+	}
+	if _, ok := c.GenerateCertsSecret(context.Background(), "cp1-system").Data[APIServerCAKey]; ok {
+		t.Errorf("expected no %s key without an external serving cert", APIServerCAKey)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"crypto/x509/pkix"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/kubestellar/kubeflex/pkg/util"
@@ -55,28 +56,35 @@ type ConfigGen struct {
 	CpPort      int
 	CpExtraDNS  string
 	Target      ConfigTarget
-	caKey       *rsa.PrivateKey
-	caTemplate  x509.Certificate
-	caPEMCert   []byte
-	key         []byte
-	cert        []byte
-	authInfo    string
-	secretName  string
+	// InClusterOnly points the admin kubeconfig at the API server service, for control
+	// planes that are not exposed outside the hosting cluster
+	InClusterOnly bool
+	caKey         *rsa.PrivateKey
+	caTemplate    x509.Certificate
+	caPEMCert     []byte
+	key           []byte
+	cert          []byte
+	authInfo      string
+	secretName    string
+	// externally issued admin client cert and key
+	externalCert []byte
+	externalKey  []byte
 }
 
 func GenerateKubeConfigSecret(ctx context.Context, certs *Certs, conf *ConfigGen) (*v1.Secret, error) {
 	var kconfInCluster []byte
 	conf.caKey = certs.caKey
 	conf.caTemplate = certs.caTemplate
-	conf.caPEMCert = certs.caPEMCert
+	conf.caPEMCert = certs.ServingCA()
 	kconf, err := GenerateKubeconfigBytes(conf)
 	if err != nil {
 		return nil, err
 	}
-	// generate the admin kubeconfig
+	// generate the admin kubeconfig, on a copy so that the target of conf is left unchanged
 	if conf.Target == Admin {
-		conf.Target = AdminInCluster
-		kconfInCluster, err = GenerateKubeconfigBytes(conf)
+		inCluster := *conf
+		inCluster.Target = AdminInCluster
+		kconfInCluster, err = GenerateKubeconfigBytes(&inCluster)
 		if err != nil {
 			return nil, err
 		}
@@ -116,6 +124,12 @@ func (c *ConfigGen) generateConfigCerts() error {
 		return fmt.Errorf("invalid target: %d", c.Target)
 	}
 
+	if c.externalCert != nil && (c.Target == Admin || c.Target == AdminInCluster) {
+		c.cert = c.externalCert
+		c.key = c.externalKey
+		return nil
+	}
+
 	key, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		return fmt.Errorf("error generating KubeConfig key pair: %s", err)
@@ -163,8 +177,34 @@ func (c *ConfigGen) generateConfig() *clientcmdapi.Config {
 	return config
 }
 
+// GenerateTokenKubeconfig returns a kubeconfig whose single context, named after the cluster,
+// authenticates to server with a bearer token such as a service account token, and defaults
+// to namespace
+func GenerateTokenKubeconfig(clusterName, authInfoName, server, namespace string, caData []byte, token string) *clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[clusterName] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: caData,
+	}
+	config.AuthInfos[authInfoName] = &clientcmdapi.AuthInfo{
+		Token: token,
+	}
+	config.Contexts[clusterName] = &clientcmdapi.Context{
+		Cluster:   clusterName,
+		AuthInfo:  authInfoName,
+		Namespace: namespace,
+	}
+	config.CurrentContext = clusterName
+	return config
+}
+
+// ServerEndpoint returns the API server URL written in the kubeconfig for the target
+func (c *ConfigGen) ServerEndpoint() string {
+	return c.generateServerEndpoint()
+}
+
 func (c *ConfigGen) generateServerEndpoint() string {
-	if c.Target == ControllerManager || c.Target == AdminInCluster {
+	if c.Target == ControllerManager || c.Target == AdminInCluster || c.InClusterOnly {
 		return fmt.Sprintf("https://%s.%s.svc.cluster.local", c.CpName, c.CpNamespace)
 	}
 	// if an external URL (e.g. OCP route) is provided, just use it
@@ -174,18 +214,36 @@ func (c *ConfigGen) generateServerEndpoint() string {
 	return fmt.Sprintf("https://%s:%d", util.GenerateDevLocalDNSName(c.CpName, c.CpDomain), c.CpPort)
 }
 
+const clusterNameSuffix = "-cluster"
+
 func GenerateClusterName(cpName string) string {
-	return fmt.Sprintf("%s-cluster", cpName)
+	return cpName + clusterNameSuffix
 }
 
 func GenerateAuthInfoAdminName(cpName string) string {
 	return fmt.Sprintf("%s-admin", cpName)
 }
 
+// GenerateAuthInfoServiceAccountName returns the name of the authInfo using the token of the
+// service account serviceAccount of a control plane
+func GenerateAuthInfoServiceAccountName(cpName, serviceAccount string) string {
+	return fmt.Sprintf("%s-sa-%s", cpName, serviceAccount)
+}
+
 func GenerateContextName(cpName string) string {
 	return cpName
 }
 
+// ControlPlaneNameFromClusterName returns the name of the control plane whose cluster name,
+// as returned by GenerateClusterName, is clusterName, or "" if clusterName was not generated
+// for a control plane
+func ControlPlaneNameFromClusterName(clusterName string) string {
+	if !strings.HasSuffix(clusterName, clusterNameSuffix) {
+		return ""
+	}
+	return strings.TrimSuffix(clusterName, clusterNameSuffix)
+}
+
 func GenerateKubeconfigBytes(conf *ConfigGen) ([]byte, error) {
 	if err := conf.generateConfigCerts(); err != nil {
 		return nil, err
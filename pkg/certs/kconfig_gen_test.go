@@ -0,0 +1,19 @@
+package certs
+
+import "testing"
+
+func TestServerEndpoint(t *testing.T) {
+	conf := &ConfigGen{CpName: "cp1", CpNamespace: "cp1-system", CpDomain: "localtest.me", CpPort: 9443, Target: Admin}
+	if got, want := conf.ServerEndpoint(), "https://cp1.localtest.me:9443"; got != want {
+		t.Errorf("expected admin endpoint %s, got %s", want, got)
+	}
+	conf.Target = AdminInCluster
+	if got, want := conf.ServerEndpoint(), "https://cp1.cp1-system.svc.cluster.local"; got != want {
+		t.Errorf("expected in-cluster endpoint %s, got %s", want, got)
+	}
+	conf.Target = Admin
+	conf.InClusterOnly = true
+	if got, want := conf.ServerEndpoint(), "https://cp1.cp1-system.svc.cluster.local"; got != want {
+		t.Errorf("expected the admin endpoint of a control plane exposed in cluster only to be %s, got %s", want, got)
+	}
+}
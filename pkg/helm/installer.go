@@ -36,13 +36,16 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	"helm.sh/helm/v3/pkg/strvals"
+	"k8s.io/apimachinery/pkg/runtime"
 	clog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -52,11 +55,21 @@ type HelmHandler struct {
 	ChartName   string
 	ReleaseName string
 	Namespace   string
-	// version is only used for "classic" helm charts, not OCI
-	Version  string
-	Args     map[string]string
-	log      logr.Logger
-	settings *cli.EnvSettings
+	// Version is the chart version for "classic" helm charts, and the tag of OCI charts
+	// whose URL does not set one
+	Version string
+	Args    map[string]string
+	// Values are the base values of the chart, overridden by the set and set-string args.
+	// They must be JSON compatible, as decoded from YAML or JSON
+	Values map[string]interface{}
+	// Keyring is the path of a PGP keyring. When set, the chart provenance is
+	// verified against it and the chart is not installed if verification fails
+	Keyring string
+	// RegistryUsername and RegistryPassword are used to log in to the registry of OCI charts
+	RegistryUsername string
+	RegistryPassword string
+	log              logr.Logger
+	settings         *cli.EnvSettings
 }
 
 func Init(ctx context.Context, handler *HelmHandler) error {
@@ -203,6 +216,11 @@ func (h *HelmHandler) chartInstall() error {
 		client.ChartPathOptions.Version = h.Version
 	}
 
+	if h.Keyring != "" {
+		client.ChartPathOptions.Verify = true
+		client.ChartPathOptions.Keyring = h.Keyring
+	}
+
 	client.ReleaseName = h.ReleaseName
 	cp, err := client.ChartPathOptions.LocateChart(fmt.Sprintf("%s/%s", h.RepoName, h.ChartName), h.settings)
 	if err != nil {
@@ -212,17 +230,11 @@ func (h *HelmHandler) chartInstall() error {
 	h.log.V(3).Info("chart path", "path", cp)
 
 	p := getter.All(h.settings)
-	valueOpts := &values.Options{}
-	vals, err := valueOpts.MergeValues(p)
+	vals, err := h.mergeValues()
 	if err != nil {
 		return err
 	}
 
-	// Add args
-	if err := strvals.ParseInto(h.Args["set"], vals); err != nil {
-		return errors.Wrap(err, "failed parsing --set data")
-	}
-
 	chartRequested, err := loader.Load(cp)
 	if err != nil {
 		return err
@@ -263,6 +275,27 @@ func (h *HelmHandler) chartInstall() error {
 	return nil
 }
 
+// mergeValues returns the chart values from the "set" and "set-string" args, the latter
+// keeping values such as resource quantities as strings
+func (h *HelmHandler) mergeValues() (map[string]interface{}, error) {
+	valueOpts := &values.Options{}
+	vals, err := valueOpts.MergeValues(getter.All(h.settings))
+	if err != nil {
+		return nil, err
+	}
+	if h.Values != nil {
+		// copy the base values so that parsing the args does not modify them
+		vals = chartutil.CoalesceTables(vals, runtime.DeepCopyJSON(h.Values))
+	}
+	if err := strvals.ParseInto(h.Args["set"], vals); err != nil {
+		return nil, errors.Wrap(err, "failed parsing --set data")
+	}
+	if err := strvals.ParseIntoString(h.Args["set-string"], vals); err != nil {
+		return nil, errors.Wrap(err, "failed parsing --set-string data")
+	}
+	return vals, nil
+}
+
 func isChartInstallable(ch *chart.Chart) (bool, error) {
 	switch ch.Metadata.Type {
 	case "", "application":
@@ -276,10 +309,11 @@ func debug(format string, v ...interface{}) {
 	log.Output(2, fmt.Sprintf(format, v...))
 }
 
+// CheckStatus returns the release, or driver.ErrReleaseNotFound if it is not installed
 func (h *HelmHandler) CheckStatus() (*release.Release, error) {
 	actionConfig := new(action.Configuration)
 	if err := actionConfig.Init(h.settings.RESTClientGetter(), h.settings.Namespace(), os.Getenv("HELM_DRIVER"), debug); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	client := action.NewGet(actionConfig)
 	return client.Run(h.ReleaseName)
@@ -299,6 +333,23 @@ func (h *HelmHandler) IsDeployed() bool {
 	return false
 }
 
+// Uninstall removes the release. It does not fail if the release is already gone,
+// so that it can be retried after a partial cleanup
+func (h *HelmHandler) Uninstall() error {
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(h.settings.RESTClientGetter(), h.settings.Namespace(), os.Getenv("HELM_DRIVER"), debug); err != nil {
+		return err
+	}
+	client := action.NewUninstall(actionConfig)
+	if _, err := client.Run(h.ReleaseName); err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 func (h *HelmHandler) installOCIChart() error {
 	actionConfig := new(action.Configuration)
 	err := actionConfig.Init(h.settings.RESTClientGetter(), h.Namespace, os.Getenv("HELM_DRIVER"), func(format string, v ...interface{}) {
@@ -311,21 +362,24 @@ func (h *HelmHandler) installOCIChart() error {
 	client.Namespace = h.Namespace
 	client.ReleaseName = h.ReleaseName
 
-	get, err := getter.NewOCIGetter()
-	if err != nil {
-		return fmt.Errorf("error creating a new OCI getter: %s", err)
-	}
-
-	b, err := get.Get(h.URL)
-	if err != nil {
-		return fmt.Errorf("error downloading the OCI chart %s : %s", h.URL, err)
+	var data []byte
+	if h.Keyring != "" {
+		data, err = h.pullVerifiedOCIChart()
+		if err != nil {
+			return err
+		}
+	} else {
+		data, err = h.pullOCIChart()
+		if err != nil {
+			return err
+		}
 	}
 
 	tmpDir := os.TempDir()
 	defer os.Remove(tmpDir)
 
 	chartPath := filepath.Join(tmpDir, h.ChartName)
-	err = os.WriteFile(chartPath, b.Bytes(), 0644)
+	err = os.WriteFile(chartPath, data, 0644)
 	if err != nil {
 		return fmt.Errorf("error saving the OCI chart: %s", err)
 	}
@@ -335,15 +389,10 @@ func (h *HelmHandler) installOCIChart() error {
 		return fmt.Errorf("error loading the OCI chart: %s", err)
 	}
 
-	p := getter.All(h.settings)
-	valueOpts := &values.Options{}
-	vals, err := valueOpts.MergeValues(p)
+	vals, err := h.mergeValues()
 	if err != nil {
 		return err
 	}
-	if err := strvals.ParseInto(h.Args["set"], vals); err != nil {
-		return errors.Wrap(err, "failed parsing --set data")
-	}
 
 	_, err = client.Run(chart, vals)
 	if err != nil {
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// VerifyChartProvenance verifies the signature of the provenance file next to the chart
+// archive at chartPath, and the chart digest it contains, against the PGP keyring file
+func VerifyChartProvenance(chartPath, keyring string) error {
+	if _, err := downloader.VerifyChart(chartPath, keyring); err != nil {
+		return fmt.Errorf("chart provenance verification failed for %s: %s", filepath.Base(chartPath), err)
+	}
+	return nil
+}
+
+// pullVerifiedOCIChart pulls the chart and its provenance from the OCI registry and
+// returns the chart archive once its provenance is verified against the handler keyring
+func (h *HelmHandler) pullVerifiedOCIChart() ([]byte, error) {
+	client, cleanup, err := h.newRegistryClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	ref := h.ociChartRef()
+	result, err := client.Pull(ref, registry.PullOptWithProv(true))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading the OCI chart %s with its provenance: %w", ref, err)
+	}
+
+	dir, err := os.MkdirTemp("", "kflex-chart-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	chartPath := filepath.Join(dir, h.ChartName+".tgz")
+	if err := os.WriteFile(chartPath, result.Chart.Data, 0644); err != nil {
+		return nil, fmt.Errorf("error saving the OCI chart: %s", err)
+	}
+	if err := os.WriteFile(chartPath+".prov", result.Prov.Data, 0644); err != nil {
+		return nil, fmt.Errorf("error saving the OCI chart provenance: %s", err)
+	}
+	if err := VerifyChartProvenance(chartPath, h.Keyring); err != nil {
+		return nil, err
+	}
+	return result.Chart.Data, nil
+}
@@ -0,0 +1,83 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+func TestVerifyChartProvenance(t *testing.T) {
+	dir := t.TempDir()
+	chartPath, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test-chart", Version: "0.1.0"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("error packaging chart: %v", err)
+	}
+
+	trusted := generateTestEntity(t, "trusted")
+	keyring := filepath.Join(dir, "pubring.gpg")
+	f, err := os.Create(keyring)
+	if err != nil {
+		t.Fatalf("error creating keyring: %v", err)
+	}
+	if err := trusted.Serialize(f); err != nil {
+		t.Fatalf("error writing keyring: %v", err)
+	}
+	f.Close()
+
+	tests := []struct {
+		name    string
+		prov    func() string
+		wantErr bool
+	}{
+		{name: "valid", prov: func() string { return signTestChart(t, trusted, chartPath) }},
+		{name: "untrusted key", prov: func() string { return signTestChart(t, generateTestEntity(t, "untrusted"), chartPath) }, wantErr: true},
+		{name: "tampered digest", prov: func() string {
+			prov := signTestChart(t, trusted, chartPath)
+			i := strings.Index(prov, "sha256:") + len("sha256:")
+			return prov[:i] + "0000" + prov[i+4:]
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(chartPath+".prov", []byte(tt.prov()), 0644); err != nil {
+				t.Fatalf("error writing provenance file: %v", err)
+			}
+			err := VerifyChartProvenance(chartPath, keyring)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyChartProvenance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := os.Remove(chartPath + ".prov"); err != nil {
+		t.Fatalf("error removing provenance file: %v", err)
+	}
+	if err := VerifyChartProvenance(chartPath, keyring); err == nil {
+		t.Errorf("expected error for missing provenance file")
+	}
+}
+
+func generateTestEntity(t *testing.T, name string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity(name, "", name+"@kubeflex.test", nil)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	return entity
+}
+
+func signTestChart(t *testing.T, entity *openpgp.Entity, chartPath string) string {
+	signer := &provenance.Signatory{Entity: entity, KeyRing: openpgp.EntityList{entity}}
+	prov, err := signer.ClearSign(chartPath)
+	if err != nil {
+		t.Fatalf("error signing chart: %v", err)
+	}
+	return prov
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// newRegistryClient returns a client for the OCI registry of the chart, logged in with the
+// handler registry credentials when they are set. The credentials are stored in a temporary
+// file, removed by the returned cleanup function, so that they are not shared across control planes.
+func (h *HelmHandler) newRegistryClient() (*registry.Client, func(), error) {
+	if h.RegistryUsername == "" && h.RegistryPassword == "" {
+		client, err := registry.NewClient()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating registry client: %w", err)
+		}
+		return client, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "kflex-registry-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	client, err := registry.NewClient(registry.ClientOptCredentialsFile(path.Join(dir, "config.json")))
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error creating registry client: %w", err)
+	}
+	host := OCIRegistryHost(h.URL)
+	if err := client.Login(host, registry.LoginOptBasicAuth(h.RegistryUsername, h.RegistryPassword)); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error logging in to registry %s: %w", host, err)
+	}
+	return client, cleanup, nil
+}
+
+// pullOCIChart pulls the chart archive from the OCI registry
+func (h *HelmHandler) pullOCIChart() ([]byte, error) {
+	client, cleanup, err := h.newRegistryClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	ref := h.ociChartRef()
+	result, err := client.Pull(ref)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading the OCI chart %s: %w", ref, err)
+	}
+	return result.Chart.Data, nil
+}
+
+// ociChartRef returns the chart reference without the oci:// scheme, with the handler version
+// as tag when the URL does not set a tag or digest
+func (h *HelmHandler) ociChartRef() string {
+	ref := strings.TrimPrefix(h.URL, "oci://")
+	if h.Version == "" || strings.Contains(ref, "@") || strings.Contains(path.Base(ref), ":") {
+		return ref
+	}
+	return ref + ":" + h.Version
+}
+
+// OCIRegistryHost returns the registry host of an oci:// chart URL
+func OCIRegistryHost(url string) string {
+	host, _, _ := strings.Cut(strings.TrimPrefix(url, "oci://"), "/")
+	return host
+}
@@ -0,0 +1,26 @@
+package helm
+
+import "testing"
+
+func TestOCIChartRef(t *testing.T) {
+	tests := []struct {
+		url      string
+		version  string
+		expected string
+	}{
+		{url: "oci://registry.example.com/charts/vcluster", version: "0.16.4", expected: "registry.example.com/charts/vcluster:0.16.4"},
+		{url: "oci://registry.example.com/charts/vcluster:0.15.0", version: "0.16.4", expected: "registry.example.com/charts/vcluster:0.15.0"},
+		{url: "oci://registry.example.com:5000/charts/vcluster", version: "0.16.4", expected: "registry.example.com:5000/charts/vcluster:0.16.4"},
+		{url: "oci://registry.example.com/charts/vcluster@sha256:abc", version: "0.16.4", expected: "registry.example.com/charts/vcluster@sha256:abc"},
+		{url: "oci://registry.example.com/charts/vcluster", expected: "registry.example.com/charts/vcluster"},
+	}
+	for _, tt := range tests {
+		h := &HelmHandler{URL: tt.url, Version: tt.version}
+		if ref := h.ociChartRef(); ref != tt.expected {
+			t.Errorf("expected %s for %s, got %s", tt.expected, tt.url, ref)
+		}
+	}
+	if host := OCIRegistryHost("oci://registry.example.com:5000/charts/vcluster"); host != "registry.example.com:5000" {
+		t.Errorf("unexpected registry host %s", host)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Upgrade upgrades the release to the chart and values of the handler. The values replace
+// the ones of the previous revision instead of being merged with them, so that a value
+// removed from the args goes back to the chart default
+func (h *HelmHandler) Upgrade() error {
+	chartRequested, err := h.loadChart()
+	if err != nil {
+		return err
+	}
+	vals, err := h.mergeValues()
+	if err != nil {
+		return err
+	}
+
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(h.settings.RESTClientGetter(), h.Namespace, os.Getenv("HELM_DRIVER"), debug); err != nil {
+		return err
+	}
+	client := action.NewUpgrade(actionConfig)
+	client.Namespace = h.Namespace
+	client.ResetValues = true
+	release, err := client.Run(h.ReleaseName, chartRequested, vals)
+	if err != nil {
+		return fmt.Errorf("error upgrading release %s: %w", h.ReleaseName, err)
+	}
+	h.log.V(3).Info(release.Manifest)
+	return nil
+}
+
+// ValuesChanged reports whether the values of the handler, merged as for an install or upgrade,
+// differ from the ones the release was installed or last upgraded with
+func (h *HelmHandler) ValuesChanged(rel *release.Release) (bool, error) {
+	vals, err := h.mergeValues()
+	if err != nil {
+		return false, err
+	}
+	var installed map[string]interface{}
+	if rel != nil {
+		installed = rel.Config
+	}
+	// the release stores its values as JSON, so the parsed args are compared in their JSON form
+	// for numbers to have the same type
+	desired, err := normalizeValues(vals)
+	if err != nil {
+		return false, err
+	}
+	current, err := normalizeValues(installed)
+	if err != nil {
+		return false, err
+	}
+	return !reflect.DeepEqual(desired, current), nil
+}
+
+// VersionChanged reports whether the chart version of the release does not match the version
+// requested by the handler, so that changing the requested version upgrades or downgrades the
+// release. The requested version of a chart from a repo may be a version constraint
+func (h *HelmHandler) VersionChanged(rel *release.Release) bool {
+	requested := h.requestedVersion()
+	if requested == "" || rel == nil || rel.Chart == nil || rel.Chart.Metadata == nil {
+		return false
+	}
+	installed := rel.Chart.Metadata.Version
+	constraint, err := semver.NewConstraint(requested)
+	if err != nil {
+		return installed != requested
+	}
+	version, err := semver.NewVersion(installed)
+	if err != nil {
+		return installed != requested
+	}
+	return !constraint.Check(version)
+}
+
+// requestedVersion returns the tag of an OCI chart, or the version of a chart from a repo. It is
+// empty when no version is requested or the OCI chart is pinned by digest
+func (h *HelmHandler) requestedVersion() string {
+	if !isOCIURL(h.URL) {
+		return h.Version
+	}
+	ref := h.ociChartRef()
+	if strings.Contains(ref, "@") {
+		return ""
+	}
+	_, tag, _ := strings.Cut(path.Base(ref), ":")
+	// OCI tags cannot hold the + of semantic version build metadata, which helm replaces with _
+	return strings.ReplaceAll(tag, "_", "+")
+}
+
+// normalizeValues returns values decoded from their JSON form, with empty values as nil
+func normalizeValues(values map[string]interface{}) (map[string]interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// loadChart pulls the chart of the handler from its OCI registry or its repo, verifying its
+// provenance when a keyring is set
+func (h *HelmHandler) loadChart() (*chart.Chart, error) {
+	if isOCIURL(h.URL) {
+		var data []byte
+		var err error
+		if h.Keyring != "" {
+			data, err = h.pullVerifiedOCIChart()
+		} else {
+			data, err = h.pullOCIChart()
+		}
+		if err != nil {
+			return nil, err
+		}
+		chartRequested, err := loader.LoadArchive(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error loading the OCI chart: %s", err)
+		}
+		return chartRequested, nil
+	}
+
+	if err := h.repoAdd(); err != nil {
+		return nil, err
+	}
+	if err := h.repoUpdate(); err != nil {
+		return nil, err
+	}
+	pathOptions := action.ChartPathOptions{Version: h.Version}
+	if h.Keyring != "" {
+		pathOptions.Verify = true
+		pathOptions.Keyring = h.Keyring
+	}
+	cp, err := pathOptions.LocateChart(fmt.Sprintf("%s/%s", h.RepoName, h.ChartName), h.settings)
+	if err != nil {
+		return nil, err
+	}
+	chartRequested, err := loader.Load(cp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := isChartInstallable(chartRequested); err != nil {
+		return nil, err
+	}
+	return chartRequested, nil
+}
@@ -0,0 +1,96 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/strvals"
+)
+
+func TestValuesChanged(t *testing.T) {
+	// the release stores the values as JSON
+	releaseWithValues := func(set string) *release.Release {
+		vals, err := strvals.Parse(set)
+		if err != nil {
+			t.Fatalf("error parsing values: %v", err)
+		}
+		data, err := json.Marshal(vals)
+		if err != nil {
+			t.Fatalf("error marshalling values: %v", err)
+		}
+		config := map[string]interface{}{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			t.Fatalf("error unmarshalling values: %v", err)
+		}
+		return &release.Release{Config: config}
+	}
+	installed := `syncer.replicas=2,nodeSelector.kubernetes\.io/os=linux,securityContext.runAsUser=null`
+	tests := []struct {
+		name      string
+		rel       *release.Release
+		set       string
+		setString string
+		values    map[string]interface{}
+		expected  bool
+	}{
+		{name: "unchanged", rel: releaseWithValues(installed), set: installed},
+		{name: "changed user value", rel: releaseWithValues(installed), set: `syncer.replicas=3,nodeSelector.kubernetes\.io/os=linux,securityContext.runAsUser=null`, expected: true},
+		{name: "removed node selector", rel: releaseWithValues(installed), set: `syncer.replicas=2,securityContext.runAsUser=null`, expected: true},
+		{name: "added string value", rel: releaseWithValues(installed), set: installed, setString: "vcluster.resources.limits.memory=1Gi", expected: true},
+		{name: "base values", rel: releaseWithValues("a.b=1,syncer.replicas=2"), set: "syncer.replicas=2", values: map[string]interface{}{"a": map[string]interface{}{"b": float64(1)}}},
+		{name: "no values", rel: &release.Release{}},
+		{name: "no release", set: "syncer.replicas=2", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HelmHandler{Args: map[string]string{"set": tt.set, "set-string": tt.setString}, Values: tt.values}
+			if err := Init(context.Background(), h); err != nil {
+				t.Fatalf("Init returned error: %v", err)
+			}
+			changed, err := h.ValuesChanged(tt.rel)
+			if err != nil {
+				t.Fatalf("ValuesChanged returned error: %v", err)
+			}
+			if changed != tt.expected {
+				t.Errorf("expected changed %t, got %t", tt.expected, changed)
+			}
+		})
+	}
+}
+
+func TestVersionChanged(t *testing.T) {
+	releaseWithVersion := func(version string) *release.Release {
+		return &release.Release{Chart: &chart.Chart{Metadata: &chart.Metadata{Version: version}}}
+	}
+	tests := []struct {
+		name     string
+		url      string
+		version  string
+		rel      *release.Release
+		expected bool
+	}{
+		{name: "same version", url: "https://charts.loft.sh", version: "0.16.4", rel: releaseWithVersion("0.16.4")},
+		{name: "newer version", url: "https://charts.loft.sh", version: "0.19.0", rel: releaseWithVersion("0.16.4"), expected: true},
+		{name: "older version", url: "https://charts.loft.sh", version: "0.15.0", rel: releaseWithVersion("0.16.4"), expected: true},
+		{name: "matching constraint", url: "https://charts.loft.sh", version: "~0.16.0", rel: releaseWithVersion("0.16.4")},
+		{name: "constraint not matched", url: "https://charts.loft.sh", version: ">=0.17.0", rel: releaseWithVersion("0.16.4"), expected: true},
+		{name: "no requested version", url: "https://charts.loft.sh", rel: releaseWithVersion("0.16.4")},
+		{name: "OCI tag", url: "oci://registry.example.com/charts/vcluster:0.16.4", version: "0.19.0", rel: releaseWithVersion("0.16.4")},
+		{name: "OCI tag changed", url: "oci://registry.example.com/charts/vcluster:0.19.0", rel: releaseWithVersion("0.16.4"), expected: true},
+		{name: "OCI version as tag", url: "oci://registry.example.com/charts/vcluster", version: "0.19.0", rel: releaseWithVersion("0.16.4"), expected: true},
+		{name: "OCI tag with build metadata", url: "oci://registry.example.com/charts/vcluster:0.16.4_build1", rel: releaseWithVersion("0.16.4+build1")},
+		{name: "OCI digest", url: "oci://registry.example.com/charts/vcluster@sha256:abc", version: "0.19.0", rel: releaseWithVersion("0.16.4")},
+		{name: "no release", url: "https://charts.loft.sh", version: "0.16.4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HelmHandler{URL: tt.url, Version: tt.version}
+			if changed := h.VersionChanged(tt.rel); changed != tt.expected {
+				t.Errorf("expected changed %t, got %t", tt.expected, changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"os"
+	"os/user"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// AuditEntry describes a merge of control plane credentials into a kubeconfig.
+// It never contains the credentials themselves.
+type AuditEntry struct {
+	// User is the local OS user that performed the merge
+	User string
+	// ControlPlaneName is the name of the merged control plane
+	ControlPlaneName string
+	// ControlPlaneType is the type of the merged control plane
+	ControlPlaneType string
+	// ContextName is the name of the context merged into the kubeconfig
+	ContextName string
+	// PreviousContext is the current context of the kubeconfig before the merge
+	PreviousContext string
+	// Server is the API server endpoint resolved for the merged context
+	Server string
+	// Timestamp is the time of the merge
+	Timestamp time.Time
+	// Conflicts lists the entries that already existed in the kubeconfig with a different
+	// content, handled as selected by the conflict policy
+	Conflicts []MergeConflict
+	// InsecureSkipTLSVerify is true if the merged cluster skips the verification of the API
+	// server certificate
+	InsecureSkipTLSVerify bool
+}
+
+// AuditSink receives an entry for each successful merge
+type AuditSink func(entry AuditEntry)
+
+func newAuditEntry(config *clientcmdapi.Config, cpName, controlPlaneType, contextName string) *AuditEntry {
+	entry := &AuditEntry{
+		User:             currentUser(),
+		ControlPlaneName: cpName,
+		ControlPlaneType: controlPlaneType,
+		ContextName:      contextName,
+		Timestamp:        time.Now(),
+	}
+	if kctx, ok := config.Contexts[contextName]; ok {
+		if cluster, ok := config.Clusters[kctx.Cluster]; ok {
+			entry.Server = cluster.Server
+		}
+	}
+	return entry
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
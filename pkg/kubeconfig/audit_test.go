@@ -0,0 +1,80 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestLoadAndMergeAuditSink(t *testing.T) {
+	cpKonfig, err := clientcmd.Write(*generateTestConfig("cp1", "https://cp1.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.AdminConfSecret,
+			Namespace: util.GenerateNamespaceFromControlPlaneName("cp1"),
+		},
+		Data: map[string][]byte{util.KubeconfigSecretKeyDefault: cpKonfig},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/cp1-system/secrets/"+util.AdminConfSecret {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(secret)
+	}))
+	defer server.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("error creating clientset: %v", err)
+	}
+
+	var entries []AuditEntry
+	sink := func(entry AuditEntry) {
+		entries = append(entries, entry)
+	}
+
+	before := time.Now()
+	konfig := clientcmdapi.NewConfig()
+	err = LoadAndMergeNoWrite(context.Background(), *clientset, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, WithAuditSink(sink))
+	if err != nil {
+		t.Fatalf("LoadAndMergeNoWrite returned error: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(entries))
+	}
+	got := entries[0]
+	if got.ControlPlaneName != "cp1" || got.ControlPlaneType != string(tenancyv1alpha1.ControlPlaneTypeK8S) {
+		t.Errorf("unexpected control plane in audit entry: %+v", got)
+	}
+	if got.ContextName != "cp1" {
+		t.Errorf("expected context cp1, got %s", got.ContextName)
+	}
+	if got.Server != "https://cp1.localtest.me:9443" {
+		t.Errorf("expected resolved server https://cp1.localtest.me:9443, got %s", got.Server)
+	}
+	if got.Timestamp.Before(before) {
+		t.Errorf("expected timestamp after %v, got %v", before, got.Timestamp)
+	}
+	if _, ok := konfig.Contexts["cp1"]; !ok {
+		t.Errorf("expected context cp1 to be merged")
+	}
+}
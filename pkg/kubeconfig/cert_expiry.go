@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+const (
+	CertKindClientCertificate    = "client-certificate"
+	CertKindCertificateAuthority = "certificate-authority"
+)
+
+// DefaultCertExpiryThreshold is how close to its expiry a certificate is reported as
+// expiring soon by InspectKubeconfigCerts
+const DefaultCertExpiryThreshold = 30 * 24 * time.Hour
+
+// CertInfo describes a certificate embedded in a kubeconfig
+type CertInfo struct {
+	// Kind is CertKindClientCertificate for the certificate of an authInfo, or
+	// CertKindCertificateAuthority for a CA of a cluster
+	Kind string
+	// Name is the name of the authInfo or cluster embedding the certificate
+	Name      string
+	Subject   string
+	NotBefore time.Time
+	NotAfter  time.Time
+	// ExpiringSoon is true when the certificate expires within DefaultCertExpiryThreshold,
+	// or has already expired
+	ExpiringSoon bool
+}
+
+// ExpiresWithin returns true if the certificate expires within d from now
+func (c CertInfo) ExpiresWithin(d time.Duration) bool {
+	return time.Now().Add(d).After(c.NotAfter)
+}
+
+// InspectKubeconfigCerts decodes the client-certificate-data of every authInfo and the
+// certificate-authority-data of every cluster of cfg, and reports their validity period.
+// Certificates referenced by file path are not read. The result is sorted by kind and name,
+// with the certificates of a CA bundle in the bundle order.
+func InspectKubeconfigCerts(cfg *clientcmdapi.Config) ([]CertInfo, error) {
+	infos := []CertInfo{}
+	for name, authInfo := range cfg.AuthInfos {
+		if len(authInfo.ClientCertificateData) == 0 {
+			continue
+		}
+		certs, err := certutil.ParseCertsPEM(authInfo.ClientCertificateData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client-certificate-data of user %s: %w", name, err)
+		}
+		infos = append(infos, newCertInfos(CertKindClientCertificate, name, certs)...)
+	}
+	for name, cluster := range cfg.Clusters {
+		if len(cluster.CertificateAuthorityData) == 0 {
+			continue
+		}
+		certs, err := certutil.ParseCertsPEM(cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate-authority-data of cluster %s: %w", name, err)
+		}
+		infos = append(infos, newCertInfos(CertKindCertificateAuthority, name, certs)...)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Kind != infos[j].Kind {
+			return infos[i].Kind < infos[j].Kind
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+func newCertInfos(kind, name string, certs []*x509.Certificate) []CertInfo {
+	infos := make([]CertInfo, 0, len(certs))
+	for _, cert := range certs {
+		info := CertInfo{
+			Kind:      kind,
+			Name:      name,
+			Subject:   cert.Subject.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		}
+		info.ExpiringSoon = info.ExpiresWithin(DefaultCertExpiryThreshold)
+		infos = append(infos, info)
+	}
+	return infos
+}
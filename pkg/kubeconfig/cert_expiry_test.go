@@ -0,0 +1,58 @@
+package kubeconfig
+
+import (
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestInspectKubeconfigCerts(t *testing.T) {
+	now := time.Now()
+	config := clientcmdapi.NewConfig()
+	config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: generateTestClientCert(t, now.Add(7*24*time.Hour)),
+	}
+	config.AuthInfos[certs.GenerateAuthInfoAdminName("cp2")] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: generateTestClientCert(t, now.Add(365*24*time.Hour)),
+	}
+	config.AuthInfos["token-user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	// a CA bundle with two certificates
+	bundle := append(generateTestClientCert(t, now.Add(-time.Hour)), generateTestClientCert(t, now.Add(10*365*24*time.Hour))...)
+	config.Clusters[certs.GenerateClusterName("cp1")] = &clientcmdapi.Cluster{CertificateAuthorityData: bundle}
+
+	infos, err := InspectKubeconfigCerts(config)
+	if err != nil {
+		t.Fatalf("InspectKubeconfigCerts returned error: %v", err)
+	}
+	expected := []struct {
+		kind, name   string
+		expiringSoon bool
+	}{
+		{CertKindCertificateAuthority, certs.GenerateClusterName("cp1"), true},
+		{CertKindCertificateAuthority, certs.GenerateClusterName("cp1"), false},
+		{CertKindClientCertificate, certs.GenerateAuthInfoAdminName("cp1"), true},
+		{CertKindClientCertificate, certs.GenerateAuthInfoAdminName("cp2"), false},
+	}
+	if len(infos) != len(expected) {
+		t.Fatalf("expected %d certificates, got %d: %v", len(expected), len(infos), infos)
+	}
+	for i, e := range expected {
+		if infos[i].Kind != e.kind || infos[i].Name != e.name || infos[i].ExpiringSoon != e.expiringSoon {
+			t.Errorf("certificate %d: expected %s %s expiring soon %t, got %+v", i, e.kind, e.name, e.expiringSoon, infos[i])
+		}
+	}
+	if !infos[0].ExpiresWithin(0) {
+		t.Errorf("expected the first CA of the bundle to be expired")
+	}
+	if infos[2].Subject != "CN=kubernetes-admin" {
+		t.Errorf("unexpected subject %s", infos[2].Subject)
+	}
+
+	config.AuthInfos["invalid"] = &clientcmdapi.AuthInfo{ClientCertificateData: []byte("not a certificate")}
+	if _, err := InspectKubeconfigCerts(config); err == nil {
+		t.Errorf("expected error for invalid certificate data")
+	}
+}
@@ -19,8 +19,12 @@ package kubeconfig
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -31,19 +35,79 @@ import (
 const (
 	ConfigExtensionName = "kflex-config-extension-name"
 	InitialContextName  = "kflex-initial-ctx-name"
+	PreviousContextName = "kflex-previous-ctx-name"
 )
 
+// ConflictPolicy selects how a merge handles kubeconfig entries that already exist with a
+// different content than the entries of the merged control plane
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyOverwrite replaces the existing entries, which is the default
+	ConflictPolicyOverwrite ConflictPolicy = "overwrite"
+	// ConflictPolicySkip keeps the existing entries
+	ConflictPolicySkip ConflictPolicy = "skip"
+	// ConflictPolicyFail fails the merge without changing the kubeconfig
+	ConflictPolicyFail ConflictPolicy = "fail"
+)
+
+// MergeConflict identifies a kubeconfig entry that already existed with a different content
+type MergeConflict struct {
+	// Kind is cluster, authInfo or context
+	Kind string
+	// Name is the name of the entry
+	Name string
+}
+
+// MergeConflictError is returned by a merge with ConflictPolicyFail when entries conflict
+type MergeConflictError struct {
+	Conflicts []MergeConflict
+}
+
+func (e *MergeConflictError) Error() string {
+	names := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		names = append(names, c.Kind+" "+c.Name)
+	}
+	return fmt.Sprintf("kubeconfig entries already exist with a different content: %s", strings.Join(names, ", "))
+}
+
 func merge(existing, new *clientcmdapi.Config) error {
+	_, err := mergeWithPolicy(existing, new, ConflictPolicyOverwrite)
+	return err
+}
+
+// mergeWithPolicy merges the entries of new into existing and returns the entries that
+// already existed with a different content, handled as selected by policy. With
+// ConflictPolicySkip the current context is only switched if the context of new was merged.
+func mergeWithPolicy(existing, new *clientcmdapi.Config, policy ConflictPolicy) ([]MergeConflict, error) {
+	conflicts := findConflicts(existing, new)
+	if len(conflicts) > 0 && policy == ConflictPolicyFail {
+		return conflicts, &MergeConflictError{Conflicts: conflicts}
+	}
+	skip := map[MergeConflict]bool{}
+	if policy == ConflictPolicySkip {
+		for _, c := range conflicts {
+			skip[c] = true
+		}
+	}
+
 	for k, v := range new.Clusters {
-		existing.Clusters[k] = v
+		if !skip[MergeConflict{Kind: conflictKindCluster, Name: k}] {
+			existing.Clusters[k] = v
+		}
 	}
 
 	for k, v := range new.AuthInfos {
-		existing.AuthInfos[k] = v
+		if !skip[MergeConflict{Kind: conflictKindAuthInfo, Name: k}] {
+			existing.AuthInfos[k] = v
+		}
 	}
 
 	for k, v := range new.Contexts {
-		existing.Contexts[k] = v
+		if !skip[MergeConflict{Kind: conflictKindContext, Name: k}] {
+			existing.Contexts[k] = v
+		}
 	}
 
 	if !IsInitialConfigSet(existing) {
@@ -51,17 +115,77 @@ func merge(existing, new *clientcmdapi.Config) error {
 	}
 
 	// set the current context to the nex context
-	existing.CurrentContext = new.CurrentContext
-	return nil
+	if !skip[MergeConflict{Kind: conflictKindContext, Name: new.CurrentContext}] {
+		existing.CurrentContext = new.CurrentContext
+	}
+	return conflicts, nil
 }
 
+const (
+	conflictKindCluster  = "cluster"
+	conflictKindAuthInfo = "authInfo"
+	conflictKindContext  = "context"
+)
+
+// findConflicts returns the entries of new that exist in existing with a different content,
+// sorted by kind and name. The file an entry was loaded from is not compared.
+func findConflicts(existing, new *clientcmdapi.Config) []MergeConflict {
+	conflicts := []MergeConflict{}
+	for k, v := range new.Clusters {
+		if old, ok := existing.Clusters[k]; ok {
+			o, n := *old, *v
+			o.LocationOfOrigin, n.LocationOfOrigin = "", ""
+			if !apiequality.Semantic.DeepEqual(o, n) {
+				conflicts = append(conflicts, MergeConflict{Kind: conflictKindCluster, Name: k})
+			}
+		}
+	}
+	for k, v := range new.AuthInfos {
+		if old, ok := existing.AuthInfos[k]; ok {
+			o, n := *old, *v
+			o.LocationOfOrigin, n.LocationOfOrigin = "", ""
+			if !apiequality.Semantic.DeepEqual(o, n) {
+				conflicts = append(conflicts, MergeConflict{Kind: conflictKindAuthInfo, Name: k})
+			}
+		}
+	}
+	for k, v := range new.Contexts {
+		if old, ok := existing.Contexts[k]; ok {
+			o, n := *old, *v
+			o.LocationOfOrigin, n.LocationOfOrigin = "", ""
+			if !apiequality.Semantic.DeepEqual(o, n) {
+				conflicts = append(conflicts, MergeConflict{Kind: conflictKindContext, Name: k})
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return conflicts
+}
+
+// SwitchContext sets the current context to the context of the control plane. A context
+// renamed with a context name override is found by the cluster and authInfo it uses.
 func SwitchContext(config *clientcmdapi.Config, cpName string) error {
 	ctxName := certs.GenerateContextName(cpName)
-	_, ok := config.Contexts[ctxName]
-	if !ok {
+	if _, ok := config.Contexts[ctxName]; ok {
+		config.CurrentContext = ctxName
+		return nil
+	}
+	names := make([]string, 0, len(config.Contexts))
+	for n, kctx := range config.Contexts {
+		if isControlPlaneContext(kctx, cpName) {
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 {
 		return fmt.Errorf("context %s not found", ctxName)
 	}
-	config.CurrentContext = ctxName
+	sort.Strings(names)
+	config.CurrentContext = names[0]
 	return nil
 }
 
@@ -91,6 +215,56 @@ func DeleteContext(config *clientcmdapi.Config, cpName string) error {
 	return nil
 }
 
+// CloneContext adds a context for newName that reuses the CA and credentials of the
+// kubeflex context for cpName but points to server, keeping the original context in place
+func CloneContext(config *clientcmdapi.Config, cpName, newName, server string) error {
+	ctxName, ok := findControlPlaneContext(config, cpName)
+	if !ok {
+		return fmt.Errorf("kubeflex context %s not found for control plane %s", certs.GenerateContextName(cpName), cpName)
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("invalid server URL %s: %s", server, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid server URL %s: must be an https URL with a host", server)
+	}
+
+	newCtxName := certs.GenerateContextName(newName)
+	newClusterName := certs.GenerateClusterName(newName)
+	newAuthName := certs.GenerateAuthInfoAdminName(newName)
+	if _, ok := config.Contexts[newCtxName]; ok {
+		return fmt.Errorf("context %s already exists", newCtxName)
+	}
+	if _, ok := config.Clusters[newClusterName]; ok {
+		return fmt.Errorf("cluster %s already exists", newClusterName)
+	}
+	if _, ok := config.AuthInfos[newAuthName]; ok {
+		return fmt.Errorf("authInfo %s already exists", newAuthName)
+	}
+
+	kctx := config.Contexts[ctxName]
+	cluster, ok := config.Clusters[kctx.Cluster]
+	if !ok {
+		return fmt.Errorf("cluster %s not found for control plane %s", kctx.Cluster, cpName)
+	}
+	authInfo, ok := config.AuthInfos[kctx.AuthInfo]
+	if !ok {
+		return fmt.Errorf("authInfo %s not found for control plane %s", kctx.AuthInfo, cpName)
+	}
+
+	newCluster := cluster.DeepCopy()
+	newCluster.Server = server
+	config.Clusters[newClusterName] = newCluster
+	config.AuthInfos[newAuthName] = authInfo.DeepCopy()
+	newCtx := kctx.DeepCopy()
+	newCtx.Cluster = newClusterName
+	newCtx.AuthInfo = newAuthName
+	config.Contexts[newCtxName] = newCtx
+	return nil
+}
+
 func SwitchToInitialContext(config *clientcmdapi.Config, removeExtension bool) error {
 	if !IsInitialConfigSet(config) {
 		return nil
@@ -131,6 +305,57 @@ func saveInitialContextName(config *clientcmdapi.Config) {
 	}
 }
 
+// RecordPreviousContext saves previous in the kubeflex extension of config as the context to
+// go back to with RestorePreviousContext. If no initial context is recorded yet, previous is
+// also recorded as the initial context.
+func RecordPreviousContext(config *clientcmdapi.Config, previous string) error {
+	if previous == "" {
+		return nil
+	}
+	if !IsInitialConfigSet(config) {
+		current := config.CurrentContext
+		config.CurrentContext = previous
+		saveInitialContextName(config)
+		config.CurrentContext = current
+	}
+	cm, err := unMarshallCM(config.Preferences.Extensions[ConfigExtensionName])
+	if err != nil {
+		return fmt.Errorf("error unmarshaling config map %s", err)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[PreviousContextName] = previous
+	config.Preferences.Extensions[ConfigExtensionName] = cm
+	return nil
+}
+
+// GetPreviousContext returns the context recorded by RecordPreviousContext, or an empty
+// string if none is recorded
+func GetPreviousContext(config *clientcmdapi.Config) string {
+	if !IsInitialConfigSet(config) {
+		return ""
+	}
+	cm, err := unMarshallCM(config.Preferences.Extensions[ConfigExtensionName])
+	if err != nil {
+		return ""
+	}
+	return cm.Data[PreviousContextName]
+}
+
+// GetInitialContext returns the context recorded as the initial context, usually the one of
+// the hosting cluster, or an empty string if none is recorded
+func GetInitialContext(config *clientcmdapi.Config) string {
+	if !IsInitialConfigSet(config) {
+		return ""
+	}
+	cm, err := unMarshallCM(config.Preferences.Extensions[ConfigExtensionName])
+	if err != nil {
+		return ""
+	}
+	return cm.Data[InitialContextName]
+}
+
 func IsInitialConfigSet(config *clientcmdapi.Config) bool {
 	if config.Preferences.Extensions != nil {
 		_, ok := config.Preferences.Extensions[ConfigExtensionName]
@@ -0,0 +1,110 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestCloneContext(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+
+	if err := CloneContext(config, "cp1", "cp1-green", "https://cp1-green.example.com:443"); err != nil {
+		t.Fatalf("CloneContext returned error: %v", err)
+	}
+
+	kctx, ok := config.Contexts[certs.GenerateContextName("cp1-green")]
+	if !ok {
+		t.Fatalf("expected cloned context cp1-green")
+	}
+	cluster := config.Clusters[kctx.Cluster]
+	if cluster == nil || cluster.Server != "https://cp1-green.example.com:443" {
+		t.Errorf("expected cloned cluster with the new server, got %+v", cluster)
+	}
+	if !bytes.Equal(cluster.CertificateAuthorityData, []byte("ca-cp1")) {
+		t.Errorf("expected cloned cluster to reuse the CA")
+	}
+	authInfo := config.AuthInfos[kctx.AuthInfo]
+	if authInfo == nil || !bytes.Equal(authInfo.ClientCertificateData, []byte("cert-cp1")) {
+		t.Errorf("expected cloned context to reuse the credentials")
+	}
+	if !IsKubeflexContext(config, "cp1-green") {
+		t.Errorf("expected cloned context to be a kubeflex context")
+	}
+
+	// the original context is kept for rollback
+	if config.Clusters[certs.GenerateClusterName("cp1")].Server != "https://cp1.localtest.me:9443" {
+		t.Errorf("expected original context to be unchanged")
+	}
+	if config.CurrentContext != "cp1" {
+		t.Errorf("expected current context to be unchanged, got %s", config.CurrentContext)
+	}
+
+	if err := CloneContext(config, "cp1", "cp1-green", "https://other.example.com"); err == nil {
+		t.Errorf("expected error for name collision")
+	}
+	if err := CloneContext(config, "cp1", "cp1-blue", "http://cp1.example.com"); err == nil {
+		t.Errorf("expected error for non https server URL")
+	}
+	if err := CloneContext(config, "cp1", "cp1-blue", "://bad"); err == nil {
+		t.Errorf("expected error for invalid server URL")
+	}
+	if err := CloneContext(config, "missing", "cp1-blue", "https://cp1.example.com"); err == nil {
+		t.Errorf("expected error for missing context")
+	}
+}
+
+func TestMergeWithPolicy(t *testing.T) {
+	// a context created by hand with the name of the control plane
+	newExisting := func() *clientcmdapi.Config {
+		config := clientcmdapi.NewConfig()
+		config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://prod.example.com"}
+		config.AuthInfos["prod"] = &clientcmdapi.AuthInfo{Token: "prod-token"}
+		config.Contexts[certs.GenerateContextName("cp1")] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: "prod"}
+		config.CurrentContext = "prod"
+		return config
+	}
+	cpKonfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	expected := []MergeConflict{{Kind: "context", Name: certs.GenerateContextName("cp1")}}
+
+	config := newExisting()
+	conflicts, err := mergeWithPolicy(config, cpKonfig, ConflictPolicyOverwrite)
+	if err != nil || !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("expected conflicts %v, got %v, err %v", expected, conflicts, err)
+	}
+	if config.Contexts[certs.GenerateContextName("cp1")].Cluster != certs.GenerateClusterName("cp1") {
+		t.Errorf("expected context to be overwritten")
+	}
+
+	config = newExisting()
+	if _, err := mergeWithPolicy(config, cpKonfig, ConflictPolicySkip); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Contexts[certs.GenerateContextName("cp1")].Cluster != "prod" || config.CurrentContext != "prod" {
+		t.Errorf("expected existing context and current context to be kept, got %+v", config)
+	}
+	if _, ok := config.Clusters[certs.GenerateClusterName("cp1")]; !ok {
+		t.Errorf("expected non conflicting entries to be merged")
+	}
+
+	config = newExisting()
+	_, err = mergeWithPolicy(config, cpKonfig, ConflictPolicyFail)
+	var conflictErr *MergeConflictError
+	if !errors.As(err, &conflictErr) || !reflect.DeepEqual(conflictErr.Conflicts, expected) {
+		t.Errorf("expected MergeConflictError with %v, got %v", expected, err)
+	}
+	if _, ok := config.Clusters[certs.GenerateClusterName("cp1")]; ok {
+		t.Errorf("expected kubeconfig to be left unchanged")
+	}
+
+	// merging the same entries again is not a conflict
+	conflicts, err = mergeWithPolicy(cpKonfig.DeepCopy(), cpKonfig, ConflictPolicyFail)
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("expected no conflicts for identical entries, got %v, err %v", conflicts, err)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+// ControlPlaneContext describes a context merged into the kubeconfig for a control plane
+type ControlPlaneContext struct {
+	// ContextName is the name of the context in the kubeconfig
+	ContextName string
+	// ControlPlaneName is the name of the control plane the context belongs to
+	ControlPlaneName string
+	// AuthInfo is the name of the authInfo the context uses
+	AuthInfo string
+	// Internal is true for the context of the in-cluster endpoint of the control plane,
+	// merged with WithInternalContext
+	Internal bool
+	// Current is true if the context is the current context
+	Current bool
+}
+
+// ListControlPlaneContexts returns the contexts of the default kubeconfig that kubeflex
+// merged for control planes, sorted by name
+func ListControlPlaneContexts(ctx context.Context) ([]ControlPlaneContext, error) {
+	konfig, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return listControlPlaneContexts(konfig), nil
+}
+
+// listControlPlaneContexts returns the contexts of config that kubeflex merged for a control
+// plane, sorted by name. Internal contexts are named after their control plane with
+// InternalContextSuffix, and are reported with the name of the control plane. Contexts of a
+// control plane whose name ends with InternalContextSuffix are reported as its own.
+func listControlPlaneContexts(config *clientcmdapi.Config) []ControlPlaneContext {
+	contexts := []ControlPlaneContext{}
+	for name, kctx := range config.Contexts {
+		cpName, ok := controlPlaneOfContext(kctx)
+		if !ok {
+			continue
+		}
+		internal := strings.HasSuffix(cpName, InternalContextSuffix) && isInternalContextOf(config, strings.TrimSuffix(cpName, InternalContextSuffix))
+		if internal {
+			cpName = strings.TrimSuffix(cpName, InternalContextSuffix)
+		}
+		contexts = append(contexts, ControlPlaneContext{
+			ContextName:      name,
+			ControlPlaneName: cpName,
+			AuthInfo:         kctx.AuthInfo,
+			Internal:         internal,
+			Current:          name == config.CurrentContext,
+		})
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].ContextName < contexts[j].ContextName
+	})
+	return contexts
+}
+
+// controlPlaneOfContext returns the name of the control plane the context belongs to, and
+// false if the context was not merged by kubeflex. Contexts are identified by the cluster and
+// authInfo generated for the control plane rather than by their name, so that contexts renamed
+// with a context name override are found and user contexts that happen to share the name of
+// a control plane are left out.
+func controlPlaneOfContext(kctx *clientcmdapi.Context) (string, bool) {
+	cpName := certs.ControlPlaneNameFromClusterName(kctx.Cluster)
+	if cpName == "" || !isControlPlaneContext(kctx, cpName) {
+		return "", false
+	}
+	return cpName, true
+}
+
+// findControlPlaneContext returns the name of the context of the control plane: the context
+// with the generated name if it belongs to the control plane, or else the first context that
+// uses the cluster and authInfo of the control plane
+func findControlPlaneContext(config *clientcmdapi.Config, cpName string) (string, bool) {
+	ctxName := certs.GenerateContextName(cpName)
+	if kctx, ok := config.Contexts[ctxName]; ok && isControlPlaneContext(kctx, cpName) {
+		return ctxName, true
+	}
+	for _, c := range listControlPlaneContexts(config) {
+		if c.ControlPlaneName == cpName && !c.Internal {
+			return c.ContextName, true
+		}
+	}
+	return "", false
+}
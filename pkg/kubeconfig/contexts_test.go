@@ -0,0 +1,88 @@
+package kubeconfig
+
+import (
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestListControlPlaneContexts(t *testing.T) {
+	config := generateTestConfig("cp2", "https://cp2.localtest.me:9443")
+	if err := merge(config, generateTestConfig("cp1", "https://cp1.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	config.CurrentContext = certs.GenerateContextName("cp2")
+	// the context of the hosting cluster is not managed by kubeflex
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+
+	contexts := listControlPlaneContexts(config)
+	if len(contexts) != 2 {
+		t.Fatalf("expected 2 control plane contexts, got %+v", contexts)
+	}
+	if contexts[0].ControlPlaneName != "cp1" || contexts[0].AuthInfo != certs.GenerateAuthInfoAdminName("cp1") || contexts[0].Current {
+		t.Errorf("unexpected first context %+v", contexts[0])
+	}
+	if contexts[1].ControlPlaneName != "cp2" || !contexts[1].Current {
+		t.Errorf("unexpected second context %+v", contexts[1])
+	}
+}
+
+func TestIsKubeflexContextMatchesList(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	// a user context named after the control plane that uses other entries
+	config.Clusters["other"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.Contexts["cp2"] = &clientcmdapi.Context{Cluster: "other", AuthInfo: certs.GenerateAuthInfoAdminName("cp2")}
+
+	names := GetKubeflexContextNames(config)
+	if len(names) != 1 || names[0] != "cp1" {
+		t.Fatalf("expected only the cp1 context, got %v", names)
+	}
+	for name := range config.Contexts {
+		_, listed := controlPlaneOfContext(config.Contexts[name])
+		if IsKubeflexContext(config, name) != listed {
+			t.Errorf("IsKubeflexContext and listControlPlaneContexts disagree on context %s", name)
+		}
+	}
+}
+
+func TestListControlPlaneContextsRenamed(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	// the context was renamed with spec.contextName
+	renameKey(config, config.Contexts, certs.GenerateContextName("cp1"), "prod")
+
+	contexts := listControlPlaneContexts(config)
+	if len(contexts) != 1 || contexts[0].ContextName != "prod" || contexts[0].ControlPlaneName != "cp1" {
+		t.Fatalf("expected the renamed context of cp1, got %+v", contexts)
+	}
+	if !IsKubeflexContext(config, "prod") {
+		t.Errorf("expected the renamed context to be a kubeflex context")
+	}
+	if name, ok := findControlPlaneContext(config, "cp1"); !ok || name != "prod" {
+		t.Errorf("expected to find the renamed context prod for cp1, got %q", name)
+	}
+	if _, err := ExportKubeconfig(config, "cp1"); err != nil {
+		t.Errorf("expected the renamed context to be exported, got %v", err)
+	}
+}
+
+func TestListControlPlaneContextsInternal(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	if err := merge(config, generateTestConfig("cp1"+InternalContextSuffix, "https://cp1.cp1-system.svc.cluster.local")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+
+	contexts := listControlPlaneContexts(config)
+	if len(contexts) != 2 {
+		t.Fatalf("expected 2 control plane contexts, got %+v", contexts)
+	}
+	if c := contexts[1]; c.ContextName != "cp1"+InternalContextSuffix || c.ControlPlaneName != "cp1" || !c.Internal {
+		t.Errorf("expected the internal context of cp1, got %+v", c)
+	}
+	if names := GetKubeflexContextNames(config); len(names) != 1 || names[0] != "cp1" {
+		t.Errorf("expected internal contexts to be left out, got %v", names)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+var (
+	// ErrCredentialsManaged is returned for credentials obtained through an exec plugin or
+	// an auth provider, such as OIDC, which refresh them on their own
+	ErrCredentialsManaged = errors.New("credentials are managed by an exec plugin or auth provider")
+	// ErrCredentialExpiryUnknown is returned for credentials without an expiry that can
+	// be inspected, such as opaque tokens and basic auth
+	ErrCredentialExpiryUnknown = errors.New("credential expiry is unknown")
+)
+
+// ContextsNeedingRefresh returns the sorted names of the kubeflex contexts in the default
+// kubeconfig whose embedded client certificate or token has expired or expires within the
+// given window. Contexts with managed credentials or an unknown expiry are not returned;
+// CredentialExpiry reports why for a given context.
+func ContextsNeedingRefresh(ctx context.Context, within time.Duration) ([]string, error) {
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return contextsNeedingRefresh(config, within, time.Now()), nil
+}
+
+func contextsNeedingRefresh(config *clientcmdapi.Config, within time.Duration, now time.Time) []string {
+	names := []string{}
+	for _, name := range GetKubeflexContextNames(config) {
+		expiry, err := CredentialExpiry(config, name)
+		if err != nil {
+			continue
+		}
+		if !expiry.After(now.Add(within)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CredentialExpiry returns when the credentials of a context expire: the NotAfter of a
+// client certificate, or the exp claim of a JWT bearer token. It returns ErrCredentialsManaged
+// for exec and auth-provider credentials and ErrCredentialExpiryUnknown when the expiry cannot
+// be inspected.
+func CredentialExpiry(config *clientcmdapi.Config, contextName string) (time.Time, error) {
+	kctx, ok := config.Contexts[contextName]
+	if !ok {
+		return time.Time{}, fmt.Errorf("context %s not found", contextName)
+	}
+	authInfo, ok := config.AuthInfos[kctx.AuthInfo]
+	if !ok {
+		return time.Time{}, fmt.Errorf("authInfo %s not found for context %s", kctx.AuthInfo, contextName)
+	}
+
+	switch GetAuthInfoType(authInfo) {
+	case AuthTypeExec, AuthTypeAuthProvider:
+		return time.Time{}, ErrCredentialsManaged
+	case AuthTypeClientCert:
+		data := authInfo.ClientCertificateData
+		if len(data) == 0 {
+			var err error
+			if data, err = os.ReadFile(authInfo.ClientCertificate); err != nil {
+				return time.Time{}, err
+			}
+		}
+		crts, err := certutil.ParseCertsPEM(data)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error parsing client certificate for context %s: %s", contextName, err)
+		}
+		return crts[0].NotAfter, nil
+	case AuthTypeToken:
+		token := authInfo.Token
+		if token == "" {
+			data, err := os.ReadFile(authInfo.TokenFile)
+			if err != nil {
+				return time.Time{}, err
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		return tokenExpiry(token)
+	default:
+		return time.Time{}, ErrCredentialExpiryUnknown
+	}
+}
+
+// tokenExpiry returns the exp claim of a JWT, without verifying its signature
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, ErrCredentialExpiryUnknown
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, ErrCredentialExpiryUnknown
+	}
+	claims := struct {
+		Exp *int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, ErrCredentialExpiryUnknown
+	}
+	return time.Unix(*claims.Exp, 0), nil
+}
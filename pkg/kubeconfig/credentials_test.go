@@ -0,0 +1,88 @@
+package kubeconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestContextsNeedingRefresh(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	config := clientcmdapi.NewConfig()
+	authInfos := map[string]*clientcmdapi.AuthInfo{
+		// client certificate expiring within the window
+		"cp-expiring": {ClientCertificateData: generateTestClientCert(t, now.Add(time.Hour))},
+		// client certificate valid well beyond the window
+		"cp-valid": {ClientCertificateData: generateTestClientCert(t, now.Add(30*24*time.Hour))},
+		// JWT bearer token already expired
+		"cp-expired": {Token: generateTestJWT(now.Add(-time.Minute))},
+		// JWT bearer token valid beyond the window
+		"cp-token": {Token: generateTestJWT(now.Add(48 * time.Hour))},
+		// credentials refreshed by an exec plugin
+		"cp-exec": {Exec: &clientcmdapi.ExecConfig{Command: "kubelogin"}},
+		// opaque token without an inspectable expiry
+		"cp-opaque": {Token: "opaque-token"},
+	}
+	for cpName, authInfo := range authInfos {
+		cpConfig := generateTestConfig(cpName, fmt.Sprintf("https://%s.localtest.me:9443", cpName))
+		cpConfig.AuthInfos[certs.GenerateAuthInfoAdminName(cpName)] = authInfo
+		if err := merge(config, cpConfig); err != nil {
+			t.Fatalf("error merging config: %v", err)
+		}
+	}
+
+	names := contextsNeedingRefresh(config, 24*time.Hour, now)
+	if expected := []string{"cp-expired", "cp-expiring"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	if _, err := CredentialExpiry(config, "cp-exec"); !errors.Is(err, ErrCredentialsManaged) {
+		t.Errorf("expected exec credentials to be reported as managed, got %v", err)
+	}
+	if _, err := CredentialExpiry(config, "cp-opaque"); !errors.Is(err, ErrCredentialExpiryUnknown) {
+		t.Errorf("expected opaque token expiry to be unknown, got %v", err)
+	}
+	expiry, err := CredentialExpiry(config, "cp-token")
+	if err != nil || !expiry.Equal(now.Add(48*time.Hour)) {
+		t.Errorf("expected token expiry %s, got %s (%v)", now.Add(48*time.Hour), expiry, err)
+	}
+}
+
+func generateTestClientCert(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kubernetes-admin"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func generateTestJWT(exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"admin","exp":%d}`, exp.Unix())))
+	return header + "." + payload + ".signature"
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DiscoverAPIs returns the API groups served by a control plane, using a client built
+// from its kubeconfig secret in the hosting cluster
+func DiscoverAPIs(ctx context.Context, hostClient kubernetes.Interface, name, controlPlaneType string) (*metav1.APIGroupList, error) {
+	dc, err := discoveryClientForControlPlane(ctx, hostClient, name, controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	return dc.ServerGroups()
+}
+
+// DiscoverOpenAPI returns the OpenAPI v2 document served by a control plane, using a client
+// built from its kubeconfig secret in the hosting cluster
+func DiscoverOpenAPI(ctx context.Context, hostClient kubernetes.Interface, name, controlPlaneType string) (*openapi_v2.Document, error) {
+	dc, err := discoveryClientForControlPlane(ctx, hostClient, name, controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	return dc.OpenAPISchema()
+}
+
+// discoveryClientForControlPlane returns a discovery client for a control plane, or an error
+// if the control plane kubeconfig has not been generated yet or its API server is not ready
+func discoveryClientForControlPlane(ctx context.Context, hostClient kubernetes.Interface, name, controlPlaneType string) (*discovery.DiscoveryClient, error) {
+	restConfig, err := RestConfigForControlPlane(ctx, hostClient, name, controlPlaneType)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("control plane %s is not ready: kubeconfig secret not found", name)
+		}
+		return nil, err
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := dc.RESTClient().Get().AbsPath("/readyz").Do(ctx).Error(); err != nil {
+		return nil, fmt.Errorf("control plane %s is not ready: %s", name, err)
+	}
+	return dc, nil
+}
@@ -0,0 +1,97 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestDiscoverAPIs(t *testing.T) {
+	ready := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body interface{}
+		switch r.URL.Path {
+		case "/readyz":
+			if !ready {
+				http.Error(w, "etcd not ready", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("ok"))
+			return
+		case "/api":
+			body = metav1.APIVersions{
+				TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+				Versions: []string{"v1"},
+			}
+		case "/apis":
+			body = metav1.APIGroupList{
+				TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+				Groups: []metav1.APIGroup{{
+					Name:             "apps",
+					Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "apps/v1", Version: "v1"}},
+					PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "apps/v1", Version: "v1"},
+				}},
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	konfig := generateTestConfig("cp1", server.URL)
+	konfig.Clusters[certs.GenerateClusterName("cp1")].CertificateAuthorityData = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	konfig.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")] = &clientcmdapi.AuthInfo{Token: "token-cp1"}
+	data, err := clientcmd.Write(*konfig)
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.AdminConfSecret,
+			Namespace: util.GenerateNamespaceFromControlPlaneName("cp1"),
+		},
+		Data: map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	groups, err := DiscoverAPIs(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	if err != nil {
+		t.Fatalf("DiscoverAPIs returned error: %v", err)
+	}
+	names := []string{}
+	for _, g := range groups.Groups {
+		names = append(names, g.Name)
+	}
+	// the legacy core group is listed first
+	if strings.Join(names, ",") != ",apps" {
+		t.Errorf("expected the core and apps groups, got %v", names)
+	}
+
+	ready = false
+	_, err = DiscoverAPIs(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	if err == nil || !strings.Contains(err.Error(), "control plane cp1 is not ready") {
+		t.Errorf("expected a not ready error, got %v", err)
+	}
+
+	_, err = DiscoverAPIs(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	if err == nil || !strings.Contains(err.Error(), "control plane cp2 is not ready") {
+		t.Errorf("expected a not ready error for a missing kubeconfig secret, got %v", err)
+	}
+}
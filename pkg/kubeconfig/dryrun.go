@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// MergeSummary describes the changes a merge makes to a kubeconfig
+type MergeSummary struct {
+	Clusters  EntryChanges `json:"clusters"`
+	AuthInfos EntryChanges `json:"users"`
+	Contexts  EntryChanges `json:"contexts"`
+	// CurrentContext is the current context after the merge
+	CurrentContext string `json:"currentContext"`
+}
+
+// EntryChanges lists the names of the entries of one kind that a merge adds or overwrites,
+// and the entries of the control plane kubeconfig renamed to the kubeflex generated names
+type EntryChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+	Renamed []Rename `json:"renamed,omitempty"`
+}
+
+// Rename is an entry of the control plane kubeconfig merged under another name
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// LoadAndMergeDryRun works as LoadAndMerge but merges into an in-memory copy of the kubeconfig
+// and never writes it. It returns the merged kubeconfig and a summary of the changes, so that
+// the merge can be previewed. The audit sink is not notified since nothing is merged.
+func LoadAndMergeDryRun(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string, opts ...MergeOption) (*clientcmdapi.Config, *MergeSummary, error) {
+	o := newMergeOptions(opts)
+	konfig, err := LoadKubeconfigFromPath(o.kubeconfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	secretRef, err := o.resolveSecretRef(name, controlPlaneType)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged, summary, err := loadAndMergeDryRun(ctx, &client, name, controlPlaneType, secretRef, konfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !o.setCurrentContext {
+		merged.CurrentContext = konfig.CurrentContext
+		summary.CurrentContext = konfig.CurrentContext
+	}
+	return merged, summary, nil
+}
+
+func loadAndMergeDryRun(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, secretRef *tenancyv1alpha1.SecretReference, konfig *clientcmdapi.Config) (*clientcmdapi.Config, *MergeSummary, error) {
+	var cpKonfig *clientcmdapi.Config
+	var err error
+	if secretRef != nil {
+		cpKonfig, err = loadKubeconfigFromSecret(ctx, client, secretRef.Namespace, secretRef.Name, secretRef.Key)
+	} else {
+		cpKonfig, err = loadControlPlaneKubeconfig(ctx, client, name, controlPlaneType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	originalClusters := sets.KeySet(cpKonfig.Clusters)
+	originalAuthInfos := sets.KeySet(cpKonfig.AuthInfos)
+	originalContexts := sets.KeySet(cpKonfig.Contexts)
+	adjustConfigKeys(cpKonfig, name, controlPlaneType)
+
+	merged := konfig.DeepCopy()
+	summary := &MergeSummary{
+		Clusters:  entryChanges(originalClusters, sets.KeySet(cpKonfig.Clusters), sets.KeySet(merged.Clusters)),
+		AuthInfos: entryChanges(originalAuthInfos, sets.KeySet(cpKonfig.AuthInfos), sets.KeySet(merged.AuthInfos)),
+		Contexts:  entryChanges(originalContexts, sets.KeySet(cpKonfig.Contexts), sets.KeySet(merged.Contexts)),
+	}
+	if err := merge(merged, cpKonfig); err != nil {
+		return nil, nil, err
+	}
+	summary.CurrentContext = merged.CurrentContext
+	return merged, summary, nil
+}
+
+// entryChanges compares the entry names of the control plane kubeconfig before and after
+// adjustConfigKeys with the names already in the kubeconfig
+func entryChanges(original, adjusted, existing sets.Set[string]) EntryChanges {
+	changes := EntryChanges{}
+	for _, n := range sets.List(adjusted) {
+		if existing.Has(n) {
+			changes.Updated = append(changes.Updated, n)
+		} else {
+			changes.Added = append(changes.Added, n)
+		}
+	}
+	// a rename can only be told apart when one entry moved
+	from := sets.List(original.Difference(adjusted))
+	to := sets.List(adjusted.Difference(original))
+	if len(from) == 1 && len(to) == 1 {
+		changes.Renamed = []Rename{{From: from[0], To: to[0]}}
+	}
+	return changes
+}
@@ -0,0 +1,69 @@
+package kubeconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestLoadAndMergeDryRun(t *testing.T) {
+	// the ocm kubeconfig uses the names of the multicluster controlplane chart
+	cpKonfig := clientcmdapi.NewConfig()
+	cpKonfig.Clusters["multicluster-controlplane"] = &clientcmdapi.Cluster{Server: "https://cp2.localtest.me:9443"}
+	cpKonfig.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	cpKonfig.Contexts["multicluster-controlplane"] = &clientcmdapi.Context{Cluster: "multicluster-controlplane", AuthInfo: "user"}
+	cpKonfig.CurrentContext = "multicluster-controlplane"
+	data, err := clientcmd.Write(*cpKonfig)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.OCMKubeConfigSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	konfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	// an earlier merge of cp2 left its cluster behind
+	konfig.Clusters[certs.GenerateClusterName("cp2")] = &clientcmdapi.Cluster{Server: "https://old.localtest.me:9443"}
+	original := konfig.DeepCopy()
+
+	merged, summary, err := loadAndMergeDryRun(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeOCM), nil, konfig)
+	if err != nil {
+		t.Fatalf("loadAndMergeDryRun returned error: %v", err)
+	}
+	if !reflect.DeepEqual(konfig, original) {
+		t.Errorf("expected the kubeconfig not to be modified")
+	}
+	if _, ok := merged.Contexts[certs.GenerateContextName("cp2")]; !ok {
+		t.Errorf("expected merged kubeconfig to have context %s", certs.GenerateContextName("cp2"))
+	}
+
+	expected := &MergeSummary{
+		Clusters: EntryChanges{
+			Updated: []string{certs.GenerateClusterName("cp2")},
+			Renamed: []Rename{{From: "multicluster-controlplane", To: certs.GenerateClusterName("cp2")}},
+		},
+		AuthInfos: EntryChanges{
+			Added:   []string{certs.GenerateAuthInfoAdminName("cp2")},
+			Renamed: []Rename{{From: "user", To: certs.GenerateAuthInfoAdminName("cp2")}},
+		},
+		Contexts: EntryChanges{
+			Added:   []string{certs.GenerateContextName("cp2")},
+			Renamed: []Rename{{From: "multicluster-controlplane", To: certs.GenerateContextName("cp2")}},
+		},
+		CurrentContext: certs.GenerateContextName("cp2"),
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected summary %+v, got %+v", expected, summary)
+	}
+}
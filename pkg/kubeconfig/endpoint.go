@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+// ReplaceContextEndpoint points the kubeflex context of a control plane in the default
+// kubeconfig to a new API server, replacing the server URL and CA of its cluster together.
+// The kubeconfig is written to a temporary file that is renamed over the original, so readers
+// never see a partially updated kubeconfig. The current context is left unchanged.
+func ReplaceContextEndpoint(ctx context.Context, name, controlPlaneType, newServerURL string, newCA []byte) error {
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := replaceContextEndpoint(config, name, controlPlaneType, newServerURL, newCA); err != nil {
+		return err
+	}
+	return WriteKubeconfigToPath(DefaultKubeconfigPath(), config)
+}
+
+func replaceContextEndpoint(config *clientcmdapi.Config, name, controlPlaneType, newServerURL string, newCA []byte) error {
+	u, err := url.Parse(newServerURL)
+	if err != nil {
+		return fmt.Errorf("invalid server URL %q: %s", newServerURL, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("server URL %q must be an https URL", newServerURL)
+	}
+	if _, err := certutil.ParseCertsPEM(newCA); err != nil {
+		return fmt.Errorf("error parsing CA certificates: %s", err)
+	}
+
+	ctxName, ok := findControlPlaneContext(config, name)
+	if !ok {
+		return fmt.Errorf("kubeflex context %s not found for %s control plane %s", certs.GenerateContextName(name), controlPlaneType, name)
+	}
+	cluster, ok := config.Clusters[config.Contexts[ctxName].Cluster]
+	if !ok {
+		return fmt.Errorf("cluster %s not found for control plane %s", config.Contexts[ctxName].Cluster, name)
+	}
+	cluster.Server = newServerURL
+	cluster.CertificateAuthorityData = newCA
+	// a CA file would take precedence over the new CA data
+	cluster.CertificateAuthority = ""
+	return nil
+}
+
+// RewriteServerToLocalhost points the cluster of a context of cfg to https://localhost:<localPort>,
+// for access to the API server through kubectl port-forward. The API server certificate does not
+// match localhost, so the original host is set as tls-server-name and the certificate is still
+// verified against the CA of the cluster. A tls-server-name already set, such as by a previous
+// rewrite, is kept.
+func RewriteServerToLocalhost(cfg *clientcmdapi.Config, contextName string, localPort int) error {
+	if localPort < 1 || localPort > 65535 {
+		return fmt.Errorf("invalid local port %d", localPort)
+	}
+	kctx, ok := cfg.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("context %s not found", contextName)
+	}
+	cluster, ok := cfg.Clusters[kctx.Cluster]
+	if !ok {
+		return fmt.Errorf("cluster %s not found for context %s", kctx.Cluster, contextName)
+	}
+	u, err := url.Parse(cluster.Server)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid server URL %q for context %s", cluster.Server, contextName)
+	}
+	if cluster.TLSServerName == "" {
+		cluster.TLSServerName = u.Hostname()
+	}
+	u.Scheme = "https"
+	u.Host = net.JoinHostPort("localhost", strconv.Itoa(localPort))
+	cluster.Server = u.String()
+	return nil
+}
@@ -0,0 +1,114 @@
+package kubeconfig
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestReplaceContextEndpoint(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+	config.CurrentContext = "kind-kubeflex"
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	newCA, _, err := certutil.GenerateSelfSignedCertKey("cp1.example.com", nil, nil)
+	if err != nil {
+		t.Fatalf("error generating CA: %v", err)
+	}
+	ctx := context.Background()
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	if err := ReplaceContextEndpoint(ctx, "cp1", cpType, "https://cp1.example.com", newCA); err != nil {
+		t.Fatalf("ReplaceContextEndpoint returned error: %v", err)
+	}
+
+	updated, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	cluster := updated.Clusters[certs.GenerateClusterName("cp1")]
+	if cluster.Server != "https://cp1.example.com" {
+		t.Errorf("expected server https://cp1.example.com, got %s", cluster.Server)
+	}
+	if string(cluster.CertificateAuthorityData) != string(newCA) {
+		t.Errorf("expected the CA to be replaced together with the server")
+	}
+	if updated.CurrentContext != "kind-kubeflex" {
+		t.Errorf("expected current context to be preserved, got %s", updated.CurrentContext)
+	}
+	if string(updated.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")].ClientCertificateData) != "cert-cp1" {
+		t.Errorf("expected credentials to be preserved")
+	}
+
+	// invalid endpoints leave the kubeconfig untouched
+	for _, tc := range []struct {
+		server string
+		ca     []byte
+	}{
+		{server: "http://cp1.example.com", ca: newCA},
+		{server: "https://", ca: newCA},
+		{server: "https://cp1.other.com", ca: []byte("not a certificate")},
+	} {
+		if err := ReplaceContextEndpoint(ctx, "cp1", cpType, tc.server, tc.ca); err == nil {
+			t.Errorf("expected error for server %q", tc.server)
+		}
+	}
+	if err := ReplaceContextEndpoint(ctx, "missing", cpType, "https://cp1.example.com", newCA); err == nil {
+		t.Errorf("expected error for missing context")
+	}
+	updated, err = clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	if updated.Clusters[certs.GenerateClusterName("cp1")].Server != "https://cp1.example.com" {
+		t.Errorf("expected a failed replacement not to modify the kubeconfig")
+	}
+}
+
+func TestRewriteServerToLocalhost(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	ctxName := certs.GenerateContextName("cp1")
+
+	if err := RewriteServerToLocalhost(config, ctxName, 8443); err != nil {
+		t.Fatalf("RewriteServerToLocalhost returned error: %v", err)
+	}
+	cluster := config.Clusters[certs.GenerateClusterName("cp1")]
+	if cluster.Server != "https://localhost:8443" {
+		t.Errorf("expected server https://localhost:8443, got %s", cluster.Server)
+	}
+	if cluster.TLSServerName != "cp1.localtest.me" {
+		t.Errorf("expected tls-server-name cp1.localtest.me, got %s", cluster.TLSServerName)
+	}
+	if string(cluster.CertificateAuthorityData) != "ca-cp1" || cluster.InsecureSkipTLSVerify {
+		t.Errorf("expected CA verification to be preserved, got %+v", cluster)
+	}
+
+	// rewriting again to another port keeps the original host
+	if err := RewriteServerToLocalhost(config, ctxName, 9444); err != nil {
+		t.Fatalf("RewriteServerToLocalhost returned error: %v", err)
+	}
+	if cluster.Server != "https://localhost:9444" || cluster.TLSServerName != "cp1.localtest.me" {
+		t.Errorf("unexpected cluster after second rewrite: %+v", cluster)
+	}
+
+	if err := RewriteServerToLocalhost(config, "missing", 8443); err == nil {
+		t.Errorf("expected error for missing context")
+	}
+	if err := RewriteServerToLocalhost(config, ctxName, 0); err == nil {
+		t.Errorf("expected error for invalid port")
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientauthv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// ExecCredentialCommand is the command run by the exec credential of a control plane
+	// kubeconfig merged with WithExecCredential
+	ExecCredentialCommand = "kflex"
+	// ExecCredentialLifetime bounds the expiry of the credentials returned by
+	// ExecCredentialForControlPlane, so that clients fetch them again after a rotation
+	ExecCredentialLifetime = 10 * time.Minute
+)
+
+// ExecCredentialArgs returns the arguments of ExecCredentialCommand that print the credentials
+// of a control plane
+func ExecCredentialArgs(cpName string) []string {
+	return []string{"auth", "token", "--controlplane", cpName}
+}
+
+// setExecCredential replaces the authInfo of the current context of a control plane kubeconfig
+// with an exec credential running `kflex auth token` for cpName, so that the merged
+// kubeconfig holds no credentials and always uses the ones of the control plane secret
+func setExecCredential(config *clientcmdapi.Config, cpName string) {
+	kctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return
+	}
+	if _, ok := config.AuthInfos[kctx.AuthInfo]; !ok {
+		return
+	}
+	config.AuthInfos[kctx.AuthInfo] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion:      clientauthv1.SchemeGroupVersion.String(),
+			Command:         ExecCredentialCommand,
+			Args:            ExecCredentialArgs(cpName),
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		},
+	}
+}
+
+// ExecCredentialForControlPlane returns an ExecCredential with the credentials of the
+// kubeconfig of a control plane, read from its secret in the hosting cluster as LoadAndMerge
+// reads it. The secret options select the kubeconfig. The credentials expire when the client
+// certificate or token does, and at the latest after ExecCredentialLifetime. Exec, auth
+// provider and file based credentials are not supported.
+func ExecCredentialForControlPlane(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string, opts ...MergeOption) (*clientauthv1.ExecCredential, error) {
+	return execCredentialForControlPlane(ctx, &client, name, controlPlaneType, newMergeOptions(opts), time.Now())
+}
+
+func execCredentialForControlPlane(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, o *mergeOptions, now time.Time) (*clientauthv1.ExecCredential, error) {
+	o.execCredential = false
+	_, config, err := getControlPlaneKubeconfig(ctx, client, name, controlPlaneType, o)
+	if err != nil {
+		return nil, err
+	}
+	return execCredentialFromConfig(config, now)
+}
+
+// execCredentialFromConfig returns an ExecCredential with the credentials of the current
+// context of config
+func execCredentialFromConfig(config *clientcmdapi.Config, now time.Time) (*clientauthv1.ExecCredential, error) {
+	kctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("context %s not found", config.CurrentContext)
+	}
+	authInfo, ok := config.AuthInfos[kctx.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("authInfo %s not found for context %s", kctx.AuthInfo, config.CurrentContext)
+	}
+
+	status := &clientauthv1.ExecCredentialStatus{}
+	switch {
+	case authInfo.Token != "":
+		status.Token = authInfo.Token
+	case len(authInfo.ClientCertificateData) > 0 && len(authInfo.ClientKeyData) > 0:
+		status.ClientCertificateData = string(authInfo.ClientCertificateData)
+		status.ClientKeyData = string(authInfo.ClientKeyData)
+	default:
+		return nil, fmt.Errorf("authInfo %s has no embedded token or client certificate", kctx.AuthInfo)
+	}
+
+	expiry := now.Add(ExecCredentialLifetime)
+	if credExpiry, err := CredentialExpiry(config, config.CurrentContext); err == nil && credExpiry.Before(expiry) {
+		expiry = credExpiry
+	}
+	status.ExpirationTimestamp = &metav1.Time{Time: expiry}
+
+	return &clientauthv1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clientauthv1.SchemeGroupVersion.String(),
+			Kind:       "ExecCredential",
+		},
+		Status: status,
+	}, nil
+}
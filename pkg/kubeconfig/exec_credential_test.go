@@ -0,0 +1,114 @@
+package kubeconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestSetExecCredential(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	o := newMergeOptions([]MergeOption{WithExecCredential(true)})
+	o.adjustKubeconfig(config, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+
+	authInfo := config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")]
+	if authInfo.Exec == nil {
+		t.Fatalf("expected exec credential, got %+v", authInfo)
+	}
+	if len(authInfo.ClientCertificateData) > 0 || len(authInfo.ClientKeyData) > 0 || authInfo.Token != "" {
+		t.Errorf("expected embedded credentials to be dropped, got %+v", authInfo)
+	}
+	if authInfo.Exec.Command != ExecCredentialCommand || !reflect.DeepEqual(authInfo.Exec.Args, ExecCredentialArgs("cp1")) {
+		t.Errorf("unexpected exec command %s %v", authInfo.Exec.Command, authInfo.Exec.Args)
+	}
+	if authInfo.Exec.InteractiveMode != clientcmdapi.NeverExecInteractiveMode {
+		t.Errorf("expected non interactive exec credential, got %s", authInfo.Exec.InteractiveMode)
+	}
+	if GetAuthInfoType(authInfo) != AuthTypeExec {
+		t.Errorf("expected exec auth type, got %s", GetAuthInfoType(authInfo))
+	}
+}
+
+func TestExecCredentialForControlPlane(t *testing.T) {
+	now := time.Now()
+	notAfter := now.Add(time.Hour).Truncate(time.Second)
+	certPEM := generateTestClientCert(t, notAfter)
+	keyPEM := []byte("key-cp1")
+	cpConfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	authInfo := cpConfig.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")]
+	authInfo.ClientCertificateData = certPEM
+	authInfo.ClientKeyData = keyPEM
+	data, err := clientcmd.Write(*cpConfig)
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.GetKubeconfSecretNameByControlPlaneType(cpType),
+			Namespace: util.GenerateNamespaceFromControlPlaneName("cp1"),
+		},
+		Data: map[string][]byte{util.GetKubeconfSecretKeyNameByControlPlaneType(cpType): data},
+	})
+
+	cred, err := execCredentialForControlPlane(context.Background(), hostClient, "cp1", cpType, newMergeOptions([]MergeOption{WithExecCredential(true)}), now)
+	if err != nil {
+		t.Fatalf("execCredentialForControlPlane returned error: %v", err)
+	}
+	if cred.Kind != "ExecCredential" || cred.APIVersion != "client.authentication.k8s.io/v1" {
+		t.Errorf("unexpected type meta %+v", cred.TypeMeta)
+	}
+	if cred.Status.ClientCertificateData != string(certPEM) || cred.Status.ClientKeyData != string(keyPEM) {
+		t.Errorf("expected client certificate and key of the secret")
+	}
+	// the lifetime caps the expiry of a certificate valid for longer
+	if !cred.Status.ExpirationTimestamp.Time.Equal(now.Add(ExecCredentialLifetime)) {
+		t.Errorf("expected expiry %v, got %v", now.Add(ExecCredentialLifetime), cred.Status.ExpirationTimestamp.Time)
+	}
+
+	cred, err = execCredentialForControlPlane(context.Background(), hostClient, "cp1", cpType, newMergeOptions(nil), notAfter.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("execCredentialForControlPlane returned error: %v", err)
+	}
+	if !cred.Status.ExpirationTimestamp.Time.Equal(notAfter) {
+		t.Errorf("expected expiry of the certificate %v, got %v", notAfter, cred.Status.ExpirationTimestamp.Time)
+	}
+
+	if _, err := execCredentialForControlPlane(context.Background(), hostClient, "missing", cpType, newMergeOptions(nil), now); err == nil {
+		t.Errorf("expected error for control plane without kubeconfig secret")
+	}
+}
+
+func TestExecCredentialFromConfig(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	authInfo := config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")]
+	*authInfo = clientcmdapi.AuthInfo{Token: "opaque-token"}
+	now := time.Now()
+
+	cred, err := execCredentialFromConfig(config, now)
+	if err != nil {
+		t.Fatalf("execCredentialFromConfig returned error: %v", err)
+	}
+	if cred.Status.Token != "opaque-token" || cred.Status.ClientCertificateData != "" {
+		t.Errorf("expected token credential, got %+v", cred.Status)
+	}
+	if !cred.Status.ExpirationTimestamp.Time.Equal(now.Add(ExecCredentialLifetime)) {
+		t.Errorf("expected expiry after the lifetime for a token without expiry, got %v", cred.Status.ExpirationTimestamp.Time)
+	}
+
+	*authInfo = clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: ExecCredentialCommand}}
+	if _, err := execCredentialFromConfig(config, now); err == nil {
+		t.Errorf("expected error for exec credentials")
+	}
+}
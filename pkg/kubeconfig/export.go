@@ -0,0 +1,231 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+// ExportOption configures ExportKubeconfig
+type ExportOption func(*exportOptions)
+
+const (
+	UserAgentExtensionName = "kflex-user-agent"
+	UserAgentKey           = "userAgent"
+	UserAgentEnvVar        = "KFLEX_USER_AGENT"
+	maxUserAgentLength     = 256
+)
+
+type exportOptions struct {
+	name      string
+	userAgent string
+}
+
+// WithExportName sets the base name used for the exported context, cluster and authInfo
+// instead of the control plane name. The names are generated from the base name the same
+// way kubeflex generates them for a control plane.
+func WithExportName(name string) ExportOption {
+	return func(o *exportOptions) {
+		o.name = name
+	}
+}
+
+// WithUserAgent records a user-agent in the exported authInfo, as an extension read back with
+// GetUserAgent by clients building their rest.Config from the kubeconfig. For authInfos using
+// an exec plugin, it is also passed to the plugin in the KFLEX_USER_AGENT environment variable.
+func WithUserAgent(userAgent string) ExportOption {
+	return func(o *exportOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// ExportKubeconfig returns a standalone kubeconfig holding only the kubeflex context for
+// cpName, with its cluster and authInfo, and that context set as the current context
+func ExportKubeconfig(config *clientcmdapi.Config, cpName string, opts ...ExportOption) (*clientcmdapi.Config, error) {
+	o := &exportOptions{name: cpName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := ValidateContextName(o.name); err != nil {
+		return nil, err
+	}
+	if o.userAgent != "" {
+		if err := ValidateUserAgent(o.userAgent); err != nil {
+			return nil, err
+		}
+	}
+
+	ctxName, ok := findControlPlaneContext(config, cpName)
+	if !ok {
+		return nil, fmt.Errorf("kubeflex context %s not found for control plane %s", certs.GenerateContextName(cpName), cpName)
+	}
+	kctx := config.Contexts[ctxName]
+	cluster, ok := config.Clusters[kctx.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found for control plane %s", kctx.Cluster, cpName)
+	}
+	authInfo, ok := config.AuthInfos[kctx.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("authInfo %s not found for control plane %s", kctx.AuthInfo, cpName)
+	}
+
+	exported := clientcmdapi.NewConfig()
+	exportedCtx := kctx.DeepCopy()
+	exportedCtx.Cluster = certs.GenerateClusterName(o.name)
+	exportedCtx.AuthInfo = certs.GenerateAuthInfoAdminName(o.name)
+	exported.Clusters[exportedCtx.Cluster] = cluster.DeepCopy()
+	exportedAuthInfo := authInfo.DeepCopy()
+	if o.userAgent != "" {
+		setUserAgent(exportedAuthInfo, o.userAgent)
+	}
+	exported.AuthInfos[exportedCtx.AuthInfo] = exportedAuthInfo
+	exported.Contexts[certs.GenerateContextName(o.name)] = exportedCtx
+	exported.CurrentContext = certs.GenerateContextName(o.name)
+	return exported, nil
+}
+
+// ExportContexts returns a standalone kubeconfig holding the contexts of controlPlanes, read from
+// their kubeconfig secrets in the hosting cluster and named as LoadAndMerge names them. The
+// current context is the context of the first control plane. With flatten, the certificates and
+// keys referenced by file are inlined in the *-data fields, so that the kubeconfig can be handed
+// to another user.
+func ExportContexts(ctx context.Context, client kubernetes.Clientset, controlPlanes []ControlPlaneRef, flatten bool) (*clientcmdapi.Config, error) {
+	return exportContexts(ctx, &client, controlPlanes, flatten)
+}
+
+func exportContexts(ctx context.Context, client kubernetes.Interface, controlPlanes []ControlPlaneRef, flatten bool) (*clientcmdapi.Config, error) {
+	if len(controlPlanes) == 0 {
+		return nil, fmt.Errorf("at least one control plane is required")
+	}
+	exported := clientcmdapi.NewConfig()
+	names := sets.New[string]()
+	for _, cp := range controlPlanes {
+		if names.Has(cp.Name) {
+			return nil, fmt.Errorf("control plane %s is listed more than once", cp.Name)
+		}
+		names.Insert(cp.Name)
+
+		cpKonfig, err := loadControlPlaneKubeconfig(ctx, client, cp.Name, cp.Type)
+		if err != nil {
+			return nil, fmt.Errorf("error loading kubeconfig for control plane %s: %w", cp.Name, err)
+		}
+		adjustConfigKeys(cpKonfig, cp.Name, cp.Type)
+		ctxName := certs.GenerateContextName(cp.Name)
+		kctx, ok := cpKonfig.Contexts[ctxName]
+		if !ok {
+			return nil, fmt.Errorf("kubeconfig of control plane %s has no context %s", cp.Name, ctxName)
+		}
+		cluster, ok := cpKonfig.Clusters[kctx.Cluster]
+		if !ok {
+			return nil, fmt.Errorf("cluster %s not found for control plane %s", kctx.Cluster, cp.Name)
+		}
+		authInfo, ok := cpKonfig.AuthInfos[kctx.AuthInfo]
+		if !ok {
+			return nil, fmt.Errorf("authInfo %s not found for control plane %s", kctx.AuthInfo, cp.Name)
+		}
+		exported.Clusters[kctx.Cluster] = cluster
+		exported.AuthInfos[kctx.AuthInfo] = authInfo
+		exported.Contexts[ctxName] = kctx
+	}
+	exported.CurrentContext = certs.GenerateContextName(controlPlanes[0].Name)
+
+	if flatten {
+		if err := clientcmdapi.FlattenConfig(exported); err != nil {
+			return nil, fmt.Errorf("error flattening kubeconfig: %w", err)
+		}
+	}
+	return exported, nil
+}
+
+// ValidateContextName checks that name can be used as a kubeconfig context name
+// and passed unquoted on the kubectl command line
+func ValidateContextName(name string) error {
+	if name == "" {
+		return fmt.Errorf("context name must not be empty")
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return fmt.Errorf("invalid context name %q: must not contain whitespace or control characters", name)
+		}
+	}
+	return nil
+}
+
+// ValidateUserAgent checks that userAgent can be sent as a User-Agent header
+func ValidateUserAgent(userAgent string) error {
+	if userAgent == "" {
+		return fmt.Errorf("user-agent must not be empty")
+	}
+	if len(userAgent) > maxUserAgentLength {
+		return fmt.Errorf("user-agent must not be longer than %d characters", maxUserAgentLength)
+	}
+	for _, r := range userAgent {
+		if r < ' ' || r > '~' {
+			return fmt.Errorf("invalid user-agent %q: must only contain printable ASCII characters", userAgent)
+		}
+	}
+	return nil
+}
+
+// GetUserAgent returns the user-agent recorded in authInfo by WithUserAgent, if any
+func GetUserAgent(authInfo *clientcmdapi.AuthInfo) string {
+	ext, ok := authInfo.Extensions[UserAgentExtensionName]
+	if !ok {
+		return ""
+	}
+	cm, err := unMarshallCM(ext)
+	if err != nil {
+		return ""
+	}
+	return cm.Data[UserAgentKey]
+}
+
+func setUserAgent(authInfo *clientcmdapi.AuthInfo, userAgent string) {
+	if authInfo.Extensions == nil {
+		authInfo.Extensions = map[string]runtime.Object{}
+	}
+	authInfo.Extensions[UserAgentExtensionName] = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: UserAgentExtensionName,
+		},
+		Data: map[string]string{
+			UserAgentKey: userAgent,
+		},
+	}
+
+	if authInfo.Exec == nil {
+		return
+	}
+	for i, env := range authInfo.Exec.Env {
+		if env.Name == UserAgentEnvVar {
+			authInfo.Exec.Env[i].Value = userAgent
+			return
+		}
+	}
+	authInfo.Exec.Env = append(authInfo.Exec.Env, clientcmdapi.ExecEnvVar{Name: UserAgentEnvVar, Value: userAgent})
+}
@@ -0,0 +1,187 @@
+package kubeconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestExportKubeconfig(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	if err := merge(config, generateTestConfig("cp2", "https://cp2.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging config: %v", err)
+	}
+
+	exported, err := ExportKubeconfig(config, "cp1", WithExportName("ci-prod"))
+	if err != nil {
+		t.Fatalf("ExportKubeconfig returned error: %v", err)
+	}
+	if len(exported.Contexts) != 1 || len(exported.Clusters) != 1 || len(exported.AuthInfos) != 1 {
+		t.Fatalf("expected only the exported context, got %d contexts, %d clusters, %d authInfos",
+			len(exported.Contexts), len(exported.Clusters), len(exported.AuthInfos))
+	}
+	if exported.CurrentContext != "ci-prod" {
+		t.Errorf("expected current context ci-prod, got %s", exported.CurrentContext)
+	}
+	kctx, ok := exported.Contexts["ci-prod"]
+	if !ok {
+		t.Fatalf("expected context ci-prod")
+	}
+	if kctx.Cluster != certs.GenerateClusterName("ci-prod") || kctx.AuthInfo != certs.GenerateAuthInfoAdminName("ci-prod") {
+		t.Errorf("expected context to reference the overridden cluster and authInfo, got %s and %s", kctx.Cluster, kctx.AuthInfo)
+	}
+	cluster, ok := exported.Clusters[certs.GenerateClusterName("ci-prod")]
+	if !ok || cluster.Server != "https://cp1.localtest.me:9443" {
+		t.Errorf("expected cluster %s with the cp1 server", certs.GenerateClusterName("ci-prod"))
+	}
+	authInfo, ok := exported.AuthInfos[certs.GenerateAuthInfoAdminName("ci-prod")]
+	if !ok || string(authInfo.ClientCertificateData) != "cert-cp1" {
+		t.Errorf("expected authInfo %s with the cp1 credentials", certs.GenerateAuthInfoAdminName("ci-prod"))
+	}
+	if !IsKubeflexContext(exported, "ci-prod") {
+		t.Errorf("expected exported names to be consistent")
+	}
+
+	// the source config must not be modified
+	authInfo.ClientCertificateData = []byte("changed")
+	if string(config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")].ClientCertificateData) != "cert-cp1" {
+		t.Errorf("expected exported config to be a copy")
+	}
+
+	exported, err = ExportKubeconfig(config, "cp2")
+	if err != nil {
+		t.Fatalf("ExportKubeconfig returned error: %v", err)
+	}
+	if _, ok := exported.Contexts["cp2"]; !ok || exported.CurrentContext != "cp2" {
+		t.Errorf("expected the control plane name to be used without override")
+	}
+
+	for _, name := range []string{"", "ci prod", "ci\tprod"} {
+		if _, err := ExportKubeconfig(config, "cp1", WithExportName(name)); err == nil {
+			t.Errorf("expected error for invalid context name %q", name)
+		}
+	}
+	if _, err := ExportKubeconfig(config, "missing"); err == nil {
+		t.Errorf("expected error for missing control plane context")
+	}
+}
+
+func TestExportKubeconfigUserAgent(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	if err := merge(config, generateTestConfig("cp2", "https://cp2.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging config: %v", err)
+	}
+	config.AuthInfos[certs.GenerateAuthInfoAdminName("cp2")] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{Command: "kflex-auth", APIVersion: "client.authentication.k8s.io/v1"},
+	}
+
+	exported, err := ExportKubeconfig(config, "cp1", WithUserAgent("kflex-ci/1.0 (pipeline 42)"))
+	if err != nil {
+		t.Fatalf("ExportKubeconfig returned error: %v", err)
+	}
+	// the user-agent must survive a round trip through the kubeconfig file
+	data, err := clientcmd.Write(*exported)
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	loaded, err := clientcmd.Load(data)
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	if ua := GetUserAgent(loaded.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")]); ua != "kflex-ci/1.0 (pipeline 42)" {
+		t.Errorf("expected user-agent to be recorded in the authInfo, got %q", ua)
+	}
+	if GetUserAgent(config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")]) != "" {
+		t.Errorf("expected the source config not to be modified")
+	}
+
+	exported, err = ExportKubeconfig(config, "cp2", WithUserAgent("kflex-ci/1.0"))
+	if err != nil {
+		t.Fatalf("ExportKubeconfig returned error: %v", err)
+	}
+	exec := exported.AuthInfos[certs.GenerateAuthInfoAdminName("cp2")].Exec
+	if len(exec.Env) != 1 || exec.Env[0].Name != UserAgentEnvVar || exec.Env[0].Value != "kflex-ci/1.0" {
+		t.Errorf("expected user-agent to be passed to the exec plugin, got %v", exec.Env)
+	}
+	if len(config.AuthInfos[certs.GenerateAuthInfoAdminName("cp2")].Exec.Env) != 0 {
+		t.Errorf("expected the source exec config not to be modified")
+	}
+
+	for _, ua := range []string{"kflex\nci", "kflex\tci", "kflex-ci/\u00e9", strings.Repeat("a", 257)} {
+		if _, err := ExportKubeconfig(config, "cp1", WithUserAgent(ua)); err == nil {
+			t.Errorf("expected error for invalid user-agent %q", ua)
+		}
+	}
+}
+
+func TestExportContexts(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caFile, []byte("file-ca"), 0600); err != nil {
+		t.Fatalf("error writing CA file: %v", err)
+	}
+	cp1 := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	cp2 := generateTestConfig("cp2", "https://cp2.localtest.me:9443")
+	cluster := cp2.Clusters[certs.GenerateClusterName("cp2")]
+	cluster.CertificateAuthorityData = nil
+	cluster.CertificateAuthority = caFile
+	var objs []runtime.Object
+	for name, config := range map[string]*clientcmdapi.Config{"cp1": cp1, "cp2": cp2} {
+		data, err := clientcmd.Write(*config)
+		if err != nil {
+			t.Fatalf("error writing test kubeconfig: %v", err)
+		}
+		objs = append(objs, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName(name)},
+			Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+		})
+	}
+	client := fake.NewSimpleClientset(objs...)
+	refs := []ControlPlaneRef{
+		{Name: "cp2", Type: string(tenancyv1alpha1.ControlPlaneTypeK8S)},
+		{Name: "cp1", Type: string(tenancyv1alpha1.ControlPlaneTypeK8S)},
+	}
+
+	exported, err := exportContexts(context.Background(), client, refs, false)
+	if err != nil {
+		t.Fatalf("exportContexts returned error: %v", err)
+	}
+	if len(exported.Contexts) != 2 || len(exported.Clusters) != 2 || len(exported.AuthInfos) != 2 {
+		t.Fatalf("expected the two control plane contexts, got %d contexts, %d clusters, %d authInfos",
+			len(exported.Contexts), len(exported.Clusters), len(exported.AuthInfos))
+	}
+	if exported.CurrentContext != certs.GenerateContextName("cp2") {
+		t.Errorf("expected current context cp2, got %s", exported.CurrentContext)
+	}
+	if exported.Clusters[certs.GenerateClusterName("cp2")].CertificateAuthority != caFile {
+		t.Errorf("expected the CA file reference to be kept without flatten")
+	}
+
+	exported, err = exportContexts(context.Background(), client, refs, true)
+	if err != nil {
+		t.Fatalf("exportContexts returned error: %v", err)
+	}
+	flattened := exported.Clusters[certs.GenerateClusterName("cp2")]
+	if flattened.CertificateAuthority != "" || string(flattened.CertificateAuthorityData) != "file-ca" {
+		t.Errorf("expected the CA file to be inlined, got %+v", flattened)
+	}
+
+	if _, err := exportContexts(context.Background(), client, append(refs, refs[0]), false); err == nil {
+		t.Errorf("expected error for a control plane listed twice")
+	}
+	if _, err := exportContexts(context.Background(), client, []ControlPlaneRef{{Name: "cp3", Type: string(tenancyv1alpha1.ControlPlaneTypeK8S)}}, false); err == nil {
+		t.Errorf("expected error for a control plane without kubeconfig secret")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	AuthTypeClientCert   = "client-cert"
+	AuthTypeToken        = "token"
+	AuthTypeBasic        = "basic"
+	AuthTypeExec         = "exec"
+	AuthTypeAuthProvider = "auth-provider"
+	AuthTypeNone         = "none"
+)
+
+// ContextFingerprint returns a stable hash of the connection-relevant parts of a context:
+// the cluster endpoint and CA, the auth type and the client certificate. Volatile
+// credentials such as bearer tokens and auth-provider caches are excluded, so a token
+// refresh does not change the fingerprint while a certificate rotation does.
+func ContextFingerprint(config *clientcmdapi.Config, contextName string) (string, error) {
+	kctx, ok := config.Contexts[contextName]
+	if !ok {
+		return "", fmt.Errorf("context %s not found", contextName)
+	}
+	cluster, ok := config.Clusters[kctx.Cluster]
+	if !ok {
+		return "", fmt.Errorf("cluster %s not found for context %s", kctx.Cluster, contextName)
+	}
+	authInfo, ok := config.AuthInfos[kctx.AuthInfo]
+	if !ok {
+		return "", fmt.Errorf("authInfo %s not found for context %s", kctx.AuthInfo, contextName)
+	}
+
+	fields := []string{
+		"server=" + cluster.Server,
+		"tls-server-name=" + cluster.TLSServerName,
+		"proxy-url=" + cluster.ProxyURL,
+		fmt.Sprintf("insecure=%t", cluster.InsecureSkipTLSVerify),
+		"ca-file=" + cluster.CertificateAuthority,
+		"ca-data=" + string(cluster.CertificateAuthorityData),
+		"auth-type=" + GetAuthInfoType(authInfo),
+		"client-cert-file=" + authInfo.ClientCertificate,
+		"client-cert-data=" + string(authInfo.ClientCertificateData),
+	}
+	if authInfo.Exec != nil {
+		fields = append(fields,
+			"exec-command="+authInfo.Exec.Command,
+			"exec-args="+strings.Join(authInfo.Exec.Args, " "),
+			"exec-api-version="+authInfo.Exec.APIVersion)
+	}
+	if authInfo.AuthProvider != nil {
+		fields = append(fields, "auth-provider="+authInfo.AuthProvider.Name)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetAuthInfoType returns the kind of credentials configured for an authInfo
+func GetAuthInfoType(authInfo *clientcmdapi.AuthInfo) string {
+	switch {
+	case authInfo.Exec != nil:
+		return AuthTypeExec
+	case authInfo.AuthProvider != nil:
+		return AuthTypeAuthProvider
+	case len(authInfo.ClientCertificateData) > 0 || authInfo.ClientCertificate != "":
+		return AuthTypeClientCert
+	case authInfo.Token != "" || authInfo.TokenFile != "":
+		return AuthTypeToken
+	case authInfo.Username != "":
+		return AuthTypeBasic
+	default:
+		return AuthTypeNone
+	}
+}
@@ -0,0 +1,71 @@
+package kubeconfig
+
+import (
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestContextFingerprint(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")].Token = "token-1"
+
+	fp1, err := ContextFingerprint(config, "cp1")
+	if err != nil {
+		t.Fatalf("ContextFingerprint returned error: %v", err)
+	}
+
+	// a token refresh must not change the fingerprint
+	config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")].Token = "token-2"
+	fp2, err := ContextFingerprint(config, "cp1")
+	if err != nil {
+		t.Fatalf("ContextFingerprint returned error: %v", err)
+	}
+	if fp1 != fp2 {
+		t.Errorf("expected fingerprint to be unchanged after token refresh")
+	}
+
+	// a cert rotation must change the fingerprint
+	config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")].ClientCertificateData = []byte("rotated-cert")
+	fp3, err := ContextFingerprint(config, "cp1")
+	if err != nil {
+		t.Fatalf("ContextFingerprint returned error: %v", err)
+	}
+	if fp1 == fp3 {
+		t.Errorf("expected fingerprint to change after cert rotation")
+	}
+
+	if _, err := ContextFingerprint(config, "missing"); err == nil {
+		t.Errorf("expected error for missing context")
+	}
+}
+
+// generateTestConfig returns a config with the cluster, authInfo and context
+// that LoadAndMerge generates for a control plane
+func generateTestConfig(cpName, server string) *clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[certs.GenerateClusterName(cpName)] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: []byte("ca-" + cpName),
+	}
+	config.AuthInfos[certs.GenerateAuthInfoAdminName(cpName)] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: []byte("cert-" + cpName),
+		ClientKeyData:         []byte("key-" + cpName),
+	}
+	config.Contexts[certs.GenerateContextName(cpName)] = &clientcmdapi.Context{
+		Cluster:  certs.GenerateClusterName(cpName),
+		AuthInfo: certs.GenerateAuthInfoAdminName(cpName),
+	}
+	config.CurrentContext = certs.GenerateContextName(cpName)
+	return config
+}
+
+// generateTestInternalConfig returns the internal context of the control plane cpName as
+// merged with WithInternalContext
+func generateTestInternalConfig(cpName, server string) *clientcmdapi.Config {
+	config := generateTestConfig(cpName+InternalContextSuffix, server)
+	markInternalContext(config, cpName)
+	return config
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// GetControlPlaneKubeconfig returns the kubeconfig of a control plane, serialized and parsed,
+// without merging it into any kubeconfig file. Its entries are renamed as LoadAndMerge names
+// them and its current context is the context of the control plane. The secret and in-cluster
+// endpoint options select the kubeconfig, and the naming, namespace and TLS options adjust it, as
+// they do for LoadAndMerge.
+func GetControlPlaneKubeconfig(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string, opts ...MergeOption) ([]byte, *clientcmdapi.Config, error) {
+	return getControlPlaneKubeconfig(ctx, &client, name, controlPlaneType, newMergeOptions(opts))
+}
+
+func getControlPlaneKubeconfig(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, o *mergeOptions) ([]byte, *clientcmdapi.Config, error) {
+	if err := util.ValidateControlPlaneType(controlPlaneType); err != nil {
+		return nil, nil, err
+	}
+	secretRef, err := o.resolveSecretRef(name, controlPlaneType)
+	if err != nil {
+		return nil, nil, err
+	}
+	var config *clientcmdapi.Config
+	if secretRef != nil {
+		config, err = loadKubeconfigFromSecret(ctx, client, secretRef.Namespace, secretRef.Name, secretRef.Key)
+	} else {
+		config, err = loadControlPlaneKubeconfig(ctx, client, name, controlPlaneType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := o.validate(); err != nil {
+		return nil, nil, err
+	}
+	o.adjustKubeconfig(config, name, controlPlaneType)
+
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, config, nil
+}
@@ -0,0 +1,78 @@
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestGetControlPlaneKubeconfig(t *testing.T) {
+	vcConfig := clientcmdapi.NewConfig()
+	vcConfig.Clusters["my-vcluster"] = &clientcmdapi.Cluster{Server: "https://cp1.localtest.me:9443"}
+	vcConfig.AuthInfos["my-vcluster"] = &clientcmdapi.AuthInfo{ClientCertificateData: []byte("cert")}
+	vcConfig.Contexts["my-vcluster"] = &clientcmdapi.Context{Cluster: "my-vcluster", AuthInfo: "my-vcluster"}
+	vcConfig.CurrentContext = "my-vcluster"
+	data, err := clientcmd.Write(*vcConfig)
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.VClusterKubeConfigSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp1")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyVCluster: data},
+	})
+
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeVCluster)
+	raw, config, err := getControlPlaneKubeconfig(context.Background(), hostClient, "cp1", cpType, newMergeOptions(nil))
+	if err != nil {
+		t.Fatalf("getControlPlaneKubeconfig returned error: %v", err)
+	}
+	if config.CurrentContext != certs.GenerateContextName("cp1") {
+		t.Errorf("expected current context %s, got %s", certs.GenerateContextName("cp1"), config.CurrentContext)
+	}
+	if !IsKubeflexContext(config, certs.GenerateContextName("cp1")) {
+		t.Errorf("expected renamed kubeflex context, got %+v", config.Contexts)
+	}
+	if len(config.Clusters) != 1 || len(config.AuthInfos) != 1 || len(config.Contexts) != 1 {
+		t.Errorf("expected only the renamed entries, got %+v", config)
+	}
+
+	parsed, err := clientcmd.Load(raw)
+	if err != nil {
+		t.Fatalf("error loading returned bytes: %v", err)
+	}
+	if parsed.CurrentContext != config.CurrentContext || parsed.Clusters[certs.GenerateClusterName("cp1")] == nil {
+		t.Errorf("expected serialized kubeconfig to match the returned config, got %+v", parsed)
+	}
+
+	if _, _, err := getControlPlaneKubeconfig(context.Background(), hostClient, "missing", cpType, newMergeOptions(nil)); err == nil {
+		t.Errorf("expected error for control plane without kubeconfig secret")
+	}
+}
+
+func TestUnsupportedControlPlaneType(t *testing.T) {
+	// the secret of the k8s type must not be read for an unknown type
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp1")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: []byte("kubeconfig")},
+	})
+	ctx := context.Background()
+	if _, _, err := getControlPlaneKubeconfig(ctx, hostClient, "cp1", "unknown", newMergeOptions(nil)); err == nil {
+		t.Errorf("expected getControlPlaneKubeconfig to reject the unknown control plane type")
+	}
+	if _, err := loadAndMergeWithOptions(ctx, hostClient, "cp1", "unknown", clientcmdapi.NewConfig(), newMergeOptions(nil)); err == nil {
+		t.Errorf("expected loadAndMergeWithOptions to reject the unknown control plane type")
+	}
+	if _, err := waitForControlPlaneReady(ctx, hostClient, "cp1", "unknown", time.Second); err == nil {
+		t.Errorf("expected waitForControlPlaneReady to reject the unknown control plane type")
+	}
+}
@@ -17,11 +17,25 @@ limitations under the License.
 package kubeconfig
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
@@ -32,77 +46,768 @@ import (
 	"github.com/kubestellar/kubeflex/pkg/util"
 )
 
-func LoadAndMerge(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string) error {
-	cpKonfig, err := loadControlPlaneKubeconfig(ctx, client, name, controlPlaneType)
+func LoadAndMerge(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string, opts ...MergeOption) error {
+	o := newMergeOptions(opts)
+	unlock, err := lockKubeconfig(o.kubeconfigPath, o.lockTimeout)
 	if err != nil {
 		return err
 	}
-	adjustConfigKeys(cpKonfig, name, controlPlaneType)
-
-	konfig, err := LoadKubeconfig(ctx)
+	defer unlock()
+	konfig, err := LoadKubeconfigFromPath(o.kubeconfigPath)
 	if err != nil {
 		return err
 	}
 
-	err = merge(konfig, cpKonfig)
+	entry, err := loadAndMergeWithOptions(ctx, &client, name, controlPlaneType, konfig, o)
 	if err != nil {
 		return err
 	}
 
-	return WriteKubeconfig(ctx, konfig)
+	if _, err = WriteKubeconfigToPathIfChanged(o.kubeconfigPath, konfig); err != nil {
+		return err
+	}
+	o.auditSink(*entry)
+	return nil
 }
 
 // LoadAndMergeNoWrite: works as LoadAndMerge but on supplied konfig from file and does not write it back
-func LoadAndMergeNoWrite(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string, konfig *clientcmdapi.Config) error {
-	cpKonfig, err := loadControlPlaneKubeconfig(ctx, client, name, controlPlaneType)
+func LoadAndMergeNoWrite(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string, konfig *clientcmdapi.Config, opts ...MergeOption) error {
+	o := newMergeOptions(opts)
+	entry, err := loadAndMergeWithOptions(ctx, &client, name, controlPlaneType, konfig, o)
 	if err != nil {
 		return err
 	}
-	adjustConfigKeys(cpKonfig, name, controlPlaneType)
+	o.auditSink(*entry)
+	return nil
+}
+
+// RemoveControlPlaneFromKubeconfig removes the cluster, authInfo and context that LoadAndMerge
+// wrote for a control plane from the default kubeconfig, including those of its internal
+// context. If the removed context was the current context, the current context falls back to
+// one of the remaining contexts, or is cleared when none is left. Entries that are already
+// absent are ignored.
+func RemoveControlPlaneFromKubeconfig(ctx context.Context, name, controlPlaneType string) error {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	konfig, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return err
+	}
+	removed := removeControlPlaneEntries(konfig, name)
+	removedInternal := removeInternalContextEntries(konfig, name, controlPlaneType)
+	if !removed && !removedInternal {
+		return nil
+	}
+	return WriteKubeconfig(ctx, konfig)
+}
+
+// RemoveControlPlanesFromKubeconfig works as RemoveControlPlaneFromKubeconfig for several
+// control planes, loading and writing the default kubeconfig once. It returns the sorted names
+// of the control planes that had entries in the kubeconfig. The refs are checked first: refs
+// without a name or listed more than once are reported in the returned aggregate error and
+// nothing is removed. The kubeconfig is only written if entries were removed.
+func RemoveControlPlanesFromKubeconfig(ctx context.Context, refs []ControlPlaneRef) (removed []string, err error) {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	konfig, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	removed, err = removeControlPlanes(konfig, refs)
+	if err != nil || len(removed) == 0 {
+		return removed, err
+	}
+	if err := WriteKubeconfig(ctx, konfig); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// removeControlPlanes removes the entries of the control planes in refs, including those of
+// their internal contexts, and returns the sorted names of the control planes that had entries
+func removeControlPlanes(config *clientcmdapi.Config, refs []ControlPlaneRef) ([]string, error) {
+	names := sets.New[string]()
+	types := map[string]string{}
+	errs := []error{}
+	for i, ref := range refs {
+		switch {
+		case ref.Name == "":
+			errs = append(errs, fmt.Errorf("control plane name is required for entry %d", i))
+		case names.Has(ref.Name):
+			errs = append(errs, fmt.Errorf("control plane %s is listed more than once", ref.Name))
+		default:
+			names.Insert(ref.Name)
+			types[ref.Name] = ref.Type
+		}
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	removed := []string{}
+	for _, name := range sets.List(names) {
+		removedEntries := removeControlPlaneEntries(config, name)
+		removedInternal := removeInternalContextEntries(config, name, types[name])
+		if removedEntries || removedInternal {
+			removed = append(removed, name)
+		}
+	}
+	return removed, nil
+}
+
+// removeInternalContextEntries deletes the kubeconfig entries of the internal context of a
+// control plane and reports whether config was changed. The entries named after the control
+// plane with InternalContextSuffix are only deleted if they are its internal context, since
+// they may also be the entries of another control plane with that name.
+func removeInternalContextEntries(config *clientcmdapi.Config, name, controlPlaneType string) bool {
+	if !mayHaveInternalContext(controlPlaneType) || !isInternalContextOf(config, name) {
+		return false
+	}
+	return removeControlPlaneEntries(config, name+InternalContextSuffix)
+}
+
+// removeControlPlaneEntries deletes the kubeconfig entries of a control plane and
+// reports whether config was changed
+func removeControlPlaneEntries(config *clientcmdapi.Config, name string) bool {
+	ctxName := certs.GenerateContextName(name)
+	clusterName := certs.GenerateClusterName(name)
+	authName := certs.GenerateAuthInfoAdminName(name)
+
+	// contexts renamed with a context name override are found by their cluster and authInfo
+	ctxNames := []string{}
+	for n, kctx := range config.Contexts {
+		if n == ctxName || isControlPlaneContext(kctx, name) {
+			ctxNames = append(ctxNames, n)
+		}
+	}
+	_, hasCluster := config.Clusters[clusterName]
+	_, hasAuth := config.AuthInfos[authName]
+	if len(ctxNames) == 0 && !hasCluster && !hasAuth {
+		return false
+	}
+	removedCurrent := false
+	for _, n := range ctxNames {
+		delete(config.Contexts, n)
+		removedCurrent = removedCurrent || config.CurrentContext == n
+	}
+	delete(config.Clusters, clusterName)
+	delete(config.AuthInfos, authName)
 
-	err = merge(konfig, cpKonfig)
+	if removedCurrent {
+		config.CurrentContext = ""
+		names := make([]string, 0, len(config.Contexts))
+		for n := range config.Contexts {
+			names = append(names, n)
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			config.CurrentContext = names[0]
+		}
+	}
+	return true
+}
+
+// RenameControlPlaneContext renames the cluster, authInfo and context that LoadAndMerge wrote
+// for the control plane oldName of type controlPlaneType in the default kubeconfig to the names
+// of newName, such as after the control plane is recreated with a new name. The entries of its
+// internal context are renamed as well when the type registered for controlPlaneType has an
+// in-cluster kubeconfig, and a context renamed with a context name override keeps its name. The
+// current context and the recorded previous context follow the rename, and the other entries are
+// left untouched. It fails if the type is not registered, if the context of oldName is not in the
+// kubeconfig or if an entry of newName already exists.
+func RenameControlPlaneContext(ctx context.Context, oldName, newName, controlPlaneType string) error {
+	spec, err := util.GetControlPlaneTypeSpec(controlPlaneType)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	konfig, err := LoadKubeconfig(ctx)
 	if err != nil {
 		return err
 	}
+	if err := renameControlPlaneEntries(konfig, oldName, newName, spec); err != nil {
+		return fmt.Errorf("error renaming control plane %s to %s: %w", oldName, newName, err)
+	}
+	return WriteKubeconfig(ctx, konfig)
+}
+
+// renameControlPlaneEntries renames the kubeconfig entries of the control plane oldName to the
+// names of newName. The entries of its internal context are only renamed for a type with an
+// in-cluster kubeconfig and when they are its internal context, since they may belong to another
+// control plane. As in
+// removeControlPlaneEntries, the context is found by the cluster and authInfo it uses.
+func renameControlPlaneEntries(config *clientcmdapi.Config, oldName, newName string, spec util.ControlPlaneTypeSpec) error {
+	if _, ok := findControlPlaneContext(config, oldName); !ok {
+		return fmt.Errorf("context %s not found", certs.GenerateContextName(oldName))
+	}
+	if oldName == newName {
+		return nil
+	}
+	renames := [][2]string{{oldName, newName}}
+	internal := spec.InClusterSecretKey != "" && isInternalContextOf(config, oldName)
+	if internal {
+		renames = append(renames, [2]string{oldName + InternalContextSuffix, newName + InternalContextSuffix})
+	}
+	for _, r := range renames {
+		if _, ok := config.Contexts[certs.GenerateContextName(r[1])]; ok {
+			return fmt.Errorf("context %s already exists", certs.GenerateContextName(r[1]))
+		}
+		if _, ok := config.Clusters[certs.GenerateClusterName(r[1])]; ok {
+			return fmt.Errorf("cluster %s already exists", certs.GenerateClusterName(r[1]))
+		}
+		if _, ok := config.AuthInfos[certs.GenerateAuthInfoAdminName(r[1])]; ok {
+			return fmt.Errorf("authInfo %s already exists", certs.GenerateAuthInfoAdminName(r[1]))
+		}
+	}
+	for _, r := range renames {
+		if err := renameEntries(config, r[0], r[1]); err != nil {
+			return err
+		}
+	}
+	if internal {
+		markInternalContext(config, newName)
+	}
+	return nil
+}
 
+// renameEntries renames the cluster and authInfo generated for oldName to those of newName.
+// The contexts using them follow the rename, and the context with the generated name of oldName
+// is renamed to the generated name of newName.
+func renameEntries(config *clientcmdapi.Config, oldName, newName string) error {
+	renameKey(config, config.Clusters, certs.GenerateClusterName(oldName), certs.GenerateClusterName(newName))
+	renameKey(config, config.AuthInfos, certs.GenerateAuthInfoAdminName(oldName), certs.GenerateAuthInfoAdminName(newName))
+	oldCtxName := certs.GenerateContextName(oldName)
+	newCtxName := certs.GenerateContextName(newName)
+	if kctx, ok := config.Contexts[oldCtxName]; !ok || !isControlPlaneContext(kctx, newName) {
+		return nil
+	}
+	renameKey(config, config.Contexts, oldCtxName, newCtxName)
+	if GetPreviousContext(config) == oldCtxName {
+		return RecordPreviousContext(config, newCtxName)
+	}
 	return nil
 }
 
-func loadControlPlaneKubeconfig(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string) (*clientcmdapi.Config, error) {
-	namespace := util.GenerateNamespaceFromControlPlaneName(name)
+// loadAndMergeWithOptions runs loadAndMerge with the secret and current context options. When
+// the merge switches the current context, the context it replaces is recorded as the previous
+// context.
+func loadAndMergeWithOptions(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, konfig *clientcmdapi.Config, o *mergeOptions) (*AuditEntry, error) {
+	if err := util.ValidateControlPlaneType(controlPlaneType); err != nil {
+		return nil, err
+	}
+	secretRef, err := o.resolveSecretRef(name, controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	currentContext := konfig.CurrentContext
+	entry, err := loadAndMergeWithPolicy(ctx, client, name, controlPlaneType, secretRef, konfig, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.internalContext {
+		conflicts, err := mergeInternalContext(ctx, client, name, controlPlaneType, konfig, o)
+		if err != nil {
+			return nil, err
+		}
+		entry.Conflicts = append(entry.Conflicts, conflicts...)
+	}
+	if o.verify {
+		if err := verifyMergedContext(ctx, konfig, entry.ContextName); err != nil {
+			return nil, err
+		}
+	}
+	entry.PreviousContext = currentContext
+	if !o.setCurrentContext {
+		konfig.CurrentContext = currentContext
+	} else if konfig.CurrentContext != currentContext {
+		if err := RecordPreviousContext(konfig, currentContext); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// InternalContextSuffix is appended to the name of a control plane to name the cluster, authInfo
+// and context of its in-cluster endpoint merged with WithInternalContext
+const InternalContextSuffix = "-internal"
+
+const (
+	// InternalContextExtensionName is the extension of the cluster of an internal context that
+	// records the control plane it belongs to
+	InternalContextExtensionName = "kflex-internal-context"
+	InternalContextOwnerKey      = "controlPlane"
+)
+
+// mayHaveInternalContext reports whether control planes of the type can have an internal
+// context: the registered types with an in-cluster kubeconfig, and external control planes
+// whose supplied kubeconfig is also used in-cluster. The type of a control plane that is no
+// longer on the hosting cluster may not be known, so an empty type may have one as well.
+func mayHaveInternalContext(controlPlaneType string) bool {
+	return controlPlaneType == "" || controlPlaneType == string(tenancyv1alpha1.ControlPlaneTypeExternal) ||
+		util.GetInClusterKubeconfSecretKeyNameByControlPlaneType(controlPlaneType) != ""
+}
+
+// markInternalContext records in the cluster of the internal context of the control plane in
+// config that it belongs to the control plane
+func markInternalContext(config *clientcmdapi.Config, name string) {
+	cluster, ok := config.Clusters[certs.GenerateClusterName(name+InternalContextSuffix)]
+	if !ok {
+		return
+	}
+	if cluster.Extensions == nil {
+		cluster.Extensions = map[string]runtime.Object{}
+	}
+	cluster.Extensions[InternalContextExtensionName] = &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: InternalContextExtensionName,
+		},
+		Data: map[string]string{
+			InternalContextOwnerKey: name,
+		},
+	}
+}
+
+// isInternalContextOf reports whether the entries named after the control plane with
+// InternalContextSuffix are its internal context rather than the entries of another control
+// plane with that name. The cluster is marked with the control plane when it is merged; clusters
+// merged before the mark was recorded are recognized by a server in the namespace of the
+// control plane.
+func isInternalContextOf(config *clientcmdapi.Config, name string) bool {
+	cluster, ok := config.Clusters[certs.GenerateClusterName(name+InternalContextSuffix)]
+	if !ok {
+		return false
+	}
+	if ext, ok := cluster.Extensions[InternalContextExtensionName]; ok {
+		cm, err := unMarshallCM(ext)
+		return err == nil && cm.Data[InternalContextOwnerKey] == name
+	}
+	u, err := url.Parse(cluster.Server)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(u.Hostname()+".", "."+util.GenerateNamespaceFromControlPlaneName(name)+".")
+}
+
+// mergeInternalContext merges the in-cluster kubeconfig of a control plane into konfig under
+// the names of the control plane with InternalContextSuffix, keeping the current context of
+// konfig. It is a no-op when the control plane type has no in-cluster kubeconfig, and fails when
+// the in-cluster kubeconfig is missing from the secret or the secret reference has no in-cluster key.
+func mergeInternalContext(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, konfig *clientcmdapi.Config, o *mergeOptions) ([]MergeConflict, error) {
+	internal := *o
+	internal.inCluster = true
+	secretRef, err := internal.resolveSecretRef(name, controlPlaneType)
+	if err != nil {
+		var noInCluster *NoInClusterKubeconfigError
+		if errors.As(err, &noInCluster) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error merging the internal context: %w", err)
+	}
+	cpKonfig, err := loadKubeconfigFromSecret(ctx, client, secretRef.Namespace, secretRef.Name, secretRef.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error merging the internal context: %w", err)
+	}
+	internalName := name + InternalContextSuffix
+	if internal.contextName != "" {
+		internal.contextName += InternalContextSuffix
+	}
+	if err := validateContextName(konfig, internalName, internal.contextName); err != nil {
+		return nil, err
+	}
+	// the exec credential fetches the credentials of the control plane, not of the internal name
+	internal.execCredential = false
+	// the in-cluster endpoint is not reached through the gateway of the external one
+	internal.proxyURL = ""
+	internal.tlsServerName = ""
+	internal.adjustKubeconfig(cpKonfig, internalName, controlPlaneType)
+	markInternalContext(cpKonfig, name)
+	if o.execCredential {
+		setExecCredential(cpKonfig, name)
+	}
+
+	currentContext := konfig.CurrentContext
+	conflicts, err := mergeWithPolicy(konfig, cpKonfig, o.conflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+	konfig.CurrentContext = currentContext
+	return conflicts, nil
+}
+
+// resolveSecretRef returns the secret reference loadAndMerge reads the kubeconfig from. With
+// the in-cluster endpoint option it points at the in-cluster key of the secret, and fails if the
+// control plane has no in-cluster kubeconfig.
+func (o *mergeOptions) resolveSecretRef(name, controlPlaneType string) (*tenancyv1alpha1.SecretReference, error) {
+	if !o.inCluster {
+		return o.secretRef, nil
+	}
+	if o.secretRef != nil {
+		if o.secretRef.InClusterKey == "" {
+			return nil, fmt.Errorf("kubeconfig secret %s/%s has no in-cluster key", o.secretRef.Namespace, o.secretRef.Name)
+		}
+		ref := *o.secretRef
+		ref.Key = ref.InClusterKey
+		return &ref, nil
+	}
+	key := util.GetInClusterKubeconfSecretKeyNameByControlPlaneType(controlPlaneType)
+	if key == "" {
+		return nil, &NoInClusterKubeconfigError{ControlPlaneType: controlPlaneType}
+	}
+	return &tenancyv1alpha1.SecretReference{
+		Namespace:    util.GenerateNamespaceFromControlPlaneName(name),
+		Name:         util.GetKubeconfSecretNameByControlPlaneType(controlPlaneType),
+		Key:          key,
+		InClusterKey: key,
+	}, nil
+}
+
+// loadAndMerge merges the kubeconfig of a control plane into konfig. The kubeconfig is read from
+// secretRef when set, or else from the secret kubeflex generates for the control plane type.
+func loadAndMerge(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, secretRef *tenancyv1alpha1.SecretReference, konfig *clientcmdapi.Config) (*AuditEntry, error) {
+	return loadAndMergeWithPolicy(ctx, client, name, controlPlaneType, secretRef, konfig, newMergeOptions(nil))
+}
 
-	ks, err := client.CoreV1().Secrets(namespace).Get(ctx,
-		util.GetKubeconfSecretNameByControlPlaneType(controlPlaneType),
-		metav1.GetOptions{})
+// loadAndMergeWithPolicy works as loadAndMerge and handles the entries that already exist with
+// a different content as selected by the conflict policy of o. The conflicting entries are
+// reported in the returned audit entry. The context name and namespace options of o are applied
+// to the merged context.
+func loadAndMergeWithPolicy(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, secretRef *tenancyv1alpha1.SecretReference, konfig *clientcmdapi.Config, o *mergeOptions) (*AuditEntry, error) {
+	var cpKonfig *clientcmdapi.Config
+	var err error
+	if secretRef != nil {
+		cpKonfig, err = loadKubeconfigFromSecret(ctx, client, secretRef.Namespace, secretRef.Name, secretRef.Key)
+	} else {
+		cpKonfig, err = loadControlPlaneKubeconfig(ctx, client, name, controlPlaneType)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if err := validateContextName(konfig, name, o.contextName); err != nil {
+		return nil, err
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if controlPlaneType == string(tenancyv1alpha1.ControlPlaneTypeHost) && !o.inCluster {
+		setHostServer(cpKonfig, konfig)
+	}
+	o.adjustKubeconfig(cpKonfig, name, controlPlaneType)
 
-	key := util.GetKubeconfSecretKeyNameByControlPlaneType(controlPlaneType)
-	return clientcmd.Load(ks.Data[key])
+	conflicts, err := mergeWithPolicy(konfig, cpKonfig, o.conflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := newAuditEntry(konfig, name, controlPlaneType, cpKonfig.CurrentContext)
+	entry.Conflicts = conflicts
+	entry.InsecureSkipTLSVerify = o.insecure
+	return entry, nil
+}
+
+// setHostServer replaces the in-cluster server of the kubeconfig of a host control plane, written
+// by the operator when no external address of the hosting cluster is configured, with the server
+// of the hosting cluster context of konfig: the initial context recorded by kubeflex, or else the
+// current context. The CA of the hosting cluster context is used with it, as the API server may
+// present another certificate on its external address.
+func setHostServer(cpKonfig, konfig *clientcmdapi.Config) {
+	kctx, ok := cpKonfig.Contexts[cpKonfig.CurrentContext]
+	if !ok {
+		return
+	}
+	cluster, ok := cpKonfig.Clusters[kctx.Cluster]
+	if !ok || cluster.Server != util.HostInClusterServer {
+		return
+	}
+	hostingContext := GetInitialContext(konfig)
+	if hostingContext == "" {
+		hostingContext = konfig.CurrentContext
+	}
+	hctx, ok := konfig.Contexts[hostingContext]
+	if !ok {
+		return
+	}
+	hosting, ok := konfig.Clusters[hctx.Cluster]
+	if !ok || hosting.Server == "" {
+		return
+	}
+	cluster.Server = hosting.Server
+	if len(hosting.CertificateAuthorityData) > 0 {
+		cluster.CertificateAuthorityData = hosting.CertificateAuthorityData
+	}
+}
+
+func loadControlPlaneKubeconfig(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string) (*clientcmdapi.Config, error) {
+	spec, err := util.GetControlPlaneTypeSpec(controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	return loadKubeconfigFromSecret(ctx, client, util.GenerateNamespaceFromControlPlaneName(name), spec.SecretName, spec.SecretKey)
+}
+
+// LoadKubeconfigFromSecret reads the kubeconfig stored under key in the secret namespace/secretName,
+// instead of the secret kubeflex generates in the control plane namespace. An empty key selects
+// the default kubeconfig key. It fails if the data is not a kubeconfig with a current context
+// and its cluster.
+func LoadKubeconfigFromSecret(ctx context.Context, client kubernetes.Clientset, namespace, secretName, key string) (*clientcmdapi.Config, error) {
+	if key == "" {
+		key = util.KubeconfigSecretKeyDefault
+	}
+	return loadKubeconfigFromSecret(ctx, &client, namespace, secretName, key)
+}
+
+func loadKubeconfigFromSecret(ctx context.Context, client kubernetes.Interface, namespace, secretName, key string) (*clientcmdapi.Config, error) {
+	ks, err := client.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := ks.Data[key]
+	if !ok {
+		return nil, &MissingSecretKeyError{Namespace: namespace, Name: ks.Name, Key: key}
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig in secret %s/%s key %s: %w", namespace, secretName, key, err)
+	}
+	if err := validateKubeconfig(config); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig in secret %s/%s key %s: %w", namespace, secretName, key, err)
+	}
+	return config, nil
+}
+
+// validateKubeconfig checks that config has a current context whose cluster is defined
+func validateKubeconfig(config *clientcmdapi.Config) error {
+	kctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return fmt.Errorf("no current context")
+	}
+	if _, ok := config.Clusters[kctx.Cluster]; !ok {
+		return fmt.Errorf("no cluster %s", kctx.Cluster)
+	}
+	return nil
+}
+
+// MissingSecretKeyError is returned when the kubeconfig secret of a control plane exists
+// but does not hold the key expected for the control plane type
+type MissingSecretKeyError struct {
+	Namespace string
+	Name      string
+	Key       string
+}
+
+func (e *MissingSecretKeyError) Error() string {
+	return fmt.Sprintf("kubeconfig secret %s/%s has no key %s", e.Namespace, e.Name, e.Key)
+}
+
+// NoInClusterKubeconfigError is returned when the in-cluster kubeconfig of a control plane is
+// requested and its control plane type has none
+type NoInClusterKubeconfigError struct {
+	ControlPlaneType string
+}
+
+func (e *NoInClusterKubeconfigError) Error() string {
+	return fmt.Sprintf("control plane type %s has no in-cluster kubeconfig", e.ControlPlaneType)
+}
+
+// DefaultKubeconfigPath returns the kubeconfig file read and written by kflex. It follows the
+// rules kubectl uses to pick the file where new entries are written: when KUBECONFIG lists
+// several files, the first one that exists is used, or the last one if none exists. Only that
+// file is read and written back, so the other files in the list are never overwritten.
+func DefaultKubeconfigPath() string {
+	return clientcmd.NewDefaultPathOptions().GetDefaultFilename()
 }
 
 func LoadKubeconfig(ctx context.Context) (*clientcmdapi.Config, error) {
-	kubeconfig := clientcmd.NewDefaultPathOptions().GetDefaultFilename()
-	return clientcmd.LoadFromFile(kubeconfig)
+	return LoadKubeconfigFromPath(DefaultKubeconfigPath())
 }
 
 func WriteKubeconfig(ctx context.Context, config *clientcmdapi.Config) error {
-	kubeconfig := clientcmd.NewDefaultPathOptions().GetDefaultFilename()
-	return clientcmd.WriteToFile(*config, kubeconfig)
+	return WriteKubeconfigToPath(DefaultKubeconfigPath(), config)
 }
 
-func WatchForSecretCreation(clientset kubernetes.Clientset, controlPlaneName, secretName string) error {
+// LoadKubeconfigFromPath loads the kubeconfig file at path. A missing file is loaded as an
+// empty config, so that the first control plane can be merged on a machine without a
+// kubeconfig; the file and its directory are created when the config is written.
+func LoadKubeconfigFromPath(path string) (*clientcmdapi.Config, error) {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return clientcmdapi.NewConfig(), nil
+		}
+		return nil, fmt.Errorf("error loading kubeconfig %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// WriteKubeconfigToPath writes config to the kubeconfig file at path. The file is replaced
+// atomically, so that an interrupted write leaves the original file untouched.
+func WriteKubeconfigToPath(path string, config *clientcmdapi.Config) error {
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(path, data)
+}
+
+// WriteKubeconfigIfChanged works as WriteKubeconfig but skips the write when the file already
+// holds the serialized config, and reports whether the file was written
+func WriteKubeconfigIfChanged(ctx context.Context, config *clientcmdapi.Config) (bool, error) {
+	return WriteKubeconfigToPathIfChanged(DefaultKubeconfigPath(), config)
+}
+
+// WriteKubeconfigToPathIfChanged writes config to the kubeconfig file at path unless the file
+// already holds the same bytes, so that its modification time is kept and file watchers are
+// not triggered. It reports whether the file was written.
+func WriteKubeconfigToPathIfChanged(path string, config *clientcmdapi.Config) (bool, error) {
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return false, err
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+	if err := writeFileAtomically(path, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// resolveSymlink returns the file path points at if it is a symlink, including a symlink to a
+// file not created yet, or path otherwise
+func resolveSymlink(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	link, err := os.Readlink(path)
+	if err != nil {
+		return path
+	}
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(filepath.Dir(path), link)
+	}
+	return link
+}
+
+// writeFileAtomically writes data to a temporary file in the same directory as path, flushes it
+// to disk and renames it over path. Readers never see a partially written file, and on failure
+// the original file is untouched. The directory of path is created if needed, and a symlink at
+// path is kept, its target being replaced. The mode of an existing file is kept, and new files
+// are only readable by their owner.
+func writeFileAtomically(path string, data []byte) error {
+	path = resolveSymlink(path)
+	mode := os.FileMode(0600)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	// the temporary file is already renamed when the write succeeds
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// persist the rename, best effort as not all platforms support syncing a directory
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// WatchForSecretCreation blocks until the secret named secretName exists in the namespace of
+// the control plane, or until ctx is cancelled or its deadline elapses. When requireKey is not
+// empty, it also waits until the secret holds a non-empty value for that key, so that a
+// placeholder secret created empty during provisioning is not mistaken for the real one. The
+// informer used to watch the secrets is stopped before returning.
+func WatchForSecretCreation(ctx context.Context, clientset kubernetes.Clientset, controlPlaneName, secretName, requireKey string) error {
+	return watchForSecretCreation(ctx, &clientset, controlPlaneName, secretName, requireKey)
+}
+
+func watchForSecretCreation(ctx context.Context, client kubernetes.Interface, controlPlaneName, secretName, requireKey string) error {
 	namespace := util.GenerateNamespaceFromControlPlaneName(controlPlaneName)
 
-	listwatch := cache.NewListWatchFromClient(
-		clientset.CoreV1().RESTClient(),
-		"secrets",
-		namespace,
-		fields.Everything(),
-	)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	found := make(chan struct{})
+	var once sync.Once
+	// seen records that the secret exists without the required key, onChange is called from a
+	// single goroutine that has returned once watchSecret does
+	seen := false
+	watchSecret(watchCtx, client, namespace, secretName, func(secret *v1.Secret) {
+		if requireKey != "" && len(secret.Data[requireKey]) == 0 {
+			seen = true
+			return
+		}
+		once.Do(func() {
+			close(found)
+			cancel()
+		})
+	})
 
-	stopCh := make(chan struct{})
+	// the secret may have been seen while the context was being cancelled
+	select {
+	case <-found:
+		return nil
+	default:
+	}
+	if seen {
+		return fmt.Errorf("timed out waiting for key %s in secret %s/%s: %w", requireKey, namespace, secretName, ctx.Err())
+	}
+	return fmt.Errorf("timed out waiting for secret %s/%s: %w", namespace, secretName, ctx.Err())
+}
+
+// WatchSecret calls onChange with the secret named secretName in namespace when it is created
+// and every time it is updated, so that consumers holding a client built from a kubeconfig
+// secret can rebuild it when the endpoint or the credentials are rotated. It blocks until ctx
+// is cancelled, and stops the informer used to watch the secret before returning. onChange is
+// called from a single goroutine.
+func WatchSecret(ctx context.Context, clientset kubernetes.Clientset, namespace, secretName string, onChange func(*v1.Secret)) {
+	watchSecret(ctx, &clientset, namespace, secretName, onChange)
+}
+
+func watchSecret(ctx context.Context, client kubernetes.Interface, namespace, secretName string, onChange func(*v1.Secret)) {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", secretName).String()
+	listwatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Secrets(namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Secrets(namespace).Watch(ctx, options)
+		},
+	}
 
 	_, controller := cache.NewInformer(
 		listwatch,
@@ -110,60 +815,203 @@ func WatchForSecretCreation(clientset kubernetes.Clientset, controlPlaneName, se
 		0,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
-				secret := obj.(*v1.Secret)
-				if secret.Name == secretName {
-					close(stopCh)
+				if secret := obj.(*v1.Secret); secret.Name == secretName {
+					onChange(secret)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				old, secret := oldObj.(*v1.Secret), newObj.(*v1.Secret)
+				if secret.Name == secretName && secret.ResourceVersion != old.ResourceVersion {
+					onChange(secret)
 				}
 			},
 		},
 	)
 
-	go controller.Run(stopCh)
-	<-stopCh
-	return nil
+	stopCh := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		controller.Run(stopCh)
+	}()
+	<-ctx.Done()
+	close(stopCh)
+	wg.Wait()
 }
 
+// adjustConfigKeys renames the entries of a control plane kubeconfig, named after the
+// conventions registered for its control plane type, to the kubeflex names of the control plane
 func adjustConfigKeys(config *clientcmdapi.Config, cpName, controlPlaneType string) {
-	switch controlPlaneType {
-	case string(tenancyv1alpha1.ControlPlaneTypeOCM):
-		renameKey(config.Clusters, "multicluster-controlplane", certs.GenerateClusterName(cpName))
-		renameKey(config.AuthInfos, "user", certs.GenerateAuthInfoAdminName(cpName))
-		renameKey(config.Contexts, "multicluster-controlplane", certs.GenerateContextName(cpName))
-		config.CurrentContext = certs.GenerateContextName(cpName)
-		config.Contexts[certs.GenerateContextName(cpName)] = &clientcmdapi.Context{
-			Cluster:  certs.GenerateClusterName(cpName),
-			AuthInfo: certs.GenerateAuthInfoAdminName(cpName),
+	spec, err := util.GetControlPlaneTypeSpec(controlPlaneType)
+	if err != nil {
+		return
+	}
+	if spec.ContextName == "" {
+		// kubeconfigs issued from external certs or supplied for adopted clusters may use
+		// generic names such as "kubernetes" that would collide with the entries of other
+		// control planes, so rename the current context
+		kctx, ok := config.Contexts[config.CurrentContext]
+		if !ok {
+			return
 		}
-	case string(tenancyv1alpha1.ControlPlaneTypeVCluster):
-		renameKey(config.Clusters, "my-vcluster", certs.GenerateClusterName(cpName))
-		renameKey(config.AuthInfos, "my-vcluster", certs.GenerateAuthInfoAdminName(cpName))
-		renameKey(config.Contexts, "my-vcluster", certs.GenerateContextName(cpName))
-		config.CurrentContext = certs.GenerateContextName(cpName)
+		renameConfigKeys(config, kctx.Cluster, kctx.AuthInfo, config.CurrentContext, cpName)
+		return
+	}
+	renameConfigKeys(config, spec.ClusterName, spec.AuthInfoName, spec.ContextName, cpName)
+}
+
+// adjustKubeconfig adjusts a control plane kubeconfig as selected by o before it is merged.
+// Its keys are renamed to the kubeflex names unless the original names are preserved.
+func (o *mergeOptions) adjustKubeconfig(config *clientcmdapi.Config, cpName, controlPlaneType string) {
+	if !o.preserveNames {
+		adjustConfigKeys(config, cpName, controlPlaneType)
+		renameContext(config, cpName, o.contextName)
+	}
+	setClusterTLS(config, o.caData, o.insecure)
+	setClusterProxy(config, o.proxyURL, o.tlsServerName)
+	setContextNamespace(config, o.defaultNamespace)
+	if o.execCredential {
+		setExecCredential(config, cpName)
+	}
+}
+
+// setContextNamespace sets the namespace of the current context of a control plane kubeconfig.
+// An empty namespace leaves the context unchanged.
+func setContextNamespace(config *clientcmdapi.Config, namespace string) {
+	if namespace == "" {
+		return
+	}
+	if kctx, ok := config.Contexts[config.CurrentContext]; ok {
+		kctx.Namespace = namespace
+	}
+}
+
+// setClusterTLS sets how the cluster of the current context of a control plane kubeconfig
+// verifies the API server certificate. A non empty caData replaces its CA bundle; insecure
+// skips the verification and drops the CA bundle, which kubectl rejects together with
+// insecure-skip-tls-verify.
+func setClusterTLS(config *clientcmdapi.Config, caData []byte, insecure bool) {
+	kctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return
+	}
+	cluster, ok := config.Clusters[kctx.Cluster]
+	if !ok {
+		return
+	}
+	if len(caData) > 0 {
+		cluster.CertificateAuthorityData = caData
+		cluster.CertificateAuthority = ""
+		cluster.InsecureSkipTLSVerify = false
+	}
+	if insecure {
+		cluster.CertificateAuthorityData = nil
+		cluster.CertificateAuthority = ""
+		cluster.InsecureSkipTLSVerify = true
+	}
+}
+
+// setClusterProxy sets the proxy-url and tls-server-name of the cluster of the current context of
+// a control plane kubeconfig. Empty values leave the cluster unchanged.
+func setClusterProxy(config *clientcmdapi.Config, proxyURL, tlsServerName string) {
+	kctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return
+	}
+	cluster, ok := config.Clusters[kctx.Cluster]
+	if !ok {
+		return
+	}
+	if proxyURL != "" {
+		cluster.ProxyURL = proxyURL
+	}
+	if tlsServerName != "" {
+		cluster.TLSServerName = tlsServerName
+	}
+}
+
+// renameContext renames the context of a control plane kubeconfig whose keys were adjusted by
+// adjustConfigKeys to contextName. An empty contextName keeps the generated name.
+func renameContext(config *clientcmdapi.Config, cpName, contextName string) {
+	if contextName == "" {
+		return
+	}
+	renameKey(config, config.Contexts, certs.GenerateContextName(cpName), contextName)
+}
+
+// validateContextName returns an error if config has a context named contextName that does
+// not belong to the control plane, so that a context name override never replaces the context
+// of another cluster
+func validateContextName(config *clientcmdapi.Config, cpName, contextName string) error {
+	if contextName == "" {
+		return nil
+	}
+	kctx, ok := config.Contexts[contextName]
+	if !ok || isControlPlaneContext(kctx, cpName) {
+		return nil
+	}
+	return fmt.Errorf("context %s already exists in the kubeconfig for cluster %s", contextName, kctx.Cluster)
+}
+
+// isControlPlaneContext returns true if kctx uses the cluster and authInfo generated for the
+// control plane, whatever the name of the context
+func isControlPlaneContext(kctx *clientcmdapi.Context, cpName string) bool {
+	return kctx.Cluster == certs.GenerateClusterName(cpName) && kctx.AuthInfo == certs.GenerateAuthInfoAdminName(cpName)
+}
+
+// renameConfigKeys renames the cluster, authInfo and context of a control plane kubeconfig to
+// the names kubeflex uses for the control plane, and makes the context the current context.
+// The context is created if the kubeconfig has none with the given name.
+func renameConfigKeys(config *clientcmdapi.Config, cluster, authInfo, contextName, cpName string) {
+	renameKey(config, config.Clusters, cluster, certs.GenerateClusterName(cpName))
+	renameKey(config, config.AuthInfos, authInfo, certs.GenerateAuthInfoAdminName(cpName))
+	renameKey(config, config.Contexts, contextName, certs.GenerateContextName(cpName))
+	if _, ok := config.Contexts[certs.GenerateContextName(cpName)]; !ok {
 		config.Contexts[certs.GenerateContextName(cpName)] = &clientcmdapi.Context{
 			Cluster:  certs.GenerateClusterName(cpName),
 			AuthInfo: certs.GenerateAuthInfoAdminName(cpName),
 		}
-	default:
-		return
 	}
+	config.CurrentContext = certs.GenerateContextName(cpName)
 }
 
-func renameKey(m interface{}, oldKey string, newKey string) interface{} {
+// renameKey renames an entry of one of the maps of config and rewrites the references to it,
+// so that the contexts using a renamed cluster or authInfo, and the current context, keep
+// pointing at the renamed entry. The entry itself is moved unchanged, so that exec and auth
+// provider credentials as well as tokens are kept
+func renameKey(config *clientcmdapi.Config, m interface{}, oldKey string, newKey string) interface{} {
+	if oldKey == newKey {
+		return m
+	}
 	switch v := m.(type) {
 	case map[string]*clientcmdapi.Cluster:
 		if cluster, ok := v[oldKey]; ok {
 			delete(v, oldKey)
 			v[newKey] = cluster
+			for _, kctx := range config.Contexts {
+				if kctx.Cluster == oldKey {
+					kctx.Cluster = newKey
+				}
+			}
 		}
 	case map[string]*clientcmdapi.AuthInfo:
 		if authInfo, ok := v[oldKey]; ok {
 			delete(v, oldKey)
 			v[newKey] = authInfo
+			for _, kctx := range config.Contexts {
+				if kctx.AuthInfo == oldKey {
+					kctx.AuthInfo = newKey
+				}
+			}
 		}
 	case map[string]*clientcmdapi.Context:
 		if context, ok := v[oldKey]; ok {
 			delete(v, oldKey)
 			v[newKey] = context
+			if config.CurrentContext == oldKey {
+				config.CurrentContext = newKey
+			}
 		}
 	default:
 		// no action
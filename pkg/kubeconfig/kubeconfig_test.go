@@ -0,0 +1,1330 @@
+package kubeconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestRemoveControlPlaneFromKubeconfig(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	if err := merge(config, generateTestConfig("cp2", "https://cp2.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	config.CurrentContext = certs.GenerateContextName("cp1")
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	ctx := context.Background()
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	if err := RemoveControlPlaneFromKubeconfig(ctx, "cp1", cpType); err != nil {
+		t.Fatalf("RemoveControlPlaneFromKubeconfig returned error: %v", err)
+	}
+	config = loadTestKubeconfig(t, kubeconfigPath)
+	if _, ok := config.Contexts[certs.GenerateContextName("cp1")]; ok {
+		t.Errorf("expected context for cp1 to be removed")
+	}
+	if _, ok := config.Clusters[certs.GenerateClusterName("cp1")]; ok {
+		t.Errorf("expected cluster for cp1 to be removed")
+	}
+	if _, ok := config.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")]; ok {
+		t.Errorf("expected authInfo for cp1 to be removed")
+	}
+	if config.CurrentContext != certs.GenerateContextName("cp2") {
+		t.Errorf("expected current context to fall back to %s, got %s", certs.GenerateContextName("cp2"), config.CurrentContext)
+	}
+
+	// removing again is a no-op
+	if err := RemoveControlPlaneFromKubeconfig(ctx, "cp1", cpType); err != nil {
+		t.Fatalf("RemoveControlPlaneFromKubeconfig returned error for absent entries: %v", err)
+	}
+
+	if err := RemoveControlPlaneFromKubeconfig(ctx, "cp2", cpType); err != nil {
+		t.Fatalf("RemoveControlPlaneFromKubeconfig returned error: %v", err)
+	}
+	config = loadTestKubeconfig(t, kubeconfigPath)
+	if len(config.Contexts) != 0 || config.CurrentContext != "" {
+		t.Errorf("expected no contexts and an empty current context, got %v and %q", config.Contexts, config.CurrentContext)
+	}
+}
+
+func TestRemoveControlPlanesFromKubeconfig(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	for _, name := range []string{"cp2", "cp3"} {
+		if err := merge(config, generateTestConfig(name, "https://"+name+".localtest.me:9443")); err != nil {
+			t.Fatalf("error merging test config: %v", err)
+		}
+	}
+	if err := merge(config, generateTestInternalConfig("cp2", "https://10.96.0.10:443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+	config.CurrentContext = certs.GenerateContextName("cp1")
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	ctx := context.Background()
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+
+	// invalid refs fail the whole batch
+	if _, err := RemoveControlPlanesFromKubeconfig(ctx, []ControlPlaneRef{{Name: "cp1"}, {Name: ""}, {Name: "cp2"}, {Name: "cp2"}}); err == nil {
+		t.Fatalf("expected error for invalid refs")
+	}
+	if config = loadTestKubeconfig(t, kubeconfigPath); len(config.Contexts) != 5 {
+		t.Fatalf("expected no entry to be removed for invalid refs, got %v", config.Contexts)
+	}
+
+	removed, err := RemoveControlPlanesFromKubeconfig(ctx, []ControlPlaneRef{{Name: "cp2", Type: cpType}, {Name: "missing", Type: cpType}, {Name: "cp1", Type: cpType}})
+	if err != nil {
+		t.Fatalf("RemoveControlPlanesFromKubeconfig returned error: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"cp1", "cp2"}) {
+		t.Errorf("expected removed [cp1 cp2], got %v", removed)
+	}
+	config = loadTestKubeconfig(t, kubeconfigPath)
+	for _, name := range []string{"cp1", "cp2", "cp2" + InternalContextSuffix} {
+		if _, ok := config.Contexts[certs.GenerateContextName(name)]; ok {
+			t.Errorf("expected context for %s to be removed", name)
+		}
+		if _, ok := config.Clusters[certs.GenerateClusterName(name)]; ok {
+			t.Errorf("expected cluster for %s to be removed", name)
+		}
+	}
+	if _, ok := config.Contexts[certs.GenerateContextName("cp3")]; !ok {
+		t.Errorf("expected context for cp3 to be kept")
+	}
+	if _, ok := config.Contexts[config.CurrentContext]; !ok {
+		t.Errorf("expected current context to fall back to a remaining context, got %q", config.CurrentContext)
+	}
+
+	removed, err = RemoveControlPlanesFromKubeconfig(ctx, []ControlPlaneRef{{Name: "cp1", Type: cpType}})
+	if err != nil || len(removed) != 0 {
+		t.Errorf("expected no-op for absent entries, got %v (%v)", removed, err)
+	}
+}
+
+func TestRemoveControlPlaneKeepsControlPlaneNamedInternal(t *testing.T) {
+	// foo-internal is a control plane of its own, not the internal context of foo
+	config := generateTestConfig("foo", "https://foo.localtest.me:9443")
+	if err := merge(config, generateTestConfig("foo"+InternalContextSuffix, "https://foo-internal.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	if err := merge(config, generateTestConfig("bar", "https://bar.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	if err := merge(config, generateTestInternalConfig("bar", "https://bar.bar-system.svc.cluster.local")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	ctx := context.Background()
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	if err := RemoveControlPlaneFromKubeconfig(ctx, "foo", cpType); err != nil {
+		t.Fatalf("RemoveControlPlaneFromKubeconfig returned error: %v", err)
+	}
+	if _, err := RemoveControlPlanesFromKubeconfig(ctx, []ControlPlaneRef{{Name: "bar"}}); err != nil {
+		t.Fatalf("RemoveControlPlanesFromKubeconfig returned error: %v", err)
+	}
+	config = loadTestKubeconfig(t, kubeconfigPath)
+	for _, name := range []string{"foo", "bar", "bar" + InternalContextSuffix} {
+		if _, ok := config.Clusters[certs.GenerateClusterName(name)]; ok {
+			t.Errorf("expected cluster for %s to be removed", name)
+		}
+	}
+	name := "foo" + InternalContextSuffix
+	if _, ok := config.Contexts[certs.GenerateContextName(name)]; !ok {
+		t.Errorf("expected context for %s to be kept", name)
+	}
+	if _, ok := config.Clusters[certs.GenerateClusterName(name)]; !ok {
+		t.Errorf("expected cluster for %s to be kept", name)
+	}
+	if _, ok := config.AuthInfos[certs.GenerateAuthInfoAdminName(name)]; !ok {
+		t.Errorf("expected authInfo for %s to be kept", name)
+	}
+	contexts := listControlPlaneContexts(config)
+	if len(contexts) != 1 || contexts[0].ControlPlaneName != name || contexts[0].Internal {
+		t.Errorf("expected %s to be listed as a control plane, got %+v", name, contexts)
+	}
+}
+
+func TestRenameControlPlaneContext(t *testing.T) {
+	config := generateTestConfig("old", "https://old.localtest.me:9443")
+	if err := merge(config, generateTestConfig("cp2", "https://cp2.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	config.CurrentContext = certs.GenerateContextName("old")
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	ctx := context.Background()
+	if err := RenameControlPlaneContext(ctx, "old", "new", string(tenancyv1alpha1.ControlPlaneTypeK8S)); err != nil {
+		t.Fatalf("RenameControlPlaneContext returned error: %v", err)
+	}
+	config = loadTestKubeconfig(t, kubeconfigPath)
+	if _, ok := config.Contexts[certs.GenerateContextName("old")]; ok {
+		t.Errorf("expected context for old to be renamed")
+	}
+	kctx, ok := config.Contexts[certs.GenerateContextName("new")]
+	if !ok {
+		t.Fatalf("expected context for new")
+	}
+	if kctx.Cluster != certs.GenerateClusterName("new") || kctx.AuthInfo != certs.GenerateAuthInfoAdminName("new") {
+		t.Errorf("expected the renamed context to reference the renamed entries, got %+v", kctx)
+	}
+	if server := config.Clusters[certs.GenerateClusterName("new")].Server; server != "https://old.localtest.me:9443" {
+		t.Errorf("expected the renamed cluster to keep its server, got %s", server)
+	}
+	if config.CurrentContext != certs.GenerateContextName("new") {
+		t.Errorf("expected current context %s, got %s", certs.GenerateContextName("new"), config.CurrentContext)
+	}
+	if _, ok := config.Contexts[certs.GenerateContextName("cp2")]; !ok {
+		t.Errorf("expected context for cp2 to be left untouched")
+	}
+
+	if err := RenameControlPlaneContext(ctx, "old", "other", string(tenancyv1alpha1.ControlPlaneTypeK8S)); err == nil {
+		t.Errorf("expected error when the old context is not present")
+	}
+	if err := RenameControlPlaneContext(ctx, "new", "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S)); err == nil {
+		t.Errorf("expected error when the new context already exists")
+	}
+	if err := RenameControlPlaneContext(ctx, "new", "other", "unknown"); err == nil {
+		t.Errorf("expected error for an unsupported control plane type")
+	}
+}
+
+func TestRenameControlPlaneEntriesInternalAndOverride(t *testing.T) {
+	config := generateTestConfig("old", "https://old.localtest.me:9443")
+	if err := merge(config, generateTestConfig("old"+InternalContextSuffix, "https://old.old-system.svc.cluster.local")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	// the context was renamed with spec.contextName
+	renameKey(config, config.Contexts, certs.GenerateContextName("old"), "prod")
+
+	spec, err := util.GetControlPlaneTypeSpec(string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	if err != nil {
+		t.Fatalf("GetControlPlaneTypeSpec returned error: %v", err)
+	}
+	if err := renameControlPlaneEntries(config, "old", "new", spec); err != nil {
+		t.Fatalf("renameControlPlaneEntries returned error: %v", err)
+	}
+	kctx, ok := config.Contexts["prod"]
+	if !ok {
+		t.Fatalf("expected the overridden context name to be kept")
+	}
+	if !isControlPlaneContext(kctx, "new") {
+		t.Errorf("expected the context to reference the renamed entries, got %+v", kctx)
+	}
+	internalName := "new" + InternalContextSuffix
+	kctx, ok = config.Contexts[certs.GenerateContextName(internalName)]
+	if !ok {
+		t.Fatalf("expected the internal context to be renamed")
+	}
+	if !isControlPlaneContext(kctx, internalName) {
+		t.Errorf("expected the internal context to reference the renamed entries, got %+v", kctx)
+	}
+	for _, name := range []string{"old", "old" + InternalContextSuffix} {
+		if _, ok := config.Clusters[certs.GenerateClusterName(name)]; ok {
+			t.Errorf("expected cluster for %s to be renamed", name)
+		}
+		if _, ok := config.AuthInfos[certs.GenerateAuthInfoAdminName(name)]; ok {
+			t.Errorf("expected authInfo for %s to be renamed", name)
+		}
+	}
+	if len(config.Contexts) != 2 {
+		t.Errorf("expected 2 contexts, got %d", len(config.Contexts))
+	}
+}
+
+func TestRenameControlPlaneEntriesWithoutInternalContext(t *testing.T) {
+	config := generateTestConfig("old", "https://old.localtest.me:9443")
+	// ocm control planes have no in-cluster kubeconfig, so these are the entries of another
+	// control plane named old-internal
+	if err := merge(config, generateTestConfig("old"+InternalContextSuffix, "https://old-internal.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	spec, err := util.GetControlPlaneTypeSpec(string(tenancyv1alpha1.ControlPlaneTypeOCM))
+	if err != nil {
+		t.Fatalf("GetControlPlaneTypeSpec returned error: %v", err)
+	}
+	if err := renameControlPlaneEntries(config, "old", "new", spec); err != nil {
+		t.Fatalf("renameControlPlaneEntries returned error: %v", err)
+	}
+	if _, ok := config.Contexts[certs.GenerateContextName("new")]; !ok {
+		t.Errorf("expected context for new")
+	}
+	if _, ok := config.Contexts[certs.GenerateContextName("old"+InternalContextSuffix)]; !ok {
+		t.Errorf("expected the context of %s to be left untouched", "old"+InternalContextSuffix)
+	}
+	if _, ok := config.Clusters[certs.GenerateClusterName("new"+InternalContextSuffix)]; ok {
+		t.Errorf("expected no cluster for %s", "new"+InternalContextSuffix)
+	}
+}
+
+func TestKubeconfigPathWithMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	missingPath := filepath.Join(dir, "missing")
+	firstPath := filepath.Join(dir, "first")
+	secondPath := filepath.Join(dir, "second")
+	if err := WriteKubeconfigToPath(firstPath, generateTestConfig("cp1", "https://cp1.localtest.me:9443")); err != nil {
+		t.Fatalf("WriteKubeconfigToPath returned error: %v", err)
+	}
+	if err := WriteKubeconfigToPath(secondPath, generateTestConfig("cp2", "https://cp2.localtest.me:9443")); err != nil {
+		t.Fatalf("WriteKubeconfigToPath returned error: %v", err)
+	}
+	secondBefore, err := os.ReadFile(secondPath)
+	if err != nil {
+		t.Fatalf("error reading kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, strings.Join([]string{missingPath, firstPath, secondPath}, string(os.PathListSeparator)))
+
+	if path := DefaultKubeconfigPath(); path != firstPath {
+		t.Fatalf("expected default kubeconfig path %s, got %s", firstPath, path)
+	}
+
+	ctx := context.Background()
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		t.Fatalf("LoadKubeconfig returned error: %v", err)
+	}
+	if err := CloneContext(config, "cp1", "cp1-clone", "https://cp1-clone.localtest.me:9443"); err != nil {
+		t.Fatalf("CloneContext returned error: %v", err)
+	}
+	if err := WriteKubeconfig(ctx, config); err != nil {
+		t.Fatalf("WriteKubeconfig returned error: %v", err)
+	}
+
+	if _, ok := loadTestKubeconfig(t, firstPath).Contexts[certs.GenerateContextName("cp1-clone")]; !ok {
+		t.Errorf("expected the new context to be written to %s", firstPath)
+	}
+	secondAfter, err := os.ReadFile(secondPath)
+	if err != nil {
+		t.Fatalf("error reading kubeconfig: %v", err)
+	}
+	if string(secondBefore) != string(secondAfter) {
+		t.Errorf("expected %s to be left untouched", secondPath)
+	}
+	if _, err := os.Stat(missingPath); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to be created", missingPath)
+	}
+}
+
+func TestAdjustConfigKeysK8s(t *testing.T) {
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	genericConfig := func(server string) *clientcmdapi.Config {
+		config := clientcmdapi.NewConfig()
+		config.Clusters["kubernetes"] = &clientcmdapi.Cluster{Server: server}
+		config.AuthInfos["kubernetes-admin"] = &clientcmdapi.AuthInfo{Token: "token-" + server}
+		config.Contexts["kubernetes-admin@kubernetes"] = &clientcmdapi.Context{Cluster: "kubernetes", AuthInfo: "kubernetes-admin", Namespace: "default"}
+		config.CurrentContext = "kubernetes-admin@kubernetes"
+		return config
+	}
+
+	merged := clientcmdapi.NewConfig()
+	for _, cpName := range []string{"cp1", "cp2"} {
+		config := genericConfig("https://" + cpName + ".localtest.me:9443")
+		adjustConfigKeys(config, cpName, cpType)
+		if config.CurrentContext != certs.GenerateContextName(cpName) {
+			t.Errorf("expected current context %s, got %s", certs.GenerateContextName(cpName), config.CurrentContext)
+		}
+		if err := merge(merged, config); err != nil {
+			t.Fatalf("error merging config for %s: %v", cpName, err)
+		}
+	}
+
+	for _, cpName := range []string{"cp1", "cp2"} {
+		kctx, ok := merged.Contexts[certs.GenerateContextName(cpName)]
+		if !ok {
+			t.Fatalf("expected context for %s", cpName)
+		}
+		if kctx.Cluster != certs.GenerateClusterName(cpName) || kctx.AuthInfo != certs.GenerateAuthInfoAdminName(cpName) || kctx.Namespace != "default" {
+			t.Errorf("unexpected context for %s: %+v", cpName, kctx)
+		}
+		cluster, ok := merged.Clusters[certs.GenerateClusterName(cpName)]
+		if !ok || cluster.Server != "https://"+cpName+".localtest.me:9443" {
+			t.Errorf("expected cluster for %s with its own server, got %+v", cpName, cluster)
+		}
+		authInfo, ok := merged.AuthInfos[certs.GenerateAuthInfoAdminName(cpName)]
+		if !ok || authInfo.Token != "token-https://"+cpName+".localtest.me:9443" {
+			t.Errorf("expected authInfo for %s with its own credentials, got %+v", cpName, authInfo)
+		}
+	}
+	if len(merged.Clusters) != 2 || len(merged.AuthInfos) != 2 || len(merged.Contexts) != 2 {
+		t.Errorf("expected no generic entries left, got clusters %d, authInfos %d, contexts %d",
+			len(merged.Clusters), len(merged.AuthInfos), len(merged.Contexts))
+	}
+
+	// kubeconfigs generated by kubeflex keep their names
+	config := generateTestConfig("cp3", "https://cp3.localtest.me:9443")
+	adjustConfigKeys(config, "cp3", cpType)
+	if _, ok := config.Clusters[certs.GenerateClusterName("cp3")]; !ok {
+		t.Errorf("expected cluster %s to be kept", certs.GenerateClusterName("cp3"))
+	}
+	if _, ok := config.AuthInfos[certs.GenerateAuthInfoAdminName("cp3")]; !ok {
+		t.Errorf("expected authInfo %s to be kept", certs.GenerateAuthInfoAdminName("cp3"))
+	}
+}
+
+func TestAdjustConfigKeysHost(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[util.HostClusterName] = &clientcmdapi.Cluster{Server: "https://kubernetes.default.svc"}
+	config.AuthInfos[util.HostServiceAccountName] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts[util.HostClusterName] = &clientcmdapi.Context{Cluster: util.HostClusterName, AuthInfo: util.HostServiceAccountName, Namespace: "cp1-system"}
+	config.CurrentContext = util.HostClusterName
+
+	adjustConfigKeys(config, "cp1", string(tenancyv1alpha1.ControlPlaneTypeHost))
+	kctx, ok := config.Contexts[certs.GenerateContextName("cp1")]
+	if !ok || config.CurrentContext != certs.GenerateContextName("cp1") {
+		t.Fatalf("expected current context %s, got %s", certs.GenerateContextName("cp1"), config.CurrentContext)
+	}
+	if kctx.Cluster != certs.GenerateClusterName("cp1") || kctx.AuthInfo != certs.GenerateAuthInfoAdminName("cp1") || kctx.Namespace != "cp1-system" {
+		t.Errorf("unexpected context %+v", kctx)
+	}
+	if len(config.Clusters) != 1 || len(config.AuthInfos) != 1 || len(config.Contexts) != 1 {
+		t.Errorf("expected no host entries left, got %+v", config)
+	}
+}
+
+func TestLoadAndMergeHostServer(t *testing.T) {
+	hostKubeconfig := func(server string) []byte {
+		config := clientcmdapi.NewConfig()
+		config.Clusters[util.HostClusterName] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: []byte("sa-ca")}
+		config.AuthInfos[util.HostServiceAccountName] = &clientcmdapi.AuthInfo{Token: "token"}
+		config.Contexts[util.HostClusterName] = &clientcmdapi.Context{Cluster: util.HostClusterName, AuthInfo: util.HostServiceAccountName, Namespace: "cp1-system"}
+		config.CurrentContext = util.HostClusterName
+		data, err := clientcmd.Write(*config)
+		if err != nil {
+			t.Fatalf("error writing test kubeconfig: %v", err)
+		}
+		return data
+	}
+	hostingConfig := func() *clientcmdapi.Config {
+		konfig := clientcmdapi.NewConfig()
+		konfig.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443", CertificateAuthorityData: []byte("hosting-ca")}
+		konfig.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "admin"}
+		konfig.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+		konfig.CurrentContext = "kind-kubeflex"
+		return konfig
+	}
+
+	for _, tc := range []struct {
+		name       string
+		server     string
+		wantServer string
+		wantCA     string
+	}{
+		{name: "in-cluster server", server: util.HostInClusterServer, wantServer: "https://127.0.0.1:6443", wantCA: "hosting-ca"},
+		{name: "configured server", server: "https://host.example.com:6443", wantServer: "https://host.example.com:6443", wantCA: "sa-ca"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hostClient := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: util.HostKubeConfigSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp1")},
+				Data: map[string][]byte{
+					util.KubeconfigSecretKeyDefault:   hostKubeconfig(tc.server),
+					util.KubeconfigSecretKeyInCluster: hostKubeconfig(util.HostInClusterServer),
+				},
+			})
+			konfig := hostingConfig()
+			o := newMergeOptions([]MergeOption{WithInternalContext(true)})
+			if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeHost), konfig, o); err != nil {
+				t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+			}
+			cluster := konfig.Clusters[certs.GenerateClusterName("cp1")]
+			if cluster == nil || cluster.Server != tc.wantServer || string(cluster.CertificateAuthorityData) != tc.wantCA {
+				t.Errorf("expected server %s with CA %s, got %+v", tc.wantServer, tc.wantCA, cluster)
+			}
+			// the internal context keeps the in-cluster server
+			internal := konfig.Clusters[certs.GenerateClusterName("cp1"+InternalContextSuffix)]
+			if internal == nil || internal.Server != util.HostInClusterServer {
+				t.Errorf("expected internal server %s, got %+v", util.HostInClusterServer, internal)
+			}
+		})
+	}
+}
+
+func loadTestKubeconfig(t *testing.T, kubeconfigPath string) *clientcmdapi.Config {
+	t.Helper()
+	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	return config
+}
+
+func TestWatchForSecretCreation(t *testing.T) {
+	namespace := util.GenerateNamespaceFromControlPlaneName("cp1")
+	client := fake.NewSimpleClientset()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = client.CoreV1().Secrets(namespace).Create(context.Background(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: namespace},
+		}, metav1.CreateOptions{})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := watchForSecretCreation(ctx, client, "cp1", util.AdminConfSecret, ""); err != nil {
+		t.Fatalf("watchForSecretCreation returned error: %v", err)
+	}
+}
+
+func TestWatchSecret(t *testing.T) {
+	namespace := util.GenerateNamespaceFromControlPlaneName("cp1")
+	client := fake.NewSimpleClientset()
+
+	changes := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchSecret(ctx, client, namespace, util.AdminConfSecret, func(secret *corev1.Secret) {
+			changes <- string(secret.Data["kubeconfig"])
+		})
+	}()
+
+	secrets := client.CoreV1().Secrets(namespace)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: namespace, ResourceVersion: "1"},
+		Data:       map[string][]byte{"kubeconfig": []byte("v1")},
+	}
+	if _, err := secrets.Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("error creating secret: %v", err)
+	}
+	// other secrets in the namespace are ignored
+	if _, err := secrets.Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: namespace},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("error creating secret: %v", err)
+	}
+	expectChange := func(expected string) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != expected {
+				t.Errorf("expected change %s, got %s", expected, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for change %s", expected)
+		}
+	}
+	expectChange("v1")
+
+	secret.ResourceVersion = "2"
+	secret.Data["kubeconfig"] = []byte("v2")
+	if _, err := secrets.Update(context.Background(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("error updating secret: %v", err)
+	}
+	expectChange("v2")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watchSecret did not return after the context was cancelled")
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no other changes, got %d", len(changes))
+	}
+}
+
+func TestWatchForSecretCreationTimeout(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := watchForSecretCreation(ctx, client, "cp1", "missing", "")
+	if err == nil {
+		t.Fatalf("expected error when the secret is never created")
+	}
+	expected := "timed out waiting for secret " + util.GenerateNamespaceFromControlPlaneName("cp1") + "/missing"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error to contain %q, got %v", expected, err)
+	}
+}
+
+func TestWatchForSecretCreationRequireKey(t *testing.T) {
+	namespace := util.GenerateNamespaceFromControlPlaneName("cp1")
+	placeholder := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: namespace, ResourceVersion: "1"},
+	}
+	client := fake.NewSimpleClientset(placeholder)
+
+	// the placeholder secret does not complete the watch
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := watchForSecretCreation(ctx, client, "cp1", util.AdminConfSecret, util.KubeconfigSecretKeyDefault)
+	expected := "timed out waiting for key " + util.KubeconfigSecretKeyDefault + " in secret " + namespace + "/" + util.AdminConfSecret
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected error to contain %q, got %v", expected, err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		secret := placeholder.DeepCopy()
+		secret.ResourceVersion = "2"
+		secret.Data = map[string][]byte{util.KubeconfigSecretKeyDefault: []byte("kubeconfig")}
+		_, _ = client.CoreV1().Secrets(namespace).Update(context.Background(), secret, metav1.UpdateOptions{})
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := watchForSecretCreation(ctx, client, "cp1", util.AdminConfSecret, util.KubeconfigSecretKeyDefault); err != nil {
+		t.Fatalf("watchForSecretCreation returned error: %v", err)
+	}
+}
+
+func TestLoadControlPlaneKubeconfigMissingKey(t *testing.T) {
+	namespace := util.GenerateNamespaceFromControlPlaneName("cp1")
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: namespace},
+		Data:       map[string][]byte{"other-key": []byte("data")},
+	})
+
+	_, err := loadControlPlaneKubeconfig(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	var missingKeyErr *MissingSecretKeyError
+	if !errors.As(err, &missingKeyErr) {
+		t.Fatalf("expected MissingSecretKeyError, got %v", err)
+	}
+	if missingKeyErr.Namespace != namespace || missingKeyErr.Name != util.AdminConfSecret || missingKeyErr.Key != util.KubeconfigSecretKeyDefault {
+		t.Errorf("unexpected error fields %+v", missingKeyErr)
+	}
+}
+
+func TestLoadKubeconfigFromSecret(t *testing.T) {
+	valid, err := clientcmd.Write(*generateTestConfig("managed", "https://managed.example.com"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	noContext := generateTestConfig("managed", "https://managed.example.com")
+	noContext.CurrentContext = ""
+	invalid, err := clientcmd.Write(*noContext)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "managed-kubeconfig", Namespace: "team-a"},
+		Data: map[string][]byte{
+			util.KubeconfigSecretKeyDefault: valid,
+			"no-context":                    invalid,
+			"garbage":                       []byte("not a kubeconfig"),
+		},
+	})
+
+	config, err := loadKubeconfigFromSecret(context.Background(), hostClient, "team-a", "managed-kubeconfig", util.KubeconfigSecretKeyDefault)
+	if err != nil {
+		t.Fatalf("loadKubeconfigFromSecret returned error: %v", err)
+	}
+	if config.CurrentContext != certs.GenerateContextName("managed") {
+		t.Errorf("unexpected current context %s", config.CurrentContext)
+	}
+
+	for _, key := range []string{"no-context", "garbage"} {
+		if _, err := loadKubeconfigFromSecret(context.Background(), hostClient, "team-a", "managed-kubeconfig", key); err == nil {
+			t.Errorf("expected error for invalid kubeconfig under key %s", key)
+		}
+	}
+}
+
+func TestLoadAndMergeExternalSecretRef(t *testing.T) {
+	// the kubeconfig of an adopted cluster uses generic names and lives in a user namespace
+	adopted := clientcmdapi.NewConfig()
+	adopted.Clusters["kubernetes"] = &clientcmdapi.Cluster{Server: "https://managed.example.com"}
+	adopted.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	adopted.Contexts["admin@kubernetes"] = &clientcmdapi.Context{Cluster: "kubernetes", AuthInfo: "admin"}
+	adopted.CurrentContext = "admin@kubernetes"
+	data, err := clientcmd.Write(*adopted)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "managed-kubeconfig", Namespace: "team-a"},
+		Data:       map[string][]byte{"value": data},
+	})
+	ref := &tenancyv1alpha1.SecretReference{Namespace: "team-a", Name: "managed-kubeconfig", Key: "value"}
+
+	konfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	entry, err := loadAndMerge(context.Background(), hostClient, "managed", string(tenancyv1alpha1.ControlPlaneTypeExternal), ref, konfig)
+	if err != nil {
+		t.Fatalf("loadAndMerge returned error: %v", err)
+	}
+	if entry.ContextName != certs.GenerateContextName("managed") {
+		t.Errorf("expected context %s, got %s", certs.GenerateContextName("managed"), entry.ContextName)
+	}
+	cluster, ok := konfig.Clusters[certs.GenerateClusterName("managed")]
+	if !ok || cluster.Server != "https://managed.example.com" {
+		t.Errorf("expected cluster %s with the adopted server, got %+v", certs.GenerateClusterName("managed"), cluster)
+	}
+	if _, ok := konfig.Clusters["kubernetes"]; ok {
+		t.Errorf("expected generic cluster name to be renamed")
+	}
+	if _, ok := konfig.Contexts[certs.GenerateContextName("cp1")]; !ok {
+		t.Errorf("expected existing control plane context to be kept")
+	}
+}
+
+func TestLoadAndMergeWithoutSettingCurrentContext(t *testing.T) {
+	cpKonfig := generateTestConfig("cp2", "https://cp2.localtest.me:9443")
+	data, err := clientcmd.Write(*cpKonfig)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	konfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	o := newMergeOptions([]MergeOption{WithSetCurrentContext(false)})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if _, ok := konfig.Contexts[certs.GenerateContextName("cp2")]; !ok {
+		t.Errorf("expected context %s to be merged", certs.GenerateContextName("cp2"))
+	}
+	if konfig.CurrentContext != certs.GenerateContextName("cp1") {
+		t.Errorf("expected current context to stay %s, got %s", certs.GenerateContextName("cp1"), konfig.CurrentContext)
+	}
+
+	if GetPreviousContext(konfig) != "" {
+		t.Errorf("expected no previous context recorded without a switch, got %s", GetPreviousContext(konfig))
+	}
+
+	// the default keeps switching to the merged context and records the one it replaced
+	entry, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, newMergeOptions(nil))
+	if err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if konfig.CurrentContext != certs.GenerateContextName("cp2") {
+		t.Errorf("expected current context %s, got %s", certs.GenerateContextName("cp2"), konfig.CurrentContext)
+	}
+	if entry.PreviousContext != certs.GenerateContextName("cp1") {
+		t.Errorf("expected previous context %s in audit entry, got %s", certs.GenerateContextName("cp1"), entry.PreviousContext)
+	}
+	if GetPreviousContext(konfig) != certs.GenerateContextName("cp1") {
+		t.Errorf("expected previous context %s recorded, got %s", certs.GenerateContextName("cp1"), GetPreviousContext(konfig))
+	}
+}
+
+func TestLoadAndMergeDefaultNamespace(t *testing.T) {
+	data, err := clientcmd.Write(*generateTestConfig("cp2", "https://cp2.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	// an empty namespace leaves the context namespace unset
+	konfig := clientcmdapi.NewConfig()
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, newMergeOptions(nil)); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if ns := konfig.Contexts[certs.GenerateContextName("cp2")].Namespace; ns != "" {
+		t.Errorf("expected no context namespace, got %s", ns)
+	}
+
+	o := newMergeOptions([]MergeOption{WithDefaultNamespace("team-a"), WithConflictPolicy(ConflictPolicySkip)})
+	konfig = clientcmdapi.NewConfig()
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if ns := konfig.Contexts[certs.GenerateContextName("cp2")].Namespace; ns != "team-a" {
+		t.Errorf("expected context namespace team-a, got %s", ns)
+	}
+
+	// merging again with the same namespace is not a conflict
+	entry, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o)
+	if err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if len(entry.Conflicts) != 0 {
+		t.Errorf("expected no conflicts, got %v", entry.Conflicts)
+	}
+}
+
+func TestLoadAndMergeContextName(t *testing.T) {
+	data, err := clientcmd.Write(*generateTestConfig("cp2", "https://cp2.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	o := newMergeOptions([]MergeOption{WithContextName("team-a-dev")})
+	konfig := clientcmdapi.NewConfig()
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if _, ok := konfig.Contexts[certs.GenerateContextName("cp2")]; ok {
+		t.Errorf("expected no context with the generated name")
+	}
+	kctx, ok := konfig.Contexts["team-a-dev"]
+	if !ok {
+		t.Fatalf("expected context team-a-dev")
+	}
+	if kctx.Cluster != certs.GenerateClusterName("cp2") || kctx.AuthInfo != certs.GenerateAuthInfoAdminName("cp2") {
+		t.Errorf("unexpected context entries: %+v", kctx)
+	}
+	if konfig.CurrentContext != "team-a-dev" {
+		t.Errorf("expected current context team-a-dev, got %s", konfig.CurrentContext)
+	}
+
+	// merging again under the same name is allowed
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+
+	konfig.CurrentContext = ""
+	if err := SwitchContext(konfig, "cp2"); err != nil {
+		t.Fatalf("SwitchContext returned error: %v", err)
+	}
+	if konfig.CurrentContext != "team-a-dev" {
+		t.Errorf("expected current context team-a-dev, got %s", konfig.CurrentContext)
+	}
+
+	if !removeControlPlaneEntries(konfig, "cp2") {
+		t.Fatalf("expected control plane entries to be removed")
+	}
+	if len(konfig.Contexts) != 0 {
+		t.Errorf("expected no contexts left, got %v", konfig.Contexts)
+	}
+
+	// a context with the same name for another cluster is not replaced
+	konfig = clientcmdapi.NewConfig()
+	konfig.Contexts["team-a-dev"] = &clientcmdapi.Context{Cluster: "other", AuthInfo: "other"}
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err == nil {
+		t.Errorf("expected error for a context name used by another cluster")
+	}
+	if konfig.Contexts["team-a-dev"].Cluster != "other" {
+		t.Errorf("expected the existing context to be kept")
+	}
+}
+
+func TestLoadAndMergeClusterTLS(t *testing.T) {
+	data, err := clientcmd.Write(*generateTestConfig("cp2", "https://cp2.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+	clusterName := certs.GenerateClusterName("cp2")
+
+	o := newMergeOptions([]MergeOption{WithCertificateAuthorityData([]byte("internal-ca"))})
+	konfig := clientcmdapi.NewConfig()
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if ca := string(konfig.Clusters[clusterName].CertificateAuthorityData); ca != "internal-ca" {
+		t.Errorf("expected the supplied CA bundle, got %s", ca)
+	}
+
+	o = newMergeOptions([]MergeOption{WithInsecureSkipTLSVerify(true)})
+	konfig = clientcmdapi.NewConfig()
+	entry, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o)
+	if err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	cluster := konfig.Clusters[clusterName]
+	if !cluster.InsecureSkipTLSVerify || len(cluster.CertificateAuthorityData) != 0 {
+		t.Errorf("expected insecure cluster without CA bundle, got %+v", cluster)
+	}
+	if !entry.InsecureSkipTLSVerify {
+		t.Errorf("expected the audit entry to flag the insecure merge")
+	}
+
+	o = newMergeOptions([]MergeOption{WithInsecureSkipTLSVerify(true), WithCertificateAuthorityData([]byte("internal-ca"))})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), clientcmdapi.NewConfig(), o); err == nil {
+		t.Errorf("expected error for a CA bundle combined with insecure-skip-tls-verify")
+	}
+}
+
+func TestLoadAndMergeClusterProxy(t *testing.T) {
+	// vcluster kubeconfigs use their own names, which adjustConfigKeys renames
+	vcluster := clientcmdapi.NewConfig()
+	vcluster.Clusters["my-vcluster"] = &clientcmdapi.Cluster{Server: "https://cp2.localtest.me:9443", CertificateAuthorityData: []byte("ca-cp2")}
+	vcluster.AuthInfos["my-vcluster"] = &clientcmdapi.AuthInfo{Token: "token-cp2"}
+	vcluster.Contexts["my-vcluster"] = &clientcmdapi.Context{Cluster: "my-vcluster", AuthInfo: "my-vcluster"}
+	vcluster.CurrentContext = "my-vcluster"
+	data, err := clientcmd.Write(*vcluster)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.VClusterKubeConfigSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyVCluster: data},
+	})
+
+	o := newMergeOptions([]MergeOption{
+		WithProxyURL("http://gateway.example.com:3128"),
+		WithTLSServerName("cp2.gateway.example.com"),
+	})
+	merged := clientcmdapi.NewConfig()
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeVCluster), merged, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+
+	// the fields survive the renaming of the entries and a write and load of the kubeconfig
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := WriteKubeconfigToPath(kubeconfigPath, merged); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	konfig := loadTestKubeconfig(t, kubeconfigPath)
+	cluster, ok := konfig.Clusters[certs.GenerateClusterName("cp2")]
+	if !ok {
+		t.Fatalf("expected cluster %s to be merged", certs.GenerateClusterName("cp2"))
+	}
+	if cluster.ProxyURL != "http://gateway.example.com:3128" {
+		t.Errorf("expected the proxy URL to be set, got %q", cluster.ProxyURL)
+	}
+	if cluster.TLSServerName != "cp2.gateway.example.com" {
+		t.Errorf("expected the TLS server name to be set, got %q", cluster.TLSServerName)
+	}
+	if cluster.Server != "https://cp2.localtest.me:9443" || string(cluster.CertificateAuthorityData) != "ca-cp2" {
+		t.Errorf("expected the other cluster fields to be kept, got %+v", cluster)
+	}
+
+	for _, proxyURL := range []string{"ftp://gateway.example.com", "gateway.example.com:3128", "http://"} {
+		o := newMergeOptions([]MergeOption{WithProxyURL(proxyURL)})
+		if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeVCluster), clientcmdapi.NewConfig(), o); err == nil {
+			t.Errorf("expected error for proxy URL %q", proxyURL)
+		}
+	}
+}
+
+func TestLoadAndMergePreserveOriginalNames(t *testing.T) {
+	vcluster := clientcmdapi.NewConfig()
+	vcluster.Clusters["my-vcluster"] = &clientcmdapi.Cluster{Server: "https://cp2.localtest.me:9443"}
+	vcluster.AuthInfos["my-vcluster"] = &clientcmdapi.AuthInfo{Token: "token"}
+	vcluster.Contexts["my-vcluster"] = &clientcmdapi.Context{Cluster: "my-vcluster", AuthInfo: "my-vcluster"}
+	vcluster.CurrentContext = "my-vcluster"
+	data, err := clientcmd.Write(*vcluster)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.VClusterKubeConfigSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyVCluster: data},
+	})
+
+	o := newMergeOptions([]MergeOption{WithPreserveOriginalNames(true), WithDefaultNamespace("team-a")})
+	konfig := clientcmdapi.NewConfig()
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeVCluster), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if _, ok := konfig.Clusters[certs.GenerateClusterName("cp2")]; ok {
+		t.Errorf("expected no cluster with the kubeflex name")
+	}
+	kctx, ok := konfig.Contexts["my-vcluster"]
+	if !ok || kctx.Cluster != "my-vcluster" || kctx.AuthInfo != "my-vcluster" {
+		t.Fatalf("expected the original context, got %+v", konfig.Contexts)
+	}
+	if kctx.Namespace != "team-a" {
+		t.Errorf("expected context namespace team-a, got %s", kctx.Namespace)
+	}
+	if konfig.CurrentContext != "my-vcluster" {
+		t.Errorf("expected current context my-vcluster, got %s", konfig.CurrentContext)
+	}
+
+	o = newMergeOptions([]MergeOption{WithPreserveOriginalNames(true), WithContextName("dev")})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeVCluster), clientcmdapi.NewConfig(), o); err == nil {
+		t.Errorf("expected error for a context name combined with the original names")
+	}
+}
+
+func TestLoadAndMergeInClusterEndpoint(t *testing.T) {
+	external, err := clientcmd.Write(*generateTestConfig("cp2", "https://cp2.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	inCluster, err := clientcmd.Write(*generateTestConfig("cp2", "https://kube-apiserver.cp2-system:9444"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data: map[string][]byte{
+			util.KubeconfigSecretKeyDefault:   external,
+			util.KubeconfigSecretKeyInCluster: inCluster,
+		},
+	})
+
+	konfig := clientcmdapi.NewConfig()
+	o := newMergeOptions([]MergeOption{WithInClusterEndpoint(true)})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if server := konfig.Clusters[certs.GenerateClusterName("cp2")].Server; server != "https://kube-apiserver.cp2-system:9444" {
+		t.Errorf("expected in-cluster server, got %s", server)
+	}
+
+	// ocm control planes have no in-cluster kubeconfig
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeOCM), konfig, o); err == nil {
+		t.Errorf("expected error for control plane type without in-cluster kubeconfig")
+	}
+
+	// the in-cluster key is missing from the secret
+	hostClient = fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: external},
+	})
+	_, err = loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o)
+	var missingKeyErr *MissingSecretKeyError
+	if !errors.As(err, &missingKeyErr) || missingKeyErr.Key != util.KubeconfigSecretKeyInCluster {
+		t.Errorf("expected MissingSecretKeyError for key %s, got %v", util.KubeconfigSecretKeyInCluster, err)
+	}
+}
+
+func TestLoadAndMergeInternalContext(t *testing.T) {
+	external, err := clientcmd.Write(*generateTestConfig("cp2", "https://cp2.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	inCluster, err := clientcmd.Write(*generateTestConfig("cp2", "https://cp2.cp2-system.svc.cluster.local"))
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data: map[string][]byte{
+			util.KubeconfigSecretKeyDefault:   external,
+			util.KubeconfigSecretKeyInCluster: inCluster,
+		},
+	})
+
+	konfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	o := newMergeOptions([]MergeOption{WithInternalContext(true)})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	internalName := "cp2" + InternalContextSuffix
+	for ctxName, server := range map[string]string{
+		certs.GenerateContextName("cp2"):        "https://cp2.localtest.me:9443",
+		certs.GenerateContextName(internalName): "https://cp2.cp2-system.svc.cluster.local",
+	} {
+		kctx, ok := konfig.Contexts[ctxName]
+		if !ok {
+			t.Fatalf("expected context %s to be merged", ctxName)
+		}
+		if got := konfig.Clusters[kctx.Cluster].Server; got != server {
+			t.Errorf("expected server %s for context %s, got %s", server, ctxName, got)
+		}
+	}
+	if kctx := konfig.Contexts[certs.GenerateContextName(internalName)]; kctx.Cluster != certs.GenerateClusterName(internalName) || kctx.AuthInfo != certs.GenerateAuthInfoAdminName(internalName) {
+		t.Errorf("expected the internal context to use distinct entries, got %+v", kctx)
+	}
+	if !isInternalContextOf(konfig, "cp2") {
+		t.Errorf("expected the internal context to be marked as the one of cp2")
+	}
+	if konfig.CurrentContext != certs.GenerateContextName("cp2") {
+		t.Errorf("expected the external context to be current, got %s", konfig.CurrentContext)
+	}
+
+	// a context name override also names the internal context
+	konfig = clientcmdapi.NewConfig()
+	o = newMergeOptions([]MergeOption{WithInternalContext(true), WithContextName("dev")})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if _, ok := konfig.Contexts["dev"+InternalContextSuffix]; !ok {
+		t.Errorf("expected context dev%s to be merged", InternalContextSuffix)
+	}
+	if konfig.CurrentContext != "dev" {
+		t.Errorf("expected current context dev, got %s", konfig.CurrentContext)
+	}
+
+	// a control plane type without an in-cluster kubeconfig only merges the external context
+	ocmClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.OCMKubeConfigSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: external},
+	})
+	konfig = clientcmdapi.NewConfig()
+	o = newMergeOptions([]MergeOption{WithInternalContext(true)})
+	if _, err := loadAndMergeWithOptions(context.Background(), ocmClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeOCM), konfig, o); err != nil {
+		t.Fatalf("loadAndMergeWithOptions returned error: %v", err)
+	}
+	if _, ok := konfig.Contexts[certs.GenerateContextName(internalName)]; ok {
+		t.Errorf("expected no internal context without an in-cluster kubeconfig")
+	}
+
+	// an in-cluster key missing from the secret of a type that has one is reported
+	hostClient = fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: external},
+	})
+	_, err = loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), clientcmdapi.NewConfig(), o)
+	var missingKeyErr *MissingSecretKeyError
+	if !errors.As(err, &missingKeyErr) || missingKeyErr.Key != util.KubeconfigSecretKeyInCluster {
+		t.Errorf("expected MissingSecretKeyError for key %s, got %v", util.KubeconfigSecretKeyInCluster, err)
+	}
+
+	// so is a secret reference without an in-cluster key
+	ref := &tenancyv1alpha1.SecretReference{Namespace: util.GenerateNamespaceFromControlPlaneName("cp2"), Name: util.AdminConfSecret, Key: util.KubeconfigSecretKeyDefault}
+	o = newMergeOptions([]MergeOption{WithInternalContext(true), WithKubeconfigSecretRef(ref)})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeExternal), clientcmdapi.NewConfig(), o); err == nil {
+		t.Errorf("expected error for a secret reference without an in-cluster key")
+	}
+
+	o = newMergeOptions([]MergeOption{WithInternalContext(true), WithInClusterEndpoint(true)})
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), clientcmdapi.NewConfig(), o); err == nil {
+		t.Errorf("expected error for an internal context combined with the in-cluster endpoint")
+	}
+}
+
+func TestRenameKeyRewritesContextReferences(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["shared"] = &clientcmdapi.Cluster{Server: "https://shared.example.com"}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["one"] = &clientcmdapi.Context{Cluster: "shared", AuthInfo: "admin"}
+	config.Contexts["two"] = &clientcmdapi.Context{Cluster: "shared", AuthInfo: "admin", Namespace: "team-a"}
+	config.CurrentContext = "two"
+
+	renameKey(config, config.Clusters, "shared", "cp1-cluster")
+	renameKey(config, config.AuthInfos, "admin", "cp1-admin")
+	renameKey(config, config.Contexts, "two", "cp1")
+
+	for name, kctx := range config.Contexts {
+		if kctx.Cluster != "cp1-cluster" || kctx.AuthInfo != "cp1-admin" {
+			t.Errorf("expected context %s to reference the renamed entries, got %+v", name, kctx)
+		}
+	}
+	if config.CurrentContext != "cp1" {
+		t.Errorf("expected current context cp1, got %s", config.CurrentContext)
+	}
+	if config.Contexts["cp1"].Namespace != "team-a" {
+		t.Errorf("expected renamed context to keep its namespace")
+	}
+}
+
+func TestWriteKubeconfigToPathIfChanged(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+
+	written, err := WriteKubeconfigToPathIfChanged(kubeconfigPath, config)
+	if err != nil || !written {
+		t.Fatalf("expected first write, got written %t, err %v", written, err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(kubeconfigPath, past, past); err != nil {
+		t.Fatalf("error setting file times: %v", err)
+	}
+
+	// a config loaded from the file and written back unchanged is not written
+	written, err = WriteKubeconfigToPathIfChanged(kubeconfigPath, loadTestKubeconfig(t, kubeconfigPath))
+	if err != nil || written {
+		t.Errorf("expected unchanged config not to be written, got written %t, err %v", written, err)
+	}
+	info, err := os.Stat(kubeconfigPath)
+	if err != nil {
+		t.Fatalf("error reading file info: %v", err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("expected modification time to be kept")
+	}
+
+	config.CurrentContext = ""
+	written, err = WriteKubeconfigToPathIfChanged(kubeconfigPath, config)
+	if err != nil || !written {
+		t.Errorf("expected changed config to be written, got written %t, err %v", written, err)
+	}
+}
+
+func TestWriteKubeconfigToPathKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+
+	// new files are only readable by their owner
+	created := filepath.Join(dir, "created")
+	if err := WriteKubeconfigToPath(created, config); err != nil {
+		t.Fatalf("WriteKubeconfigToPath returned error: %v", err)
+	}
+	if fi, err := os.Stat(created); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 for a new kubeconfig, got %v, %v", fi, err)
+	}
+
+	// the mode set by the user is kept when the kubeconfig is rewritten
+	existing := filepath.Join(dir, "existing")
+	if err := os.WriteFile(existing, []byte{}, 0640); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	if err := os.Chmod(existing, 0640); err != nil {
+		t.Fatalf("error setting the kubeconfig mode: %v", err)
+	}
+	if err := WriteKubeconfigToPath(existing, config); err != nil {
+		t.Fatalf("WriteKubeconfigToPath returned error: %v", err)
+	}
+	if fi, err := os.Stat(existing); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640 to be kept, got %v, %v", fi, err)
+	}
+}
+
+func TestWriteKubeconfigToPathAtomically(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	kubeconfigPath := filepath.Join(dir, "config")
+	if err := os.Symlink(target, kubeconfigPath); err != nil {
+		t.Fatalf("error creating symlink: %v", err)
+	}
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	if err := WriteKubeconfigToPath(kubeconfigPath, config); err != nil {
+		t.Fatalf("WriteKubeconfigToPath returned error: %v", err)
+	}
+	if fi, err := os.Lstat(kubeconfigPath); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the symlink to be kept, got %v, %v", fi, err)
+	}
+	if loadTestKubeconfig(t, target).CurrentContext != certs.GenerateContextName("cp1") {
+		t.Errorf("expected the symlink target to be written")
+	}
+
+	// a failed rename leaves the target untouched and no temporary file behind
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.MkdirAll(filepath.Join(blocked, "entry"), 0755); err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+	if err := WriteKubeconfigToPath(blocked, config); err == nil {
+		t.Errorf("expected error when the target cannot be replaced")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("error reading directory: %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			t.Errorf("unexpected temporary file %s", e.Name())
+		}
+	}
+}
+
+func TestLoadKubeconfigFromMissingPath(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), ".kube", "config")
+	config, err := LoadKubeconfigFromPath(kubeconfigPath)
+	if err != nil {
+		t.Fatalf("expected missing kubeconfig to load as empty config, got %v", err)
+	}
+	if len(config.Contexts) != 0 || config.CurrentContext != "" {
+		t.Errorf("expected empty config, got %+v", config)
+	}
+
+	if err := merge(config, generateTestConfig("cp1", "https://cp1.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+	if err := WriteKubeconfigToPath(kubeconfigPath, config); err != nil {
+		t.Fatalf("expected kubeconfig and its directory to be created, got %v", err)
+	}
+	if loadTestKubeconfig(t, kubeconfigPath).CurrentContext != certs.GenerateContextName("cp1") {
+		t.Errorf("expected current context %s", certs.GenerateContextName("cp1"))
+	}
+
+	if err := os.WriteFile(kubeconfigPath, []byte("not: [a kubeconfig"), 0600); err != nil {
+		t.Fatalf("error writing malformed kubeconfig: %v", err)
+	}
+	if _, err := LoadKubeconfigFromPath(kubeconfigPath); err == nil {
+		t.Errorf("expected error for malformed kubeconfig")
+	}
+}
+
+func TestLoadAndMergeExecCredentials(t *testing.T) {
+	execAuthInfo := func() *clientcmdapi.AuthInfo {
+		return &clientcmdapi.AuthInfo{
+			Token: "static-token",
+			Exec: &clientcmdapi.ExecConfig{
+				Command:            "vcluster-token-helper",
+				Args:               []string{"token", "--name", "cp2"},
+				Env:                []clientcmdapi.ExecEnvVar{{Name: "VCLUSTER_NAMESPACE", Value: "cp2-system"}},
+				APIVersion:         "client.authentication.k8s.io/v1",
+				InstallHint:        "install the vcluster token helper",
+				ProvideClusterInfo: true,
+				InteractiveMode:    clientcmdapi.NeverExecInteractiveMode,
+			},
+		}
+	}
+	vcluster := clientcmdapi.NewConfig()
+	vcluster.Clusters["my-vcluster"] = &clientcmdapi.Cluster{Server: "https://cp2.localtest.me:9443"}
+	vcluster.AuthInfos["my-vcluster"] = execAuthInfo()
+	vcluster.Contexts["my-vcluster"] = &clientcmdapi.Context{Cluster: "my-vcluster", AuthInfo: "my-vcluster"}
+	vcluster.CurrentContext = "my-vcluster"
+	data, err := clientcmd.Write(*vcluster)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.VClusterKubeConfigSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp2")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyVCluster: data},
+	})
+
+	konfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	konfig.AuthInfos["gke-user"] = &clientcmdapi.AuthInfo{
+		AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "gcp", Config: map[string]string{"access-token": "gcp-token"}},
+	}
+	if _, err := loadAndMerge(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeVCluster), nil, konfig); err != nil {
+		t.Fatalf("loadAndMerge returned error: %v", err)
+	}
+
+	// the credentials survive the rename and a round trip through the kubeconfig file
+	path := filepath.Join(t.TempDir(), "config")
+	if err := WriteKubeconfigToPath(path, konfig); err != nil {
+		t.Fatalf("WriteKubeconfigToPath returned error: %v", err)
+	}
+	konfig = loadTestKubeconfig(t, path)
+	authInfo, ok := konfig.AuthInfos[certs.GenerateAuthInfoAdminName("cp2")]
+	if !ok {
+		t.Fatalf("expected authInfo %s, got %v", certs.GenerateAuthInfoAdminName("cp2"), konfig.AuthInfos)
+	}
+	authInfo.LocationOfOrigin = ""
+	if want := execAuthInfo(); !apiequality.Semantic.DeepEqual(authInfo, want) {
+		t.Errorf("expected exec credentials %+v, got %+v", want, authInfo)
+	}
+	gke := konfig.AuthInfos["gke-user"]
+	if gke == nil || gke.AuthProvider == nil || gke.AuthProvider.Name != "gcp" || gke.AuthProvider.Config["access-token"] != "gcp-token" {
+		t.Errorf("expected the auth provider of the existing authInfo to be kept, got %+v", gke)
+	}
+	if fp, err := ContextFingerprint(konfig, "cp2"); err != nil || fp == "" {
+		t.Errorf("expected a fingerprint for the exec based context, got %q, %v", fp, err)
+	}
+
+	// merging the same kubeconfig again finds no conflict
+	entry, err := loadAndMerge(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeVCluster), nil, konfig)
+	if err != nil {
+		t.Fatalf("loadAndMerge returned error: %v", err)
+	}
+	if len(entry.Conflicts) > 0 {
+		t.Errorf("expected no conflicts when merging the exec credentials again, got %v", entry.Conflicts)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultLockTimeout is how long a kubeconfig read-modify-write waits for the lock held by
+// another process before failing
+const DefaultLockTimeout = 30 * time.Second
+
+// interval between two attempts to acquire the kubeconfig lock
+var lockRetryInterval = 100 * time.Millisecond
+
+// lockKubeconfig takes an advisory lock on the kubeconfig file at path, so that concurrent
+// kflex invocations serialize their read-modify-write cycles instead of overwriting each other's
+// changes. The lock is a <path>.lock file, which is the file kubectl locks when it modifies the
+// kubeconfig. The returned function releases the lock and must be called on all paths.
+func lockKubeconfig(path string, timeout time.Duration) (unlock func(), err error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory for kubeconfig lock %s: %w", lockPath, err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("error creating kubeconfig lock %s: %w", lockPath, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for kubeconfig lock %s, remove it if no other kflex or kubectl process is running", timeout, lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
@@ -0,0 +1,88 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestLockKubeconfig(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+
+	unlock, err := lockKubeconfig(kubeconfigPath, time.Second)
+	if err != nil {
+		t.Fatalf("lockKubeconfig returned error: %v", err)
+	}
+	if _, err := lockKubeconfig(kubeconfigPath, 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout while the lock is held, got %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(kubeconfigPath + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("expected lock file to be removed, got %v", err)
+	}
+
+	unlock, err = lockKubeconfig(kubeconfigPath, time.Second)
+	if err != nil {
+		t.Fatalf("lockKubeconfig after unlock returned error: %v", err)
+	}
+	unlock()
+}
+
+func TestLockKubeconfigSerializesWrites(t *testing.T) {
+	defer func(d time.Duration) { lockRetryInterval = d }(lockRetryInterval)
+	lockRetryInterval = time.Millisecond
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := WriteKubeconfigToPath(kubeconfigPath, clientcmdapi.NewConfig()); err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+
+	// each writer merges its own control plane with a read-modify-write cycle
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(cpName string) {
+			defer wg.Done()
+			unlock, err := lockKubeconfig(kubeconfigPath, 10*time.Second)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock()
+			config, err := LoadKubeconfigFromPath(kubeconfigPath)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := merge(config, generateTestConfig(cpName, "https://"+cpName+".localtest.me:9443")); err != nil {
+				errs <- err
+				return
+			}
+			errs <- WriteKubeconfigToPath(kubeconfigPath, config)
+		}(fmt.Sprintf("cp%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent merge returned error: %v", err)
+		}
+	}
+
+	config := loadTestKubeconfig(t, kubeconfigPath)
+	for i := 0; i < writers; i++ {
+		if _, ok := config.Contexts[certs.GenerateContextName(fmt.Sprintf("cp%d", i))]; !ok {
+			t.Errorf("expected context for cp%d to be kept", i)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// MergeOption configures LoadAndMerge and LoadAndMergeNoWrite
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	auditSink         AuditSink
+	kubeconfigPath    string
+	secretRef         *tenancyv1alpha1.SecretReference
+	setCurrentContext bool
+	lockTimeout       time.Duration
+	inCluster         bool
+	conflictPolicy    ConflictPolicy
+	defaultNamespace  string
+	contextName       string
+	caData            []byte
+	insecure          bool
+	preserveNames     bool
+	internalContext   bool
+	execCredential    bool
+	verify            bool
+	proxyURL          string
+	tlsServerName     string
+}
+
+// WithAuditSink sets the sink notified after control plane credentials are merged
+func WithAuditSink(sink AuditSink) MergeOption {
+	return func(o *mergeOptions) {
+		if sink != nil {
+			o.auditSink = sink
+		}
+	}
+}
+
+// WithKubeconfigPath makes LoadAndMerge read and write the kubeconfig file at path instead
+// of the one returned by DefaultKubeconfigPath. It is ignored by LoadAndMergeNoWrite.
+func WithKubeconfigPath(path string) MergeOption {
+	return func(o *mergeOptions) {
+		if path != "" {
+			o.kubeconfigPath = path
+		}
+	}
+}
+
+// WithKubeconfigSecretRef makes LoadAndMerge and LoadAndMergeNoWrite read the control plane
+// kubeconfig from the referenced secret instead of the secret kubeflex generates in the control
+// plane namespace. It is used for external control planes, whose kubeconfig is user supplied.
+func WithKubeconfigSecretRef(ref *tenancyv1alpha1.SecretReference) MergeOption {
+	return func(o *mergeOptions) {
+		o.secretRef = ref
+	}
+}
+
+// WithSetCurrentContext controls whether the merge sets the merged control plane context as
+// the current context. It defaults to true; with false the entries are added and the current
+// context is left unchanged.
+func WithSetCurrentContext(set bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.setCurrentContext = set
+	}
+}
+
+// WithLockTimeout sets how long LoadAndMerge and LoadAndMergeAll wait for the kubeconfig
+// lock held by another process. It defaults to DefaultLockTimeout.
+func WithLockTimeout(timeout time.Duration) MergeOption {
+	return func(o *mergeOptions) {
+		if timeout > 0 {
+			o.lockTimeout = timeout
+		}
+	}
+}
+
+// WithInClusterEndpoint makes the merge read the in-cluster kubeconfig of the control plane,
+// whose server is the control plane service in the hosting cluster instead of the external
+// ingress. It is meant for controllers running in the hosting cluster.
+func WithInClusterEndpoint(inCluster bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.inCluster = inCluster
+	}
+}
+
+// WithConflictPolicy selects how the merge handles entries that already exist in the kubeconfig
+// with a different content, such as a context created by hand with the name of the control
+// plane. It defaults to ConflictPolicyOverwrite. Refreshed credentials of a control plane
+// merged before also count as a different content.
+func WithConflictPolicy(policy ConflictPolicy) MergeOption {
+	return func(o *mergeOptions) {
+		if policy != "" {
+			o.conflictPolicy = policy
+		}
+	}
+}
+
+// WithDefaultNamespace sets the namespace of the merged control plane context, so that kubectl
+// commands run in it without -n. With an empty namespace the context keeps the namespace of the
+// control plane kubeconfig, which is usually unset.
+func WithDefaultNamespace(namespace string) MergeOption {
+	return func(o *mergeOptions) {
+		o.defaultNamespace = namespace
+	}
+}
+
+// WithContextName sets the name of the merged control plane context, used verbatim instead of
+// the name generated from the control plane name. The merge fails if the kubeconfig already has
+// a context with this name for another cluster. An empty name keeps the generated name.
+func WithContextName(name string) MergeOption {
+	return func(o *mergeOptions) {
+		o.contextName = name
+	}
+}
+
+// WithCertificateAuthorityData sets the CA bundle used to verify the API server certificate of
+// the merged control plane, replacing the one in the control plane kubeconfig. It is needed when
+// the API server certificate is signed by an internal CA not included in the kubeconfig secret.
+func WithCertificateAuthorityData(caData []byte) MergeOption {
+	return func(o *mergeOptions) {
+		o.caData = caData
+	}
+}
+
+// WithInsecureSkipTLSVerify makes the merged control plane cluster skip the verification of the
+// API server certificate. It is only meant for development clusters: the connection is then
+// open to man-in-the-middle attacks. It cannot be combined with WithCertificateAuthorityData,
+// and merges using it are flagged in the audit entry.
+func WithInsecureSkipTLSVerify(insecure bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.insecure = insecure
+	}
+}
+
+// WithPreserveOriginalNames merges the cluster, authInfo and context of the control plane
+// kubeconfig with their original names, such as my-vcluster, instead of renaming them after the
+// control plane, and sets the current context to the one of the control plane kubeconfig. It is
+// meant for single cluster workflows whose scripts reference the original names: control planes
+// of the same type use the same names, so merging several of them this way makes each merge
+// replace the entries of the previous one. The entries are not found by the kflex commands
+// looking up a control plane context by its name. It cannot be combined with WithContextName.
+func WithPreserveOriginalNames(preserve bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.preserveNames = preserve
+	}
+}
+
+// WithInternalContext also merges the in-cluster kubeconfig of the control plane, when its
+// secret has one, as a second context named after the control plane with InternalContextSuffix,
+// whose server is the control plane service in the hosting cluster. The context of the external
+// endpoint stays the one set as current context. It cannot be combined with
+// WithInClusterEndpoint or WithPreserveOriginalNames.
+func WithInternalContext(internal bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.internalContext = internal
+	}
+}
+
+// WithExecCredential replaces the credentials of the merged control plane context with an exec
+// credential running `kflex auth token --controlplane <name>`, which reads them from the control
+// plane secret in the hosting cluster each time they expire. The kubeconfig then never holds
+// stale credentials after a rotation of the control plane certificates or tokens. kflex must be
+// in the PATH of the clients using the context.
+func WithExecCredential(exec bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.execCredential = exec
+	}
+}
+
+// WithVerify checks that the merged control plane context authenticates against its API server
+// with a GET /version before the kubeconfig is written. If the check fails, a
+// ContextVerificationError is returned and the kubeconfig is left unchanged.
+func WithVerify(verify bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.verify = verify
+	}
+}
+
+// WithProxyURL sets the proxy-url of the merged control plane cluster, for control planes sitting
+// behind a shared gateway reached through an HTTP, HTTPS or SOCKS5 proxy. It does not apply to
+// the internal context merged by WithInternalContext, whose server is only reachable from
+// within the hosting cluster.
+func WithProxyURL(proxyURL string) MergeOption {
+	return func(o *mergeOptions) {
+		o.proxyURL = proxyURL
+	}
+}
+
+// WithTLSServerName sets the tls-server-name of the merged control plane cluster, the name the
+// API server certificate is verified against when it differs from the host of the server, such
+// as when a gateway routes to the control plane by SNI. Like WithProxyURL, it does not apply to
+// the internal context.
+func WithTLSServerName(name string) MergeOption {
+	return func(o *mergeOptions) {
+		o.tlsServerName = name
+	}
+}
+
+// validate checks that the merge options can be used together
+func (o *mergeOptions) validate() error {
+	switch o.conflictPolicy {
+	case ConflictPolicyOverwrite, ConflictPolicySkip, ConflictPolicyFail:
+	default:
+		return fmt.Errorf("invalid conflict policy %q: must be one of %s, %s or %s",
+			o.conflictPolicy, ConflictPolicyOverwrite, ConflictPolicySkip, ConflictPolicyFail)
+	}
+	if o.insecure && len(o.caData) > 0 {
+		return fmt.Errorf("a certificate authority cannot be set together with insecure-skip-tls-verify")
+	}
+	if o.preserveNames && o.contextName != "" {
+		return fmt.Errorf("a context name cannot be set when preserving the original names")
+	}
+	if o.internalContext && o.inCluster {
+		return fmt.Errorf("an internal context cannot be merged when merging the in-cluster endpoint")
+	}
+	if o.internalContext && o.preserveNames {
+		return fmt.Errorf("an internal context cannot be merged when preserving the original names")
+	}
+	if o.proxyURL != "" {
+		if err := validateProxyURL(o.proxyURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProxyURL checks that proxyURL is an absolute URL with one of the proxy schemes
+// supported by client-go
+func validateProxyURL(proxyURL string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid proxy URL %q: the scheme must be http, https or socks5", proxyURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q: missing host", proxyURL)
+	}
+	return nil
+}
+
+func newMergeOptions(opts []MergeOption) *mergeOptions {
+	o := &mergeOptions{
+		auditSink:         func(AuditEntry) {},
+		kubeconfigPath:    DefaultKubeconfigPath(),
+		setCurrentContext: true,
+		lockTimeout:       DefaultLockTimeout,
+		conflictPolicy:    ConflictPolicyOverwrite,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,16 @@
+package kubeconfig
+
+import (
+	"testing"
+)
+
+func TestMergeOptionsValidateConflictPolicy(t *testing.T) {
+	for _, policy := range []ConflictPolicy{"", ConflictPolicyOverwrite, ConflictPolicySkip, ConflictPolicyFail} {
+		if err := newMergeOptions([]MergeOption{WithConflictPolicy(policy)}).validate(); err != nil {
+			t.Errorf("unexpected error for conflict policy %q: %v", policy, err)
+		}
+	}
+	if err := newMergeOptions([]MergeOption{WithConflictPolicy("replace")}).validate(); err == nil {
+		t.Errorf("expected error for an unknown conflict policy")
+	}
+}
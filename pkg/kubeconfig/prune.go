@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// PruneOption configures PruneOrphanedContexts
+type PruneOption func(*pruneOptions)
+
+type pruneOptions struct {
+	dryRun bool
+}
+
+// WithPruneDryRun makes PruneOrphanedContexts return the names it would prune without
+// changing the kubeconfig
+func WithPruneDryRun(dryRun bool) PruneOption {
+	return func(o *pruneOptions) {
+		o.dryRun = dryRun
+	}
+}
+
+// PruneOrphanedContexts removes from the default kubeconfig the cluster, authInfo and context
+// of every kubeflex context with no ControlPlane left on the hosting cluster, including those of
+// its internal context, and returns the sorted names of the pruned control planes. Contexts not
+// generated by kubeflex are never touched. If the current context is pruned, it falls back to
+// one of the remaining contexts.
+func PruneOrphanedContexts(ctx context.Context, cl client.Client, opts ...PruneOption) ([]string, error) {
+	o := &pruneOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cpList := &tenancyv1alpha1.ControlPlaneList{}
+	if err := cl.List(ctx, cpList); err != nil {
+		return nil, err
+	}
+	live := sets.New[string]()
+	for _, cp := range cpList.Items {
+		live.Insert(cp.Name)
+	}
+
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	konfig, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := orphanedContexts(konfig, live)
+	if o.dryRun || len(pruned) == 0 {
+		return pruned, nil
+	}
+	for _, name := range pruned {
+		removeControlPlaneEntries(konfig, name)
+		removeInternalContextEntries(konfig, name, "")
+	}
+	if err := WriteKubeconfig(ctx, konfig); err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
+
+// orphanedContexts returns the sorted names of the control planes of the kubeflex contexts
+// in config that are not in live. Internal contexts count for the control plane they belong to.
+func orphanedContexts(config *clientcmdapi.Config, live sets.Set[string]) []string {
+	orphaned := sets.New[string]()
+	for _, c := range listControlPlaneContexts(config) {
+		if !live.Has(c.ControlPlaneName) {
+			orphaned.Insert(c.ControlPlaneName)
+		}
+	}
+	return sets.List(orphaned)
+}
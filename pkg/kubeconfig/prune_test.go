@@ -0,0 +1,110 @@
+package kubeconfig
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestPruneOrphanedContexts(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	for _, name := range []string{"cp2", "cp3"} {
+		if err := merge(config, generateTestConfig(name, "https://"+name+".localtest.me:9443")); err != nil {
+			t.Fatalf("error merging test config: %v", err)
+		}
+	}
+	for _, name := range []string{"cp1", "cp2"} {
+		if err := merge(config, generateTestInternalConfig(name, "https://"+name+".localtest.me:9443")); err != nil {
+			t.Fatalf("error merging test config: %v", err)
+		}
+	}
+	config.Clusters["kind-kind"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kind"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kind"] = &clientcmdapi.Context{Cluster: "kind-kind", AuthInfo: "kind-kind"}
+	config.CurrentContext = certs.GenerateContextName("cp1")
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	scheme := runtime.NewScheme()
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&tenancyv1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp2"}},
+	).Build()
+
+	ctx := context.Background()
+	pruned, err := PruneOrphanedContexts(ctx, cl, WithPruneDryRun(true))
+	if err != nil {
+		t.Fatalf("PruneOrphanedContexts returned error: %v", err)
+	}
+	if !reflect.DeepEqual(pruned, []string{"cp1", "cp3"}) {
+		t.Errorf("expected [cp1 cp3] to be pruned, got %v", pruned)
+	}
+	if got := GetKubeflexContextNames(loadTestKubeconfig(t, kubeconfigPath)); len(got) != 3 {
+		t.Errorf("expected dry run to keep all contexts, got %v", got)
+	}
+
+	pruned, err = PruneOrphanedContexts(ctx, cl)
+	if err != nil {
+		t.Fatalf("PruneOrphanedContexts returned error: %v", err)
+	}
+	if !reflect.DeepEqual(pruned, []string{"cp1", "cp3"}) {
+		t.Errorf("expected [cp1 cp3] to be pruned, got %v", pruned)
+	}
+	config = loadTestKubeconfig(t, kubeconfigPath)
+	if got := GetKubeflexContextNames(config); !reflect.DeepEqual(got, []string{"cp2"}) {
+		t.Errorf("expected only the cp2 context to be left, got %v", got)
+	}
+	if _, ok := config.Clusters[certs.GenerateClusterName("cp1")]; ok {
+		t.Errorf("expected cluster for cp1 to be removed")
+	}
+	if _, ok := config.Contexts["cp1"+InternalContextSuffix]; ok {
+		t.Errorf("expected internal context for cp1 to be removed")
+	}
+	if _, ok := config.Contexts["cp2"+InternalContextSuffix]; !ok {
+		t.Errorf("expected internal context for cp2 to be kept")
+	}
+	if _, ok := config.Contexts["kind-kind"]; !ok {
+		t.Errorf("expected context not managed by kubeflex to be kept")
+	}
+	if _, ok := config.Contexts[config.CurrentContext]; !ok {
+		t.Errorf("expected current context to fall back to an existing context, got %q", config.CurrentContext)
+	}
+}
+
+func TestOrphanedContextsInternal(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	for _, name := range []string{"cp1", "cp2"} {
+		if err := merge(config, generateTestInternalConfig(name, "https://"+name+"."+name+"-system.svc.cluster.local")); err != nil {
+			t.Fatalf("error merging test config: %v", err)
+		}
+	}
+	if err := merge(config, generateTestConfig("cp3", "https://cp3.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging test config: %v", err)
+	}
+
+	// the internal context of a live control plane is kept
+	if got := orphanedContexts(config, sets.New("cp1", "cp2")); !reflect.DeepEqual(got, []string{"cp3"}) {
+		t.Errorf("expected only cp3 to be orphaned, got %v", got)
+	}
+	// a control plane is reported once for its context and its internal context
+	if got := orphanedContexts(config, sets.New("cp3")); !reflect.DeepEqual(got, []string{"cp1", "cp2"}) {
+		t.Errorf("expected [cp1 cp2] to be orphaned, got %v", got)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// interval between two readiness probes of the control plane API server
+var readyPollInterval = 2 * time.Second
+
+// WaitForControlPlaneReady waits until the kubeconfig secret of a control plane holds its
+// kubeconfig and the API server it points to answers /readyz, or until timeout elapses. It returns the API server
+// endpoint that answered.
+func WaitForControlPlaneReady(ctx context.Context, clientset kubernetes.Clientset, name, controlPlaneType string, timeout time.Duration) (string, error) {
+	return waitForControlPlaneReady(ctx, &clientset, name, controlPlaneType, timeout)
+}
+
+func waitForControlPlaneReady(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string, timeout time.Duration) (string, error) {
+	if err := util.ValidateControlPlaneType(controlPlaneType); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := watchForSecretCreation(ctx, client, name,
+		util.GetKubeconfSecretNameByControlPlaneType(controlPlaneType),
+		util.GetKubeconfSecretKeyNameByControlPlaneType(controlPlaneType)); err != nil {
+		return "", err
+	}
+
+	var endpoint string
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, readyPollInterval, true, func(ctx context.Context) (bool, error) {
+		// the kubeconfig is read again on each probe since it may be rewritten while the
+		// control plane comes up
+		restConfig, err := RestConfigForControlPlane(ctx, client, name, controlPlaneType)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		dc, err := discovery.NewDiscoveryClientForConfig(restConfig)
+		if err != nil {
+			return false, err
+		}
+		if err := dc.RESTClient().Get().AbsPath("/readyz").Do(ctx).Error(); err != nil {
+			lastErr = err
+			return false, nil
+		}
+		endpoint = restConfig.Host
+		return true, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			return "", fmt.Errorf("control plane %s is not ready after %s: %w", name, timeout, lastErr)
+		}
+		return "", fmt.Errorf("control plane %s is not ready after %s: %w", name, timeout, err)
+	}
+	return endpoint, nil
+}
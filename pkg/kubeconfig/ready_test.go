@@ -0,0 +1,61 @@
+package kubeconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestWaitForControlPlaneReady(t *testing.T) {
+	defer func(interval time.Duration) { readyPollInterval = interval }(readyPollInterval)
+	readyPollInterval = 10 * time.Millisecond
+
+	// the API server answers /readyz after a few probes
+	var probes int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/readyz" && atomic.AddInt32(&probes, 1) > 2 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cpKonfig := generateTestConfig("cp1", server.URL)
+	cpKonfig.Clusters[certs.GenerateClusterName("cp1")].CertificateAuthorityData = nil
+	cpKonfig.Clusters[certs.GenerateClusterName("cp1")].InsecureSkipTLSVerify = true
+	delete(cpKonfig.AuthInfos, certs.GenerateAuthInfoAdminName("cp1"))
+	data, err := clientcmd.Write(*cpKonfig)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp1")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	endpoint, err := waitForControlPlaneReady(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S), 5*time.Second)
+	if err != nil {
+		t.Fatalf("waitForControlPlaneReady returned error: %v", err)
+	}
+	if endpoint != server.URL {
+		t.Errorf("expected endpoint %s, got %s", server.URL, endpoint)
+	}
+
+	// the secret of another control plane never shows up
+	if _, err := waitForControlPlaneReady(context.Background(), hostClient, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S), 100*time.Millisecond); err == nil {
+		t.Errorf("expected timeout for a control plane without kubeconfig secret")
+	}
+}
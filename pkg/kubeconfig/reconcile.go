@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+// ControlPlaneRef identifies a control plane whose context should be present in the kubeconfig
+type ControlPlaneRef struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ReconcileKubeconfig converges the kubeflex contexts in the default kubeconfig to desired:
+// contexts for missing control planes are merged from the hosting cluster and kubeflex
+// contexts for control planes not in desired are pruned. Contexts not managed by kubeflex
+// and existing kubeflex contexts in desired are left untouched. The kubeconfig is only
+// written if something changed.
+func ReconcileKubeconfig(ctx context.Context, client kubernetes.Clientset, desired []ControlPlaneRef) (added, removed []string, err error) {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+	konfig, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	added, removed, err = reconcileKubeconfig(ctx, &client, konfig, desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return added, removed, nil
+	}
+	if err = WriteKubeconfig(ctx, konfig); err != nil {
+		return nil, nil, err
+	}
+	return added, removed, nil
+}
+
+func reconcileKubeconfig(ctx context.Context, client kubernetes.Interface, konfig *clientcmdapi.Config, desired []ControlPlaneRef) (added, removed []string, err error) {
+	desiredNames := sets.New[string]()
+	for _, cp := range desired {
+		if cp.Name == "" {
+			return nil, nil, fmt.Errorf("control plane name is required")
+		}
+		if desiredNames.Has(cp.Name) {
+			return nil, nil, fmt.Errorf("control plane %s is listed more than once", cp.Name)
+		}
+		desiredNames.Insert(cp.Name)
+	}
+
+	currentContext := konfig.CurrentContext
+	current := sets.New(GetKubeflexContextNames(konfig)...)
+
+	added = []string{}
+	for _, cp := range desired {
+		if current.Has(certs.GenerateContextName(cp.Name)) {
+			continue
+		}
+		if _, err := loadAndMerge(ctx, client, cp.Name, cp.Type, nil, konfig); err != nil {
+			return nil, nil, fmt.Errorf("error merging context for control plane %s: %s", cp.Name, err)
+		}
+		added = append(added, cp.Name)
+	}
+
+	removed = []string{}
+	for _, name := range sets.List(current) {
+		if desiredNames.Has(name) {
+			continue
+		}
+		if err := DeleteContext(konfig, name); err != nil {
+			return nil, nil, err
+		}
+		removed = append(removed, name)
+	}
+
+	// merging switches to the merged context: keep the current context unless it was pruned
+	if _, ok := konfig.Contexts[currentContext]; ok {
+		konfig.CurrentContext = currentContext
+	} else if err := SwitchToInitialContext(konfig, false); err != nil {
+		return nil, nil, err
+	}
+	return added, removed, nil
+}
+
+// LoadAndMergeAll merges the contexts of all controlPlanes into the default kubeconfig, with a
+// single write at the end, and returns the names of the merged control planes. Existing contexts
+// are refreshed. A control plane that cannot be merged does not prevent merging the others: its
+// error is collected in the returned aggregate error. The current context is left unchanged.
+func LoadAndMergeAll(ctx context.Context, client kubernetes.Clientset, controlPlanes []ControlPlaneRef, opts ...MergeOption) ([]string, error) {
+	o := newMergeOptions(opts)
+	unlock, err := lockKubeconfig(o.kubeconfigPath, o.lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	konfig, err := LoadKubeconfigFromPath(o.kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, entries, errs := loadAndMergeAll(ctx, &client, controlPlanes, konfig)
+	if len(merged) > 0 {
+		if _, err := WriteKubeconfigToPathIfChanged(o.kubeconfigPath, konfig); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			o.auditSink(*entry)
+		}
+	}
+	return merged, utilerrors.NewAggregate(errs)
+}
+
+func loadAndMergeAll(ctx context.Context, client kubernetes.Interface, controlPlanes []ControlPlaneRef, konfig *clientcmdapi.Config) ([]string, []*AuditEntry, []error) {
+	currentContext := konfig.CurrentContext
+	merged := []string{}
+	entries := []*AuditEntry{}
+	errs := []error{}
+	for _, cp := range controlPlanes {
+		entry, err := loadAndMerge(ctx, client, cp.Name, cp.Type, nil, konfig)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error merging context for control plane %s: %s", cp.Name, err))
+			continue
+		}
+		merged = append(merged, cp.Name)
+		entries = append(entries, entry)
+	}
+	// merging switches to the merged context
+	konfig.CurrentContext = currentContext
+	return merged, entries, errs
+}
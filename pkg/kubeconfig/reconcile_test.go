@@ -0,0 +1,125 @@
+package kubeconfig
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileKubeconfig(t *testing.T) {
+	hostClient := fake.NewSimpleClientset()
+	for _, name := range []string{"cp1", "cp2", "cp3", "cp4"} {
+		cpKonfig, err := clientcmd.Write(*generateTestConfig(name, "https://"+name+".localtest.me:9443"))
+		if err != nil {
+			t.Fatalf("error serializing kubeconfig: %v", err)
+		}
+		_, err = hostClient.CoreV1().Secrets(util.GenerateNamespaceFromControlPlaneName(name)).Create(context.Background(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret},
+			Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: cpKonfig},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("error creating kubeconfig secret: %v", err)
+		}
+	}
+
+	// start from cp1 and cp2 plus a context not managed by kubeflex
+	konfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	if err := merge(konfig, generateTestConfig("cp2", "https://cp2.localtest.me:9443")); err != nil {
+		t.Fatalf("error merging config: %v", err)
+	}
+	konfig.Clusters["kind-kind"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	konfig.AuthInfos["kind-kind"] = &clientcmdapi.AuthInfo{Token: "token"}
+	konfig.Contexts["kind-kind"] = &clientcmdapi.Context{Cluster: "kind-kind", AuthInfo: "kind-kind"}
+	konfig.CurrentContext = "cp2"
+
+	k8sType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	desired := []ControlPlaneRef{{Name: "cp2", Type: k8sType}, {Name: "cp3", Type: k8sType}, {Name: "cp4", Type: k8sType}}
+	added, removed, err := reconcileKubeconfig(context.Background(), hostClient, konfig, desired)
+	if err != nil {
+		t.Fatalf("reconcileKubeconfig returned error: %v", err)
+	}
+	if !reflect.DeepEqual(added, []string{"cp3", "cp4"}) {
+		t.Errorf("expected added [cp3 cp4], got %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"cp1"}) {
+		t.Errorf("expected removed [cp1], got %v", removed)
+	}
+	if got := GetKubeflexContextNames(konfig); !reflect.DeepEqual(got, []string{"cp2", "cp3", "cp4"}) {
+		t.Errorf("expected kubeflex contexts [cp2 cp3 cp4], got %v", got)
+	}
+	if _, ok := konfig.Contexts["kind-kind"]; !ok {
+		t.Errorf("expected context not managed by kubeflex to be kept")
+	}
+	if konfig.CurrentContext != "cp2" {
+		t.Errorf("expected current context cp2 to be kept, got %s", konfig.CurrentContext)
+	}
+
+	// a second pass with the same desired state is a no-op
+	added, removed, err = reconcileKubeconfig(context.Background(), hostClient, konfig, desired)
+	if err != nil {
+		t.Fatalf("reconcileKubeconfig returned error: %v", err)
+	}
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("expected no changes once converged, got added %v removed %v", added, removed)
+	}
+
+	if _, _, err := reconcileKubeconfig(context.Background(), hostClient, konfig, []ControlPlaneRef{{Name: "missing", Type: k8sType}}); err == nil {
+		t.Errorf("expected error for control plane without kubeconfig secret")
+	}
+	if _, _, err := reconcileKubeconfig(context.Background(), hostClient, konfig, []ControlPlaneRef{{Name: "cp2"}, {Name: "cp2"}}); err == nil {
+		t.Errorf("expected error for duplicate control plane")
+	}
+}
+
+func TestLoadAndMergeAll(t *testing.T) {
+	hostClient := fake.NewSimpleClientset()
+	for _, name := range []string{"cp1", "cp3"} {
+		cpKonfig, err := clientcmd.Write(*generateTestConfig(name, "https://"+name+".localtest.me:9443"))
+		if err != nil {
+			t.Fatalf("error serializing kubeconfig: %v", err)
+		}
+		_, err = hostClient.CoreV1().Secrets(util.GenerateNamespaceFromControlPlaneName(name)).Create(context.Background(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret},
+			Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: cpKonfig},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("error creating kubeconfig secret: %v", err)
+		}
+	}
+
+	konfig := clientcmdapi.NewConfig()
+	konfig.Clusters["kind-kind"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	konfig.AuthInfos["kind-kind"] = &clientcmdapi.AuthInfo{Token: "token"}
+	konfig.Contexts["kind-kind"] = &clientcmdapi.Context{Cluster: "kind-kind", AuthInfo: "kind-kind"}
+	konfig.CurrentContext = "kind-kind"
+
+	// cp2 has no kubeconfig secret, which must not prevent merging cp3
+	k8sType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	controlPlanes := []ControlPlaneRef{{Name: "cp1", Type: k8sType}, {Name: "cp2", Type: k8sType}, {Name: "cp3", Type: k8sType}}
+	merged, entries, errs := loadAndMergeAll(context.Background(), hostClient, controlPlanes, konfig)
+	if !reflect.DeepEqual(merged, []string{"cp1", "cp3"}) {
+		t.Errorf("expected merged [cp1 cp3], got %v", merged)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected an audit entry per merged control plane, got %d", len(entries))
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "cp2") {
+		t.Errorf("expected a single error for cp2, got %v", errs)
+	}
+	if got := GetKubeflexContextNames(konfig); !reflect.DeepEqual(got, []string{"cp1", "cp3"}) {
+		t.Errorf("expected kubeflex contexts [cp1 cp3], got %v", got)
+	}
+	if konfig.CurrentContext != "kind-kind" {
+		t.Errorf("expected current context kind-kind to be kept, got %s", konfig.CurrentContext)
+	}
+}
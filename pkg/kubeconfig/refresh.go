@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+// RefreshControlPlaneContext reloads the kubeconfig secret of a control plane and replaces
+// the cluster and authInfo of the control plane in the default kubeconfig, keeping all the
+// other entries and the current context. When the kubeconfig has no context for the control
+// plane yet, it falls back to a full merge as done by LoadAndMerge. It reports whether the
+// kubeconfig was changed; the file is not written when nothing changed.
+func RefreshControlPlaneContext(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType string) (bool, error) {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	konfig, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	changed, err := refreshControlPlaneContext(ctx, &client, konfig, name, controlPlaneType)
+	if err != nil || !changed {
+		return false, err
+	}
+	return WriteKubeconfigIfChanged(ctx, konfig)
+}
+
+func refreshControlPlaneContext(ctx context.Context, client kubernetes.Interface, konfig *clientcmdapi.Config, name, controlPlaneType string) (bool, error) {
+	if _, ok := konfig.Contexts[certs.GenerateContextName(name)]; !ok {
+		if _, err := loadAndMerge(ctx, client, name, controlPlaneType, nil, konfig); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	cpKonfig, err := loadControlPlaneKubeconfig(ctx, client, name, controlPlaneType)
+	if err != nil {
+		return false, err
+	}
+	adjustConfigKeys(cpKonfig, name, controlPlaneType)
+
+	clusterName := certs.GenerateClusterName(name)
+	authName := certs.GenerateAuthInfoAdminName(name)
+	cluster, ok := cpKonfig.Clusters[clusterName]
+	if !ok {
+		return false, fmt.Errorf("cluster %s not found in kubeconfig secret of control plane %s", clusterName, name)
+	}
+	authInfo, ok := cpKonfig.AuthInfos[authName]
+	if !ok {
+		return false, fmt.Errorf("authInfo %s not found in kubeconfig secret of control plane %s", authName, name)
+	}
+
+	refreshed := clientcmdapi.NewConfig()
+	refreshed.Clusters[clusterName] = cluster
+	refreshed.AuthInfos[authName] = authInfo
+	_, hasCluster := konfig.Clusters[clusterName]
+	_, hasAuth := konfig.AuthInfos[authName]
+	if hasCluster && hasAuth && len(findConflicts(konfig, refreshed)) == 0 {
+		return false, nil
+	}
+	konfig.Clusters[clusterName] = cluster
+	konfig.AuthInfos[authName] = authInfo
+	return true, nil
+}
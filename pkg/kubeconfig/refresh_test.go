@@ -0,0 +1,90 @@
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestRefreshControlPlaneContext(t *testing.T) {
+	hostClient := fake.NewSimpleClientset()
+	setSecret := func(name string, config *clientcmdapi.Config) {
+		data, err := clientcmd.Write(*config)
+		if err != nil {
+			t.Fatalf("error serializing kubeconfig: %v", err)
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret},
+			Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+		}
+		secrets := hostClient.CoreV1().Secrets(util.GenerateNamespaceFromControlPlaneName(name))
+		if _, err := secrets.Update(context.Background(), secret, metav1.UpdateOptions{}); err != nil {
+			if _, err := secrets.Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("error creating kubeconfig secret: %v", err)
+			}
+		}
+	}
+	k8sType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	setSecret("cp1", generateTestConfig("cp1", "https://cp1.localtest.me:9443"))
+	setSecret("cp2", generateTestConfig("cp2", "https://cp2.localtest.me:9443"))
+
+	konfig := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	konfig.Clusters["kind-kind"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	konfig.AuthInfos["kind-kind"] = &clientcmdapi.AuthInfo{Token: "token"}
+	konfig.Contexts["kind-kind"] = &clientcmdapi.Context{Cluster: "kind-kind", AuthInfo: "kind-kind"}
+	konfig.CurrentContext = "kind-kind"
+
+	changed, err := refreshControlPlaneContext(context.Background(), hostClient, konfig, "cp1", k8sType)
+	if err != nil {
+		t.Fatalf("refreshControlPlaneContext returned error: %v", err)
+	}
+	if changed {
+		t.Errorf("expected no change when the secret matches the kubeconfig")
+	}
+
+	// rotate the cert of cp1
+	rotated := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	rotated.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")].ClientCertificateData = []byte("rotated-cert")
+	setSecret("cp1", rotated)
+	changed, err = refreshControlPlaneContext(context.Background(), hostClient, konfig, "cp1", k8sType)
+	if err != nil {
+		t.Fatalf("refreshControlPlaneContext returned error: %v", err)
+	}
+	if !changed {
+		t.Errorf("expected change after cert rotation")
+	}
+	if got := string(konfig.AuthInfos[certs.GenerateAuthInfoAdminName("cp1")].ClientCertificateData); got != "rotated-cert" {
+		t.Errorf("expected rotated cert, got %s", got)
+	}
+	if konfig.CurrentContext != "kind-kind" {
+		t.Errorf("expected current context kind-kind to be kept, got %s", konfig.CurrentContext)
+	}
+	if _, ok := konfig.Contexts["kind-kind"]; !ok {
+		t.Errorf("expected context not managed by kubeflex to be kept")
+	}
+
+	// cp2 has no context yet and is fully merged
+	changed, err = refreshControlPlaneContext(context.Background(), hostClient, konfig, "cp2", k8sType)
+	if err != nil {
+		t.Fatalf("refreshControlPlaneContext returned error: %v", err)
+	}
+	if !changed {
+		t.Errorf("expected change when merging a new control plane")
+	}
+	if !IsKubeflexContext(konfig, certs.GenerateContextName("cp2")) {
+		t.Errorf("expected kubeflex context for cp2 to be merged")
+	}
+
+	if _, err := refreshControlPlaneContext(context.Background(), hostClient, konfig, "missing", k8sType); err == nil {
+		t.Errorf("expected error for control plane without kubeconfig secret")
+	}
+}
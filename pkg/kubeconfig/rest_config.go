@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// RestConfigForControlPlane builds a rest.Config for a control plane from its kubeconfig
+// secret in the hosting cluster, and applies the overrides in order
+func RestConfigForControlPlane(ctx context.Context, hostClient kubernetes.Interface, name, controlPlaneType string, overrides ...func(*rest.Config)) (*rest.Config, error) {
+	return restConfigForControlPlane(ctx, hostClient, name, controlPlaneType, false, overrides)
+}
+
+// InClusterRestConfigForControlPlane works as RestConfigForControlPlane with the in-cluster
+// kubeconfig of the control plane, whose server is the control plane service in the hosting
+// cluster instead of the external ingress. It is meant for controllers running in the hosting
+// cluster, and fails for control plane types without an in-cluster kubeconfig.
+func InClusterRestConfigForControlPlane(ctx context.Context, hostClient kubernetes.Interface, name, controlPlaneType string, overrides ...func(*rest.Config)) (*rest.Config, error) {
+	return restConfigForControlPlane(ctx, hostClient, name, controlPlaneType, true, overrides)
+}
+
+func restConfigForControlPlane(ctx context.Context, hostClient kubernetes.Interface, name, controlPlaneType string, inCluster bool, overrides []func(*rest.Config)) (*rest.Config, error) {
+	var cpKonfig *clientcmdapi.Config
+	var err error
+	if inCluster {
+		cpKonfig, err = loadInClusterControlPlaneKubeconfig(ctx, hostClient, name, controlPlaneType)
+	} else {
+		cpKonfig, err = loadControlPlaneKubeconfig(ctx, hostClient, name, controlPlaneType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	adjustConfigKeys(cpKonfig, name, controlPlaneType)
+
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*cpKonfig, certs.GenerateContextName(name), &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		override(restConfig)
+	}
+	return restConfig, nil
+}
+
+// loadInClusterControlPlaneKubeconfig reads the in-cluster kubeconfig from the secret kubeflex
+// generates for the control plane type
+func loadInClusterControlPlaneKubeconfig(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string) (*clientcmdapi.Config, error) {
+	if err := util.ValidateControlPlaneType(controlPlaneType); err != nil {
+		return nil, err
+	}
+	ref, err := newMergeOptions([]MergeOption{WithInClusterEndpoint(true)}).resolveSecretRef(name, controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	return loadKubeconfigFromSecret(ctx, client, ref.Namespace, ref.Name, ref.Key)
+}
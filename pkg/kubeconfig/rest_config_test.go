@@ -0,0 +1,91 @@
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestRestConfigForControlPlane(t *testing.T) {
+	cpKonfig, err := clientcmd.Write(*generateTestConfig("cp1", "https://cp1.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.AdminConfSecret,
+			Namespace: util.GenerateNamespaceFromControlPlaneName("cp1"),
+		},
+		Data: map[string][]byte{util.KubeconfigSecretKeyDefault: cpKonfig},
+	})
+
+	restConfig, err := RestConfigForControlPlane(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S),
+		func(c *rest.Config) { c.UserAgent = "kflex-test" },
+		func(c *rest.Config) { c.Timeout = 10 * time.Second },
+	)
+	if err != nil {
+		t.Fatalf("RestConfigForControlPlane returned error: %v", err)
+	}
+	if restConfig.Host != "https://cp1.localtest.me:9443" {
+		t.Errorf("expected host https://cp1.localtest.me:9443, got %s", restConfig.Host)
+	}
+	if restConfig.UserAgent != "kflex-test" {
+		t.Errorf("expected user agent override to be applied, got %s", restConfig.UserAgent)
+	}
+	if restConfig.Timeout != 10*time.Second {
+		t.Errorf("expected timeout override to be applied, got %s", restConfig.Timeout)
+	}
+	if string(restConfig.TLSClientConfig.CertData) != "cert-cp1" {
+		t.Errorf("expected client cert from the control plane kubeconfig")
+	}
+
+	if _, err := RestConfigForControlPlane(context.Background(), hostClient, "missing", string(tenancyv1alpha1.ControlPlaneTypeK8S)); err == nil {
+		t.Errorf("expected error for missing control plane kubeconfig secret")
+	}
+}
+
+func TestInClusterRestConfigForControlPlane(t *testing.T) {
+	external, err := clientcmd.Write(*generateTestConfig("cp1", "https://cp1.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	inCluster, err := clientcmd.Write(*generateTestConfig("cp1", "https://cp1.cp1-system:9444"))
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.AdminConfSecret,
+			Namespace: util.GenerateNamespaceFromControlPlaneName("cp1"),
+		},
+		Data: map[string][]byte{
+			util.KubeconfigSecretKeyDefault:   external,
+			util.KubeconfigSecretKeyInCluster: inCluster,
+		},
+	})
+
+	restConfig, err := InClusterRestConfigForControlPlane(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S),
+		func(c *rest.Config) { c.UserAgent = "kflex-test" })
+	if err != nil {
+		t.Fatalf("InClusterRestConfigForControlPlane returned error: %v", err)
+	}
+	if restConfig.Host != "https://cp1.cp1-system:9444" {
+		t.Errorf("expected the in-cluster host https://cp1.cp1-system:9444, got %s", restConfig.Host)
+	}
+	if restConfig.UserAgent != "kflex-test" {
+		t.Errorf("expected user agent override to be applied, got %s", restConfig.UserAgent)
+	}
+
+	if _, err := InClusterRestConfigForControlPlane(context.Background(), hostClient, "cp1", string(tenancyv1alpha1.ControlPlaneTypeOCM)); err == nil {
+		t.Errorf("expected error for a control plane type without an in-cluster kubeconfig")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+// scopedTokenExpirationSeconds is the lifetime requested for the tokens of scoped kubeconfigs
+const scopedTokenExpirationSeconds = int64(24 * 60 * 60)
+
+// GenerateScopedKubeconfig returns a standalone kubeconfig for a control plane that uses a
+// token of serviceAccount, given as namespace/name or as a name in the default namespace,
+// instead of the admin credentials. The cluster entry is the one of the admin kubeconfig, and
+// the access is limited to the RBAC granted to the service account in the control plane. The
+// token is requested from the control plane and expires after 24 hours.
+func GenerateScopedKubeconfig(ctx context.Context, client kubernetes.Clientset, name, controlPlaneType, serviceAccount string) (*clientcmdapi.Config, error) {
+	restConfig, err := RestConfigForControlPlane(ctx, &client, name, controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	cpClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return generateScopedKubeconfig(ctx, &client, cpClient, name, controlPlaneType, serviceAccount)
+}
+
+func generateScopedKubeconfig(ctx context.Context, hostClient, cpClient kubernetes.Interface, name, controlPlaneType, serviceAccount string) (*clientcmdapi.Config, error) {
+	namespace, saName, err := parseServiceAccount(serviceAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	cpKonfig, err := loadControlPlaneKubeconfig(ctx, hostClient, name, controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	adjustConfigKeys(cpKonfig, name, controlPlaneType)
+	cluster, ok := cpKonfig.Clusters[certs.GenerateClusterName(name)]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found in kubeconfig secret of control plane %s", certs.GenerateClusterName(name), name)
+	}
+
+	expiration := scopedTokenExpirationSeconds
+	tr, err := cpClient.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, saName, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expiration},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error requesting token for service account %s/%s: %w", namespace, saName, err)
+	}
+	if tr.Status.Token == "" {
+		return nil, fmt.Errorf("empty token returned for service account %s/%s", namespace, saName)
+	}
+
+	clusterName := certs.GenerateClusterName(name)
+	authName := certs.GenerateAuthInfoServiceAccountName(name, saName)
+	config := clientcmdapi.NewConfig()
+	config.Clusters[clusterName] = cluster
+	config.AuthInfos[authName] = &clientcmdapi.AuthInfo{Token: tr.Status.Token}
+	config.Contexts[authName] = &clientcmdapi.Context{
+		Cluster:   clusterName,
+		AuthInfo:  authName,
+		Namespace: namespace,
+	}
+	config.CurrentContext = authName
+	return config, nil
+}
+
+// parseServiceAccount splits a service account given as namespace/name or name
+func parseServiceAccount(serviceAccount string) (namespace, name string, err error) {
+	parts := strings.Split(serviceAccount, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return metav1.NamespaceDefault, parts[0], nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], nil
+	default:
+		return "", "", fmt.Errorf("invalid service account %q: must be a name or namespace/name", serviceAccount)
+	}
+}
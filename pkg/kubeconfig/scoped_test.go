@@ -0,0 +1,72 @@
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestGenerateScopedKubeconfig(t *testing.T) {
+	cpKonfig, err := clientcmd.Write(*generateTestConfig("cp1", "https://cp1.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName("cp1")},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: cpKonfig},
+	})
+	cpClient := fake.NewSimpleClientset()
+	var requested string
+	cpClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateAction)
+		if create.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		requested = create.GetNamespace() + "/" + action.(k8stesting.CreateActionImpl).Name
+		tr := create.GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		tr.Status.Token = "sa-token"
+		return true, tr, nil
+	})
+
+	k8sType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	config, err := generateScopedKubeconfig(context.Background(), hostClient, cpClient, "cp1", k8sType, "team-a/reader")
+	if err != nil {
+		t.Fatalf("generateScopedKubeconfig returned error: %v", err)
+	}
+	if requested != "team-a/reader" {
+		t.Errorf("expected token request for team-a/reader, got %s", requested)
+	}
+	authName := certs.GenerateAuthInfoServiceAccountName("cp1", "reader")
+	kctx, ok := config.Contexts[config.CurrentContext]
+	if !ok || config.CurrentContext != authName {
+		t.Fatalf("expected current context %s, got %s", authName, config.CurrentContext)
+	}
+	if kctx.Namespace != "team-a" || kctx.AuthInfo != authName || kctx.Cluster != certs.GenerateClusterName("cp1") {
+		t.Errorf("unexpected context %+v", kctx)
+	}
+	authInfo := config.AuthInfos[authName]
+	if authInfo.Token != "sa-token" || len(authInfo.ClientCertificateData) != 0 {
+		t.Errorf("expected token credentials only, got %+v", authInfo)
+	}
+	if cluster := config.Clusters[kctx.Cluster]; cluster == nil || cluster.Server != "https://cp1.localtest.me:9443" {
+		t.Errorf("expected cluster of the admin kubeconfig, got %+v", cluster)
+	}
+
+	if _, err := generateScopedKubeconfig(context.Background(), hostClient, cpClient, "cp1", k8sType, "a/b/c"); err == nil {
+		t.Errorf("expected error for invalid service account")
+	}
+	if _, err := generateScopedKubeconfig(context.Background(), hostClient, cpClient, "missing", k8sType, "reader"); err == nil {
+		t.Errorf("expected error for control plane without kubeconfig secret")
+	}
+}
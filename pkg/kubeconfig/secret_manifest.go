@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	ManagedByKubeflex           = "kubeflex"
+	ControlPlaneNameLabelKey    = "kflex.kubestellar.io/cpname"
+	ControlPlaneTypeLabelKey    = "kflex.kubestellar.io/cptype"
+	SecretManifestKubeconfigKey = util.KubeconfigSecretKeyDefault
+)
+
+// ExportAsSecretManifest returns a YAML manifest for an Opaque Secret named secretName in
+// namespace, holding the minimal kubeconfig for a control plane under the "kubeconfig" key.
+// The kubeconfig contains the control plane credentials in clear, so the manifest should be
+// sealed or encrypted before being committed to a repository.
+func ExportAsSecretManifest(ctx context.Context, client kubernetes.Interface, name, controlPlaneType, secretName, namespace string) ([]byte, error) {
+	if errs := validation.IsDNS1123Subdomain(secretName); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid secret name %q: %s", secretName, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, ", "))
+	}
+
+	cpKonfig, err := loadControlPlaneKubeconfig(ctx, client, name, controlPlaneType)
+	if err != nil {
+		return nil, err
+	}
+	adjustConfigKeys(cpKonfig, name, controlPlaneType)
+	exported, err := ExportKubeconfig(cpKonfig, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := clientcmd.Write(*exported)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				util.ManagedByKey:        ManagedByKubeflex,
+				ControlPlaneNameLabelKey: name,
+				ControlPlaneTypeLabelKey: controlPlaneType,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{SecretManifestKubeconfigKey: data},
+	}
+	return yaml.Marshal(secret)
+}
@@ -0,0 +1,70 @@
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestExportAsSecretManifest(t *testing.T) {
+	cpKonfig, err := clientcmd.Write(*generateTestConfig("cp1", "https://cp1.localtest.me:9443"))
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	hostClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.AdminConfSecret,
+			Namespace: util.GenerateNamespaceFromControlPlaneName("cp1"),
+		},
+		Data: map[string][]byte{util.KubeconfigSecretKeyDefault: cpKonfig},
+	})
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+
+	manifest, err := ExportAsSecretManifest(context.Background(), hostClient, "cp1", cpType, "cp1-kubeconfig", "gitops")
+	if err != nil {
+		t.Fatalf("ExportAsSecretManifest returned error: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := yaml.UnmarshalStrict(manifest, secret); err != nil {
+		t.Fatalf("manifest does not parse as a Secret: %v", err)
+	}
+	if secret.Kind != "Secret" || secret.APIVersion != "v1" {
+		t.Errorf("expected kind Secret and apiVersion v1, got %s %s", secret.Kind, secret.APIVersion)
+	}
+	if secret.Name != "cp1-kubeconfig" || secret.Namespace != "gitops" {
+		t.Errorf("expected secret gitops/cp1-kubeconfig, got %s/%s", secret.Namespace, secret.Name)
+	}
+	if secret.Labels[util.ManagedByKey] != ManagedByKubeflex || secret.Labels[ControlPlaneNameLabelKey] != "cp1" ||
+		secret.Labels[ControlPlaneTypeLabelKey] != cpType {
+		t.Errorf("unexpected labels %v", secret.Labels)
+	}
+
+	config, err := clientcmd.Load(secret.Data[SecretManifestKubeconfigKey])
+	if err != nil {
+		t.Fatalf("error loading kubeconfig from secret: %v", err)
+	}
+	if config.CurrentContext != certs.GenerateContextName("cp1") || len(config.Contexts) != 1 {
+		t.Errorf("expected a single context %s, got %v", certs.GenerateContextName("cp1"), config.Contexts)
+	}
+	cluster := config.Clusters[certs.GenerateClusterName("cp1")]
+	if cluster == nil || cluster.Server != "https://cp1.localtest.me:9443" {
+		t.Errorf("expected cluster with server https://cp1.localtest.me:9443, got %v", cluster)
+	}
+
+	if _, err := ExportAsSecretManifest(context.Background(), hostClient, "cp1", cpType, "Bad_Name", "gitops"); err == nil {
+		t.Errorf("expected error for invalid secret name")
+	}
+	if _, err := ExportAsSecretManifest(context.Background(), hostClient, "missing", cpType, "cp1-kubeconfig", "gitops"); err == nil {
+		t.Errorf("expected error for missing control plane kubeconfig secret")
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+// SwitchContextSafely switches the current context of the default kubeconfig to the context
+// of a control plane, and returns a function that switches back to the previous current
+// context. The target context must already exist, under its generated name or the name set
+// with spec.contextName. Restoring is best effort: the kubeconfig
+// is reloaded, so changes made in between are kept, and the previous context is only restored
+// if it still exists.
+func SwitchContextSafely(ctx context.Context, name, controlPlaneType string) (restore func(), err error) {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctxName, ok := findControlPlaneContext(config, name)
+	if !ok {
+		return nil, fmt.Errorf("context %s not found for %s control plane %s", certs.GenerateContextName(name), controlPlaneType, name)
+	}
+
+	previous := config.CurrentContext
+	config.CurrentContext = ctxName
+	if err := WriteKubeconfig(ctx, config); err != nil {
+		return nil, err
+	}
+
+	restore = func() {
+		unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+		if err != nil {
+			return
+		}
+		defer unlock()
+		config, err := LoadKubeconfig(ctx)
+		if err != nil {
+			return
+		}
+		if _, ok := config.Contexts[previous]; !ok || config.CurrentContext == previous {
+			return
+		}
+		config.CurrentContext = previous
+		_ = WriteKubeconfig(ctx, config)
+	}
+	return restore, nil
+}
+
+// SwitchToControlPlaneContext sets the context of a control plane already merged into the
+// default kubeconfig as the current context, without fetching its kubeconfig secret again.
+// The context is found under its generated name or the name set with spec.contextName.
+// It is the kubeconfig file counterpart of SwitchContext.
+func SwitchToControlPlaneContext(ctx context.Context, name, controlPlaneType string) error {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return err
+	}
+	ctxName, ok := findControlPlaneContext(config, name)
+	if !ok {
+		return fmt.Errorf("context %s not found for %s control plane %s, it must be merged into the kubeconfig first", certs.GenerateContextName(name), controlPlaneType, name)
+	}
+	config.CurrentContext = ctxName
+	return WriteKubeconfig(ctx, config)
+}
+
+// SwitchToHostingClusterContext sets originalContext as the current context of the default
+// kubeconfig. When originalContext is empty, the initial context recorded by kubeflex when
+// control plane credentials were first merged is used instead.
+func SwitchToHostingClusterContext(ctx context.Context, originalContext string) error {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return err
+	}
+	if originalContext == "" {
+		if !IsInitialConfigSet(config) {
+			return fmt.Errorf("no hosting cluster context given and no initial context recorded")
+		}
+		if err := SwitchToInitialContext(config, false); err != nil {
+			return err
+		}
+		originalContext = config.CurrentContext
+	}
+	if _, ok := config.Contexts[originalContext]; !ok {
+		return fmt.Errorf("hosting cluster context %s not found", originalContext)
+	}
+	config.CurrentContext = originalContext
+	return WriteKubeconfig(ctx, config)
+}
+
+// RestorePreviousContext sets previous as the current context of the default kubeconfig and
+// records the context it replaces as the new previous context, so that calling it again
+// switches back. When previous is empty, the previous context recorded by LoadAndMerge or
+// RecordPreviousContext is used. If the previous context no longer exists, the current context
+// is kept and a warning is returned instead of an error.
+func RestorePreviousContext(ctx context.Context, previous string) (warning string, err error) {
+	unlock, err := lockKubeconfig(DefaultKubeconfigPath(), DefaultLockTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if previous == "" {
+		previous = GetPreviousContext(config)
+		if previous == "" {
+			return "", fmt.Errorf("no previous context given and no previous context recorded")
+		}
+	}
+	if _, ok := config.Contexts[previous]; !ok {
+		return fmt.Sprintf("previous context %s no longer exists, keeping current context %s", previous, config.CurrentContext), nil
+	}
+	if config.CurrentContext == previous {
+		return "", nil
+	}
+	current := config.CurrentContext
+	config.CurrentContext = previous
+	if err := RecordPreviousContext(config, current); err != nil {
+		return "", err
+	}
+	return "", WriteKubeconfig(ctx, config)
+}
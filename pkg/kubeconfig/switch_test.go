@@ -0,0 +1,186 @@
+package kubeconfig
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+)
+
+func TestSwitchContextSafely(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+	config.CurrentContext = "kind-kubeflex"
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	ctx := context.Background()
+	restore, err := SwitchContextSafely(ctx, "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	if err != nil {
+		t.Fatalf("SwitchContextSafely returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+
+	restore()
+	assertCurrentContext(t, kubeconfigPath, "kind-kubeflex")
+
+	if _, err := SwitchContextSafely(ctx, "missing", string(tenancyv1alpha1.ControlPlaneTypeK8S)); err == nil {
+		t.Errorf("expected error for missing context")
+	}
+	assertCurrentContext(t, kubeconfigPath, "kind-kubeflex")
+}
+
+func TestSwitchContextSafelyRenamed(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	// the context was renamed with spec.contextName
+	renameKey(config, config.Contexts, certs.GenerateContextName("cp1"), "prod")
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+	config.CurrentContext = "kind-kubeflex"
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	restore, err := SwitchContextSafely(context.Background(), "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	if err != nil {
+		t.Fatalf("SwitchContextSafely returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "prod")
+
+	restore()
+	assertCurrentContext(t, kubeconfigPath, "kind-kubeflex")
+}
+
+func TestSwitchToControlPlaneAndHostingClusterContext(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+	config.CurrentContext = "kind-kubeflex"
+	saveInitialContextName(config)
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	ctx := context.Background()
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	if err := SwitchToControlPlaneContext(ctx, "cp1", cpType); err != nil {
+		t.Fatalf("SwitchToControlPlaneContext returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+
+	if err := SwitchToControlPlaneContext(ctx, "missing", cpType); err == nil {
+		t.Errorf("expected error for missing context")
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+
+	// with no context given, the recorded initial context is used
+	if err := SwitchToHostingClusterContext(ctx, ""); err != nil {
+		t.Fatalf("SwitchToHostingClusterContext returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "kind-kubeflex")
+
+	if err := SwitchToHostingClusterContext(ctx, "cp1"); err != nil {
+		t.Fatalf("SwitchToHostingClusterContext returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+
+	if err := SwitchToHostingClusterContext(ctx, "missing"); err == nil {
+		t.Errorf("expected error for missing hosting cluster context")
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+}
+
+func TestSwitchToControlPlaneContextRenamed(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	// the context was renamed with spec.contextName
+	renameKey(config, config.Contexts, certs.GenerateContextName("cp1"), "prod")
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+	config.CurrentContext = "kind-kubeflex"
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	if err := SwitchToControlPlaneContext(context.Background(), "cp1", string(tenancyv1alpha1.ControlPlaneTypeK8S)); err != nil {
+		t.Fatalf("SwitchToControlPlaneContext returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "prod")
+}
+
+func assertCurrentContext(t *testing.T, kubeconfigPath, expected string) {
+	t.Helper()
+	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	if config.CurrentContext != expected {
+		t.Errorf("expected current context %s, got %s", expected, config.CurrentContext)
+	}
+}
+
+func TestRestorePreviousContext(t *testing.T) {
+	config := generateTestConfig("cp1", "https://cp1.localtest.me:9443")
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+	config.CurrentContext = "kind-kubeflex"
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	ctx := context.Background()
+	if _, err := RestorePreviousContext(ctx, ""); err == nil {
+		t.Errorf("expected error when no previous context is recorded")
+	}
+
+	warning, err := RestorePreviousContext(ctx, "cp1")
+	if err != nil || warning != "" {
+		t.Fatalf("RestorePreviousContext returned warning %q, error %v", warning, err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+
+	// the context switched away from is recorded, so restoring again toggles back
+	if _, err := RestorePreviousContext(ctx, ""); err != nil {
+		t.Fatalf("RestorePreviousContext returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "kind-kubeflex")
+	if _, err := RestorePreviousContext(ctx, ""); err != nil {
+		t.Fatalf("RestorePreviousContext returned error: %v", err)
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+
+	warning, err = RestorePreviousContext(ctx, "missing")
+	if err != nil {
+		t.Fatalf("RestorePreviousContext returned error: %v", err)
+	}
+	if warning == "" {
+		t.Errorf("expected warning for missing previous context")
+	}
+	assertCurrentContext(t, kubeconfigPath, "cp1")
+}
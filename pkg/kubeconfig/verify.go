@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	verifyConcurrency = 5
+	verifyTimeout     = 5 * time.Second
+)
+
+// IsKubeflexContext returns true if the context uses the cluster and authInfo
+// names generated by kubeflex for a control plane, whatever the name of the context
+func IsKubeflexContext(config *clientcmdapi.Config, contextName string) bool {
+	kctx, ok := config.Contexts[contextName]
+	if !ok {
+		return false
+	}
+	_, ok = controlPlaneOfContext(kctx)
+	return ok
+}
+
+// GetKubeflexContextNames returns the sorted names of all kubeflex contexts in config, leaving
+// out the internal contexts merged with WithInternalContext
+func GetKubeflexContextNames(config *clientcmdapi.Config) []string {
+	names := []string{}
+	for _, c := range listControlPlaneContexts(config) {
+		if !c.Internal {
+			names = append(names, c.ContextName)
+		}
+	}
+	return names
+}
+
+// VerifyAllContexts checks that the API server of every kubeflex context in the
+// default kubeconfig is reachable. The returned map has an entry for each kubeflex
+// context, with a nil error for reachable contexts. Checks run concurrently and
+// one failure does not stop the others; contexts not yet checked when ctx is
+// cancelled get the context error.
+func VerifyAllContexts(ctx context.Context) (map[string]error, error) {
+	config, err := LoadKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return verifyContexts(ctx, config, verifyConcurrency), nil
+}
+
+func verifyContexts(ctx context.Context, config *clientcmdapi.Config, concurrency int) map[string]error {
+	results := map[string]error{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for _, c := range listControlPlaneContexts(config) {
+		// the in-cluster endpoint of internal contexts is only reachable from the hosting cluster
+		if c.Internal {
+			continue
+		}
+		name := c.ContextName
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			results[name] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := verifyContext(ctx, config, name)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return results
+}
+
+// verifyContext does a lightweight GET of the API server version endpoint
+func verifyContext(ctx context.Context, config *clientcmdapi.Config, contextName string) error {
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	restConfig.Timeout = verifyTimeout
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	return clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+}
+
+// ContextVerificationError is returned when a context merged with WithVerify cannot get the
+// version of its API server
+type ContextVerificationError struct {
+	ContextName string
+	Server      string
+	Err         error
+}
+
+func (e *ContextVerificationError) Error() string {
+	return fmt.Sprintf("context %s cannot reach the API server at %s: %v; check that the server URL is reachable "+
+		"from this machine and that the control plane credentials are valid", e.ContextName, e.Server, e.Err)
+}
+
+func (e *ContextVerificationError) Unwrap() error {
+	return e.Err
+}
+
+// verifyMergedContext works as verifyContext and returns a ContextVerificationError naming the
+// server of the context
+func verifyMergedContext(ctx context.Context, config *clientcmdapi.Config, contextName string) error {
+	err := verifyContext(ctx, config, contextName)
+	if err == nil {
+		return nil
+	}
+	e := &ContextVerificationError{ContextName: contextName, Err: err}
+	if kctx, ok := config.Contexts[contextName]; ok {
+		if cluster, ok := config.Clusters[kctx.Cluster]; ok {
+			e.Server = cluster.Server
+		}
+	}
+	return e
+}
@@ -0,0 +1,121 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestVerifyAllContexts(t *testing.T) {
+	reachable := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"27","gitVersion":"v1.27.1"}`))
+	}))
+	defer reachable.Close()
+	unreachable := httptest.NewTLSServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: reachable.Certificate().Raw})
+	config := clientcmdapi.NewConfig()
+	for cpName, server := range map[string]string{"cp1": reachable.URL, "cp2": unreachable.URL} {
+		cpConfig := generateTestConfig(cpName, server)
+		cpConfig.Clusters[certs.GenerateClusterName(cpName)].CertificateAuthorityData = caData
+		cpConfig.AuthInfos[certs.GenerateAuthInfoAdminName(cpName)] = &clientcmdapi.AuthInfo{Token: "token-" + cpName}
+		if err := merge(config, cpConfig); err != nil {
+			t.Fatalf("error merging config: %v", err)
+		}
+	}
+	// contexts not generated by kubeflex are skipped
+	config.Clusters["kind-kubeflex"] = &clientcmdapi.Cluster{Server: unreachable.URL}
+	config.AuthInfos["kind-kubeflex"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["kind-kubeflex"] = &clientcmdapi.Context{Cluster: "kind-kubeflex", AuthInfo: "kind-kubeflex"}
+
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatalf("error writing kubeconfig: %v", err)
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfigPath)
+
+	results, err := VerifyAllContexts(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyAllContexts returned error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected results for 2 contexts, got %d: %v", len(results), results)
+	}
+	if err := results["cp1"]; err != nil {
+		t.Errorf("expected cp1 to be reachable, got %v", err)
+	}
+	if err := results["cp2"]; err == nil {
+		t.Errorf("expected cp2 to be unreachable")
+	}
+}
+
+func TestVerifyContextsCancelled(t *testing.T) {
+	config := generateTestConfig("cp1", "https://127.0.0.1:1")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := verifyContexts(ctx, config, 1)
+	if err, ok := results["cp1"]; !ok || err == nil {
+		t.Errorf("expected an error for cp1 after cancellation, got %v", results)
+	}
+}
+
+func TestLoadAndMergeVerify(t *testing.T) {
+	reachable := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"27","gitVersion":"v1.27.1"}`))
+	}))
+	defer reachable.Close()
+	unreachable := httptest.NewTLSServer(http.NotFoundHandler())
+	unreachable.Close()
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: reachable.Certificate().Raw})
+
+	secrets := []runtime.Object{}
+	for cpName, server := range map[string]string{"cp1": reachable.URL, "cp2": unreachable.URL} {
+		cpConfig := generateTestConfig(cpName, server)
+		cpConfig.Clusters[certs.GenerateClusterName(cpName)].CertificateAuthorityData = caData
+		cpConfig.AuthInfos[certs.GenerateAuthInfoAdminName(cpName)] = &clientcmdapi.AuthInfo{Token: "token-" + cpName}
+		data, err := clientcmd.Write(*cpConfig)
+		if err != nil {
+			t.Fatalf("error serializing kubeconfig: %v", err)
+		}
+		secrets = append(secrets, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: util.GenerateNamespaceFromControlPlaneName(cpName)},
+			Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+		})
+	}
+	hostClient := fake.NewSimpleClientset(secrets...)
+	cpType := string(tenancyv1alpha1.ControlPlaneTypeK8S)
+	o := newMergeOptions([]MergeOption{WithVerify(true)})
+
+	konfig := clientcmdapi.NewConfig()
+	if _, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp1", cpType, konfig, o); err != nil {
+		t.Fatalf("expected verification of a reachable context to pass, got %v", err)
+	}
+
+	_, err := loadAndMergeWithOptions(context.Background(), hostClient, "cp2", cpType, konfig, o)
+	var verifyErr *ContextVerificationError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("expected a ContextVerificationError, got %v", err)
+	}
+	if verifyErr.ContextName != certs.GenerateContextName("cp2") || verifyErr.Server != unreachable.URL {
+		t.Errorf("unexpected verification error %+v", verifyErr)
+	}
+}
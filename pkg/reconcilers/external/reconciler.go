@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+)
+
+// ExternalReconciler reconciles an external ControlPlane, which adopts an existing cluster
+// through a user supplied kubeconfig without provisioning anything
+type ExternalReconciler struct {
+	*shared.BaseReconciler
+}
+
+func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient, recorder record.EventRecorder) *ExternalReconciler {
+	return &ExternalReconciler{
+		BaseReconciler: &shared.BaseReconciler{
+			Client:        cl,
+			Scheme:        scheme,
+			ClientSet:     clientSet,
+			DynamicClient: dynamicClient,
+			Recorder:      recorder,
+		},
+	}
+}
+
+func (r *ExternalReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (ctrl.Result, error) {
+	shared.ControlPlaneLogger(ctx, hcp).V(1).Info("Reconciling control plane")
+
+	if err := r.ValidateKubeconfigSecret(ctx, hcp); err != nil {
+		shared.SetHealthConditions(hcp, false)
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	ref := hcp.Spec.External.KubeconfigSecretRef
+	hcp.Status.SecretRef = &tenancyv1alpha1.SecretReference{
+		Name:         ref.Name,
+		Namespace:    ref.Namespace,
+		Key:          ref.Key,
+		InClusterKey: ref.Key,
+	}
+	// there is nothing to provision, so the control plane is available as soon as
+	// the referenced kubeconfig is valid
+	shared.SetHealthConditions(hcp, true)
+
+	return r.UpdateStatusForSyncingSuccess(ctx, hcp)
+}
+
+// ValidateKubeconfigSecret checks that the secret referenced by the external spec exists
+// and holds a kubeconfig with a current context under the referenced key
+func (r *ExternalReconciler) ValidateKubeconfigSecret(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	if hcp.Spec.External == nil {
+		return fmt.Errorf("spec.external is required for control plane type %s", tenancyv1alpha1.ControlPlaneTypeExternal)
+	}
+	ref := hcp.Spec.External.KubeconfigSecretRef
+	if ref.Name == "" || ref.Namespace == "" || ref.Key == "" {
+		return fmt.Errorf("spec.external.kubeconfigSecretRef requires a namespace, name and key")
+	}
+
+	secret := &v1.Secret{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret, &client.GetOptions{}); err != nil {
+		return fmt.Errorf("error getting kubeconfig secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		return fmt.Errorf("kubeconfig secret %s/%s has no key %s", ref.Namespace, ref.Name, ref.Key)
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return fmt.Errorf("invalid kubeconfig in secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	kctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return fmt.Errorf("kubeconfig in secret %s/%s has no current context", ref.Namespace, ref.Name)
+	}
+	if _, ok := config.Clusters[kctx.Cluster]; !ok {
+		return fmt.Errorf("kubeconfig in secret %s/%s has no cluster %s", ref.Namespace, ref.Name, kctx.Cluster)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package external
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+)
+
+func TestReconcileExternal(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["kubernetes"] = &clientcmdapi.Cluster{Server: "https://managed.example.com"}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["admin"] = &clientcmdapi.Context{Cluster: "kubernetes", AuthInfo: "admin"}
+	config.CurrentContext = "admin"
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatalf("error writing test kubeconfig: %v", err)
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "managed-kubeconfig", Namespace: "team-a"},
+		Data:       map[string][]byte{"value": data},
+	}
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "managed"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeExternal,
+			External: &tenancyv1alpha1.ExternalSpec{
+				KubeconfigSecretRef: tenancyv1alpha1.SecretKeyReference{Namespace: "team-a", Name: "managed-kubeconfig", Key: "value"},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp, secret)
+
+	if _, err := r.Reconcile(context.TODO(), hcp); err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	if !tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		t.Errorf("expected control plane to be available, got %+v", hcp.Status.Conditions)
+	}
+	ref := hcp.Status.SecretRef
+	if ref == nil || ref.Namespace != "team-a" || ref.Name != "managed-kubeconfig" || ref.Key != "value" {
+		t.Errorf("expected status secret ref to point at the user secret, got %+v", ref)
+	}
+	// nothing is provisioned for an external control plane
+	nsList := &v1.NamespaceList{}
+	if err := cl.List(context.TODO(), nsList); err != nil {
+		t.Fatalf("error listing namespaces: %v", err)
+	}
+	if len(nsList.Items) != 0 {
+		t.Errorf("expected no namespace to be created, got %d", len(nsList.Items))
+	}
+}
+
+func TestValidateKubeconfigSecret(t *testing.T) {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "managed-kubeconfig", Namespace: "team-a"},
+		Data:       map[string][]byte{"value": []byte("apiVersion: v1\nkind: Config\n")},
+	}
+	tests := []struct {
+		name     string
+		external *tenancyv1alpha1.ExternalSpec
+		expected string
+	}{
+		{name: "missing spec", expected: "spec.external is required"},
+		{
+			name:     "missing secret",
+			external: &tenancyv1alpha1.ExternalSpec{KubeconfigSecretRef: tenancyv1alpha1.SecretKeyReference{Namespace: "team-a", Name: "missing", Key: "value"}},
+			expected: "error getting kubeconfig secret team-a/missing",
+		},
+		{
+			name:     "missing key",
+			external: &tenancyv1alpha1.ExternalSpec{KubeconfigSecretRef: tenancyv1alpha1.SecretKeyReference{Namespace: "team-a", Name: "managed-kubeconfig", Key: "other"}},
+			expected: "has no key other",
+		},
+		{
+			name:     "no current context",
+			external: &tenancyv1alpha1.ExternalSpec{KubeconfigSecretRef: tenancyv1alpha1.SecretKeyReference{Namespace: "team-a", Name: "managed-kubeconfig", Key: "value"}},
+			expected: "has no current context",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcp := &tenancyv1alpha1.ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "managed"},
+				Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeExternal, External: tt.external},
+			}
+			r, _ := newTestReconciler(t, hcp, secret)
+			err := r.ValidateKubeconfigSecret(context.TODO(), hcp)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func newTestReconciler(t *testing.T, objs ...client.Object) (*ExternalReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding client-go scheme: %v", err)
+	}
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&tenancyv1alpha1.ControlPlane{}).Build()
+	return &ExternalReconciler{BaseReconciler: &shared.BaseReconciler{Client: cl, Scheme: scheme}}, cl
+}
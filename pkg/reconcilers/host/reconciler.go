@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	// adminClusterRole and viewClusterRole are the cluster roles bound to the service account
+	// of a host control plane in its namespace for the namespace-admin and view access
+	adminClusterRole = "admin"
+	viewClusterRole  = "view"
+	tokenSecretName  = util.HostServiceAccountName + "-token"
+)
+
+// HostReconciler reconciles a host ControlPlane, which provisions no API server and gives
+// access to a namespace of the hosting cluster through a service account token
+type HostReconciler struct {
+	*shared.BaseReconciler
+}
+
+func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient, recorder record.EventRecorder) *HostReconciler {
+	return &HostReconciler{
+		BaseReconciler: &shared.BaseReconciler{
+			Client:        cl,
+			Scheme:        scheme,
+			ClientSet:     clientSet,
+			DynamicClient: dynamicClient,
+			Recorder:      recorder,
+		},
+	}
+}
+
+func (r *HostReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (ctrl.Result, error) {
+	logger := shared.ControlPlaneLogger(ctx, hcp)
+	logger.V(1).Info("Reconciling control plane")
+
+	start := time.Now()
+	if err := r.BaseReconciler.ReconcileNamespace(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	shared.LogPhase(logger, "namespace", start)
+
+	start = time.Now()
+	if err := r.ReconcileServiceAccount(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := r.ReconcileRoleBinding(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	token, err := r.ReconcileTokenSecret(ctx, hcp)
+	if err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	// re-queue until the token controller populates the token secret
+	if token == nil {
+		shared.SetHealthConditions(hcp, false)
+		if _, err := r.UpdateStatusForSyncingSuccess(ctx, hcp); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+	}
+	shared.LogPhase(logger, "rbac", start)
+
+	cfg, err := r.GetConfig(ctx)
+	if err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	server := hostServer(cfg)
+	if err := r.ReconcileKubeconfigSecret(ctx, hcp, token, server); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	hcp.Status.APIServerEndpoint = server
+	r.UpdateStatusWithSecretRef(hcp, util.HostKubeConfigSecret, util.KubeconfigSecretKeyDefault, util.KubeconfigSecretKeyInCluster)
+	// there is no API server to wait for, so the control plane is available as soon as
+	// its kubeconfig is generated
+	shared.SetHealthConditions(hcp, true)
+
+	return r.UpdateStatusForSyncingSuccess(ctx, hcp)
+}
+
+// ReconcileServiceAccount creates the service account whose token is used in the kubeconfig
+// of the control plane
+func (r *HostReconciler) ReconcileServiceAccount(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.HostServiceAccountName,
+			Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+		},
+	}
+	return r.createIfNotFound(hcp, sa)
+}
+
+// ReconcileRoleBinding binds the cluster role selected by spec.host.access to the service
+// account of the control plane, which scopes its access to the namespace of the control plane.
+// The role of a binding cannot be changed, so the binding is replaced when the access changes.
+func (r *HostReconciler) ReconcileRoleBinding(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.HostServiceAccountName,
+			Namespace: namespace,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     accessClusterRole(hcp),
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      util.HostServiceAccountName,
+				Namespace: namespace,
+			},
+		},
+	}
+	existing := &rbacv1.RoleBinding{}
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(binding), existing, &client.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return err
+	case existing.RoleRef == binding.RoleRef:
+		return nil
+	default:
+		if err := r.Client.Delete(context.TODO(), existing, &client.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	if err := controllerutil.SetControllerReference(hcp, binding, r.Scheme); err != nil {
+		return err
+	}
+	return r.Client.Create(context.TODO(), binding, &client.CreateOptions{})
+}
+
+// accessClusterRole returns the cluster role granting the access selected by spec.host.access
+func accessClusterRole(hcp *tenancyv1alpha1.ControlPlane) string {
+	if hcp.Spec.Host != nil && hcp.Spec.Host.Access == tenancyv1alpha1.HostAccessView {
+		return viewClusterRole
+	}
+	return adminClusterRole
+}
+
+// ReconcileTokenSecret creates the token secret of the service account of the control plane
+// and returns it once the token controller has populated its token and CA, or nil before
+func (r *HostReconciler) ReconcileTokenSecret(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tokenSecretName,
+			Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: util.HostServiceAccountName,
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	if err := r.createIfNotFound(hcp, secret); err != nil {
+		return nil, err
+	}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret, &client.GetOptions{}); err != nil {
+		return nil, err
+	}
+	if len(secret.Data[corev1.ServiceAccountTokenKey]) == 0 || len(secret.Data[corev1.ServiceAccountRootCAKey]) == 0 {
+		return nil, nil
+	}
+	return secret, nil
+}
+
+// createIfNotFound creates obj, owned by the control plane, unless it already exists
+func (r *HostReconciler) createIfNotFound(hcp *tenancyv1alpha1.ControlPlane, obj client.Object) error {
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object), &client.GetOptions{})
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(hcp, obj, r.Scheme); err != nil {
+		return err
+	}
+	return r.Client.Create(context.TODO(), obj, &client.CreateOptions{})
+}
@@ -0,0 +1,163 @@
+package host
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileHost(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeHost},
+	}
+	r, cl := newTestReconciler(t, hcp, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: util.SystemConfigMap, Namespace: util.SystemNamespace},
+		Data: map[string]string{
+			"externalPort":     "9443",
+			"isOpenShift":      "false",
+			"hostAPIServerURL": "https://host.example.com:6443",
+		},
+	})
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+
+	// the control plane is not available until the token secret is populated
+	result, err := r.Reconcile(context.TODO(), hcp)
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Errorf("expected re-queue while the token secret is not populated")
+	}
+	if tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		t.Errorf("expected control plane to be unavailable before the token is populated")
+	}
+	binding := &rbacv1.RoleBinding{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: util.HostServiceAccountName}, binding); err != nil {
+		t.Fatalf("expected role binding: %v", err)
+	}
+	if binding.RoleRef.Kind != "ClusterRole" || binding.RoleRef.Name != adminClusterRole {
+		t.Errorf("expected binding to the admin cluster role, got %+v", binding.RoleRef)
+	}
+
+	// simulate the token controller
+	token := &corev1.Secret{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: tokenSecretName}, token); err != nil {
+		t.Fatalf("expected token secret: %v", err)
+	}
+	if token.Type != corev1.SecretTypeServiceAccountToken || token.Annotations[corev1.ServiceAccountNameKey] != util.HostServiceAccountName {
+		t.Errorf("unexpected token secret: %+v", token)
+	}
+	token.Data = map[string][]byte{
+		corev1.ServiceAccountTokenKey:  []byte("sa-token"),
+		corev1.ServiceAccountRootCAKey: []byte("ca-data"),
+	}
+	if err := cl.Update(context.TODO(), token); err != nil {
+		t.Fatalf("error updating token secret: %v", err)
+	}
+
+	if _, err := r.Reconcile(context.TODO(), hcp); err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	if !tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		t.Errorf("expected control plane to be available, got %+v", hcp.Status.Conditions)
+	}
+	ref := hcp.Status.SecretRef
+	if ref == nil || ref.Namespace != namespace || ref.Name != util.HostKubeConfigSecret || ref.Key != util.KubeconfigSecretKeyDefault {
+		t.Errorf("unexpected status secret ref %+v", ref)
+	}
+
+	secret := &corev1.Secret{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: util.HostKubeConfigSecret}, secret); err != nil {
+		t.Fatalf("expected kubeconfig secret: %v", err)
+	}
+	config, err := clientcmd.Load(secret.Data[util.KubeconfigSecretKeyInCluster])
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	kctx := config.Contexts[config.CurrentContext]
+	if kctx == nil || kctx.Namespace != namespace {
+		t.Fatalf("expected current context scoped to %s, got %+v", namespace, kctx)
+	}
+	if got := config.AuthInfos[kctx.AuthInfo].Token; got != "sa-token" {
+		t.Errorf("expected service account token, got %q", got)
+	}
+	if got := config.Clusters[kctx.Cluster]; got.Server != util.HostInClusterServer || string(got.CertificateAuthorityData) != "ca-data" {
+		t.Errorf("unexpected cluster %+v", got)
+	}
+
+	// the default kubeconfig uses the configured external address of the hosting cluster
+	config, err = clientcmd.Load(secret.Data[util.KubeconfigSecretKeyDefault])
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	if got := config.Clusters[config.Contexts[config.CurrentContext].Cluster].Server; got != "https://host.example.com:6443" {
+		t.Errorf("expected server https://host.example.com:6443 in the default kubeconfig, got %s", got)
+	}
+	if hcp.Status.APIServerEndpoint != "https://host.example.com:6443" {
+		t.Errorf("expected endpoint https://host.example.com:6443, got %s", hcp.Status.APIServerEndpoint)
+	}
+}
+
+func TestHostServer(t *testing.T) {
+	if got := hostServer(&shared.SharedConfig{}); got != util.HostInClusterServer {
+		t.Errorf("expected the in-cluster address without a configured address, got %s", got)
+	}
+	if got := hostServer(&shared.SharedConfig{HostAPIServerURL: "https://host.example.com:6443"}); got != "https://host.example.com:6443" {
+		t.Errorf("expected the configured address, got %s", got)
+	}
+}
+
+func TestReconcileRoleBindingAccess(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeHost},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.HostServiceAccountName}
+
+	for _, tc := range []struct {
+		access   tenancyv1alpha1.HostAccess
+		expected string
+	}{
+		{tenancyv1alpha1.HostAccessView, viewClusterRole},
+		{tenancyv1alpha1.HostAccessView, viewClusterRole},
+		{tenancyv1alpha1.HostAccessNamespaceAdmin, adminClusterRole},
+	} {
+		hcp.Spec.Host = &tenancyv1alpha1.HostSpec{Access: tc.access}
+		if err := r.ReconcileRoleBinding(context.TODO(), hcp); err != nil {
+			t.Fatalf("ReconcileRoleBinding returned error: %v", err)
+		}
+		binding := &rbacv1.RoleBinding{}
+		if err := cl.Get(context.TODO(), key, binding); err != nil {
+			t.Fatalf("expected role binding: %v", err)
+		}
+		if binding.RoleRef.Name != tc.expected {
+			t.Errorf("expected binding to the %s cluster role for %s access, got %s", tc.expected, tc.access, binding.RoleRef.Name)
+		}
+	}
+}
+
+func newTestReconciler(t *testing.T, objs ...client.Object) (*HostReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding client-go scheme: %v", err)
+	}
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&tenancyv1alpha1.ControlPlane{}).Build()
+	return &HostReconciler{BaseReconciler: &shared.BaseReconciler{Client: cl, Scheme: scheme}}, cl
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"bytes"
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// ReconcileKubeconfigSecret writes the kubeconfig of the control plane, which uses the
+// service account token and defaults to the namespace of the control plane, under the
+// default key with server and under the in-cluster key with the in-cluster address
+func (r *HostReconciler) ReconcileKubeconfigSecret(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, token *corev1.Secret, server string) error {
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	data := map[string][]byte{}
+	for key, s := range map[string]string{
+		util.KubeconfigSecretKeyDefault:   server,
+		util.KubeconfigSecretKeyInCluster: util.HostInClusterServer,
+	} {
+		konfig, err := clientcmd.Write(generateKubeconfig(s, namespace, token))
+		if err != nil {
+			return err
+		}
+		data[key] = konfig
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.HostKubeConfigSecret,
+			Namespace: namespace,
+		},
+	}
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret, &client.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		secret.Data = data
+		if err := controllerutil.SetControllerReference(hcp, secret, r.Scheme); err != nil {
+			return err
+		}
+		return r.Client.Create(context.TODO(), secret, &client.CreateOptions{})
+	}
+
+	if kubeconfigDataEqual(secret.Data, data) {
+		return nil
+	}
+	secret.Data = data
+	return r.Client.Update(context.TODO(), secret, &client.UpdateOptions{})
+}
+
+func generateKubeconfig(server, namespace string, token *corev1.Secret) clientcmdapi.Config {
+	return *certs.GenerateTokenKubeconfig(util.HostClusterName, util.HostServiceAccountName, server, namespace,
+		token.Data[corev1.ServiceAccountRootCAKey], string(token.Data[corev1.ServiceAccountTokenKey]))
+}
+
+func kubeconfigDataEqual(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range b {
+		if !bytes.Equal(a[k], v) {
+			return false
+		}
+	}
+	return true
+}
+
+// hostServer returns the address of the API server of the hosting cluster for clients running
+// outside of it, set with hostAPIServerURL in the kubeflex config. The operator reaches the API
+// server through its cluster IP, which is not routable from outside, so the in-cluster address is
+// used when none is set, and the kflex CLI replaces it with the server of the hosting cluster
+// context when it merges the kubeconfig.
+func hostServer(cfg *shared.SharedConfig) string {
+	if cfg.HostAPIServerURL != "" {
+		return cfg.HostAPIServerURL
+	}
+	return util.HostInClusterServer
+}
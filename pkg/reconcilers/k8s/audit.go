@@ -0,0 +1,184 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	AuditPolicyConfigMapName = "audit-policy"
+	AuditPolicyKey           = "audit-policy.yaml"
+	AuditPolicyMountPath     = "/etc/kubernetes/audit"
+	auditPolicyVolumeName    = "audit-policy"
+	auditPolicyKind          = "Policy"
+)
+
+// ReconcileAuditPolicy writes the audit policy generated for the audit level, or a copy of
+// the referenced custom policy, to a config map in the control plane namespace, where it is
+// mounted by the API server
+func (r *K8sReconciler) ReconcileAuditPolicy(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	audit := hcp.Spec.Audit
+	if !auditEnabled(audit) {
+		return nil
+	}
+
+	var data string
+	if audit.PolicyConfigMapRef != nil {
+		var err error
+		data, err = r.GetConfigMapKeyData(ctx, *audit.PolicyConfigMapRef)
+		if err != nil {
+			return err
+		}
+		if err := ValidateAuditPolicy([]byte(data)); err != nil {
+			return err
+		}
+	} else {
+		policy, err := GenerateAuditPolicy(audit.Level)
+		if err != nil {
+			return err
+		}
+		data = string(policy)
+	}
+	return r.ReconcileControlPlaneConfigMap(ctx, hcp, AuditPolicyConfigMapName, map[string]string{AuditPolicyKey: data})
+}
+
+// GenerateAuditPolicy returns the audit policy for an audit level. Both levels skip
+// health checks and the RequestReceived stage, and never log the bodies of secrets,
+// config maps and token reviews
+func GenerateAuditPolicy(level tenancyv1alpha1.AuditLevel) ([]byte, error) {
+	rules := []auditv1.PolicyRule{
+		{
+			Level:           auditv1.LevelNone,
+			NonResourceURLs: []string{"/healthz*", "/livez*", "/readyz*", "/version"},
+		},
+		{
+			Level: auditv1.LevelNone,
+			Users: []string{"system:apiserver"},
+			Verbs: []string{"get", "list", "watch"},
+		},
+	}
+
+	switch level {
+	case "", tenancyv1alpha1.AuditLevelMetadata:
+	case tenancyv1alpha1.AuditLevelRequestResponse:
+		rules = append(rules,
+			auditv1.PolicyRule{
+				Level: auditv1.LevelMetadata,
+				Resources: []auditv1.GroupResources{
+					{Group: "", Resources: []string{"secrets", "configmaps", "serviceaccounts/token"}},
+					{Group: "authentication.k8s.io", Resources: []string{"tokenreviews"}},
+				},
+			},
+			auditv1.PolicyRule{
+				Level: auditv1.LevelRequestResponse,
+				Verbs: []string{"create", "update", "patch", "delete", "deletecollection"},
+				Resources: []auditv1.GroupResources{
+					{Group: "rbac.authorization.k8s.io"},
+					{Group: "certificates.k8s.io"},
+					{Group: "admissionregistration.k8s.io"},
+					{Group: "apiextensions.k8s.io"},
+					{Group: "", Resources: []string{"serviceaccounts", "namespaces"}},
+				},
+			})
+	default:
+		return nil, fmt.Errorf("no audit policy can be generated for audit level %q", level)
+	}
+	rules = append(rules, auditv1.PolicyRule{Level: auditv1.LevelMetadata})
+
+	policy := &auditv1.Policy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       auditPolicyKind,
+			APIVersion: auditv1.SchemeGroupVersion.String(),
+		},
+		OmitStages: []auditv1.Stage{auditv1.StageRequestReceived},
+		Rules:      rules,
+	}
+	return yaml.Marshal(policy)
+}
+
+// ValidateAuditPolicy checks that data is a well formed audit policy with at least one rule
+func ValidateAuditPolicy(data []byte) error {
+	policy := &auditv1.Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return fmt.Errorf("error parsing audit policy: %s", err)
+	}
+	if policy.Kind != auditPolicyKind {
+		return fmt.Errorf("invalid audit policy kind %q, expected %s", policy.Kind, auditPolicyKind)
+	}
+	if policy.APIVersion != auditv1.SchemeGroupVersion.String() {
+		return fmt.Errorf("unsupported audit policy apiVersion %q", policy.APIVersion)
+	}
+	if len(policy.Rules) == 0 {
+		return fmt.Errorf("audit policy must contain at least one rule")
+	}
+	for i, rule := range policy.Rules {
+		switch rule.Level {
+		case auditv1.LevelNone, auditv1.LevelMetadata, auditv1.LevelRequest, auditv1.LevelRequestResponse:
+		default:
+			return fmt.Errorf("audit policy rule %d: invalid level %q", i, rule.Level)
+		}
+	}
+	return nil
+}
+
+// configureAudit mounts the audit policy in the API server container and sends
+// audit events to the container log
+func configureAudit(deployment *appsv1.Deployment, audit *tenancyv1alpha1.AuditSpec) {
+	if !auditEnabled(audit) {
+		return
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+
+	apiServer.Command = append(apiServer.Command,
+		fmt.Sprintf("--audit-policy-file=%s/%s", AuditPolicyMountPath, AuditPolicyKey),
+		"--audit-log-path=-")
+	apiServer.VolumeMounts = append(apiServer.VolumeMounts, v1.VolumeMount{
+		MountPath: AuditPolicyMountPath,
+		Name:      auditPolicyVolumeName,
+		ReadOnly:  true,
+	})
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: auditPolicyVolumeName,
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: AuditPolicyConfigMapName},
+			},
+		},
+	})
+}
+
+// auditEnabled returns true unless audit is unset or the None level is selected without a custom policy
+func auditEnabled(audit *tenancyv1alpha1.AuditSpec) bool {
+	return audit != nil && (audit.PolicyConfigMapRef != nil || audit.Level != tenancyv1alpha1.AuditLevelNone)
+}
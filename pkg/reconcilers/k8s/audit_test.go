@@ -0,0 +1,269 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const testAuditPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: RequestResponse
+`
+
+func TestGenerateAuditPolicy(t *testing.T) {
+	tests := []struct {
+		name  string
+		level tenancyv1alpha1.AuditLevel
+		// expected level for a write to a clusterrole and a read of a secret
+		rbacLevel   auditv1.Level
+		secretLevel auditv1.Level
+		wantErr     bool
+	}{
+		{name: "default", level: "", rbacLevel: auditv1.LevelMetadata, secretLevel: auditv1.LevelMetadata},
+		{name: "metadata", level: tenancyv1alpha1.AuditLevelMetadata, rbacLevel: auditv1.LevelMetadata, secretLevel: auditv1.LevelMetadata},
+		{name: "request response", level: tenancyv1alpha1.AuditLevelRequestResponse, rbacLevel: auditv1.LevelRequestResponse, secretLevel: auditv1.LevelMetadata},
+		{name: "none", level: tenancyv1alpha1.AuditLevelNone, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GenerateAuditPolicy(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateAuditPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := ValidateAuditPolicy(data); err != nil {
+				t.Fatalf("generated policy is not valid: %v", err)
+			}
+			policy := &auditv1.Policy{}
+			if err := yaml.Unmarshal(data, policy); err != nil {
+				t.Fatalf("error parsing generated policy: %v", err)
+			}
+			if len(policy.OmitStages) != 1 || policy.OmitStages[0] != auditv1.StageRequestReceived {
+				t.Errorf("expected RequestReceived stage to be omitted, got %v", policy.OmitStages)
+			}
+			if got := matchAuditLevel(policy, "update", "rbac.authorization.k8s.io", "clusterroles"); got != tt.rbacLevel {
+				t.Errorf("expected level %s for clusterrole updates, got %s", tt.rbacLevel, got)
+			}
+			if got := matchAuditLevel(policy, "get", "", "secrets"); got != tt.secretLevel {
+				t.Errorf("expected level %s for secret reads, got %s", tt.secretLevel, got)
+			}
+		})
+	}
+}
+
+func TestReconcileAuditPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		audit      *tenancyv1alpha1.AuditSpec
+		wantPolicy bool
+		custom     bool
+	}{
+		{name: "unset"},
+		{name: "none", audit: &tenancyv1alpha1.AuditSpec{Level: tenancyv1alpha1.AuditLevelNone}},
+		{name: "generated", audit: &tenancyv1alpha1.AuditSpec{Level: tenancyv1alpha1.AuditLevelRequestResponse}, wantPolicy: true},
+		{
+			name: "custom",
+			audit: &tenancyv1alpha1.AuditSpec{
+				Level: tenancyv1alpha1.AuditLevelNone,
+				PolicyConfigMapRef: &tenancyv1alpha1.ConfigMapKeyReference{
+					Namespace: "default",
+					Name:      "audit",
+					Key:       "policy.yaml",
+				},
+			},
+			wantPolicy: true,
+			custom:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcp := &tenancyv1alpha1.ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+				Spec: tenancyv1alpha1.ControlPlaneSpec{
+					Type:  tenancyv1alpha1.ControlPlaneTypeK8S,
+					Audit: tt.audit,
+				},
+			}
+			r, cl := newTestReconciler(t, hcp, &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "audit", Namespace: "default"},
+				Data:       map[string]string{"policy.yaml": testAuditPolicy},
+			})
+
+			ctx := context.Background()
+			if err := r.ReconcileAuditPolicy(ctx, hcp); err != nil {
+				t.Fatalf("ReconcileAuditPolicy returned error: %v", err)
+			}
+			if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+				t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+			}
+
+			namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+			cm := &v1.ConfigMap{}
+			err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: AuditPolicyConfigMapName}, cm)
+			if tt.wantPolicy && err != nil {
+				t.Fatalf("expected audit policy config map: %v", err)
+			}
+			if !tt.wantPolicy && err == nil {
+				t.Fatalf("expected no audit policy config map")
+			}
+			if tt.custom && cm.Data[AuditPolicyKey] != testAuditPolicy {
+				t.Errorf("expected custom audit policy to be copied, got %s", cm.Data[AuditPolicyKey])
+			}
+
+			deployment := &appsv1.Deployment{}
+			if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.APIServerDeploymentName}, deployment); err != nil {
+				t.Fatalf("error getting apiserver deployment: %v", err)
+			}
+			apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+			if apiServer == nil {
+				t.Fatalf("apiserver container not found")
+			}
+			flag := fmt.Sprintf("--audit-policy-file=%s/%s", AuditPolicyMountPath, AuditPolicyKey)
+			if hasString(apiServer.Command, flag) != tt.wantPolicy {
+				t.Errorf("expected %s in apiserver command: %t", flag, tt.wantPolicy)
+			}
+			if hasMount(apiServer.VolumeMounts, auditPolicyVolumeName, AuditPolicyMountPath) != tt.wantPolicy {
+				t.Errorf("expected audit policy mounted at %s: %t", AuditPolicyMountPath, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestReconcileAPIServerDeploymentAuditUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	flag := fmt.Sprintf("--audit-policy-file=%s/%s", AuditPolicyMountPath, AuditPolicyKey)
+	hcp.Spec.Audit = &tenancyv1alpha1.AuditSpec{Level: tenancyv1alpha1.AuditLevelMetadata}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if !hasString(apiServer.Command, flag) || !hasMount(apiServer.VolumeMounts, auditPolicyVolumeName, AuditPolicyMountPath) || !hasVolume(podSpec, auditPolicyVolumeName) {
+		t.Errorf("expected audit policy flag, mount and volume after enabling audit")
+	}
+
+	hcp.Spec.Audit = nil
+	deployment = reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	podSpec = &deployment.Spec.Template.Spec
+	apiServer = findContainer(podSpec, util.APIServerDeploymentName)
+	if hasString(apiServer.Command, flag) || hasMount(apiServer.VolumeMounts, auditPolicyVolumeName, AuditPolicyMountPath) || hasVolume(podSpec, auditPolicyVolumeName) {
+		t.Errorf("expected audit policy flag, mount and volume to be removed after disabling audit")
+	}
+}
+
+func TestReconcileAPIServerDeploymentAuditPolicyChange(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			Audit: &tenancyv1alpha1.AuditSpec{
+				PolicyConfigMapRef: &tenancyv1alpha1.ConfigMapKeyReference{
+					Namespace: "default",
+					Name:      "audit",
+					Key:       "policy.yaml",
+				},
+			},
+		},
+	}
+	source := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "audit", Namespace: "default"},
+		Data:       map[string]string{"policy.yaml": testAuditPolicy},
+	}
+	r, cl := newTestReconciler(t, hcp, source)
+
+	ctx := context.Background()
+	if err := r.ReconcileAuditPolicy(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAuditPolicy returned error: %v", err)
+	}
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	before := getAPIServerDeployment(t, cl, hcp).Spec.Template.Annotations[configChecksumAnnotation]
+	if before == "" {
+		t.Fatalf("expected %s annotation on the apiserver pod template", configChecksumAnnotation)
+	}
+
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(source), source); err != nil {
+		t.Fatalf("error getting audit policy source: %v", err)
+	}
+	source.Data["policy.yaml"] = "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n"
+	if err := cl.Update(ctx, source); err != nil {
+		t.Fatalf("error updating audit policy source: %v", err)
+	}
+	if err := r.ReconcileAuditPolicy(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAuditPolicy returned error: %v", err)
+	}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	if after := deployment.Spec.Template.Annotations[configChecksumAnnotation]; after == before {
+		t.Errorf("expected the config checksum to change with the audit policy, got %s", after)
+	}
+}
+
+func TestValidateAuditPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+	}{
+		{name: "valid", policy: testAuditPolicy},
+		{name: "wrong kind", policy: "apiVersion: audit.k8s.io/v1\nkind: ConfigMap\nrules:\n- level: Metadata\n", wantErr: true},
+		{name: "wrong version", policy: "apiVersion: audit.k8s.io/v1beta1\nkind: Policy\nrules:\n- level: Metadata\n", wantErr: true},
+		{name: "no rules", policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules: []\n", wantErr: true},
+		{name: "invalid level", policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Everything\n", wantErr: true},
+		{name: "not yaml", policy: "{", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAuditPolicy([]byte(tt.policy))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAuditPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// matchAuditLevel returns the level of the first rule matching a resource request,
+// following the apiserver policy evaluation for the fields used by the generated policies
+func matchAuditLevel(policy *auditv1.Policy, verb, group, resource string) auditv1.Level {
+	for _, rule := range policy.Rules {
+		if len(rule.NonResourceURLs) > 0 || len(rule.Users) > 0 {
+			continue
+		}
+		if len(rule.Verbs) > 0 && !hasString(rule.Verbs, verb) {
+			continue
+		}
+		if len(rule.Resources) > 0 {
+			matched := false
+			for _, gr := range rule.Resources {
+				if gr.Group == group && (len(gr.Resources) == 0 || hasString(gr.Resources, resource)) {
+					matched = true
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		return rule.Level
+	}
+	return auditv1.LevelNone
+}
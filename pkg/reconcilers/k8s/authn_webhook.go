@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	AuthnWebhookSecretName = "authn-webhook-config"
+	AuthnWebhookConfigKey  = "authn-webhook-config.yaml"
+	AuthnWebhookMountPath  = "/etc/kubernetes/authn-webhook"
+	authnWebhookVolumeName = "authn-webhook-config"
+)
+
+// ReconcileAuthenticationWebhookConfig validates the referenced token webhook configuration and
+// copies it into the control plane namespace, where it is mounted by the API server
+func (r *K8sReconciler) ReconcileAuthenticationWebhookConfig(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.AuthenticationWebhook == nil {
+		return nil
+	}
+
+	data, err := r.GetSecretKeyData(ctx, hcp.Spec.AuthenticationWebhook.ConfigSecretRef)
+	if err != nil {
+		return err
+	}
+	if err := ValidateAuthenticationWebhookConfig(data); err != nil {
+		return err
+	}
+	return r.ReconcileControlPlaneSecret(ctx, hcp, AuthnWebhookSecretName, v1.SecretTypeOpaque, map[string][]byte{AuthnWebhookConfigKey: data})
+}
+
+// ValidateAuthenticationWebhookConfig checks that data is a kubeconfig whose current
+// context points to an https webhook server
+func ValidateAuthenticationWebhookConfig(data []byte) error {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return fmt.Errorf("error parsing authentication webhook config: %s", err)
+	}
+	if len(config.Clusters) == 0 {
+		return fmt.Errorf("authentication webhook config must define the webhook server as a cluster")
+	}
+	contextName := config.CurrentContext
+	if contextName == "" && len(config.Contexts) == 1 {
+		for name := range config.Contexts {
+			contextName = name
+		}
+	}
+	kctx, ok := config.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("authentication webhook config must set a current context")
+	}
+	cluster, ok := config.Clusters[kctx.Cluster]
+	if !ok {
+		return fmt.Errorf("cluster %s not found in authentication webhook config", kctx.Cluster)
+	}
+	u, err := url.Parse(cluster.Server)
+	if err != nil {
+		return fmt.Errorf("invalid authentication webhook server %q: %s", cluster.Server, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("authentication webhook server %q must be an https URL", cluster.Server)
+	}
+	return nil
+}
+
+// configureAuthenticationWebhook mounts the token webhook config in the API server
+// container and sets the webhook flags
+func configureAuthenticationWebhook(deployment *appsv1.Deployment, webhook *tenancyv1alpha1.AuthenticationWebhookSpec) {
+	if webhook == nil {
+		return
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+
+	apiServer.Command = append(apiServer.Command,
+		fmt.Sprintf("--authentication-token-webhook-config-file=%s/%s", AuthnWebhookMountPath, AuthnWebhookConfigKey))
+	if webhook.CacheTTL != nil {
+		apiServer.Command = append(apiServer.Command,
+			fmt.Sprintf("--authentication-token-webhook-cache-ttl=%s", webhook.CacheTTL.Duration))
+	}
+	if webhook.Version != "" {
+		apiServer.Command = append(apiServer.Command,
+			fmt.Sprintf("--authentication-token-webhook-version=%s", webhook.Version))
+	}
+	apiServer.VolumeMounts = append(apiServer.VolumeMounts, v1.VolumeMount{
+		MountPath: AuthnWebhookMountPath,
+		Name:      authnWebhookVolumeName,
+		ReadOnly:  true,
+	})
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: authnWebhookVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{
+				SecretName: AuthnWebhookSecretName,
+			},
+		},
+	})
+}
@@ -0,0 +1,145 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const testAuthnWebhookConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: authn
+  cluster:
+    server: https://authn.example.com/authenticate
+users:
+- name: apiserver
+  user: {}
+contexts:
+- name: webhook
+  context:
+    cluster: authn
+    user: apiserver
+current-context: webhook
+`
+
+func TestReconcileAPIServerDeploymentAuthenticationWebhook(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			AuthenticationWebhook: &tenancyv1alpha1.AuthenticationWebhookSpec{
+				ConfigSecretRef: tenancyv1alpha1.SecretKeyReference{
+					Namespace: "default",
+					Name:      "authn",
+					Key:       "config",
+				},
+				CacheTTL: &metav1.Duration{Duration: 30 * time.Second},
+				Version:  "v1",
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "authn", Namespace: "default"},
+		Data:       map[string][]byte{"config": []byte(testAuthnWebhookConfig)},
+	})
+
+	ctx := context.Background()
+	if err := r.ReconcileAuthenticationWebhookConfig(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAuthenticationWebhookConfig returned error: %v", err)
+	}
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: AuthnWebhookSecretName}, secret); err != nil {
+		t.Fatalf("expected authentication webhook config to be copied: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.APIServerDeploymentName}, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+	for _, flag := range []string{
+		fmt.Sprintf("--authentication-token-webhook-config-file=%s/%s", AuthnWebhookMountPath, AuthnWebhookConfigKey),
+		"--authentication-token-webhook-cache-ttl=30s",
+		"--authentication-token-webhook-version=v1",
+	} {
+		if !hasString(apiServer.Command, flag) {
+			t.Errorf("expected apiserver command to contain %s", flag)
+		}
+	}
+	if !hasMount(apiServer.VolumeMounts, authnWebhookVolumeName, AuthnWebhookMountPath) {
+		t.Errorf("expected authentication webhook config to be mounted at %s", AuthnWebhookMountPath)
+	}
+}
+
+func TestReconcileAPIServerDeploymentAuthenticationWebhookUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			AuthenticationWebhook: &tenancyv1alpha1.AuthenticationWebhookSpec{
+				ConfigSecretRef: tenancyv1alpha1.SecretKeyReference{Namespace: "default", Name: "authn", Key: "config"},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.AuthenticationWebhook.CacheTTL = &metav1.Duration{Duration: time.Minute}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	if !hasString(findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command, "--authentication-token-webhook-cache-ttl=1m0s") {
+		t.Errorf("expected apiserver command to contain the new cache TTL")
+	}
+
+	hcp.Spec.AuthenticationWebhook = nil
+	deployment = reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	podSpec := &deployment.Spec.Template.Spec
+	flag := fmt.Sprintf("--authentication-token-webhook-config-file=%s/%s", AuthnWebhookMountPath, AuthnWebhookConfigKey)
+	if hasString(findContainer(podSpec, util.APIServerDeploymentName).Command, flag) || hasVolume(podSpec, authnWebhookVolumeName) {
+		t.Errorf("expected authentication webhook flag and volume to be removed")
+	}
+}
+
+func TestValidateAuthenticationWebhookConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "valid", config: testAuthnWebhookConfig},
+		{name: "not a kubeconfig", config: "{", wantErr: true},
+		{name: "no clusters", config: "apiVersion: v1\nkind: Config\n", wantErr: true},
+		{
+			name:    "http server",
+			config:  "apiVersion: v1\nkind: Config\nclusters:\n- name: a\n  cluster:\n    server: http://authn\ncontexts:\n- name: a\n  context:\n    cluster: a\ncurrent-context: a\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAuthenticationWebhookConfig([]byte(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAuthenticationWebhookConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
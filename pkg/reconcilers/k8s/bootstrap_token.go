@@ -0,0 +1,231 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+const (
+	BootstrapTokenNamespace       = "kube-system"
+	BootstrapTokenSecretPrefix    = "bootstrap-token-"
+	BootstrapTokenIDKey           = "token-id"
+	BootstrapTokenSecretKey       = "token-secret"
+	BootstrapTokenExpirationKey   = "expiration"
+	BootstrapTokenExtraGroupsKey  = "auth-extra-groups"
+	BootstrapTokenUsagePrefix     = "usage-bootstrap-"
+	BootstrapTokenGroupPrefix     = "system:bootstrappers:"
+	DefaultBootstrapTokenTTL      = 24 * time.Hour
+	MinBootstrapTokenTTL          = time.Minute
+	bootstrapTokenIDLength        = 6
+	bootstrapTokenSecretLength    = 16
+	bootstrapTokenChars           = "0123456789abcdefghijklmnopqrstuvwxyz"
+	bootstrapTokenRotationDivisor = 4
+)
+
+// ValidateBootstrapToken checks the bootstrap token TTL and extra groups
+func ValidateBootstrapToken(spec *tenancyv1alpha1.BootstrapTokenSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if spec.TTL != nil && spec.TTL.Duration < MinBootstrapTokenTTL {
+		return fmt.Errorf("bootstrap token ttl %s must be at least %s", spec.TTL.Duration, MinBootstrapTokenTTL)
+	}
+	for _, group := range spec.ExtraGroups {
+		if !strings.HasPrefix(group, BootstrapTokenGroupPrefix) {
+			return fmt.Errorf("bootstrap token extra group %q must start with %s", group, BootstrapTokenGroupPrefix)
+		}
+	}
+	return nil
+}
+
+// ReconcileBootstrapToken creates a bootstrap token inside the control plane and records its
+// expiration in the status. A new token is created when less than a quarter of the TTL is left,
+// or when the token no longer matches the spec; the previous token is left to expire, unless it
+// no longer matches the spec. When the bootstrap token is disabled, the current token is removed.
+func (r *K8sReconciler) ReconcileBootstrapToken(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.BootstrapToken == nil && hcp.Status.BootstrapToken == nil {
+		return nil
+	}
+
+	cpClient, err := r.GetControlPlaneClientSet(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	return reconcileBootstrapToken(ctx, hcp, cpClient, time.Now())
+}
+
+func reconcileBootstrapToken(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, cpClient kubernetes.Interface, now time.Time) error {
+	spec := hcp.Spec.BootstrapToken
+	var current *v1.Secret
+	if hcp.Status.BootstrapToken != nil {
+		secret, err := cpClient.CoreV1().Secrets(BootstrapTokenNamespace).Get(ctx, BootstrapTokenSecretPrefix+hcp.Status.BootstrapToken.TokenID, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			current = secret
+		}
+	}
+
+	if spec == nil {
+		if current != nil {
+			if err := cpClient.CoreV1().Secrets(BootstrapTokenNamespace).Delete(ctx, current.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		hcp.Status.BootstrapToken = nil
+		return nil
+	}
+
+	ttl := BootstrapTokenTTL(spec)
+	if current != nil {
+		expiration, err := time.Parse(time.RFC3339, string(current.Data[BootstrapTokenExpirationKey]))
+		matches := err == nil && bootstrapTokenMatches(current, spec) && !expiration.After(now.Add(ttl))
+		if matches && expiration.Sub(now) > ttl/bootstrapTokenRotationDivisor {
+			hcp.Status.BootstrapToken.Expiration = metav1.NewTime(expiration)
+			return nil
+		}
+		if !matches {
+			if err := cpClient.CoreV1().Secrets(BootstrapTokenNamespace).Delete(ctx, current.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	secret, err := generateBootstrapTokenSecret(spec, now.Add(ttl))
+	if err != nil {
+		return err
+	}
+	if _, err := cpClient.CoreV1().Secrets(BootstrapTokenNamespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	hcp.Status.BootstrapToken = &tenancyv1alpha1.BootstrapTokenStatus{
+		TokenID:    string(secret.Data[BootstrapTokenIDKey]),
+		Expiration: metav1.NewTime(now.Add(ttl).Truncate(time.Second)),
+	}
+	return nil
+}
+
+// BootstrapTokenTTL returns the configured bootstrap token TTL, or the default one
+func BootstrapTokenTTL(spec *tenancyv1alpha1.BootstrapTokenSpec) time.Duration {
+	if spec.TTL == nil {
+		return DefaultBootstrapTokenTTL
+	}
+	return spec.TTL.Duration
+}
+
+// BootstrapTokenRotationTime returns when the current bootstrap token of the control plane
+// must be rotated, or the zero time if there is no token
+func BootstrapTokenRotationTime(hcp *tenancyv1alpha1.ControlPlane) time.Time {
+	if hcp.Spec.BootstrapToken == nil || hcp.Status.BootstrapToken == nil {
+		return time.Time{}
+	}
+	ttl := BootstrapTokenTTL(hcp.Spec.BootstrapToken)
+	return hcp.Status.BootstrapToken.Expiration.Add(-ttl / bootstrapTokenRotationDivisor)
+}
+
+func generateBootstrapTokenSecret(spec *tenancyv1alpha1.BootstrapTokenSpec, expiration time.Time) (*v1.Secret, error) {
+	id, err := randomBootstrapTokenString(bootstrapTokenIDLength)
+	if err != nil {
+		return nil, err
+	}
+	tokenSecret, err := randomBootstrapTokenString(bootstrapTokenSecretLength)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string][]byte{
+		BootstrapTokenIDKey:         []byte(id),
+		BootstrapTokenSecretKey:     []byte(tokenSecret),
+		BootstrapTokenExpirationKey: []byte(expiration.UTC().Format(time.RFC3339)),
+	}
+	for _, usage := range bootstrapTokenUsages(spec) {
+		data[BootstrapTokenUsagePrefix+string(usage)] = []byte("true")
+	}
+	if len(spec.ExtraGroups) > 0 {
+		data[BootstrapTokenExtraGroupsKey] = []byte(strings.Join(spec.ExtraGroups, ","))
+	}
+
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      BootstrapTokenSecretPrefix + id,
+			Namespace: BootstrapTokenNamespace,
+		},
+		Type: v1.SecretTypeBootstrapToken,
+		Data: data,
+	}, nil
+}
+
+// bootstrapTokenMatches checks that the usages and extra groups of a bootstrap token
+// secret are the ones in the spec
+func bootstrapTokenMatches(secret *v1.Secret, spec *tenancyv1alpha1.BootstrapTokenSpec) bool {
+	usages := []string{}
+	for key, value := range secret.Data {
+		if strings.HasPrefix(key, BootstrapTokenUsagePrefix) && string(value) == "true" {
+			usages = append(usages, strings.TrimPrefix(key, BootstrapTokenUsagePrefix))
+		}
+	}
+	desired := []string{}
+	for _, usage := range bootstrapTokenUsages(spec) {
+		desired = append(desired, string(usage))
+	}
+	sort.Strings(usages)
+	sort.Strings(desired)
+	if strings.Join(usages, ",") != strings.Join(desired, ",") {
+		return false
+	}
+	return string(secret.Data[BootstrapTokenExtraGroupsKey]) == strings.Join(spec.ExtraGroups, ",")
+}
+
+func bootstrapTokenUsages(spec *tenancyv1alpha1.BootstrapTokenSpec) []tenancyv1alpha1.BootstrapTokenUsage {
+	if len(spec.Usages) == 0 {
+		return []tenancyv1alpha1.BootstrapTokenUsage{
+			tenancyv1alpha1.BootstrapTokenUsageAuthentication,
+			tenancyv1alpha1.BootstrapTokenUsageSigning,
+		}
+	}
+	return spec.Usages
+}
+
+func randomBootstrapTokenString(length int) (string, error) {
+	max := big.NewInt(int64(len(bootstrapTokenChars)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = bootstrapTokenChars[n.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestReconcileBootstrapToken(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			BootstrapToken: &tenancyv1alpha1.BootstrapTokenSpec{
+				TTL:         &metav1.Duration{Duration: time.Hour},
+				Usages:      []tenancyv1alpha1.BootstrapTokenUsage{tenancyv1alpha1.BootstrapTokenUsageAuthentication},
+				ExtraGroups: []string{"system:bootstrappers:agents"},
+			},
+		},
+	}
+	cpClient := fake.NewSimpleClientset()
+
+	if err := reconcileBootstrapToken(ctx, hcp, cpClient, now); err != nil {
+		t.Fatalf("reconcileBootstrapToken returned error: %v", err)
+	}
+	if hcp.Status.BootstrapToken == nil {
+		t.Fatalf("expected bootstrap token status to be set")
+	}
+	if !hcp.Status.BootstrapToken.Expiration.Time.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected expiration %s, got %s", now.Add(time.Hour), hcp.Status.BootstrapToken.Expiration)
+	}
+	tokenID := hcp.Status.BootstrapToken.TokenID
+	secret, err := cpClient.CoreV1().Secrets(BootstrapTokenNamespace).Get(ctx, BootstrapTokenSecretPrefix+tokenID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting bootstrap token secret: %v", err)
+	}
+	if secret.Type != v1.SecretTypeBootstrapToken {
+		t.Errorf("expected secret type %s, got %s", v1.SecretTypeBootstrapToken, secret.Type)
+	}
+	if got := string(secret.Data[BootstrapTokenExpirationKey]); got != "2024-01-01T13:00:00Z" {
+		t.Errorf("expected the ttl to set the expiration to 2024-01-01T13:00:00Z, got %s", got)
+	}
+	if len(secret.Data[BootstrapTokenSecretKey]) != bootstrapTokenSecretLength {
+		t.Errorf("expected a token secret of %d characters", bootstrapTokenSecretLength)
+	}
+	if string(secret.Data["usage-bootstrap-authentication"]) != "true" {
+		t.Errorf("expected the authentication usage to be set")
+	}
+	if _, ok := secret.Data["usage-bootstrap-signing"]; ok {
+		t.Errorf("expected the signing usage not to be set")
+	}
+	if got := string(secret.Data[BootstrapTokenExtraGroupsKey]); got != "system:bootstrappers:agents" {
+		t.Errorf("unexpected extra groups %q", got)
+	}
+
+	// the token is kept while more than a quarter of the ttl is left
+	if err := reconcileBootstrapToken(ctx, hcp, cpClient, now.Add(30*time.Minute)); err != nil {
+		t.Fatalf("reconcileBootstrapToken returned error: %v", err)
+	}
+	if hcp.Status.BootstrapToken.TokenID != tokenID {
+		t.Errorf("expected bootstrap token not to be rotated")
+	}
+
+	// and rotated afterwards, leaving the previous token to expire
+	if err := reconcileBootstrapToken(ctx, hcp, cpClient, now.Add(50*time.Minute)); err != nil {
+		t.Fatalf("reconcileBootstrapToken returned error: %v", err)
+	}
+	if hcp.Status.BootstrapToken.TokenID == tokenID {
+		t.Errorf("expected bootstrap token to be rotated")
+	}
+	secrets, err := cpClient.CoreV1().Secrets(BootstrapTokenNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("error listing secrets: %v", err)
+	}
+	if len(secrets.Items) != 2 {
+		t.Errorf("expected 2 bootstrap token secrets, got %d", len(secrets.Items))
+	}
+
+	// disabling the bootstrap token removes the current token
+	hcp.Spec.BootstrapToken = nil
+	if err := reconcileBootstrapToken(ctx, hcp, cpClient, now.Add(50*time.Minute)); err != nil {
+		t.Fatalf("reconcileBootstrapToken returned error: %v", err)
+	}
+	if hcp.Status.BootstrapToken != nil {
+		t.Errorf("expected bootstrap token status to be cleared")
+	}
+}
+
+func TestValidateBootstrapToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *tenancyv1alpha1.BootstrapTokenSpec
+		wantErr bool
+	}{
+		{name: "nil", spec: nil},
+		{name: "default ttl", spec: &tenancyv1alpha1.BootstrapTokenSpec{}},
+		{name: "valid ttl", spec: &tenancyv1alpha1.BootstrapTokenSpec{TTL: &metav1.Duration{Duration: 15 * time.Minute}}},
+		{name: "ttl too short", spec: &tenancyv1alpha1.BootstrapTokenSpec{TTL: &metav1.Duration{Duration: 30 * time.Second}}, wantErr: true},
+		{name: "negative ttl", spec: &tenancyv1alpha1.BootstrapTokenSpec{TTL: &metav1.Duration{Duration: -time.Hour}}, wantErr: true},
+		{name: "invalid group", spec: &tenancyv1alpha1.BootstrapTokenSpec{ExtraGroups: []string{"system:masters"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBootstrapToken(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBootstrapToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -18,7 +18,13 @@ package k8s
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -36,65 +42,162 @@ import (
 	"github.com/kubestellar/kubeflex/pkg/util"
 )
 
+// templateHashAnnotation records on the API server and controller manager deployments the hash
+// of the pod template generated from the control plane spec. Comparing hashes rather than the
+// pod templates ignores the fields defaulted by the API server, so that a reconcile updates the
+// deployment only when the spec changed
+const templateHashAnnotation = "tenancy.kflex.kubestellar.org/template-hash"
+
+// configChecksumAnnotation records on the pod templates of the API server and controller manager
+// the checksum of the config maps and secrets they mount. The API server only reads files such
+// as the audit policy or the authentication webhook kubeconfig at startup, so a change of their
+// content changes the pod template and restarts the pods
+const configChecksumAnnotation = "tenancy.kflex.kubestellar.org/config-checksum"
+
 func (r *K8sReconciler) ReconcileAPIServerDeployment(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, isOCP bool) error {
 	_ = clog.FromContext(ctx)
 	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      util.APIServerDeploymentName,
-			Namespace: namespace,
-		},
+	dbName := util.ReplaceNotAllowedCharsInDBName(hcp.Name)
+	deployment, err := r.generateAPIServerDeployment(namespace, dbName, isOCP)
+	if err != nil {
+		return err
+	}
+	configureEgressSelector(deployment, hcp.Spec.EgressSelector)
+	configureAuthenticationWebhook(deployment, hcp.Spec.AuthenticationWebhook)
+	configureShutdownDelay(deployment, hcp.Spec.ShutdownDelay)
+	configureAudit(deployment, hcp.Spec.Audit)
+	configureServiceAccountIssuer(deployment, hcp.Spec.ServiceAccountIssuer)
+	configureWatchCache(deployment, hcp.Spec.WatchCache)
+	configureOIDC(deployment, hcp.Spec.OIDC)
+	configureEtcdCompaction(deployment, hcp.Spec.EtcdMaintenance)
+	configureGoawayChance(deployment, hcp.Spec.GoawayChance)
+	configureProfiling(deployment, hcp.Spec.ProfilingEnabled)
+	deployment.Spec.Replicas = pointer.Int32(util.APIServerReplicas(*hcp))
+	deployment.Spec.Template.Spec.ImagePullSecrets = shared.GetImagePullSecrets(hcp)
+	deployment.Spec.Template.Spec.TopologySpreadConstraints = shared.GetTopologySpreadConstraints(hcp, deployment.Spec.Template.Labels)
+	if err := r.setConfigChecksum(ctx, deployment); err != nil {
+		return err
+	}
+
+	// the replica count is kept in sync after creation, so that the API server can be scaled
+	// without re-creating its deployment
+	return r.reconcileDeployment(hcp, deployment, hcp.Spec.Replicas)
+}
+
+func (r *K8sReconciler) ReconcileCMDeployment(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	deployment, err := r.generateCMDeployment(hcp.Name, namespace)
+	if err != nil {
+		return err
+	}
+	configureServiceAccountSigningKey(deployment, hcp.Spec.ServiceAccountIssuer)
+	configureExternalServingCA(deployment, hcp.Spec.ExternalCerts)
+	deployment.Spec.Template.Spec.ImagePullSecrets = shared.GetImagePullSecrets(hcp)
+	deployment.Spec.Template.Spec.TopologySpreadConstraints = shared.GetTopologySpreadConstraints(hcp, deployment.Spec.Template.Labels)
+	if err := r.setConfigChecksum(ctx, deployment); err != nil {
+		return err
 	}
+	return r.reconcileDeployment(hcp, deployment, nil)
+}
 
-	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(deployment), deployment, &client.GetOptions{})
+// reconcileDeployment creates the desired deployment, or replaces the pod template of the
+// existing one when it was generated from a different spec, so that spec changes made after
+// creation are rolled out. A deployment created before the template hash was recorded is only
+// annotated with the hash, so that upgrading kubeflex does not roll out the API servers of all
+// the control planes at once; its template is replaced on the next spec change. The replica
+// count is only synced when replicas is set
+func (r *K8sReconciler) reconcileDeployment(hcp *tenancyv1alpha1.ControlPlane, desired *appsv1.Deployment, replicas *int32) error {
+	hash := podTemplateHash(desired)
+	deployment := &appsv1.Deployment{}
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(desired), deployment, &client.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			dbName := util.ReplaceNotAllowedCharsInDBName(hcp.Name)
-			deployment, err = r.generateAPIServerDeployment(namespace, dbName, isOCP)
-			if err != nil {
-				return err
-			}
-			if err := controllerutil.SetControllerReference(hcp, deployment, r.Scheme); err != nil {
+			metav1.SetMetaDataAnnotation(&desired.ObjectMeta, templateHashAnnotation, hash)
+			if err := controllerutil.SetControllerReference(hcp, desired, r.Scheme); err != nil {
 				return err
 			}
-			if err = r.Client.Create(context.TODO(), deployment, &client.CreateOptions{}); err != nil {
+			if err = r.Client.Create(context.TODO(), desired, &client.CreateOptions{}); err != nil {
 				return err
 			}
 		}
 		return err
 	}
-	return nil
-}
 
-func (r *K8sReconciler) ReconcileCMDeployment(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
-	_ = clog.FromContext(ctx)
-	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      util.CMDeploymentName,
-			Namespace: namespace,
-		},
+	changed := false
+	if current, ok := deployment.Annotations[templateHashAnnotation]; !ok {
+		metav1.SetMetaDataAnnotation(&deployment.ObjectMeta, templateHashAnnotation, hash)
+		changed = true
+	} else if current != hash {
+		deployment.Spec.Template = desired.Spec.Template
+		metav1.SetMetaDataAnnotation(&deployment.ObjectMeta, templateHashAnnotation, hash)
+		changed = true
+	}
+	if replicas != nil && (deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != *replicas) {
+		deployment.Spec.Replicas = pointer.Int32(*replicas)
+		changed = true
+	}
+	if !changed {
+		return nil
 	}
+	return r.Client.Update(context.TODO(), deployment, &client.UpdateOptions{})
+}
 
-	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(deployment), deployment, &client.GetOptions{})
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			deployment, err = r.generateCMDeployment(hcp.Name, namespace)
-			if err != nil {
+// setConfigChecksum sets configChecksumAnnotation on the pod template of deployment to the
+// checksum of the data of the config maps and secrets mounted by its volumes. Missing config
+// maps and secrets count as empty, so that their creation restarts the pods as well
+func (r *K8sReconciler) setConfigChecksum(ctx context.Context, deployment *appsv1.Deployment) error {
+	hash := sha256.New()
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		var data map[string][]byte
+		switch {
+		case volume.ConfigMap != nil:
+			cm := &v1.ConfigMap{}
+			key := client.ObjectKey{Namespace: deployment.Namespace, Name: volume.ConfigMap.Name}
+			if err := r.Client.Get(ctx, key, cm); client.IgnoreNotFound(err) != nil {
 				return err
 			}
-			if err := controllerutil.SetControllerReference(hcp, deployment, r.Scheme); err != nil {
-				return err
+			data = make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+			for k, v := range cm.Data {
+				data[k] = []byte(v)
 			}
-			if err = r.Client.Create(context.TODO(), deployment, &client.CreateOptions{}); err != nil {
+			for k, v := range cm.BinaryData {
+				data[k] = v
+			}
+			fmt.Fprintf(hash, "configmap/%s\n", volume.ConfigMap.Name)
+		case volume.Secret != nil:
+			secret := &v1.Secret{}
+			key := client.ObjectKey{Namespace: deployment.Namespace, Name: volume.Secret.SecretName}
+			if err := r.Client.Get(ctx, key, secret); client.IgnoreNotFound(err) != nil {
 				return err
 			}
+			data = secret.Data
+			fmt.Fprintf(hash, "secret/%s\n", volume.Secret.SecretName)
+		default:
+			continue
+		}
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(hash, "%s=%d:", k, len(data[k]))
+			hash.Write(data[k])
 		}
-		return err
 	}
+	metav1.SetMetaDataAnnotation(&deployment.Spec.Template.ObjectMeta, configChecksumAnnotation, hex.EncodeToString(hash.Sum(nil)))
 	return nil
 }
 
+// podTemplateHash returns the hash of the pod template of deployment, recorded in
+// templateHashAnnotation. Pod templates always marshal
+func podTemplateHash(deployment *appsv1.Deployment) string {
+	data, _ := json.Marshal(deployment.Spec.Template)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func (r *K8sReconciler) generateAPIServerDeployment(namespace, dbName string, isOCP bool) (*appsv1.Deployment, error) {
 	dbPassword, err := util.GetPGDBPassword(r.Client)
 	if err != nil {
@@ -277,6 +380,68 @@ func (r *K8sReconciler) generateAPIServerDeployment(namespace, dbName string, is
 	return deployment, nil
 }
 
+// configureShutdownDelay makes the API server keep serving for the shutdown delay after
+// SIGTERM while failing readiness, and extends the termination grace period to cover it
+func configureShutdownDelay(deployment *appsv1.Deployment, delay *metav1.Duration) {
+	if delay == nil || delay.Duration <= 0 {
+		return
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+	apiServer.Command = append(apiServer.Command, fmt.Sprintf("--shutdown-delay-duration=%s", delay.Duration))
+
+	gracePeriod := int64(v1.DefaultTerminationGracePeriodSeconds)
+	if podSpec.TerminationGracePeriodSeconds != nil {
+		gracePeriod = *podSpec.TerminationGracePeriodSeconds
+	}
+	podSpec.TerminationGracePeriodSeconds = pointer.Int64(gracePeriod + int64(math.Ceil(delay.Duration.Seconds())))
+}
+
+// maxGoawayChance is the largest value the API server accepts for --goaway-chance
+const maxGoawayChance = 0.02
+
+// ValidateGoawayChance checks that chance is a decimal number between 0 and 0.02,
+// the range accepted by --goaway-chance
+func ValidateGoawayChance(chance string) error {
+	if chance == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(chance, 64)
+	if err != nil {
+		return fmt.Errorf("invalid goaway chance %q: %s", chance, err)
+	}
+	if value < 0 || value > maxGoawayChance {
+		return fmt.Errorf("goaway chance %s must be between 0 and %g", chance, maxGoawayChance)
+	}
+	return nil
+}
+
+// configureGoawayChance makes the API server occasionally send a GOAWAY to HTTP/2
+// clients so that long-lived connections spread across replicas
+func configureGoawayChance(deployment *appsv1.Deployment, chance string) {
+	if chance == "" {
+		return
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+	apiServer.Command = append(apiServer.Command, fmt.Sprintf("--goaway-chance=%s", chance))
+}
+
+// configureProfiling sets --profiling explicitly, since the API server enables it
+// when the flag is not given
+func configureProfiling(deployment *appsv1.Deployment, enabled bool) {
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+	apiServer.Command = append(apiServer.Command, fmt.Sprintf("--profiling=%t", enabled))
+}
+
 func (r *K8sReconciler) generateCMDeployment(cpName, namespace string) (*appsv1.Deployment, error) {
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -308,7 +473,7 @@ func (r *K8sReconciler) generateCMDeployment(cpName, namespace string) (*appsv1.
 							ImagePullPolicy: v1.PullIfNotPresent,
 							Command: []string{
 								"kube-controller-manager",
-								fmt.Sprintf("--master=https://%s", cpName+"."+cpName+"-system"),
+								fmt.Sprintf("--master=https://%s", cpName+"."+util.GenerateNamespaceFromControlPlaneName(cpName)),
 								"--authentication-kubeconfig=/etc/kubernetes/kubeconfig",
 								"--authorization-kubeconfig=/etc/kubernetes/kubeconfig",
 								"--bind-address=0.0.0.0",
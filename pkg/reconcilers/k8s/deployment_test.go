@@ -0,0 +1,513 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileAPIServerDeploymentImagePullSecrets(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			ImagePullSecrets: []tenancyv1alpha1.ImagePullSecretReference{
+				{Namespace: "default", Name: "registry-creds"},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-creds", Namespace: "default"},
+		Type:       v1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{v1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+	})
+
+	ctx := context.Background()
+	if err := r.ReconcileImagePullSecrets(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileImagePullSecrets returned error: %v", err)
+	}
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "registry-creds"}, secret); err != nil {
+		t.Fatalf("expected image pull secret to be copied: %v", err)
+	}
+	if secret.Type != v1.SecretTypeDockerConfigJson {
+		t.Errorf("expected copied secret type %s, got %s", v1.SecretTypeDockerConfigJson, secret.Type)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.APIServerDeploymentName}, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	pullSecrets := deployment.Spec.Template.Spec.ImagePullSecrets
+	if len(pullSecrets) != 1 || pullSecrets[0].Name != "registry-creds" {
+		t.Errorf("expected pod image pull secrets [registry-creds], got %v", pullSecrets)
+	}
+}
+
+func TestReconcileAPIServerDeploymentImagePullSecretsUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.ImagePullSecrets = []tenancyv1alpha1.ImagePullSecretReference{{Namespace: "default", Name: "registry-creds"}}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	pullSecrets := deployment.Spec.Template.Spec.ImagePullSecrets
+	if len(pullSecrets) != 1 || pullSecrets[0].Name != "registry-creds" {
+		t.Errorf("expected pod image pull secrets [registry-creds] after the spec change, got %v", pullSecrets)
+	}
+}
+
+func TestReconcileAPIServerDeploymentShutdownDelay(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:          tenancyv1alpha1.ControlPlaneTypeK8S,
+			ShutdownDelay: &metav1.Duration{Duration: 15 * time.Second},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.APIServerDeploymentName}
+	if err := cl.Get(ctx, key, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+	if !hasString(apiServer.Command, "--shutdown-delay-duration=15s") {
+		t.Errorf("expected apiserver command to contain --shutdown-delay-duration=15s")
+	}
+	expectedGracePeriod := int64(v1.DefaultTerminationGracePeriodSeconds + 15)
+	if podSpec.TerminationGracePeriodSeconds == nil || *podSpec.TerminationGracePeriodSeconds != expectedGracePeriod {
+		t.Errorf("expected termination grace period %d, got %v", expectedGracePeriod, podSpec.TerminationGracePeriodSeconds)
+	}
+}
+
+func TestReconcileAPIServerDeploymentShutdownDelayUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.ShutdownDelay = &metav1.Duration{Duration: 20 * time.Second}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	podSpec := &deployment.Spec.Template.Spec
+	if !hasString(findContainer(podSpec, util.APIServerDeploymentName).Command, "--shutdown-delay-duration=20s") {
+		t.Errorf("expected apiserver command to contain --shutdown-delay-duration=20s after the spec change")
+	}
+	expectedGracePeriod := int64(v1.DefaultTerminationGracePeriodSeconds + 20)
+	if podSpec.TerminationGracePeriodSeconds == nil || *podSpec.TerminationGracePeriodSeconds != expectedGracePeriod {
+		t.Errorf("expected termination grace period %d, got %v", expectedGracePeriod, podSpec.TerminationGracePeriodSeconds)
+	}
+}
+
+func TestReconcileAPIServerDeploymentGoawayChance(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:         tenancyv1alpha1.ControlPlaneTypeK8S,
+			GoawayChance: "0.001",
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.APIServerDeploymentName}
+	if err := cl.Get(ctx, key, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+	if !hasString(apiServer.Command, "--goaway-chance=0.001") {
+		t.Errorf("expected apiserver command to contain --goaway-chance=0.001")
+	}
+}
+
+func TestReconcileAPIServerDeploymentGoawayChanceUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.GoawayChance = "0.01"
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	if !hasString(findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command, "--goaway-chance=0.01") {
+		t.Errorf("expected apiserver command to contain --goaway-chance=0.01 after the spec change")
+	}
+}
+
+func TestReconcileAPIServerDeploymentProfiling(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		expected string
+	}{
+		{"disabled by default", false, "--profiling=false"},
+		{"enabled for debugging", true, "--profiling=true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcp := &tenancyv1alpha1.ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+				Spec: tenancyv1alpha1.ControlPlaneSpec{
+					Type:             tenancyv1alpha1.ControlPlaneTypeK8S,
+					ProfilingEnabled: tt.enabled,
+				},
+			}
+			r, cl := newTestReconciler(t, hcp)
+
+			ctx := context.Background()
+			if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+				t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+			}
+
+			deployment := &appsv1.Deployment{}
+			key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.APIServerDeploymentName}
+			if err := cl.Get(ctx, key, deployment); err != nil {
+				t.Fatalf("error getting apiserver deployment: %v", err)
+			}
+			apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+			if apiServer == nil {
+				t.Fatalf("apiserver container not found")
+			}
+			if !hasString(apiServer.Command, tt.expected) {
+				t.Errorf("expected apiserver command to contain %s", tt.expected)
+			}
+		})
+	}
+}
+
+func TestReconcileAPIServerDeploymentProfilingUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.ProfilingEnabled = true
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	command := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command
+	if !hasString(command, "--profiling=true") || hasString(command, "--profiling=false") {
+		t.Errorf("expected apiserver command to switch to --profiling=true, got %v", command)
+	}
+}
+
+func TestReconcileAPIServerDeploymentUnchanged(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	before := getAPIServerDeployment(t, cl, hcp)
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	if after := getAPIServerDeployment(t, cl, hcp); after.ResourceVersion != before.ResourceVersion {
+		t.Errorf("expected deployment not to be updated without a spec change")
+	}
+}
+
+func TestReconcileAPIServerDeploymentWithoutTemplateHash(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	// a deployment created by a kubeflex version that did not record the template hash
+	deployment := getAPIServerDeployment(t, cl, hcp)
+	delete(deployment.Annotations, templateHashAnnotation)
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	apiServer.Command = removeString(apiServer.Command, "--profiling=false")
+	if err := cl.Update(ctx, deployment); err != nil {
+		t.Fatalf("error updating deployment: %v", err)
+	}
+
+	// the hash is recorded without rolling out the pod template
+	deployment = reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	if deployment.Annotations[templateHashAnnotation] == "" {
+		t.Errorf("expected the template hash to be recorded")
+	}
+	if hasString(findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command, "--profiling=false") {
+		t.Errorf("expected the pod template to be kept until the next spec change")
+	}
+
+	// the next spec change rolls out the pod template
+	hcp.Spec.ShutdownDelay = &metav1.Duration{Duration: 10 * time.Second}
+	deployment = reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	if !hasString(findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command, "--profiling=false") {
+		t.Errorf("expected the pod template to be replaced after a spec change")
+	}
+}
+
+func TestValidateGoawayChance(t *testing.T) {
+	tests := []struct {
+		chance  string
+		wantErr bool
+	}{
+		{"", false},
+		{"0", false},
+		{"0.001", false},
+		{"0.02", false},
+		{"0.03", true},
+		{"1", true},
+		{"-0.01", true},
+		{"abc", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateGoawayChance(tt.chance); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateGoawayChance(%q) error = %v, wantErr %v", tt.chance, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReconcileImagePullSecretsValidation(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			ImagePullSecrets: []tenancyv1alpha1.ImagePullSecretReference{
+				{Namespace: "default", Name: "missing"},
+			},
+		},
+	}
+	r, _ := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "default"},
+		Type:       v1.SecretTypeOpaque,
+	})
+
+	if err := r.ReconcileImagePullSecrets(context.Background(), hcp); err == nil {
+		t.Errorf("expected error for missing image pull secret")
+	}
+
+	hcp.Spec.ImagePullSecrets[0].Name = "opaque"
+	if err := r.ReconcileImagePullSecrets(context.Background(), hcp); err == nil {
+		t.Errorf("expected error for image pull secret of wrong type")
+	}
+}
+
+func TestReconcileDeploymentsTopologySpreadConstraints(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: v1.DoNotSchedule},
+				{
+					MaxSkew:           2,
+					TopologyKey:       "kubernetes.io/hostname",
+					WhenUnsatisfiable: v1.ScheduleAnyway,
+					LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "control-plane"}},
+				},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	if err := r.ReconcileCMDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileCMDeployment returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	for _, name := range []string{util.APIServerDeploymentName, util.CMDeploymentName} {
+		deployment := &appsv1.Deployment{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, deployment); err != nil {
+			t.Fatalf("error getting deployment %s: %v", name, err)
+		}
+		constraints := deployment.Spec.Template.Spec.TopologySpreadConstraints
+		if len(constraints) != 2 {
+			t.Fatalf("expected 2 topology spread constraints in %s pod template, got %d", name, len(constraints))
+		}
+		zone := constraints[0]
+		if zone.TopologyKey != "topology.kubernetes.io/zone" || zone.MaxSkew != 1 || zone.WhenUnsatisfiable != v1.DoNotSchedule {
+			t.Errorf("unexpected zone constraint in %s: %+v", name, zone)
+		}
+		if zone.LabelSelector == nil || zone.LabelSelector.MatchLabels["app"] != deployment.Spec.Template.Labels["app"] {
+			t.Errorf("expected zone constraint in %s to default to the pod labels, got %v", name, zone.LabelSelector)
+		}
+		if sel := constraints[1].LabelSelector; sel == nil || len(sel.MatchLabels) != 1 || sel.MatchLabels["tier"] != "control-plane" {
+			t.Errorf("expected explicit label selector to be kept in %s, got %v", name, sel)
+		}
+	}
+	if hcp.Spec.TopologySpreadConstraints[0].LabelSelector != nil {
+		t.Errorf("expected control plane spec not to be modified")
+	}
+}
+
+func TestReconcileDeploymentsTopologySpreadConstraintsUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	if err := r.ReconcileCMDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileCMDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.TopologySpreadConstraints = []v1.TopologySpreadConstraint{
+		{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: v1.DoNotSchedule},
+	}
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	if err := r.ReconcileCMDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileCMDeployment returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	for _, name := range []string{util.APIServerDeploymentName, util.CMDeploymentName} {
+		deployment := &appsv1.Deployment{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, deployment); err != nil {
+			t.Fatalf("error getting deployment %s: %v", name, err)
+		}
+		constraints := deployment.Spec.Template.Spec.TopologySpreadConstraints
+		if len(constraints) != 1 || constraints[0].TopologyKey != "topology.kubernetes.io/zone" {
+			t.Errorf("expected zone constraint in %s pod template after the spec change, got %+v", name, constraints)
+		}
+	}
+}
+
+// newTestReconciler returns a reconciler backed by a fake client holding objs
+// and the postgres secret required to generate the apiserver deployment
+func newTestReconciler(t *testing.T, objs ...client.Object) (*K8sReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding client-go scheme: %v", err)
+	}
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+	objs = append(objs, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres-postgresql", Namespace: util.SystemNamespace},
+		Data:       map[string][]byte{"postgres-password": []byte("password")},
+	})
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &K8sReconciler{BaseReconciler: &shared.BaseReconciler{Client: cl, Scheme: scheme}}, cl
+}
+
+func hasString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMount(mounts []v1.VolumeMount, name, path string) bool {
+	for _, m := range mounts {
+		if m.Name == name && m.MountPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolume(podSpec *v1.PodSpec, name string) bool {
+	for _, v := range podSpec.Volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	result := []string{}
+	for _, item := range list {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func getAPIServerDeployment(t *testing.T, cl client.Client, hcp *tenancyv1alpha1.ControlPlane) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.APIServerDeploymentName}
+	if err := cl.Get(context.Background(), key, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	return deployment
+}
+
+// reconcileAPIServerDeploymentUpdate reconciles the apiserver deployment after a change of the
+// spec of hcp, checks that the existing deployment was updated and returns it
+func reconcileAPIServerDeploymentUpdate(t *testing.T, r *K8sReconciler, cl client.Client, hcp *tenancyv1alpha1.ControlPlane) *appsv1.Deployment {
+	before := getAPIServerDeployment(t, cl, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	after := getAPIServerDeployment(t, cl, hcp)
+	if after.ResourceVersion == before.ResourceVersion {
+		t.Fatalf("expected apiserver deployment to be updated after the spec change")
+	}
+	return after
+}
@@ -0,0 +1,405 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	apiserverv1beta1 "k8s.io/apiserver/pkg/apis/apiserver/v1beta1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	EgressSelectorConfigMapName  = "egress-selector-config"
+	EgressSelectorConfigKey      = "egress-selector-config.yaml"
+	EgressSelectorMountPath      = "/etc/kubernetes/egress"
+	KonnectivityDeploymentName   = "konnectivity-agent"
+	KonnectivitySocketDir        = "/etc/kubernetes/konnectivity-server"
+	KonnectivitySocketPath       = KonnectivitySocketDir + "/konnectivity-server.socket"
+	KonnectivityAgentPort        = 8132
+	KonnectivityAdminPort        = 8133
+	KonnectivityHealthPort       = 8134
+	KonnectivityServerImage      = "registry.k8s.io/kas-network-proxy/proxy-server:v0.1.2"
+	KonnectivityAgentImage       = "registry.k8s.io/kas-network-proxy/proxy-agent:v0.1.2"
+	egressSelectorVolumeName     = "egress-selector-config"
+	konnectivitySocketVolumeName = "konnectivity-uds"
+	egressSelectorConfigKind     = "EgressSelectorConfiguration"
+	egressSelectorConfigV1beta1  = "apiserver.k8s.io/v1beta1"
+	egressSelectorConfigV1alpha1 = "apiserver.k8s.io/v1alpha1"
+)
+
+// the konnectivity agents authenticate to the konnectivity server with a token of the
+// KonnectivityAgentServiceAccount service account of the control plane namespace, bound to
+// KonnectivityAuthAudience
+const (
+	KonnectivityAgentServiceAccount = "konnectivity-agent"
+	KonnectivityAuthAudience        = "system:konnectivity-server"
+	konnectivityTokenDir            = "/var/run/secrets/tokens"
+	konnectivityTokenFile           = "konnectivity-agent-token"
+	konnectivityTokenVolumeName     = "konnectivity-agent-token"
+	konnectivityTokenExpiration     = 3600
+	authDelegatorClusterRole        = "system:auth-delegator"
+)
+
+var validEgressSelectionNames = sets.New("controlplane", "master", "etcd", "cluster")
+
+// ReconcileEgressSelectorConfig validates the referenced EgressSelectorConfiguration and
+// copies it into the control plane namespace, where it is mounted by the API server
+func (r *K8sReconciler) ReconcileEgressSelectorConfig(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.EgressSelector == nil {
+		return nil
+	}
+
+	data, err := r.GetConfigMapKeyData(ctx, hcp.Spec.EgressSelector.ConfigMapRef)
+	if err != nil {
+		return err
+	}
+	if err := ValidateEgressSelectorConfig([]byte(data), hcp.Spec.EgressSelector.Konnectivity); err != nil {
+		return err
+	}
+	return r.ReconcileControlPlaneConfigMap(ctx, hcp, EgressSelectorConfigMapName, map[string]string{EgressSelectorConfigKey: data})
+}
+
+// ValidateEgressSelectorConfig checks that data is a well formed EgressSelectorConfiguration.
+// When konnectivity is set, at least one egress selection must dial the konnectivity server sidecar socket
+func ValidateEgressSelectorConfig(data []byte, konnectivity bool) error {
+	config := &apiserverv1beta1.EgressSelectorConfiguration{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return fmt.Errorf("error parsing egress selector config: %s", err)
+	}
+	if config.Kind != egressSelectorConfigKind {
+		return fmt.Errorf("invalid egress selector config kind %q, expected %s", config.Kind, egressSelectorConfigKind)
+	}
+	if config.APIVersion != egressSelectorConfigV1beta1 && config.APIVersion != egressSelectorConfigV1alpha1 {
+		return fmt.Errorf("unsupported egress selector config apiVersion %q", config.APIVersion)
+	}
+	if len(config.EgressSelections) == 0 {
+		return fmt.Errorf("egress selector config must contain at least one egress selection")
+	}
+
+	seen := sets.New[string]()
+	usesKonnectivity := false
+	for _, selection := range config.EgressSelections {
+		if !validEgressSelectionNames.Has(selection.Name) {
+			return fmt.Errorf("invalid egress selection name %q", selection.Name)
+		}
+		if seen.Has(selection.Name) {
+			return fmt.Errorf("duplicate egress selection %q", selection.Name)
+		}
+		seen.Insert(selection.Name)
+
+		conn := selection.Connection
+		switch conn.ProxyProtocol {
+		case apiserverv1beta1.ProtocolDirect:
+			if conn.Transport != nil {
+				return fmt.Errorf("egress selection %q: transport must not be set for Direct proxy protocol", selection.Name)
+			}
+		case apiserverv1beta1.ProtocolHTTPConnect, apiserverv1beta1.ProtocolGRPC:
+			if conn.Transport == nil || (conn.Transport.TCP == nil && conn.Transport.UDS == nil) {
+				return fmt.Errorf("egress selection %q: transport is required for %s proxy protocol", selection.Name, conn.ProxyProtocol)
+			}
+			if conn.Transport.TCP != nil && conn.Transport.UDS != nil {
+				return fmt.Errorf("egress selection %q: only one of tcp or uds transport can be set", selection.Name)
+			}
+			if conn.Transport.TCP != nil && conn.Transport.TCP.URL == "" {
+				return fmt.Errorf("egress selection %q: tcp transport requires a url", selection.Name)
+			}
+			if conn.Transport.UDS != nil {
+				if conn.Transport.UDS.UDSName == "" {
+					return fmt.Errorf("egress selection %q: uds transport requires a udsName", selection.Name)
+				}
+				if conn.Transport.UDS.UDSName == KonnectivitySocketPath {
+					usesKonnectivity = true
+				}
+			}
+		default:
+			return fmt.Errorf("egress selection %q: invalid proxy protocol %q", selection.Name, conn.ProxyProtocol)
+		}
+	}
+
+	if konnectivity && !usesKonnectivity {
+		return fmt.Errorf("konnectivity is enabled but no egress selection uses the uds transport %s", KonnectivitySocketPath)
+	}
+	return nil
+}
+
+// configureEgressSelector mounts the egress selector config in the API server container
+// and, when konnectivity is enabled, adds the konnectivity server sidecar
+func configureEgressSelector(deployment *appsv1.Deployment, egress *tenancyv1alpha1.EgressSelectorSpec) {
+	if egress == nil {
+		return
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+
+	apiServer.Command = append(apiServer.Command,
+		fmt.Sprintf("--egress-selector-config-file=%s/%s", EgressSelectorMountPath, EgressSelectorConfigKey))
+	apiServer.VolumeMounts = append(apiServer.VolumeMounts, v1.VolumeMount{
+		MountPath: EgressSelectorMountPath,
+		Name:      egressSelectorVolumeName,
+		ReadOnly:  true,
+	})
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: egressSelectorVolumeName,
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: EgressSelectorConfigMapName,
+				},
+			},
+		},
+	})
+
+	if !egress.Konnectivity {
+		return
+	}
+	socketMount := v1.VolumeMount{
+		MountPath: KonnectivitySocketDir,
+		Name:      konnectivitySocketVolumeName,
+	}
+	apiServer.VolumeMounts = append(apiServer.VolumeMounts, socketMount)
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: konnectivitySocketVolumeName,
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+	podSpec.Containers = append(podSpec.Containers, v1.Container{
+		Name:            "konnectivity-server",
+		Image:           KonnectivityServerImage,
+		ImagePullPolicy: v1.PullIfNotPresent,
+		Command: []string{
+			"/proxy-server",
+			"--mode=grpc",
+			"--uds-name=" + KonnectivitySocketPath,
+			"--delete-existing-uds-file",
+			"--server-port=0",
+			fmt.Sprintf("--agent-port=%d", KonnectivityAgentPort),
+			fmt.Sprintf("--admin-port=%d", KonnectivityAdminPort),
+			fmt.Sprintf("--health-port=%d", KonnectivityHealthPort),
+			"--cluster-cert=/etc/kubernetes/pki/apiserver.crt",
+			"--cluster-key=/etc/kubernetes/pki/apiserver.key",
+			// agent tokens are reviewed by the hosting cluster, with the in-cluster config
+			// of the API server pod
+			"--agent-namespace=" + deployment.Namespace,
+			"--agent-service-account=" + KonnectivityAgentServiceAccount,
+			"--authentication-audience=" + KonnectivityAuthAudience,
+		},
+		Ports: []v1.ContainerPort{{
+			ContainerPort: KonnectivityAgentPort,
+		}},
+		VolumeMounts: []v1.VolumeMount{
+			{
+				MountPath: "/etc/kubernetes/pki",
+				Name:      "k8s-certs",
+				ReadOnly:  true,
+			},
+			socketMount,
+		},
+	})
+}
+
+func findContainer(podSpec *v1.PodSpec, name string) *v1.Container {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == name {
+			return &podSpec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// ReconcileKonnectivityAgentDeployment deploys the konnectivity agent connecting back
+// to the konnectivity server sidecar of the API server
+func (r *K8sReconciler) ReconcileKonnectivityAgentDeployment(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.EgressSelector == nil || !hcp.Spec.EgressSelector.Konnectivity {
+		return nil
+	}
+	if err := r.reconcileKonnectivityAgentRBAC(hcp); err != nil {
+		return err
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	desired := generateKonnectivityAgentDeployment(hcp.Name, namespace)
+	desired.Spec.Template.Spec.ImagePullSecrets = shared.GetImagePullSecrets(hcp)
+	// the konnectivity server presents the API server serving cert
+	if hcp.Spec.ExternalCerts != nil {
+		setFlag(&desired.Spec.Template.Spec.Containers[0], "--ca-cert", apiServerCAPath)
+	}
+
+	// unlike the API server, an agent created before the template hash was recorded is updated
+	// right away, as it has no token to authenticate to a konnectivity server rolled out later
+	deployment := &appsv1.Deployment{}
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(desired), deployment, &client.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return err
+	case deployment.Annotations[templateHashAnnotation] == "":
+		deployment.Spec.Template = desired.Spec.Template
+		metav1.SetMetaDataAnnotation(&deployment.ObjectMeta, templateHashAnnotation, podTemplateHash(desired))
+		return r.Client.Update(context.TODO(), deployment, &client.UpdateOptions{})
+	}
+	return r.reconcileDeployment(hcp, desired, nil)
+}
+
+// reconcileKonnectivityAgentRBAC creates the service account of the konnectivity agent, and
+// allows the service account of the API server pod, used by the konnectivity server sidecar,
+// to review the tokens of the agents
+func (r *K8sReconciler) reconcileKonnectivityAgentRBAC(hcp *tenancyv1alpha1.ControlPlane) error {
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KonnectivityAgentServiceAccount,
+			Namespace: namespace,
+		},
+	}
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace + "-konnectivity-server",
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     authDelegatorClusterRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      "default",
+				Namespace: namespace,
+			},
+		},
+	}
+	for _, obj := range []client.Object{sa, binding} {
+		err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object), &client.GetOptions{})
+		if err == nil {
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := controllerutil.SetControllerReference(hcp, obj, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Client.Create(context.TODO(), obj, &client.CreateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func generateKonnectivityAgentDeployment(cpName, namespace string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KonnectivityDeploymentName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"component": KonnectivityDeploymentName,
+				"tier":      "control-plane",
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": KonnectivityDeploymentName,
+				},
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": KonnectivityDeploymentName,
+					},
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name:            KonnectivityDeploymentName,
+							Image:           KonnectivityAgentImage,
+							ImagePullPolicy: v1.PullIfNotPresent,
+							Command: []string{
+								"/proxy-agent",
+								"--ca-cert=/etc/kubernetes/pki/ca.crt",
+								fmt.Sprintf("--proxy-server-host=%s.%s.svc", cpName, namespace),
+								fmt.Sprintf("--proxy-server-port=%d", KonnectivityAgentPort),
+								fmt.Sprintf("--health-server-port=%d", KonnectivityHealthPort),
+								fmt.Sprintf("--service-account-token-path=%s/%s", konnectivityTokenDir, konnectivityTokenFile),
+							},
+							VolumeMounts: []v1.VolumeMount{
+								{
+									MountPath: "/etc/kubernetes/pki",
+									Name:      "k8s-certs",
+									ReadOnly:  true,
+								},
+								{
+									MountPath: konnectivityTokenDir,
+									Name:      konnectivityTokenVolumeName,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					ServiceAccountName: KonnectivityAgentServiceAccount,
+					Volumes: []v1.Volume{
+						{
+							Name: "k8s-certs",
+							VolumeSource: v1.VolumeSource{
+								Secret: &v1.SecretVolumeSource{
+									SecretName: "k8s-certs",
+								},
+							},
+						},
+						{
+							Name: konnectivityTokenVolumeName,
+							VolumeSource: v1.VolumeSource{
+								Projected: &v1.ProjectedVolumeSource{
+									Sources: []v1.VolumeProjection{{
+										ServiceAccountToken: &v1.ServiceAccountTokenProjection{
+											Audience:          KonnectivityAuthAudience,
+											ExpirationSeconds: pointer.Int64(konnectivityTokenExpiration),
+											Path:              konnectivityTokenFile,
+										},
+									}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,258 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const testEgressConfig = `apiVersion: apiserver.k8s.io/v1beta1
+kind: EgressSelectorConfiguration
+egressSelections:
+- name: cluster
+  connection:
+    proxyProtocol: GRPC
+    transport:
+      uds:
+        udsName: /etc/kubernetes/konnectivity-server/konnectivity-server.socket
+- name: controlplane
+  connection:
+    proxyProtocol: Direct
+`
+
+func TestReconcileAPIServerDeploymentEgressSelector(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			EgressSelector: &tenancyv1alpha1.EgressSelectorSpec{
+				ConfigMapRef: tenancyv1alpha1.ConfigMapKeyReference{
+					Namespace: "default",
+					Name:      "egress",
+					Key:       "config.yaml",
+				},
+				Konnectivity: true,
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "egress", Namespace: "default"},
+		Data:       map[string]string{"config.yaml": testEgressConfig},
+	})
+
+	ctx := context.Background()
+	if err := r.ReconcileEgressSelectorConfig(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileEgressSelectorConfig returned error: %v", err)
+	}
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	cm := &v1.ConfigMap{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: EgressSelectorConfigMapName}, cm); err != nil {
+		t.Fatalf("expected egress selector config map to be copied: %v", err)
+	}
+	if cm.Data[EgressSelectorConfigKey] != testEgressConfig {
+		t.Errorf("unexpected egress selector config data: %s", cm.Data[EgressSelectorConfigKey])
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.APIServerDeploymentName}, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+
+	flag := fmt.Sprintf("--egress-selector-config-file=%s/%s", EgressSelectorMountPath, EgressSelectorConfigKey)
+	if !hasString(apiServer.Command, flag) {
+		t.Errorf("expected apiserver command to contain %s", flag)
+	}
+	if !hasMount(apiServer.VolumeMounts, egressSelectorVolumeName, EgressSelectorMountPath) {
+		t.Errorf("expected egress selector config to be mounted at %s", EgressSelectorMountPath)
+	}
+	if !hasMount(apiServer.VolumeMounts, konnectivitySocketVolumeName, KonnectivitySocketDir) {
+		t.Errorf("expected konnectivity socket to be mounted at %s", KonnectivitySocketDir)
+	}
+	volumeFound := false
+	for _, vol := range podSpec.Volumes {
+		if vol.Name == egressSelectorVolumeName && vol.ConfigMap != nil && vol.ConfigMap.Name == EgressSelectorConfigMapName {
+			volumeFound = true
+		}
+	}
+	if !volumeFound {
+		t.Errorf("expected volume for config map %s", EgressSelectorConfigMapName)
+	}
+	server := findContainer(podSpec, "konnectivity-server")
+	if server == nil {
+		t.Fatalf("expected konnectivity server sidecar")
+	}
+	for _, arg := range []string{
+		"--agent-namespace=" + namespace,
+		"--agent-service-account=" + KonnectivityAgentServiceAccount,
+		"--authentication-audience=" + KonnectivityAuthAudience,
+	} {
+		if !hasString(server.Command, arg) {
+			t.Errorf("expected konnectivity server command to contain %s", arg)
+		}
+	}
+}
+
+func TestReconcileKonnectivityAgentDeployment(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:           tenancyv1alpha1.ControlPlaneTypeK8S,
+			EgressSelector: &tenancyv1alpha1.EgressSelectorSpec{Konnectivity: true},
+		},
+	}
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	// an agent created before agent authentication, without a template hash
+	legacy := generateKonnectivityAgentDeployment(hcp.Name, namespace)
+	legacy.Spec.Template.Spec.ServiceAccountName = ""
+	legacy.Spec.Template.Spec.Volumes = legacy.Spec.Template.Spec.Volumes[:1]
+	r, cl := newTestReconciler(t, hcp, legacy)
+
+	ctx := context.Background()
+	if err := r.ReconcileKonnectivityAgentDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileKonnectivityAgentDeployment returned error: %v", err)
+	}
+
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: KonnectivityAgentServiceAccount}, &v1.ServiceAccount{}); err != nil {
+		t.Errorf("expected konnectivity agent service account: %v", err)
+	}
+	binding := &rbacv1.ClusterRoleBinding{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: namespace + "-konnectivity-server"}, binding); err != nil {
+		t.Fatalf("expected konnectivity server cluster role binding: %v", err)
+	}
+	if binding.RoleRef.Name != authDelegatorClusterRole || len(binding.Subjects) != 1 || binding.Subjects[0].Namespace != namespace {
+		t.Errorf("unexpected konnectivity server cluster role binding: %v", binding)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: KonnectivityDeploymentName}, deployment); err != nil {
+		t.Fatalf("error getting konnectivity agent deployment: %v", err)
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	if podSpec.ServiceAccountName != KonnectivityAgentServiceAccount {
+		t.Errorf("expected the agent to run as %s, got %q", KonnectivityAgentServiceAccount, podSpec.ServiceAccountName)
+	}
+	flag := fmt.Sprintf("--service-account-token-path=%s/%s", konnectivityTokenDir, konnectivityTokenFile)
+	if !hasString(findContainer(podSpec, KonnectivityDeploymentName).Command, flag) {
+		t.Errorf("expected konnectivity agent command to contain %s", flag)
+	}
+	var audience string
+	for _, volume := range podSpec.Volumes {
+		if volume.Name == konnectivityTokenVolumeName && volume.Projected != nil {
+			audience = volume.Projected.Sources[0].ServiceAccountToken.Audience
+		}
+	}
+	if audience != KonnectivityAuthAudience {
+		t.Errorf("expected a token projected for audience %s, got %q", KonnectivityAuthAudience, audience)
+	}
+	if deployment.Annotations[templateHashAnnotation] == "" {
+		t.Errorf("expected the template hash to be recorded")
+	}
+}
+
+func TestReconcileKonnectivityAgentDeploymentExternalCerts(t *testing.T) {
+	hcp := externalCertsControlPlane()
+	hcp.Spec.EgressSelector = &tenancyv1alpha1.EgressSelectorSpec{Konnectivity: true}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileKonnectivityAgentDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileKonnectivityAgentDeployment returned error: %v", err)
+	}
+	deployment := &appsv1.Deployment{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: KonnectivityDeploymentName}
+	if err := cl.Get(ctx, key, deployment); err != nil {
+		t.Fatalf("error getting konnectivity agent deployment: %v", err)
+	}
+	// the konnectivity server presents the external API server serving cert
+	if !hasString(deployment.Spec.Template.Spec.Containers[0].Command, "--ca-cert="+apiServerCAPath) {
+		t.Errorf("expected the agent to trust the CA of the external API server cert")
+	}
+}
+
+func TestReconcileAPIServerDeploymentEgressSelectorUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.EgressSelector = &tenancyv1alpha1.EgressSelectorSpec{
+		ConfigMapRef: tenancyv1alpha1.ConfigMapKeyReference{Namespace: "default", Name: "egress", Key: "config.yaml"},
+		Konnectivity: true,
+	}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	podSpec := &deployment.Spec.Template.Spec
+	flag := fmt.Sprintf("--egress-selector-config-file=%s/%s", EgressSelectorMountPath, EgressSelectorConfigKey)
+	if !hasString(findContainer(podSpec, util.APIServerDeploymentName).Command, flag) {
+		t.Errorf("expected apiserver command to contain %s after setting the egress selector", flag)
+	}
+	if !hasVolume(podSpec, egressSelectorVolumeName) || findContainer(podSpec, "konnectivity-server") == nil {
+		t.Errorf("expected egress selector volume and konnectivity server sidecar after setting the egress selector")
+	}
+
+	hcp.Spec.EgressSelector.Konnectivity = false
+	deployment = reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	podSpec = &deployment.Spec.Template.Spec
+	if findContainer(podSpec, "konnectivity-server") != nil || hasVolume(podSpec, konnectivitySocketVolumeName) {
+		t.Errorf("expected konnectivity server sidecar and socket volume to be removed")
+	}
+}
+
+func TestValidateEgressSelectorConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		konnectivity bool
+		wantErr      bool
+	}{
+		{name: "valid", config: testEgressConfig, konnectivity: true},
+		{name: "wrong kind", config: "apiVersion: apiserver.k8s.io/v1beta1\nkind: ConfigMap\negressSelections: []\n", wantErr: true},
+		{name: "no selections", config: "apiVersion: apiserver.k8s.io/v1beta1\nkind: EgressSelectorConfiguration\negressSelections: []\n", wantErr: true},
+		{
+			name:    "invalid name",
+			config:  "apiVersion: apiserver.k8s.io/v1beta1\nkind: EgressSelectorConfiguration\negressSelections:\n- name: foo\n  connection:\n    proxyProtocol: Direct\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing transport",
+			config:  "apiVersion: apiserver.k8s.io/v1beta1\nkind: EgressSelectorConfiguration\negressSelections:\n- name: cluster\n  connection:\n    proxyProtocol: GRPC\n",
+			wantErr: true,
+		},
+		{
+			name:         "konnectivity without socket",
+			config:       "apiVersion: apiserver.k8s.io/v1beta1\nkind: EgressSelectorConfiguration\negressSelections:\n- name: cluster\n  connection:\n    proxyProtocol: Direct\n",
+			konnectivity: true,
+			wantErr:      true,
+		},
+		{name: "not yaml", config: "{", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEgressSelectorConfig([]byte(tt.config), tt.konnectivity)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEgressSelectorConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
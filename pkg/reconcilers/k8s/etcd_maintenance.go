@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	EtcdDefragJobPrefix   = "etcd-defrag-"
+	EtcdDefragImage       = "registry.k8s.io/etcd:3.5.9-0"
+	MinEtcdDefragInterval = time.Hour
+	etcdClientPort        = 2379
+	etcdDefragJobTTL      = int32(3600)
+)
+
+// ValidateEtcdMaintenance checks that the compaction interval is not negative and that
+// defragmentation, which blocks the datastore while it runs, is not scheduled too often
+func ValidateEtcdMaintenance(maintenance *tenancyv1alpha1.EtcdMaintenanceSpec) error {
+	if maintenance == nil {
+		return nil
+	}
+	if maintenance.CompactionInterval != nil && maintenance.CompactionInterval.Duration < 0 {
+		return fmt.Errorf("etcd compaction interval %s must not be negative", maintenance.CompactionInterval.Duration)
+	}
+	if maintenance.DefragInterval != nil && maintenance.DefragInterval.Duration < MinEtcdDefragInterval {
+		return fmt.Errorf("etcd defrag interval %s must be at least %s", maintenance.DefragInterval.Duration, MinEtcdDefragInterval)
+	}
+	return nil
+}
+
+// ReconcileEtcdDefrag starts a job defragmenting the datastore of each running API server pod
+// once the defrag interval has elapsed since the last defragmentation, and records the start
+// time in the status
+func (r *K8sReconciler) ReconcileEtcdDefrag(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	return r.reconcileEtcdDefrag(ctx, hcp, time.Now())
+}
+
+func (r *K8sReconciler) reconcileEtcdDefrag(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, now time.Time) error {
+	if next := EtcdDefragTime(hcp); next.IsZero() || now.Before(next) {
+		return nil
+	}
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+
+	pods := &v1.PodList{}
+	if err := r.Client.List(context.TODO(), pods, client.InNamespace(namespace), client.MatchingLabels{"app": util.APIServerDeploymentName}); err != nil {
+		return err
+	}
+	endpoints := []string{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodRunning && pod.Status.PodIP != "" {
+			endpoints = append(endpoints, fmt.Sprintf("http://%s:%d", pod.Status.PodIP, etcdClientPort))
+		}
+	}
+	// retry on the next reconcile once the API server is running
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	job := generateEtcdDefragJob(namespace, endpoints, now)
+	if err := controllerutil.SetControllerReference(hcp, job, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Client.Create(context.TODO(), job, &client.CreateOptions{}); err != nil {
+		return err
+	}
+	hcp.Status.LastDefragTime = &metav1.Time{Time: now}
+	return nil
+}
+
+// EtcdDefragTime returns when the next datastore defragmentation is due, or the zero time
+// if defragmentation is disabled
+func EtcdDefragTime(hcp *tenancyv1alpha1.ControlPlane) time.Time {
+	maintenance := hcp.Spec.EtcdMaintenance
+	if maintenance == nil || maintenance.DefragInterval == nil {
+		return time.Time{}
+	}
+	if hcp.Status.LastDefragTime == nil {
+		// due right away
+		return time.Unix(0, 0)
+	}
+	return hcp.Status.LastDefragTime.Add(maintenance.DefragInterval.Duration)
+}
+
+func generateEtcdDefragJob(namespace string, endpoints []string, now time.Time) *batchv1.Job {
+	name := fmt.Sprintf("%s%d", EtcdDefragJobPrefix, now.Unix())
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            pointer.Int32(2),
+			TTLSecondsAfterFinished: pointer.Int32(etcdDefragJobTTL),
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name:            "etcd-defrag",
+							Image:           EtcdDefragImage,
+							ImagePullPolicy: v1.PullIfNotPresent,
+							Command: []string{
+								"etcdctl",
+								fmt.Sprintf("--endpoints=%s", strings.Join(endpoints, ",")),
+								"defrag",
+							},
+							Env: []v1.EnvVar{{Name: "ETCDCTL_API", Value: "3"}},
+						},
+					},
+					RestartPolicy: v1.RestartPolicyNever,
+				},
+			},
+		},
+	}
+}
+
+// configureEtcdCompaction passes the compaction interval to the API server
+func configureEtcdCompaction(deployment *appsv1.Deployment, maintenance *tenancyv1alpha1.EtcdMaintenanceSpec) {
+	if maintenance == nil || maintenance.CompactionInterval == nil {
+		return
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+	apiServer.Command = append(apiServer.Command, fmt.Sprintf("--etcd-compaction-interval=%s", maintenance.CompactionInterval.Duration))
+}
@@ -0,0 +1,159 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileAPIServerDeploymentEtcdCompaction(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			EtcdMaintenance: &tenancyv1alpha1.EtcdMaintenanceSpec{
+				CompactionInterval: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.APIServerDeploymentName}
+	if err := cl.Get(ctx, key, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+	if !hasString(apiServer.Command, "--etcd-compaction-interval=10m0s") {
+		t.Errorf("expected apiserver command to contain --etcd-compaction-interval=10m0s")
+	}
+}
+
+func TestReconcileAPIServerDeploymentEtcdCompactionUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.EtcdMaintenance = &tenancyv1alpha1.EtcdMaintenanceSpec{
+		CompactionInterval: &metav1.Duration{Duration: 15 * time.Minute},
+	}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	if !hasString(findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command, "--etcd-compaction-interval=15m0s") {
+		t.Errorf("expected apiserver command to contain --etcd-compaction-interval=15m0s after the spec change")
+	}
+}
+
+func TestReconcileEtcdDefrag(t *testing.T) {
+	namespace := util.GenerateNamespaceFromControlPlaneName("cp1")
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			EtcdMaintenance: &tenancyv1alpha1.EtcdMaintenanceSpec{
+				DefragInterval: &metav1.Duration{Duration: 24 * time.Hour},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp, &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kube-apiserver-abc",
+			Namespace: namespace,
+			Labels:    map[string]string{"app": util.APIServerDeploymentName},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.244.0.12"},
+	})
+
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := r.reconcileEtcdDefrag(ctx, hcp, now); err != nil {
+		t.Fatalf("reconcileEtcdDefrag returned error: %v", err)
+	}
+	if hcp.Status.LastDefragTime == nil || !hcp.Status.LastDefragTime.Time.Equal(now) {
+		t.Fatalf("expected the defrag start time to be recorded, got %v", hcp.Status.LastDefragTime)
+	}
+	jobs := &batchv1.JobList{}
+	if err := cl.List(ctx, jobs, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("error listing jobs: %v", err)
+	}
+	if len(jobs.Items) != 1 {
+		t.Fatalf("expected one defrag job, got %d", len(jobs.Items))
+	}
+	container := jobs.Items[0].Spec.Template.Spec.Containers[0]
+	if !hasString(container.Command, "--endpoints=http://10.244.0.12:2379") || !hasString(container.Command, "defrag") {
+		t.Errorf("expected the job to defrag the apiserver datastore, got %v", container.Command)
+	}
+
+	// the next defrag is scheduled after the interval
+	if next := EtcdDefragTime(hcp); !next.Equal(now.Add(24 * time.Hour)) {
+		t.Errorf("expected the next defrag at %s, got %s", now.Add(24*time.Hour), next)
+	}
+	if err := r.reconcileEtcdDefrag(ctx, hcp, now.Add(time.Hour)); err != nil {
+		t.Fatalf("reconcileEtcdDefrag returned error: %v", err)
+	}
+	if err := cl.List(ctx, jobs, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("error listing jobs: %v", err)
+	}
+	if len(jobs.Items) != 1 {
+		t.Errorf("expected no defrag job before the interval elapses, got %d jobs", len(jobs.Items))
+	}
+	if err := r.reconcileEtcdDefrag(ctx, hcp, now.Add(25*time.Hour)); err != nil {
+		t.Fatalf("reconcileEtcdDefrag returned error: %v", err)
+	}
+	if err := cl.List(ctx, jobs, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("error listing jobs: %v", err)
+	}
+	if len(jobs.Items) != 2 {
+		t.Errorf("expected a second defrag job after the interval, got %d jobs", len(jobs.Items))
+	}
+}
+
+func TestValidateEtcdMaintenance(t *testing.T) {
+	tests := []struct {
+		name        string
+		maintenance *tenancyv1alpha1.EtcdMaintenanceSpec
+		wantErr     bool
+	}{
+		{name: "nil"},
+		{name: "valid", maintenance: &tenancyv1alpha1.EtcdMaintenanceSpec{
+			CompactionInterval: &metav1.Duration{Duration: 5 * time.Minute},
+			DefragInterval:     &metav1.Duration{Duration: 24 * time.Hour},
+		}},
+		{name: "compaction disabled", maintenance: &tenancyv1alpha1.EtcdMaintenanceSpec{CompactionInterval: &metav1.Duration{}}},
+		{name: "negative compaction", maintenance: &tenancyv1alpha1.EtcdMaintenanceSpec{
+			CompactionInterval: &metav1.Duration{Duration: -time.Minute},
+		}, wantErr: true},
+		{name: "defrag too often", maintenance: &tenancyv1alpha1.EtcdMaintenanceSpec{
+			DefragInterval: &metav1.Duration{Duration: 10 * time.Minute},
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEtcdMaintenance(tt.maintenance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEtcdMaintenance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// apiServerCAPath is the path of the CA of the external API server serving cert in the certs
+// secret mounted by the control plane components
+const apiServerCAPath = "/etc/kubernetes/pki/" + certs.APIServerCAKey
+
+// applyExternalCerts swaps the generated API server serving cert with the externally issued
+// one, and trusts the CA of the external admin cert for client certificates
+func (r *K8sReconciler) applyExternalCerts(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, crts *certs.Certs) error {
+	if hcp.Spec.ExternalCerts == nil {
+		return nil
+	}
+	apiServer, err := r.getTLSSecret(ctx, hcp.Spec.ExternalCerts.APIServerSecretRef)
+	if err != nil {
+		return err
+	}
+	if err := crts.UseExternalAPIServerCert(apiServer.Data[v1.TLSCertKey], apiServer.Data[v1.TLSPrivateKeyKey], apiServer.Data[v1.ServiceAccountRootCAKey]); err != nil {
+		return fmt.Errorf("invalid API server certificate in secret %s/%s: %s", apiServer.Namespace, apiServer.Name, err)
+	}
+
+	admin, err := r.getTLSSecret(ctx, hcp.Spec.ExternalCerts.AdminSecretRef)
+	if err != nil {
+		return err
+	}
+	if err := certs.ValidateKeyPair(admin.Data[v1.TLSCertKey], admin.Data[v1.TLSPrivateKeyKey], admin.Data[v1.ServiceAccountRootCAKey]); err != nil {
+		return fmt.Errorf("invalid admin certificate in secret %s/%s: %s", admin.Namespace, admin.Name, err)
+	}
+	crts.AddTrustedCA(admin.Data[v1.ServiceAccountRootCAKey])
+	return nil
+}
+
+// applyExternalAdminCert makes the admin kubeconfig use the externally issued admin cert
+func (r *K8sReconciler) applyExternalAdminCert(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, conf *certs.ConfigGen) error {
+	if hcp.Spec.ExternalCerts == nil {
+		return nil
+	}
+	admin, err := r.getTLSSecret(ctx, hcp.Spec.ExternalCerts.AdminSecretRef)
+	if err != nil {
+		return err
+	}
+	if err := conf.UseExternalClientCert(admin.Data[v1.TLSCertKey], admin.Data[v1.TLSPrivateKeyKey]); err != nil {
+		return fmt.Errorf("invalid admin certificate in secret %s/%s: %s", admin.Namespace, admin.Name, err)
+	}
+	return nil
+}
+
+func (r *K8sReconciler) getTLSSecret(ctx context.Context, ref tenancyv1alpha1.TLSSecretReference) (*v1.Secret, error) {
+	secret := &v1.Secret{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret, &client.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("error getting certificate secret %s/%s: %s", ref.Namespace, ref.Name, err)
+	}
+	return secret, nil
+}
+
+// configureExternalServingCA makes the controller manager publish the CA of the external API
+// server serving cert as the root CA of the control plane, so that its pods can verify the
+// API server. Without external certs the generated CA in ca.crt signs the serving cert.
+func configureExternalServingCA(deployment *appsv1.Deployment, externalCerts *tenancyv1alpha1.ExternalCertsSpec) {
+	if externalCerts == nil {
+		return
+	}
+	if cm := findContainer(&deployment.Spec.Template.Spec, util.CMDeploymentName); cm != nil {
+		setFlag(cm, "--root-ca-file", apiServerCAPath)
+	}
+}
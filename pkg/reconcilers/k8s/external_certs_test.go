@@ -0,0 +1,357 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileExternalCerts(t *testing.T) {
+	caCert, caKey := generateTestCA(t)
+	adminCACert, adminCAKey := generateTestCA(t)
+	apiServerCert, apiServerKey := generateTestCert(t, caCert, caKey, "kube-apiserver", x509.ExtKeyUsageServerAuth)
+	adminCert, adminKey := generateTestCert(t, adminCACert, adminCAKey, "kubernetes-admin", x509.ExtKeyUsageClientAuth)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})
+	adminCAPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: adminCACert.Raw})
+
+	hcp := externalCertsControlPlane()
+	r, cl := newTestReconciler(t, hcp,
+		externalTLSSecret("apiserver", apiServerCert, apiServerKey, caPEM),
+		externalTLSSecret("admin", adminCert, adminKey, adminCAPEM),
+	)
+
+	ctx := context.Background()
+	cfg := &shared.SharedConfig{Domain: "localtest.me", ExternalPort: 9443}
+	crts, err := r.ReconcileCertsSecret(ctx, hcp, cfg, "")
+	if err != nil {
+		t.Fatalf("ReconcileCertsSecret returned error: %v", err)
+	}
+	confGen := &certs.ConfigGen{CpName: hcp.Name, CpHost: hcp.Name, CpPort: cfg.ExternalPort, CpDomain: cfg.Domain, Target: certs.Admin}
+	if err := r.applyExternalAdminCert(ctx, hcp, confGen); err != nil {
+		t.Fatalf("applyExternalAdminCert returned error: %v", err)
+	}
+	if err := r.ReconcileKubeconfigSecret(ctx, crts, confGen, hcp); err != nil {
+		t.Fatalf("ReconcileKubeconfigSecret returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	certsSecret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: certs.CertsSecretName}, certsSecret); err != nil {
+		t.Fatalf("error getting certs secret: %v", err)
+	}
+	if !bytes.Equal(certsSecret.Data["apiserver.crt"], apiServerCert) || !bytes.Equal(certsSecret.Data["apiserver.key"], apiServerKey) {
+		t.Errorf("expected the external API server certificate and key to be used verbatim")
+	}
+	if !bytes.Contains(certsSecret.Data["ca.crt"], adminCAPEM) {
+		t.Errorf("expected the CA of the external admin cert to be trusted for client certs")
+	}
+	if bytes.Contains(certsSecret.Data["ca.crt"], caPEM) {
+		t.Errorf("expected the CA of the external API server cert not to be trusted for client certs")
+	}
+	if !bytes.Equal(certsSecret.Data[certs.APIServerCAKey], caPEM) {
+		t.Errorf("expected %s to hold the CA of the external API server cert", certs.APIServerCAKey)
+	}
+
+	kubeconfigSecret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.AdminConfSecret}, kubeconfigSecret); err != nil {
+		t.Fatalf("error getting admin kubeconfig secret: %v", err)
+	}
+	for _, key := range []string{util.KubeconfigSecretKeyDefault, util.KubeconfigSecretKeyInCluster} {
+		config, err := clientcmd.Load(kubeconfigSecret.Data[key])
+		if err != nil {
+			t.Fatalf("error loading %s: %v", key, err)
+		}
+		authInfo := config.AuthInfos[certs.GenerateAuthInfoAdminName(hcp.Name)]
+		if authInfo == nil || !bytes.Equal(authInfo.ClientCertificateData, adminCert) || !bytes.Equal(authInfo.ClientKeyData, adminKey) {
+			t.Errorf("expected %s to use the external admin certificate and key", key)
+		}
+		cluster := config.Clusters[certs.GenerateClusterName(hcp.Name)]
+		if cluster == nil || !bytes.Equal(cluster.CertificateAuthorityData, caPEM) {
+			t.Errorf("expected %s to trust the CA of the external API server cert", key)
+		}
+	}
+
+	if err := r.ReconcileCMDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileCMDeployment returned error: %v", err)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.CMDeploymentName}, deployment); err != nil {
+		t.Fatalf("error getting controller manager deployment: %v", err)
+	}
+	cm := findContainer(&deployment.Spec.Template.Spec, util.CMDeploymentName)
+	if cm == nil || !hasString(cm.Command, "--root-ca-file="+apiServerCAPath) {
+		t.Errorf("expected the controller manager to publish the CA of the external API server cert")
+	}
+}
+
+func TestReconcileExternalCertsClientCABundleMigration(t *testing.T) {
+	caCert, caKey := generateTestCA(t)
+	adminCACert, adminCAKey := generateTestCA(t)
+	apiServerCert, apiServerKey := generateTestCert(t, caCert, caKey, "kube-apiserver", x509.ExtKeyUsageServerAuth)
+	adminCert, adminKey := generateTestCert(t, adminCACert, adminCAKey, "kubernetes-admin", x509.ExtKeyUsageClientAuth)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})
+	adminCAPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: adminCACert.Raw})
+
+	hcp := externalCertsControlPlane()
+	r, cl := newTestReconciler(t, hcp,
+		externalTLSSecret("apiserver", apiServerCert, apiServerKey, caPEM),
+		externalTLSSecret("admin", adminCert, adminKey, adminCAPEM),
+	)
+	ctx := context.Background()
+	cfg := &shared.SharedConfig{Domain: "localtest.me", ExternalPort: 9443}
+	if _, err := r.ReconcileCertsSecret(ctx, hcp, cfg, ""); err != nil {
+		t.Fatalf("ReconcileCertsSecret returned error: %v", err)
+	}
+
+	// a certs secret written before the serving CA was kept out of the client CA bundle
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	certsSecret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: certs.CertsSecretName}, certsSecret); err != nil {
+		t.Fatalf("error getting certs secret: %v", err)
+	}
+	generatedCA := certsSecret.Data["ca.crt"][:bytes.Index(certsSecret.Data["ca.crt"], adminCAPEM)]
+	certsSecret.Data["ca.crt"] = append(append(append([]byte{}, generatedCA...), caPEM...), adminCAPEM...)
+	delete(certsSecret.Data, certs.APIServerCAKey)
+	if err := cl.Update(ctx, certsSecret); err != nil {
+		t.Fatalf("error updating certs secret: %v", err)
+	}
+
+	if _, err := r.ReconcileCertsSecret(ctx, hcp, cfg, ""); err != nil {
+		t.Fatalf("ReconcileCertsSecret returned error: %v", err)
+	}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: certs.CertsSecretName}, certsSecret); err != nil {
+		t.Fatalf("error getting certs secret: %v", err)
+	}
+	if bytes.Contains(certsSecret.Data["ca.crt"], caPEM) {
+		t.Errorf("expected the CA of the external API server cert to be removed from the client CA bundle")
+	}
+	if !bytes.HasPrefix(certsSecret.Data["ca.crt"], generatedCA) || !bytes.Contains(certsSecret.Data["ca.crt"], adminCAPEM) {
+		t.Errorf("expected the client CA bundle to keep the generated CA and the CA of the external admin cert")
+	}
+	if !bytes.Equal(certsSecret.Data[certs.APIServerCAKey], caPEM) {
+		t.Errorf("expected %s to hold the CA of the external API server cert", certs.APIServerCAKey)
+	}
+}
+
+func TestReconcileExternalCertsRotation(t *testing.T) {
+	caCert, caKey := generateTestCA(t)
+	adminCACert, adminCAKey := generateTestCA(t)
+	apiServerCert, apiServerKey := generateTestCert(t, caCert, caKey, "kube-apiserver", x509.ExtKeyUsageServerAuth)
+	adminCert, adminKey := generateTestCert(t, adminCACert, adminCAKey, "kubernetes-admin", x509.ExtKeyUsageClientAuth)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})
+	adminCAPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: adminCACert.Raw})
+
+	hcp := externalCertsControlPlane()
+	apiServerSecret := externalTLSSecret("apiserver", apiServerCert, apiServerKey, caPEM)
+	adminSecret := externalTLSSecret("admin", adminCert, adminKey, adminCAPEM)
+	r, cl := newTestReconciler(t, hcp, apiServerSecret, adminSecret)
+
+	ctx := context.Background()
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	cfg := &shared.SharedConfig{Domain: "localtest.me", ExternalPort: 9443}
+	reconcile := func() *certs.Certs {
+		crts, err := r.ReconcileCertsSecret(ctx, hcp, cfg, "")
+		if err != nil {
+			t.Fatalf("ReconcileCertsSecret returned error: %v", err)
+		}
+		confGen := &certs.ConfigGen{CpName: hcp.Name, CpHost: hcp.Name, CpPort: cfg.ExternalPort, CpDomain: cfg.Domain, Target: certs.Admin}
+		if err := r.applyExternalAdminCert(ctx, hcp, confGen); err != nil {
+			t.Fatalf("applyExternalAdminCert returned error: %v", err)
+		}
+		if err := r.ReconcileKubeconfigSecret(ctx, crts, confGen, hcp); err != nil {
+			t.Fatalf("ReconcileKubeconfigSecret returned error: %v", err)
+		}
+		return crts
+	}
+	getSecret := func(name string) *v1.Secret {
+		secret := &v1.Secret{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+			t.Fatalf("error getting secret %s: %v", name, err)
+		}
+		return secret
+	}
+	assertKubeconfig := func(caData, clientCert []byte) {
+		t.Helper()
+		secret := getSecret(util.AdminConfSecret)
+		for _, key := range []string{util.KubeconfigSecretKeyDefault, util.KubeconfigSecretKeyInCluster} {
+			config, err := clientcmd.Load(secret.Data[key])
+			if err != nil {
+				t.Fatalf("error loading %s: %v", key, err)
+			}
+			if cluster := config.Clusters[certs.GenerateClusterName(hcp.Name)]; !bytes.Equal(cluster.CertificateAuthorityData, caData) {
+				t.Errorf("unexpected certificate authority in %s", key)
+			}
+			authInfo := config.AuthInfos[certs.GenerateAuthInfoAdminName(hcp.Name)]
+			if clientCert != nil && !bytes.Equal(authInfo.ClientCertificateData, clientCert) {
+				t.Errorf("unexpected client certificate in %s", key)
+			}
+			if clientCert == nil && bytes.Equal(authInfo.ClientCertificateData, adminCert) {
+				t.Errorf("expected %s not to use the external admin certificate", key)
+			}
+		}
+	}
+	reconcile()
+
+	// the external API server cert is renewed
+	apiServerCert, apiServerKey = generateTestCert(t, caCert, caKey, "kube-apiserver", x509.ExtKeyUsageServerAuth)
+	apiServerSecret.Data[v1.TLSCertKey], apiServerSecret.Data[v1.TLSPrivateKeyKey] = apiServerCert, apiServerKey
+	if err := cl.Update(ctx, apiServerSecret); err != nil {
+		t.Fatalf("error updating API server secret: %v", err)
+	}
+	if reconcile() == nil {
+		t.Errorf("expected the updated certs after renewing the API server cert")
+	}
+	certsSecret := getSecret(certs.CertsSecretName)
+	if !bytes.Equal(certsSecret.Data["apiserver.crt"], apiServerCert) || !bytes.Equal(certsSecret.Data["apiserver.key"], apiServerKey) {
+		t.Errorf("expected the renewed API server certificate and key to be used")
+	}
+	assertKubeconfig(caPEM, adminCert)
+
+	// the external admin cert is renewed by the same CA, which leaves the certs secret unchanged
+	adminCert, adminKey = generateTestCert(t, adminCACert, adminCAKey, "kubernetes-admin", x509.ExtKeyUsageClientAuth)
+	adminSecret.Data[v1.TLSCertKey], adminSecret.Data[v1.TLSPrivateKeyKey] = adminCert, adminKey
+	if err := cl.Update(ctx, adminSecret); err != nil {
+		t.Fatalf("error updating admin secret: %v", err)
+	}
+	if reconcile() != nil {
+		t.Errorf("expected no certs for an unchanged certs secret")
+	}
+	assertKubeconfig(caPEM, adminCert)
+
+	// the external certs are removed
+	hcp.Spec.ExternalCerts = nil
+	if reconcile() == nil {
+		t.Errorf("expected the updated certs after removing the external certs")
+	}
+	certsSecret = getSecret(certs.CertsSecretName)
+	if _, ok := certsSecret.Data[certs.APIServerCAKey]; ok {
+		t.Errorf("expected %s to be removed", certs.APIServerCAKey)
+	}
+	if bytes.Contains(certsSecret.Data["ca.crt"], adminCAPEM) {
+		t.Errorf("expected the CA of the external admin cert not to be trusted anymore")
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certsSecret.Data["ca.crt"])
+	block, _ := pem.Decode(certsSecret.Data["apiserver.crt"])
+	if block == nil {
+		t.Fatalf("error decoding the API server certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("error parsing the API server certificate: %v", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots}); err != nil {
+		t.Errorf("expected a serving certificate issued by the generated CA: %v", err)
+	}
+	assertKubeconfig(certsSecret.Data["ca.crt"], nil)
+}
+
+func TestReconcileExternalCertsMismatch(t *testing.T) {
+	caCert, caKey := generateTestCA(t)
+	apiServerCert, _ := generateTestCert(t, caCert, caKey, "kube-apiserver", x509.ExtKeyUsageServerAuth)
+	_, otherKey := generateTestCert(t, caCert, caKey, "other", x509.ExtKeyUsageServerAuth)
+
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			ExternalCerts: &tenancyv1alpha1.ExternalCertsSpec{
+				APIServerSecretRef: tenancyv1alpha1.TLSSecretReference{Namespace: "pki", Name: "apiserver"},
+				AdminSecretRef:     tenancyv1alpha1.TLSSecretReference{Namespace: "pki", Name: "admin"},
+			},
+		},
+	}
+	r, _ := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "apiserver", Namespace: "pki"},
+		Data:       map[string][]byte{v1.TLSCertKey: apiServerCert, v1.TLSPrivateKeyKey: otherKey},
+	})
+
+	cfg := &shared.SharedConfig{Domain: "localtest.me", ExternalPort: 9443}
+	if _, err := r.ReconcileCertsSecret(context.Background(), hcp, cfg, ""); err == nil {
+		t.Errorf("expected error for mismatched certificate and key")
+	}
+}
+
+func externalCertsControlPlane() *tenancyv1alpha1.ControlPlane {
+	return &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			ExternalCerts: &tenancyv1alpha1.ExternalCertsSpec{
+				APIServerSecretRef: tenancyv1alpha1.TLSSecretReference{Namespace: "pki", Name: "apiserver"},
+				AdminSecretRef:     tenancyv1alpha1.TLSSecretReference{Namespace: "pki", Name: "admin"},
+			},
+		},
+	}
+}
+
+func externalTLSSecret(name string, cert, key, ca []byte) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pki"},
+		Type:       v1.SecretTypeTLS,
+		Data:       map[string][]byte{v1.TLSCertKey: cert, v1.TLSPrivateKeyKey: key, v1.ServiceAccountRootCAKey: ca},
+	}
+}
+
+func generateTestCA(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "external-ca"},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error parsing CA certificate: %v", err)
+	}
+	return cert, key
+}
+
+func generateTestCert(t *testing.T, ca *x509.Certificate, caKey *rsa.PrivateKey, cn string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
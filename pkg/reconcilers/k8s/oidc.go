@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	OIDCCASecretName     = "oidc-ca"
+	OIDCCAKey            = "ca.crt"
+	OIDCCAMountPath      = "/etc/kubernetes/oidc"
+	oidcCAVolumeName     = "oidc-ca"
+	defaultOIDCUserClaim = "sub"
+)
+
+// ReconcileOIDCConfig validates the OIDC authenticator settings and copies the referenced
+// CA certificates into the control plane namespace, where they are mounted by the API server
+func (r *K8sReconciler) ReconcileOIDCConfig(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	oidc := hcp.Spec.OIDC
+	if oidc == nil {
+		return nil
+	}
+	if err := ValidateOIDC(oidc); err != nil {
+		return err
+	}
+	if oidc.CASecretRef == nil {
+		return nil
+	}
+
+	data, err := r.GetSecretKeyData(ctx, *oidc.CASecretRef)
+	if err != nil {
+		return err
+	}
+	if _, err := certutil.ParseCertsPEM(data); err != nil {
+		return fmt.Errorf("error parsing OIDC CA certificates: %s", err)
+	}
+	return r.ReconcileControlPlaneSecret(ctx, hcp, OIDCCASecretName, v1.SecretTypeOpaque, map[string][]byte{OIDCCAKey: data})
+}
+
+// ValidateOIDC checks that the issuer is an https URL usable for discovery, that a client
+// id is set and that the claim names are valid
+func ValidateOIDC(oidc *tenancyv1alpha1.OIDCSpec) error {
+	if oidc == nil {
+		return nil
+	}
+	if err := validateIssuerURL("OIDC issuer", oidc.IssuerURL); err != nil {
+		return err
+	}
+	if oidc.ClientID == "" {
+		return fmt.Errorf("OIDC client id must not be empty")
+	}
+	if oidc.UsernameClaim != "" {
+		if err := validateOIDCClaim("username", oidc.UsernameClaim); err != nil {
+			return err
+		}
+	}
+	if oidc.GroupsClaim != "" {
+		if err := validateOIDCClaim("groups", oidc.GroupsClaim); err != nil {
+			return err
+		}
+	}
+	for claim := range oidc.RequiredClaims {
+		if err := validateOIDCClaim("required", claim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateOIDCClaim(kind, claim string) error {
+	if claim == "" || strings.ContainsAny(claim, " \t\r\n,=") {
+		return fmt.Errorf("invalid OIDC %s claim %q: must not be empty or contain whitespace, commas or equal signs", kind, claim)
+	}
+	return nil
+}
+
+// configureOIDC sets the OIDC authenticator flags of the API server, and mounts the
+// provider CA certificates when referenced
+func configureOIDC(deployment *appsv1.Deployment, oidc *tenancyv1alpha1.OIDCSpec) {
+	if oidc == nil {
+		return
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+
+	usernameClaim := oidc.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = defaultOIDCUserClaim
+	}
+	apiServer.Command = append(apiServer.Command,
+		fmt.Sprintf("--oidc-issuer-url=%s", oidc.IssuerURL),
+		fmt.Sprintf("--oidc-client-id=%s", oidc.ClientID),
+		fmt.Sprintf("--oidc-username-claim=%s", usernameClaim))
+	if oidc.UsernamePrefix != "" {
+		apiServer.Command = append(apiServer.Command, fmt.Sprintf("--oidc-username-prefix=%s", oidc.UsernamePrefix))
+	}
+	if oidc.GroupsClaim != "" {
+		apiServer.Command = append(apiServer.Command, fmt.Sprintf("--oidc-groups-claim=%s", oidc.GroupsClaim))
+	}
+	if oidc.GroupsPrefix != "" {
+		apiServer.Command = append(apiServer.Command, fmt.Sprintf("--oidc-groups-prefix=%s", oidc.GroupsPrefix))
+	}
+	claims := make([]string, 0, len(oidc.RequiredClaims))
+	for claim := range oidc.RequiredClaims {
+		claims = append(claims, claim)
+	}
+	sort.Strings(claims)
+	for _, claim := range claims {
+		apiServer.Command = append(apiServer.Command, fmt.Sprintf("--oidc-required-claim=%s=%s", claim, oidc.RequiredClaims[claim]))
+	}
+	if len(oidc.SigningAlgorithms) > 0 {
+		algs := make([]string, 0, len(oidc.SigningAlgorithms))
+		for _, alg := range oidc.SigningAlgorithms {
+			algs = append(algs, string(alg))
+		}
+		apiServer.Command = append(apiServer.Command, fmt.Sprintf("--oidc-signing-algs=%s", strings.Join(algs, ",")))
+	}
+	if oidc.CASecretRef == nil {
+		return
+	}
+
+	apiServer.Command = append(apiServer.Command, fmt.Sprintf("--oidc-ca-file=%s/%s", OIDCCAMountPath, OIDCCAKey))
+	apiServer.VolumeMounts = append(apiServer.VolumeMounts, v1.VolumeMount{
+		MountPath: OIDCCAMountPath,
+		Name:      oidcCAVolumeName,
+		ReadOnly:  true,
+	})
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: oidcCAVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{
+				SecretName: OIDCCASecretName,
+			},
+		},
+	})
+}
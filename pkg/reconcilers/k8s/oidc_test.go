@@ -0,0 +1,141 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileAPIServerDeploymentOIDC(t *testing.T) {
+	caData, _, err := certutil.GenerateSelfSignedCertKey("sso.example.com", nil, nil)
+	if err != nil {
+		t.Fatalf("error generating CA: %v", err)
+	}
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			OIDC: &tenancyv1alpha1.OIDCSpec{
+				IssuerURL:         "https://sso.example.com/realms/corp",
+				ClientID:          "kubeflex",
+				UsernameClaim:     "email",
+				UsernamePrefix:    "oidc:",
+				GroupsClaim:       "groups",
+				RequiredClaims:    map[string]string{"hd": "example.com"},
+				SigningAlgorithms: []tenancyv1alpha1.OIDCSigningAlgorithm{"RS256", "ES256"},
+				CASecretRef: &tenancyv1alpha1.SecretKeyReference{
+					Namespace: "default",
+					Name:      "sso-ca",
+					Key:       "ca.pem",
+				},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sso-ca", Namespace: "default"},
+		Data:       map[string][]byte{"ca.pem": caData},
+	})
+
+	ctx := context.Background()
+	if err := r.ReconcileOIDCConfig(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileOIDCConfig returned error: %v", err)
+	}
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: OIDCCASecretName}, secret); err != nil {
+		t.Fatalf("expected OIDC CA to be copied: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.APIServerDeploymentName}, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+	for _, flag := range []string{
+		"--oidc-issuer-url=https://sso.example.com/realms/corp",
+		"--oidc-client-id=kubeflex",
+		"--oidc-username-claim=email",
+		"--oidc-username-prefix=oidc:",
+		"--oidc-groups-claim=groups",
+		"--oidc-required-claim=hd=example.com",
+		"--oidc-signing-algs=RS256,ES256",
+		fmt.Sprintf("--oidc-ca-file=%s/%s", OIDCCAMountPath, OIDCCAKey),
+	} {
+		if !hasString(apiServer.Command, flag) {
+			t.Errorf("expected apiserver command to contain %s", flag)
+		}
+	}
+	if !hasMount(apiServer.VolumeMounts, oidcCAVolumeName, OIDCCAMountPath) {
+		t.Errorf("expected OIDC CA to be mounted at %s", OIDCCAMountPath)
+	}
+}
+
+func TestReconcileAPIServerDeploymentOIDCUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.OIDC = &tenancyv1alpha1.OIDCSpec{
+		IssuerURL: "https://sso.example.com/realms/corp",
+		ClientID:  "kubeflex",
+	}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	command := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command
+	for _, flag := range []string{"--oidc-issuer-url=https://sso.example.com/realms/corp", "--oidc-client-id=kubeflex"} {
+		if !hasString(command, flag) {
+			t.Errorf("expected apiserver command to contain %s after the spec change", flag)
+		}
+	}
+}
+
+func TestValidateOIDC(t *testing.T) {
+	valid := func() *tenancyv1alpha1.OIDCSpec {
+		return &tenancyv1alpha1.OIDCSpec{IssuerURL: "https://sso.example.com", ClientID: "kubeflex"}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*tenancyv1alpha1.OIDCSpec)
+		wantErr bool
+	}{
+		{name: "valid", mutate: func(o *tenancyv1alpha1.OIDCSpec) {}},
+		{name: "http issuer", mutate: func(o *tenancyv1alpha1.OIDCSpec) { o.IssuerURL = "http://sso.example.com" }, wantErr: true},
+		{name: "issuer with query", mutate: func(o *tenancyv1alpha1.OIDCSpec) { o.IssuerURL = "https://sso.example.com?x=y" }, wantErr: true},
+		{name: "no client id", mutate: func(o *tenancyv1alpha1.OIDCSpec) { o.ClientID = "" }, wantErr: true},
+		{name: "username claim with space", mutate: func(o *tenancyv1alpha1.OIDCSpec) { o.UsernameClaim = "user name" }, wantErr: true},
+		{name: "groups claim with comma", mutate: func(o *tenancyv1alpha1.OIDCSpec) { o.GroupsClaim = "a,b" }, wantErr: true},
+		{name: "required claim with equal sign", mutate: func(o *tenancyv1alpha1.OIDCSpec) {
+			o.RequiredClaims = map[string]string{"a=b": "c"}
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oidc := valid()
+			tt.mutate(oidc)
+			err := ValidateOIDC(oidc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOIDC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ package k8s
 
 import (
 	"context"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
@@ -25,9 +27,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	clog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/kubestellar/kubeflex/api/v1alpha1"
 	"github.com/kubestellar/kubeflex/pkg/certs"
@@ -38,35 +40,74 @@ type K8sReconciler struct {
 	*shared.BaseReconciler
 }
 
-func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient) *K8sReconciler {
+func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient, recorder record.EventRecorder) *K8sReconciler {
 	return &K8sReconciler{
 		BaseReconciler: &shared.BaseReconciler{
 			Client:        cl,
 			Scheme:        scheme,
 			ClientSet:     clientSet,
 			DynamicClient: dynamicClient,
+			Recorder:      recorder,
 		},
 	}
 }
 
 func (r *K8sReconciler) Reconcile(ctx context.Context, hcp *v1alpha1.ControlPlane) (ctrl.Result, error) {
-	var routeURL string
-	_ = clog.FromContext(ctx)
+	var routeURL, externalHost string
+	logger := shared.ControlPlaneLogger(ctx, hcp)
+	logger.V(1).Info("Reconciling control plane")
 
 	cfg, err := r.BaseReconciler.GetConfig(ctx)
 	if err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := shared.ValidateTopologySpreadConstraints(hcp.Spec.TopologySpreadConstraints); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := ValidateBootstrapToken(hcp.Spec.BootstrapToken); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := ValidateWatchCache(hcp.Spec.WatchCache); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := ValidateEtcdMaintenance(hcp.Spec.EtcdMaintenance); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := ValidateGoawayChance(hcp.Spec.GoawayChance); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := shared.ValidateIngress(hcp.Spec.Ingress); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	start := time.Now()
 	if err := r.BaseReconciler.ReconcileNamespace(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := r.BaseReconciler.ReconcileImagePullSecrets(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	shared.LogPhase(logger, "namespace", start)
+
+	start = time.Now()
 	if err = r.ReconcileAPIServerService(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
-	if cfg.IsOpenShift {
+	if hcp.Spec.Expose == v1alpha1.ExposeNone {
+		// drop the ingress of a control plane that was exposed through one before
+		if err := r.DeleteAPIServerIngress(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		hcp.Status.NodePort = 0
+	} else if cfg.IsOpenShift {
 		if err = r.ReconcileAPIServerRoute(ctx, hcp, "", shared.SecurePort, cfg.Domain); err != nil {
 			return r.UpdateStatusForSyncingError(hcp, err)
 		}
@@ -78,28 +119,59 @@ func (r *K8sReconciler) Reconcile(ctx context.Context, hcp *v1alpha1.ControlPlan
 		if routeURL == "" {
 			return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
 		}
+		externalHost = routeURL
 	} else {
-		if err = r.ReconcileAPIServerIngress(ctx, hcp, "", shared.DefaulPort, cfg.Domain); err != nil {
-			return r.UpdateStatusForSyncingError(hcp, err)
+		switch hcp.Spec.Expose {
+		case v1alpha1.ExposeNodePort, v1alpha1.ExposeLoadBalancer:
+			routeURL, externalHost, err = r.GetAPIServerServiceEndpoint(ctx, hcp)
+			if err != nil {
+				return r.UpdateStatusForSyncingError(hcp, err)
+			}
+			// re-queue until the node port or the load balancer address is assigned
+			if routeURL == "" {
+				if hcp.Spec.Expose == v1alpha1.ExposeLoadBalancer {
+					v1alpha1.EnsureCondition(hcp, v1alpha1.ConditionWaitingForLoadBalancer(hcp.Name))
+					if _, err := r.UpdateStatusForSyncingSuccess(ctx, hcp); err != nil {
+						return ctrl.Result{}, err
+					}
+				}
+				return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+			}
+		default:
+			if err = r.ReconcileAPIServerIngress(ctx, hcp, "", shared.DefaulPort, cfg.Domain); err != nil {
+				return r.UpdateStatusForSyncingError(hcp, err)
+			}
+			if hcp.Spec.Ingress != nil && hcp.Spec.Ingress.Hostname != "" {
+				externalHost = hcp.Spec.Ingress.Hostname
+				routeURL = net.JoinHostPort(externalHost, strconv.Itoa(cfg.ExternalPort))
+			}
 		}
 	}
 
-	crts, err := r.ReconcileCertsSecret(ctx, hcp, cfg, routeURL)
+	shared.LogPhase(logger, "ingress", start)
+
+	crts, err := r.ReconcileCertsSecret(ctx, hcp, cfg, externalHost)
 	if err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
 	confGen := &certs.ConfigGen{
-		CpName:     hcp.Name,
-		CpHost:     hcp.Name,
-		CpPort:     cfg.ExternalPort,
-		CpDomain:   cfg.Domain,
-		CpExtraDNS: routeURL}
+		CpName:        hcp.Name,
+		CpNamespace:   util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+		CpHost:        hcp.Name,
+		CpPort:        cfg.ExternalPort,
+		CpDomain:      cfg.Domain,
+		CpExtraDNS:    routeURL,
+		InClusterOnly: hcp.Spec.Expose == v1alpha1.ExposeNone}
 	// reconcile kubeconfig for admin
 	confGen.Target = certs.Admin
+	if err = r.applyExternalAdminCert(ctx, hcp, confGen); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
 	if err = r.ReconcileKubeconfigSecret(ctx, crts, confGen, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
+	hcp.Status.APIServerEndpoint = confGen.ServerEndpoint()
 
 	// reconcile kubeconfig for cm
 	confGen.Target = certs.ControllerManager
@@ -108,13 +180,48 @@ func (r *K8sReconciler) Reconcile(ctx context.Context, hcp *v1alpha1.ControlPlan
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err = r.ReconcileEgressSelectorConfig(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err = r.ReconcileAuthenticationWebhookConfig(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err = r.ReconcileAuditPolicy(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err = r.ReconcileServiceAccountSigningKey(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err = r.ReconcileOIDCConfig(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	start = time.Now()
 	if err = r.ReconcileAPIServerDeployment(ctx, hcp, cfg.IsOpenShift); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err = r.ReconcileAPIServerPodDisruptionBudget(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	// reports the API server replicas that are not updated or available yet
+	if err = shared.SetRolledOutCondition(r.Client, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err = r.ReconcileKonnectivityAgentDeployment(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
 	if err = r.ReconcileCMDeployment(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
+	shared.LogPhase(logger, "deployments", start)
 
 	r.UpdateStatusWithSecretRef(hcp, util.AdminConfSecret, util.KubeconfigSecretKeyDefault, util.KubeconfigSecretKeyInCluster)
 
@@ -125,5 +232,37 @@ func (r *K8sReconciler) Reconcile(ctx context.Context, hcp *v1alpha1.ControlPlan
 		}
 	}
 
-	return r.UpdateStatusForSyncingSuccess(ctx, hcp)
+	if len(hcp.Spec.PostCreateHooks) > 0 &&
+		v1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		if err := r.ReconcilePostCreateManifests(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+	}
+
+	if v1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		if err := r.ReconcileMetricsRBAC(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		if err := r.ReconcileDefaultStorageClass(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		if err := r.ReconcileBootstrapToken(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		if err := r.ReconcileEtcdDefrag(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+	}
+
+	result, err := r.UpdateStatusForSyncingSuccess(ctx, hcp)
+	// re-queue to rotate the bootstrap token before it expires and to run the next defrag
+	for _, next := range []time.Time{BootstrapTokenRotationTime(hcp), EtcdDefragTime(hcp)} {
+		if err != nil || next.IsZero() {
+			continue
+		}
+		if after := time.Until(next); after > 0 && (result.RequeueAfter == 0 || after < result.RequeueAfter) {
+			result.RequeueAfter = after
+		}
+	}
+	return result, err
 }
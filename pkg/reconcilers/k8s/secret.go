@@ -17,11 +17,13 @@ limitations under the License.
 package k8s
 
 import (
+	"bytes"
 	"context"
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	clog "sigs.k8s.io/controller-runtime/pkg/log"
@@ -47,10 +49,14 @@ func (r *K8sReconciler) ReconcileCertsSecret(ctx context.Context, hcp *tenancyv1
 	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(csecret), csecret, &client.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			csecret, crts, err := generateCertsSecret(ctx, hcp.Name, namespace, cfg.Domain, extraDNSName)
+			crts, err := generateCerts(ctx, hcp.Name, namespace, cfg.Domain, extraDNSName)
 			if err != nil {
 				return nil, err
 			}
+			if err := r.applyExternalCerts(ctx, hcp, crts); err != nil {
+				return nil, err
+			}
+			csecret := crts.GenerateCertsSecret(ctx, namespace)
 			if err := controllerutil.SetControllerReference(hcp, csecret, r.Scheme); err != nil {
 				return nil, err
 			}
@@ -61,26 +67,69 @@ func (r *K8sReconciler) ReconcileCertsSecret(ctx context.Context, hcp *tenancyv1
 		}
 		return nil, err
 	}
-	return nil, nil
+	return r.reconcileCertsSecretData(ctx, hcp, cfg.Domain, csecret, extraDNSName)
+}
+
+// reconcileCertsSecretData updates the existing certs secret, keeping its CA, when the external
+// certs referenced by the control plane differ from the ones it holds, and returns the updated
+// certs so that the kubeconfigs are generated again, or nil if the secret is unchanged. Without
+// external certs, the serving cert is issued again when it was external or when the external
+// host of the API server is not in its SANs. The host changes after creation with the ingress
+// hostname, spec.expose or the load balancer address.
+func (r *K8sReconciler) reconcileCertsSecretData(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, domain string, csecret *v1.Secret, extraDNSName string) (*certs.Certs, error) {
+	crts, err := certs.LoadCertsSecret(csecret)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.applyExternalCerts(ctx, hcp, crts); err != nil {
+		return nil, err
+	}
+	// a serving cert provided through spec.externalCerts is managed outside of kubeflex
+	if hcp.Spec.ExternalCerts == nil && (crts.HasExternalAPIServerCert() || extraDNSName != "" && !crts.APIServerCertHasHost(extraDNSName)) {
+		if err := crts.RegenerateAPIServerCert(ctx, apiServerDNSNames(hcp.Name, csecret.Namespace, domain, extraDNSName)); err != nil {
+			return nil, err
+		}
+	}
+	data := crts.GenerateCertsSecret(ctx, csecret.Namespace).Data
+	if secretDataEqual(csecret.Data, data) {
+		return nil, nil
+	}
+	csecret.Data = data
+	if err := r.Client.Update(context.TODO(), csecret, &client.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	return crts, nil
+}
+
+func secretDataEqual(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !bytes.Equal(w, v) {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *K8sReconciler) ReconcileKubeconfigSecret(ctx context.Context, crts *certs.Certs, conf *certs.ConfigGen, hcp *tenancyv1alpha1.ControlPlane) error {
 	// TODO - temp hack - we should make this independent of the certs gen.
 	// Should gen kconfig from certs secret otherwise it may fail if certs are not generated before this func
+	conf.CpNamespace = util.GenerateNamespaceFromControlPlaneName(conf.CpName)
 	if crts == nil {
-		return nil
+		return r.syncKubeconfigServer(ctx, conf, hcp)
 	}
 	_ = clog.FromContext(ctx)
-	namespace := util.GenerateNamespaceFromControlPlaneName(conf.CpName)
 
 	// create certs secret object
-	conf.CpNamespace = namespace
 	csecret, err := certs.GenerateKubeConfigSecret(ctx, crts, conf)
 	if err != nil {
 		return err
 	}
 
-	err = r.Client.Get(context.TODO(), client.ObjectKeyFromObject(csecret), csecret, &client.GetOptions{})
+	existing := &v1.Secret{}
+	err = r.Client.Get(context.TODO(), client.ObjectKeyFromObject(csecret), existing, &client.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			if err := controllerutil.SetControllerReference(hcp, csecret, r.Scheme); err != nil {
@@ -92,18 +141,86 @@ func (r *K8sReconciler) ReconcileKubeconfigSecret(ctx context.Context, crts *cer
 		}
 		return err
 	}
-	return nil
+	// the certs secret was updated, e.g. with a new serving cert or new external certs
+	existing.Data = csecret.Data
+	return r.Client.Update(context.TODO(), existing, &client.UpdateOptions{})
 }
 
-func generateCertsSecret(ctx context.Context, name, namespace, domain, extraDNSName string) (*v1.Secret, *certs.Certs, error) {
+// syncKubeconfigServer updates the existing admin kubeconfig when the API server endpoint
+// changes, e.g. after the ingress hostname is changed, or when the external admin cert is
+// rotated without changing its CA
+func (r *K8sReconciler) syncKubeconfigServer(ctx context.Context, conf *certs.ConfigGen, hcp *tenancyv1alpha1.ControlPlane) error {
+	if conf.Target != certs.Admin {
+		return nil
+	}
+	secret := &v1.Secret{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.AdminConfSecret}
+	if err := r.Client.Get(context.TODO(), key, secret, &client.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	changed := false
+	for _, dataKey := range []string{util.KubeconfigSecretKeyDefault, util.KubeconfigSecretKeyInCluster} {
+		if secret.Data[dataKey] == nil {
+			continue
+		}
+		// the in-cluster kubeconfig always points at the API server service
+		server := ""
+		if dataKey == util.KubeconfigSecretKeyDefault {
+			server = conf.ServerEndpoint()
+		}
+		data, err := syncKubeconfigData(secret.Data[dataKey], hcp.Name, server, conf)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			secret.Data[dataKey] = data
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return r.Client.Update(context.TODO(), secret, &client.UpdateOptions{})
+}
+
+// syncKubeconfigData returns the admin kubeconfig data with the server, if set, and the external
+// client cert of conf, or nil if they are already up to date
+func syncKubeconfigData(data []byte, cpName, server string, conf *certs.ConfigGen) ([]byte, error) {
+	konfig, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	if cluster, ok := konfig.Clusters[certs.GenerateClusterName(cpName)]; ok && server != "" && cluster.Server != server {
+		cluster.Server = server
+		changed = true
+	}
+	cert, key := conf.ExternalClientCert()
+	authInfo, ok := konfig.AuthInfos[certs.GenerateAuthInfoAdminName(cpName)]
+	if ok && cert != nil && (!bytes.Equal(authInfo.ClientCertificateData, cert) || !bytes.Equal(authInfo.ClientKeyData, key)) {
+		authInfo.ClientCertificateData = cert
+		authInfo.ClientKeyData = key
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	return clientcmd.Write(*konfig)
+}
+
+func generateCerts(ctx context.Context, name, namespace, domain, extraDNSName string) (*certs.Certs, error) {
+	return certs.New(ctx, apiServerDNSNames(name, namespace, domain, extraDNSName))
+}
+
+// apiServerDNSNames returns the names of the API server serving cert, besides the default ones
+func apiServerDNSNames(name, namespace, domain, extraDNSName string) []string {
 	extraDnsNames := util.GenerateHostedDNSName(namespace, name)
 	extraDnsNames = append(extraDnsNames, util.GenerateDevLocalDNSName(name, domain))
 	if extraDNSName != "" {
 		extraDnsNames = append(extraDnsNames, extraDNSName)
 	}
-	c, err := certs.New(ctx, extraDnsNames)
-	if err != nil {
-		return nil, nil, err
-	}
-	return c.GenerateCertsSecret(ctx, namespace), c, nil
+	return extraDnsNames
 }
@@ -0,0 +1,269 @@
+package k8s
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/certs"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileKubeconfigSecretSyncsServer(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:    tenancyv1alpha1.ControlPlaneTypeK8S,
+			Ingress: &tenancyv1alpha1.IngressSpec{Hostname: "cp1.example.com"},
+		},
+	}
+	konfig := clientcmdapi.NewConfig()
+	konfig.Clusters[certs.GenerateClusterName(hcp.Name)] = &clientcmdapi.Cluster{Server: "https://cp1.localtest.me:9443"}
+	data, err := clientcmd.Write(*konfig)
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	r, cl := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: namespace},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	// the certs already exist, so only the server of the kubeconfig is updated
+	conf := &certs.ConfigGen{
+		CpName:     hcp.Name,
+		CpPort:     9443,
+		CpExtraDNS: "cp1.example.com:9443",
+		Target:     certs.Admin,
+	}
+	ctx := context.Background()
+	if err := r.ReconcileKubeconfigSecret(ctx, nil, conf, hcp); err != nil {
+		t.Fatalf("ReconcileKubeconfigSecret returned error: %v", err)
+	}
+
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.AdminConfSecret}, secret); err != nil {
+		t.Fatalf("error getting kubeconfig secret: %v", err)
+	}
+	konfig, err = clientcmd.Load(secret.Data[util.KubeconfigSecretKeyDefault])
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	if server := konfig.Clusters[certs.GenerateClusterName(hcp.Name)].Server; server != "https://cp1.example.com:9443" {
+		t.Errorf("expected server https://cp1.example.com:9443, got %s", server)
+	}
+}
+
+func TestReconcileKubeconfigSecretSyncsInClusterServer(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:   tenancyv1alpha1.ControlPlaneTypeK8S,
+			Expose: tenancyv1alpha1.ExposeNone,
+		},
+	}
+	konfig := clientcmdapi.NewConfig()
+	konfig.Clusters[certs.GenerateClusterName(hcp.Name)] = &clientcmdapi.Cluster{Server: "https://cp1.localtest.me:9443"}
+	data, err := clientcmd.Write(*konfig)
+	if err != nil {
+		t.Fatalf("error serializing kubeconfig: %v", err)
+	}
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	r, cl := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: util.AdminConfSecret, Namespace: namespace},
+		Data:       map[string][]byte{util.KubeconfigSecretKeyDefault: data},
+	})
+
+	conf := &certs.ConfigGen{
+		CpName:        hcp.Name,
+		CpPort:        9443,
+		Target:        certs.Admin,
+		InClusterOnly: true,
+	}
+	ctx := context.Background()
+	if err := r.ReconcileKubeconfigSecret(ctx, nil, conf, hcp); err != nil {
+		t.Fatalf("ReconcileKubeconfigSecret returned error: %v", err)
+	}
+
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.AdminConfSecret}, secret); err != nil {
+		t.Fatalf("error getting kubeconfig secret: %v", err)
+	}
+	konfig, err = clientcmd.Load(secret.Data[util.KubeconfigSecretKeyDefault])
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	want := "https://cp1.cp1-system.svc.cluster.local"
+	if server := konfig.Clusters[certs.GenerateClusterName(hcp.Name)].Server; server != want {
+		t.Errorf("expected server %s, got %s", want, server)
+	}
+	if endpoint := conf.ServerEndpoint(); endpoint != want {
+		t.Errorf("expected endpoint %s, got %s", want, endpoint)
+	}
+}
+
+func TestReconcileKubeconfigSecretKeepsTarget(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	ctx := context.Background()
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	crts, err := generateCerts(ctx, hcp.Name, namespace, "localtest.me", "")
+	if err != nil {
+		t.Fatalf("error generating certs: %v", err)
+	}
+
+	// the certs were just generated, so the admin kubeconfig secret is created
+	conf := &certs.ConfigGen{
+		CpName:   hcp.Name,
+		CpHost:   hcp.Name,
+		CpDomain: "localtest.me",
+		CpPort:   9443,
+		Target:   certs.Admin,
+	}
+	if err := r.ReconcileKubeconfigSecret(ctx, crts, conf, hcp); err != nil {
+		t.Fatalf("ReconcileKubeconfigSecret returned error: %v", err)
+	}
+	if conf.Target != certs.Admin {
+		t.Errorf("expected the target to stay %d, got %d", certs.Admin, conf.Target)
+	}
+	if got, want := conf.ServerEndpoint(), "https://cp1.localtest.me:9443"; got != want {
+		t.Errorf("expected endpoint %s, got %s", want, got)
+	}
+
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.AdminConfSecret}, secret); err != nil {
+		t.Fatalf("error getting kubeconfig secret: %v", err)
+	}
+	for key, want := range map[string]string{
+		util.KubeconfigSecretKeyDefault:   "https://cp1.localtest.me:9443",
+		util.KubeconfigSecretKeyInCluster: "https://cp1.cp1-system.svc.cluster.local",
+	} {
+		konfig, err := clientcmd.Load(secret.Data[key])
+		if err != nil {
+			t.Fatalf("error loading kubeconfig %s: %v", key, err)
+		}
+		if server := konfig.Clusters[certs.GenerateClusterName(hcp.Name)].Server; server != want {
+			t.Errorf("expected server %s in %s, got %s", want, key, server)
+		}
+	}
+}
+
+func TestReconcileCertsSecretHostnameChange(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:    tenancyv1alpha1.ControlPlaneTypeK8S,
+			Ingress: &tenancyv1alpha1.IngressSpec{Hostname: "cp1.example.com"},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	ctx := context.Background()
+	cfg := &shared.SharedConfig{Domain: "localtest.me", ExternalPort: 9443}
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+
+	// reconciles the admin kubeconfig for the ingress hostname
+	reconcile := func(crts *certs.Certs) {
+		t.Helper()
+		conf := &certs.ConfigGen{
+			CpName:     hcp.Name,
+			CpHost:     hcp.Name,
+			CpDomain:   cfg.Domain,
+			CpPort:     cfg.ExternalPort,
+			CpExtraDNS: hcp.Spec.Ingress.Hostname + ":9443",
+			Target:     certs.Admin,
+		}
+		if err := r.ReconcileKubeconfigSecret(ctx, crts, conf, hcp); err != nil {
+			t.Fatalf("ReconcileKubeconfigSecret returned error: %v", err)
+		}
+	}
+	crts, err := r.ReconcileCertsSecret(ctx, hcp, cfg, hcp.Spec.Ingress.Hostname)
+	if err != nil {
+		t.Fatalf("ReconcileCertsSecret returned error: %v", err)
+	}
+	reconcile(crts)
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	before := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: certs.CertsSecretName}, before); err != nil {
+		t.Fatalf("error getting certs secret: %v", err)
+	}
+
+	hcp.Spec.Ingress.Hostname = "cp1.example.org"
+	crts, err = r.ReconcileCertsSecret(ctx, hcp, cfg, hcp.Spec.Ingress.Hostname)
+	if err != nil {
+		t.Fatalf("ReconcileCertsSecret returned error: %v", err)
+	}
+	if crts == nil {
+		t.Fatalf("expected the updated certs, to generate the kubeconfigs again")
+	}
+	reconcile(crts)
+	// the new serving cert is mounted by the API server, which is restarted
+	reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+
+	after := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: certs.CertsSecretName}, after); err != nil {
+		t.Fatalf("error getting certs secret: %v", err)
+	}
+	if string(after.Data["ca.crt"]) != string(before.Data["ca.crt"]) || string(after.Data["ca.key"]) != string(before.Data["ca.key"]) {
+		t.Errorf("expected the CA to be kept")
+	}
+
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.AdminConfSecret}, secret); err != nil {
+		t.Fatalf("error getting kubeconfig secret: %v", err)
+	}
+	konfig, err := clientcmd.Load(secret.Data[util.KubeconfigSecretKeyDefault])
+	if err != nil {
+		t.Fatalf("error loading kubeconfig: %v", err)
+	}
+	cluster := konfig.Clusters[certs.GenerateClusterName(hcp.Name)]
+	if cluster.Server != "https://cp1.example.org:9443" {
+		t.Errorf("expected server https://cp1.example.org:9443, got %s", cluster.Server)
+	}
+
+	// the kubeconfig trusts the new serving cert for its server
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(cluster.CertificateAuthorityData) {
+		t.Fatalf("error parsing the CA of the kubeconfig")
+	}
+	block, _ := pem.Decode(after.Data["apiserver.crt"])
+	if block == nil {
+		t.Fatalf("error decoding the API server certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("error parsing the API server certificate: %v", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{DNSName: "cp1.example.org", Roots: roots}); err != nil {
+		t.Errorf("expected the API server certificate to be valid for the new hostname: %v", err)
+	}
+
+	// an unchanged hostname keeps the serving cert
+	crts, err = r.ReconcileCertsSecret(ctx, hcp, cfg, hcp.Spec.Ingress.Hostname)
+	if err != nil {
+		t.Fatalf("ReconcileCertsSecret returned error: %v", err)
+	}
+	if crts != nil {
+		t.Errorf("expected no certs for an unchanged certs secret")
+	}
+	unchanged := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: certs.CertsSecretName}, unchanged); err != nil {
+		t.Fatalf("error getting certs secret: %v", err)
+	}
+	if unchanged.ResourceVersion != after.ResourceVersion {
+		t.Errorf("expected the certs secret not to be updated for an unchanged hostname")
+	}
+}
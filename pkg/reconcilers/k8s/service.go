@@ -18,6 +18,9 @@ package k8s
 
 import (
 	"context"
+	"net"
+	"sort"
+	"strconv"
 
 	"github.com/kubestellar/kubeflex/pkg/util"
 	corev1 "k8s.io/api/core/v1"
@@ -32,6 +35,8 @@ import (
 	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
 )
 
+const konnectivityServicePortName = "konnectivity"
+
 func (r *K8sReconciler) ReconcileAPIServerService(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
 	_ = clog.FromContext(ctx)
 	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
@@ -47,7 +52,7 @@ func (r *K8sReconciler) ReconcileAPIServerService(ctx context.Context, hcp *tena
 	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(service), service, &client.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			service := generateAPIServerService(hcp.Name, namespace)
+			service := generateAPIServerService(hcp.Name, namespace, hcp.Spec.EgressSelector, hcp.Spec.Expose)
 			if err := controllerutil.SetControllerReference(hcp, service, r.Scheme); err != nil {
 				return nil
 			}
@@ -58,11 +63,135 @@ func (r *K8sReconciler) ReconcileAPIServerService(ctx context.Context, hcp *tena
 		}
 		return err
 	}
+
+	updated := reconcileKonnectivityServicePort(service, hcp.Spec.EgressSelector)
+
+	// switching between node port and load balancer keeps the assigned node ports, while a
+	// cluster IP service takes none
+	if serviceType := apiServerServiceType(hcp.Spec.Expose); service.Spec.Type != serviceType {
+		service.Spec.Type = serviceType
+		if serviceType == corev1.ServiceTypeClusterIP {
+			for i := range service.Spec.Ports {
+				service.Spec.Ports[i].NodePort = 0
+			}
+		}
+		updated = true
+	}
+	if updated {
+		return r.Client.Update(context.TODO(), service, &client.UpdateOptions{})
+	}
 	return nil
 }
 
-func generateAPIServerService(name, namespace string) *corev1.Service {
-	return &corev1.Service{
+// reconcileKonnectivityServicePort adds the konnectivity agent port to service when konnectivity
+// is enabled and removes it otherwise. It returns true when the ports were changed.
+func reconcileKonnectivityServicePort(service *corev1.Service, egress *tenancyv1alpha1.EgressSelectorSpec) bool {
+	want := egress != nil && egress.Konnectivity
+	ports := make([]corev1.ServicePort, 0, len(service.Spec.Ports)+1)
+	found := false
+	for _, port := range service.Spec.Ports {
+		if port.Name == konnectivityServicePortName {
+			found = true
+			if !want {
+				continue
+			}
+		}
+		ports = append(ports, port)
+	}
+	if found == want {
+		return false
+	}
+	if want {
+		ports = append(ports, konnectivityServicePort())
+	}
+	service.Spec.Ports = ports
+	return true
+}
+
+func konnectivityServicePort() corev1.ServicePort {
+	return corev1.ServicePort{
+		Port:       KonnectivityAgentPort,
+		TargetPort: intstr.FromInt(KonnectivityAgentPort),
+		Name:       konnectivityServicePortName,
+		Protocol:   "TCP",
+	}
+}
+
+// GetAPIServerServiceEndpoint returns the endpoint of the API server when it is exposed through
+// a node port or a load balancer, in the host:port form, and the host alone. The node port is
+// recorded in the control plane status. An empty endpoint is returned until the node port or
+// the load balancer address is assigned.
+func (r *K8sReconciler) GetAPIServerServiceEndpoint(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (string, string, error) {
+	_ = clog.FromContext(ctx)
+	service := &corev1.Service{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: hcp.Name}
+	if err := r.Client.Get(context.TODO(), key, service, &client.GetOptions{}); err != nil {
+		return "", "", err
+	}
+
+	if hcp.Spec.Expose == tenancyv1alpha1.ExposeLoadBalancer {
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			host := ingress.IP
+			if host == "" {
+				host = ingress.Hostname
+			}
+			if host != "" {
+				return net.JoinHostPort(host, strconv.Itoa(shared.DefaulPort)), host, nil
+			}
+		}
+		return "", "", nil
+	}
+
+	var nodePort int32
+	for _, port := range service.Spec.Ports {
+		if port.Name == "https" {
+			nodePort = port.NodePort
+		}
+	}
+	if nodePort == 0 {
+		return "", "", nil
+	}
+	host, err := r.getNodeAddress(ctx)
+	if err != nil || host == "" {
+		return "", "", err
+	}
+	hcp.Status.NodePort = nodePort
+	return net.JoinHostPort(host, strconv.Itoa(int(nodePort))), host, nil
+}
+
+// getNodeAddress returns the address of the first hosting cluster node, preferring
+// external addresses over internal ones
+func (r *K8sReconciler) getNodeAddress(ctx context.Context) (string, error) {
+	nodes := &corev1.NodeList{}
+	if err := r.Client.List(context.TODO(), nodes); err != nil {
+		return "", err
+	}
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	for _, addressType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+		for _, node := range nodes.Items {
+			for _, address := range node.Status.Addresses {
+				if address.Type == addressType && address.Address != "" {
+					return address.Address, nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+func apiServerServiceType(expose tenancyv1alpha1.ExposeType) corev1.ServiceType {
+	switch expose {
+	case tenancyv1alpha1.ExposeLoadBalancer:
+		return corev1.ServiceTypeLoadBalancer
+	case tenancyv1alpha1.ExposeNone:
+		return corev1.ServiceTypeClusterIP
+	default:
+		return corev1.ServiceTypeNodePort
+	}
+}
+
+func generateAPIServerService(name, namespace string, egress *tenancyv1alpha1.EgressSelectorSpec, expose tenancyv1alpha1.ExposeType) *corev1.Service {
+	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: namespace,
@@ -71,7 +200,7 @@ func generateAPIServerService(name, namespace string) *corev1.Service {
 			Selector: map[string]string{
 				"app": util.APIServerDeploymentName,
 			},
-			Type: corev1.ServiceTypeNodePort,
+			Type: apiServerServiceType(expose),
 			Ports: []corev1.ServicePort{
 				{
 					Port:       shared.DefaulPort,
@@ -82,4 +211,8 @@ func generateAPIServerService(name, namespace string) *corev1.Service {
 			},
 		},
 	}
+	if egress != nil && egress.Konnectivity {
+		service.Spec.Ports = append(service.Spec.Ports, konnectivityServicePort())
+	}
+	return service
 }
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/keyutil"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	SASigningKeySecretName = "sa-signing-key"
+	SASigningKeyKey        = "sa.key"
+	SAPublicKeyKey         = "sa.pub"
+	SASigningKeyMountPath  = "/etc/kubernetes/service-account"
+	saSigningKeyVolumeName = "sa-signing-key"
+)
+
+// ReconcileServiceAccountSigningKey validates the service account issuer and copies the
+// referenced signing key, together with its public key, into the control plane namespace,
+// where it is mounted by the API server and the controller manager
+func (r *K8sReconciler) ReconcileServiceAccountSigningKey(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	issuer := hcp.Spec.ServiceAccountIssuer
+	if issuer == nil {
+		return nil
+	}
+	if err := ValidateServiceAccountIssuerURL(issuer.URL); err != nil {
+		return err
+	}
+	if issuer.SigningKeySecretRef == nil {
+		return nil
+	}
+
+	data, err := r.GetSecretKeyData(ctx, *issuer.SigningKeySecretRef)
+	if err != nil {
+		return err
+	}
+	publicKey, err := ServiceAccountPublicKey(data)
+	if err != nil {
+		return err
+	}
+	return r.ReconcileControlPlaneSecret(ctx, hcp, SASigningKeySecretName, v1.SecretTypeOpaque, map[string][]byte{
+		SASigningKeyKey: data,
+		SAPublicKeyKey:  publicKey,
+	})
+}
+
+// ValidateServiceAccountIssuerURL checks that issuer is an https URL without query or
+// fragment, as required for OIDC discovery
+func ValidateServiceAccountIssuerURL(issuer string) error {
+	return validateIssuerURL("service account issuer", issuer)
+}
+
+func validateIssuerURL(kind, issuer string) error {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %s", kind, issuer, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s %q must be an https URL", kind, issuer)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%s %q must not have a query or fragment", kind, issuer)
+	}
+	return nil
+}
+
+// ServiceAccountPublicKey parses a PEM encoded RSA or ECDSA private key and returns
+// its PEM encoded public key
+func ServiceAccountPublicKey(data []byte) ([]byte, error) {
+	key, err := keyutil.ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing service account signing key: %s", err)
+	}
+	var public crypto.PublicKey
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		public = &k.PublicKey
+	default:
+		return nil, fmt.Errorf("service account signing key must be an RSA or ECDSA key")
+	}
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: keyutil.PublicKeyBlockType, Bytes: der}), nil
+}
+
+// configureServiceAccountIssuer sets the service account issuer of the API server and,
+// when a signing key is referenced, mounts it in place of the generated one
+func configureServiceAccountIssuer(deployment *appsv1.Deployment, issuer *tenancyv1alpha1.ServiceAccountIssuerSpec) {
+	if issuer == nil {
+		return
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	apiServer := findContainer(podSpec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+
+	setFlag(apiServer, "--service-account-issuer", issuer.URL)
+	if issuer.SigningKeySecretRef == nil {
+		return
+	}
+	setFlag(apiServer, "--service-account-key-file", fmt.Sprintf("%s/%s", SASigningKeyMountPath, SAPublicKeyKey))
+	setFlag(apiServer, "--service-account-signing-key-file", fmt.Sprintf("%s/%s", SASigningKeyMountPath, SASigningKeyKey))
+	mountServiceAccountSigningKey(podSpec, apiServer)
+}
+
+// configureServiceAccountSigningKey makes the controller manager sign the service account
+// token secrets with the referenced signing key
+func configureServiceAccountSigningKey(deployment *appsv1.Deployment, issuer *tenancyv1alpha1.ServiceAccountIssuerSpec) {
+	if issuer == nil || issuer.SigningKeySecretRef == nil {
+		return
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	cm := findContainer(podSpec, util.CMDeploymentName)
+	if cm == nil {
+		return
+	}
+	setFlag(cm, "--service-account-private-key-file", fmt.Sprintf("%s/%s", SASigningKeyMountPath, SASigningKeyKey))
+	mountServiceAccountSigningKey(podSpec, cm)
+}
+
+func mountServiceAccountSigningKey(podSpec *v1.PodSpec, container *v1.Container) {
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		MountPath: SASigningKeyMountPath,
+		Name:      saSigningKeyVolumeName,
+		ReadOnly:  true,
+	})
+	podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+		Name: saSigningKeyVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{
+				SecretName: SASigningKeySecretName,
+			},
+		},
+	})
+}
+
+// setFlag replaces the value of a flag in the container command, or appends the flag
+func setFlag(container *v1.Container, flag, value string) {
+	arg := fmt.Sprintf("%s=%s", flag, value)
+	for i, c := range container.Command {
+		if strings.HasPrefix(c, flag+"=") {
+			container.Command[i] = arg
+			return
+		}
+	}
+	container.Command = append(container.Command, arg)
+}
@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileServiceAccountIssuer(t *testing.T) {
+	signingKey := generateTestSigningKey(t)
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			ServiceAccountIssuer: &tenancyv1alpha1.ServiceAccountIssuerSpec{
+				URL: "https://oidc.example.com/cp1",
+				SigningKeySecretRef: &tenancyv1alpha1.SecretKeyReference{
+					Namespace: "default",
+					Name:      "sa-key",
+					Key:       "key.pem",
+				},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sa-key", Namespace: "default"},
+		Data:       map[string][]byte{"key.pem": signingKey},
+	})
+
+	ctx := context.Background()
+	if err := r.ReconcileServiceAccountSigningKey(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileServiceAccountSigningKey returned error: %v", err)
+	}
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	if err := r.ReconcileCMDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileCMDeployment returned error: %v", err)
+	}
+
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	secret := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: SASigningKeySecretName}, secret); err != nil {
+		t.Fatalf("expected signing key to be copied: %v", err)
+	}
+	if string(secret.Data[SASigningKeyKey]) != string(signingKey) {
+		t.Errorf("expected the signing key to be copied verbatim")
+	}
+	if !strings.Contains(string(secret.Data[SAPublicKeyKey]), "PUBLIC KEY") {
+		t.Errorf("expected the public key to be derived from the signing key")
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.APIServerDeploymentName}, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+	for _, flag := range []string{
+		"--service-account-issuer=https://oidc.example.com/cp1",
+		fmt.Sprintf("--service-account-key-file=%s/%s", SASigningKeyMountPath, SAPublicKeyKey),
+		fmt.Sprintf("--service-account-signing-key-file=%s/%s", SASigningKeyMountPath, SASigningKeyKey),
+	} {
+		if !hasString(apiServer.Command, flag) {
+			t.Errorf("expected apiserver command to contain %s", flag)
+		}
+	}
+	if hasString(apiServer.Command, "--service-account-issuer=https://kubernetes.default.svc.cluster.local") {
+		t.Errorf("expected the default service account issuer to be replaced")
+	}
+	if !hasMount(apiServer.VolumeMounts, saSigningKeyVolumeName, SASigningKeyMountPath) {
+		t.Errorf("expected signing key to be mounted at %s in the apiserver", SASigningKeyMountPath)
+	}
+
+	cmDeployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.CMDeploymentName}, cmDeployment); err != nil {
+		t.Fatalf("error getting controller manager deployment: %v", err)
+	}
+	cm := findContainer(&cmDeployment.Spec.Template.Spec, util.CMDeploymentName)
+	if cm == nil {
+		t.Fatalf("controller manager container not found")
+	}
+	if !hasString(cm.Command, fmt.Sprintf("--service-account-private-key-file=%s/%s", SASigningKeyMountPath, SASigningKeyKey)) {
+		t.Errorf("expected controller manager to sign tokens with the referenced key")
+	}
+	if !hasMount(cm.VolumeMounts, saSigningKeyVolumeName, SASigningKeyMountPath) {
+		t.Errorf("expected signing key to be mounted at %s in the controller manager", SASigningKeyMountPath)
+	}
+}
+
+func TestReconcileDeploymentsServiceAccountIssuerUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+	if err := r.ReconcileCMDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileCMDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.ServiceAccountIssuer = &tenancyv1alpha1.ServiceAccountIssuerSpec{
+		URL:                 "https://oidc.example.com/cp1",
+		SigningKeySecretRef: &tenancyv1alpha1.SecretKeyReference{Namespace: "default", Name: "sa-key", Key: "key.pem"},
+	}
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if !hasString(apiServer.Command, fmt.Sprintf("--service-account-key-file=%s/%s", SASigningKeyMountPath, SAPublicKeyKey)) {
+		t.Errorf("expected apiserver to verify tokens with the referenced key after the spec change")
+	}
+
+	if err := r.ReconcileCMDeployment(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileCMDeployment returned error: %v", err)
+	}
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	cmDeployment := &appsv1.Deployment{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: util.CMDeploymentName}, cmDeployment); err != nil {
+		t.Fatalf("error getting controller manager deployment: %v", err)
+	}
+	cm := findContainer(&cmDeployment.Spec.Template.Spec, util.CMDeploymentName)
+	if !hasString(cm.Command, fmt.Sprintf("--service-account-private-key-file=%s/%s", SASigningKeyMountPath, SASigningKeyKey)) {
+		t.Errorf("expected controller manager to sign tokens with the referenced key after the spec change")
+	}
+	if !hasMount(cm.VolumeMounts, saSigningKeyVolumeName, SASigningKeyMountPath) || !hasVolume(&cmDeployment.Spec.Template.Spec, saSigningKeyVolumeName) {
+		t.Errorf("expected signing key to be mounted in the controller manager after the spec change")
+	}
+}
+
+func TestValidateServiceAccountIssuer(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  string
+		wantErr bool
+	}{
+		{name: "valid", issuer: "https://oidc.example.com"},
+		{name: "valid with path", issuer: "https://oidc.example.com/cp1"},
+		{name: "http", issuer: "http://oidc.example.com", wantErr: true},
+		{name: "not a url", issuer: "kubernetes", wantErr: true},
+		{name: "query", issuer: "https://oidc.example.com?a=b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateServiceAccountIssuerURL(tt.issuer)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateServiceAccountIssuerURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ServiceAccountPublicKey([]byte("not a key")); err == nil {
+		t.Errorf("expected error for a signing key that does not parse")
+	}
+}
+
+func generateTestSigningKey(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshaling key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
@@ -0,0 +1,210 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileAPIServerServiceExpose(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:   tenancyv1alpha1.ControlPlaneTypeK8S,
+			Expose: tenancyv1alpha1.ExposeLoadBalancer,
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerService(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerService returned error: %v", err)
+	}
+	assertServiceType(t, cl, hcp.Name, v1.ServiceTypeLoadBalancer)
+
+	hcp.Spec.Expose = tenancyv1alpha1.ExposeNodePort
+	if err := r.ReconcileAPIServerService(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerService returned error: %v", err)
+	}
+	assertServiceType(t, cl, hcp.Name, v1.ServiceTypeNodePort)
+
+	// a service that is not exposed drops its node ports
+	service := &v1.Service{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: hcp.Name}
+	if err := cl.Get(ctx, key, service); err != nil {
+		t.Fatalf("error getting service: %v", err)
+	}
+	service.Spec.Ports[0].NodePort = 31443
+	if err := cl.Update(ctx, service); err != nil {
+		t.Fatalf("error updating service: %v", err)
+	}
+	hcp.Spec.Expose = tenancyv1alpha1.ExposeNone
+	if err := r.ReconcileAPIServerService(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerService returned error: %v", err)
+	}
+	assertServiceType(t, cl, hcp.Name, v1.ServiceTypeClusterIP)
+	if err := cl.Get(ctx, key, service); err != nil {
+		t.Fatalf("error getting service: %v", err)
+	}
+	for _, port := range service.Spec.Ports {
+		if port.NodePort != 0 {
+			t.Errorf("expected no node port on the cluster IP service, got %d", port.NodePort)
+		}
+	}
+}
+
+func TestReconcileAPIServerServiceKonnectivityPort(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerService(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerService returned error: %v", err)
+	}
+	assertKonnectivityPort(t, cl, hcp.Name, false)
+
+	hcp.Spec.EgressSelector = &tenancyv1alpha1.EgressSelectorSpec{Konnectivity: true}
+	if err := r.ReconcileAPIServerService(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerService returned error: %v", err)
+	}
+	assertKonnectivityPort(t, cl, hcp.Name, true)
+
+	hcp.Spec.EgressSelector.Konnectivity = false
+	if err := r.ReconcileAPIServerService(ctx, hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerService returned error: %v", err)
+	}
+	assertKonnectivityPort(t, cl, hcp.Name, false)
+}
+
+func TestGetAPIServerServiceEndpointNodePort(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:   tenancyv1alpha1.ControlPlaneTypeK8S,
+			Expose: tenancyv1alpha1.ExposeNodePort,
+		},
+	}
+	service := generateAPIServerService(hcp.Name, util.GenerateNamespaceFromControlPlaneName(hcp.Name), nil, hcp.Spec.Expose)
+	r, cl := newTestReconciler(t, hcp, service)
+
+	// the endpoint is empty until the node port is assigned
+	ctx := context.Background()
+	endpoint, _, err := r.GetAPIServerServiceEndpoint(ctx, hcp)
+	if err != nil {
+		t.Fatalf("GetAPIServerServiceEndpoint returned error: %v", err)
+	}
+	if endpoint != "" {
+		t.Errorf("expected empty endpoint before the node port is assigned, got %s", endpoint)
+	}
+
+	service.Spec.Ports[0].NodePort = 30443
+	if err := cl.Update(ctx, service); err != nil {
+		t.Fatalf("error updating service: %v", err)
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "kind-control-plane"},
+		Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+			{Type: v1.NodeHostName, Address: "kind-control-plane"},
+			{Type: v1.NodeInternalIP, Address: "172.18.0.2"},
+		}},
+	}
+	if err := cl.Create(ctx, node); err != nil {
+		t.Fatalf("error creating node: %v", err)
+	}
+
+	endpoint, host, err := r.GetAPIServerServiceEndpoint(ctx, hcp)
+	if err != nil {
+		t.Fatalf("GetAPIServerServiceEndpoint returned error: %v", err)
+	}
+	if endpoint != "172.18.0.2:30443" || host != "172.18.0.2" {
+		t.Errorf("expected endpoint 172.18.0.2:30443 and host 172.18.0.2, got %s and %s", endpoint, host)
+	}
+	if hcp.Status.NodePort != 30443 {
+		t.Errorf("expected node port 30443 in status, got %d", hcp.Status.NodePort)
+	}
+}
+
+func TestGetAPIServerServiceEndpointLoadBalancer(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:   tenancyv1alpha1.ControlPlaneTypeK8S,
+			Expose: tenancyv1alpha1.ExposeLoadBalancer,
+		},
+	}
+	service := generateAPIServerService(hcp.Name, util.GenerateNamespaceFromControlPlaneName(hcp.Name), nil, hcp.Spec.Expose)
+	service.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{Hostname: "cp1.elb.example.com"}}
+	r, _ := newTestReconciler(t, hcp, service)
+
+	endpoint, host, err := r.GetAPIServerServiceEndpoint(context.Background(), hcp)
+	if err != nil {
+		t.Fatalf("GetAPIServerServiceEndpoint returned error: %v", err)
+	}
+	if endpoint != "cp1.elb.example.com:443" || host != "cp1.elb.example.com" {
+		t.Errorf("expected endpoint cp1.elb.example.com:443 and host cp1.elb.example.com, got %s and %s", endpoint, host)
+	}
+}
+
+func TestGetAPIServerServiceEndpointLoadBalancerPending(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:   tenancyv1alpha1.ControlPlaneTypeK8S,
+			Expose: tenancyv1alpha1.ExposeLoadBalancer,
+		},
+	}
+	// the load balancer controller has not assigned an address yet
+	service := generateAPIServerService(hcp.Name, util.GenerateNamespaceFromControlPlaneName(hcp.Name), nil, hcp.Spec.Expose)
+	service.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{}}
+	r, _ := newTestReconciler(t, hcp, service)
+
+	endpoint, host, err := r.GetAPIServerServiceEndpoint(context.Background(), hcp)
+	if err != nil {
+		t.Fatalf("GetAPIServerServiceEndpoint returned error: %v", err)
+	}
+	if endpoint != "" || host != "" {
+		t.Errorf("expected no endpoint while the load balancer is pending, got %s and %s", endpoint, host)
+	}
+}
+
+func assertServiceType(t *testing.T, cl client.Client, name string, expected v1.ServiceType) {
+	t.Helper()
+	service := &v1.Service{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(name), Name: name}
+	if err := cl.Get(context.Background(), key, service); err != nil {
+		t.Fatalf("error getting apiserver service: %v", err)
+	}
+	if service.Spec.Type != expected {
+		t.Errorf("expected service type %s, got %s", expected, service.Spec.Type)
+	}
+}
+
+func assertKonnectivityPort(t *testing.T, cl client.Client, name string, expected bool) {
+	t.Helper()
+	service := &v1.Service{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(name), Name: name}
+	if err := cl.Get(context.Background(), key, service); err != nil {
+		t.Fatalf("error getting service: %v", err)
+	}
+	found := false
+	for _, port := range service.Spec.Ports {
+		if port.Name == konnectivityServicePortName && port.Port == KonnectivityAgentPort {
+			found = true
+		}
+	}
+	if found != expected {
+		t.Errorf("expected konnectivity port on the service: %t, ports %v", expected, service.Spec.Ports)
+	}
+	if len(service.Spec.Ports) == 0 || service.Spec.Ports[0].Name != "https" {
+		t.Errorf("expected the https port to be kept, ports %v", service.Spec.Ports)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// watchCacheResourceRegexp matches a lowercase resource name optionally followed by its API group
+var watchCacheResourceRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// ValidateWatchCache checks that the watch cache sizes are not negative and that each
+// resource is a valid resource[.group] that appears only once
+func ValidateWatchCache(watchCache *tenancyv1alpha1.WatchCacheSpec) error {
+	if watchCache == nil {
+		return nil
+	}
+	if watchCache.DefaultSize != nil && *watchCache.DefaultSize < 0 {
+		return fmt.Errorf("default watch cache size %d must not be negative", *watchCache.DefaultSize)
+	}
+	seen := map[string]bool{}
+	for _, size := range watchCache.Sizes {
+		if !watchCacheResourceRegexp.MatchString(size.Resource) {
+			return fmt.Errorf("invalid watch cache resource %q, expected resource[.group]", size.Resource)
+		}
+		if size.Size < 0 {
+			return fmt.Errorf("watch cache size %d for %s must not be negative", size.Size, size.Resource)
+		}
+		if seen[size.Resource] {
+			return fmt.Errorf("duplicate watch cache size for %s", size.Resource)
+		}
+		seen[size.Resource] = true
+	}
+	return nil
+}
+
+// configureWatchCache passes the watch cache sizes to the API server
+func configureWatchCache(deployment *appsv1.Deployment, watchCache *tenancyv1alpha1.WatchCacheSpec) {
+	if watchCache == nil {
+		return
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		return
+	}
+
+	if watchCache.DefaultSize != nil {
+		apiServer.Command = append(apiServer.Command, fmt.Sprintf("--default-watch-cache-size=%d", *watchCache.DefaultSize))
+	}
+	if len(watchCache.Sizes) > 0 {
+		sizes := make([]string, 0, len(watchCache.Sizes))
+		for _, size := range watchCache.Sizes {
+			sizes = append(sizes, fmt.Sprintf("%s#%d", size.Resource, size.Size))
+		}
+		apiServer.Command = append(apiServer.Command, fmt.Sprintf("--watch-cache-sizes=%s", strings.Join(sizes, ",")))
+	}
+}
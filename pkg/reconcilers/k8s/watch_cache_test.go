@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileAPIServerDeploymentWatchCache(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			WatchCache: &tenancyv1alpha1.WatchCacheSpec{
+				DefaultSize: pointer.Int32(50),
+				Sizes: []tenancyv1alpha1.WatchCacheSize{
+					{Resource: "pods", Size: 1000},
+					{Resource: "deployments.apps", Size: 0},
+				},
+			},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerDeployment(ctx, hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: util.APIServerDeploymentName}
+	if err := cl.Get(ctx, key, deployment); err != nil {
+		t.Fatalf("error getting apiserver deployment: %v", err)
+	}
+	apiServer := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName)
+	if apiServer == nil {
+		t.Fatalf("apiserver container not found")
+	}
+	for _, flag := range []string{
+		"--default-watch-cache-size=50",
+		"--watch-cache-sizes=pods#1000,deployments.apps#0",
+	} {
+		if !hasString(apiServer.Command, flag) {
+			t.Errorf("expected apiserver command to contain %s", flag)
+		}
+	}
+}
+
+func TestReconcileAPIServerDeploymentWatchCacheUpdate(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:       tenancyv1alpha1.ControlPlaneTypeK8S,
+			WatchCache: &tenancyv1alpha1.WatchCacheSpec{DefaultSize: pointer.Int32(50)},
+		},
+	}
+	r, cl := newTestReconciler(t, hcp)
+	if err := r.ReconcileAPIServerDeployment(context.Background(), hcp, false); err != nil {
+		t.Fatalf("ReconcileAPIServerDeployment returned error: %v", err)
+	}
+
+	hcp.Spec.WatchCache.DefaultSize = pointer.Int32(200)
+	deployment := reconcileAPIServerDeploymentUpdate(t, r, cl, hcp)
+	command := findContainer(&deployment.Spec.Template.Spec, util.APIServerDeploymentName).Command
+	if !hasString(command, "--default-watch-cache-size=200") || hasString(command, "--default-watch-cache-size=50") {
+		t.Errorf("expected apiserver command to switch to --default-watch-cache-size=200, got %v", command)
+	}
+}
+
+func TestValidateWatchCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		watchCache *tenancyv1alpha1.WatchCacheSpec
+		wantErr    bool
+	}{
+		{name: "nil"},
+		{name: "valid", watchCache: &tenancyv1alpha1.WatchCacheSpec{
+			DefaultSize: pointer.Int32(100),
+			Sizes:       []tenancyv1alpha1.WatchCacheSize{{Resource: "pods", Size: 10}, {Resource: "leases.coordination.k8s.io", Size: 0}},
+		}},
+		{name: "negative default", watchCache: &tenancyv1alpha1.WatchCacheSpec{DefaultSize: pointer.Int32(-1)}, wantErr: true},
+		{name: "negative size", watchCache: &tenancyv1alpha1.WatchCacheSpec{
+			Sizes: []tenancyv1alpha1.WatchCacheSize{{Resource: "pods", Size: -1}},
+		}, wantErr: true},
+		{name: "invalid resource", watchCache: &tenancyv1alpha1.WatchCacheSpec{
+			Sizes: []tenancyv1alpha1.WatchCacheSize{{Resource: "pods#10", Size: 10}},
+		}, wantErr: true},
+		{name: "uppercase resource", watchCache: &tenancyv1alpha1.WatchCacheSpec{
+			Sizes: []tenancyv1alpha1.WatchCacheSize{{Resource: "Pods", Size: 10}},
+		}, wantErr: true},
+		{name: "duplicate resource", watchCache: &tenancyv1alpha1.WatchCacheSpec{
+			Sizes: []tenancyv1alpha1.WatchCacheSize{{Resource: "pods", Size: 10}, {Resource: "pods", Size: 20}},
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWatchCache(tt.watchCache)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWatchCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -19,7 +19,9 @@ package ocm
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
 	"github.com/kubestellar/kubeflex/pkg/helm"
@@ -32,6 +34,10 @@ const (
 	RepoName    = "multicluster-controlplane"
 	ChartName   = "multicluster-controlplane-chart"
 	ReleaseName = "multicluster-controlplane"
+	// resourcesValuesPath is the values path of the resources of the controlplane container
+	resourcesValuesPath = "resources"
+	// replicasValuesPath is the values path of the replica count of the controlplane deployment
+	replicasValuesPath = "replicas"
 )
 
 var (
@@ -44,32 +50,70 @@ var (
 )
 
 func (r *OCMReconciler) ReconcileChart(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, cfg *shared.SharedConfig) error {
-	dnsName := util.GenerateDevLocalDNSName(hcp.Name, cfg.Domain)
+	dnsName := shared.GetAPIServerHostname(hcp, cfg.Domain)
 	port := cfg.ExternalPort
+	// copy the defaults so that per control plane values do not leak into the package level configs
+	configs := append([]string{}, configs...)
 	if cfg.ExternalURL != "" {
 		dnsName = cfg.ExternalURL
 		port = 443
 	}
 	configs = append(configs, fmt.Sprintf("apiserver.externalHostname=%s", dnsName))
 	configs = append(configs, fmt.Sprintf("apiserver.port=%d", port))
-	h := &helm.HelmHandler{
-		URL:         URL,
-		RepoName:    RepoName,
-		ChartName:   ChartName,
-		Namespace:   util.GenerateNamespaceFromControlPlaneName(hcp.Name),
-		ReleaseName: ReleaseName,
-		Args:        map[string]string{"set": strings.Join(configs, ",")},
+	configs = append(configs, shared.GetImagePullSecretsHelmValues(hcp)...)
+	configs = append(configs, shared.GetReplicasHelmValues(hcp, replicasValuesPath)...)
+	configs = append(configs, hubConfigs(hcp.Spec.OCM)...)
+	keyring, err := r.WriteChartKeyring(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	if keyring != "" {
+		defer os.Remove(keyring)
 	}
-	err := helm.Init(ctx, h)
+	username, password, err := r.GetChartRegistryCredentials(ctx, hcp)
 	if err != nil {
 		return err
 	}
-
-	if !h.IsDeployed() {
-		err := h.Install()
-		if err != nil {
+	values, err := r.GetValuesFrom(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	url := URL
+	if hcp.Spec.Chart != nil {
+		url = hcp.Spec.Chart.URL
+	}
+	args := map[string]string{
+		"set":        strings.Join(configs, ","),
+		"set-string": strings.Join(shared.GetResourcesHelmValues(hcp, resourcesValuesPath), ","),
+	}
+	h := &helm.HelmHandler{
+		URL:              url,
+		RepoName:         RepoName,
+		ChartName:        ChartName,
+		Namespace:        util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+		ReleaseName:      ReleaseName,
+		Args:             args,
+		Values:           values,
+		Keyring:          keyring,
+		RegistryUsername: username,
+		RegistryPassword: password,
+	}
+	return shared.RetryChart(ctx, cfg.ChartRetry, func() error {
+		if err := helm.Init(ctx, h); err != nil {
 			return err
 		}
-	}
-	return nil
+		defer shared.SetChartReleasedCondition(hcp, h)
+		if !h.IsDeployed() {
+			start := time.Now()
+			err := h.Install()
+			shared.ObserveChartInstall(hcp, ChartName, start)
+			if err != nil {
+				return fmt.Errorf("error installing chart %s: %w", url, err)
+			}
+			hcp.Status.ValuesFromHash = shared.ValuesFromHash(values)
+			r.RecordNormalEvent(hcp, shared.EventReasonChartInstalled, "Installed chart %s as release %s", url, ReleaseName)
+			return nil
+		}
+		return r.UpgradeChartOnValuesChange(hcp, h)
+	})
 }
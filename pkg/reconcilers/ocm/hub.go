@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+const (
+	// featuresValue is the chart value holding the feature gates of the hub as a comma
+	// separated list of name=bool pairs
+	featuresValue = "features"
+	// autoApprovalUsersValue is the chart value holding the comma separated users whose
+	// registration requests are accepted without approval
+	autoApprovalUsersValue = "autoApprovalBootstrapUsers"
+)
+
+var featureGateNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// ValidateOCMSpec checks that the feature gate names are alphanumeric and that the auto
+// approval users are not empty and hold no commas, as both are passed as comma separated lists
+func ValidateOCMSpec(spec *tenancyv1alpha1.OCMSpec) error {
+	if spec == nil {
+		return nil
+	}
+	for name := range spec.FeatureGates {
+		if !featureGateNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid ocm feature gate %q: must be alphanumeric and start with a letter", name)
+		}
+	}
+	for _, user := range spec.AutoApprovalUsers {
+		if strings.TrimSpace(user) == "" || strings.Contains(user, ",") {
+			return fmt.Errorf("invalid ocm auto approval user %q: must be non empty and hold no commas", user)
+		}
+	}
+	return nil
+}
+
+// hubConfigs returns the helm values setting the hub options of the spec. The commas of the
+// lists are escaped, as the values are joined with commas into the set arg
+func hubConfigs(spec *tenancyv1alpha1.OCMSpec) []string {
+	if spec == nil {
+		return nil
+	}
+	var configs []string
+	if len(spec.FeatureGates) > 0 {
+		names := make([]string, 0, len(spec.FeatureGates))
+		for name := range spec.FeatureGates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		features := make([]string, 0, len(names))
+		for _, name := range names {
+			features = append(features, fmt.Sprintf("%s=%t", name, spec.FeatureGates[name]))
+		}
+		configs = append(configs, fmt.Sprintf("%s=%s", featuresValue, strings.Join(features, `\,`)))
+	}
+	if len(spec.AutoApprovalUsers) > 0 {
+		configs = append(configs, fmt.Sprintf("%s=%s", autoApprovalUsersValue, strings.Join(spec.AutoApprovalUsers, `\,`)))
+	}
+	return configs
+}
@@ -0,0 +1,114 @@
+package ocm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/helm"
+)
+
+func TestHubConfigs(t *testing.T) {
+	spec := &tenancyv1alpha1.OCMSpec{
+		FeatureGates: map[string]bool{
+			"ManagedClusterAutoApproval": true,
+			"DefaultClusterSet":          false,
+		},
+		AutoApprovalUsers: []string{"system:serviceaccount:open-cluster-management:agent-registration-bootstrap", "admin"},
+	}
+	configs := hubConfigs(spec)
+	values, err := strvals.Parse(strings.Join(append([]string{"route.enabled=false"}, configs...), ","))
+	if err != nil {
+		t.Fatalf("error parsing hub configs %v: %v", configs, err)
+	}
+	want := map[string]interface{}{
+		"route":                map[string]interface{}{"enabled": false},
+		featuresValue:          "DefaultClusterSet=false,ManagedClusterAutoApproval=true",
+		autoApprovalUsersValue: "system:serviceaccount:open-cluster-management:agent-registration-bootstrap,admin",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("expected values %v, got %v", want, values)
+	}
+	if configs := hubConfigs(nil); configs != nil {
+		t.Errorf("expected no configs without a spec, got %v", configs)
+	}
+}
+
+func TestHubConfigsChangeUpgradesChart(t *testing.T) {
+	spec := &tenancyv1alpha1.OCMSpec{
+		FeatureGates:      map[string]bool{"ManagedClusterAutoApproval": true},
+		AutoApprovalUsers: []string{"admin"},
+	}
+	installed, err := strvals.Parse(strings.Join(append(append([]string{}, configs...), hubConfigs(spec)...), ","))
+	if err != nil {
+		t.Fatalf("error parsing installed values: %v", err)
+	}
+	rel := &release.Release{Config: installed}
+
+	tests := []struct {
+		name     string
+		spec     *tenancyv1alpha1.OCMSpec
+		expected bool
+	}{
+		{name: "unchanged", spec: spec},
+		{name: "feature gate disabled", spec: &tenancyv1alpha1.OCMSpec{
+			FeatureGates:      map[string]bool{"ManagedClusterAutoApproval": false},
+			AutoApprovalUsers: []string{"admin"},
+		}, expected: true},
+		{name: "auto approval user added", spec: &tenancyv1alpha1.OCMSpec{
+			FeatureGates:      map[string]bool{"ManagedClusterAutoApproval": true},
+			AutoApprovalUsers: []string{"admin", "agent"},
+		}, expected: true},
+		{name: "hub options removed", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &helm.HelmHandler{Args: map[string]string{"set": strings.Join(append(append([]string{}, configs...), hubConfigs(tt.spec)...), ",")}}
+			if err := helm.Init(context.Background(), h); err != nil {
+				t.Fatalf("Init returned error: %v", err)
+			}
+			changed, err := h.ValuesChanged(rel)
+			if err != nil {
+				t.Fatalf("ValuesChanged returned error: %v", err)
+			}
+			if changed != tt.expected {
+				t.Errorf("expected changed %t, got %t", tt.expected, changed)
+			}
+		})
+	}
+}
+
+func TestValidateOCMSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *tenancyv1alpha1.OCMSpec
+		wantErr bool
+	}{
+		{name: "nil spec"},
+		{name: "valid", spec: &tenancyv1alpha1.OCMSpec{
+			FeatureGates:      map[string]bool{"ManagedClusterAutoApproval": true},
+			AutoApprovalUsers: []string{"admin"},
+		}},
+		{name: "invalid feature gate", spec: &tenancyv1alpha1.OCMSpec{
+			FeatureGates: map[string]bool{"Auto=Approval": true},
+		}, wantErr: true},
+		{name: "user with a comma", spec: &tenancyv1alpha1.OCMSpec{
+			AutoApprovalUsers: []string{"a,b"},
+		}, wantErr: true},
+		{name: "empty user", spec: &tenancyv1alpha1.OCMSpec{
+			AutoApprovalUsers: []string{" "},
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateOCMSpec(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
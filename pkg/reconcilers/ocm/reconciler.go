@@ -25,10 +25,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
-	clog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
 	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
@@ -40,34 +39,55 @@ type OCMReconciler struct {
 	*shared.BaseReconciler
 }
 
-func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient) *OCMReconciler {
+func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient, recorder record.EventRecorder) *OCMReconciler {
 	return &OCMReconciler{
 		BaseReconciler: &shared.BaseReconciler{
 			Client:        cl,
 			Scheme:        scheme,
 			ClientSet:     clientSet,
 			DynamicClient: dynamicClient,
+			Recorder:      recorder,
 		},
 	}
 }
 
 func (r *OCMReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (ctrl.Result, error) {
 	var routeURL string
-	_ = clog.FromContext(ctx)
+	logger := shared.ControlPlaneLogger(ctx, hcp)
+	logger.V(1).Info("Reconciling control plane")
 
 	cfg, err := r.BaseReconciler.GetConfig(ctx)
 	if err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := shared.ValidateIngress(hcp.Spec.Ingress); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := shared.ValidateResources(hcp.Spec.Resources); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := ValidateOCMSpec(hcp.Spec.OCM); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	start := time.Now()
 	if err := r.BaseReconciler.ReconcileNamespace(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := r.BaseReconciler.ReconcileImagePullSecrets(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	shared.LogPhase(logger, "namespace", start)
+
 	if err := r.ReconcileOCMService(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	start = time.Now()
 	if cfg.IsOpenShift {
 		if err = r.ReconcileAPIServerRoute(ctx, hcp, ServiceName, shared.SecurePort, cfg.Domain); err != nil {
 			return r.UpdateStatusForSyncingError(hcp, err)
@@ -78,7 +98,7 @@ func (r *OCMReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.Cont
 		}
 		// re-queue until valid route URL is retrieved
 		if routeURL == "" {
-			return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: r.ProvisioningRequeueDelay()}, nil
 		}
 		cfg.ExternalURL = routeURL
 	} else {
@@ -86,10 +106,24 @@ func (r *OCMReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.Cont
 			return r.UpdateStatusForSyncingError(hcp, err)
 		}
 	}
+	shared.LogPhase(logger, "ingress", start)
+
+	start = time.Now()
+	acquired, err := r.ReconcileChartLimited(func() error { return r.ReconcileChart(ctx, hcp, cfg) })
+	if err != nil {
+		return r.UpdateStatusForChartError(hcp, cfg, err)
+	}
+	// re-queue until the other chart operations leave room for this one
+	if !acquired {
+		logger.V(1).Info("Chart operations limit reached, requeueing")
+		return ctrl.Result{RequeueAfter: shared.ChartOpsRequeueDelay}, nil
+	}
+	shared.LogPhase(logger, "chart", start)
 
-	if err := r.ReconcileChart(ctx, hcp, cfg); err != nil {
+	if err := shared.SetRolledOutCondition(r.Client, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
+	r.CheckChartRollout(hcp, cfg)
 
 	if err := r.ReconcileUpdateClusterInfoJobRole(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
@@ -107,6 +141,11 @@ func (r *OCMReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.Cont
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := r.ReconcileAPIServerPodDisruptionBudget(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	hcp.Status.APIServerEndpoint = shared.GetAPIServerEndpoint(hcp, cfg)
 	r.UpdateStatusWithSecretRef(hcp, util.OCMKubeConfigSecret, util.KubeconfigSecretKeyDefault, "")
 
 	if hcp.Spec.PostCreateHook != nil &&
@@ -116,7 +155,36 @@ func (r *OCMReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.Cont
 		}
 	}
 
-	return r.UpdateStatusForSyncingSuccess(ctx, hcp)
+	if len(hcp.Spec.PostCreateHooks) > 0 &&
+		tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		if err := r.ReconcilePostCreateManifests(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+	}
+
+	if tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		if err := r.ReconcileMetricsRBAC(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		if err := r.ReconcileDefaultStorageClass(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+	}
+
+	// the hub serves requests only once its pod runs, so success is reported once the minimum
+	// available replicas are ready and the reconcile is re-queued until then
+	ready, message, err := util.GetAPIServerReadyReplicas(r.Client, *hcp)
+	if err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	if ready < util.APIServerMinAvailable(*hcp) {
+		if err := r.UpdateStatusForWaitingForReady(ctx, hcp, message); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.ProvisioningRequeueDelay()}, nil
+	}
+
+	return r.UpdateStatusForSyncingSuccessWithRequeue(ctx, hcp)
 }
 
 // add owner ref to allow capturing lifecycle events for the OCM deployment
@@ -132,13 +200,6 @@ func (r *OCMReconciler) addOwnerReference(ctx context.Context, hcp *tenancyv1alp
 		return err
 	}
 
-	if err := controllerutil.SetControllerReference(hcp, deployment, r.Scheme); err != nil {
-		return err
-	}
-
-	if err := r.Client.Update(context.TODO(), deployment, &client.UpdateOptions{}); err != nil {
-		return err
-	}
-
-	return nil
+	// the deployment is rendered by the chart, so only the owner reference is applied
+	return r.ApplyControllerReference(ctx, hcp, deployment)
 }
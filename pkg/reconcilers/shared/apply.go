@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"encoding/base64"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// GetFieldManager returns the field manager used to server-side apply the fields kubeflex sets
+// on the objects rendered by the chart of a control plane type. The name is stable so that
+// every reconcile of a control plane type owns the same fields.
+func GetFieldManager(controlPlaneType tenancyv1alpha1.ControlPlaneType) string {
+	return FieldOwner + "/" + string(controlPlaneType)
+}
+
+// ApplyControllerReference sets the control plane as the controller of obj, an object rendered
+// by the chart, with a server-side apply of the owner reference only. The other fields of obj,
+// including the owner references set by other actors, are left to their managers, so the
+// reconcile converges without conflicting with concurrent updates. Only the name and namespace
+// of obj are used.
+func (r *BaseReconciler) ApplyControllerReference(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	owned := &metav1.PartialObjectMetadata{}
+	if err := controllerutil.SetControllerReference(hcp, owned, r.Scheme); err != nil {
+		return err
+	}
+
+	patch := &unstructured.Unstructured{}
+	patch.SetGroupVersionKind(gvk)
+	patch.SetName(obj.GetName())
+	patch.SetNamespace(obj.GetNamespace())
+	patch.SetOwnerReferences(owned.GetOwnerReferences())
+	return r.Client.Patch(ctx, patch, client.Apply, client.FieldOwner(GetFieldManager(hcp.Spec.Type)), client.ForceOwnership)
+}
+
+// ApplySecretData sets the keys of data in the secret rendered by the chart with a server-side
+// apply, leaving the other keys of the secret to their managers
+func (r *BaseReconciler) ApplySecretData(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, namespace, name string, data map[string][]byte) error {
+	patch := &unstructured.Unstructured{}
+	patch.SetAPIVersion("v1")
+	patch.SetKind("Secret")
+	patch.SetName(name)
+	patch.SetNamespace(namespace)
+	encoded := map[string]interface{}{}
+	for key, value := range data {
+		encoded[key] = base64.StdEncoding.EncodeToString(value)
+	}
+	if err := unstructured.SetNestedField(patch.Object, encoded, "data"); err != nil {
+		return err
+	}
+	return r.Client.Patch(ctx, patch, client.Apply, client.FieldOwner(GetFieldManager(hcp.Spec.Type)), client.ForceOwnership)
+}
@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestApplyControllerReference(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1", UID: "cp1-uid"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	replicas := int32(1)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "vcluster", Namespace: "cp1-system", Labels: map[string]string{"app": "vcluster"}},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	}
+	r, cl := newTestBaseReconciler(t, hcp, sts)
+	ctx := context.Background()
+
+	if err := r.ApplyControllerReference(ctx, hcp, sts); err != nil {
+		t.Fatalf("ApplyControllerReference returned error: %v", err)
+	}
+
+	// another actor modifies the statefulset between reconciles
+	current := &appsv1.StatefulSet{}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(sts), current); err != nil {
+		t.Fatalf("error getting statefulset: %v", err)
+	}
+	external := int32(3)
+	current.Spec.Replicas = &external
+	current.Labels["example.com/owner"] = "team-a"
+	current.OwnerReferences = append(current.OwnerReferences, metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"})
+	if err := cl.Update(ctx, current); err != nil {
+		t.Fatalf("error updating statefulset: %v", err)
+	}
+
+	// the stale object held by the reconciler does not conflict with the update
+	if err := r.ApplyControllerReference(ctx, hcp, sts); err != nil {
+		t.Fatalf("ApplyControllerReference returned error after external update: %v", err)
+	}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(sts), current); err != nil {
+		t.Fatalf("error getting statefulset: %v", err)
+	}
+	if *current.Spec.Replicas != 3 || current.Labels["example.com/owner"] != "team-a" {
+		t.Errorf("expected fields set by the other actor to be kept, got replicas %d labels %v", *current.Spec.Replicas, current.Labels)
+	}
+	var controllers, others int
+	for _, ref := range current.OwnerReferences {
+		switch ref.UID {
+		case hcp.UID:
+			controllers++
+			if ref.Controller == nil || !*ref.Controller {
+				t.Errorf("expected control plane to be the controller, got %+v", ref)
+			}
+		case "other-uid":
+			others++
+		}
+	}
+	if controllers != 1 || others != 1 {
+		t.Errorf("expected one controller reference and the other owner to be kept, got %+v", current.OwnerReferences)
+	}
+}
+
+func TestApplySecretData(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vc-vcluster", Namespace: "cp1-system"},
+		Data:       map[string][]byte{"config": []byte("chart")},
+	}
+	r, cl := newTestBaseReconciler(t, hcp, secret)
+	ctx := context.Background()
+
+	if err := r.ApplySecretData(ctx, hcp, secret.Namespace, secret.Name, map[string][]byte{"config-incluster": []byte("kubeflex")}); err != nil {
+		t.Fatalf("ApplySecretData returned error: %v", err)
+	}
+	current := &v1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(secret), current); err != nil {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	if string(current.Data["config"]) != "chart" || string(current.Data["config-incluster"]) != "kubeflex" {
+		t.Errorf("expected both keys to be set, got %v", current.Data)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ChartOpsRequeueDelay is the delay after which a reconcile that could not start its chart
+// operation because of the concurrency limit is requeued
+const ChartOpsRequeueDelay = 5 * time.Second
+
+// ChartLimiter bounds the number of chart operations running at the same time for all the
+// control planes, so that many control planes created together do not overwhelm the API
+// server of the hosting cluster and the chart repository. A nil ChartLimiter sets no limit.
+type ChartLimiter struct {
+	sem *semaphore.Weighted
+}
+
+// NewChartLimiter returns a limiter allowing up to max concurrent chart operations, or nil
+// if max is not positive
+func NewChartLimiter(max int) *ChartLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &ChartLimiter{sem: semaphore.NewWeighted(int64(max))}
+}
+
+// TryAcquire reserves a chart operation without blocking and reports whether it succeeded.
+// Each successful call must be followed by a call to Release.
+func (l *ChartLimiter) TryAcquire() bool {
+	if l != nil && !l.sem.TryAcquire(1) {
+		return false
+	}
+	chartOpsInFlight.Inc()
+	return true
+}
+
+// Release ends a chart operation reserved with TryAcquire
+func (l *ChartLimiter) Release() {
+	chartOpsInFlight.Dec()
+	if l != nil {
+		l.sem.Release(1)
+	}
+}
+
+// ReconcileChartLimited runs reconcileChart if the chart limiter of the reconciler has room for
+// another chart operation. It returns false without running it otherwise, and the caller is
+// expected to requeue the reconcile after ChartOpsRequeueDelay instead of blocking a worker.
+func (r *BaseReconciler) ReconcileChartLimited(reconcileChart func() error) (bool, error) {
+	if !r.ChartLimiter.TryAcquire() {
+		return false, nil
+	}
+	defer r.ChartLimiter.Release()
+	return true, reconcileChart()
+}
@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReconcileChartLimited(t *testing.T) {
+	r := &BaseReconciler{ChartLimiter: NewChartLimiter(1)}
+
+	// hold the only slot while another chart operation is attempted
+	var inner bool
+	acquired, err := r.ReconcileChartLimited(func() error {
+		if got := testutil.ToFloat64(chartOpsInFlight); got != 1 {
+			t.Errorf("expected 1 chart operation in flight, got %v", got)
+		}
+		var err error
+		inner, err = r.ReconcileChartLimited(func() error {
+			t.Errorf("chart operation over the limit must not run")
+			return nil
+		})
+		return err
+	})
+	if err != nil || !acquired {
+		t.Fatalf("expected the chart operation to run, got acquired=%v err=%v", acquired, err)
+	}
+	if inner {
+		t.Errorf("expected the chart operation over the limit not to be acquired")
+	}
+	if got := testutil.ToFloat64(chartOpsInFlight); got != 0 {
+		t.Errorf("expected no chart operation in flight, got %v", got)
+	}
+
+	// the slot is released also when the operation fails
+	if _, err := r.ReconcileChartLimited(func() error { return fmt.Errorf("install failed") }); err == nil {
+		t.Errorf("expected the chart operation error")
+	}
+	if acquired, _ := r.ReconcileChartLimited(func() error { return nil }); !acquired {
+		t.Errorf("expected the slot to be released after a failed operation")
+	}
+}
+
+func TestNewChartLimiterUnlimited(t *testing.T) {
+	if l := NewChartLimiter(0); l != nil {
+		t.Fatalf("expected no limiter for a zero limit")
+	}
+	r := &BaseReconciler{}
+	for i := 0; i < 3; i++ {
+		if acquired, err := r.ReconcileChartLimited(func() error { return nil }); !acquired || err != nil {
+			t.Errorf("expected the chart operation to run without a limiter, got acquired=%v err=%v", acquired, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/helm"
+)
+
+// dockerConfigJSON is the content of a kubernetes.io/dockerconfigjson secret
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// GetChartRegistryCredentials returns the username and password for the registry of the
+// chart URL from the pull secret referenced by the chart spec, or empty credentials when
+// no chart or pull secret is set
+func (r *BaseReconciler) GetChartRegistryCredentials(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (string, string, error) {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.Chart == nil || hcp.Spec.Chart.PullSecretRef == nil {
+		return "", "", nil
+	}
+	ref := hcp.Spec.Chart.PullSecretRef
+	secret := &v1.Secret{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret, &client.GetOptions{}); err != nil {
+		return "", "", fmt.Errorf("error getting chart pull secret %s/%s: %s", ref.Namespace, ref.Name, err)
+	}
+	if secret.Type != v1.SecretTypeDockerConfigJson {
+		return "", "", fmt.Errorf("chart pull secret %s/%s has type %s, expected %s",
+			ref.Namespace, ref.Name, secret.Type, v1.SecretTypeDockerConfigJson)
+	}
+	host := helm.OCIRegistryHost(hcp.Spec.Chart.URL)
+	username, password, err := registryCredentials(secret.Data[v1.DockerConfigJsonKey], host)
+	if err != nil {
+		return "", "", fmt.Errorf("chart pull secret %s/%s: %s", ref.Namespace, ref.Name, err)
+	}
+	return username, password, nil
+}
+
+// registryCredentials returns the credentials for host in a docker config json, matching
+// entries keyed by the host alone or by a URL with the host
+func registryCredentials(data []byte, host string) (string, string, error) {
+	config := dockerConfigJSON{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", fmt.Errorf("invalid docker config json: %s", err)
+	}
+	for key, entry := range config.Auths {
+		key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+		key, _, _ = strings.Cut(key, "/")
+		if key != host {
+			continue
+		}
+		if entry.Username != "" || entry.Password != "" {
+			return entry.Username, entry.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid auth for registry %s: %s", host, err)
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return "", "", fmt.Errorf("invalid auth for registry %s: expected username:password", host)
+		}
+		return username, password, nil
+	}
+	return "", "", fmt.Errorf("no credentials for registry %s", host)
+}
@@ -0,0 +1,53 @@
+package shared
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestGetChartRegistryCredentials(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cret"))
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-creds", Namespace: "default"},
+		Type:       v1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			v1.DockerConfigJsonKey: []byte(`{"auths":{"docker.io":{"username":"other","password":"x"},"https://registry.example.com":{"auth":"` + auth + `"}}}`),
+		},
+	}
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeVCluster,
+			Chart: &tenancyv1alpha1.ChartSpec{
+				URL:           "oci://registry.example.com/charts/vcluster",
+				PullSecretRef: &tenancyv1alpha1.ImagePullSecretReference{Namespace: "default", Name: "registry-creds"},
+			},
+		},
+	}
+	r, _ := newTestBaseReconciler(t, hcp, secret)
+
+	username, password, err := r.GetChartRegistryCredentials(context.TODO(), hcp)
+	if err != nil {
+		t.Fatalf("GetChartRegistryCredentials returned error: %v", err)
+	}
+	if username != "robot" || password != "s3cret" {
+		t.Errorf("expected robot:s3cret, got %s:%s", username, password)
+	}
+
+	hcp.Spec.Chart.URL = "oci://ghcr.io/charts/vcluster"
+	if _, _, err := r.GetChartRegistryCredentials(context.TODO(), hcp); err == nil {
+		t.Errorf("expected error when the secret has no credentials for the registry")
+	}
+
+	hcp.Spec.Chart.PullSecretRef = nil
+	username, password, err = r.GetChartRegistryCredentials(context.TODO(), hcp)
+	if err != nil || username != "" || password != "" {
+		t.Errorf("expected empty credentials without pull secret, got %s:%s, %v", username, password, err)
+	}
+}
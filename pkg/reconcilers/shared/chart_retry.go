@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// DefaultChartMaxAttempts is the number of chart install attempts made before giving up
+	DefaultChartMaxAttempts = 5
+	// DefaultChartBackoffCap is the maximum delay between two chart install attempts
+	DefaultChartBackoffCap = 30 * time.Second
+)
+
+// initial delay between two chart install attempts, doubled on each retry
+var chartRetryInitialDelay = time.Second
+
+// ChartRetryConfig bounds the retries of a chart install
+type ChartRetryConfig struct {
+	MaxAttempts int
+	Cap         time.Duration
+}
+
+// parseChartRetryConfig reads the optional chartMaxAttempts and chartBackoffCap
+// keys of the system config map, falling back to the defaults when they are not set
+func parseChartRetryConfig(data map[string]string) (ChartRetryConfig, error) {
+	retry := ChartRetryConfig{
+		MaxAttempts: DefaultChartMaxAttempts,
+		Cap:         DefaultChartBackoffCap,
+	}
+	if v := data["chartMaxAttempts"]; v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil {
+			return retry, fmt.Errorf("invalid chartMaxAttempts %q: %w", v, err)
+		}
+		if attempts < 1 {
+			return retry, fmt.Errorf("invalid chartMaxAttempts %q: must be at least 1", v)
+		}
+		retry.MaxAttempts = attempts
+	}
+	if v := data["chartBackoffCap"]; v != "" {
+		backoffCap, err := time.ParseDuration(v)
+		if err != nil {
+			return retry, fmt.Errorf("invalid chartBackoffCap %q: %w", v, err)
+		}
+		retry.Cap = backoffCap
+	}
+	return retry, nil
+}
+
+// RetryChart runs fn until it succeeds or the max attempts are reached, waiting
+// with an exponential backoff between attempts, so that transient helm and registry
+// errors are retried in place instead of going through the controller requeue.
+// The returned error reports how many attempts were made.
+func RetryChart(ctx context.Context, retry ChartRetryConfig, fn func() error) error {
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+	backoff := wait.Backoff{
+		Duration: chartRetryInitialDelay,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    retry.MaxAttempts,
+		Cap:      retry.Cap,
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= retry.MaxAttempts {
+			return fmt.Errorf("chart reconcile failed after %d attempts: %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("chart reconcile canceled after %d attempts: %w", attempt, err)
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryChart(t *testing.T) {
+	defer func(d time.Duration) { chartRetryInitialDelay = d }(chartRetryInitialDelay)
+	chartRetryInitialDelay = time.Millisecond
+	retry := ChartRetryConfig{MaxAttempts: 3, Cap: 5 * time.Millisecond}
+
+	// succeeds after a transient error
+	calls := 0
+	err := RetryChart(context.TODO(), retry, func() error {
+		calls++
+		if calls < 2 {
+			return fmt.Errorf("registry rate limited")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RetryChart returned error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+
+	// gives up after the max attempts
+	calls = 0
+	err = RetryChart(context.TODO(), retry, func() error {
+		calls++
+		return fmt.Errorf("connection refused")
+	})
+	if err == nil {
+		t.Fatalf("expected error after max attempts")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestParseChartRetryConfig(t *testing.T) {
+	retry, err := parseChartRetryConfig(map[string]string{})
+	if err != nil {
+		t.Fatalf("parseChartRetryConfig returned error: %v", err)
+	}
+	if retry.MaxAttempts != DefaultChartMaxAttempts || retry.Cap != DefaultChartBackoffCap {
+		t.Errorf("expected defaults, got %+v", retry)
+	}
+
+	retry, err = parseChartRetryConfig(map[string]string{"chartMaxAttempts": "2", "chartBackoffCap": "10s"})
+	if err != nil {
+		t.Fatalf("parseChartRetryConfig returned error: %v", err)
+	}
+	if retry.MaxAttempts != 2 || retry.Cap != 10*time.Second {
+		t.Errorf("unexpected config %+v", retry)
+	}
+
+	for _, data := range []map[string]string{
+		{"chartMaxAttempts": "0"},
+		{"chartMaxAttempts": "many"},
+		{"chartBackoffCap": "soon"},
+	} {
+		if _, err := parseChartRetryConfig(data); err == nil {
+			t.Errorf("expected error for %v", data)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// ReleaseStatusChecker returns the helm release of a control plane chart
+type ReleaseStatusChecker interface {
+	CheckStatus() (*release.Release, error)
+}
+
+// SetChartReleasedCondition reads back the helm release of the control plane chart and sets
+// the ChartReleased condition from its status, so that a release stuck in a pending or failed
+// state is told apart from API server pods that are not ready
+func SetChartReleasedCondition(hcp *tenancyv1alpha1.ControlPlane, checker ReleaseStatusChecker) {
+	rel, err := checker.CheckStatus()
+	if err != nil || rel == nil || rel.Info == nil {
+		message := "release not found"
+		if err != nil {
+			message = err.Error()
+		}
+		tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionChartReleased(false, tenancyv1alpha1.ReasonChartNotInstalled, message))
+		return
+	}
+	message := fmt.Sprintf("release %s revision %d is %s", rel.Name, rel.Version, rel.Info.Status)
+	if rel.Info.Description != "" {
+		message = fmt.Sprintf("%s: %s", message, rel.Info.Description)
+	}
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionChartReleased(
+		rel.Info.Status == release.StatusDeployed, releaseStatusReason(rel.Info.Status), message))
+}
+
+// releaseStatusReason turns a helm release status such as pending-install into a
+// condition reason such as PendingInstall
+func releaseStatusReason(status release.Status) tenancyv1alpha1.ConditionReason {
+	var b strings.Builder
+	for _, part := range strings.Split(status.String(), "-") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return tenancyv1alpha1.ConditionReason(b.String())
+}
@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+type fakeReleaseStatusChecker struct {
+	rel *release.Release
+	err error
+}
+
+func (f fakeReleaseStatusChecker) CheckStatus() (*release.Release, error) {
+	return f.rel, f.err
+}
+
+func TestSetChartReleasedCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		checker    fakeReleaseStatusChecker
+		wantStatus corev1.ConditionStatus
+		wantReason tenancyv1alpha1.ConditionReason
+	}{
+		{
+			name:       "deployed",
+			checker:    fakeReleaseStatusChecker{rel: &release.Release{Name: "vcluster", Version: 1, Info: &release.Info{Status: release.StatusDeployed}}},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: "Deployed",
+		},
+		{
+			name:       "pending install",
+			checker:    fakeReleaseStatusChecker{rel: &release.Release{Name: "vcluster", Version: 1, Info: &release.Info{Status: release.StatusPendingInstall}}},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: "PendingInstall",
+		},
+		{
+			name:       "failed",
+			checker:    fakeReleaseStatusChecker{rel: &release.Release{Name: "vcluster", Version: 2, Info: &release.Info{Status: release.StatusFailed, Description: "timed out waiting for the condition"}}},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: "Failed",
+		},
+		{
+			name:       "not installed",
+			checker:    fakeReleaseStatusChecker{err: driver.ErrReleaseNotFound},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: tenancyv1alpha1.ReasonChartNotInstalled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcp := &tenancyv1alpha1.ControlPlane{}
+			SetChartReleasedCondition(hcp, tt.checker)
+			c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartReleased)
+			if c == nil {
+				t.Fatalf("expected ChartReleased condition")
+			}
+			if c.Status != tt.wantStatus || c.Reason != tt.wantReason {
+				t.Errorf("expected status %s and reason %s, got %s and %s", tt.wantStatus, tt.wantReason, c.Status, c.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// DefaultChartInstallTimeout is how long a chart may keep failing to install before the control
+// plane is reported as timed out, when the system config map does not set one
+const DefaultChartInstallTimeout = 15 * time.Minute
+
+// parseChartInstallTimeout reads the optional chartInstallTimeout key of the system config map,
+// falling back to the default when it is not set
+func parseChartInstallTimeout(data map[string]string) (time.Duration, error) {
+	v := data["chartInstallTimeout"]
+	if v == "" {
+		return DefaultChartInstallTimeout, nil
+	}
+	timeout, err := time.ParseDuration(v)
+	if err != nil {
+		return DefaultChartInstallTimeout, fmt.Errorf("invalid chartInstallTimeout %q: %w", v, err)
+	}
+	if timeout < 0 {
+		return DefaultChartInstallTimeout, fmt.Errorf("invalid chartInstallTimeout %q: must not be negative", v)
+	}
+	return timeout, nil
+}
+
+// ChartInstallTimeout returns the chart install timeout of the control plane, which is
+// spec.chartInstallTimeout when set and the one of the system config map otherwise
+func ChartInstallTimeout(hcp *tenancyv1alpha1.ControlPlane, cfg *SharedConfig) time.Duration {
+	if hcp.Spec.ChartInstallTimeout != nil {
+		return hcp.Spec.ChartInstallTimeout.Duration
+	}
+	return cfg.ChartInstallTimeout
+}
+
+// UpdateStatusForChartError works as UpdateStatusForSyncingError for a failed chart reconcile,
+// and records in status.chartInstallStartTime when the chart started failing. Once it has been
+// failing for longer than the chart install timeout, ChartInstallTimedOut is set true with
+// the timeout and the last helm release status, and the reconcile is requeued after the steady
+// state interval, or the provisioning delay when none is set, instead of retrying right away.
+func (r *BaseReconciler) UpdateStatusForChartError(hcp *tenancyv1alpha1.ControlPlane, cfg *SharedConfig, e error) (ctrl.Result, error) {
+	return r.updateStatusForChartError(hcp, cfg, e, time.Now())
+}
+
+func (r *BaseReconciler) updateStatusForChartError(hcp *tenancyv1alpha1.ControlPlane, cfg *SharedConfig, e error, now time.Time) (ctrl.Result, error) {
+	timedOut, ok := r.checkChartInstallTimeout(hcp, cfg, "failing", lastReleaseStatus(hcp), now)
+	if !ok {
+		return r.UpdateStatusForSyncingError(hcp, e)
+	}
+	result, err := r.UpdateStatusForSyncingError(hcp, fmt.Errorf("%s: %w", timedOut, e))
+	if err == nil {
+		result.RequeueAfter = r.SteadyStateRequeueInterval
+		if result.RequeueAfter <= 0 {
+			result.RequeueAfter = r.ProvisioningRequeueDelay()
+		}
+	}
+	return result, err
+}
+
+// CheckChartRollout runs the chart install timeout of a chart that installed without error
+// until the ChartReleased and RolledOut conditions are true, since charts are installed without
+// waiting for their resources to become ready. The start time is cleared once both are true,
+// and a ChartInstallTimedOut condition set by an earlier timeout turns false. When the rollout
+// takes longer than the chart install timeout, ChartInstallTimedOut is set true as for a failing
+// chart, and the rest of the reconcile goes on.
+func (r *BaseReconciler) CheckChartRollout(hcp *tenancyv1alpha1.ControlPlane, cfg *SharedConfig) {
+	r.checkChartRollout(hcp, cfg, time.Now())
+}
+
+func (r *BaseReconciler) checkChartRollout(hcp *tenancyv1alpha1.ControlPlane, cfg *SharedConfig, now time.Time) {
+	released := conditionTrue(hcp, tenancyv1alpha1.TypeChartReleased)
+	if released && conditionTrue(hcp, tenancyv1alpha1.TypeRolledOut) {
+		hcp.Status.ChartInstallStartTime = nil
+		if conditionTrue(hcp, tenancyv1alpha1.TypeChartInstallTimedOut) {
+			tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionChartInstallComplete())
+		}
+		return
+	}
+	status := lastReleaseStatus(hcp)
+	if released {
+		status = "API server rollout status unknown"
+		if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeRolledOut); c != nil && c.Message != "" {
+			status = c.Message
+		}
+	}
+	r.checkChartInstallTimeout(hcp, cfg, "rolling out", status, now)
+}
+
+// checkChartInstallTimeout starts the chart install timeout if it is not running and, once it
+// has elapsed, records an event, sets ChartInstallTimedOut true with what the chart is doing
+// and its status, and returns the timeout message and true. The messages leave out the elapsed
+// time, so that they do not change between reconciles and write no new status
+func (r *BaseReconciler) checkChartInstallTimeout(hcp *tenancyv1alpha1.ControlPlane, cfg *SharedConfig, doing, status string, now time.Time) (string, bool) {
+	if hcp.Status.ChartInstallStartTime == nil {
+		hcp.Status.ChartInstallStartTime = &metav1.Time{Time: now}
+	}
+	timeout := ChartInstallTimeout(hcp, cfg)
+	if timeout <= 0 || now.Sub(hcp.Status.ChartInstallStartTime.Time) < timeout {
+		return "", false
+	}
+
+	phase := "install"
+	if !IsProvisioning(hcp) {
+		phase = "upgrade"
+	}
+	timedOut := fmt.Sprintf("chart %s timed out after %s", phase, timeout)
+	// the event message does not change between reconciles, so that the recorder aggregates them
+	r.RecordEvent(hcp, v1.EventTypeWarning, EventReasonChartInstallTimeout, "%s", timedOut)
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionChartInstallTimeout(
+		fmt.Sprintf("%s while %s: %s", timedOut, doing, status)))
+	return timedOut, true
+}
+
+// conditionTrue reports whether the condition of the given type is true
+func conditionTrue(hcp *tenancyv1alpha1.ControlPlane, t tenancyv1alpha1.ConditionType) bool {
+	c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, t)
+	return c != nil && c.Status == v1.ConditionTrue
+}
+
+// lastReleaseStatus returns the helm release status recorded in the ChartReleased condition
+func lastReleaseStatus(hcp *tenancyv1alpha1.ControlPlane) string {
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartReleased); c != nil && c.Message != "" {
+		return c.Message
+	}
+	return "release status unknown"
+}
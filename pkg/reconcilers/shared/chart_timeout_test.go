@@ -0,0 +1,189 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestParseChartInstallTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", data: map[string]string{}, want: DefaultChartInstallTimeout},
+		{name: "set", data: map[string]string{"chartInstallTimeout": "30m"}, want: 30 * time.Minute},
+		{name: "disabled", data: map[string]string{"chartInstallTimeout": "0s"}, want: 0},
+		{name: "invalid", data: map[string]string{"chartInstallTimeout": "soon"}, wantErr: true},
+		{name: "negative", data: map[string]string{"chartInstallTimeout": "-1m"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChartInstallTimeout(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateStatusForChartError(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionChartReleased(false, "PendingInstall", "release vcluster revision 1 is pending-install"))
+	r, _ := newTestBaseReconciler(t, hcp)
+	defer DeleteControlPlaneMetrics(hcp)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	r.SteadyStateRequeueInterval = 10 * time.Minute
+	cfg := &SharedConfig{ChartInstallTimeout: 15 * time.Minute}
+	// status timestamps are stored with a one second precision
+	start := time.Now().Truncate(time.Second)
+	chartErr := fmt.Errorf("cannot re-use a name that is still in use")
+
+	// the first failure starts the timeout
+	result, err := r.updateStatusForChartError(hcp, cfg, chartErr, start)
+	if err != nil {
+		t.Fatalf("updateStatusForChartError returned error: %v", err)
+	}
+	if hcp.Status.ChartInstallStartTime == nil || !hcp.Status.ChartInstallStartTime.Time.Equal(start) {
+		t.Fatalf("expected the chart install start time to be recorded, got %v", hcp.Status.ChartInstallStartTime)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("expected no requeue delay before the timeout, got %s", result.RequeueAfter)
+	}
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartInstallTimedOut); c != nil {
+		t.Errorf("expected no ChartInstallTimedOut condition before the timeout, got %+v", c)
+	}
+
+	// the start time is kept by the next failures, until the timeout elapses
+	result, err = r.updateStatusForChartError(hcp, cfg, chartErr, start.Add(20*time.Minute))
+	if err != nil {
+		t.Fatalf("updateStatusForChartError returned error: %v", err)
+	}
+	c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartInstallTimedOut)
+	if c == nil || c.Reason != tenancyv1alpha1.ReasonChartInstallTimeout {
+		t.Fatalf("expected a ChartInstallTimedOut condition, got %+v", c)
+	}
+	expected := "chart install timed out after 15m0s while failing: release vcluster revision 1 is pending-install"
+	if c.Message != expected {
+		t.Errorf("expected message %q, got %q", expected, c.Message)
+	}
+	if result.RequeueAfter != r.SteadyStateRequeueInterval {
+		t.Errorf("expected requeue after %s, got %s", r.SteadyStateRequeueInterval, result.RequeueAfter)
+	}
+	synced := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeSynced)
+	if synced == nil || !strings.Contains(synced.Message, "chart install timed out after 15m0s") {
+		t.Errorf("expected the Synced condition to report the timeout, got %+v", synced)
+	}
+
+	var timeoutEvents int
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, EventReasonChartInstallTimeout) {
+			timeoutEvents++
+		}
+	}
+	if timeoutEvents != 1 {
+		t.Errorf("expected one %s event, got %d", EventReasonChartInstallTimeout, timeoutEvents)
+	}
+
+	// the conditions do not change while the chart keeps failing, so that no new status is written
+	conditions := append([]tenancyv1alpha1.ControlPlaneCondition{}, hcp.Status.Conditions...)
+	if _, err := r.updateStatusForChartError(hcp, cfg, chartErr, start.Add(25*time.Minute)); err != nil {
+		t.Fatalf("updateStatusForChartError returned error: %v", err)
+	}
+	if !tenancyv1alpha1.AreConditionSlicesSame(conditions, hcp.Status.Conditions) {
+		t.Errorf("expected the conditions to be unchanged by a later failure, got %+v", hcp.Status.Conditions)
+	}
+
+	// without a steady state interval the reconcile is still delayed
+	r.SteadyStateRequeueInterval = 0
+	result, err = r.updateStatusForChartError(hcp, cfg, chartErr, start.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("updateStatusForChartError returned error: %v", err)
+	}
+	if result.RequeueAfter != r.ProvisioningRequeueDelay() {
+		t.Errorf("expected requeue after %s, got %s", r.ProvisioningRequeueDelay(), result.RequeueAfter)
+	}
+
+	// a spec timeout of zero disables it
+	hcp.Status.Conditions = nil
+	hcp.Spec.ChartInstallTimeout = &metav1.Duration{}
+	if _, err := r.updateStatusForChartError(hcp, cfg, chartErr, start.Add(time.Hour)); err != nil {
+		t.Fatalf("updateStatusForChartError returned error: %v", err)
+	}
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartInstallTimedOut); c != nil {
+		t.Errorf("expected no ChartInstallTimedOut condition with the timeout disabled, got %+v", c)
+	}
+}
+
+func TestCheckChartRollout(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionChartReleased(true, "Deployed", "release vcluster revision 1 is deployed"))
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionRolledOut(false, "0 of 1 replicas are available"))
+	r, _ := newTestBaseReconciler(t, hcp)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	cfg := &SharedConfig{ChartInstallTimeout: 15 * time.Minute}
+	start := time.Now().Truncate(time.Second)
+
+	// a chart installed without error starts the timeout while its pods are not available
+	r.checkChartRollout(hcp, cfg, start)
+	if hcp.Status.ChartInstallStartTime == nil || !hcp.Status.ChartInstallStartTime.Time.Equal(start) {
+		t.Fatalf("expected the chart install start time to be recorded, got %v", hcp.Status.ChartInstallStartTime)
+	}
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartInstallTimedOut); c != nil {
+		t.Errorf("expected no ChartInstallTimedOut condition before the timeout, got %+v", c)
+	}
+	if delay := r.SyncedRequeueDelay(hcp); delay != r.ProvisioningRequeueDelay() {
+		t.Errorf("expected the reconcile to be requeued after %s while the timeout runs, got %s", r.ProvisioningRequeueDelay(), delay)
+	}
+
+	// a rollout stuck for longer than the timeout sets ChartInstallTimedOut
+	r.checkChartRollout(hcp, cfg, start.Add(20*time.Minute))
+	c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartInstallTimedOut)
+	if c == nil || c.Reason != tenancyv1alpha1.ReasonChartInstallTimeout {
+		t.Fatalf("expected a ChartInstallTimedOut condition, got %+v", c)
+	}
+	expected := "chart install timed out after 15m0s while rolling out: 0 of 1 replicas are available"
+	if c.Message != expected {
+		t.Errorf("expected message %q, got %q", expected, c.Message)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected one %s event, got %d", EventReasonChartInstallTimeout, len(recorder.Events))
+	}
+
+	// the health conditions of the API server do not overwrite the timeout
+	SetHealthConditions(hcp, true)
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartInstallTimedOut); c == nil || c.Status != corev1.ConditionTrue {
+		t.Errorf("expected ChartInstallTimedOut to stay true, got %+v", c)
+	}
+
+	// the timeout is only cleared once the chart is rolled out
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionRolledOut(true, ""))
+	r.checkChartRollout(hcp, cfg, start.Add(21*time.Minute))
+	if hcp.Status.ChartInstallStartTime != nil {
+		t.Errorf("expected the chart install start time to be cleared, got %v", hcp.Status.ChartInstallStartTime)
+	}
+	c = tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeChartInstallTimedOut)
+	if c == nil || c.Status != corev1.ConditionFalse || c.Reason != tenancyv1alpha1.ReasonChartInstallComplete {
+		t.Errorf("expected ChartInstallTimedOut to turn false once rolled out, got %+v", c)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// DefaultValuesKey is the key holding the values of a spec.valuesFrom reference that sets no key
+const DefaultValuesKey = "values.yaml"
+
+// GetValuesFrom returns the chart values of the spec.valuesFrom references, merged so that a
+// later reference overrides an earlier one, or nil when the control plane references none
+func (r *BaseReconciler) GetValuesFrom(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (map[string]interface{}, error) {
+	_ = clog.FromContext(ctx)
+	var merged map[string]interface{}
+	for _, ref := range hcp.Spec.ValuesFrom {
+		data, err := r.getValuesReferenceData(ref)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("error decoding values %s: %w", valuesReferenceKey(ref), err)
+		}
+		if merged == nil {
+			merged = map[string]interface{}{}
+		}
+		merged = chartutil.CoalesceTables(values, merged)
+	}
+	return merged, nil
+}
+
+// ValuesFromHash returns the hash of the values returned by GetValuesFrom, recorded in
+// status.valuesFromHash to tell when the chart needs an upgrade. It is empty when there
+// are no values
+func ValuesFromHash(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	// maps are marshalled with sorted keys, so equal values have equal hashes. Decoded
+	// values always marshal
+	data, _ := json.Marshal(values)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValuesFromIndexField is the field index of the control planes on the objects referenced in
+// their spec.valuesFrom, with the keys returned by ValuesFromIndexKey
+const ValuesFromIndexField = "spec.valuesFrom"
+
+// ValuesFromIndexKey returns the key of an object of the given kind in ValuesFromIndexField
+func ValuesFromIndexKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// IndexValuesFrom returns the ValuesFromIndexField keys of the objects referenced in the
+// spec.valuesFrom of a control plane
+func IndexValuesFrom(obj client.Object) []string {
+	hcp, ok := obj.(*tenancyv1alpha1.ControlPlane)
+	if !ok {
+		return nil
+	}
+	keys := []string{}
+	for _, ref := range hcp.Spec.ValuesFrom {
+		keys = append(keys, ValuesFromIndexKey(ref.Kind, ref.Namespace, ref.Name))
+	}
+	return keys
+}
+
+// valuesReferenceKey returns the kind/namespace/name/key of a values reference, for messages
+func valuesReferenceKey(ref tenancyv1alpha1.ValuesReference) string {
+	return fmt.Sprintf("%s/%s/%s/%s", ref.Kind, ref.Namespace, ref.Name, valuesKey(ref))
+}
+
+func valuesKey(ref tenancyv1alpha1.ValuesReference) string {
+	if ref.Key == "" {
+		return DefaultValuesKey
+	}
+	return ref.Key
+}
+
+// getValuesReferenceData returns the value under the key of the referenced ConfigMap or Secret
+func (r *BaseReconciler) getValuesReferenceData(ref tenancyv1alpha1.ValuesReference) ([]byte, error) {
+	key := client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+	var data []byte
+	var found bool
+	switch ref.Kind {
+	case "ConfigMap":
+		cm := &v1.ConfigMap{}
+		if err := r.Client.Get(context.TODO(), key, cm, &client.GetOptions{}); err != nil {
+			return nil, fmt.Errorf("error retrieving values %s: %w", valuesReferenceKey(ref), err)
+		}
+		var value string
+		value, found = cm.Data[valuesKey(ref)]
+		data = []byte(value)
+	case "Secret":
+		secret := &v1.Secret{}
+		if err := r.Client.Get(context.TODO(), key, secret, &client.GetOptions{}); err != nil {
+			return nil, fmt.Errorf("error retrieving values %s: %w", valuesReferenceKey(ref), err)
+		}
+		data, found = secret.Data[valuesKey(ref)]
+	default:
+		return nil, fmt.Errorf("unsupported values kind %s, must be ConfigMap or Secret", ref.Kind)
+	}
+	if !found {
+		return nil, fmt.Errorf("key %s not found in %s %s/%s", valuesKey(ref), ref.Kind, ref.Namespace, ref.Name)
+	}
+	return data, nil
+}
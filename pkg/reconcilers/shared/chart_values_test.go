@@ -0,0 +1,120 @@
+package shared
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestGetValuesFrom(t *testing.T) {
+	r, _ := newTestBaseReconciler(t,
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "platform"},
+			Data:       map[string]string{"values.yaml": "syncer:\n  replicas: 1\n  extraArgs:\n  - --a\nisolation:\n  enabled: true\n"},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "overrides", Namespace: "team-a"},
+			Data:       map[string][]byte{"cp1.yaml": []byte("syncer:\n  replicas: 2\n")},
+		})
+
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			ValuesFrom: []tenancyv1alpha1.ValuesReference{
+				{Kind: "ConfigMap", Namespace: "platform", Name: "defaults"},
+				{Kind: "Secret", Namespace: "team-a", Name: "overrides", Key: "cp1.yaml"},
+			},
+		},
+	}
+	values, err := r.GetValuesFrom(context.TODO(), hcp)
+	if err != nil {
+		t.Fatalf("GetValuesFrom returned error: %v", err)
+	}
+	want := map[string]interface{}{
+		"syncer":    map[string]interface{}{"replicas": float64(2), "extraArgs": []interface{}{"--a"}},
+		"isolation": map[string]interface{}{"enabled": true},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("expected the later reference to override the earlier one: want %v, got %v", want, values)
+	}
+
+	hash := ValuesFromHash(values)
+	if hash == "" {
+		t.Errorf("expected a hash for non empty values")
+	}
+	if ValuesFromHash(want) != hash {
+		t.Errorf("expected equal values to have equal hashes")
+	}
+	if ValuesFromHash(nil) != "" {
+		t.Errorf("expected an empty hash without values")
+	}
+
+	keys := sets.New(IndexValuesFrom(hcp)...)
+	if !keys.Has(ValuesFromIndexKey("Secret", "team-a", "overrides")) {
+		t.Errorf("expected the secret to be referenced")
+	}
+	if keys.Has(ValuesFromIndexKey("ConfigMap", "team-a", "overrides")) {
+		t.Errorf("expected a config map with the name of the secret not to be referenced")
+	}
+
+	hcp.Spec.ValuesFrom = []tenancyv1alpha1.ValuesReference{{Kind: "Secret", Namespace: "team-a", Name: "overrides"}}
+	if _, err := r.GetValuesFrom(context.TODO(), hcp); err == nil {
+		t.Errorf("expected an error when the default key is missing")
+	}
+	hcp.Spec.ValuesFrom = nil
+	if values, err := r.GetValuesFrom(context.TODO(), hcp); err != nil || values != nil {
+		t.Errorf("expected no values without references, got %v, %v", values, err)
+	}
+}
+
+func TestIndexValuesFrom(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&tenancyv1alpha1.ControlPlane{}, ValuesFromIndexField, IndexValuesFrom).
+		WithObjects(
+			&tenancyv1alpha1.ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+				Spec: tenancyv1alpha1.ControlPlaneSpec{ValuesFrom: []tenancyv1alpha1.ValuesReference{
+					{Kind: "ConfigMap", Namespace: "platform", Name: "defaults"},
+					{Kind: "Secret", Namespace: "team-a", Name: "overrides"},
+				}},
+			},
+			&tenancyv1alpha1.ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "cp2"},
+				Spec: tenancyv1alpha1.ControlPlaneSpec{ValuesFrom: []tenancyv1alpha1.ValuesReference{
+					{Kind: "ConfigMap", Namespace: "platform", Name: "defaults"},
+				}},
+			},
+			&tenancyv1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp3"}},
+		).Build()
+
+	for key, want := range map[string][]string{
+		ValuesFromIndexKey("ConfigMap", "platform", "defaults"): {"cp1", "cp2"},
+		ValuesFromIndexKey("Secret", "team-a", "overrides"):     {"cp1"},
+		ValuesFromIndexKey("ConfigMap", "team-a", "overrides"):  {},
+	} {
+		list := &tenancyv1alpha1.ControlPlaneList{}
+		if err := cl.List(context.TODO(), list, client.MatchingFields{ValuesFromIndexField: key}); err != nil {
+			t.Fatalf("error listing control planes for %s: %v", key, err)
+		}
+		got := []string{}
+		for _, cp := range list.Items {
+			got = append(got, cp.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected control planes %v, got %v", key, want, got)
+		}
+	}
+}
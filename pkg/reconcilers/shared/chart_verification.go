@@ -0,0 +1,51 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"os"
+
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// WriteChartKeyring writes the keyring referenced by the chart verification spec to a temporary
+// file and returns its path, or an empty path when chart verification is not enabled.
+// The caller is responsible for removing the file.
+func (r *BaseReconciler) WriteChartKeyring(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (string, error) {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.ChartVerification == nil {
+		return "", nil
+	}
+
+	data, err := r.GetSecretKeyData(ctx, hcp.Spec.ChartVerification.KeyringSecretRef)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "kflex-keyring-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
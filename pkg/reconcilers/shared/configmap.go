@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/kubestellar/kubeflex/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// GetConfigMapKeyData returns the data stored under the key of a referenced config map
+func (r *BaseReconciler) GetConfigMapKeyData(ctx context.Context, ref tenancyv1alpha1.ConfigMapKeyReference) (string, error) {
+	_ = clog.FromContext(ctx)
+	cm := &v1.ConfigMap{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, cm, &client.GetOptions{}); err != nil {
+		return "", err
+	}
+	data, ok := cm.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in config map %s/%s", ref.Key, ref.Namespace, ref.Name)
+	}
+	return data, nil
+}
+
+// ReconcileControlPlaneConfigMap creates or updates a config map with the given
+// data in the control plane namespace, owned by the control plane
+func (r *BaseReconciler) ReconcileControlPlaneConfigMap(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, name string, data map[string]string) error {
+	_ = clog.FromContext(ctx)
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(cm), cm, &client.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			cm.Data = data
+			if err := controllerutil.SetControllerReference(hcp, cm, r.Scheme); err != nil {
+				return err
+			}
+			if err = r.Client.Create(context.TODO(), cm, &client.CreateOptions{}); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	if !reflect.DeepEqual(cm.Data, data) {
+		cm.Data = data
+		if err = r.Client.Update(context.TODO(), cm, &client.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// GetControlPlaneKubeconfig returns the control plane admin kubeconfig from the secret referenced
+// in the control plane status. When inCluster is set, the kubeconfig reachable from the hosting
+// cluster pods is returned if the control plane type provides one.
+func (r *BaseReconciler) GetControlPlaneKubeconfig(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, inCluster bool) (*clientcmdapi.Config, error) {
+	_ = clog.FromContext(ctx)
+	ref := hcp.Status.SecretRef
+	if ref == nil {
+		return nil, fmt.Errorf("kubeconfig secret for control plane %s is not available yet", hcp.Name)
+	}
+	key := ref.Key
+	if inCluster && ref.InClusterKey != "" {
+		key = ref.InClusterKey
+	}
+
+	secret := &v1.Secret{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret, &client.GetOptions{}); err != nil {
+		return nil, err
+	}
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in secret %s/%s", key, ref.Namespace, ref.Name)
+	}
+	return clientcmd.Load(data)
+}
+
+// GetControlPlaneClientSet returns a clientset for the control plane API server, using
+// the in-cluster kubeconfig when the manager runs in the hosting cluster
+func (r *BaseReconciler) GetControlPlaneClientSet(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (kubernetes.Interface, error) {
+	restConfig, err := r.GetControlPlaneRestConfig(ctx, hcp)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// GetControlPlaneRestConfig returns a rest config for the control plane API server, using
+// the in-cluster kubeconfig when the manager runs in the hosting cluster
+func (r *BaseReconciler) GetControlPlaneRestConfig(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (*rest.Config, error) {
+	konfig, err := r.GetControlPlaneKubeconfig(ctx, hcp, util.IsInCluster())
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.NewDefaultClientConfig(*konfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	v1 "k8s.io/api/core/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// reasons of the events recorded on the control plane
+const (
+	EventReasonNamespaceCreated      = "NamespaceCreated"
+	EventReasonChartInstalled        = "ChartInstalled"
+	EventReasonChartUpgraded         = "ChartUpgraded"
+	EventReasonChartInstallTimeout   = "ChartInstallTimeout"
+	EventReasonIngressCreated        = "IngressCreated"
+	EventReasonIngressUpdated        = "IngressUpdated"
+	EventReasonIngressDeleted        = "IngressDeleted"
+	EventReasonNoIngressController   = "NoIngressController"
+	EventReasonReconcileError        = "ReconcileError"
+	EventReasonPostCreateHookApplied = "PostCreateHookApplied"
+)
+
+// RecordEvent records an event on the control plane so that it shows up in
+// `kubectl describe controlplane`. It is a no-op when no recorder is set.
+func (r *BaseReconciler) RecordEvent(hcp *tenancyv1alpha1.ControlPlane, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(hcp, eventType, reason, messageFmt, args...)
+}
+
+// RecordNormalEvent records an event of type Normal on the control plane
+func (r *BaseReconciler) RecordNormalEvent(hcp *tenancyv1alpha1.ControlPlane, reason, messageFmt string, args ...interface{}) {
+	r.RecordEvent(hcp, v1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+// RecordWarningEvent records an event of type Warning on the control plane
+func (r *BaseReconciler) RecordWarningEvent(hcp *tenancyv1alpha1.ControlPlane, reason, messageFmt string, args ...interface{}) {
+	r.RecordEvent(hcp, v1.EventTypeWarning, reason, messageFmt, args...)
+}
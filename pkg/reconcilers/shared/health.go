@@ -0,0 +1,50 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// SetHealthConditions sets the Ready condition from whether the API server of the control plane
+// is ready, along with the Provisioning and Degraded conditions that tell a control plane still
+// being installed from one that was ready and is now failing
+func SetHealthConditions(hcp *tenancyv1alpha1.ControlPlane, ready bool) {
+	if ready {
+		tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionAvailable())
+		tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionProvisioning(false))
+		tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionDegraded(false))
+		return
+	}
+
+	provisioned := wasReady(hcp)
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionUnavailable())
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionProvisioning(!provisioned))
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionDegraded(provisioned))
+}
+
+// wasReady returns true if the control plane has been ready before, which is recorded by the
+// Provisioning condition turning false. Control planes reconciled before the Provisioning
+// condition was introduced were ready if their Ready condition is true
+func wasReady(hcp *tenancyv1alpha1.ControlPlane) bool {
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeProvisioning); c != nil {
+		return c.Status == corev1.ConditionFalse
+	}
+	return tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions)
+}
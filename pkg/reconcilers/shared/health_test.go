@@ -0,0 +1,46 @@
+package shared
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestSetHealthConditions(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{}
+	expect := func(step string, ready, provisioning, degraded corev1.ConditionStatus) {
+		t.Helper()
+		for conditionType, want := range map[tenancyv1alpha1.ConditionType]corev1.ConditionStatus{
+			tenancyv1alpha1.TypeReady:        ready,
+			tenancyv1alpha1.TypeProvisioning: provisioning,
+			tenancyv1alpha1.TypeDegraded:     degraded,
+		} {
+			c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, conditionType)
+			if c == nil || c.Status != want {
+				t.Errorf("%s: expected %s condition %s, got %v", step, conditionType, want, c)
+			}
+		}
+	}
+
+	SetHealthConditions(hcp, false)
+	expect("installing", corev1.ConditionFalse, corev1.ConditionTrue, corev1.ConditionFalse)
+
+	SetHealthConditions(hcp, true)
+	expect("ready", corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionFalse)
+
+	SetHealthConditions(hcp, false)
+	expect("failing after ready", corev1.ConditionFalse, corev1.ConditionFalse, corev1.ConditionTrue)
+
+	SetHealthConditions(hcp, true)
+	expect("recovered", corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionFalse)
+
+	// a control plane that was ready before the provisioning condition existed is degraded
+	upgraded := &tenancyv1alpha1.ControlPlane{}
+	tenancyv1alpha1.EnsureCondition(upgraded, tenancyv1alpha1.ConditionAvailable())
+	SetHealthConditions(upgraded, false)
+	if c := tenancyv1alpha1.GetCondition(upgraded.Status.Conditions, tenancyv1alpha1.TypeDegraded); c == nil || c.Status != corev1.ConditionTrue {
+		t.Errorf("expected control plane ready before the upgrade to be degraded, got %v", c)
+	}
+}
@@ -18,10 +18,14 @@ package shared
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strings"
 
 	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -33,6 +37,11 @@ import (
 
 const (
 	IngressClassNameNGINX = "nginx"
+
+	annotationSSLPassthrough           = "nginx.ingress.kubernetes.io/ssl-passthrough"
+	annotationBackendProtocol          = "nginx.ingress.kubernetes.io/backend-protocol"
+	annotationCertManagerIssuer        = "cert-manager.io/issuer"
+	annotationCertManagerClusterIssuer = "cert-manager.io/cluster-issuer"
 )
 
 var (
@@ -43,6 +52,21 @@ func (r *BaseReconciler) ReconcileAPIServerIngress(ctx context.Context, hcp *ten
 	_ = clog.FromContext(ctx)
 	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
 
+	// an ingress is not served without an ingress controller, so report it instead of
+	// creating an ingress that leaves the control plane unreachable
+	available, err := r.HasIngressController(ctx)
+	if err != nil {
+		return err
+	}
+	if !available {
+		message := fmt.Sprintf("no ingress controller for the %s ingress class is installed in the hosting cluster, so the API server is not exposed. Install the ingress controller or set spec.expose to %s or %s",
+			IngressClassNameNGINX, tenancyv1alpha1.ExposeNodePort, tenancyv1alpha1.ExposeLoadBalancer)
+		tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionNoIngressController(message))
+		r.RecordWarningEvent(hcp, EventReasonNoIngressController, "%s", message)
+		return nil
+	}
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionIngressAvailable())
+
 	if svcName == "" {
 		svcName = hcp.Name
 	}
@@ -55,40 +79,162 @@ func (r *BaseReconciler) ReconcileAPIServerIngress(ctx context.Context, hcp *ten
 		},
 	}
 
-	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(ingress), ingress, &client.GetOptions{})
+	desired := generateAPIServerIngress(hcp.Name, svcName, namespace, svcPort, GetAPIServerHostname(hcp, domain), hcp.Spec.Ingress)
+	err = r.Client.Get(context.TODO(), client.ObjectKeyFromObject(ingress), ingress, &client.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			ingress = generateAPIServerIngress(hcp.Name, svcName, namespace, svcPort, domain)
-			if err := controllerutil.SetControllerReference(hcp, ingress, r.Scheme); err != nil {
-				return nil
+			if err := controllerutil.SetControllerReference(hcp, desired, r.Scheme); err != nil {
+				return err
 			}
-			if err = r.Client.Create(context.TODO(), ingress, &client.CreateOptions{}); err != nil {
+			if err = r.Client.Create(context.TODO(), desired, &client.CreateOptions{}); err != nil {
 				return err
 			}
+			r.RecordNormalEvent(hcp, EventReasonIngressCreated, "Created ingress %s/%s for host %s", namespace, hcp.Name, GetAPIServerHostname(hcp, domain))
 		}
 		return err
 	}
+
+	// keep the annotations, class, host and TLS in sync with the spec, dropping annotations no
+	// longer set and reverting out-of-band edits. The control plane is kept as the controller
+	// of the ingress, so that edits and deletions of the ingress trigger a reconcile
+	owned := metav1.IsControlledBy(ingress, hcp)
+	if !owned || !reflect.DeepEqual(ingress.Annotations, desired.Annotations) || !reflect.DeepEqual(ingress.Spec.Rules, desired.Spec.Rules) ||
+		!reflect.DeepEqual(ingress.Spec.TLS, desired.Spec.TLS) || !reflect.DeepEqual(ingress.Spec.IngressClassName, desired.Spec.IngressClassName) {
+		if !owned {
+			if err := controllerutil.SetControllerReference(hcp, ingress, r.Scheme); err != nil {
+				return err
+			}
+		}
+		ingress.Annotations = desired.Annotations
+		ingress.Spec.IngressClassName = desired.Spec.IngressClassName
+		ingress.Spec.Rules = desired.Spec.Rules
+		ingress.Spec.TLS = desired.Spec.TLS
+		if err := r.Client.Update(context.TODO(), ingress, &client.UpdateOptions{}); err != nil {
+			return err
+		}
+		r.RecordNormalEvent(hcp, EventReasonIngressUpdated, "Updated ingress %s/%s", namespace, hcp.Name)
+	}
+	return nil
+}
+
+// HasIngressController returns true if the hosting cluster has the IngressClass of the API
+// server ingress, which the ingress controller serving the class installs
+func (r *BaseReconciler) HasIngressController(ctx context.Context) (bool, error) {
+	class := &networkingv1.IngressClass{}
+	err := r.Client.Get(context.TODO(), client.ObjectKey{Name: IngressClassNameNGINX}, class, &client.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteAPIServerIngress deletes the API server ingress of a control plane that is no longer
+// exposed through an ingress, along with its IngressAvailable condition. It is a no-op when the
+// ingress does not exist
+func (r *BaseReconciler) DeleteAPIServerIngress(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	tenancyv1alpha1.RemoveCondition(hcp, tenancyv1alpha1.TypeIngressAvailable)
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hcp.Name,
+			Namespace: namespace,
+		},
+	}
+	if err := r.Client.Delete(context.TODO(), ingress, &client.DeleteOptions{}); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	r.RecordNormalEvent(hcp, EventReasonIngressDeleted, "Deleted ingress %s/%s", namespace, hcp.Name)
 	return nil
 }
 
-func generateAPIServerIngress(name, svcName, namespace string, svcPort int, domain string) *networkingv1.Ingress {
+// GetAPIServerHostname returns the hostname of the API server ingress, which is the hostname
+// set in the spec or else the host generated from the control plane name and domain
+func GetAPIServerHostname(hcp *tenancyv1alpha1.ControlPlane, domain string) string {
+	if hcp.Spec.Ingress != nil && hcp.Spec.Ingress.Hostname != "" {
+		return hcp.Spec.Ingress.Hostname
+	}
+	return util.GenerateDevLocalDNSName(hcp.Name, domain)
+}
+
+// ValidateIngress checks that the ingress hostname is a valid DNS name, and that a secret is
+// set when TLS is terminated at the ingress
+func ValidateIngress(spec *tenancyv1alpha1.IngressSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if spec.Hostname != "" {
+		if errs := validation.IsDNS1123Subdomain(spec.Hostname); len(errs) > 0 {
+			return fmt.Errorf("invalid ingress hostname %q: %s", spec.Hostname, strings.Join(errs, ", "))
+		}
+	}
+	if tls := spec.TLS; tls != nil && !tls.Passthrough {
+		if tls.SecretName == "" {
+			return fmt.Errorf("spec.ingress.tls.secretName is required unless passthrough is set")
+		}
+		if errs := validation.IsDNS1123Subdomain(tls.SecretName); len(errs) > 0 {
+			return fmt.Errorf("invalid ingress TLS secret name %q: %s", tls.SecretName, strings.Join(errs, ", "))
+		}
+		if tls.IssuerKind != "" && tls.Issuer == "" {
+			return fmt.Errorf("spec.ingress.tls.issuerKind requires spec.ingress.tls.issuer")
+		}
+	}
+	return nil
+}
+
+// isIngressTLSTerminated returns true if the ingress terminates TLS instead of passing it
+// through to the API server
+func isIngressTLSTerminated(spec *tenancyv1alpha1.IngressSpec) bool {
+	return spec != nil && spec.TLS != nil && !spec.TLS.Passthrough
+}
+
+// ingressTLSAnnotations returns the annotations that configure the TLS of the ingress
+func ingressTLSAnnotations(spec *tenancyv1alpha1.IngressSpec) map[string]string {
+	if !isIngressTLSTerminated(spec) {
+		return map[string]string{annotationSSLPassthrough: "true"}
+	}
+	// the API server only serves TLS
+	annotations := map[string]string{annotationBackendProtocol: "HTTPS"}
+	if spec.TLS.Issuer != "" {
+		if spec.TLS.IssuerKind == tenancyv1alpha1.CertManagerIssuerKindClusterIssuer {
+			annotations[annotationCertManagerClusterIssuer] = spec.TLS.Issuer
+		} else {
+			annotations[annotationCertManagerIssuer] = spec.TLS.Issuer
+		}
+	}
+	return annotations
+}
+
+func generateAPIServerIngress(name, svcName, namespace string, svcPort int, host string, spec *tenancyv1alpha1.IngressSpec) *networkingv1.Ingress {
+	annotations := ingressTLSAnnotations(spec)
+	if spec != nil {
+		for key, value := range spec.Annotations {
+			annotations[key] = value
+		}
+	}
+	var tls []networkingv1.IngressTLS
+	if isIngressTLSTerminated(spec) {
+		tls = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: spec.TLS.SecretName}}
+	}
 	return &networkingv1.Ingress{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Ingress",
 			APIVersion: "networking.k8s.io/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-			Annotations: map[string]string{
-				"nginx.ingress.kubernetes.io/ssl-passthrough": "true",
-			},
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: annotations,
 		},
 		Spec: networkingv1.IngressSpec{
 			IngressClassName: pointer.String(IngressClassNameNGINX),
+			TLS:              tls,
 			Rules: []networkingv1.IngressRule{
 				{
-					Host: util.GenerateDevLocalDNSName(name, domain),
+					Host: host,
 					IngressRuleValue: networkingv1.IngressRuleValue{
 						HTTP: &networkingv1.HTTPIngressRuleValue{
 							Paths: []networkingv1.HTTPIngressPath{
@@ -0,0 +1,321 @@
+package shared
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileAPIServerIngressCustomization(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			Ingress: &tenancyv1alpha1.IngressSpec{
+				Annotations: map[string]string{
+					"traefik.ingress.kubernetes.io/router.tls": "true",
+					"example.com/owner":                        "team-a",
+				},
+				Hostname: "cp1.example.com",
+			},
+		},
+	}
+	r, cl := newTestBaseReconciler(t, hcp, newTestIngressClass())
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	ingress := getTestIngress(t, cl, hcp.Name)
+	if ingress.Spec.Rules[0].Host != "cp1.example.com" {
+		t.Errorf("expected host cp1.example.com, got %s", ingress.Spec.Rules[0].Host)
+	}
+	for key, value := range map[string]string{
+		"nginx.ingress.kubernetes.io/ssl-passthrough": "true",
+		"traefik.ingress.kubernetes.io/router.tls":    "true",
+		"example.com/owner":                           "team-a",
+	} {
+		if ingress.Annotations[key] != value {
+			t.Errorf("expected annotation %s=%s, got %q", key, value, ingress.Annotations[key])
+		}
+	}
+
+	// changes to the spec update the ingress and drop stale annotations
+	hcp.Spec.Ingress = &tenancyv1alpha1.IngressSpec{
+		Annotations: map[string]string{"traefik.ingress.kubernetes.io/router.tls": "true"},
+	}
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	ingress = getTestIngress(t, cl, hcp.Name)
+	if expected := util.GenerateDevLocalDNSName(hcp.Name, "localtest.me"); ingress.Spec.Rules[0].Host != expected {
+		t.Errorf("expected host %s, got %s", expected, ingress.Spec.Rules[0].Host)
+	}
+	if _, ok := ingress.Annotations["example.com/owner"]; ok {
+		t.Errorf("expected stale annotation example.com/owner to be removed")
+	}
+	if ingress.Annotations["traefik.ingress.kubernetes.io/router.tls"] != "true" {
+		t.Errorf("expected annotation traefik.ingress.kubernetes.io/router.tls to be kept")
+	}
+}
+
+func TestReconcileAPIServerIngressTLS(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeK8S,
+			Ingress: &tenancyv1alpha1.IngressSpec{
+				Hostname: "cp1.example.com",
+				TLS: &tenancyv1alpha1.IngressTLSSpec{
+					SecretName: "cp1-tls",
+					Issuer:     "letsencrypt",
+					IssuerKind: tenancyv1alpha1.CertManagerIssuerKindClusterIssuer,
+				},
+			},
+		},
+	}
+	r, cl := newTestBaseReconciler(t, hcp, newTestIngressClass())
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	ingress := getTestIngress(t, cl, hcp.Name)
+	if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != "cp1-tls" || ingress.Spec.TLS[0].Hosts[0] != "cp1.example.com" {
+		t.Errorf("unexpected ingress TLS %+v", ingress.Spec.TLS)
+	}
+	if ingress.Annotations[annotationCertManagerClusterIssuer] != "letsencrypt" {
+		t.Errorf("expected cluster issuer annotation, got %v", ingress.Annotations)
+	}
+	if ingress.Annotations[annotationBackendProtocol] != "HTTPS" {
+		t.Errorf("expected HTTPS backend protocol annotation, got %v", ingress.Annotations)
+	}
+	if _, ok := ingress.Annotations[annotationSSLPassthrough]; ok {
+		t.Errorf("expected no passthrough annotation when TLS is terminated at the ingress")
+	}
+
+	// switching to passthrough updates the live ingress
+	hcp.Spec.Ingress.TLS.Passthrough = true
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	ingress = getTestIngress(t, cl, hcp.Name)
+	if len(ingress.Spec.TLS) != 0 {
+		t.Errorf("expected no ingress TLS with passthrough, got %+v", ingress.Spec.TLS)
+	}
+	if ingress.Annotations[annotationSSLPassthrough] != "true" {
+		t.Errorf("expected passthrough annotation, got %v", ingress.Annotations)
+	}
+	for _, key := range []string{annotationCertManagerClusterIssuer, annotationBackendProtocol} {
+		if _, ok := ingress.Annotations[key]; ok {
+			t.Errorf("expected annotation %s to be removed", key)
+		}
+	}
+}
+
+func TestValidateIngress(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *tenancyv1alpha1.IngressSpec
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"no hostname", &tenancyv1alpha1.IngressSpec{Annotations: map[string]string{"a": "b"}}, false},
+		{"valid hostname", &tenancyv1alpha1.IngressSpec{Hostname: "cp1.example.com"}, false},
+		{"invalid hostname", &tenancyv1alpha1.IngressSpec{Hostname: "CP1_example"}, true},
+		{"tls secret", &tenancyv1alpha1.IngressSpec{TLS: &tenancyv1alpha1.IngressTLSSpec{SecretName: "cp1-tls", Issuer: "ca"}}, false},
+		{"tls passthrough", &tenancyv1alpha1.IngressSpec{TLS: &tenancyv1alpha1.IngressTLSSpec{Passthrough: true}}, false},
+		{"tls without secret", &tenancyv1alpha1.IngressSpec{TLS: &tenancyv1alpha1.IngressTLSSpec{Issuer: "ca"}}, true},
+		{"tls issuer kind without issuer", &tenancyv1alpha1.IngressSpec{TLS: &tenancyv1alpha1.IngressTLSSpec{SecretName: "cp1-tls", IssuerKind: tenancyv1alpha1.CertManagerIssuerKindIssuer}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateIngress(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateIngress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func getTestIngress(t *testing.T, cl client.Client, name string) *networkingv1.Ingress {
+	t.Helper()
+	ingress := &networkingv1.Ingress{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(name), Name: name}
+	if err := cl.Get(context.Background(), key, ingress); err != nil {
+		t.Fatalf("error getting ingress: %v", err)
+	}
+	return ingress
+}
+
+func TestGetAPIServerEndpoint(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp1"}}
+	cfg := &SharedConfig{Domain: "localtest.me", ExternalPort: 9443}
+	if endpoint := GetAPIServerEndpoint(hcp, cfg); endpoint != "https://"+util.GenerateDevLocalDNSName("cp1", "localtest.me")+":9443" {
+		t.Errorf("unexpected default endpoint %s", endpoint)
+	}
+
+	hcp.Spec.Ingress = &tenancyv1alpha1.IngressSpec{Hostname: "cp1.example.com"}
+	if endpoint := GetAPIServerEndpoint(hcp, cfg); endpoint != "https://cp1.example.com:9443" {
+		t.Errorf("unexpected endpoint for custom hostname %s", endpoint)
+	}
+
+	cfg.ExternalURL = "cp1-route.apps.example.com"
+	if endpoint := GetAPIServerEndpoint(hcp, cfg); endpoint != "https://cp1-route.apps.example.com" {
+		t.Errorf("unexpected endpoint for route %s", endpoint)
+	}
+}
+
+func TestDeleteAPIServerIngress(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestBaseReconciler(t, hcp, newTestIngressClass())
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	if err := r.DeleteAPIServerIngress(ctx, hcp); err != nil {
+		t.Fatalf("DeleteAPIServerIngress returned error: %v", err)
+	}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: hcp.Name}
+	if err := cl.Get(ctx, key, &networkingv1.Ingress{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the ingress to be deleted, got %v", err)
+	}
+	// deleting an ingress that does not exist is a no-op
+	if err := r.DeleteAPIServerIngress(ctx, hcp); err != nil {
+		t.Errorf("expected no error when the ingress does not exist, got %v", err)
+	}
+}
+
+func TestReconcileAPIServerIngressDrift(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1", UID: "cp1-uid"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestBaseReconciler(t, hcp, newTestIngressClass())
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	ingress := getTestIngress(t, cl, hcp.Name)
+	if !metav1.IsControlledBy(ingress, hcp) {
+		t.Fatalf("expected the ingress to be controlled by the control plane, got %+v", ingress.OwnerReferences)
+	}
+
+	// an ingress deleted out-of-band is recreated
+	if err := cl.Delete(ctx, ingress); err != nil {
+		t.Fatalf("error deleting ingress: %v", err)
+	}
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	ingress = getTestIngress(t, cl, hcp.Name)
+	if !metav1.IsControlledBy(ingress, hcp) {
+		t.Errorf("expected the recreated ingress to be controlled by the control plane")
+	}
+
+	// out-of-band edits are reverted and the owner reference is restored
+	host := ingress.Spec.Rules[0].Host
+	ingress.OwnerReferences = nil
+	ingress.Spec.IngressClassName = nil
+	ingress.Spec.Rules[0].Host = "edited.example.com"
+	if err := cl.Update(ctx, ingress); err != nil {
+		t.Fatalf("error updating ingress: %v", err)
+	}
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	ingress = getTestIngress(t, cl, hcp.Name)
+	if ingress.Spec.Rules[0].Host != host {
+		t.Errorf("expected host %s to be restored, got %s", host, ingress.Spec.Rules[0].Host)
+	}
+	if ingress.Spec.IngressClassName == nil || *ingress.Spec.IngressClassName != IngressClassNameNGINX {
+		t.Errorf("expected ingress class %s to be restored, got %v", IngressClassNameNGINX, ingress.Spec.IngressClassName)
+	}
+	if !metav1.IsControlledBy(ingress, hcp) {
+		t.Errorf("expected the owner reference to be restored, got %+v", ingress.OwnerReferences)
+	}
+}
+
+func TestReconcileAPIServerIngressNoIngressController(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestBaseReconciler(t, hcp)
+
+	ctx := context.Background()
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: hcp.Name}
+	if err := cl.Get(ctx, key, &networkingv1.Ingress{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no ingress without an ingress controller, got %v", err)
+	}
+	c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeIngressAvailable)
+	if c == nil || c.Status != corev1.ConditionFalse || c.Reason != tenancyv1alpha1.ReasonNoIngressController {
+		t.Fatalf("expected IngressAvailable condition with reason %s, got %+v", tenancyv1alpha1.ReasonNoIngressController, c)
+	}
+	if !strings.Contains(c.Message, string(tenancyv1alpha1.ExposeNodePort)) {
+		t.Errorf("expected the message to suggest the nodeport mode, got %q", c.Message)
+	}
+
+	// the health conditions of the API server do not overwrite it
+	SetHealthConditions(hcp, true)
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeIngressAvailable); c == nil || c.Status != corev1.ConditionFalse {
+		t.Errorf("expected IngressAvailable to stay false, got %+v", c)
+	}
+
+	// the ingress class of another ingress controller does not serve the ingress
+	other := &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "traefik"},
+		Spec:       networkingv1.IngressClassSpec{Controller: "traefik.io/ingress-controller"},
+	}
+	if err := cl.Create(ctx, other); err != nil {
+		t.Fatalf("error creating ingress class: %v", err)
+	}
+	if available, err := r.HasIngressController(ctx); err != nil || available {
+		t.Errorf("expected no ingress controller for the %s class, got %v (%v)", IngressClassNameNGINX, available, err)
+	}
+
+	// the ingress is created once an ingress controller is installed
+	if err := cl.Create(ctx, newTestIngressClass()); err != nil {
+		t.Fatalf("error creating ingress class: %v", err)
+	}
+	if err := r.ReconcileAPIServerIngress(ctx, hcp, "", DefaulPort, "localtest.me"); err != nil {
+		t.Fatalf("ReconcileAPIServerIngress returned error: %v", err)
+	}
+	getTestIngress(t, cl, hcp.Name)
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeIngressAvailable); c == nil || c.Status != corev1.ConditionTrue {
+		t.Errorf("expected IngressAvailable to turn true, got %+v", c)
+	}
+
+	// the condition is removed with the ingress when the control plane is no longer exposed
+	// through an ingress
+	if err := r.DeleteAPIServerIngress(ctx, hcp); err != nil {
+		t.Fatalf("DeleteAPIServerIngress returned error: %v", err)
+	}
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeIngressAvailable); c != nil {
+		t.Errorf("expected no IngressAvailable condition, got %+v", c)
+	}
+}
+
+// newTestIngressClass returns the ingress class installed by an ingress controller
+func newTestIngressClass() *networkingv1.IngressClass {
+	return &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: IngressClassNameNGINX},
+		Spec:       networkingv1.IngressClassSpec{Controller: "k8s.io/ingress-nginx"},
+	}
+}
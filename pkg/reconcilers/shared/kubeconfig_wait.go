@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	// KubeconfigRequeueMinDelay is the first delay the reconcile is re-queued after while the
+	// kubeconfig secret of the control plane does not exist
+	KubeconfigRequeueMinDelay = time.Second
+	// KubeconfigRequeueMaxDelay bounds the delay the reconcile is re-queued after while the
+	// kubeconfig secret of the control plane does not exist
+	KubeconfigRequeueMaxDelay = 30 * time.Second
+)
+
+// WaitForKubeconfigSecret checks that the kubeconfig secret of the control plane type holds
+// its kubeconfig key. While it does not, it sets the Synced condition to WaitingForKubeconfig
+// and returns the delay to re-queue the reconcile after, which doubles with each wait up to
+// KubeconfigRequeueMaxDelay. It returns a zero delay once the kubeconfig exists.
+func (r *BaseReconciler) WaitForKubeconfigSecret(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (time.Duration, error) {
+	if err := util.ValidateControlPlaneType(string(hcp.Spec.Type)); err != nil {
+		return 0, err
+	}
+	name := util.GetKubeconfSecretNameByControlPlaneType(string(hcp.Spec.Type))
+	key := util.GetKubeconfSecretKeyNameByControlPlaneType(string(hcp.Spec.Type))
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+
+	secret := &v1.Secret{}
+	err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, secret, &client.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return 0, err
+	}
+	if err == nil && len(secret.Data[key]) > 0 {
+		return 0, nil
+	}
+
+	message := fmt.Sprintf("waiting for kubeconfig in secret %s/%s key %s", namespace, name, key)
+	ControlPlaneLogger(ctx, hcp).V(1).Info("Waiting for the kubeconfig secret", "status", message)
+	condition := tenancyv1alpha1.ConditionWaitingForKubeconfig(message)
+	// keep when the wait started, which sets the delay
+	if existing := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeSynced); existing != nil &&
+		existing.Reason == tenancyv1alpha1.ReasonWaitingForKubeconfig {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	delay := kubeconfigRequeueDelay(time.Since(condition.LastTransitionTime.Time))
+	tenancyv1alpha1.EnsureCondition(hcp, condition)
+	return delay, r.updateStatus(hcp)
+}
+
+// kubeconfigRequeueDelay returns the time already spent waiting, bounded by the min and max
+// delays, so that the next wait ends after twice the time spent so far
+func kubeconfigRequeueDelay(waited time.Duration) time.Duration {
+	if waited < KubeconfigRequeueMinDelay {
+		return KubeconfigRequeueMinDelay
+	}
+	if waited > KubeconfigRequeueMaxDelay {
+		return KubeconfigRequeueMaxDelay
+	}
+	return waited
+}
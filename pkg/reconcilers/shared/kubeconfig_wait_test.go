@@ -0,0 +1,74 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestWaitForKubeconfigSecret(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	r, cl := newTestBaseReconciler(t, hcp)
+
+	delay, err := r.WaitForKubeconfigSecret(context.TODO(), hcp)
+	if err != nil {
+		t.Fatalf("WaitForKubeconfigSecret returned error: %v", err)
+	}
+	if delay != KubeconfigRequeueMinDelay {
+		t.Errorf("expected the first delay to be %s, got %s", KubeconfigRequeueMinDelay, delay)
+	}
+	condition := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeSynced)
+	if condition == nil || condition.Reason != tenancyv1alpha1.ReasonWaitingForKubeconfig {
+		t.Fatalf("expected the Synced condition to wait for the kubeconfig, got %+v", condition)
+	}
+
+	// the delay grows with the time spent waiting
+	condition.LastTransitionTime = metav1.NewTime(time.Now().Add(-8 * time.Second))
+	delay, err = r.WaitForKubeconfigSecret(context.TODO(), hcp)
+	if err != nil {
+		t.Fatalf("WaitForKubeconfigSecret returned error: %v", err)
+	}
+	if delay < 8*time.Second || delay > 9*time.Second {
+		t.Errorf("expected a delay of about 8s, got %s", delay)
+	}
+	condition = tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeSynced)
+	condition.LastTransitionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	if delay, _ = r.WaitForKubeconfigSecret(context.TODO(), hcp); delay != KubeconfigRequeueMaxDelay {
+		t.Errorf("expected the delay to be bounded by %s, got %s", KubeconfigRequeueMaxDelay, delay)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.VClusterKubeConfigSecret,
+			Namespace: util.GenerateNamespaceFromControlPlaneName("cp1"),
+		},
+		Data: map[string][]byte{util.KubeconfigSecretKeyVCluster: []byte("kubeconfig")},
+	}
+	if err := cl.Create(context.TODO(), secret); err != nil {
+		t.Fatalf("error creating the kubeconfig secret: %v", err)
+	}
+	delay, err = r.WaitForKubeconfigSecret(context.TODO(), hcp)
+	if err != nil || delay != 0 {
+		t.Errorf("expected no delay once the kubeconfig exists, got %s, %v", delay, err)
+	}
+}
+
+func TestWaitForKubeconfigSecretUnsupportedType(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: "unknown"},
+	}
+	r, _ := newTestBaseReconciler(t, hcp)
+	if _, err := r.WaitForKubeconfigSecret(context.TODO(), hcp); err == nil {
+		t.Errorf("expected WaitForKubeconfigSecret to reject the unknown control plane type")
+	}
+}
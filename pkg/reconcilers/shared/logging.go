@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// ControlPlaneLogger returns the logger of ctx with the name, type and namespace of the
+// control plane as fields, so that the log lines of a reconcile can be grouped per control plane
+func ControlPlaneLogger(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) logr.Logger {
+	return clog.FromContext(ctx).WithValues(controlPlaneLogFields(hcp)...)
+}
+
+// LogPhase logs at debug level that a reconcile phase started at start is completed
+func LogPhase(logger logr.Logger, phase string, start time.Time) {
+	logger.V(1).Info("Reconciled phase", "phase", phase, "duration", time.Since(start).String())
+}
+
+func controlPlaneLogFields(hcp *tenancyv1alpha1.ControlPlane) []interface{} {
+	return []interface{}{
+		"controlPlane", hcp.Name,
+		"controlPlaneType", string(hcp.Spec.Type),
+		"namespace", util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+	}
+}
@@ -0,0 +1,37 @@
+package shared
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestControlPlaneLogger(t *testing.T) {
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: 1})
+	ctx := clog.IntoContext(context.Background(), logger)
+
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	LogPhase(ControlPlaneLogger(ctx, hcp), "chart", time.Now())
+
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 log line, got %d", len(lines))
+	}
+	for _, field := range []string{`"controlPlane"="cp1"`, `"controlPlaneType"="vcluster"`, `"namespace"="cp1-system"`, `"phase"="chart"`, `"duration"=`} {
+		if !strings.Contains(lines[0], field) {
+			t.Errorf("expected log line to contain %s, got %s", field, lines[0])
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// outcomes of a control plane reconcile
+const (
+	ReconcileOutcomeSuccess = "success"
+	ReconcileOutcomeError   = "error"
+)
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubeflex_controlplane_reconcile_total",
+			Help: "Number of control plane reconciles by control plane type and outcome",
+		},
+		[]string{"type", "outcome"},
+	)
+	chartInstallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kubeflex_chart_install_duration_seconds",
+			Help:    "Duration of the control plane chart installs by control plane type and chart",
+			Buckets: []float64{5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{"type", "chart"},
+	)
+	controlPlaneReady = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubeflex_controlplane_ready",
+			Help: "Whether a control plane is ready (1) or not (0)",
+		},
+		[]string{"name", "type"},
+	)
+	chartOpsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kubeflex_chart_operations_in_flight",
+			Help: "Number of control plane chart operations currently running",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileTotal, chartInstallDuration, controlPlaneReady, chartOpsInFlight)
+}
+
+// recordReconcileMetrics counts a reconcile of the control plane with the given outcome,
+// and records whether the control plane is ready
+func recordReconcileMetrics(hcp *tenancyv1alpha1.ControlPlane, outcome string) {
+	reconcileTotal.WithLabelValues(string(hcp.Spec.Type), outcome).Inc()
+	ready := 0.0
+	if tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		ready = 1
+	}
+	controlPlaneReady.WithLabelValues(hcp.Name, string(hcp.Spec.Type)).Set(ready)
+}
+
+// ObserveChartInstall records the duration of a chart install started at start
+func ObserveChartInstall(hcp *tenancyv1alpha1.ControlPlane, chartName string, start time.Time) {
+	chartInstallDuration.WithLabelValues(string(hcp.Spec.Type), chartName).Observe(time.Since(start).Seconds())
+}
+
+// DeleteControlPlaneMetrics removes the per control plane series of a deleted control plane
+func DeleteControlPlaneMetrics(hcp *tenancyv1alpha1.ControlPlane) {
+	controlPlaneReady.DeleteLabelValues(hcp.Name, string(hcp.Spec.Type))
+}
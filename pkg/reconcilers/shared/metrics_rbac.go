@@ -0,0 +1,241 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+const (
+	MetricsReaderSecretName         = "metrics-reader-kubeconfig"
+	MetricsReaderKubeconfigKey      = "kubeconfig"
+	MetricsReaderTokenKey           = "token"
+	MetricsReaderServiceAccountKey  = "serviceAccount"
+	MetricsReaderNamespace          = "kube-system"
+	DefaultMetricsReaderAccountName = "kflex-metrics-reader"
+	metricsReaderContextName        = "metrics-reader"
+)
+
+// ReconcileMetricsRBAC creates, inside the control plane, a service account bound to a cluster role
+// allowed to read the metrics endpoints, and stores a kubeconfig with its token in the control
+// plane namespace. When metrics RBAC is disabled, previously created resources are removed.
+func (r *BaseReconciler) ReconcileMetricsRBAC(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.MetricsRBAC == nil {
+		secret, err := r.getMetricsReaderSecret(hcp)
+		if err != nil || secret == nil {
+			return err
+		}
+	}
+
+	cpClient, err := r.GetControlPlaneClientSet(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	// the kubeconfig is used by a scraper in the hosting cluster
+	konfig, err := r.GetControlPlaneKubeconfig(ctx, hcp, true)
+	if err != nil {
+		return err
+	}
+	return r.reconcileMetricsRBAC(ctx, hcp, cpClient, konfig)
+}
+
+func (r *BaseReconciler) reconcileMetricsRBAC(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, cpClient kubernetes.Interface, konfig *clientcmdapi.Config) error {
+	secret, err := r.getMetricsReaderSecret(hcp)
+	if err != nil {
+		return err
+	}
+
+	if hcp.Spec.MetricsRBAC == nil {
+		if secret == nil {
+			return nil
+		}
+		if err := deleteMetricsReader(ctx, cpClient, string(secret.Data[MetricsReaderServiceAccountKey])); err != nil {
+			return err
+		}
+		return client.IgnoreNotFound(r.Client.Delete(context.TODO(), secret))
+	}
+
+	name := hcp.Spec.MetricsRBAC.ServiceAccountName
+	if name == "" {
+		name = DefaultMetricsReaderAccountName
+	}
+	if secret != nil {
+		if previous := string(secret.Data[MetricsReaderServiceAccountKey]); previous != "" && previous != name {
+			if err := deleteMetricsReader(ctx, cpClient, previous); err != nil {
+				return err
+			}
+		}
+	}
+
+	token, err := ensureMetricsReader(ctx, cpClient, name)
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := generateMetricsReaderKubeconfig(konfig, token)
+	if err != nil {
+		return err
+	}
+	return r.ReconcileControlPlaneSecret(ctx, hcp, MetricsReaderSecretName, v1.SecretTypeOpaque, map[string][]byte{
+		MetricsReaderKubeconfigKey:     kubeconfig,
+		MetricsReaderTokenKey:          token,
+		MetricsReaderServiceAccountKey: []byte(name),
+	})
+}
+
+func (r *BaseReconciler) getMetricsReaderSecret(hcp *tenancyv1alpha1.ControlPlane) (*v1.Secret, error) {
+	secret := &v1.Secret{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: MetricsReaderSecretName}
+	if err := r.Client.Get(context.TODO(), key, secret, &client.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// ensureMetricsReader creates the metrics reader service account, cluster role, binding and
+// token secret in the control plane, and returns the token once issued
+func ensureMetricsReader(ctx context.Context, cpClient kubernetes.Interface, name string) ([]byte, error) {
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: MetricsReaderNamespace},
+	}
+	if _, err := cpClient.CoreV1().ServiceAccounts(MetricsReaderNamespace).Create(ctx, sa, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Rules: []rbacv1.PolicyRule{
+			{
+				NonResourceURLs: []string{"/metrics", "/metrics/*"},
+				Verbs:           []string{"get"},
+			},
+		},
+	}
+	if _, err := cpClient.RbacV1().ClusterRoles().Create(ctx, role, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     name,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: MetricsReaderNamespace,
+			},
+		},
+	}
+	if _, err := cpClient.RbacV1().ClusterRoleBindings().Create(ctx, binding, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	// tokens are no longer generated by default for service accounts, so request a long lived
+	// token through a service account token secret, populated by the token controller
+	tokenSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   MetricsReaderNamespace,
+			Annotations: map[string]string{v1.ServiceAccountNameKey: name},
+		},
+		Type: v1.SecretTypeServiceAccountToken,
+	}
+	if _, err := cpClient.CoreV1().Secrets(MetricsReaderNamespace).Create(ctx, tokenSecret, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	tokenSecret, err := cpClient.CoreV1().Secrets(MetricsReaderNamespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	token := tokenSecret.Data[v1.ServiceAccountTokenKey]
+	if len(token) == 0 {
+		return nil, fmt.Errorf("token for service account %s/%s has not been issued yet", MetricsReaderNamespace, name)
+	}
+	return token, nil
+}
+
+// deleteMetricsReader removes the metrics reader resources from the control plane
+func deleteMetricsReader(ctx context.Context, cpClient kubernetes.Interface, name string) error {
+	if name == "" {
+		return nil
+	}
+	deletes := []func() error{
+		func() error { return cpClient.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{}) },
+		func() error { return cpClient.RbacV1().ClusterRoles().Delete(ctx, name, metav1.DeleteOptions{}) },
+		func() error {
+			return cpClient.CoreV1().Secrets(MetricsReaderNamespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+		func() error {
+			return cpClient.CoreV1().ServiceAccounts(MetricsReaderNamespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	}
+	for _, del := range deletes {
+		if err := del(); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateMetricsReaderKubeconfig returns a kubeconfig for the server and CA of the current
+// context of konfig, authenticating with token
+func generateMetricsReaderKubeconfig(konfig *clientcmdapi.Config, token []byte) ([]byte, error) {
+	kctx, ok := konfig.Contexts[konfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %s not found in control plane kubeconfig", konfig.CurrentContext)
+	}
+	cluster, ok := konfig.Clusters[kctx.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found in control plane kubeconfig", kctx.Cluster)
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[metricsReaderContextName] = &clientcmdapi.Cluster{
+		Server:                   cluster.Server,
+		CertificateAuthorityData: cluster.CertificateAuthorityData,
+		TLSServerName:            cluster.TLSServerName,
+		InsecureSkipTLSVerify:    cluster.InsecureSkipTLSVerify,
+	}
+	config.AuthInfos[metricsReaderContextName] = &clientcmdapi.AuthInfo{Token: string(token)}
+	config.Contexts[metricsReaderContextName] = &clientcmdapi.Context{
+		Cluster:  metricsReaderContextName,
+		AuthInfo: metricsReaderContextName,
+	}
+	config.CurrentContext = metricsReaderContextName
+	return clientcmd.Write(*config)
+}
@@ -0,0 +1,138 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestReconcileMetricsRBAC(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:        tenancyv1alpha1.ControlPlaneTypeK8S,
+			MetricsRBAC: &tenancyv1alpha1.MetricsRBACSpec{},
+		},
+	}
+	r, cl := newTestBaseReconciler(t, hcp)
+	cpClient := newTestControlPlaneClient()
+	konfig := clientcmdapi.NewConfig()
+	konfig.Clusters["cp1-cluster"] = &clientcmdapi.Cluster{Server: "https://cp1.cp1-system:443", CertificateAuthorityData: []byte("ca")}
+	konfig.Contexts["cp1"] = &clientcmdapi.Context{Cluster: "cp1-cluster"}
+	konfig.CurrentContext = "cp1"
+
+	ctx := context.Background()
+	if err := r.reconcileMetricsRBAC(ctx, hcp, cpClient, konfig); err != nil {
+		t.Fatalf("reconcileMetricsRBAC returned error: %v", err)
+	}
+	// a second pass must be a no-op
+	if err := r.reconcileMetricsRBAC(ctx, hcp, cpClient, konfig); err != nil {
+		t.Fatalf("reconcileMetricsRBAC returned error on second pass: %v", err)
+	}
+
+	name := DefaultMetricsReaderAccountName
+	if _, err := cpClient.CoreV1().ServiceAccounts(MetricsReaderNamespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected service account %s in the control plane: %v", name, err)
+	}
+	role, err := cpClient.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected cluster role %s in the control plane: %v", name, err)
+	}
+	if len(role.Rules) != 1 || role.Rules[0].NonResourceURLs[0] != "/metrics" || role.Rules[0].Verbs[0] != "get" {
+		t.Errorf("expected cluster role to only grant get on /metrics, got %v", role.Rules)
+	}
+	binding, err := cpClient.RbacV1().ClusterRoleBindings().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected cluster role binding %s in the control plane: %v", name, err)
+	}
+	if binding.RoleRef.Name != name || binding.Subjects[0].Name != name || binding.Subjects[0].Namespace != MetricsReaderNamespace {
+		t.Errorf("unexpected cluster role binding: %+v", binding)
+	}
+
+	secret := &v1.Secret{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: MetricsReaderSecretName}
+	if err := cl.Get(ctx, key, secret); err != nil {
+		t.Fatalf("expected metrics reader kubeconfig secret: %v", err)
+	}
+	if string(secret.Data[MetricsReaderTokenKey]) != "token-"+name {
+		t.Errorf("expected issued token in secret, got %s", secret.Data[MetricsReaderTokenKey])
+	}
+	readerConfig, err := clientcmd.Load(secret.Data[MetricsReaderKubeconfigKey])
+	if err != nil {
+		t.Fatalf("error parsing metrics reader kubeconfig: %v", err)
+	}
+	cluster := readerConfig.Clusters[readerConfig.Contexts[readerConfig.CurrentContext].Cluster]
+	if cluster.Server != "https://cp1.cp1-system:443" || string(cluster.CertificateAuthorityData) != "ca" {
+		t.Errorf("expected metrics reader kubeconfig for the control plane server, got %+v", cluster)
+	}
+	if readerConfig.AuthInfos[readerConfig.Contexts[readerConfig.CurrentContext].AuthInfo].Token != "token-"+name {
+		t.Errorf("expected metrics reader kubeconfig to use the issued token")
+	}
+
+	// disabling removes the resources from the control plane and the hosting cluster
+	hcp.Spec.MetricsRBAC = nil
+	if err := r.reconcileMetricsRBAC(ctx, hcp, cpClient, konfig); err != nil {
+		t.Fatalf("reconcileMetricsRBAC returned error when disabled: %v", err)
+	}
+	if _, err := cpClient.RbacV1().ClusterRoleBindings().Get(ctx, name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected cluster role binding to be removed, got %v", err)
+	}
+	if _, err := cpClient.CoreV1().ServiceAccounts(MetricsReaderNamespace).Get(ctx, name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected service account to be removed, got %v", err)
+	}
+	if err := cl.Get(ctx, key, &v1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected metrics reader kubeconfig secret to be removed, got %v", err)
+	}
+}
+
+func TestReconcileMetricsRBACTokenNotIssued(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{MetricsRBAC: &tenancyv1alpha1.MetricsRBACSpec{}},
+	}
+	r, _ := newTestBaseReconciler(t, hcp)
+	if err := r.reconcileMetricsRBAC(context.Background(), hcp, kubefake.NewSimpleClientset(), clientcmdapi.NewConfig()); err == nil {
+		t.Errorf("expected error while the token is not issued")
+	}
+}
+
+// newTestControlPlaneClient returns a fake control plane clientset that issues a token
+// for service account token secrets, as the token controller does
+func newTestControlPlaneClient() *kubefake.Clientset {
+	cpClient := kubefake.NewSimpleClientset()
+	cpClient.PrependReactor("create", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		secret := action.(clienttesting.CreateAction).GetObject().(*v1.Secret)
+		if secret.Type == v1.SecretTypeServiceAccountToken {
+			secret.Data = map[string][]byte{v1.ServiceAccountTokenKey: []byte("token-" + secret.Annotations[v1.ServiceAccountNameKey])}
+		}
+		return false, nil, nil
+	})
+	return cpClient
+}
+
+// newTestBaseReconciler returns a reconciler backed by a fake client holding objs
+func newTestBaseReconciler(t *testing.T, objs ...client.Object) (*BaseReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding client-go scheme: %v", err)
+	}
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&tenancyv1alpha1.ControlPlane{}).Build()
+	return &BaseReconciler{Client: cl, Scheme: scheme}, cl
+}
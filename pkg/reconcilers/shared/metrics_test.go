@@ -0,0 +1,47 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestReconcileMetrics(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-cp"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	r, _ := newTestBaseReconciler(t, hcp)
+	vclusterType := string(tenancyv1alpha1.ControlPlaneTypeVCluster)
+	successBefore := testutil.ToFloat64(reconcileTotal.WithLabelValues(vclusterType, ReconcileOutcomeSuccess))
+	errorBefore := testutil.ToFloat64(reconcileTotal.WithLabelValues(vclusterType, ReconcileOutcomeError))
+
+	_, _ = r.UpdateStatusForSyncingError(hcp, errors.New("chart install failed"))
+	if got := testutil.ToFloat64(reconcileTotal.WithLabelValues(vclusterType, ReconcileOutcomeError)); got != errorBefore+1 {
+		t.Errorf("expected error count %v, got %v", errorBefore+1, got)
+	}
+	if got := testutil.ToFloat64(controlPlaneReady.WithLabelValues("metrics-cp", vclusterType)); got != 0 {
+		t.Errorf("expected control plane not ready, got %v", got)
+	}
+
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionAvailable())
+	if _, err := r.UpdateStatusForSyncingSuccess(context.Background(), hcp); err != nil {
+		t.Fatalf("UpdateStatusForSyncingSuccess returned error: %v", err)
+	}
+	if got := testutil.ToFloat64(reconcileTotal.WithLabelValues(vclusterType, ReconcileOutcomeSuccess)); got != successBefore+1 {
+		t.Errorf("expected success count %v, got %v", successBefore+1, got)
+	}
+	if got := testutil.ToFloat64(controlPlaneReady.WithLabelValues("metrics-cp", vclusterType)); got != 1 {
+		t.Errorf("expected control plane ready, got %v", got)
+	}
+
+	DeleteControlPlaneMetrics(hcp)
+	if got := testutil.CollectAndCount(controlPlaneReady, "kubeflex_controlplane_ready"); got != 0 {
+		t.Errorf("expected no ready series after delete, got %d", got)
+	}
+}
@@ -30,9 +30,21 @@ import (
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
 )
 
+const (
+	// ControlPlaneNameLabelKey labels the namespace of a control plane with the control plane name
+	ControlPlaneNameLabelKey = "kflex.kubestellar.io/controlplane-name"
+	// ControlPlaneTypeLabelKey labels the namespace of a control plane with the control plane type
+	ControlPlaneTypeLabelKey = "kflex.kubestellar.io/controlplane-type"
+)
+
+// ReconcileNamespace creates the namespace of the control plane, owned by the control plane
+// and labeled with its name and type. The labels are added to an existing namespace, keeping
+// the labels set by users. The name of the control plane is validated before its namespace is
+// created, so that control planes created before a name was reserved keep being reconciled.
 func (r *BaseReconciler) ReconcileNamespace(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
 	_ = clog.FromContext(ctx)
 	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	labels := namespaceLabels(hcp)
 
 	// create namespace object
 	ns := &v1.Namespace{
@@ -44,13 +56,62 @@ func (r *BaseReconciler) ReconcileNamespace(ctx context.Context, hcp *tenancyv1a
 	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(ns), ns, &client.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			if err := util.ValidateControlPlaneName(hcp.Name); err != nil {
+				return err
+			}
+			ns.Labels = labels
 			if err := controllerutil.SetControllerReference(hcp, ns, r.Scheme); err != nil {
 				return err
 			}
 			if err = r.Client.Create(context.TODO(), ns, &client.CreateOptions{}); err != nil {
 				return err
 			}
+			r.RecordNormalEvent(hcp, EventReasonNamespaceCreated, "Created namespace %s", namespace)
+		}
+		return err
+	}
+
+	if hasLabels(ns.Labels, labels) {
+		return nil
+	}
+	patch := client.MergeFrom(ns.DeepCopy())
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		ns.Labels[k] = v
+	}
+	return r.Client.Patch(context.TODO(), ns, patch)
+}
+
+// namespaceLabels returns the labels identifying the control plane owning a namespace
+func namespaceLabels(hcp *tenancyv1alpha1.ControlPlane) map[string]string {
+	return map[string]string{
+		ControlPlaneNameLabelKey: hcp.Name,
+		ControlPlaneTypeLabelKey: string(hcp.Spec.Type),
+	}
+}
+
+// hasLabels returns true if all the wanted labels are set in labels with the same value
+func hasLabels(labels, wanted map[string]string) bool {
+	for k, v := range wanted {
+		if labels[k] != v {
+			return false
 		}
+	}
+	return true
+}
+
+// DeleteNamespace deletes the namespace of the control plane. It does not fail
+// if the namespace is already gone or being deleted.
+func (r *BaseReconciler) DeleteNamespace(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+		},
+	}
+	if err := r.Client.Delete(context.TODO(), ns, &client.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
 	return nil
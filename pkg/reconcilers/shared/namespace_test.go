@@ -0,0 +1,155 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestDeleteNamespace(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	nsName := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}}
+	r, cl := newTestBaseReconciler(t, hcp, ns)
+
+	if err := r.DeleteNamespace(context.TODO(), hcp); err != nil {
+		t.Fatalf("DeleteNamespace returned error: %v", err)
+	}
+	err := cl.Get(context.TODO(), client.ObjectKey{Name: nsName}, &v1.Namespace{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected namespace %s to be deleted, got: %v", nsName, err)
+	}
+
+	// a second delete, e.g. when the finalizer is processed again, must succeed
+	if err := r.DeleteNamespace(context.TODO(), hcp); err != nil {
+		t.Fatalf("DeleteNamespace on missing namespace returned error: %v", err)
+	}
+}
+
+func TestReconcileNamespaceRecordsEvents(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	r, _ := newTestBaseReconciler(t, hcp)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	if err := r.ReconcileNamespace(context.TODO(), hcp); err != nil {
+		t.Fatalf("ReconcileNamespace returned error: %v", err)
+	}
+	// the namespace exists now, so a second reconcile must not record another event
+	if err := r.ReconcileNamespace(context.TODO(), hcp); err != nil {
+		t.Fatalf("ReconcileNamespace returned error: %v", err)
+	}
+	if _, err := r.UpdateStatusForSyncingError(hcp, fmt.Errorf("chart not found")); err != nil {
+		t.Fatalf("UpdateStatusForSyncingError returned error: %v", err)
+	}
+	close(recorder.Events)
+
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	if !strings.HasPrefix(events[0], "Normal "+EventReasonNamespaceCreated) {
+		t.Errorf("expected namespace created event, got %q", events[0])
+	}
+	if events[1] != "Warning "+EventReasonReconcileError+" chart not found" {
+		t.Errorf("expected reconcile error event, got %q", events[1])
+	}
+}
+
+func TestReconcileNamespaceLabels(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1", UID: "cp1-uid"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	nsName := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	r, cl := newTestBaseReconciler(t, hcp)
+
+	if err := r.ReconcileNamespace(context.TODO(), hcp); err != nil {
+		t.Fatalf("ReconcileNamespace returned error: %v", err)
+	}
+	ns := &v1.Namespace{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Name: nsName}, ns); err != nil {
+		t.Fatalf("error getting namespace: %v", err)
+	}
+	if ns.Labels[ControlPlaneNameLabelKey] != "cp1" || ns.Labels[ControlPlaneTypeLabelKey] != string(tenancyv1alpha1.ControlPlaneTypeVCluster) {
+		t.Errorf("unexpected namespace labels: %v", ns.Labels)
+	}
+	if len(ns.OwnerReferences) != 1 || ns.OwnerReferences[0].UID != hcp.UID {
+		t.Errorf("expected namespace owned by the control plane, got %v", ns.OwnerReferences)
+	}
+}
+
+func TestReconcileNamespaceLabelsExisting(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	nsName := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	existing := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   nsName,
+		Labels: map[string]string{"team": "a", ControlPlaneTypeLabelKey: "stale"},
+	}}
+	r, cl := newTestBaseReconciler(t, hcp, existing)
+
+	// reconciling twice must leave the same labels
+	for i := 0; i < 2; i++ {
+		if err := r.ReconcileNamespace(context.TODO(), hcp); err != nil {
+			t.Fatalf("ReconcileNamespace returned error: %v", err)
+		}
+	}
+	ns := &v1.Namespace{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Name: nsName}, ns); err != nil {
+		t.Fatalf("error getting namespace: %v", err)
+	}
+	want := map[string]string{
+		"team":                   "a",
+		ControlPlaneNameLabelKey: "cp1",
+		ControlPlaneTypeLabelKey: string(tenancyv1alpha1.ControlPlaneTypeK8S),
+	}
+	if len(ns.Labels) != len(want) || !hasLabels(ns.Labels, want) {
+		t.Errorf("expected labels %v, got %v", want, ns.Labels)
+	}
+}
+
+func TestReconcileNamespaceReservedName(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "list"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	nsName := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	r, cl := newTestBaseReconciler(t, hcp)
+
+	if err := r.ReconcileNamespace(context.TODO(), hcp); err == nil {
+		t.Fatalf("expected error for the reserved name %s", hcp.Name)
+	}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Name: nsName}, &v1.Namespace{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected no namespace for the reserved name, got %v", err)
+	}
+
+	// a control plane created before the name was reserved keeps being reconciled
+	if err := cl.Create(context.TODO(), &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}}); err != nil {
+		t.Fatalf("error creating namespace: %v", err)
+	}
+	if err := r.ReconcileNamespace(context.TODO(), hcp); err != nil {
+		t.Errorf("ReconcileNamespace returned error for an existing namespace: %v", err)
+	}
+}
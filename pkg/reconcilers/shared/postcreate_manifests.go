@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// ReconcilePostCreateManifests applies the manifests held by the ConfigMaps and Secrets in
+// spec.postCreateHooks into the control plane, using a client built from its kubeconfig
+// secret. Each reference is recorded in status.appliedPostCreateHooks once applied, so it is
+// not applied again on the next reconcile.
+func (r *BaseReconciler) ReconcilePostCreateManifests(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	logger := clog.FromContext(ctx)
+
+	var pending []tenancyv1alpha1.PostCreateHookReference
+	for _, ref := range hcp.Spec.PostCreateHooks {
+		if !hcp.Status.AppliedPostCreateHooks[postCreateHookKey(ref)] {
+			pending = append(pending, ref)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	restConfig, err := r.GetControlPlaneRestConfig(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	for _, ref := range pending {
+		key := postCreateHookKey(ref)
+		data, err := r.getPostCreateHookData(ref)
+		if err != nil {
+			return err
+		}
+		objs, err := decodeManifests(data)
+		if err != nil {
+			return fmt.Errorf("error decoding post create hook %s: %w", key, err)
+		}
+		logger.Info("Applying post create hook", "post-create-hook", key, "objects", len(objs))
+		for _, obj := range objs {
+			if err := applyManifest(ctx, dynamicClient, mapper, obj); err != nil {
+				return fmt.Errorf("error applying post create hook %s: %w", key, err)
+			}
+		}
+
+		if hcp.Status.AppliedPostCreateHooks == nil {
+			hcp.Status.AppliedPostCreateHooks = map[string]bool{}
+		}
+		hcp.Status.AppliedPostCreateHooks[key] = true
+		r.RecordNormalEvent(hcp, EventReasonPostCreateHookApplied, "Applied post create hook %s", key)
+	}
+	return nil
+}
+
+// postCreateHookKey returns the key of a post create hook reference in status.appliedPostCreateHooks
+func postCreateHookKey(ref tenancyv1alpha1.PostCreateHookReference) string {
+	return fmt.Sprintf("%s/%s/%s", ref.Kind, ref.Namespace, ref.Name)
+}
+
+// getPostCreateHookData returns the data values of the referenced ConfigMap or Secret,
+// sorted by key so that the manifests are applied in a predictable order
+func (r *BaseReconciler) getPostCreateHookData(ref tenancyv1alpha1.PostCreateHookReference) ([][]byte, error) {
+	data := map[string][]byte{}
+	switch ref.Kind {
+	case "ConfigMap":
+		cm := &v1.ConfigMap{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, cm, &client.GetOptions{}); err != nil {
+			return nil, fmt.Errorf("error retrieving post create hook %s: %w", postCreateHookKey(ref), err)
+		}
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+	case "Secret":
+		secret := &v1.Secret{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret, &client.GetOptions{}); err != nil {
+			return nil, fmt.Errorf("error retrieving post create hook %s: %w", postCreateHookKey(ref), err)
+		}
+		data = secret.Data
+	default:
+		return nil, fmt.Errorf("unsupported post create hook kind %s, must be ConfigMap or Secret", ref.Kind)
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, data[k])
+	}
+	return values, nil
+}
+
+// decodeManifests decodes the objects of YAML or JSON documents, skipping empty documents
+func decodeManifests(data [][]byte) ([]*unstructured.Unstructured, error) {
+	objs := []*unstructured.Unstructured{}
+	for _, d := range data {
+		decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(d), 4096)
+		for {
+			obj := &unstructured.Unstructured{}
+			if err := decoder.Decode(&obj.Object); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, err
+			}
+			if len(obj.Object) == 0 {
+				continue
+			}
+			if obj.GetKind() == "" || obj.GetName() == "" {
+				return nil, fmt.Errorf("manifest without kind or name")
+			}
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// applyManifest server-side applies an object into the control plane. Namespaced objects
+// without namespace go to the default namespace. The discovery cache is refreshed when the
+// kind is unknown, so a hook can create CRDs and then custom resources of those CRDs.
+func applyManifest(ctx context.Context, dynamicClient dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	var resource dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		resource = dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	}
+	_, err = resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{FieldManager: FieldManager, Force: true})
+	return err
+}
@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestGetPostCreateHookManifests(t *testing.T) {
+	r, _ := newTestBaseReconciler(t,
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap", Namespace: "team-a"},
+			Data: map[string]string{
+				"02-rbac.yaml": "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: reader\n",
+				"01-ns.yaml":   "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: apps\n---\n---\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: deployer\n  namespace: apps\n",
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap", Namespace: "team-a"},
+			Data:       map[string][]byte{"bad.yaml": []byte("apiVersion: v1\nmetadata:\n  name: nokind\n")},
+		})
+
+	data, err := r.getPostCreateHookData(tenancyv1alpha1.PostCreateHookReference{Kind: "ConfigMap", Namespace: "team-a", Name: "bootstrap"})
+	if err != nil {
+		t.Fatalf("getPostCreateHookData returned error: %v", err)
+	}
+	objs, err := decodeManifests(data)
+	if err != nil {
+		t.Fatalf("decodeManifests returned error: %v", err)
+	}
+	var kinds []string
+	for _, obj := range objs {
+		kinds = append(kinds, obj.GetKind())
+	}
+	if len(kinds) != 3 || kinds[0] != "Namespace" || kinds[1] != "ServiceAccount" || kinds[2] != "ClusterRole" {
+		t.Errorf("expected manifests in key order, got %v", kinds)
+	}
+
+	data, err = r.getPostCreateHookData(tenancyv1alpha1.PostCreateHookReference{Kind: "Secret", Namespace: "team-a", Name: "bootstrap"})
+	if err != nil {
+		t.Fatalf("getPostCreateHookData returned error: %v", err)
+	}
+	if _, err := decodeManifests(data); err == nil {
+		t.Errorf("expected error for manifest without kind")
+	}
+
+	if _, err := r.getPostCreateHookData(tenancyv1alpha1.PostCreateHookReference{Kind: "ConfigMap", Namespace: "team-a", Name: "missing"}); err == nil {
+		t.Errorf("expected error for missing configmap")
+	}
+}
+
+func TestReconcilePostCreateManifestsSkipsAppliedHooks(t *testing.T) {
+	ref := tenancyv1alpha1.PostCreateHookReference{Kind: "ConfigMap", Namespace: "team-a", Name: "bootstrap"}
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{PostCreateHooks: []tenancyv1alpha1.PostCreateHookReference{ref}},
+		Status:     tenancyv1alpha1.ControlPlaneStatus{AppliedPostCreateHooks: map[string]bool{postCreateHookKey(ref): true}},
+	}
+	r, _ := newTestBaseReconciler(t)
+	// no kubeconfig secret is needed when every hook has already been applied
+	if err := r.ReconcilePostCreateManifests(context.Background(), hcp); err != nil {
+		t.Errorf("ReconcilePostCreateManifests returned error: %v", err)
+	}
+
+	hcp.Status.AppliedPostCreateHooks = nil
+	if err := r.ReconcilePostCreateManifests(context.Background(), hcp); err == nil {
+		t.Errorf("expected error when the control plane kubeconfig is not available")
+	}
+}
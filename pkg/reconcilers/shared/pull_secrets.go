@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// ReconcileImagePullSecrets checks that the image pull secrets referenced by the control plane
+// exist and copies them with the same name into the control plane namespace
+func (r *BaseReconciler) ReconcileImagePullSecrets(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	for _, ref := range hcp.Spec.ImagePullSecrets {
+		secret := &v1.Secret{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret, &client.GetOptions{}); err != nil {
+			return fmt.Errorf("error getting image pull secret %s/%s: %s", ref.Namespace, ref.Name, err)
+		}
+		if secret.Type != v1.SecretTypeDockerConfigJson && secret.Type != v1.SecretTypeDockercfg {
+			return fmt.Errorf("image pull secret %s/%s has type %s, expected %s or %s",
+				ref.Namespace, ref.Name, secret.Type, v1.SecretTypeDockerConfigJson, v1.SecretTypeDockercfg)
+		}
+		if err := r.ReconcileControlPlaneSecret(ctx, hcp, ref.Name, secret.Type, secret.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetImagePullSecrets returns the references to the image pull secrets copied
+// into the control plane namespace, for use in a pod spec
+func GetImagePullSecrets(hcp *tenancyv1alpha1.ControlPlane) []v1.LocalObjectReference {
+	var refs []v1.LocalObjectReference
+	for _, ref := range hcp.Spec.ImagePullSecrets {
+		refs = append(refs, v1.LocalObjectReference{Name: ref.Name})
+	}
+	return refs
+}
+
+// GetImagePullSecretsHelmValues returns the helm values setting the chart imagePullSecrets
+func GetImagePullSecretsHelmValues(hcp *tenancyv1alpha1.ControlPlane) []string {
+	var values []string
+	for i, ref := range hcp.Spec.ImagePullSecrets {
+		values = append(values, fmt.Sprintf("imagePullSecrets[%d].name=%s", i, ref.Name))
+	}
+	return values
+}
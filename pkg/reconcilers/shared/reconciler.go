@@ -18,7 +18,10 @@ package shared
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -29,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	clog "sigs.k8s.io/controller-runtime/pkg/log"
@@ -46,6 +50,15 @@ type BaseReconciler struct {
 	Version       string
 	ClientSet     *kubernetes.Clientset
 	DynamicClient *dynamic.DynamicClient
+	Recorder      record.EventRecorder
+	// ChartLimiter bounds the concurrent chart operations, it is shared by all the reconcilers
+	ChartLimiter *ChartLimiter
+	// ProvisioningRequeueInterval is the delay after which a reconcile waiting for the control
+	// plane to be provisioned is requeued. Zero selects DefaultProvisioningRequeueInterval
+	ProvisioningRequeueInterval time.Duration
+	// SteadyStateRequeueInterval is the delay after which the reconcile of a provisioned
+	// control plane is requeued. Zero disables the periodic reconcile
+	SteadyStateRequeueInterval time.Duration
 }
 
 type SharedConfig struct {
@@ -53,11 +66,20 @@ type SharedConfig struct {
 	Domain       string
 	IsOpenShift  bool
 	ExternalURL  string
+	ChartRetry   ChartRetryConfig
+	// ChartInstallTimeout is the default timeout of the chart installs, zero disables it
+	ChartInstallTimeout time.Duration
+	// HostAPIServerURL is the address of the API server of the hosting cluster for clients
+	// outside of it, used in the kubeconfig of host control planes
+	HostAPIServerURL string
 }
 
 func (r *BaseReconciler) UpdateStatusForSyncingError(hcp *tenancyv1alpha1.ControlPlane, e error) (ctrl.Result, error) {
+	clog.Log.WithValues(controlPlaneLogFields(hcp)...).Error(e, "Reconcile failed")
 	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionReconcileError(e))
-	err := r.Status().Update(context.Background(), hcp)
+	r.RecordEvent(hcp, v1.EventTypeWarning, EventReasonReconcileError, "%s", e.Error())
+	recordReconcileMetrics(hcp, ReconcileOutcomeError)
+	err := r.updateStatus(hcp)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(e, err.Error())
 	}
@@ -65,15 +87,31 @@ func (r *BaseReconciler) UpdateStatusForSyncingError(hcp *tenancyv1alpha1.Contro
 }
 
 func (r *BaseReconciler) UpdateStatusForSyncingSuccess(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (ctrl.Result, error) {
-	_ = clog.FromContext(ctx)
+	ControlPlaneLogger(ctx, hcp).V(1).Info("Reconcile succeeded")
 	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionReconcileSuccess())
-	err := r.Status().Update(context.Background(), hcp)
+	recordReconcileMetrics(hcp, ReconcileOutcomeSuccess)
+	err := r.updateStatus(hcp)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 	return ctrl.Result{}, err
 }
 
+// UpdateStatusForWaitingForReady writes the status of a control plane whose resources are
+// reconciled but whose API server has no ready replica yet, without reporting success
+func (r *BaseReconciler) UpdateStatusForWaitingForReady(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, message string) error {
+	ControlPlaneLogger(ctx, hcp).V(1).Info("Waiting for the API server to be ready", "status", message)
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionWaitingForReady(message))
+	return r.updateStatus(hcp)
+}
+
+// updateStatus writes the status of a control plane, recording the generation of the spec
+// it reflects so that clients can tell whether the conditions are up to date with the spec
+func (r *BaseReconciler) updateStatus(hcp *tenancyv1alpha1.ControlPlane) error {
+	hcp.Status.ObservedGeneration = hcp.Generation
+	return r.Status().Update(context.Background(), hcp)
+}
+
 func (r *BaseReconciler) GetConfig(ctx context.Context) (*SharedConfig, error) {
 	cmap := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -93,10 +131,21 @@ func (r *BaseReconciler) GetConfig(ctx context.Context) (*SharedConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	chartRetry, err := parseChartRetryConfig(cmap.Data)
+	if err != nil {
+		return nil, err
+	}
+	chartInstallTimeout, err := parseChartInstallTimeout(cmap.Data)
+	if err != nil {
+		return nil, err
+	}
 	return &SharedConfig{
-		Domain:       cmap.Data["domain"],
-		ExternalPort: port,
-		IsOpenShift:  isOpenShift,
+		Domain:              cmap.Data["domain"],
+		ExternalPort:        port,
+		IsOpenShift:         isOpenShift,
+		ChartRetry:          chartRetry,
+		ChartInstallTimeout: chartInstallTimeout,
+		HostAPIServerURL:    cmap.Data["hostAPIServerURL"],
 	}, nil
 }
 
@@ -109,3 +158,12 @@ func (r *BaseReconciler) UpdateStatusWithSecretRef(hcp *tenancyv1alpha1.ControlP
 		InClusterKey: inClusterKey,
 	}
 }
+
+// GetAPIServerEndpoint returns the URL of the API server exposed through the ingress, or
+// through the route whose host is set as external URL
+func GetAPIServerEndpoint(hcp *tenancyv1alpha1.ControlPlane, cfg *SharedConfig) string {
+	if cfg.ExternalURL != "" {
+		return fmt.Sprintf("https://%s", cfg.ExternalURL)
+	}
+	return fmt.Sprintf("https://%s", net.JoinHostPort(GetAPIServerHostname(hcp, cfg.Domain), strconv.Itoa(cfg.ExternalPort)))
+}
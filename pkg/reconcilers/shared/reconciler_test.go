@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestUpdateStatusObservedGeneration(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1", Generation: 3},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	r, cl := newTestBaseReconciler(t, hcp)
+
+	assertObservedGeneration := func(want int64) {
+		t.Helper()
+		got := &tenancyv1alpha1.ControlPlane{}
+		if err := cl.Get(context.Background(), client.ObjectKeyFromObject(hcp), got); err != nil {
+			t.Fatalf("error getting control plane: %v", err)
+		}
+		if got.Status.ObservedGeneration != want {
+			t.Errorf("expected status observedGeneration %d, got %d", want, got.Status.ObservedGeneration)
+		}
+		for _, c := range got.Status.Conditions {
+			if c.ObservedGeneration != want {
+				t.Errorf("expected condition %s observedGeneration %d, got %d", c.Type, want, c.ObservedGeneration)
+			}
+		}
+	}
+
+	if _, err := r.UpdateStatusForSyncingSuccess(context.Background(), hcp); err != nil {
+		t.Fatalf("UpdateStatusForSyncingSuccess returned error: %v", err)
+	}
+	assertObservedGeneration(3)
+
+	// a spec edit bumps the generation of the conditions set by the next reconcile
+	hcp.Generation = 4
+	if _, err := r.UpdateStatusForSyncingError(hcp, errors.New("chart install failed")); err != nil {
+		t.Fatalf("UpdateStatusForSyncingError returned error: %v", err)
+	}
+	assertObservedGeneration(4)
+
+	hcp.Generation = 5
+	if err := r.UpdateStatusForWaitingForReady(context.Background(), hcp, "0/1 replicas ready"); err != nil {
+		t.Fatalf("UpdateStatusForWaitingForReady returned error: %v", err)
+	}
+	assertObservedGeneration(5)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/strvals"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// GetReplicasHelmValues returns the helm values setting the replica count of the control plane
+// under each of the given values paths, e.g. syncer.replicas
+func GetReplicasHelmValues(hcp *tenancyv1alpha1.ControlPlane, paths ...string) []string {
+	if hcp.Spec.Replicas == nil {
+		return nil
+	}
+	values := make([]string, 0, len(paths))
+	for _, path := range paths {
+		values = append(values, fmt.Sprintf("%s=%d", path, *hcp.Spec.Replicas))
+	}
+	return values
+}
+
+// ChartReplicasChanged reports whether the replica count the release was installed with under
+// any of the given paths differs from spec.replicas, in which case the chart needs an upgrade.
+// The chart defaults are left alone when spec.replicas is not set
+func ChartReplicasChanged(rel *release.Release, hcp *tenancyv1alpha1.ControlPlane, paths ...string) (bool, error) {
+	if hcp.Spec.Replicas == nil {
+		return false, nil
+	}
+	desired, err := strvals.Parse(strings.Join(GetReplicasHelmValues(hcp, paths...), ","))
+	if err != nil {
+		return false, err
+	}
+	var installed map[string]interface{}
+	if rel != nil {
+		installed = rel.Config
+	}
+	for _, path := range paths {
+		// the release stores its values as JSON, so integers come back as floats
+		if fmt.Sprint(lookupValue(desired, path)) != fmt.Sprint(lookupValue(installed, path)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lookupValue returns the value at the dot separated path, or nil when it is not set
+func lookupValue(values map[string]interface{}, path string) interface{} {
+	parent, key := "", path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		parent, key = path[:i], path[i+1:]
+	}
+	if parent != "" {
+		values = lookupValues(values, parent)
+	}
+	return values[key]
+}
+
+// ReconcileAPIServerPodDisruptionBudget creates or updates the pod disruption budget of the API
+// server pods when spec.podDisruptionBudget is set, and removes the one it created otherwise.
+// The budget selects the pods of the API server deployment or statefulset, so it is only
+// created once the workload exists
+func (r *BaseReconciler) ReconcileAPIServerPodDisruptionBudget(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.GetAPIServerDeploymentNameByControlPlaneType(string(hcp.Spec.Type)),
+			Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+		},
+	}
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(pdb), pdb, &client.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if hcp.Spec.PodDisruptionBudget == nil {
+		// leave alone a budget with the same name that was not created by kubeflex
+		if !exists || !metav1.IsControlledBy(pdb, hcp) {
+			return nil
+		}
+		return client.IgnoreNotFound(r.Client.Delete(context.TODO(), pdb, &client.DeleteOptions{}))
+	}
+
+	selector, err := r.getAPIServerPodSelector(hcp, pdb.Namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	minAvailable := intstr.FromInt(int(util.APIServerMinAvailable(*hcp)))
+	spec := policyv1.PodDisruptionBudgetSpec{
+		MinAvailable: &minAvailable,
+		Selector:     selector,
+	}
+
+	if !exists {
+		pdb.Spec = spec
+		if err := controllerutil.SetControllerReference(hcp, pdb, r.Scheme); err != nil {
+			return err
+		}
+		return r.Client.Create(context.TODO(), pdb, &client.CreateOptions{})
+	}
+	if reflect.DeepEqual(pdb.Spec.MinAvailable, spec.MinAvailable) && reflect.DeepEqual(pdb.Spec.Selector, spec.Selector) {
+		return nil
+	}
+	pdb.Spec.MinAvailable = spec.MinAvailable
+	pdb.Spec.MaxUnavailable = nil
+	pdb.Spec.Selector = spec.Selector
+	return r.Client.Update(context.TODO(), pdb, &client.UpdateOptions{})
+}
+
+// getAPIServerPodSelector returns the pod selector of the API server statefulset of vcluster
+// control planes or of the API server deployment of the other types
+func (r *BaseReconciler) getAPIServerPodSelector(hcp *tenancyv1alpha1.ControlPlane, namespace string) (*metav1.LabelSelector, error) {
+	key := client.ObjectKey{
+		Name:      util.GetAPIServerDeploymentNameByControlPlaneType(string(hcp.Spec.Type)),
+		Namespace: namespace,
+	}
+	if hcp.Spec.Type == tenancyv1alpha1.ControlPlaneTypeVCluster {
+		s := &appsv1.StatefulSet{}
+		if err := r.Client.Get(context.TODO(), key, s, &client.GetOptions{}); err != nil {
+			return nil, err
+		}
+		return s.Spec.Selector.DeepCopy(), nil
+	}
+	d := &appsv1.Deployment{}
+	if err := r.Client.Get(context.TODO(), key, d, &client.GetOptions{}); err != nil {
+		return nil, err
+	}
+	return d.Spec.Selector.DeepCopy(), nil
+}
@@ -0,0 +1,128 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestGetReplicasHelmValues(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{Spec: tenancyv1alpha1.ControlPlaneSpec{Replicas: pointer.Int32(3)}}
+	want := []string{"syncer.replicas=3", "api.replicas=3"}
+	if got := GetReplicasHelmValues(hcp, "syncer.replicas", "api.replicas"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := GetReplicasHelmValues(&tenancyv1alpha1.ControlPlane{}, "syncer.replicas"); got != nil {
+		t.Errorf("expected no values when replicas are unset, got %v", got)
+	}
+}
+
+func TestChartReplicasChanged(t *testing.T) {
+	// the release stores the values as JSON
+	releaseWithConfig := func(config string) *release.Release {
+		values := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(config), &values); err != nil {
+			t.Fatalf("error decoding values: %v", err)
+		}
+		return &release.Release{Config: values}
+	}
+
+	tests := []struct {
+		name     string
+		rel      *release.Release
+		replicas *int32
+		want     bool
+	}{
+		{name: "unchanged", rel: releaseWithConfig(`{"syncer":{"replicas":3}}`), replicas: pointer.Int32(3)},
+		{name: "scaled", rel: releaseWithConfig(`{"syncer":{"replicas":1}}`), replicas: pointer.Int32(3), want: true},
+		{name: "not installed with replicas", rel: releaseWithConfig(`{}`), replicas: pointer.Int32(2), want: true},
+		{name: "replicas unset", rel: releaseWithConfig(`{"syncer":{"replicas":3}}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcp := &tenancyv1alpha1.ControlPlane{Spec: tenancyv1alpha1.ControlPlaneSpec{Replicas: tt.replicas}}
+			got, err := ChartReplicasChanged(tt.rel, hcp, "syncer.replicas")
+			if err != nil {
+				t.Fatalf("ChartReplicasChanged returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReconcileAPIServerPodDisruptionBudget(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1", UID: "cp1-uid"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:                tenancyv1alpha1.ControlPlaneTypeK8S,
+			Replicas:            pointer.Int32(3),
+			PodDisruptionBudget: &tenancyv1alpha1.PodDisruptionBudgetSpec{},
+		},
+	}
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	key := client.ObjectKey{Name: util.APIServerDeploymentName, Namespace: namespace}
+
+	// no budget is created before the API server deployment exists
+	r, cl := newTestBaseReconciler(t, hcp)
+	if err := r.ReconcileAPIServerPodDisruptionBudget(context.TODO(), hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerPodDisruptionBudget returned error: %v", err)
+	}
+	if err := cl.Get(context.TODO(), key, &policyv1.PodDisruptionBudget{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected no pod disruption budget, got %v", err)
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "kube-apiserver"}}
+	r, cl = newTestBaseReconciler(t, hcp, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		Spec:       appsv1.DeploymentSpec{Selector: selector},
+	})
+	if err := r.ReconcileAPIServerPodDisruptionBudget(context.TODO(), hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerPodDisruptionBudget returned error: %v", err)
+	}
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := cl.Get(context.TODO(), key, pdb); err != nil {
+		t.Fatalf("error getting pod disruption budget: %v", err)
+	}
+	if pdb.Spec.MinAvailable == nil || pdb.Spec.MinAvailable.IntValue() != 2 {
+		t.Errorf("expected minAvailable 2, got %v", pdb.Spec.MinAvailable)
+	}
+	if !reflect.DeepEqual(pdb.Spec.Selector, selector) {
+		t.Errorf("expected selector %v, got %v", selector, pdb.Spec.Selector)
+	}
+	if !metav1.IsControlledBy(pdb, hcp) {
+		t.Errorf("expected the pod disruption budget to be controlled by the control plane")
+	}
+
+	hcp.Spec.PodDisruptionBudget.MinAvailable = pointer.Int32(1)
+	if err := r.ReconcileAPIServerPodDisruptionBudget(context.TODO(), hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerPodDisruptionBudget returned error: %v", err)
+	}
+	if err := cl.Get(context.TODO(), key, pdb); err != nil {
+		t.Fatalf("error getting pod disruption budget: %v", err)
+	}
+	if pdb.Spec.MinAvailable.IntValue() != 1 {
+		t.Errorf("expected minAvailable 1 after the update, got %v", pdb.Spec.MinAvailable)
+	}
+
+	hcp.Spec.PodDisruptionBudget = nil
+	if err := r.ReconcileAPIServerPodDisruptionBudget(context.TODO(), hcp); err != nil {
+		t.Fatalf("ReconcileAPIServerPodDisruptionBudget returned error: %v", err)
+	}
+	if err := cl.Get(context.TODO(), key, pdb); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the pod disruption budget to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// DefaultProvisioningRequeueInterval is the delay after which a reconcile waiting for the
+// control plane to be provisioned is requeued when no interval is configured
+const DefaultProvisioningRequeueInterval = 3 * time.Second
+
+// ProvisioningRequeueDelay returns the delay after which a reconcile waiting on the control
+// plane, such as for its route URL or a ready replica, is requeued
+func (r *BaseReconciler) ProvisioningRequeueDelay() time.Duration {
+	if r.ProvisioningRequeueInterval > 0 {
+		return r.ProvisioningRequeueInterval
+	}
+	return DefaultProvisioningRequeueInterval
+}
+
+// SyncedRequeueDelay returns the delay after which a successful reconcile is requeued: the
+// provisioning delay while the control plane is still provisioning or its chart is rolling out,
+// so that its conditions catch up with the API server and the chart install timeout is reported,
+// and the steady state interval afterwards
+func (r *BaseReconciler) SyncedRequeueDelay(hcp *tenancyv1alpha1.ControlPlane) time.Duration {
+	if IsProvisioning(hcp) || hcp.Status.ChartInstallStartTime != nil {
+		return r.ProvisioningRequeueDelay()
+	}
+	return r.SteadyStateRequeueInterval
+}
+
+// UpdateStatusForSyncingSuccessWithRequeue works as UpdateStatusForSyncingSuccess and requeues
+// the reconcile after SyncedRequeueDelay
+func (r *BaseReconciler) UpdateStatusForSyncingSuccessWithRequeue(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (ctrl.Result, error) {
+	result, err := r.UpdateStatusForSyncingSuccess(ctx, hcp)
+	if err != nil {
+		return result, err
+	}
+	result.RequeueAfter = r.SyncedRequeueDelay(hcp)
+	return result, nil
+}
+
+// IsProvisioning returns true if the control plane has not been ready yet
+func IsProvisioning(hcp *tenancyv1alpha1.ControlPlane) bool {
+	return !wasReady(hcp)
+}
@@ -0,0 +1,39 @@
+package shared
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestSyncedRequeueDelay(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp1"}}
+	r := &BaseReconciler{}
+
+	if got := r.SyncedRequeueDelay(hcp); got != DefaultProvisioningRequeueInterval {
+		t.Errorf("expected the default provisioning delay for a new control plane, got %s", got)
+	}
+	r.ProvisioningRequeueInterval = 10 * time.Second
+	r.SteadyStateRequeueInterval = 5 * time.Minute
+	if got := r.SyncedRequeueDelay(hcp); got != 10*time.Second {
+		t.Errorf("expected the provisioning delay, got %s", got)
+	}
+
+	SetHealthConditions(hcp, true)
+	if got := r.SyncedRequeueDelay(hcp); got != 5*time.Minute {
+		t.Errorf("expected the steady state delay once provisioned, got %s", got)
+	}
+	// a provisioned control plane that fails again is not provisioning
+	SetHealthConditions(hcp, false)
+	if got := r.SyncedRequeueDelay(hcp); got != 5*time.Minute {
+		t.Errorf("expected the steady state delay for a degraded control plane, got %s", got)
+	}
+
+	r.SteadyStateRequeueInterval = 0
+	if got := r.SyncedRequeueDelay(hcp); got != 0 {
+		t.Errorf("expected no periodic requeue by default, got %s", got)
+	}
+}
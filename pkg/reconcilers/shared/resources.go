@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/strvals"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/helm"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// ValidateResources checks that no resource request of the control plane exceeds its limit
+func ValidateResources(resources *v1.ResourceRequirements) error {
+	if resources == nil {
+		return nil
+	}
+	for name, request := range resources.Requests {
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			return fmt.Errorf("invalid resources: %s request %s must be less than or equal to its limit %s", name, request.String(), limit.String())
+		}
+	}
+	return nil
+}
+
+// GetResourcesHelmValues returns the helm values setting the resource requests and limits of
+// the main container of the control plane under the given values path, e.g. vcluster.resources.
+// The values are meant to be passed with set-string, so that quantities stay strings
+func GetResourcesHelmValues(hcp *tenancyv1alpha1.ControlPlane, path string) []string {
+	if hcp.Spec.Resources == nil {
+		return nil
+	}
+	var values []string
+	for _, r := range []struct {
+		field string
+		list  v1.ResourceList
+	}{{"requests", hcp.Spec.Resources.Requests}, {"limits", hcp.Spec.Resources.Limits}} {
+		names := make([]string, 0, len(r.list))
+		for name := range r.list {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			quantity := r.list[v1.ResourceName(name)]
+			values = append(values, fmt.Sprintf("%s.%s.%s=%s", path, r.field, name, quantity.String()))
+		}
+	}
+	return values
+}
+
+// ChartResourcesChanged reports whether the resources values the release was installed with
+// under the given path differ from the ones of the control plane spec, in which case the chart
+// needs an upgrade
+func ChartResourcesChanged(rel *release.Release, hcp *tenancyv1alpha1.ControlPlane, path string) (bool, error) {
+	desired, err := strvals.ParseString(strings.Join(GetResourcesHelmValues(hcp, path), ","))
+	if err != nil {
+		return false, err
+	}
+	var installed map[string]interface{}
+	if rel != nil {
+		installed = rel.Config
+	}
+	return !reflect.DeepEqual(lookupValues(desired, path), lookupValues(installed, path)), nil
+}
+
+// lookupValues returns the values table at the dot separated path, or nil when it is not set
+// or empty
+func lookupValues(values map[string]interface{}, path string) map[string]interface{} {
+	for _, key := range strings.Split(path, ".") {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		values = next
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// SetRolledOutCondition sets the RolledOut condition from the status of the API server
+// workload, so that a rollout in progress after a chart upgrade is told apart from a
+// completed one. The condition is left unchanged while the workload does not exist yet
+func SetRolledOutCondition(c client.Client, hcp *tenancyv1alpha1.ControlPlane) error {
+	complete, message, err := util.GetAPIServerRolloutStatus(c, *hcp)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	tenancyv1alpha1.EnsureCondition(hcp, tenancyv1alpha1.ConditionRolledOut(complete, message))
+	return nil
+}
+
+// UpgradeChartOnValuesChange upgrades the deployed release of the handler when the values it
+// was installed with differ from the ones generated from the control plane spec, including the
+// values of spec.valuesFrom, when the values of spec.valuesFrom differ from the ones recorded
+// in status.valuesFromHash, or when the chart version of the release does not match the
+// requested one
+func (r *BaseReconciler) UpgradeChartOnValuesChange(hcp *tenancyv1alpha1.ControlPlane, h *helm.HelmHandler) error {
+	rel, err := h.CheckStatus()
+	if err != nil {
+		return err
+	}
+	changed := h.VersionChanged(rel)
+	if !changed {
+		if changed, err = h.ValuesChanged(rel); err != nil {
+			return err
+		}
+	}
+	valuesFromHash := ValuesFromHash(h.Values)
+	if !changed && valuesFromHash == hcp.Status.ValuesFromHash {
+		return nil
+	}
+	if err := h.Upgrade(); err != nil {
+		return fmt.Errorf("error upgrading chart %s to apply the control plane values: %w", h.ChartName, err)
+	}
+	hcp.Status.ValuesFromHash = valuesFromHash
+	r.RecordNormalEvent(hcp, EventReasonChartUpgraded, "Upgraded release %s to apply the control plane values", h.ReleaseName)
+	return nil
+}
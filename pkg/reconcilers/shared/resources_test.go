@@ -0,0 +1,181 @@
+package shared
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/strvals"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func testResourcesControlPlane(resources *corev1.ResourceRequirements) *tenancyv1alpha1.ControlPlane {
+	return &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type:      tenancyv1alpha1.ControlPlaneTypeVCluster,
+			Resources: resources,
+		},
+	}
+}
+
+func TestGetResourcesHelmValues(t *testing.T) {
+	hcp := testResourcesControlPlane(&corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+			corev1.ResourceCPU:    resource.MustParse("200m"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+		},
+	})
+	want := []string{
+		"vcluster.resources.requests.cpu=200m",
+		"vcluster.resources.requests.memory=512Mi",
+		"vcluster.resources.limits.memory=1Gi",
+	}
+	if got := GetResourcesHelmValues(hcp, "vcluster.resources"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := GetResourcesHelmValues(testResourcesControlPlane(nil), "vcluster.resources"); got != nil {
+		t.Errorf("expected no values when resources are unset, got %v", got)
+	}
+}
+
+func TestChartResourcesChanged(t *testing.T) {
+	resources := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+	}
+	// the release stores the values as JSON
+	releaseWithValues := func(set, setString string) *release.Release {
+		vals, err := strvals.Parse(set)
+		if err != nil {
+			t.Fatalf("error parsing values: %v", err)
+		}
+		if err := strvals.ParseIntoString(setString, vals); err != nil {
+			t.Fatalf("error parsing values: %v", err)
+		}
+		data, _ := json.Marshal(vals)
+		config := map[string]interface{}{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			t.Fatalf("error decoding values: %v", err)
+		}
+		return &release.Release{Config: config}
+	}
+
+	tests := []struct {
+		name      string
+		rel       *release.Release
+		resources *corev1.ResourceRequirements
+		want      bool
+	}{
+		{
+			name:      "unchanged",
+			rel:       releaseWithValues("vcluster.image=k3s", "vcluster.resources.requests.cpu=1"),
+			resources: resources,
+		},
+		{
+			name:      "unset and not installed",
+			rel:       releaseWithValues("vcluster.image=k3s", ""),
+			resources: nil,
+		},
+		{
+			name:      "added",
+			rel:       releaseWithValues("vcluster.image=k3s", ""),
+			resources: resources,
+			want:      true,
+		},
+		{
+			name:      "changed",
+			rel:       releaseWithValues("vcluster.image=k3s", "vcluster.resources.requests.cpu=2"),
+			resources: resources,
+			want:      true,
+		},
+		{
+			name:      "removed",
+			rel:       releaseWithValues("vcluster.image=k3s", "vcluster.resources.requests.cpu=1"),
+			resources: nil,
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChartResourcesChanged(tt.rel, testResourcesControlPlane(tt.resources), "vcluster.resources")
+			if err != nil {
+				t.Fatalf("ChartResourcesChanged returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected changed %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	valid := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	if err := ValidateResources(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	invalid := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	if err := ValidateResources(invalid); err == nil {
+		t.Errorf("expected error for request exceeding its limit")
+	}
+}
+
+func TestSetRolledOutCondition(t *testing.T) {
+	hcp := testResourcesControlPlane(nil)
+
+	// no condition until the statefulset exists
+	r, _ := newTestBaseReconciler(t)
+	if err := SetRolledOutCondition(r.Client, hcp); err != nil {
+		t.Fatalf("SetRolledOutCondition returned error: %v", err)
+	}
+	if c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeRolledOut); c != nil {
+		t.Fatalf("expected no condition, got %v", c)
+	}
+
+	statefulset := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "vcluster", Namespace: "cp1-system", Generation: 2},
+		Spec:       appsv1.StatefulSetSpec{Replicas: pointer.Int32(1)},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 2,
+			Replicas:           1,
+			ReadyReplicas:      1,
+			UpdatedReplicas:    0,
+			CurrentRevision:    "vcluster-1",
+			UpdateRevision:     "vcluster-2",
+		},
+	}
+	r, _ = newTestBaseReconciler(t, statefulset)
+	if err := SetRolledOutCondition(r.Client, hcp); err != nil {
+		t.Fatalf("SetRolledOutCondition returned error: %v", err)
+	}
+	c := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeRolledOut)
+	if c == nil || c.Status != corev1.ConditionFalse || c.Reason != tenancyv1alpha1.ReasonRolloutInProgress {
+		t.Fatalf("expected rollout in progress, got %v", c)
+	}
+
+	statefulset.Status.UpdatedReplicas = 1
+	statefulset.Status.CurrentRevision = "vcluster-2"
+	r, _ = newTestBaseReconciler(t, statefulset)
+	if err := SetRolledOutCondition(r.Client, hcp); err != nil {
+		t.Fatalf("SetRolledOutCondition returned error: %v", err)
+	}
+	c = tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeRolledOut)
+	if c == nil || c.Status != corev1.ConditionTrue || c.Reason != tenancyv1alpha1.ReasonRolloutComplete {
+		t.Fatalf("expected rollout complete, got %v", c)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/kubestellar/kubeflex/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// GetSecretKeyData returns the data stored under the key of a referenced secret
+func (r *BaseReconciler) GetSecretKeyData(ctx context.Context, ref tenancyv1alpha1.SecretKeyReference) ([]byte, error) {
+	_ = clog.FromContext(ctx)
+	secret := &v1.Secret{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret, &client.GetOptions{}); err != nil {
+		return nil, err
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in secret %s/%s", ref.Key, ref.Namespace, ref.Name)
+	}
+	return data, nil
+}
+
+// ReconcileControlPlaneSecret creates or updates a secret with the given type and
+// data in the control plane namespace, owned by the control plane
+func (r *BaseReconciler) ReconcileControlPlaneSecret(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, name string, secretType v1.SecretType, data map[string][]byte) error {
+	_ = clog.FromContext(ctx)
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret, &client.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			secret.Type = secretType
+			secret.Data = data
+			if err := controllerutil.SetControllerReference(hcp, secret, r.Scheme); err != nil {
+				return err
+			}
+			if err = r.Client.Create(context.TODO(), secret, &client.CreateOptions{}); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	if !reflect.DeepEqual(secret.Data, data) {
+		secret.Data = data
+		if err = r.Client.Update(context.TODO(), secret, &client.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
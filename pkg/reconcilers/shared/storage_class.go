@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+const (
+	DefaultStorageClassName = "standard"
+	// IsDefaultStorageClassAnnotation marks a StorageClass as the cluster default
+	IsDefaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+)
+
+// ReconcileDefaultStorageClass creates the default StorageClass described in the spec inside
+// the control plane, if it does not exist yet
+func (r *BaseReconciler) ReconcileDefaultStorageClass(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.DefaultStorageClass == nil {
+		return nil
+	}
+
+	cpClient, err := r.GetControlPlaneClientSet(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	return r.reconcileDefaultStorageClass(ctx, hcp, cpClient)
+}
+
+func (r *BaseReconciler) reconcileDefaultStorageClass(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, cpClient kubernetes.Interface) error {
+	spec := hcp.Spec.DefaultStorageClass
+	name := spec.Name
+	if name == "" {
+		name = DefaultStorageClassName
+	}
+
+	_, err := cpClient.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	provisioner := spec.Provisioner
+	if provisioner == "" {
+		provisioner, err = r.getHostDefaultProvisioner()
+		if err != nil {
+			return err
+		}
+	}
+
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{IsDefaultStorageClassAnnotation: "true"},
+		},
+		Provisioner:          provisioner,
+		Parameters:           spec.Parameters,
+		ReclaimPolicy:        spec.ReclaimPolicy,
+		VolumeBindingMode:    spec.VolumeBindingMode,
+		AllowVolumeExpansion: spec.AllowVolumeExpansion,
+	}
+	_, err = cpClient.StorageV1().StorageClasses().Create(ctx, storageClass, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// getHostDefaultProvisioner returns the provisioner of the default StorageClass of the hosting cluster
+func (r *BaseReconciler) getHostDefaultProvisioner() (string, error) {
+	storageClasses := &storagev1.StorageClassList{}
+	if err := r.Client.List(context.TODO(), storageClasses, &client.ListOptions{}); err != nil {
+		return "", err
+	}
+	for _, sc := range storageClasses.Items {
+		if sc.Annotations[IsDefaultStorageClassAnnotation] == "true" {
+			return sc.Provisioner, nil
+		}
+	}
+	return "", fmt.Errorf("no provisioner set for the default storage class and the hosting cluster has no default storage class")
+}
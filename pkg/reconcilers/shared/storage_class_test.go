@@ -0,0 +1,79 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestReconcileDefaultStorageClass(t *testing.T) {
+	retain := v1.PersistentVolumeReclaimRetain
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			Type: tenancyv1alpha1.ControlPlaneTypeVCluster,
+			DefaultStorageClass: &tenancyv1alpha1.DefaultStorageClassSpec{
+				ReclaimPolicy: &retain,
+				Parameters:    map[string]string{"type": "ssd"},
+			},
+		},
+	}
+	hostStorageClass := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "host-default",
+			Annotations: map[string]string{IsDefaultStorageClassAnnotation: "true"},
+		},
+		Provisioner: "rancher.io/local-path",
+	}
+	r, _ := newTestBaseReconciler(t, hcp, hostStorageClass)
+	cpClient := kubefake.NewSimpleClientset()
+
+	ctx := context.Background()
+	if err := r.reconcileDefaultStorageClass(ctx, hcp, cpClient); err != nil {
+		t.Fatalf("reconcileDefaultStorageClass returned error: %v", err)
+	}
+	sc, err := cpClient.StorageV1().StorageClasses().Get(ctx, DefaultStorageClassName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected storage class %s in the control plane: %v", DefaultStorageClassName, err)
+	}
+	if sc.Annotations[IsDefaultStorageClassAnnotation] != "true" {
+		t.Errorf("expected storage class to be marked as default")
+	}
+	if sc.Provisioner != "rancher.io/local-path" {
+		t.Errorf("expected provisioner of the host default storage class, got %s", sc.Provisioner)
+	}
+	if sc.ReclaimPolicy == nil || *sc.ReclaimPolicy != retain || sc.Parameters["type"] != "ssd" {
+		t.Errorf("expected reclaim policy and parameters from the spec, got %v and %v", sc.ReclaimPolicy, sc.Parameters)
+	}
+
+	// reconciling again leaves the existing storage class untouched
+	hcp.Spec.DefaultStorageClass.Provisioner = "example.com/other"
+	if err := r.reconcileDefaultStorageClass(ctx, hcp, cpClient); err != nil {
+		t.Fatalf("reconcileDefaultStorageClass returned error on second pass: %v", err)
+	}
+	sc, err = cpClient.StorageV1().StorageClasses().Get(ctx, DefaultStorageClassName, metav1.GetOptions{})
+	if err != nil || sc.Provisioner != "rancher.io/local-path" {
+		t.Errorf("expected existing storage class to be kept, got %v, %v", sc, err)
+	}
+
+	// an explicit name and provisioner do not need a host default storage class
+	hcp.Spec.DefaultStorageClass = &tenancyv1alpha1.DefaultStorageClassSpec{Name: "fast", Provisioner: "example.com/fast"}
+	r, _ = newTestBaseReconciler(t, hcp)
+	if err := r.reconcileDefaultStorageClass(ctx, hcp, cpClient); err != nil {
+		t.Fatalf("reconcileDefaultStorageClass returned error: %v", err)
+	}
+	if sc, err := cpClient.StorageV1().StorageClasses().Get(ctx, "fast", metav1.GetOptions{}); err != nil || sc.Provisioner != "example.com/fast" {
+		t.Errorf("expected storage class fast with provisioner example.com/fast, got %v, %v", sc, err)
+	}
+
+	hcp.Spec.DefaultStorageClass = &tenancyv1alpha1.DefaultStorageClassSpec{Name: "missing"}
+	if err := r.reconcileDefaultStorageClass(ctx, hcp, cpClient); err == nil {
+		t.Errorf("expected error without provisioner nor host default storage class")
+	}
+}
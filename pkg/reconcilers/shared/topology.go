@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// ValidateTopologySpreadConstraints checks the control plane topology spread constraints
+// with the rules the API server applies to pod specs
+func ValidateTopologySpreadConstraints(constraints []v1.TopologySpreadConstraint) error {
+	seen := map[string]bool{}
+	for i, c := range constraints {
+		if c.MaxSkew < 1 {
+			return fmt.Errorf("topology spread constraint %d: maxSkew must be greater than zero", i)
+		}
+		if c.TopologyKey == "" {
+			return fmt.Errorf("topology spread constraint %d: topologyKey is required", i)
+		}
+		if errs := validation.IsQualifiedName(c.TopologyKey); len(errs) > 0 {
+			return fmt.Errorf("topology spread constraint %d: invalid topologyKey %q: %s", i, c.TopologyKey, strings.Join(errs, "; "))
+		}
+		switch c.WhenUnsatisfiable {
+		case v1.DoNotSchedule, v1.ScheduleAnyway:
+		default:
+			return fmt.Errorf("topology spread constraint %d: whenUnsatisfiable must be %s or %s", i, v1.DoNotSchedule, v1.ScheduleAnyway)
+		}
+		if c.MinDomains != nil {
+			if *c.MinDomains < 1 {
+				return fmt.Errorf("topology spread constraint %d: minDomains must be greater than zero", i)
+			}
+			if c.WhenUnsatisfiable != v1.DoNotSchedule {
+				return fmt.Errorf("topology spread constraint %d: minDomains requires whenUnsatisfiable %s", i, v1.DoNotSchedule)
+			}
+		}
+		for _, policy := range []*v1.NodeInclusionPolicy{c.NodeAffinityPolicy, c.NodeTaintsPolicy} {
+			if policy != nil && *policy != v1.NodeInclusionPolicyHonor && *policy != v1.NodeInclusionPolicyIgnore {
+				return fmt.Errorf("topology spread constraint %d: invalid node inclusion policy %q", i, *policy)
+			}
+		}
+		if c.LabelSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(c.LabelSelector); err != nil {
+				return fmt.Errorf("topology spread constraint %d: invalid labelSelector: %s", i, err)
+			}
+		}
+		for _, key := range c.MatchLabelKeys {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return fmt.Errorf("topology spread constraint %d: invalid matchLabelKeys entry %q: %s", i, key, strings.Join(errs, "; "))
+			}
+			if c.LabelSelector != nil {
+				if _, ok := c.LabelSelector.MatchLabels[key]; ok {
+					return fmt.Errorf("topology spread constraint %d: key %q is both in matchLabelKeys and labelSelector", i, key)
+				}
+			}
+		}
+
+		pair := fmt.Sprintf("%s/%s", c.TopologyKey, c.WhenUnsatisfiable)
+		if seen[pair] {
+			return fmt.Errorf("topology spread constraint %d: duplicate topologyKey %s with whenUnsatisfiable %s", i, c.TopologyKey, c.WhenUnsatisfiable)
+		}
+		seen[pair] = true
+	}
+	return nil
+}
+
+// GetTopologySpreadConstraints returns the control plane topology spread constraints for a
+// pod spec, selecting the pods with podLabels when a constraint has no labelSelector
+func GetTopologySpreadConstraints(hcp *tenancyv1alpha1.ControlPlane, podLabels map[string]string) []v1.TopologySpreadConstraint {
+	var constraints []v1.TopologySpreadConstraint
+	for _, c := range hcp.Spec.TopologySpreadConstraints {
+		constraint := *c.DeepCopy()
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{}}
+			for k, v := range podLabels {
+				constraint.LabelSelector.MatchLabels[k] = v
+			}
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints
+}
+
+// GetTopologySpreadConstraintsHelmValues returns the helm values setting the chart value key
+// to the control plane topology spread constraints, defaulted as in GetTopologySpreadConstraints
+func GetTopologySpreadConstraintsHelmValues(hcp *tenancyv1alpha1.ControlPlane, key string, podLabels map[string]string) ([]string, error) {
+	constraints := GetTopologySpreadConstraints(hcp, podLabels)
+	if len(constraints) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(constraints)
+	if err != nil {
+		return nil, err
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return flattenHelmValues(key, obj), nil
+}
+
+// flattenHelmValues converts obj to --set values under prefix, escaping the
+// characters that have a special meaning in helm value keys and values
+func flattenHelmValues(prefix string, obj interface{}) []string {
+	var values []string
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			values = append(values, flattenHelmValues(prefix+"."+escapeHelmKey(k), v[k])...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			values = append(values, flattenHelmValues(fmt.Sprintf("%s[%d]", prefix, i), item)...)
+		}
+	default:
+		values = append(values, fmt.Sprintf("%s=%s", prefix, escapeHelmValue(fmt.Sprint(v))))
+	}
+	return values
+}
+
+var (
+	helmKeyEscaper   = strings.NewReplacer(`\`, `\\`, `.`, `\.`, `,`, `\,`, `=`, `\=`, `[`, `\[`)
+	helmValueEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`)
+)
+
+func escapeHelmKey(key string) string {
+	return helmKeyEscaper.Replace(key)
+}
+
+func escapeHelmValue(value string) string {
+	return helmValueEscaper.Replace(value)
+}
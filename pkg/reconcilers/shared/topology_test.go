@@ -0,0 +1,107 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/strvals"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestValidateTopologySpreadConstraints(t *testing.T) {
+	valid := v1.TopologySpreadConstraint{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: v1.DoNotSchedule}
+	honor := v1.NodeInclusionPolicyHonor
+	invalidPolicy := v1.NodeInclusionPolicy("Sometimes")
+	tests := []struct {
+		name    string
+		mutate  func(c *v1.TopologySpreadConstraint)
+		wantErr bool
+	}{
+		{name: "valid", mutate: func(c *v1.TopologySpreadConstraint) {}},
+		{name: "valid with options", mutate: func(c *v1.TopologySpreadConstraint) {
+			c.MinDomains = pointer.Int32(3)
+			c.NodeAffinityPolicy = &honor
+			c.MatchLabelKeys = []string{"pod-template-hash"}
+		}},
+		{name: "zero max skew", mutate: func(c *v1.TopologySpreadConstraint) { c.MaxSkew = 0 }, wantErr: true},
+		{name: "missing topology key", mutate: func(c *v1.TopologySpreadConstraint) { c.TopologyKey = "" }, wantErr: true},
+		{name: "invalid topology key", mutate: func(c *v1.TopologySpreadConstraint) { c.TopologyKey = "not a key" }, wantErr: true},
+		{name: "invalid when unsatisfiable", mutate: func(c *v1.TopologySpreadConstraint) { c.WhenUnsatisfiable = "Never" }, wantErr: true},
+		{name: "min domains with schedule anyway", mutate: func(c *v1.TopologySpreadConstraint) {
+			c.WhenUnsatisfiable = v1.ScheduleAnyway
+			c.MinDomains = pointer.Int32(2)
+		}, wantErr: true},
+		{name: "invalid node policy", mutate: func(c *v1.TopologySpreadConstraint) { c.NodeTaintsPolicy = &invalidPolicy }, wantErr: true},
+		{name: "invalid selector", mutate: func(c *v1.TopologySpreadConstraint) {
+			c.LabelSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Near"}}}
+		}, wantErr: true},
+		{name: "match label key in selector", mutate: func(c *v1.TopologySpreadConstraint) {
+			c.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "vcluster"}}
+			c.MatchLabelKeys = []string{"app"}
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *valid.DeepCopy()
+			tt.mutate(&c)
+			err := ValidateTopologySpreadConstraints([]v1.TopologySpreadConstraint{c})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTopologySpreadConstraints() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := ValidateTopologySpreadConstraints([]v1.TopologySpreadConstraint{valid, valid}); err == nil {
+		t.Errorf("expected error for duplicate constraints")
+	}
+}
+
+func TestGetTopologySpreadConstraintsHelmValues(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{
+		Spec: tenancyv1alpha1.ControlPlaneSpec{
+			TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: v1.DoNotSchedule},
+				{
+					MaxSkew:           2,
+					TopologyKey:       "kubernetes.io/hostname",
+					WhenUnsatisfiable: v1.ScheduleAnyway,
+					LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": "a,b"}},
+				},
+			},
+		},
+	}
+	values, err := GetTopologySpreadConstraintsHelmValues(hcp, "topologySpreadConstraints", map[string]string{"app": "vcluster"})
+	if err != nil {
+		t.Fatalf("GetTopologySpreadConstraintsHelmValues returned error: %v", err)
+	}
+
+	parsed := map[string]interface{}{}
+	if err := strvals.ParseInto(strings.Join(values, ","), parsed); err != nil {
+		t.Fatalf("error parsing helm values %v: %v", values, err)
+	}
+	constraints, ok := parsed["topologySpreadConstraints"].([]interface{})
+	if !ok || len(constraints) != 2 {
+		t.Fatalf("expected 2 constraints in helm values, got %v", parsed)
+	}
+	zone := constraints[0].(map[string]interface{})
+	if zone["topologyKey"] != "topology.kubernetes.io/zone" || zone["maxSkew"] != int64(1) || zone["whenUnsatisfiable"] != "DoNotSchedule" {
+		t.Errorf("unexpected zone constraint values: %v", zone)
+	}
+	matchLabels := zone["labelSelector"].(map[string]interface{})["matchLabels"].(map[string]interface{})
+	if matchLabels["app"] != "vcluster" {
+		t.Errorf("expected zone constraint to default to the pod labels, got %v", matchLabels)
+	}
+	matchLabels = constraints[1].(map[string]interface{})["labelSelector"].(map[string]interface{})["matchLabels"].(map[string]interface{})
+	if matchLabels["app.kubernetes.io/name"] != "a,b" {
+		t.Errorf("expected escaped label key and value to round trip, got %v", matchLabels)
+	}
+
+	values, err = GetTopologySpreadConstraintsHelmValues(&tenancyv1alpha1.ControlPlane{}, "topologySpreadConstraints", nil)
+	if err != nil || len(values) != 0 {
+		t.Errorf("expected no values without constraints, got %v, %v", values, err)
+	}
+}
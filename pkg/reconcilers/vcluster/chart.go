@@ -19,8 +19,14 @@ package vcluster
 import (
 	"context"
 	"fmt"
+	"os"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
@@ -34,20 +40,36 @@ const (
 	Version            = "0.16.4"
 	RepoName           = "loft"
 	ChartName          = "vcluster"
+	ChartNameK0s       = "vcluster-k0s"
+	ChartNameK8s       = "vcluster-k8s"
+	ChartNameEKS       = "vcluster-eks"
 	ReleaseName        = "vcluster"
 	internalKindAdress = "kubeflex-control-plane"
 )
 
 var (
+	// configs are the default values of the k3s chart
 	configs = []string{
 		"vcluster.image=rancher/k3s:v1.27.2-k3s1",
 	}
+	// podLabels are the labels of the vcluster statefulset pods
+	podLabels = map[string]string{
+		"app":     "vcluster",
+		"release": ReleaseName,
+	}
 )
 
 func (r *VClusterReconciler) ReconcileChart(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane, cfg *shared.SharedConfig) error {
 	_ = clog.FromContext(ctx)
-	dnsName := util.GenerateDevLocalDNSName(hcp.Name, cfg.Domain)
+	dnsName := shared.GetAPIServerHostname(hcp, cfg.Domain)
 	port := cfg.ExternalPort
+	chartName, version := chartForDistro(hcp.Spec.VCluster)
+	var defaults []string
+	if chartName == ChartName {
+		defaults = configs
+	}
+	// copy the defaults so that per control plane values do not leak into the package level configs
+	configs := append([]string{}, defaults...)
 	if cfg.ExternalURL != "" {
 		dnsName = cfg.ExternalURL
 		port = 443
@@ -66,25 +88,196 @@ func (r *VClusterReconciler) ReconcileChart(ctx context.Context, hcp *tenancyv1a
 	configs = append(configs, fmt.Sprintf("syncer.extraArgs[0]=--tls-san=%s", dnsName))
 	configs = append(configs, fmt.Sprintf("syncer.extraArgs[1]=--out-kube-config-server=https://%s:%d", dnsName, port))
 	configs = append(configs, fmt.Sprintf("syncer.extraArgs[2]=--tls-san=%s", internalKindAdress))
-	h := &helm.HelmHandler{
-		URL:         URL,
-		RepoName:    RepoName,
-		ChartName:   ChartName,
-		Version:     Version,
-		Namespace:   util.GenerateNamespaceFromControlPlaneName(hcp.Name),
-		ReleaseName: ReleaseName,
-		Args:        map[string]string{"set": strings.Join(configs, ",")},
-	}
-	err := helm.Init(ctx, h)
+	configs = append(configs, shared.GetImagePullSecretsHelmValues(hcp)...)
+	topologyConfigs, err := shared.GetTopologySpreadConstraintsHelmValues(hcp, "topologySpreadConstraints", podLabels)
+	if err != nil {
+		return err
+	}
+	configs = append(configs, topologyConfigs...)
+	if hcp.Spec.ShutdownDelay != nil && hcp.Spec.ShutdownDelay.Duration > 0 {
+		configs = append(configs, fmt.Sprintf("vcluster.extraArgs[0]=--kube-apiserver-arg=shutdown-delay-duration=%s", hcp.Spec.ShutdownDelay.Duration))
+	}
+	configs = append(configs, persistenceConfigs(hcp.Spec.VCluster)...)
+	configs = append(configs, shared.GetReplicasHelmValues(hcp, replicasValuesPaths(hcp.Spec.VCluster)...)...)
+	// user values go last so that they take precedence
+	configs = append(configs, vclusterSpecConfigs(hcp.Spec.VCluster)...)
+	keyring, err := r.WriteChartKeyring(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	if keyring != "" {
+		defer os.Remove(keyring)
+	}
+	username, password, err := r.GetChartRegistryCredentials(ctx, hcp)
+	if err != nil {
+		return err
+	}
+	values, err := r.GetValuesFrom(ctx, hcp)
 	if err != nil {
 		return err
 	}
+	url := URL
+	if hcp.Spec.Chart != nil {
+		// the chart is pulled from the OCI reference, the chart name only names the archive
+		url = hcp.Spec.Chart.URL
+		chartName = ociChartName(url)
+	}
+	args := map[string]string{
+		"set":        strings.Join(configs, ","),
+		"set-string": strings.Join(shared.GetResourcesHelmValues(hcp, resourcesValuesPath(hcp.Spec.VCluster)), ","),
+	}
+	h := &helm.HelmHandler{
+		URL:              url,
+		RepoName:         RepoName,
+		ChartName:        chartName,
+		Version:          version,
+		Namespace:        util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+		ReleaseName:      ReleaseName,
+		Args:             args,
+		Values:           values,
+		Keyring:          keyring,
+		RegistryUsername: username,
+		RegistryPassword: password,
+	}
+	return shared.RetryChart(ctx, cfg.ChartRetry, func() error {
+		if err := helm.Init(ctx, h); err != nil {
+			return fmt.Errorf("error initializing %s chart version %s: %w", chartName, version, err)
+		}
+		defer shared.SetChartReleasedCondition(hcp, h)
+		if !h.IsDeployed() {
+			start := time.Now()
+			err := h.Install()
+			shared.ObserveChartInstall(hcp, chartName, start)
+			if err != nil {
+				return fmt.Errorf("error installing %s chart version %s: %w", chartName, version, err)
+			}
+			hcp.Status.ValuesFromHash = shared.ValuesFromHash(values)
+			r.RecordNormalEvent(hcp, shared.EventReasonChartInstalled, "Installed chart %s version %s as release %s", chartName, version, ReleaseName)
+			return nil
+		}
+		return r.UpgradeChartOnValuesChange(hcp, h)
+	})
+}
+
+// resourcesValuesPath returns the values path of the resources of the container running the
+// API server, which is the k3s or k0s container or the api container of the k8s and eks charts
+func resourcesValuesPath(spec *tenancyv1alpha1.VClusterSpec) string {
+	switch distroOf(spec) {
+	case tenancyv1alpha1.VClusterDistroK8s, tenancyv1alpha1.VClusterDistroEKS:
+		return "api.resources"
+	default:
+		return "vcluster.resources"
+	}
+}
+
+// replicasValuesPaths returns the values paths of the replica count of the API server, which
+// runs in the syncer statefulset with k3s and k0s, and in its own deployment next to the
+// syncer with the k8s and eks charts
+func replicasValuesPaths(spec *tenancyv1alpha1.VClusterSpec) []string {
+	switch distroOf(spec) {
+	case tenancyv1alpha1.VClusterDistroK8s, tenancyv1alpha1.VClusterDistroEKS:
+		return []string{"syncer.replicas", "api.replicas"}
+	default:
+		return []string{"syncer.replicas"}
+	}
+}
 
-	if !h.IsDeployed() {
-		err := h.Install()
-		if err != nil {
-			return err
+// ValidateVClusterSpec checks that the chart version is a semantic version or version
+// constraint, that the node selector keys are valid label keys, that the values are
+// in the key=value form, that the persistence size is positive and that the service name
+// is a valid service name
+func ValidateVClusterSpec(spec *tenancyv1alpha1.VClusterSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if spec.ChartVersion != "" {
+		if _, err := semver.NewConstraint(spec.ChartVersion); err != nil {
+			return fmt.Errorf("invalid vcluster chart version %q: %s", spec.ChartVersion, err)
+		}
+	}
+	for key := range spec.NodeSelector {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid vcluster node selector key %q: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for _, value := range spec.Values {
+		if key, _, ok := strings.Cut(value, "="); !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid vcluster value %q: must be in the key=value form", value)
+		}
+	}
+	if p := spec.Persistence; p != nil && p.Size != nil && p.Size.Sign() <= 0 {
+		return fmt.Errorf("invalid vcluster persistence size %s: must be positive", p.Size.String())
+	}
+	if spec.ServiceName != "" {
+		if errs := validation.IsDNS1035Label(spec.ServiceName); len(errs) > 0 {
+			return fmt.Errorf("invalid vcluster service name %q: %s", spec.ServiceName, strings.Join(errs, ", "))
 		}
 	}
 	return nil
 }
+
+// distroOf returns the distro selected by the spec, or k3s when none is set
+func distroOf(spec *tenancyv1alpha1.VClusterSpec) tenancyv1alpha1.VClusterDistro {
+	if spec == nil || spec.Distro == "" {
+		return tenancyv1alpha1.VClusterDistroK3s
+	}
+	return spec.Distro
+}
+
+// ValidateDistroUnchanged returns an error if the distro of the spec differs from the distro
+// the vcluster was installed with, since switching distro is not an in-place migration
+func ValidateDistroUnchanged(hcp *tenancyv1alpha1.ControlPlane) error {
+	installed := hcp.Status.VClusterDistro
+	if installed == "" || installed == distroOf(hcp.Spec.VCluster) {
+		return nil
+	}
+	return fmt.Errorf("vcluster distro cannot be changed from %s to %s: the distros do not share their data store, delete and recreate the control plane to switch distro",
+		installed, distroOf(hcp.Spec.VCluster))
+}
+
+// chartForDistro returns the name and version of the vcluster chart for the distro
+func chartForDistro(spec *tenancyv1alpha1.VClusterSpec) (string, string) {
+	chartName, version := ChartName, Version
+	if spec == nil {
+		return chartName, version
+	}
+	switch spec.Distro {
+	case tenancyv1alpha1.VClusterDistroK0s:
+		chartName = ChartNameK0s
+	case tenancyv1alpha1.VClusterDistroK8s:
+		chartName = ChartNameK8s
+	case tenancyv1alpha1.VClusterDistroEKS:
+		chartName = ChartNameEKS
+	}
+	if spec.ChartVersion != "" {
+		version = spec.ChartVersion
+	}
+	return chartName, version
+}
+
+// vclusterSpecConfigs returns the helm values for the node selector and the user values
+func vclusterSpecConfigs(spec *tenancyv1alpha1.VClusterSpec) []string {
+	if spec == nil {
+		return nil
+	}
+	configs := []string{}
+	keys := make([]string, 0, len(spec.NodeSelector))
+	for key := range spec.NodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// dots in label keys such as kubernetes.io/os would be read as nested keys
+		configs = append(configs, fmt.Sprintf("nodeSelector.%s=%s",
+			strings.ReplaceAll(key, ".", `\.`), strings.ReplaceAll(spec.NodeSelector[key], ",", `\,`)))
+	}
+	return append(configs, spec.Values...)
+}
+
+// ociChartName returns the chart name of an OCI chart reference, without the tag or digest
+func ociChartName(url string) string {
+	name := path.Base(url)
+	name, _, _ = strings.Cut(name, "@")
+	name, _, _ = strings.Cut(name, ":")
+	return name
+}
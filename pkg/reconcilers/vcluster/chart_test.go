@@ -0,0 +1,121 @@
+package vcluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/strvals"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestVClusterSpecConfigs(t *testing.T) {
+	spec := &tenancyv1alpha1.VClusterSpec{
+		NodeSelector: map[string]string{
+			"kubernetes.io/os": "linux",
+			"pool":             "control-planes",
+		},
+		Values: []string{
+			"vcluster.image=rancher/k3s:v1.28.2-k3s1",
+			"sync.ingresses.enabled=true",
+		},
+	}
+	// the user values come after the defaults, so they take precedence
+	set := append(append([]string{}, configs...), vclusterSpecConfigs(spec)...)
+	vals, err := strvals.Parse(strings.Join(set, ","))
+	if err != nil {
+		t.Fatalf("error parsing helm values: %v", err)
+	}
+
+	nodeSelector, ok := vals["nodeSelector"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected nodeSelector values, got %v", vals["nodeSelector"])
+	}
+	if nodeSelector["kubernetes.io/os"] != "linux" || nodeSelector["pool"] != "control-planes" {
+		t.Errorf("unexpected nodeSelector values %v", nodeSelector)
+	}
+	vcluster, ok := vals["vcluster"].(map[string]interface{})
+	if !ok || vcluster["image"] != "rancher/k3s:v1.28.2-k3s1" {
+		t.Errorf("expected the user image to override the default, got %v", vals["vcluster"])
+	}
+
+	if configs := vclusterSpecConfigs(nil); len(configs) != 0 {
+		t.Errorf("expected no values without a vcluster spec, got %v", configs)
+	}
+}
+
+func TestChartForDistro(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            *tenancyv1alpha1.VClusterSpec
+		expectedChart   string
+		expectedVersion string
+	}{
+		{"defaults", nil, ChartName, Version},
+		{"k3s", &tenancyv1alpha1.VClusterSpec{Distro: tenancyv1alpha1.VClusterDistroK3s}, ChartName, Version},
+		{"k0s", &tenancyv1alpha1.VClusterSpec{Distro: tenancyv1alpha1.VClusterDistroK0s}, ChartNameK0s, Version},
+		{"k8s with version", &tenancyv1alpha1.VClusterSpec{Distro: tenancyv1alpha1.VClusterDistroK8s, ChartVersion: "0.17.0"}, ChartNameK8s, "0.17.0"},
+		{"eks", &tenancyv1alpha1.VClusterSpec{Distro: tenancyv1alpha1.VClusterDistroEKS}, ChartNameEKS, Version},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chart, version := chartForDistro(tt.spec)
+			if chart != tt.expectedChart || version != tt.expectedVersion {
+				t.Errorf("expected chart %s %s, got %s %s", tt.expectedChart, tt.expectedVersion, chart, version)
+			}
+		})
+	}
+}
+
+func TestValidateDistroUnchanged(t *testing.T) {
+	hcp := &tenancyv1alpha1.ControlPlane{}
+	if err := ValidateDistroUnchanged(hcp); err != nil {
+		t.Errorf("unexpected error before install: %v", err)
+	}
+
+	// an unset distro is the k3s default
+	hcp.Status.VClusterDistro = tenancyv1alpha1.VClusterDistroK3s
+	if err := ValidateDistroUnchanged(hcp); err != nil {
+		t.Errorf("unexpected error for the default distro: %v", err)
+	}
+
+	hcp.Spec.VCluster = &tenancyv1alpha1.VClusterSpec{Distro: tenancyv1alpha1.VClusterDistroK8s}
+	if err := ValidateDistroUnchanged(hcp); err == nil {
+		t.Errorf("expected error when changing the distro")
+	}
+}
+
+func TestValidateVClusterSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *tenancyv1alpha1.VClusterSpec
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid", &tenancyv1alpha1.VClusterSpec{NodeSelector: map[string]string{"kubernetes.io/os": "linux"}, Values: []string{"a.b=c"}}, false},
+		{"chart version", &tenancyv1alpha1.VClusterSpec{ChartVersion: "0.17.0"}, false},
+		{"chart version constraint", &tenancyv1alpha1.VClusterSpec{ChartVersion: "~0.16"}, false},
+		{"invalid chart version", &tenancyv1alpha1.VClusterSpec{ChartVersion: "latest!"}, true},
+		{"invalid node selector key", &tenancyv1alpha1.VClusterSpec{NodeSelector: map[string]string{"bad key": "x"}}, true},
+		{"value without key", &tenancyv1alpha1.VClusterSpec{Values: []string{"=c"}}, true},
+		{"value without equal sign", &tenancyv1alpha1.VClusterSpec{Values: []string{"a.b"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateVClusterSpec(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateVClusterSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReplicasValuesPaths(t *testing.T) {
+	if got := replicasValuesPaths(nil); !reflect.DeepEqual(got, []string{"syncer.replicas"}) {
+		t.Errorf("unexpected paths for the default distro: %v", got)
+	}
+	k8s := &tenancyv1alpha1.VClusterSpec{Distro: tenancyv1alpha1.VClusterDistroK8s}
+	if got := replicasValuesPaths(k8s); !reflect.DeepEqual(got, []string{"syncer.replicas", "api.replicas"}) {
+		t.Errorf("unexpected paths for the k8s distro: %v", got)
+	}
+}
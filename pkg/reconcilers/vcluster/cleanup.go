@@ -0,0 +1,46 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcluster
+
+import (
+	"context"
+	"fmt"
+
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/helm"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// Cleanup uninstalls the vcluster release and deletes the control plane namespace when the
+// control plane is deleted. Both steps tolerate resources that are already gone, so a cleanup
+// interrupted midway is completed when the finalizer is processed again.
+func (r *VClusterReconciler) Cleanup(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	h := &helm.HelmHandler{
+		Namespace:   util.GenerateNamespaceFromControlPlaneName(hcp.Name),
+		ReleaseName: ReleaseName,
+	}
+	if err := helm.Init(ctx, h); err != nil {
+		return err
+	}
+	if err := h.Uninstall(); err != nil {
+		return fmt.Errorf("error uninstalling release %s: %w", ReleaseName, err)
+	}
+	return r.DeleteNamespace(ctx, hcp)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcluster
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+// persistenceConfigs returns the helm values for the vcluster persistence. The k8s and eks
+// distros keep their data in etcd, the other distros in the vcluster statefulset
+func persistenceConfigs(spec *tenancyv1alpha1.VClusterSpec) []string {
+	if spec == nil || spec.Persistence == nil {
+		return nil
+	}
+	prefix := "storage"
+	if hasEtcd(spec) {
+		prefix = "etcd.storage"
+	}
+	p := spec.Persistence
+	configs := []string{}
+	if p.Enabled != nil {
+		configs = append(configs, fmt.Sprintf("%s.persistence=%t", prefix, *p.Enabled))
+	}
+	if p.StorageClass != "" {
+		configs = append(configs, fmt.Sprintf("%s.className=%s", prefix, p.StorageClass))
+	}
+	if p.Size != nil {
+		configs = append(configs, fmt.Sprintf("%s.size=%s", prefix, p.Size.String()))
+	}
+	return configs
+}
+
+// persistenceClaimName returns the name of the PVC the vcluster statefulset creates for its data
+func persistenceClaimName(spec *tenancyv1alpha1.VClusterSpec) string {
+	if hasEtcd(spec) {
+		return fmt.Sprintf("data-%s-etcd-0", ReleaseName)
+	}
+	return fmt.Sprintf("data-%s-0", ReleaseName)
+}
+
+// hasEtcd returns true for the distros whose chart runs a separate etcd statefulset
+func hasEtcd(spec *tenancyv1alpha1.VClusterSpec) bool {
+	distro := distroOf(spec)
+	return distro == tenancyv1alpha1.VClusterDistroK8s || distro == tenancyv1alpha1.VClusterDistroEKS
+}
+
+// ValidatePersistenceUnchanged returns an error if the persistence settings differ from the
+// volume already created for the vcluster. The volume is created with the control plane,
+// and resizing it or changing its storage class is not applied to the running vcluster.
+func (r *VClusterReconciler) ValidatePersistenceUnchanged(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) error {
+	_ = clog.FromContext(ctx)
+	if hcp.Spec.VCluster == nil || hcp.Spec.VCluster.Persistence == nil {
+		return nil
+	}
+	p := hcp.Spec.VCluster.Persistence
+
+	pvc := &v1.PersistentVolumeClaim{}
+	key := client.ObjectKey{Namespace: util.GenerateNamespaceFromControlPlaneName(hcp.Name), Name: persistenceClaimName(hcp.Spec.VCluster)}
+	if err := r.Client.Get(context.TODO(), key, pvc, &client.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if p.Enabled != nil && !*p.Enabled {
+		return fmt.Errorf("vcluster persistence cannot be disabled after creation: volume claim %s exists", pvc.Name)
+	}
+	if p.StorageClass != "" && (pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != p.StorageClass) {
+		current := ""
+		if pvc.Spec.StorageClassName != nil {
+			current = *pvc.Spec.StorageClassName
+		}
+		return fmt.Errorf("vcluster persistence storage class cannot be changed after creation: volume claim %s uses %q, spec requests %q",
+			pvc.Name, current, p.StorageClass)
+	}
+	if p.Size != nil {
+		current, ok := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+		if !ok || current.Cmp(*p.Size) != 0 {
+			return fmt.Errorf("vcluster persistence size cannot be changed after creation: volume claim %s requests %s, spec requests %s",
+				pvc.Name, current.String(), p.Size.String())
+		}
+	}
+	return nil
+}
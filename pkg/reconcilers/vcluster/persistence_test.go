@@ -0,0 +1,89 @@
+package vcluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestPersistenceConfigs(t *testing.T) {
+	disabled := false
+	size := resource.MustParse("10Gi")
+	spec := &tenancyv1alpha1.VClusterSpec{
+		Persistence: &tenancyv1alpha1.VClusterPersistenceSpec{Enabled: &disabled, StorageClass: "fast", Size: &size},
+	}
+	expected := []string{"storage.persistence=false", "storage.className=fast", "storage.size=10Gi"}
+	if configs := persistenceConfigs(spec); !reflect.DeepEqual(configs, expected) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+
+	spec.Distro = tenancyv1alpha1.VClusterDistroK8s
+	if configs := persistenceConfigs(spec); configs[0] != "etcd.storage.persistence=false" {
+		t.Errorf("expected etcd storage values for the k8s distro, got %v", configs)
+	}
+
+	if configs := persistenceConfigs(&tenancyv1alpha1.VClusterSpec{}); len(configs) != 0 {
+		t.Errorf("expected no values without persistence, got %v", configs)
+	}
+}
+
+func TestValidatePersistenceUnchanged(t *testing.T) {
+	storageClass := "standard"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: persistenceClaimName(nil), Namespace: util.GenerateNamespaceFromControlPlaneName("cp1")},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+			},
+		},
+	}
+	disabled := false
+	sameSize := resource.MustParse("5120Mi")
+	newSize := resource.MustParse("10Gi")
+	tests := []struct {
+		name        string
+		persistence *tenancyv1alpha1.VClusterPersistenceSpec
+		expectedErr string
+	}{
+		{name: "unset"},
+		{name: "same settings", persistence: &tenancyv1alpha1.VClusterPersistenceSpec{StorageClass: "standard", Size: &sameSize}},
+		{name: "resized", persistence: &tenancyv1alpha1.VClusterPersistenceSpec{Size: &newSize}, expectedErr: "size cannot be changed"},
+		{name: "storage class changed", persistence: &tenancyv1alpha1.VClusterPersistenceSpec{StorageClass: "fast"}, expectedErr: "storage class cannot be changed"},
+		{name: "disabled", persistence: &tenancyv1alpha1.VClusterPersistenceSpec{Enabled: &disabled}, expectedErr: "cannot be disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestReconciler(t, pvc.DeepCopy())
+			hcp := &tenancyv1alpha1.ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+				Spec:       tenancyv1alpha1.ControlPlaneSpec{VCluster: &tenancyv1alpha1.VClusterSpec{Persistence: tt.persistence}},
+			}
+			err := r.ValidatePersistenceUnchanged(context.Background(), hcp)
+			if tt.expectedErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectedErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+
+	// settings are free before the volume claim is created
+	r := newTestReconciler(t)
+	hcp := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{VCluster: &tenancyv1alpha1.VClusterSpec{Persistence: &tenancyv1alpha1.VClusterPersistenceSpec{Size: &newSize}}},
+	}
+	if err := r.ValidatePersistenceUnchanged(context.Background(), hcp); err != nil {
+		t.Errorf("unexpected error before the volume claim exists: %v", err)
+	}
+}
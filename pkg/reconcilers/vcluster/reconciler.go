@@ -25,10 +25,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
-	clog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
 	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
@@ -37,7 +36,6 @@ import (
 
 const (
 	ServiceName      = "vcluster"
-	ServicePort      = 443
 	kubeconfigSecret = "TODO"
 )
 
@@ -46,32 +44,56 @@ type VClusterReconciler struct {
 	*shared.BaseReconciler
 }
 
-func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient) *VClusterReconciler {
+func New(cl client.Client, scheme *runtime.Scheme, version string, clientSet *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient, recorder record.EventRecorder) *VClusterReconciler {
 	return &VClusterReconciler{
 		BaseReconciler: &shared.BaseReconciler{
 			Client:        cl,
 			Scheme:        scheme,
 			ClientSet:     clientSet,
 			DynamicClient: dynamicClient,
+			Recorder:      recorder,
 		},
 	}
 }
 
 func (r *VClusterReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (ctrl.Result, error) {
 	var routeURL string
-	_ = clog.FromContext(ctx)
+	logger := shared.ControlPlaneLogger(ctx, hcp)
+	logger.V(1).Info("Reconciling control plane")
 
 	cfg, err := r.BaseReconciler.GetConfig(ctx)
 	if err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := shared.ValidateTopologySpreadConstraints(hcp.Spec.TopologySpreadConstraints); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := ValidateVClusterSpec(hcp.Spec.VCluster); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := shared.ValidateIngress(hcp.Spec.Ingress); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	if err := shared.ValidateResources(hcp.Spec.Resources); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	start := time.Now()
 	if err := r.BaseReconciler.ReconcileNamespace(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := r.BaseReconciler.ReconcileImagePullSecrets(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	shared.LogPhase(logger, "namespace", start)
+
 	if cfg.IsOpenShift {
-		if err = r.ReconcileAPIServerRoute(ctx, hcp, ServiceName, shared.SecurePort, cfg.Domain); err != nil {
+		if err = r.ReconcileAPIServerRoute(ctx, hcp, apiServerServiceName(hcp.Spec.VCluster), shared.SecurePort, cfg.Domain); err != nil {
 			return r.UpdateStatusForSyncingError(hcp, err)
 		}
 		routeURL, err = r.GetAPIServerRouteURL(ctx, hcp)
@@ -80,19 +102,51 @@ func (r *VClusterReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1
 		}
 		// re-queue until valid route URL is retrieved
 		if routeURL == "" {
-			return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: r.ProvisioningRequeueDelay()}, nil
 		}
 		cfg.ExternalURL = routeURL
-	} else {
-		if err := r.ReconcileAPIServerIngress(ctx, hcp, ServiceName, ServicePort, cfg.Domain); err != nil {
-			return r.UpdateStatusForSyncingError(hcp, err)
-		}
 	}
 
-	if err := r.ReconcileChart(ctx, hcp, cfg); err != nil {
+	if err := ValidateDistroUnchanged(hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := r.ValidatePersistenceUnchanged(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	start = time.Now()
+	acquired, err := r.ReconcileChartLimited(func() error { return r.ReconcileChart(ctx, hcp, cfg) })
+	if err != nil {
+		return r.UpdateStatusForChartError(hcp, cfg, err)
+	}
+	// re-queue until the other chart operations leave room for this one
+	if !acquired {
+		logger.V(1).Info("Chart operations limit reached, requeueing")
+		return ctrl.Result{RequeueAfter: shared.ChartOpsRequeueDelay}, nil
+	}
+	shared.LogPhase(logger, "chart", start)
+	hcp.Status.VClusterDistro = distroOf(hcp.Spec.VCluster)
+
+	if err := shared.SetRolledOutCondition(r.Client, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	r.CheckChartRollout(hcp, cfg)
+
+	// the ingress is reconciled once the chart is installed, so that it points at the
+	// service the chart actually rendered
+	if !cfg.IsOpenShift {
+		start = time.Now()
+		svcName, svcPort, err := r.GetAPIServerService(ctx, hcp)
+		if err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		if err := r.ReconcileAPIServerIngress(ctx, hcp, svcName, svcPort, cfg.Domain); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		shared.LogPhase(logger, "ingress", start)
+	}
+
 	if err := r.ReconcileNodePortService(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
@@ -101,6 +155,20 @@ func (r *VClusterReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	if err := r.ReconcileAPIServerPodDisruptionBudget(ctx, hcp); err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+
+	// the kubeconfig secret is generated by vcluster once its pod runs, so re-queue with a
+	// growing delay until it exists
+	delay, err := r.WaitForKubeconfigSecret(ctx, hcp)
+	if err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	if delay > 0 {
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
 	if err := r.ReconcileUpdateClusterInfoJobRole(ctx, hcp); err != nil {
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
@@ -113,6 +181,7 @@ func (r *VClusterReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1
 		return r.UpdateStatusForSyncingError(hcp, err)
 	}
 
+	hcp.Status.APIServerEndpoint = shared.GetAPIServerEndpoint(hcp, cfg)
 	r.UpdateStatusWithSecretRef(hcp, util.VClusterKubeConfigSecret,
 		util.KubeconfigSecretKeyVCluster, util.KubeconfigSecretKeyVClusterInCluster)
 
@@ -123,6 +192,13 @@ func (r *VClusterReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1
 		}
 	}
 
+	if len(hcp.Spec.PostCreateHooks) > 0 &&
+		tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		if err := r.ReconcilePostCreateManifests(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+	}
+
 	// update kubeconfig secret to add the incluster config
 	if tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
 		if err := r.ReconcileKubeconfigSecret(ctx, hcp); err != nil {
@@ -130,7 +206,29 @@ func (r *VClusterReconciler) Reconcile(ctx context.Context, hcp *tenancyv1alpha1
 		}
 	}
 
-	return r.UpdateStatusForSyncingSuccess(ctx, hcp)
+	if tenancyv1alpha1.HasConditionAvailable(hcp.Status.Conditions) {
+		if err := r.ReconcileMetricsRBAC(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+		if err := r.ReconcileDefaultStorageClass(ctx, hcp); err != nil {
+			return r.UpdateStatusForSyncingError(hcp, err)
+		}
+	}
+
+	// vcluster serves requests only once its pod runs, so success is reported once the minimum
+	// available replicas are ready and the reconcile is re-queued until then
+	ready, message, err := util.GetAPIServerReadyReplicas(r.Client, *hcp)
+	if err != nil {
+		return r.UpdateStatusForSyncingError(hcp, err)
+	}
+	if ready < util.APIServerMinAvailable(*hcp) {
+		if err := r.UpdateStatusForWaitingForReady(ctx, hcp, message); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.ProvisioningRequeueDelay()}, nil
+	}
+
+	return r.UpdateStatusForSyncingSuccessWithRequeue(ctx, hcp)
 }
 
 // add owner ref to allow capturing lifecycle events for the OCM deployment
@@ -146,13 +244,6 @@ func (r *VClusterReconciler) addOwnerReference(ctx context.Context, hcp *tenancy
 		return err
 	}
 
-	if err := controllerutil.SetControllerReference(hcp, statefulset, r.Scheme); err != nil {
-		return err
-	}
-
-	if err := r.Client.Update(context.TODO(), statefulset, &client.UpdateOptions{}); err != nil {
-		return err
-	}
-
-	return nil
+	// the statefulset is rendered by the chart, so only the owner reference is applied
+	return r.ApplyControllerReference(ctx, hcp, statefulset)
 }
@@ -68,13 +68,7 @@ func (r *VClusterReconciler) ReconcileKubeconfigSecret(ctx context.Context, hcp
 		return err
 	}
 
-	// update secret and write it back
-	ksecret.Data[util.KubeconfigSecretKeyVClusterInCluster] = inclusterConfig
-
-	err = r.Client.Update(context.TODO(), ksecret, &client.UpdateOptions{})
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// the secret is generated by vcluster, so only the in-cluster key is applied
+	return r.ApplySecretData(ctx, hcp, namespace, ksecret.Name,
+		map[string][]byte{util.KubeconfigSecretKeyVClusterInCluster: inclusterConfig})
 }
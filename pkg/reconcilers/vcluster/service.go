@@ -18,6 +18,7 @@ package vcluster
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/kubestellar/kubeflex/pkg/util"
 	corev1 "k8s.io/api/core/v1"
@@ -87,3 +88,46 @@ func generateNodePortService(name, namespace string) *corev1.Service {
 		},
 	}
 }
+
+// GetAPIServerService returns the name and port of the vcluster API server service rendered
+// by the chart in the control plane namespace. The name and port set in the vcluster spec take
+// precedence over the defaults. It fails if the service or port does not exist, so that the
+// ingress never points at a missing backend.
+func (r *VClusterReconciler) GetAPIServerService(ctx context.Context, hcp *tenancyv1alpha1.ControlPlane) (string, int, error) {
+	_ = clog.FromContext(ctx)
+	namespace := util.GenerateNamespaceFromControlPlaneName(hcp.Name)
+	name := apiServerServiceName(hcp.Spec.VCluster)
+
+	service := &corev1.Service{}
+	err := r.Client.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, service, &client.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", 0, fmt.Errorf("vcluster API server service %s/%s not found, set spec.vcluster.serviceName if the chart renders it with another name", namespace, name)
+		}
+		return "", 0, err
+	}
+
+	var port int32
+	if hcp.Spec.VCluster != nil {
+		port = hcp.Spec.VCluster.ServicePort
+	}
+	for _, p := range service.Spec.Ports {
+		if (port != 0 && p.Port == port) || (port == 0 && p.Name == "https") {
+			return name, int(p.Port), nil
+		}
+	}
+	if port == 0 && len(service.Spec.Ports) == 1 {
+		return name, int(service.Spec.Ports[0].Port), nil
+	}
+	if port != 0 {
+		return "", 0, fmt.Errorf("vcluster API server service %s/%s has no port %d", namespace, name, port)
+	}
+	return "", 0, fmt.Errorf("vcluster API server service %s/%s has no port named https, set spec.vcluster.servicePort", namespace, name)
+}
+
+func apiServerServiceName(spec *tenancyv1alpha1.VClusterSpec) string {
+	if spec != nil && spec.ServiceName != "" {
+		return spec.ServiceName
+	}
+	return ServiceName
+}
@@ -0,0 +1,102 @@
+package vcluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+	"github.com/kubestellar/kubeflex/pkg/reconcilers/shared"
+	"github.com/kubestellar/kubeflex/pkg/util"
+)
+
+func TestGetAPIServerService(t *testing.T) {
+	namespace := util.GenerateNamespaceFromControlPlaneName("cp1")
+	newService := func(name string, ports ...corev1.ServicePort) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.ServiceSpec{Ports: ports},
+		}
+	}
+	tests := []struct {
+		name         string
+		spec         *tenancyv1alpha1.VClusterSpec
+		service      *corev1.Service
+		expectedName string
+		expectedPort int
+		expectedErr  string
+	}{
+		{
+			name:         "default service",
+			service:      newService(ServiceName, corev1.ServicePort{Name: "kubelet", Port: 10250}, corev1.ServicePort{Name: "https", Port: 443}),
+			expectedName: ServiceName,
+			expectedPort: 443,
+		},
+		{
+			name:         "custom name and port",
+			spec:         &tenancyv1alpha1.VClusterSpec{ServiceName: "api", ServicePort: 8443},
+			service:      newService("api", corev1.ServicePort{Name: "https", Port: 443}, corev1.ServicePort{Name: "alt", Port: 8443}),
+			expectedName: "api",
+			expectedPort: 8443,
+		},
+		{
+			name:         "single unnamed port",
+			service:      newService(ServiceName, corev1.ServicePort{Port: 6443}),
+			expectedName: ServiceName,
+			expectedPort: 6443,
+		},
+		{
+			name:        "missing service",
+			spec:        &tenancyv1alpha1.VClusterSpec{ServiceName: "api"},
+			service:     newService(ServiceName, corev1.ServicePort{Name: "https", Port: 443}),
+			expectedErr: "service " + namespace + "/api not found",
+		},
+		{
+			name:        "missing port",
+			spec:        &tenancyv1alpha1.VClusterSpec{ServicePort: 8443},
+			service:     newService(ServiceName, corev1.ServicePort{Name: "https", Port: 443}),
+			expectedErr: "has no port 8443",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcp := &tenancyv1alpha1.ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+				Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster, VCluster: tt.spec},
+			}
+			r := newTestReconciler(t, hcp, tt.service)
+			name, port, err := r.GetAPIServerService(context.TODO(), hcp)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAPIServerService returned error: %v", err)
+			}
+			if name != tt.expectedName || port != tt.expectedPort {
+				t.Errorf("expected %s:%d, got %s:%d", tt.expectedName, tt.expectedPort, name, port)
+			}
+		})
+	}
+}
+
+func newTestReconciler(t *testing.T, objs ...client.Object) *VClusterReconciler {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding client-go scheme: %v", err)
+	}
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &VClusterReconciler{BaseReconciler: &shared.BaseReconciler{Client: cl, Scheme: scheme}}
+}
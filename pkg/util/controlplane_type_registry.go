@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+// ControlPlaneTypeSpec captures the kubeconfig conventions of a control plane type
+type ControlPlaneTypeSpec struct {
+	// ClusterName, AuthInfoName and ContextName are the names of the entries of the kubeconfig
+	// generated for the control plane type, which are renamed to the names kubeflex uses for the
+	// control plane. An empty ContextName selects the entries of the current context, for
+	// kubeconfigs whose names are not known in advance.
+	ClusterName  string
+	AuthInfoName string
+	ContextName  string
+	// SecretName is the name of the secret holding the kubeconfig in the control plane namespace
+	SecretName string
+	// SecretKey is the key of the kubeconfig in the secret
+	SecretKey string
+	// InClusterSecretKey is the key of the kubeconfig whose server is the control plane service
+	// in the hosting cluster, empty if the secret has no in-cluster variant
+	InClusterSecretKey string
+}
+
+var (
+	controlPlaneTypesMutex sync.RWMutex
+	controlPlaneTypes      = map[string]ControlPlaneTypeSpec{}
+	// controlPlaneTypeNames keeps the registration order for the error of unsupported types
+	controlPlaneTypeNames []string
+)
+
+func init() {
+	RegisterControlPlaneType(string(tenancyv1alpha1.ControlPlaneTypeK8S), ControlPlaneTypeSpec{
+		// kubeflex generates the k8s kubeconfig with the kubeflex names already, but kubeconfigs
+		// issued from external certs may use generic names such as "kubernetes"
+		SecretName:         AdminConfSecret,
+		SecretKey:          KubeconfigSecretKeyDefault,
+		InClusterSecretKey: KubeconfigSecretKeyInCluster,
+	})
+	RegisterControlPlaneType(string(tenancyv1alpha1.ControlPlaneTypeOCM), ControlPlaneTypeSpec{
+		ClusterName:  "multicluster-controlplane",
+		AuthInfoName: "user",
+		ContextName:  "multicluster-controlplane",
+		SecretName:   OCMKubeConfigSecret,
+		SecretKey:    KubeconfigSecretKeyDefault,
+	})
+	RegisterControlPlaneType(string(tenancyv1alpha1.ControlPlaneTypeVCluster), ControlPlaneTypeSpec{
+		ClusterName:        "my-vcluster",
+		AuthInfoName:       "my-vcluster",
+		ContextName:        "my-vcluster",
+		SecretName:         VClusterKubeConfigSecret,
+		SecretKey:          KubeconfigSecretKeyVCluster,
+		InClusterSecretKey: KubeconfigSecretKeyVClusterInCluster,
+	})
+	RegisterControlPlaneType(string(tenancyv1alpha1.ControlPlaneTypeExternal), ControlPlaneTypeSpec{
+		// the kubeconfig of an adopted cluster is supplied by the user with any names
+		SecretName: AdminConfSecret,
+		SecretKey:  KubeconfigSecretKeyDefault,
+	})
+	RegisterControlPlaneType(string(tenancyv1alpha1.ControlPlaneTypeHost), ControlPlaneTypeSpec{
+		ClusterName:        HostClusterName,
+		AuthInfoName:       HostServiceAccountName,
+		ContextName:        HostClusterName,
+		SecretName:         HostKubeConfigSecret,
+		SecretKey:          KubeconfigSecretKeyDefault,
+		InClusterSecretKey: KubeconfigSecretKeyInCluster,
+	})
+}
+
+// RegisterControlPlaneType registers the kubeconfig conventions of a control plane type, or
+// replaces them if the type is already registered
+func RegisterControlPlaneType(name string, spec ControlPlaneTypeSpec) {
+	controlPlaneTypesMutex.Lock()
+	defer controlPlaneTypesMutex.Unlock()
+	if _, ok := controlPlaneTypes[name]; !ok {
+		controlPlaneTypeNames = append(controlPlaneTypeNames, name)
+	}
+	controlPlaneTypes[name] = spec
+}
+
+// GetControlPlaneTypeSpec returns the conventions of a registered control plane type, or an
+// error listing the registered types
+func GetControlPlaneTypeSpec(name string) (ControlPlaneTypeSpec, error) {
+	controlPlaneTypesMutex.RLock()
+	defer controlPlaneTypesMutex.RUnlock()
+	spec, ok := controlPlaneTypes[name]
+	if !ok {
+		return ControlPlaneTypeSpec{}, fmt.Errorf("unsupported control plane type %q: must be one of %s", name, strings.Join(controlPlaneTypeNames, ", "))
+	}
+	return spec, nil
+}
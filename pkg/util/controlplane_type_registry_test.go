@@ -0,0 +1,57 @@
+package util
+
+import "testing"
+
+func TestRegisterControlPlaneType(t *testing.T) {
+	if err := ValidateControlPlaneType("test-type"); err == nil {
+		t.Fatalf("expected error for unregistered control plane type")
+	}
+	RegisterControlPlaneType("test-type", ControlPlaneTypeSpec{
+		ClusterName:  "test-cluster",
+		AuthInfoName: "test-user",
+		ContextName:  "test-context",
+		SecretName:   "test-kubeconfig",
+		SecretKey:    "config",
+	})
+	if err := ValidateControlPlaneType("test-type"); err != nil {
+		t.Errorf("expected registered control plane type to be valid, got %v", err)
+	}
+	if got := GetKubeconfSecretNameByControlPlaneType("test-type"); got != "test-kubeconfig" {
+		t.Errorf("expected secret name test-kubeconfig, got %s", got)
+	}
+	if got := GetKubeconfSecretKeyNameByControlPlaneType("test-type"); got != "config" {
+		t.Errorf("expected secret key config, got %s", got)
+	}
+	if got := GetInClusterKubeconfSecretKeyNameByControlPlaneType("test-type"); got != "" {
+		t.Errorf("expected no in-cluster key, got %s", got)
+	}
+
+	// registering again replaces the conventions
+	RegisterControlPlaneType("test-type", ControlPlaneTypeSpec{SecretName: "other-kubeconfig"})
+	if got := GetKubeconfSecretNameByControlPlaneType("test-type"); got != "other-kubeconfig" {
+		t.Errorf("expected secret name other-kubeconfig, got %s", got)
+	}
+}
+
+func TestBuiltinControlPlaneTypeSecrets(t *testing.T) {
+	tests := []struct {
+		cpType, secret, key, inClusterKey string
+	}{
+		{"k8s", AdminConfSecret, KubeconfigSecretKeyDefault, KubeconfigSecretKeyInCluster},
+		{"ocm", OCMKubeConfigSecret, KubeconfigSecretKeyDefault, ""},
+		{"vcluster", VClusterKubeConfigSecret, KubeconfigSecretKeyVCluster, KubeconfigSecretKeyVClusterInCluster},
+		{"external", AdminConfSecret, KubeconfigSecretKeyDefault, ""},
+		{"host", HostKubeConfigSecret, KubeconfigSecretKeyDefault, KubeconfigSecretKeyInCluster},
+	}
+	for _, tt := range tests {
+		if got := GetKubeconfSecretNameByControlPlaneType(tt.cpType); got != tt.secret {
+			t.Errorf("%s: expected secret %s, got %s", tt.cpType, tt.secret, got)
+		}
+		if got := GetKubeconfSecretKeyNameByControlPlaneType(tt.cpType); got != tt.key {
+			t.Errorf("%s: expected key %s, got %s", tt.cpType, tt.key, got)
+		}
+		if got := GetInClusterKubeconfSecretKeyNameByControlPlaneType(tt.cpType); got != tt.inClusterKey {
+			t.Errorf("%s: expected in-cluster key %q, got %q", tt.cpType, tt.inClusterKey, got)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+const (
+	fleetStatusConcurrency = 10
+	PhaseUnknown           = "Unknown"
+)
+
+// FleetEntry summarizes the status of a control plane for tabular rendering
+type FleetEntry struct {
+	Name               string
+	Type               tenancyv1alpha1.ControlPlaneType
+	Phase              string
+	Ready              bool
+	LastTransitionTime metav1.Time
+	Error              string
+}
+
+// FleetStatus returns one entry per control plane, sorted by name. The phase and last
+// transition time come from the Ready condition, and a control plane is ready only
+// if its API server is ready too. Failures to check a single control plane are
+// reported in its entry Error and do not fail the whole call.
+func FleetStatus(ctx context.Context, c client.Client) ([]FleetEntry, error) {
+	list := &tenancyv1alpha1.ControlPlaneList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	entries := make([]FleetEntry, len(list.Items))
+	sem := make(chan struct{}, fleetStatusConcurrency)
+	var wg sync.WaitGroup
+	for i := range list.Items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			entries[i] = fleetEntry(c, list.Items[i])
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+func fleetEntry(c client.Client, hcp tenancyv1alpha1.ControlPlane) FleetEntry {
+	entry := FleetEntry{
+		Name:  hcp.Name,
+		Type:  hcp.Spec.Type,
+		Phase: PhaseUnknown,
+	}
+
+	ready := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeReady)
+	if ready != nil {
+		entry.Phase = string(ready.Reason)
+		entry.LastTransitionTime = ready.LastTransitionTime
+		entry.Ready = ready.Status == corev1.ConditionTrue
+	}
+	synced := tenancyv1alpha1.GetCondition(hcp.Status.Conditions, tenancyv1alpha1.TypeSynced)
+	if synced != nil && synced.Reason == tenancyv1alpha1.ReasonReconcileError {
+		entry.Error = synced.Message
+	}
+
+	if entry.Ready {
+		apiServerReady, err := IsAPIServerDeploymentReady(c, hcp)
+		if err != nil {
+			entry.Ready = false
+			if entry.Error == "" {
+				entry.Error = err.Error()
+			}
+		} else {
+			entry.Ready = apiServerReady
+		}
+	}
+	return entry
+}
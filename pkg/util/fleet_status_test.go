@@ -0,0 +1,98 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestFleetStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding client-go scheme: %v", err)
+	}
+	if err := tenancyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding tenancy scheme: %v", err)
+	}
+
+	available := newTestControlPlane("cp-available", tenancyv1alpha1.ConditionAvailable(), tenancyv1alpha1.ConditionReconcileSuccess())
+	creating := newTestControlPlane("cp-creating", tenancyv1alpha1.ConditionCreating(), tenancyv1alpha1.ConditionReconcileSuccess())
+	failing := newTestControlPlane("cp-failing", tenancyv1alpha1.ConditionUnavailable(), tenancyv1alpha1.ConditionReconcileError(fmt.Errorf("boom")))
+	// reports available but its API server namespace is gone
+	missing := newTestControlPlane("cp-missing", tenancyv1alpha1.ConditionAvailable(), tenancyv1alpha1.ConditionReconcileSuccess())
+	unknown := &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp-unknown"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+
+	objs := []client.Object{available, creating, failing, missing, unknown}
+	objs = append(objs, newTestAPIServer("cp-available", 1)...)
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+	entries, err := FleetStatus(context.Background(), cl)
+	if err != nil {
+		t.Fatalf("FleetStatus returned error: %v", err)
+	}
+
+	expected := []struct {
+		name     string
+		phase    string
+		ready    bool
+		hasError bool
+	}{
+		{"cp-available", string(tenancyv1alpha1.ReasonAvailable), true, false},
+		{"cp-creating", string(tenancyv1alpha1.ReasonCreating), false, false},
+		{"cp-failing", string(tenancyv1alpha1.ReasonUnavailable), false, true},
+		{"cp-missing", string(tenancyv1alpha1.ReasonAvailable), false, true},
+		{"cp-unknown", PhaseUnknown, false, false},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(entries), entries)
+	}
+	for i, e := range expected {
+		got := entries[i]
+		if got.Name != e.name || got.Phase != e.phase || got.Ready != e.ready || (got.Error != "") != e.hasError {
+			t.Errorf("entry %d: expected %+v, got %+v", i, e, got)
+		}
+		if got.Type != tenancyv1alpha1.ControlPlaneTypeK8S {
+			t.Errorf("entry %d: expected type k8s, got %s", i, got.Type)
+		}
+	}
+	if entries[2].Error != "boom" {
+		t.Errorf("expected reconcile error message boom, got %s", entries[2].Error)
+	}
+	if entries[0].LastTransitionTime.IsZero() {
+		t.Errorf("expected last transition time to be set")
+	}
+}
+
+func newTestControlPlane(name string, conditions ...tenancyv1alpha1.ControlPlaneCondition) *tenancyv1alpha1.ControlPlane {
+	return &tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+		Status:     tenancyv1alpha1.ControlPlaneStatus{Conditions: conditions},
+	}
+}
+
+func newTestAPIServer(cpName string, readyReplicas int32) []client.Object {
+	namespace := GenerateNamespaceFromControlPlaneName(cpName)
+	return []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: APIServerDeploymentName, Namespace: namespace},
+			Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(1)},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: readyReplicas},
+		},
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+const (
+	helmOwnerLabelKey     = "owner"
+	helmReleaseNameLabel  = "name"
+	helmReleaseSecretType = "helm.sh/release.v1"
+)
+
+// ResourceRef identifies a resource in the hosting cluster
+type ResourceRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (r ResourceRef) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// OwnedResources returns the hosting cluster resources that belong to a control plane.
+// A resource belongs to the control plane when it has an owner reference to it, when it
+// was installed by a helm release in the control plane namespace, or when it lives in
+// the control plane namespace and that namespace is owned by the control plane, since
+// in that case it is garbage collected together with the namespace.
+func OwnedResources(ctx context.Context, client kubernetes.Interface, name, controlPlaneType string) ([]ResourceRef, error) {
+	switch tenancyv1alpha1.ControlPlaneType(controlPlaneType) {
+	case tenancyv1alpha1.ControlPlaneTypeK8S, tenancyv1alpha1.ControlPlaneTypeOCM, tenancyv1alpha1.ControlPlaneTypeVCluster:
+	default:
+		return nil, fmt.Errorf("unsupported control plane type %q", controlPlaneType)
+	}
+
+	namespace := GenerateNamespaceFromControlPlaneName(name)
+	refs := []ResourceRef{}
+
+	nsOwned := false
+	ns, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && isOwnedByControlPlane(ns.ObjectMeta, name) {
+		nsOwned = true
+		refs = append(refs, ResourceRef{Kind: "Namespace", Name: namespace})
+	}
+
+	belongs := func(meta metav1.ObjectMeta) bool {
+		return nsOwned || isOwnedByControlPlane(meta, name) || isManagedByRelease(meta, namespace)
+	}
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	releases := map[string]bool{}
+	for _, s := range secrets.Items {
+		// helm stores one secret per release revision, report the release instead
+		if s.Type == helmReleaseSecretType && s.Labels[helmOwnerLabelKey] == "helm" {
+			releases[s.Labels[helmReleaseNameLabel]] = true
+			continue
+		}
+		if belongs(s.ObjectMeta) {
+			refs = append(refs, ResourceRef{Kind: "Secret", Namespace: namespace, Name: s.Name})
+		}
+	}
+	releaseNames := make([]string, 0, len(releases))
+	for release := range releases {
+		releaseNames = append(releaseNames, release)
+	}
+	sort.Strings(releaseNames)
+	for _, release := range releaseNames {
+		refs = append(refs, ResourceRef{Kind: "Release", Namespace: namespace, Name: release})
+	}
+
+	services, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services.Items {
+		if belongs(s.ObjectMeta) {
+			refs = append(refs, ResourceRef{Kind: "Service", Namespace: namespace, Name: s.Name})
+		}
+	}
+
+	ingresses, err := client.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range ingresses.Items {
+		if belongs(i.ObjectMeta) {
+			refs = append(refs, ResourceRef{Kind: "Ingress", Namespace: namespace, Name: i.Name})
+		}
+	}
+
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pvcs.Items {
+		if belongs(p.ObjectMeta) {
+			refs = append(refs, ResourceRef{Kind: "PersistentVolumeClaim", Namespace: namespace, Name: p.Name})
+		}
+	}
+
+	// cluster-scoped resources only belong through owner references or helm labels
+	helmSelector := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=Helm", ManagedByKey)}
+	clusterRoles, err := client.RbacV1().ClusterRoles().List(ctx, helmSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, cr := range clusterRoles.Items {
+		if isOwnedByControlPlane(cr.ObjectMeta, name) || isManagedByRelease(cr.ObjectMeta, namespace) {
+			refs = append(refs, ResourceRef{Kind: "ClusterRole", Name: cr.Name})
+		}
+	}
+	clusterRoleBindings, err := client.RbacV1().ClusterRoleBindings().List(ctx, helmSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, crb := range clusterRoleBindings.Items {
+		if isOwnedByControlPlane(crb.ObjectMeta, name) || isManagedByRelease(crb.ObjectMeta, namespace) {
+			refs = append(refs, ResourceRef{Kind: "ClusterRoleBinding", Name: crb.Name})
+		}
+	}
+
+	return refs, nil
+}
+
+func isOwnedByControlPlane(meta metav1.ObjectMeta, name string) bool {
+	for _, ref := range meta.OwnerReferences {
+		if ref.Kind == "ControlPlane" && ref.Name == name &&
+			ref.APIVersion == tenancyv1alpha1.GroupVersion.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func isManagedByRelease(meta metav1.ObjectMeta, namespace string) bool {
+	return meta.Labels[ManagedByKey] == "Helm" &&
+		meta.Annotations[HelmReleaseNamespaceAnnotationKey] == namespace
+}
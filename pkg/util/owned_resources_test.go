@@ -0,0 +1,103 @@
+package util
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestOwnedResources(t *testing.T) {
+	objs := []runtime.Object{
+		// cp1: namespace owned by the control plane, so everything in it belongs
+		&corev1.Namespace{ObjectMeta: ownedMeta("", "cp1-system", "cp1")},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "cp1-system", Name: "vc-vcluster"}},
+		helmReleaseSecret("cp1-system", "vcluster", "v1"),
+		helmReleaseSecret("cp1-system", "vcluster", "v2"),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "cp1-system", Name: "vcluster"}},
+		&networkingv1.Ingress{ObjectMeta: ownedMeta("cp1-system", "cp1", "cp1")},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "cp1-system", Name: "data-vcluster-0"}},
+		&rbacv1.ClusterRole{ObjectMeta: helmMeta("vc-cp1-role", "cp1-system")},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: helmMeta("vc-cp1-binding", "cp1-system")},
+		// cp2: namespace not owned, only labeled or owned resources belong
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cp2-system"}},
+		&corev1.Secret{ObjectMeta: ownedMeta("cp2-system", "admin-kubeconfig", "cp2")},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "cp2-system", Name: "unrelated"}},
+		&corev1.Service{ObjectMeta: ownedMeta("cp2-system", "cp2", "other")},
+		&rbacv1.ClusterRole{ObjectMeta: helmMeta("vc-cp2-role", "cp2-system")},
+	}
+	client := fake.NewSimpleClientset(objs...)
+
+	refs, err := OwnedResources(context.Background(), client, "cp1", string(tenancyv1alpha1.ControlPlaneTypeVCluster))
+	if err != nil {
+		t.Fatalf("OwnedResources returned error: %v", err)
+	}
+	expected := []ResourceRef{
+		{Kind: "Namespace", Name: "cp1-system"},
+		{Kind: "Secret", Namespace: "cp1-system", Name: "vc-vcluster"},
+		{Kind: "Release", Namespace: "cp1-system", Name: "vcluster"},
+		{Kind: "Service", Namespace: "cp1-system", Name: "vcluster"},
+		{Kind: "Ingress", Namespace: "cp1-system", Name: "cp1"},
+		{Kind: "PersistentVolumeClaim", Namespace: "cp1-system", Name: "data-vcluster-0"},
+		{Kind: "ClusterRole", Name: "vc-cp1-role"},
+		{Kind: "ClusterRoleBinding", Name: "vc-cp1-binding"},
+	}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected %v, got %v", expected, refs)
+	}
+
+	refs, err = OwnedResources(context.Background(), client, "cp2", string(tenancyv1alpha1.ControlPlaneTypeK8S))
+	if err != nil {
+		t.Fatalf("OwnedResources returned error: %v", err)
+	}
+	expected = []ResourceRef{
+		{Kind: "Secret", Namespace: "cp2-system", Name: "admin-kubeconfig"},
+		{Kind: "ClusterRole", Name: "vc-cp2-role"},
+	}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected %v, got %v", expected, refs)
+	}
+
+	if _, err := OwnedResources(context.Background(), client, "cp1", "unknown"); err == nil {
+		t.Errorf("expected error for unsupported control plane type")
+	}
+}
+
+func ownedMeta(namespace, name, cpName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      name,
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: tenancyv1alpha1.GroupVersion.String(),
+			Kind:       "ControlPlane",
+			Name:       cpName,
+		}},
+	}
+}
+
+func helmMeta(name, releaseNamespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Labels:      map[string]string{ManagedByKey: "Helm"},
+		Annotations: map[string]string{HelmReleaseNamespaceAnnotationKey: releaseNamespace},
+	}
+}
+
+func helmReleaseSecret(namespace, release, revision string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "sh.helm.release.v1." + release + "." + revision,
+			Labels:    map[string]string{"owner": "helm", "name": release},
+		},
+		Type: helmReleaseSecretType,
+	}
+}
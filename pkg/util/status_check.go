@@ -123,6 +123,8 @@ func WaitForNamespaceDeletion(clientset kubernetes.Clientset, name string) error
 	}
 }
 
+// IsAPIServerDeploymentReady reports whether all the replicas of the API server are ready or,
+// when the control plane has a pod disruption budget, whether its minimum available are
 func IsAPIServerDeploymentReady(c client.Client, hcp tenancyv1alpha1.ControlPlane) (bool, error) {
 	ns := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
@@ -146,6 +148,9 @@ func IsAPIServerDeploymentReady(c client.Client, hcp tenancyv1alpha1.ControlPlan
 		}
 
 		// we need to ensure that there is al least one replica in the spec
+		if hcp.Spec.PodDisruptionBudget != nil {
+			return *s.Spec.Replicas > 0 && s.Status.ReadyReplicas >= APIServerMinAvailable(hcp), nil
+		}
 		if s.Status.ReadyReplicas == s.Status.Replicas &&
 			s.Status.Replicas == *s.Spec.Replicas &&
 			*s.Spec.Replicas > 0 {
@@ -164,6 +169,9 @@ func IsAPIServerDeploymentReady(c client.Client, hcp tenancyv1alpha1.ControlPlan
 		}
 
 		// we need to ensure that there is al least one replica in the spec
+		if hcp.Spec.PodDisruptionBudget != nil {
+			return *d.Spec.Replicas > 0 && d.Status.ReadyReplicas >= APIServerMinAvailable(hcp), nil
+		}
 		if d.Status.ReadyReplicas == d.Status.Replicas &&
 			d.Status.Replicas == *d.Spec.Replicas &&
 			*d.Spec.Replicas > 0 {
@@ -173,3 +181,93 @@ func IsAPIServerDeploymentReady(c client.Client, hcp tenancyv1alpha1.ControlPlan
 
 	return false, nil
 }
+
+// GetAPIServerRolloutStatus reports whether all the replicas of the API server statefulset or
+// deployment run its latest spec, with a message describing the progress of the rollout
+func GetAPIServerRolloutStatus(c client.Client, hcp tenancyv1alpha1.ControlPlane) (bool, string, error) {
+	key := types.NamespacedName{
+		Name:      GetAPIServerDeploymentNameByControlPlaneType(string(hcp.Spec.Type)),
+		Namespace: GenerateNamespaceFromControlPlaneName(hcp.Name),
+	}
+	if hcp.Spec.Type == tenancyv1alpha1.ControlPlaneTypeVCluster {
+		s := &v1.StatefulSet{}
+		if err := c.Get(context.Background(), key, s); err != nil {
+			return false, "", err
+		}
+		replicas := int32(1)
+		if s.Spec.Replicas != nil {
+			replicas = *s.Spec.Replicas
+		}
+		complete := s.Status.ObservedGeneration >= s.Generation &&
+			s.Status.UpdatedReplicas == replicas &&
+			s.Status.ReadyReplicas == replicas &&
+			(s.Status.UpdateRevision == "" || s.Status.CurrentRevision == s.Status.UpdateRevision)
+		return complete, fmt.Sprintf("statefulset %s: %d of %d replicas updated, %d ready",
+			key.Name, s.Status.UpdatedReplicas, replicas, s.Status.ReadyReplicas), nil
+	}
+
+	d := &v1.Deployment{}
+	if err := c.Get(context.Background(), key, d); err != nil {
+		return false, "", err
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	complete := d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+	return complete, fmt.Sprintf("deployment %s: %d of %d replicas updated, %d available",
+		key.Name, d.Status.UpdatedReplicas, replicas, d.Status.AvailableReplicas), nil
+}
+
+// GetAPIServerReadyReplicas returns the number of ready replicas of the API server statefulset
+// or deployment of the control plane, with a message describing them
+func GetAPIServerReadyReplicas(c client.Client, hcp tenancyv1alpha1.ControlPlane) (int32, string, error) {
+	key := types.NamespacedName{
+		Name:      GetAPIServerDeploymentNameByControlPlaneType(string(hcp.Spec.Type)),
+		Namespace: GenerateNamespaceFromControlPlaneName(hcp.Name),
+	}
+	if hcp.Spec.Type == tenancyv1alpha1.ControlPlaneTypeVCluster {
+		s := &v1.StatefulSet{}
+		if err := c.Get(context.Background(), key, s); err != nil {
+			return 0, "", err
+		}
+		return s.Status.ReadyReplicas, fmt.Sprintf("statefulset %s: %d of %d replicas ready",
+			key.Name, s.Status.ReadyReplicas, s.Status.Replicas), nil
+	}
+
+	d := &v1.Deployment{}
+	if err := c.Get(context.Background(), key, d); err != nil {
+		return 0, "", err
+	}
+	return d.Status.ReadyReplicas, fmt.Sprintf("deployment %s: %d of %d replicas ready",
+		key.Name, d.Status.ReadyReplicas, d.Status.Replicas), nil
+}
+
+// APIServerReplicas returns the number of API server replicas requested by the control plane,
+// or one when spec.replicas is not set
+func APIServerReplicas(hcp tenancyv1alpha1.ControlPlane) int32 {
+	if hcp.Spec.Replicas == nil {
+		return 1
+	}
+	return *hcp.Spec.Replicas
+}
+
+// APIServerMinAvailable returns the number of ready API server replicas the control plane
+// needs to be ready: the minAvailable of its pod disruption budget, defaulting to one less
+// than the requested replicas, and one when no pod disruption budget is set
+func APIServerMinAvailable(hcp tenancyv1alpha1.ControlPlane) int32 {
+	pdb := hcp.Spec.PodDisruptionBudget
+	if pdb == nil {
+		return 1
+	}
+	if pdb.MinAvailable != nil {
+		return *pdb.MinAvailable
+	}
+	if replicas := APIServerReplicas(hcp); replicas > 1 {
+		return replicas - 1
+	}
+	return 1
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenancyv1alpha1 "github.com/kubestellar/kubeflex/api/v1alpha1"
+)
+
+func TestGetAPIServerReadyReplicas(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding scheme: %v", err)
+	}
+	vcluster := tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	k8s := tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp2"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: VClusterServerDeploymentName, Namespace: GenerateNamespaceFromControlPlaneName("cp1")},
+			Status:     appsv1.StatefulSetStatus{Replicas: 1},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: APIServerDeploymentName, Namespace: GenerateNamespaceFromControlPlaneName("cp2")},
+			Status:     appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 1},
+		},
+	).Build()
+
+	ready, message, err := GetAPIServerReadyReplicas(c, vcluster)
+	if err != nil {
+		t.Fatalf("GetAPIServerReadyReplicas returned error: %v", err)
+	}
+	if ready != 0 || message != "statefulset vcluster: 0 of 1 replicas ready" {
+		t.Errorf("unexpected ready replicas %d, message %q", ready, message)
+	}
+
+	ready, message, err = GetAPIServerReadyReplicas(c, k8s)
+	if err != nil {
+		t.Fatalf("GetAPIServerReadyReplicas returned error: %v", err)
+	}
+	if ready != 1 || message != "deployment kube-apiserver: 1 of 2 replicas ready" {
+		t.Errorf("unexpected ready replicas %d, message %q", ready, message)
+	}
+
+	missing := tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp3"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeVCluster},
+	}
+	if _, _, err := GetAPIServerReadyReplicas(c, missing); err == nil {
+		t.Errorf("expected error for a missing statefulset")
+	}
+}
+
+func TestAPIServerMinAvailable(t *testing.T) {
+	tests := []struct {
+		name string
+		spec tenancyv1alpha1.ControlPlaneSpec
+		want int32
+	}{
+		{name: "no budget", spec: tenancyv1alpha1.ControlPlaneSpec{Replicas: pointer.Int32(3)}, want: 1},
+		{name: "default", spec: tenancyv1alpha1.ControlPlaneSpec{Replicas: pointer.Int32(3), PodDisruptionBudget: &tenancyv1alpha1.PodDisruptionBudgetSpec{}}, want: 2},
+		{name: "single replica", spec: tenancyv1alpha1.ControlPlaneSpec{PodDisruptionBudget: &tenancyv1alpha1.PodDisruptionBudgetSpec{}}, want: 1},
+		{name: "explicit", spec: tenancyv1alpha1.ControlPlaneSpec{Replicas: pointer.Int32(5), PodDisruptionBudget: &tenancyv1alpha1.PodDisruptionBudgetSpec{MinAvailable: pointer.Int32(3)}}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := APIServerMinAvailable(tenancyv1alpha1.ControlPlane{Spec: tt.spec}); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsAPIServerDeploymentReadyMinAvailable(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("error adding scheme: %v", err)
+	}
+	namespace := GenerateNamespaceFromControlPlaneName("cp1")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: APIServerDeploymentName, Namespace: namespace},
+			Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(3)},
+			Status:     appsv1.DeploymentStatus{Replicas: 3, ReadyReplicas: 2},
+		},
+	).Build()
+
+	hcp := tenancyv1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Spec:       tenancyv1alpha1.ControlPlaneSpec{Type: tenancyv1alpha1.ControlPlaneTypeK8S, Replicas: pointer.Int32(3)},
+	}
+	// without a budget all the replicas must be ready
+	if ready, err := IsAPIServerDeploymentReady(c, hcp); err != nil || ready {
+		t.Errorf("expected not ready without a pod disruption budget, got %v, %v", ready, err)
+	}
+	hcp.Spec.PodDisruptionBudget = &tenancyv1alpha1.PodDisruptionBudgetSpec{}
+	if ready, err := IsAPIServerDeploymentReady(c, hcp); err != nil || !ready {
+		t.Errorf("expected ready with 2 of 3 replicas and minAvailable 2, got %v, %v", ready, err)
+	}
+	hcp.Spec.PodDisruptionBudget.MinAvailable = pointer.Int32(3)
+	if ready, err := IsAPIServerDeploymentReady(c, hcp); err != nil || ready {
+		t.Errorf("expected not ready with 2 of 3 replicas and minAvailable 3, got %v, %v", ready, err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/kubestellar/kubeflex/pkg/client"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -39,6 +40,9 @@ const (
 	AdminConfSecret                      = "admin-kubeconfig"
 	OCMKubeConfigSecret                  = "multicluster-controlplane-kubeconfig"
 	VClusterKubeConfigSecret             = "vc-vcluster"
+	HostKubeConfigSecret                 = "host-kubeconfig"
+	HostServiceAccountName               = "kubeflex-admin"
+	HostClusterName                      = "host"
 	VClusterNodePortServiceName          = "vcluster-nodeport"
 	VClusterServiceName                  = "vcluster"
 	KubeconfigSecretKeyDefault           = "kubeconfig"
@@ -47,8 +51,72 @@ const (
 	KubeconfigSecretKeyVClusterInCluster = "config-incluster"
 )
 
+// HostInClusterServer is the address of the API server of the hosting cluster for clients
+// running in the hosting cluster, used as the server of the kubeconfig of a host control plane
+// when no external address is configured
+const HostInClusterServer = "https://kubernetes.default.svc"
+
+// namespaceSuffix is appended to the control plane name to generate its namespace
+const namespaceSuffix = "-system"
+
+// MaxControlPlaneNameLength is the longest control plane name whose generated namespace
+// fits in the 63 characters allowed for a DNS-1123 label when no namespace prefix is set
+const MaxControlPlaneNameLength = validation.DNS1123LabelMaxLength - len(namespaceSuffix)
+
+// NamespacePrefixEnvVar is the environment variable setting a prefix prepended to the
+// namespace of every control plane, so that several KubeFlex installations can share
+// a hosting cluster. The default empty prefix keeps the <name>-system convention.
+const NamespacePrefixEnvVar = "KFLEX_NAMESPACE_PREFIX"
+
+var namespacePrefix = os.Getenv(NamespacePrefixEnvVar)
+
+// SetNamespacePrefix overrides the prefix read from NamespacePrefixEnvVar
+func SetNamespacePrefix(prefix string) {
+	namespacePrefix = prefix
+}
+
+// GetNamespacePrefix returns the prefix prepended to the namespace of every control plane
+func GetNamespacePrefix() string {
+	return namespacePrefix
+}
+
 func GenerateNamespaceFromControlPlaneName(name string) string {
-	return fmt.Sprintf("%s-system", name)
+	return namespacePrefix + name + namespaceSuffix
+}
+
+// reservedControlPlaneNames are the names of the kflex ctx subcommands, since kflex ctx <name>
+// runs the subcommand instead of switching to a control plane with that name
+var reservedControlPlaneNames = []string{"list", "prune"}
+
+// ValidateControlPlaneName returns an error if the namespace generated for a control plane
+// name would not be a valid DNS-1123 label, that is if the name is longer than
+// MaxControlPlaneNameLength minus the namespace prefix or contains characters other than
+// lowercase alphanumerics and '-', or if the name is reserved by a kflex ctx subcommand
+func ValidateControlPlaneName(name string) error {
+	for _, reserved := range reservedControlPlaneNames {
+		if name == reserved {
+			return fmt.Errorf("invalid control plane name %q: must not be one of %s, which are kflex ctx subcommands",
+				name, strings.Join(reservedControlPlaneNames, ", "))
+		}
+	}
+	if maxLength := MaxControlPlaneNameLength - len(namespacePrefix); len(name) > maxLength {
+		return fmt.Errorf("invalid control plane name %q: must be no more than %d characters so that its namespace %q fits in %d characters",
+			name, maxLength, GenerateNamespaceFromControlPlaneName(name), validation.DNS1123LabelMaxLength)
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid control plane name %q: %s", name, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Label(GenerateNamespaceFromControlPlaneName(name)); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace prefix %q: %s", namespacePrefix, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateControlPlaneType returns an error listing the supported control plane types if t
+// is not a registered control plane type
+func ValidateControlPlaneType(t string) error {
+	_, err := GetControlPlaneTypeSpec(t)
+	return err
 }
 
 // GenerateDevLocalDNSName: generates the local dns name for test/dev
@@ -84,30 +152,37 @@ func GetKubernetesClusterVersionInfo(kubeconfig string) (string, error) {
 	return serverVersion.String(), nil
 }
 
+// GetKubeconfSecretNameByControlPlaneType returns the name of the kubeconfig secret of a
+// control plane type. Unregistered types get the name of the k8s secret: callers must reject
+// them with ValidateControlPlaneType first.
 func GetKubeconfSecretNameByControlPlaneType(controlPlaneType string) string {
-	switch controlPlaneType {
-	case string(tenancyv1alpha1.ControlPlaneTypeK8S):
-		return AdminConfSecret
-	case string(tenancyv1alpha1.ControlPlaneTypeOCM):
-		return OCMKubeConfigSecret
-	case string(tenancyv1alpha1.ControlPlaneTypeVCluster):
-		return VClusterKubeConfigSecret
-	default:
-		// TODO - should we instead throw an error?
+	spec, err := GetControlPlaneTypeSpec(controlPlaneType)
+	if err != nil {
 		return AdminConfSecret
 	}
+	return spec.SecretName
 }
 
+// GetKubeconfSecretKeyNameByControlPlaneType returns the key of the kubeconfig in the secret of
+// a control plane type. Unregistered types get the default key: callers must reject them with
+// ValidateControlPlaneType first.
 func GetKubeconfSecretKeyNameByControlPlaneType(controlPlaneType string) string {
-	switch controlPlaneType {
-	case string(tenancyv1alpha1.ControlPlaneTypeK8S), string(tenancyv1alpha1.ControlPlaneTypeOCM):
-		return KubeconfigSecretKeyDefault
-	case string(tenancyv1alpha1.ControlPlaneTypeVCluster):
-		return KubeconfigSecretKeyVCluster
-	default:
-		// TODO - should we instead throw an error?
+	spec, err := GetControlPlaneTypeSpec(controlPlaneType)
+	if err != nil {
 		return KubeconfigSecretKeyDefault
 	}
+	return spec.SecretKey
+}
+
+// GetInClusterKubeconfSecretKeyNameByControlPlaneType returns the key of the kubeconfig whose
+// server is the control plane service in the hosting cluster, or an empty string for the
+// control plane types whose secret has no in-cluster variant
+func GetInClusterKubeconfSecretKeyNameByControlPlaneType(controlPlaneType string) string {
+	spec, err := GetControlPlaneTypeSpec(controlPlaneType)
+	if err != nil {
+		return ""
+	}
+	return spec.InClusterSecretKey
 }
 
 func GetAPIServerDeploymentNameByControlPlaneType(controlPlaneType string) string {
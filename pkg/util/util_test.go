@@ -0,0 +1,74 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateControlPlaneName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "cp1"},
+		{name: strings.Repeat("a", MaxControlPlaneNameLength)},
+		{name: strings.Repeat("a", MaxControlPlaneNameLength+1), wantErr: true},
+		{name: "CP1", wantErr: true},
+		{name: "cp1-", wantErr: true},
+		{name: "cp.1", wantErr: true},
+		{name: "", wantErr: true},
+		{name: "list", wantErr: true},
+		{name: "prune", wantErr: true},
+		{name: "list1"},
+	}
+	for _, tt := range tests {
+		err := ValidateControlPlaneName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateControlPlaneName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && len(GenerateNamespaceFromControlPlaneName(tt.name)) > 63 {
+			t.Errorf("namespace for %q exceeds 63 characters", tt.name)
+		}
+	}
+}
+
+func TestGenerateNamespaceWithPrefix(t *testing.T) {
+	if got := GenerateNamespaceFromControlPlaneName("cp1"); got != "cp1-system" {
+		t.Errorf("expected default namespace cp1-system, got %s", got)
+	}
+
+	defer SetNamespacePrefix(GetNamespacePrefix())
+	SetNamespacePrefix("kf2-")
+	if got := GenerateNamespaceFromControlPlaneName("cp1"); got != "kf2-cp1-system" {
+		t.Errorf("expected namespace kf2-cp1-system, got %s", got)
+	}
+	if err := ValidateControlPlaneName(strings.Repeat("a", MaxControlPlaneNameLength)); err == nil {
+		t.Errorf("expected error for name whose prefixed namespace exceeds 63 characters")
+	}
+	if err := ValidateControlPlaneName(strings.Repeat("a", MaxControlPlaneNameLength-len("kf2-"))); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	SetNamespacePrefix("KF2_")
+	if err := ValidateControlPlaneName("cp1"); err == nil {
+		t.Errorf("expected error for invalid namespace prefix")
+	}
+}
+
+func TestValidateControlPlaneType(t *testing.T) {
+	for _, cpType := range []string{"k8s", "ocm", "vcluster", "external", "host"} {
+		if err := ValidateControlPlaneType(cpType); err != nil {
+			t.Errorf("ValidateControlPlaneType(%q) returned error: %v", cpType, err)
+		}
+	}
+	for _, cpType := range []string{"", "K8S", "kind"} {
+		err := ValidateControlPlaneType(cpType)
+		if err == nil {
+			t.Errorf("expected error for control plane type %q", cpType)
+			continue
+		}
+		if !strings.Contains(err.Error(), "k8s, ocm, vcluster, external, host") {
+			t.Errorf("expected the supported types in the error, got %v", err)
+		}
+	}
+}